@@ -5,9 +5,11 @@ import (
 	"flag"
 	"fmt"
 	"github.com/dashenmiren/EdgeAPI/internal/apps"
+	"github.com/dashenmiren/EdgeAPI/internal/blobs"
 	"github.com/dashenmiren/EdgeAPI/internal/configs"
 	teaconst "github.com/dashenmiren/EdgeAPI/internal/const"
 	"github.com/dashenmiren/EdgeAPI/internal/nodes"
+	"github.com/dashenmiren/EdgeAPI/internal/secrets"
 	"github.com/dashenmiren/EdgeAPI/internal/setup"
 	_ "github.com/dashenmiren/EdgeAPI/internal/tasks"
 	"github.com/iwind/TeaGo/Tea"
@@ -17,7 +19,9 @@ import (
 	"github.com/iwind/gosock/pkg/gosock"
 	"log"
 	"os"
+	"sort"
 	"strings"
+	"time"
 )
 
 func main() {
@@ -27,7 +31,7 @@ func main() {
 	var app = apps.NewAppCmd()
 	app.Version(teaconst.Version)
 	app.Product(teaconst.ProductName)
-	app.Usage(teaconst.ProcessName + " [-h|-v|start|stop|restart|setup|upgrade|service|daemon|issues]")
+	app.Usage(teaconst.ProcessName + " [-h|-v|start|stop|restart|setup|upgrade|service|daemon|issues|secrets|files|ip-items]")
 
 	// 短版本号
 	app.On("-V", func() {
@@ -189,6 +193,133 @@ func main() {
 			}
 		}
 	})
+	app.On("secrets", func() {
+		var action = ""
+		if len(os.Args) > 2 {
+			action = os.Args[2]
+		}
+		switch action {
+		case "genkey": // 生成新的主密钥
+			var keyId = ""
+			var set = flag.NewFlagSet("", flag.ExitOnError)
+			set.StringVar(&keyId, "id", "k"+time.Now().Format("20060102"), "edge-api secrets genkey --id=KEY_ID")
+			_ = set.Parse(os.Args[3:])
+
+			keyLine, err := secrets.GenerateKey(keyId)
+			if err != nil {
+				fmt.Println("[ERROR]" + err.Error())
+				return
+			}
+			fmt.Println(keyLine)
+		case "migrate", "rotate": // 加密数据库中的明文信息 | 使用当前主密钥重新加密数据密钥
+			var sock = gosock.NewTmpSock(teaconst.ProcessName)
+			reply, err := sock.Send(&gosock.Command{Code: "secrets." + action})
+			if err != nil {
+				fmt.Println("[ERROR]" + err.Error() + ", please make sure the api node is running")
+				return
+			}
+			var resultMap = maps.NewMap(reply.Params)
+			if !resultMap.GetBool("isOk") {
+				fmt.Println("[ERROR]" + resultMap.GetString("err"))
+				return
+			}
+			var countMap = resultMap.GetMap("result")
+			fmt.Println("master key: " + countMap.GetString("keyId"))
+			var tables = []string{}
+			for table := range countMap {
+				if table != "keyId" {
+					tables = append(tables, table)
+				}
+			}
+			sort.Strings(tables)
+			for _, table := range tables {
+				fmt.Println(table + ": " + types.String(countMap.GetInt64(table)) + " updated")
+			}
+			fmt.Println("done")
+		default:
+			fmt.Println("usage: " + teaconst.ProcessName + " secrets [genkey [--id=KEY_ID]|migrate|rotate]")
+		}
+	})
+	app.On("files", func() {
+		var action = ""
+		if len(os.Args) > 2 {
+			action = os.Args[2]
+		}
+		switch action {
+		case "migrate": // 迁移文件片段到其他存储
+			var storageType = ""
+			var set = flag.NewFlagSet("", flag.ExitOnError)
+			set.StringVar(&storageType, "to", "", "edge-api files migrate --to=db|file|s3")
+			_ = set.Parse(os.Args[3:])
+			if len(storageType) > 0 && !blobs.IsValidStorageType(storageType) {
+				fmt.Println("[ERROR]invalid storage type '" + storageType + "'")
+				return
+			}
+
+			var sock = gosock.NewTmpSock(teaconst.ProcessName)
+			reply, err := sock.Send(&gosock.Command{
+				Code: "files.migrate",
+				Params: map[string]any{
+					"storage": storageType,
+				},
+			})
+			if err != nil {
+				fmt.Println("[ERROR]" + err.Error() + ", please make sure the api node is running")
+				return
+			}
+			var resultMap = maps.NewMap(reply.Params)
+			if !resultMap.GetBool("isOk") {
+				fmt.Println("[ERROR]" + resultMap.GetString("err") + ", " + types.String(resultMap.GetInt64("count")) + " chunks migrated before error")
+				return
+			}
+			fmt.Println(types.String(resultMap.GetInt64("count")) + " chunks migrated")
+			fmt.Println("done")
+		default:
+			fmt.Println("usage: " + teaconst.ProcessName + " files migrate [--to=db|file|s3]")
+		}
+	})
+	app.On("ip-items", func() {
+		var action = ""
+		if len(os.Args) > 2 {
+			action = os.Args[2]
+		}
+		switch action {
+		case "compact": // 合并IP名单中重叠或者相邻的IP范围
+			var listId int64
+			var dryRun bool
+			var set = flag.NewFlagSet("", flag.ExitOnError)
+			set.Int64Var(&listId, "list", 0, "edge-api ip-items compact --list=LIST_ID")
+			set.BoolVar(&dryRun, "dry-run", false, "edge-api ip-items compact --dry-run")
+			_ = set.Parse(os.Args[3:])
+
+			var sock = gosock.NewTmpSock(teaconst.ProcessName)
+			reply, err := sock.Send(&gosock.Command{
+				Code: "ipItems.compact",
+				Params: map[string]any{
+					"listId": listId,
+					"dryRun": dryRun,
+				},
+			})
+			if err != nil {
+				fmt.Println("[ERROR]" + err.Error() + ", please make sure the api node is running")
+				return
+			}
+			var resultMap = maps.NewMap(reply.Params)
+			if !resultMap.GetBool("isOk") {
+				fmt.Println("[ERROR]" + resultMap.GetString("err"))
+				return
+			}
+			fmt.Println(types.String(resultMap.GetInt("countItems")) + " items in " + types.String(resultMap.GetInt("countLists")) + " lists checked")
+			if dryRun {
+				fmt.Println(types.String(resultMap.GetInt("countMerged")) + " items can be merged into " + types.String(resultMap.GetInt("countCreated")) + " items")
+			} else {
+				fmt.Println(types.String(resultMap.GetInt("countMerged")) + " items merged into " + types.String(resultMap.GetInt("countCreated")) + " items")
+				fmt.Println("done")
+			}
+		default:
+			fmt.Println("usage: " + teaconst.ProcessName + " ip-items compact [--list=LIST_ID] [--dry-run]")
+		}
+	})
 	app.On("token", func() {
 		var role = ""
 		if len(os.Args) <= 2 {
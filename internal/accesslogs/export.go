@@ -0,0 +1,238 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type ExportFormat = string
+
+const (
+	ExportFormatNDJSON  ExportFormat = "ndjson"
+	ExportFormatCSV     ExportFormat = "csv"
+	ExportFormatParquet ExportFormat = "parquet"
+)
+
+var exportCursorReg = regexp.MustCompile(`^(\d{8}):(\d*)$`)
+
+// ExportWriterInterface 导出访问日志的编码器
+type ExportWriterInterface interface {
+	// Write 写入一批访问日志
+	Write(accessLogs []*pb.HTTPAccessLog) error
+
+	// Close 写入剩余的数据，不会关闭底层的Writer
+	Close() error
+}
+
+// NewExportWriter 根据格式创建编码器
+func NewExportWriter(format ExportFormat, writer io.Writer) (ExportWriterInterface, error) {
+	switch format {
+	case ExportFormatNDJSON, "":
+		return newNDJSONExportWriter(writer), nil
+	case ExportFormatCSV:
+		return newCSVExportWriter(writer), nil
+	case ExportFormatParquet:
+		return newParquetExportWriter(writer), nil
+	}
+	return nil, errors.New("invalid export format '" + format + "'")
+}
+
+// IsValidExportFormat 检查导出格式是否有效
+func IsValidExportFormat(format ExportFormat) bool {
+	switch format {
+	case ExportFormatNDJSON, ExportFormatCSV, ExportFormatParquet:
+		return true
+	}
+	return false
+}
+
+// ExportContentType 导出格式对应的Content-Type
+func ExportContentType(format ExportFormat) string {
+	switch format {
+	case ExportFormatCSV:
+		return "text/csv; charset=utf-8"
+	case ExportFormatParquet:
+		return "application/vnd.apache.parquet"
+	}
+	return "application/x-ndjson"
+}
+
+// ExportCursor 导出位置，用来在中断后继续导出
+// 表示已经导出了 Day 这一天中 RequestId 及之前的所有日志
+type ExportCursor struct {
+	Day       string // YYYYMMDD
+	RequestId string // 为空表示从这一天的开头开始
+}
+
+// DecodeExportCursor 解析导出位置
+func DecodeExportCursor(cursorString string) (*ExportCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursorString)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var matches = exportCursorReg.FindStringSubmatch(string(data))
+	if len(matches) == 0 {
+		return nil, errors.New("invalid cursor")
+	}
+	return &ExportCursor{
+		Day:       matches[1],
+		RequestId: matches[2],
+	}, nil
+}
+
+// Encode 编码为字符串
+func (this *ExportCursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(this.Day + ":" + this.RequestId))
+}
+
+// 导出的列，用于CSV和Parquet等表格格式
+type exportColumn struct {
+	Name  string
+	Kind  exportColumnKind
+	Value func(accessLog *pb.HTTPAccessLog) any
+}
+
+type exportColumnKind = int
+
+const (
+	exportColumnKindString    exportColumnKind = iota + 1 // string
+	exportColumnKindInt32                                 // int32
+	exportColumnKindInt64                                 // int64
+	exportColumnKindDouble                                // float64
+	exportColumnKindTimestamp                             // int64，单位为秒
+)
+
+var exportColumns = []*exportColumn{
+	{Name: "requestId", Kind: exportColumnKindString, Value: func(accessLog *pb.HTTPAccessLog) any { return accessLog.RequestId }},
+	{Name: "timestamp", Kind: exportColumnKindTimestamp, Value: func(accessLog *pb.HTTPAccessLog) any { return accessLog.Timestamp }},
+	{Name: "timeISO8601", Kind: exportColumnKindString, Value: func(accessLog *pb.HTTPAccessLog) any { return accessLog.TimeISO8601 }},
+	{Name: "serverId", Kind: exportColumnKindInt64, Value: func(accessLog *pb.HTTPAccessLog) any { return accessLog.ServerId }},
+	{Name: "nodeId", Kind: exportColumnKindInt64, Value: func(accessLog *pb.HTTPAccessLog) any { return accessLog.NodeId }},
+	{Name: "remoteAddr", Kind: exportColumnKindString, Value: func(accessLog *pb.HTTPAccessLog) any { return accessLog.RemoteAddr }},
+	{Name: "host", Kind: exportColumnKindString, Value: func(accessLog *pb.HTTPAccessLog) any { return accessLog.Host }},
+	{Name: "requestMethod", Kind: exportColumnKindString, Value: func(accessLog *pb.HTTPAccessLog) any { return accessLog.RequestMethod }},
+	{Name: "scheme", Kind: exportColumnKindString, Value: func(accessLog *pb.HTTPAccessLog) any { return accessLog.Scheme }},
+	{Name: "proto", Kind: exportColumnKindString, Value: func(accessLog *pb.HTTPAccessLog) any { return accessLog.Proto }},
+	{Name: "requestURI", Kind: exportColumnKindString, Value: func(accessLog *pb.HTTPAccessLog) any { return accessLog.RequestURI }},
+	{Name: "status", Kind: exportColumnKindInt32, Value: func(accessLog *pb.HTTPAccessLog) any { return accessLog.Status }},
+	{Name: "bytesSent", Kind: exportColumnKindInt64, Value: func(accessLog *pb.HTTPAccessLog) any { return accessLog.BytesSent }},
+	{Name: "requestTime", Kind: exportColumnKindDouble, Value: func(accessLog *pb.HTTPAccessLog) any { return accessLog.RequestTime }},
+	{Name: "userAgent", Kind: exportColumnKindString, Value: func(accessLog *pb.HTTPAccessLog) any { return accessLog.UserAgent }},
+	{Name: "referer", Kind: exportColumnKindString, Value: func(accessLog *pb.HTTPAccessLog) any { return accessLog.Referer }},
+	{Name: "firewallPolicyId", Kind: exportColumnKindInt64, Value: func(accessLog *pb.HTTPAccessLog) any { return accessLog.FirewallPolicyId }},
+	{Name: "firewallRuleGroupId", Kind: exportColumnKindInt64, Value: func(accessLog *pb.HTTPAccessLog) any { return accessLog.FirewallRuleGroupId }},
+	{Name: "firewallRuleSetId", Kind: exportColumnKindInt64, Value: func(accessLog *pb.HTTPAccessLog) any { return accessLog.FirewallRuleSetId }},
+	{Name: "firewallRuleId", Kind: exportColumnKindInt64, Value: func(accessLog *pb.HTTPAccessLog) any { return accessLog.FirewallRuleId }},
+	{Name: "tags", Kind: exportColumnKindString, Value: func(accessLog *pb.HTTPAccessLog) any { return strings.Join(accessLog.Tags, ",") }},
+}
+
+// NDJSON，每行一条完整的访问日志
+type ndjsonExportWriter struct {
+	writer *bufio.Writer
+}
+
+func newNDJSONExportWriter(writer io.Writer) *ndjsonExportWriter {
+	return &ndjsonExportWriter{
+		writer: bufio.NewWriter(writer),
+	}
+}
+
+func (this *ndjsonExportWriter) Write(accessLogs []*pb.HTTPAccessLog) error {
+	for _, accessLog := range accessLogs {
+		data, err := json.Marshal(accessLog)
+		if err != nil {
+			return err
+		}
+		_, err = this.writer.Write(data)
+		if err != nil {
+			return err
+		}
+		err = this.writer.WriteByte('\n')
+		if err != nil {
+			return err
+		}
+	}
+	return this.writer.Flush()
+}
+
+func (this *ndjsonExportWriter) Close() error {
+	return this.writer.Flush()
+}
+
+// CSV，第一行为列名
+type csvExportWriter struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func newCSVExportWriter(writer io.Writer) *csvExportWriter {
+	return &csvExportWriter{
+		writer: csv.NewWriter(writer),
+	}
+}
+
+func (this *csvExportWriter) Write(accessLogs []*pb.HTTPAccessLog) error {
+	err := this.writeHeader()
+	if err != nil {
+		return err
+	}
+
+	var record = make([]string, len(exportColumns))
+	for _, accessLog := range accessLogs {
+		for index, column := range exportColumns {
+			record[index] = formatExportValue(column.Value(accessLog))
+		}
+		err = this.writer.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	this.writer.Flush()
+	return this.writer.Error()
+}
+
+func (this *csvExportWriter) Close() error {
+	err := this.writeHeader()
+	if err != nil {
+		return err
+	}
+	this.writer.Flush()
+	return this.writer.Error()
+}
+
+func (this *csvExportWriter) writeHeader() error {
+	if this.headerWritten {
+		return nil
+	}
+	this.headerWritten = true
+
+	var header = []string{}
+	for _, column := range exportColumns {
+		header = append(header, column.Name)
+	}
+	return this.writer.Write(header)
+}
+
+func formatExportValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"github.com/parquet-go/parquet-go"
+	"io"
+)
+
+// Parquet文件使用 parquet-go 编码，所有列均为REQUIRED，
+// 每次Write生成一个RowGroup，所以可以边查询边输出，不需要在内存中缓存整个文件
+
+var parquetExportSchema, parquetExportColumns = newParquetExportSchema()
+
+// 根据导出字段构造Parquet Schema
+// parquet-go 会按照字段名对列排序，所以同时返回和列顺序一致的导出字段
+func newParquetExportSchema() (*parquet.Schema, []*exportColumn) {
+	var group = parquet.Group{}
+	for _, column := range exportColumns {
+		group[column.Name] = parquetColumnNode(column.Kind)
+	}
+	var schema = parquet.NewSchema("accessLog", group)
+
+	var columns = make([]*exportColumn, len(exportColumns))
+	for _, column := range exportColumns {
+		leaf, ok := schema.Lookup(column.Name)
+		if !ok {
+			panic("parquet column '" + column.Name + "' not found")
+		}
+		columns[leaf.ColumnIndex] = column
+	}
+	return schema, columns
+}
+
+func parquetColumnNode(kind exportColumnKind) parquet.Node {
+	switch kind {
+	case exportColumnKindInt32:
+		return parquet.Int(32)
+	case exportColumnKindInt64:
+		return parquet.Int(64)
+	case exportColumnKindDouble:
+		return parquet.Leaf(parquet.DoubleType)
+	case exportColumnKindTimestamp:
+		return parquet.Timestamp(parquet.Millisecond)
+	default:
+		return parquet.String()
+	}
+}
+
+func parquetColumnValue(kind exportColumnKind, value any) parquet.Value {
+	switch kind {
+	case exportColumnKindTimestamp:
+		// 秒 => 毫秒
+		return parquet.Int64Value(value.(int64) * 1000)
+	default:
+		return parquet.ValueOf(value)
+	}
+}
+
+type parquetExportWriter struct {
+	writer *parquet.Writer
+}
+
+func newParquetExportWriter(writer io.Writer) *parquetExportWriter {
+	return &parquetExportWriter{
+		// 不使用内部缓冲，保证每次Write后当前批次的数据都已经写入writer
+		writer: parquet.NewWriter(writer, parquetExportSchema, parquet.Compression(&parquet.Snappy), parquet.WriteBufferSize(0)),
+	}
+}
+
+func (this *parquetExportWriter) Write(accessLogs []*pb.HTTPAccessLog) error {
+	if len(accessLogs) == 0 {
+		return nil
+	}
+
+	var rows = make([]parquet.Row, 0, len(accessLogs))
+	for _, accessLog := range accessLogs {
+		var row = make(parquet.Row, len(parquetExportColumns))
+		for index, column := range parquetExportColumns {
+			row[index] = parquetColumnValue(column.Kind, column.Value(accessLog)).Level(0, 0, index)
+		}
+		rows = append(rows, row)
+	}
+
+	_, err := this.writer.WriteRows(rows)
+	if err != nil {
+		return err
+	}
+
+	// 输出当前RowGroup
+	return this.writer.Flush()
+}
+
+// Close 写入文件尾部元数据，不会关闭底层的Writer
+func (this *parquetExportWriter) Close() error {
+	return this.writer.Close()
+}
@@ -0,0 +1,294 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"github.com/parquet-go/parquet-go"
+	"io"
+	"testing"
+)
+
+var testExportAccessLogs = []*pb.HTTPAccessLog{
+	{
+		RequestId:   "17000000000000000001",
+		Timestamp:   1700000000,
+		ServerId:    1,
+		NodeId:      2,
+		RemoteAddr:  "192.168.1.100",
+		Host:        "example.com",
+		Status:      200,
+		BytesSent:   1024,
+		RequestTime: 0.25,
+		UserAgent:   "Mozilla/5.0, \"quoted\"",
+		Tags:        []string{"a", "b"},
+	},
+	{
+		RequestId: "17000000000000000002",
+		Timestamp: 1700000001,
+		Host:      "中文.com",
+		Status:    502,
+	},
+}
+
+func TestExportCursor(t *testing.T) {
+	var cursor = &ExportCursor{
+		Day:       "20231114",
+		RequestId: "17000000000000000001",
+	}
+	decodedCursor, err := DecodeExportCursor(cursor.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *decodedCursor != *cursor {
+		t.Fatal("expect", cursor, "got", decodedCursor)
+	}
+
+	for _, s := range []string{"", "abc", (&ExportCursor{Day: "2023", RequestId: "1"}).Encode(), (&ExportCursor{Day: "20231114", RequestId: "1 OR 1=1"}).Encode()} {
+		_, err = DecodeExportCursor(s)
+		if err == nil {
+			t.Fatal("expect error for cursor '" + s + "'")
+		}
+	}
+}
+
+func TestExportWriter_NDJSON(t *testing.T) {
+	var buf = &bytes.Buffer{}
+	writer, err := NewExportWriter(ExportFormatNDJSON, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, accessLog := range testExportAccessLogs {
+		err = writer.Write([]*pb.HTTPAccessLog{accessLog})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var scanner = bufio.NewScanner(buf)
+	var count = 0
+	for scanner.Scan() {
+		var accessLog = &pb.HTTPAccessLog{}
+		err = json.Unmarshal(scanner.Bytes(), accessLog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if accessLog.RequestId != testExportAccessLogs[count].RequestId {
+			t.Fatal("unexpected requestId:", accessLog.RequestId)
+		}
+		count++
+	}
+	if count != len(testExportAccessLogs) {
+		t.Fatal("expect", len(testExportAccessLogs), "lines, got", count)
+	}
+}
+
+func TestExportWriter_CSV(t *testing.T) {
+	var buf = &bytes.Buffer{}
+	writer, err := NewExportWriter(ExportFormatCSV, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writer.Write(testExportAccessLogs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatal("expect 3 records, got", len(records))
+	}
+	var row = map[string]string{}
+	for index, name := range records[0] {
+		row[name] = records[1][index]
+	}
+	for name, value := range map[string]string{
+		"requestId":   "17000000000000000001",
+		"status":      "200",
+		"requestTime": "0.25",
+		"userAgent":   "Mozilla/5.0, \"quoted\"",
+		"tags":        "a,b",
+	} {
+		if row[name] != value {
+			t.Fatal("expect", name, "=", value, "got", row[name])
+		}
+	}
+}
+
+func TestExportWriter_CSV_Empty(t *testing.T) {
+	var buf = &bytes.Buffer{}
+	writer, err := NewExportWriter(ExportFormatCSV, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatal("expect header only, got", len(records), "records")
+	}
+}
+
+func TestExportWriter_Parquet(t *testing.T) {
+	var buf = &bytes.Buffer{}
+	writer, err := NewExportWriter(ExportFormatParquet, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 两个RowGroup，每次Write后数据都应该已经写入
+	var lastSize = 0
+	for _, accessLog := range testExportAccessLogs {
+		err = writer.Write([]*pb.HTTPAccessLog{accessLog})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() <= lastSize {
+			t.Fatal("expect row group to be written on Write()")
+		}
+		lastSize = buf.Len()
+	}
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var data = buf.Bytes()
+	file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// schema
+	var schema = file.Schema()
+	var expectedTypes = map[string]string{
+		"requestId":   "BYTE_ARRAY",
+		"timestamp":   "INT64",
+		"status":      "INT32",
+		"bytesSent":   "INT64",
+		"requestTime": "DOUBLE",
+	}
+	if len(schema.Fields()) != len(exportColumns) {
+		t.Fatal("expect", len(exportColumns), "fields, got", len(schema.Fields()))
+	}
+	for _, column := range exportColumns {
+		leaf, ok := schema.Lookup(column.Name)
+		if !ok {
+			t.Fatal("column '" + column.Name + "' not found")
+		}
+		expectedType, ok := expectedTypes[column.Name]
+		if ok && leaf.Node.Type().Kind().String() != expectedType {
+			t.Fatal("column '"+column.Name+"': expect", expectedType, "got", leaf.Node.Type().Kind().String())
+		}
+	}
+	timestampLeaf, _ := schema.Lookup("timestamp")
+	if timestampLeaf.Node.Type().LogicalType().Timestamp == nil {
+		t.Fatal("expect timestamp logical type")
+	}
+
+	// row groups
+	if len(file.RowGroups()) != 2 {
+		t.Fatal("expect 2 row groups, got", len(file.RowGroups()))
+	}
+	if file.NumRows() != int64(len(testExportAccessLogs)) {
+		t.Fatal("expect", len(testExportAccessLogs), "rows, got", file.NumRows())
+	}
+
+	// values
+	var reader = parquet.NewReader(file)
+	defer func() {
+		_ = reader.Close()
+	}()
+	var rows = []parquet.Row{}
+	for {
+		var buffer = make([]parquet.Row, 10)
+		n, readErr := reader.ReadRows(buffer)
+		for _, row := range buffer[:n] {
+			rows = append(rows, row.Clone())
+		}
+		if readErr != nil {
+			if readErr != io.EOF {
+				t.Fatal(readErr)
+			}
+			break
+		}
+	}
+	if len(rows) != len(testExportAccessLogs) {
+		t.Fatal("expect", len(testExportAccessLogs), "rows, got", len(rows))
+	}
+	var valueOf = func(row parquet.Row, name string) parquet.Value {
+		leaf, _ := schema.Lookup(name)
+		return row[leaf.ColumnIndex]
+	}
+	for index, accessLog := range testExportAccessLogs {
+		var row = rows[index]
+		if valueOf(row, "requestId").String() != accessLog.RequestId {
+			t.Fatal("requestId: expect", accessLog.RequestId, "got", valueOf(row, "requestId").String())
+		}
+		if valueOf(row, "timestamp").Int64() != accessLog.Timestamp*1000 {
+			t.Fatal("timestamp: expect", accessLog.Timestamp*1000, "got", valueOf(row, "timestamp").Int64())
+		}
+		if valueOf(row, "host").String() != accessLog.Host {
+			t.Fatal("host: expect", accessLog.Host, "got", valueOf(row, "host").String())
+		}
+		if valueOf(row, "status").Int32() != accessLog.Status {
+			t.Fatal("status: expect", accessLog.Status, "got", valueOf(row, "status").Int32())
+		}
+		if valueOf(row, "bytesSent").Int64() != accessLog.BytesSent {
+			t.Fatal("bytesSent: expect", accessLog.BytesSent, "got", valueOf(row, "bytesSent").Int64())
+		}
+		if valueOf(row, "requestTime").Double() != float64(accessLog.RequestTime) {
+			t.Fatal("requestTime: expect", accessLog.RequestTime, "got", valueOf(row, "requestTime").Double())
+		}
+	}
+	if valueOf(rows[0], "tags").String() != "a,b" {
+		t.Fatal("tags: expect 'a,b', got", valueOf(rows[0], "tags").String())
+	}
+}
+
+func TestExportWriter_Parquet_Empty(t *testing.T) {
+	var buf = &bytes.Buffer{}
+	writer, err := NewExportWriter(ExportFormatParquet, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var data = buf.Bytes()
+	file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.NumRows() != 0 {
+		t.Fatal("expect 0 rows, got", file.NumRows())
+	}
+}
+
+func TestExportWriter_InvalidFormat(t *testing.T) {
+	_, err := NewExportWriter("xml", &bytes.Buffer{})
+	if err == nil {
+		t.Fatal("expect error")
+	}
+}
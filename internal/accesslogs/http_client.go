@@ -0,0 +1,73 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"bytes"
+	"crypto/tls"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const httpSinkTimeout = 30 * time.Second
+
+// HTTPSinkOptions 通过HTTP写入的存储共用的选项
+type HTTPSinkOptions struct {
+	Endpoint           string `json:"endpoint"`           // 接口地址
+	Username           string `json:"username"`           // 用户名
+	Password           string `json:"password"`           // 密码
+	InsecureSkipVerify bool   `json:"insecureSkipVerify"` // 是否跳过证书校验
+}
+
+func (this *HTTPSinkOptions) validate() error {
+	if len(this.Endpoint) == 0 {
+		return errors.New("'endpoint' should not be empty")
+	}
+	if !strings.HasPrefix(this.Endpoint, "http://") && !strings.HasPrefix(this.Endpoint, "https://") {
+		return errors.New("'endpoint' should start with 'http://' or 'https://'")
+	}
+	this.Endpoint = strings.TrimRight(this.Endpoint, "/")
+	return nil
+}
+
+func newHTTPSinkClient(options *HTTPSinkOptions) *http.Client {
+	return &http.Client{
+		Timeout: httpSinkTimeout,
+		Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			MaxIdleConnsPerHost: 8,
+			IdleConnTimeout:     90 * time.Second,
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: options.InsecureSkipVerify,
+			},
+		},
+	}
+}
+
+// 发送请求，状态码不是2xx时返回错误
+func doHTTPSinkRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var message = string(bytes.TrimSpace(data))
+		if len(message) > 256 {
+			message = message[:256] + "..."
+		}
+		return nil, errors.New("unexpected response status '" + strconv.Itoa(resp.StatusCode) + "': " + message)
+	}
+	return data, nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/goman"
+	"github.com/dashenmiren/EdgeAPI/internal/remotelogs"
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"github.com/iwind/TeaGo/types"
+	"sync"
+	"time"
+)
+
+// ManagerReloadInterval 重新加载策略的间隔
+const ManagerReloadInterval = 10 * time.Second
+
+var SharedManager = NewManager()
+
+// Manager 访问日志策略管理器
+// 按策略ID管理所有启用的策略，并将访问日志分发到每个策略
+type Manager struct {
+	policies       map[int64]*Policy // policyId => *Policy
+	failedVersions map[int64]int     // policyId => version，用来避免重复创建失败的策略
+	canWriteToDB   bool
+
+	loader   func() ([]*PolicyConfig, error)
+	loadedAt time.Time
+
+	locker       sync.RWMutex
+	reloadLocker sync.Mutex
+}
+
+func NewManager() *Manager {
+	return &Manager{
+		policies:       map[int64]*Policy{},
+		failedVersions: map[int64]int{},
+		canWriteToDB:   true,
+	}
+}
+
+// SetPoliciesLoader 设置读取策略的方法
+func (this *Manager) SetPoliciesLoader(loader func() ([]*PolicyConfig, error)) {
+	this.reloadLocker.Lock()
+	this.loader = loader
+	this.loadedAt = time.Time{}
+	this.reloadLocker.Unlock()
+}
+
+// Write 将访问日志分发到所有策略
+func (this *Manager) Write(accessLogs []*pb.HTTPAccessLog) {
+	if len(accessLogs) == 0 {
+		return
+	}
+
+	this.checkReload()
+
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+
+	for _, policy := range this.policies {
+		policy.Push(accessLogs)
+	}
+}
+
+// CanWriteToDB 是否仍然需要写入默认的数据库
+func (this *Manager) CanWriteToDB() bool {
+	this.checkReload()
+
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+	return this.canWriteToDB
+}
+
+// UpdatePolicies 更新策略
+// 版本号没有变化的策略保持不变，有变化的策略会重新创建，不存在的策略会被停止
+func (this *Manager) UpdatePolicies(configs []*PolicyConfig) {
+	var stoppingPolicies = []*Policy{}
+	var canWriteToDB = true
+
+	this.locker.Lock()
+
+	var policyIds = map[int64]bool{}
+	for _, config := range configs {
+		policyIds[config.Id] = true
+		if config.DisableDefaultDB {
+			canWriteToDB = false
+		}
+
+		oldPolicy, ok := this.policies[config.Id]
+		if ok {
+			var oldConfig = oldPolicy.Config()
+			if oldConfig.Version == config.Version && oldConfig.Type == config.Type {
+				continue
+			}
+			stoppingPolicies = append(stoppingPolicies, oldPolicy)
+			delete(this.policies, config.Id)
+		}
+
+		failedVersion, ok := this.failedVersions[config.Id]
+		if ok && failedVersion == config.Version {
+			continue
+		}
+
+		policy, err := NewPolicy(config)
+		if err != nil {
+			this.failedVersions[config.Id] = config.Version
+			remotelogs.Error("ACCESS_LOG_POLICY", "create policy '"+types.String(config.Id)+"' failed: "+err.Error())
+			continue
+		}
+		delete(this.failedVersions, config.Id)
+		policy.Start()
+		this.policies[config.Id] = policy
+	}
+
+	for policyId, policy := range this.policies {
+		if !policyIds[policyId] {
+			stoppingPolicies = append(stoppingPolicies, policy)
+			delete(this.policies, policyId)
+		}
+	}
+	for policyId := range this.failedVersions {
+		if !policyIds[policyId] {
+			delete(this.failedVersions, policyId)
+		}
+	}
+
+	this.canWriteToDB = canWriteToDB
+
+	this.locker.Unlock()
+
+	// 停止时需要写入剩余的日志，所以放在后台进行
+	if len(stoppingPolicies) > 0 {
+		goman.New(func() {
+			for _, policy := range stoppingPolicies {
+				policy.Stop()
+			}
+		})
+	}
+}
+
+// Stop 停止所有策略
+func (this *Manager) Stop() {
+	this.locker.Lock()
+	var policies = this.policies
+	this.policies = map[int64]*Policy{}
+	this.locker.Unlock()
+
+	for _, policy := range policies {
+		policy.Stop()
+	}
+}
+
+func (this *Manager) checkReload() {
+	if !this.reloadLocker.TryLock() {
+		return
+	}
+	defer this.reloadLocker.Unlock()
+
+	if this.loader == nil || time.Since(this.loadedAt) < ManagerReloadInterval {
+		return
+	}
+	this.loadedAt = time.Now()
+
+	configs, err := this.loader()
+	if err != nil {
+		remotelogs.Error("ACCESS_LOG_POLICY", "load policies failed: "+err.Error())
+		return
+	}
+	this.UpdatePolicies(configs)
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"errors"
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"testing"
+	"time"
+)
+
+func TestManager_UpdatePolicies(t *testing.T) {
+	var manager = NewManager()
+	defer manager.Stop()
+
+	manager.UpdatePolicies([]*PolicyConfig{
+		{Id: 1, Version: 1, Type: testSinkType},
+		{Id: 2, Version: 1, Type: "unknown"},
+	})
+	var policy1 = manager.policies[1]
+	if policy1 == nil {
+		t.Fatal("policy 1 should be created")
+	}
+	if manager.policies[2] != nil {
+		t.Fatal("policy with unknown type should be ignored")
+	}
+	if !manager.CanWriteToDB() {
+		t.Fatal("should write to db")
+	}
+
+	// 版本号不变时保持原有策略
+	manager.UpdatePolicies([]*PolicyConfig{
+		{Id: 1, Version: 1, Type: testSinkType, DisableDefaultDB: true},
+	})
+	if manager.policies[1] != policy1 {
+		t.Fatal("policy 1 should not be recreated")
+	}
+	if manager.CanWriteToDB() {
+		t.Fatal("should not write to db")
+	}
+
+	// 版本号变化时重新创建
+	manager.UpdatePolicies([]*PolicyConfig{
+		{Id: 1, Version: 2, Type: testSinkType},
+	})
+	if manager.policies[1] == policy1 {
+		t.Fatal("policy 1 should be recreated")
+	}
+
+	// 删除
+	manager.UpdatePolicies(nil)
+	if len(manager.policies) != 0 {
+		t.Fatal("all policies should be removed")
+	}
+}
+
+func TestManager_Write(t *testing.T) {
+	var manager = NewManager()
+	defer manager.Stop()
+
+	var countLoads = 0
+	manager.SetPoliciesLoader(func() ([]*PolicyConfig, error) {
+		countLoads++
+		if countLoads > 1 {
+			return nil, errors.New("should not reload so soon")
+		}
+		return []*PolicyConfig{{Id: 1, Version: 1, Type: testSinkType}}, nil
+	})
+
+	manager.Write([]*pb.HTTPAccessLog{{RequestId: "1"}})
+	manager.Write([]*pb.HTTPAccessLog{{RequestId: "2"}})
+	if countLoads != 1 {
+		t.Fatal("expect 1 load, got", countLoads)
+	}
+
+	var sink = testSinkInstance
+	time.Sleep(DefaultPolicyFlushInterval + 200*time.Millisecond)
+
+	sink.locker.Lock()
+	defer sink.locker.Unlock()
+	if len(sink.accessLogs) != 2 {
+		t.Fatal("expect 2 access logs, got", len(sink.accessLogs))
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"encoding/json"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/dashenmiren/EdgeAPI/internal/goman"
+	"github.com/dashenmiren/EdgeAPI/internal/remotelogs"
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"github.com/dashenmiren/EdgeCommon/pkg/serverconfigs/shared"
+	"github.com/iwind/TeaGo/types"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultPolicyQueueSize     = 10_000          // 每个策略缓冲的日志数量
+	DefaultPolicyBatchSize     = 500             // 每批写入的日志数量
+	DefaultPolicyFlushInterval = 1 * time.Second // 缓冲区未满时写入的间隔
+	DefaultPolicyMaxRetries    = 3               // 写入失败后的重试次数
+	DefaultPolicyRetryInterval = 1 * time.Second // 第一次重试的间隔，之后每次翻倍
+)
+
+// PolicyConfig 访问日志策略配置
+type PolicyConfig struct {
+	Id               int64
+	Version          int
+	Type             SinkType
+	OptionsJSON      []byte
+	CondsJSON        []byte
+	IsPublic         bool
+	FirewallOnly     bool
+	DisableDefaultDB bool
+}
+
+// Policy 访问日志策略
+// 对日志进行过滤、缓冲，并分批写入到存储中，写入失败时会重试
+type Policy struct {
+	config *PolicyConfig
+	sink   SinkInterface
+	conds  *shared.HTTPRequestCondsConfig
+
+	queue    chan *pb.HTTPAccessLog
+	stopChan chan struct{}
+	doneChan chan struct{}
+	stopOnce sync.Once
+
+	batchSize     int
+	flushInterval time.Duration
+	maxRetries    int
+	retryInterval time.Duration
+
+	countDropped int64
+	countFailed  int64
+}
+
+// NewPolicy 创建策略
+func NewPolicy(config *PolicyConfig) (*Policy, error) {
+	var sink = NewSink(config.Type)
+	if sink == nil {
+		return nil, errors.New("invalid policy type '" + config.Type + "'")
+	}
+	err := sink.Init(config.OptionsJSON)
+	if err != nil {
+		return nil, errors.New("init '" + config.Type + "' sink failed: " + err.Error())
+	}
+
+	var policy = &Policy{
+		config:        config,
+		sink:          sink,
+		queue:         make(chan *pb.HTTPAccessLog, DefaultPolicyQueueSize),
+		stopChan:      make(chan struct{}),
+		doneChan:      make(chan struct{}),
+		batchSize:     DefaultPolicyBatchSize,
+		flushInterval: DefaultPolicyFlushInterval,
+		maxRetries:    DefaultPolicyMaxRetries,
+		retryInterval: DefaultPolicyRetryInterval,
+	}
+
+	if len(config.CondsJSON) > 0 && string(config.CondsJSON) != "null" {
+		var conds = &shared.HTTPRequestCondsConfig{}
+		err = json.Unmarshal(config.CondsJSON, conds)
+		if err != nil {
+			_ = sink.Close()
+			return nil, errors.New("decode conds failed: " + err.Error())
+		}
+		if conds.IsOn {
+			err = conds.Init()
+			if err != nil {
+				_ = sink.Close()
+				return nil, errors.New("init conds failed: " + err.Error())
+			}
+			policy.conds = conds
+		}
+	}
+
+	return policy, nil
+}
+
+// Config 策略配置
+func (this *Policy) Config() *PolicyConfig {
+	return this.config
+}
+
+// Start 启动写入
+func (this *Policy) Start() {
+	goman.New(func() {
+		this.loop()
+	})
+}
+
+// Push 放入日志，不符合条件的日志会被忽略，缓冲区已满时丢弃日志
+func (this *Policy) Push(accessLogs []*pb.HTTPAccessLog) {
+	for _, accessLog := range accessLogs {
+		if !this.Match(accessLog) {
+			continue
+		}
+		select {
+		case this.queue <- accessLog:
+		default:
+			atomic.AddInt64(&this.countDropped, 1)
+		}
+	}
+}
+
+// Match 检查日志是否符合策略条件
+func (this *Policy) Match(accessLog *pb.HTTPAccessLog) bool {
+	if this.config.FirewallOnly && accessLog.FirewallPolicyId == 0 {
+		return false
+	}
+	if this.conds != nil {
+		var formatter = func(source string) string {
+			return formatAccessLogVariables(accessLog, source)
+		}
+		return this.conds.MatchRequest(formatter) && this.conds.MatchResponse(formatter)
+	}
+	return true
+}
+
+// Stop 停止写入，缓冲区中的日志会在停止前写入
+func (this *Policy) Stop() {
+	this.stopOnce.Do(func() {
+		close(this.stopChan)
+	})
+	<-this.doneChan
+}
+
+// CountDropped 因为缓冲区已满而丢弃的日志数量
+func (this *Policy) CountDropped() int64 {
+	return atomic.LoadInt64(&this.countDropped)
+}
+
+// CountFailed 重试后仍然写入失败的日志数量
+func (this *Policy) CountFailed() int64 {
+	return atomic.LoadInt64(&this.countFailed)
+}
+
+func (this *Policy) loop() {
+	defer close(this.doneChan)
+
+	var ticker = time.NewTicker(this.flushInterval)
+	defer ticker.Stop()
+
+	var batch = make([]*pb.HTTPAccessLog, 0, this.batchSize)
+	for {
+		select {
+		case accessLog := <-this.queue:
+			batch = append(batch, accessLog)
+			if len(batch) >= this.batchSize {
+				this.flush(batch)
+				batch = make([]*pb.HTTPAccessLog, 0, this.batchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				this.flush(batch)
+				batch = make([]*pb.HTTPAccessLog, 0, this.batchSize)
+			}
+		case <-this.stopChan:
+			// 写入剩余的日志
+			for len(this.queue) > 0 {
+				batch = append(batch, <-this.queue)
+				if len(batch) >= this.batchSize {
+					this.flush(batch)
+					batch = make([]*pb.HTTPAccessLog, 0, this.batchSize)
+				}
+			}
+			if len(batch) > 0 {
+				this.flush(batch)
+			}
+			_ = this.sink.Close()
+			return
+		}
+	}
+}
+
+func (this *Policy) flush(batch []*pb.HTTPAccessLog) {
+	err := this.writeWithRetry(batch)
+	if err != nil {
+		atomic.AddInt64(&this.countFailed, int64(len(batch)))
+		remotelogs.Error("ACCESS_LOG_POLICY", "write "+types.String(len(batch))+" access logs to policy '"+types.String(this.config.Id)+"' failed: "+err.Error())
+	}
+}
+
+func (this *Policy) writeWithRetry(batch []*pb.HTTPAccessLog) error {
+	var retryInterval = this.retryInterval
+	for i := 0; ; i++ {
+		err := this.sink.Write(batch)
+		if err == nil || i >= this.maxRetries {
+			return err
+		}
+
+		select {
+		case <-time.After(retryInterval):
+			retryInterval *= 2
+		case <-this.stopChan:
+			// 正在停止，不再等待重试
+			return err
+		}
+	}
+}
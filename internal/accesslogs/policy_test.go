@@ -0,0 +1,171 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"errors"
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testSinkType = "test"
+
+// 用于测试的存储，前面的若干次写入会失败
+type testSink struct {
+	failures    int
+	countWrites int
+	accessLogs  []*pb.HTTPAccessLog
+	isClosed    bool
+
+	locker sync.Mutex
+}
+
+var testSinkInstance *testSink
+
+func init() {
+	RegisterSink(testSinkType, func() SinkInterface {
+		testSinkInstance = &testSink{}
+		return testSinkInstance
+	})
+}
+
+func (this *testSink) Init(optionsJSON []byte) error {
+	return nil
+}
+
+func (this *testSink) Write(accessLogs []*pb.HTTPAccessLog) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	this.countWrites++
+	if this.failures > 0 {
+		this.failures--
+		return errors.New("write failed")
+	}
+	this.accessLogs = append(this.accessLogs, accessLogs...)
+	return nil
+}
+
+func (this *testSink) Close() error {
+	this.locker.Lock()
+	this.isClosed = true
+	this.locker.Unlock()
+	return nil
+}
+
+func testNewPolicy(t *testing.T, config *PolicyConfig) (*Policy, *testSink) {
+	config.Type = testSinkType
+	policy, err := NewPolicy(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy.flushInterval = 10 * time.Millisecond
+	policy.retryInterval = 10 * time.Millisecond
+	return policy, testSinkInstance
+}
+
+func TestPolicy_Retry(t *testing.T) {
+	policy, sink := testNewPolicy(t, &PolicyConfig{Id: 1})
+	sink.failures = 2
+	policy.Start()
+
+	policy.Push([]*pb.HTTPAccessLog{{RequestId: "1"}, {RequestId: "2"}})
+	time.Sleep(200 * time.Millisecond)
+	policy.Stop()
+
+	if len(sink.accessLogs) != 2 {
+		t.Fatal("expect 2 access logs, got", len(sink.accessLogs))
+	}
+	if sink.countWrites != 3 {
+		t.Fatal("expect 3 writes, got", sink.countWrites)
+	}
+	if !sink.isClosed {
+		t.Fatal("sink should be closed after stop")
+	}
+}
+
+func TestPolicy_Retry_Failed(t *testing.T) {
+	policy, sink := testNewPolicy(t, &PolicyConfig{Id: 1})
+	sink.failures = 100
+	policy.Start()
+
+	policy.Push([]*pb.HTTPAccessLog{{RequestId: "1"}})
+	time.Sleep(300 * time.Millisecond)
+	policy.Stop()
+
+	if sink.countWrites != DefaultPolicyMaxRetries+1 {
+		t.Fatal("expect", DefaultPolicyMaxRetries+1, "writes, got", sink.countWrites)
+	}
+	if policy.CountFailed() != 1 {
+		t.Fatal("expect 1 failed access log, got", policy.CountFailed())
+	}
+}
+
+func TestPolicy_Batch(t *testing.T) {
+	policy, sink := testNewPolicy(t, &PolicyConfig{Id: 1})
+	policy.batchSize = 10
+	policy.flushInterval = time.Hour
+	policy.Start()
+
+	var accessLogs = []*pb.HTTPAccessLog{}
+	for i := 0; i < 25; i++ {
+		accessLogs = append(accessLogs, &pb.HTTPAccessLog{})
+	}
+	policy.Push(accessLogs)
+
+	// 未满一批的日志在停止时写入
+	policy.Stop()
+	if sink.countWrites != 3 || len(sink.accessLogs) != 25 {
+		t.Fatal("unexpected writes:", sink.countWrites, len(sink.accessLogs))
+	}
+}
+
+func TestPolicy_Match(t *testing.T) {
+	policy, _ := testNewPolicy(t, &PolicyConfig{
+		Id:           1,
+		FirewallOnly: true,
+		CondsJSON:    []byte(`{"isOn": true, "connector": "or", "groups": [{"isOn": true, "connector": "and", "conds": [{"type": "params", "isRequest": false, "param": "${status}", "operator": "eq", "value": "403"}]}]}`),
+	})
+
+	for _, testCase := range []struct {
+		accessLog *pb.HTTPAccessLog
+		match     bool
+	}{
+		{&pb.HTTPAccessLog{Status: 403, FirewallPolicyId: 1}, true},
+		{&pb.HTTPAccessLog{Status: 200, FirewallPolicyId: 1}, false},
+		{&pb.HTTPAccessLog{Status: 403}, false},
+	} {
+		if policy.Match(testCase.accessLog) != testCase.match {
+			t.Fatal("unexpected match result for status", testCase.accessLog.Status, "firewall policy", testCase.accessLog.FirewallPolicyId)
+		}
+	}
+}
+
+func TestPolicy_Dropped(t *testing.T) {
+	policy, _ := testNewPolicy(t, &PolicyConfig{Id: 1})
+	policy.queue = make(chan *pb.HTTPAccessLog, 2)
+
+	// 没有启动，缓冲区满后丢弃
+	policy.Push([]*pb.HTTPAccessLog{{}, {}, {}})
+	if policy.CountDropped() != 1 {
+		t.Fatal("expect 1 dropped access log, got", policy.CountDropped())
+	}
+}
+
+func TestFormatAccessLogVariables(t *testing.T) {
+	var accessLog = &pb.HTTPAccessLog{
+		Host:        "example.com",
+		Status:      404,
+		QueryString: "a=1&b=2",
+		Header: map[string]*pb.Strings{
+			"User-Agent": {Values: []string{"curl/8.0"}},
+		},
+		Cookie: map[string]string{"sid": "abc"},
+	}
+	var result = formatAccessLogVariables(accessLog, "${host} ${status} ${arg.b} ${header.user-agent} ${cookie.sid} ${unknown}")
+	if result != "example.com 404 2 curl/8.0 abc ${unknown}" {
+		t.Fatal("unexpected result:", result)
+	}
+}
@@ -0,0 +1,434 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 访问日志查询语法：
+//
+//	status:>=500 AND host:*.example.com AND ua:~"curl" AND cost:>2s
+//	(status:404 OR status:5xx) -method:HEAD
+//	ip:192.168.1.0/24 bytes:>1m
+//
+// 每个条件的格式为 字段名:值 或者 字段名:操作符值，字段名和值之间必须使用冒号分隔，
+// 比如 status:5xx、status:!=200、cost:>2s；操作符有 = != > >= < <= ~ !~，省略时表示 =。
+// 类似 status=5xx 这样没有冒号的写法不是字段条件，会被当作关键词查询。
+//
+// 多个条件之间没有连接词时使用AND，NOT或者前缀"-"表示取反，括号用来分组；
+// 没有字段名的词作为关键词在IP、URL、域名和User-Agent中模糊查询。
+
+const (
+	QueryMaxLength = 2048 // 查询语句最大长度
+	QueryMaxTerms  = 64   // 最多的条件数
+	QueryMaxDepth  = 16   // 最大嵌套层级
+)
+
+type QueryNodeType = string
+
+const (
+	QueryNodeTypeAnd  QueryNodeType = "and"
+	QueryNodeTypeOr   QueryNodeType = "or"
+	QueryNodeTypeNot  QueryNodeType = "not"
+	QueryNodeTypeTerm QueryNodeType = "term"
+)
+
+type QueryOperator = string
+
+const (
+	QueryOperatorEq       QueryOperator = "="
+	QueryOperatorNeq      QueryOperator = "!="
+	QueryOperatorGt       QueryOperator = ">"
+	QueryOperatorGte      QueryOperator = ">="
+	QueryOperatorLt       QueryOperator = "<"
+	QueryOperatorLte      QueryOperator = "<="
+	QueryOperatorRegexp   QueryOperator = "~"
+	QueryOperatorNoRegexp QueryOperator = "!~"
+)
+
+// 按匹配顺序排列，长的操作符在前
+var queryOperators = []QueryOperator{
+	QueryOperatorGte,
+	QueryOperatorLte,
+	QueryOperatorNeq,
+	QueryOperatorNoRegexp,
+	QueryOperatorGt,
+	QueryOperatorLt,
+	QueryOperatorEq,
+	QueryOperatorRegexp,
+}
+
+var queryFieldNameReg = regexp.MustCompile(`^[a-zA-Z]+$`)
+
+// QueryTerm 单个查询条件
+type QueryTerm struct {
+	Field    string        // 字段名，为空表示关键词
+	Operator QueryOperator // 操作符
+	Value    string        // 值
+}
+
+// QueryNode 语法树节点
+type QueryNode struct {
+	Type     QueryNodeType
+	Children []*QueryNode // and、or、not的子节点
+	Term     *QueryTerm   // 查询条件
+}
+
+func (this *QueryNode) String() string {
+	switch this.Type {
+	case QueryNodeTypeAnd, QueryNodeTypeOr:
+		var pieces = []string{}
+		for _, child := range this.Children {
+			pieces = append(pieces, child.String())
+		}
+		return "(" + strings.Join(pieces, " "+strings.ToUpper(this.Type)+" ") + ")"
+	case QueryNodeTypeNot:
+		return "NOT " + this.Children[0].String()
+	case QueryNodeTypeTerm:
+		if len(this.Term.Field) == 0 {
+			return strconv.Quote(this.Term.Value)
+		}
+		return this.Term.Field + ":" + this.Term.Operator + strconv.Quote(this.Term.Value)
+	}
+	return ""
+}
+
+// Query 解析后的查询
+type Query struct {
+	Raw  string
+	Root *QueryNode
+}
+
+// ParseQuery 解析查询语句
+func ParseQuery(raw string) (*Query, error) {
+	raw = strings.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil, errors.New("query should not be empty")
+	}
+	if len(raw) > QueryMaxLength {
+		return nil, errors.New("query is too long (max " + strconv.Itoa(QueryMaxLength) + " characters)")
+	}
+
+	tokens, err := lexQuery(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var parser = &queryParser{tokens: tokens}
+	root, err := parser.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if parser.pos < len(parser.tokens) {
+		return nil, errors.New("unexpected '" + parser.tokens[parser.pos].text + "' at position " + strconv.Itoa(parser.tokens[parser.pos].offset))
+	}
+
+	return &Query{
+		Raw:  raw,
+		Root: root,
+	}, nil
+}
+
+// Terms 所有的查询条件
+func (this *Query) Terms() []*QueryTerm {
+	var result = []*QueryTerm{}
+	var walk func(node *QueryNode)
+	walk = func(node *QueryNode) {
+		if node.Term != nil {
+			result = append(result, node.Term)
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(this.Root)
+	return result
+}
+
+func (this *Query) String() string {
+	return this.Root.String()
+}
+
+/** 词法分析 **/
+
+type queryTokenType = int
+
+const (
+	queryTokenLParen queryTokenType = iota + 1
+	queryTokenRParen
+	queryTokenAnd
+	queryTokenOr
+	queryTokenNot
+	queryTokenTerm
+)
+
+type queryToken struct {
+	tokenType queryTokenType
+	text      string
+	offset    int
+	term      *QueryTerm
+}
+
+func lexQuery(raw string) ([]*queryToken, error) {
+	var tokens = []*queryToken{}
+	var runes = []rune(raw)
+	var pos = 0
+	for pos < len(runes) {
+		var r = runes[pos]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			pos++
+		case r == '(':
+			tokens = append(tokens, &queryToken{tokenType: queryTokenLParen, text: "(", offset: pos})
+			pos++
+		case r == ')':
+			tokens = append(tokens, &queryToken{tokenType: queryTokenRParen, text: ")", offset: pos})
+			pos++
+		default:
+			var start = pos
+
+			// 前缀"-"表示取反
+			if r == '-' && pos+1 < len(runes) && runes[pos+1] != ' ' {
+				tokens = append(tokens, &queryToken{tokenType: queryTokenNot, text: "-", offset: pos})
+				pos++
+				continue
+			}
+
+			var word strings.Builder
+			var quoted = false
+			for pos < len(runes) {
+				r = runes[pos]
+				if r == '"' {
+					value, newPos, err := lexQuotedString(runes, pos)
+					if err != nil {
+						return nil, err
+					}
+					word.WriteString(value)
+					pos = newPos
+					quoted = true
+					continue
+				}
+				if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '(' || r == ')' {
+					break
+				}
+				word.WriteRune(r)
+				pos++
+			}
+
+			var text = word.String()
+			if !quoted {
+				switch strings.ToUpper(text) {
+				case "AND", "&&":
+					tokens = append(tokens, &queryToken{tokenType: queryTokenAnd, text: text, offset: start})
+					continue
+				case "OR", "||":
+					tokens = append(tokens, &queryToken{tokenType: queryTokenOr, text: text, offset: start})
+					continue
+				case "NOT":
+					tokens = append(tokens, &queryToken{tokenType: queryTokenNot, text: text, offset: start})
+					continue
+				}
+			}
+
+			term, err := parseQueryTerm(string(runes[start:pos]), text, quoted)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, &queryToken{tokenType: queryTokenTerm, text: text, offset: start, term: term})
+		}
+	}
+	return tokens, nil
+}
+
+// 读取引号中的字符串，支持 \" 和 \\ 转义
+func lexQuotedString(runes []rune, pos int) (value string, newPos int, err error) {
+	var builder strings.Builder
+	for i := pos + 1; i < len(runes); i++ {
+		var r = runes[i]
+		if r == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\') {
+			builder.WriteRune(runes[i+1])
+			i++
+			continue
+		}
+		if r == '"' {
+			return builder.String(), i + 1, nil
+		}
+		builder.WriteRune(r)
+	}
+	return "", 0, errors.New("unterminated quoted string at position " + strconv.Itoa(pos))
+}
+
+// 解析单个条件，source为原始文本，text为去掉引号后的文本
+func parseQueryTerm(source string, text string, quoted bool) (*QueryTerm, error) {
+	// 字段名必须在引号之前
+	var colonIndex = strings.Index(source, ":")
+	var quoteIndex = strings.Index(source, "\"")
+	if colonIndex <= 0 || (quoteIndex >= 0 && quoteIndex < colonIndex) {
+		return &QueryTerm{Value: text}, nil
+	}
+
+	var fieldName = source[:colonIndex]
+	if !queryFieldNameReg.MatchString(fieldName) {
+		return &QueryTerm{Value: text}, nil
+	}
+
+	// URL作为关键词
+	var lowerFieldName = strings.ToLower(fieldName)
+	if lowerFieldName == "http" || lowerFieldName == "https" {
+		return &QueryTerm{Value: text}, nil
+	}
+
+	var field = findQueryField(fieldName)
+	if field == nil {
+		return nil, errors.New("unknown field '" + fieldName + "'")
+	}
+
+	var value = text[len(fieldName)+1:]
+	var operator = QueryOperatorEq
+	for _, op := range queryOperators {
+		if strings.HasPrefix(value, op) {
+			operator = op
+			value = value[len(op):]
+			break
+		}
+	}
+	if len(value) == 0 && !quoted {
+		return nil, errors.New("value of field '" + fieldName + "' should not be empty")
+	}
+
+	var term = &QueryTerm{
+		Field:    field.Name,
+		Operator: operator,
+		Value:    value,
+	}
+	err := field.validate(term)
+	if err != nil {
+		return nil, err
+	}
+	return term, nil
+}
+
+/** 语法分析 **/
+
+type queryParser struct {
+	tokens     []*queryToken
+	pos        int
+	countTerms int
+}
+
+func (this *queryParser) peek() *queryToken {
+	if this.pos < len(this.tokens) {
+		return this.tokens[this.pos]
+	}
+	return nil
+}
+
+// or := and ("OR" and)*
+func (this *queryParser) parseOr(depth int) (*QueryNode, error) {
+	node, err := this.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	var children = []*QueryNode{node}
+	for {
+		var token = this.peek()
+		if token == nil || token.tokenType != queryTokenOr {
+			break
+		}
+		this.pos++
+		node, err = this.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, node)
+	}
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return &QueryNode{Type: QueryNodeTypeOr, Children: children}, nil
+}
+
+// and := not (["AND"] not)*
+func (this *queryParser) parseAnd(depth int) (*QueryNode, error) {
+	node, err := this.parseNot(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	var children = []*QueryNode{node}
+	for {
+		var token = this.peek()
+		if token == nil || token.tokenType == queryTokenOr || token.tokenType == queryTokenRParen {
+			break
+		}
+		if token.tokenType == queryTokenAnd {
+			this.pos++
+		}
+		node, err = this.parseNot(depth)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, node)
+	}
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return &QueryNode{Type: QueryNodeTypeAnd, Children: children}, nil
+}
+
+// not := "NOT" not | primary
+func (this *queryParser) parseNot(depth int) (*QueryNode, error) {
+	var token = this.peek()
+	if token != nil && token.tokenType == queryTokenNot {
+		if depth >= QueryMaxDepth {
+			return nil, errors.New("query is nested too deeply")
+		}
+		this.pos++
+		node, err := this.parseNot(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &QueryNode{Type: QueryNodeTypeNot, Children: []*QueryNode{node}}, nil
+	}
+	return this.parsePrimary(depth)
+}
+
+// primary := "(" or ")" | term
+func (this *queryParser) parsePrimary(depth int) (*QueryNode, error) {
+	var token = this.peek()
+	if token == nil {
+		return nil, errors.New("unexpected end of query")
+	}
+
+	switch token.tokenType {
+	case queryTokenLParen:
+		if depth >= QueryMaxDepth {
+			return nil, errors.New("query is nested too deeply")
+		}
+		this.pos++
+		node, err := this.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		var closeToken = this.peek()
+		if closeToken == nil || closeToken.tokenType != queryTokenRParen {
+			return nil, errors.New("missing ')' for '(' at position " + strconv.Itoa(token.offset))
+		}
+		this.pos++
+		return node, nil
+	case queryTokenTerm:
+		this.countTerms++
+		if this.countTerms > QueryMaxTerms {
+			return nil, errors.New("too many conditions in query (max " + strconv.Itoa(QueryMaxTerms) + ")")
+		}
+		this.pos++
+		return &QueryNode{Type: QueryNodeTypeTerm, Term: token.term}, nil
+	}
+
+	return nil, errors.New("unexpected '" + token.text + "' at position " + strconv.Itoa(token.offset))
+}
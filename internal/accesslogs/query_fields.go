@@ -0,0 +1,228 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type queryFieldKind = int
+
+const (
+	queryFieldKindInt      queryFieldKind = iota + 1 // 整数
+	queryFieldKindString                             // 字符串
+	queryFieldKindIP                                 // IP
+	queryFieldKindDuration                           // 耗时，单位为秒
+	queryFieldKindSize                               // 尺寸，单位为字节
+	queryFieldKindTag                                // 标签
+)
+
+const queryMaxRegexpLength = 256
+
+var queryIntReg = regexp.MustCompile(`^\d{1,19}$`)
+var queryIntRangeReg = regexp.MustCompile(`^(\d{1,19})-(\d{1,19})$`)
+var queryStatusClassReg = regexp.MustCompile(`^([1-5])xx$`)
+var querySizeReg = regexp.MustCompile(`^(?i)(\d+(?:\.\d+)?)\s*([kmgt]?)b?$`)
+
+// 可以查询的字段
+type queryField struct {
+	Name     string         // 字段名
+	Aliases  []string       // 别名
+	Kind     queryFieldKind // 类型
+	Column   string         // 数据表中的字段
+	JSONPath string         // 在content中的路径，用于没有单独字段的数据表
+	Case     int            // 值的大小写转换：1 大写，-1 小写
+	CanGroup bool           // 是否可以用来分组统计
+}
+
+var queryFields = []*queryField{
+	{Name: "status", Kind: queryFieldKindInt, Column: "status", CanGroup: true},
+	{Name: "host", Aliases: []string{"domain"}, Kind: queryFieldKindString, Column: "domain", JSONPath: "$.host", Case: -1, CanGroup: true},
+	{Name: "ip", Aliases: []string{"remoteAddr"}, Kind: queryFieldKindIP, Column: "remoteAddr", JSONPath: "$.remoteAddr", CanGroup: true},
+	{Name: "ua", Aliases: []string{"userAgent"}, Kind: queryFieldKindString, JSONPath: "$.userAgent", CanGroup: true},
+	{Name: "method", Aliases: []string{"requestMethod"}, Kind: queryFieldKindString, JSONPath: "$.requestMethod", Case: 1, CanGroup: true},
+	{Name: "path", Aliases: []string{"requestPath"}, Kind: queryFieldKindString, JSONPath: "$.requestPath", CanGroup: true},
+	{Name: "uri", Aliases: []string{"requestURI", "url"}, Kind: queryFieldKindString, JSONPath: "$.requestURI"},
+	{Name: "referer", Kind: queryFieldKindString, JSONPath: "$.referer", CanGroup: true},
+	{Name: "proto", Kind: queryFieldKindString, JSONPath: "$.proto", Case: 1, CanGroup: true},
+	{Name: "scheme", Kind: queryFieldKindString, JSONPath: "$.scheme", Case: -1, CanGroup: true},
+	{Name: "cost", Aliases: []string{"requestTime"}, Kind: queryFieldKindDuration, JSONPath: "$.requestTime"},
+	{Name: "bytes", Aliases: []string{"bytesSent"}, Kind: queryFieldKindSize, JSONPath: "$.bytesSent"},
+	{Name: "tag", Aliases: []string{"tags"}, Kind: queryFieldKindTag, JSONPath: "$.tags"},
+	{Name: "requestId", Kind: queryFieldKindString, Column: "requestId"},
+	{Name: "serverId", Aliases: []string{"server"}, Kind: queryFieldKindInt, Column: "serverId", CanGroup: true},
+	{Name: "nodeId", Aliases: []string{"node"}, Kind: queryFieldKindInt, Column: "nodeId", CanGroup: true},
+	{Name: "firewallPolicyId", Aliases: []string{"waf"}, Kind: queryFieldKindInt, Column: "firewallPolicyId", CanGroup: true},
+	{Name: "firewallRuleGroupId", Kind: queryFieldKindInt, Column: "firewallRuleGroupId", CanGroup: true},
+	{Name: "firewallRuleSetId", Kind: queryFieldKindInt, Column: "firewallRuleSetId", CanGroup: true},
+	{Name: "firewallRuleId", Kind: queryFieldKindInt, Column: "firewallRuleId", CanGroup: true},
+}
+
+// 根据名称或者别名查找字段，不区分大小写
+func findQueryField(name string) *queryField {
+	for _, field := range queryFields {
+		if strings.EqualFold(field.Name, name) {
+			return field
+		}
+		for _, alias := range field.Aliases {
+			if strings.EqualFold(alias, name) {
+				return field
+			}
+		}
+	}
+	return nil
+}
+
+// FindAllQueryGroupFields 所有可以用来分组统计的字段
+func FindAllQueryGroupFields() []string {
+	var result = []string{}
+	for _, field := range queryFields {
+		if field.CanGroup {
+			result = append(result, field.Name)
+		}
+	}
+	return result
+}
+
+// 检查操作符和值是否适用于当前字段，并规范化值
+func (this *queryField) validate(term *QueryTerm) error {
+	var invalidOperatorErr = errors.New("operator '" + term.Operator + "' can not be used with field '" + this.Name + "'")
+
+	switch this.Case {
+	case 1:
+		term.Value = strings.ToUpper(term.Value)
+	case -1:
+		term.Value = strings.ToLower(term.Value)
+	}
+
+	switch this.Kind {
+	case queryFieldKindInt:
+		switch term.Operator {
+		case QueryOperatorEq, QueryOperatorNeq:
+			if queryIntRangeReg.MatchString(term.Value) || (this.Name == "status" && queryStatusClassReg.MatchString(term.Value)) {
+				return nil
+			}
+		case QueryOperatorGt, QueryOperatorGte, QueryOperatorLt, QueryOperatorLte:
+		default:
+			return invalidOperatorErr
+		}
+		if !queryIntReg.MatchString(term.Value) {
+			return errors.New("invalid integer '" + term.Value + "' for field '" + this.Name + "'")
+		}
+	case queryFieldKindDuration, queryFieldKindSize:
+		switch term.Operator {
+		case QueryOperatorEq, QueryOperatorNeq, QueryOperatorGt, QueryOperatorGte, QueryOperatorLt, QueryOperatorLte:
+		default:
+			return invalidOperatorErr
+		}
+		var err error
+		if this.Kind == queryFieldKindDuration {
+			_, err = parseQueryDuration(term.Value)
+		} else {
+			_, err = parseQuerySize(term.Value)
+		}
+		if err != nil {
+			return errors.New("invalid value '" + term.Value + "' for field '" + this.Name + "'")
+		}
+	case queryFieldKindString:
+		switch term.Operator {
+		case QueryOperatorEq, QueryOperatorNeq:
+		case QueryOperatorRegexp, QueryOperatorNoRegexp:
+			if len(term.Value) > queryMaxRegexpLength {
+				return errors.New("regular expression for field '" + this.Name + "' is too long")
+			}
+			_, err := regexp.Compile(term.Value)
+			if err != nil {
+				return errors.New("invalid regular expression for field '" + this.Name + "': " + err.Error())
+			}
+		default:
+			return invalidOperatorErr
+		}
+	case queryFieldKindIP:
+		if term.Operator != QueryOperatorEq && term.Operator != QueryOperatorNeq {
+			return invalidOperatorErr
+		}
+		_, _, err := parseQueryIPRange(term.Value)
+		if err != nil {
+			return err
+		}
+	case queryFieldKindTag:
+		if term.Operator != QueryOperatorEq && term.Operator != QueryOperatorNeq {
+			return invalidOperatorErr
+		}
+	}
+	return nil
+}
+
+// 解析耗时，比如 2s、500ms，没有单位时使用秒
+func parseQueryDuration(value string) (seconds float64, err error) {
+	seconds, err = strconv.ParseFloat(value, 64)
+	if err == nil {
+		return
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	return duration.Seconds(), nil
+}
+
+// 解析尺寸，比如 512、10k、1.5m、2GB
+func parseQuerySize(value string) (int64, error) {
+	var matches = querySizeReg.FindStringSubmatch(value)
+	if len(matches) == 0 {
+		return 0, errors.New("invalid size '" + value + "'")
+	}
+	size, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0, err
+	}
+	switch strings.ToLower(matches[2]) {
+	case "k":
+		size *= 1 << 10
+	case "m":
+		size *= 1 << 20
+	case "g":
+		size *= 1 << 30
+	case "t":
+		size *= 1 << 40
+	}
+	return int64(size), nil
+}
+
+// 解析IP、CIDR或者IP范围（ip1-ip2），单个IP时ipFrom和ipTo相同
+func parseQueryIPRange(value string) (ipFrom net.IP, ipTo net.IP, err error) {
+	value = strings.Trim(value, "[]")
+
+	if strings.Contains(value, "/") {
+		_, ipNet, cidrErr := net.ParseCIDR(value)
+		if cidrErr != nil {
+			return nil, nil, errors.New("invalid CIDR '" + value + "'")
+		}
+		ipFrom = ipNet.IP
+		ipTo = make(net.IP, len(ipNet.IP))
+		for i := range ipNet.IP {
+			ipTo[i] = ipNet.IP[i] | ^ipNet.Mask[i]
+		}
+		return ipFrom, ipTo, nil
+	}
+
+	var pieces = strings.SplitN(value, "-", 2)
+	ipFrom = net.ParseIP(strings.TrimSpace(pieces[0]))
+	if ipFrom == nil {
+		return nil, nil, errors.New("invalid ip '" + value + "'")
+	}
+	if len(pieces) == 1 {
+		return ipFrom, ipFrom, nil
+	}
+	ipTo = net.ParseIP(strings.TrimSpace(pieces[1]))
+	if ipTo == nil || (ipFrom.To4() == nil) != (ipTo.To4() == nil) {
+		return nil, nil, errors.New("invalid ip range '" + value + "'")
+	}
+	return ipFrom, ipTo, nil
+}
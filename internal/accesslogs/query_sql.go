@@ -0,0 +1,255 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	dbutils "github.com/dashenmiren/EdgeAPI/internal/db/utils"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/iwind/TeaGo/types"
+	"golang.org/x/net/idna"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var queryASCIIDomainReg = regexp.MustCompile(`^[a-z0-9-.*]+$`)
+
+// QuerySQLOptions 生成SQL时需要的数据表信息
+type QuerySQLOptions struct {
+	HasRemoteAddrField bool   // 是否有remoteAddr字段
+	HasDomainField     bool   // 是否有domain字段
+	ParamPrefix        string // 参数名前缀，用来避免和其他条件中的参数冲突
+}
+
+// AsSQL 转换为SQL中的WHERE条件
+// 字段名和操作符均来自白名单，所有的值都作为参数传递
+func (this *Query) AsSQL(options *QuerySQLOptions) (where string, params map[string]any, err error) {
+	var compilerOptions = &QuerySQLOptions{}
+	if options != nil {
+		*compilerOptions = *options
+	}
+	if len(compilerOptions.ParamPrefix) == 0 {
+		compilerOptions.ParamPrefix = "q"
+	}
+	var compiler = &querySQLCompiler{
+		options: compilerOptions,
+		params:  map[string]any{},
+	}
+	where, err = compiler.compile(this.Root)
+	if err != nil {
+		return "", nil, err
+	}
+	return where, compiler.params, nil
+}
+
+// QueryGroupFieldSQL 分组统计字段对应的SQL表达式
+func QueryGroupFieldSQL(fieldName string, options *QuerySQLOptions) (string, error) {
+	var field = findQueryField(fieldName)
+	if field == nil || !field.CanGroup {
+		return "", errors.New("can not group by field '" + fieldName + "'")
+	}
+	if options == nil {
+		options = &QuerySQLOptions{}
+	}
+	return queryFieldSQL(field, options), nil
+}
+
+// 字段对应的SQL表达式
+func queryFieldSQL(field *queryField, options *QuerySQLOptions) string {
+	if len(field.Column) > 0 {
+		switch field.Column {
+		case "remoteAddr":
+			if options.HasRemoteAddrField {
+				return "`remoteAddr`"
+			}
+		case "domain":
+			if options.HasDomainField {
+				return "`domain`"
+			}
+		default:
+			return "`" + field.Column + "`"
+		}
+	}
+
+	switch field.Kind {
+	case queryFieldKindDuration, queryFieldKindSize:
+		return "JSON_EXTRACT(content, '" + field.JSONPath + "')"
+	}
+	return "JSON_UNQUOTE(JSON_EXTRACT(content, '" + field.JSONPath + "'))"
+}
+
+type querySQLCompiler struct {
+	options    *QuerySQLOptions
+	params     map[string]any
+	paramIndex int
+}
+
+func (this *querySQLCompiler) param(value any) string {
+	var name = this.options.ParamPrefix + strconv.Itoa(this.paramIndex)
+	this.paramIndex++
+	this.params[name] = value
+	return ":" + name
+}
+
+func (this *querySQLCompiler) compile(node *QueryNode) (string, error) {
+	switch node.Type {
+	case QueryNodeTypeAnd, QueryNodeTypeOr:
+		var pieces = []string{}
+		for _, child := range node.Children {
+			piece, err := this.compile(child)
+			if err != nil {
+				return "", err
+			}
+			pieces = append(pieces, piece)
+		}
+		return "(" + strings.Join(pieces, " "+strings.ToUpper(node.Type)+" ") + ")", nil
+	case QueryNodeTypeNot:
+		piece, err := this.compile(node.Children[0])
+		if err != nil {
+			return "", err
+		}
+		return "NOT (" + piece + ")", nil
+	case QueryNodeTypeTerm:
+		return this.compileTerm(node.Term)
+	}
+	return "", errors.New("invalid query node type '" + node.Type + "'")
+}
+
+func (this *querySQLCompiler) compileTerm(term *QueryTerm) (string, error) {
+	// 关键词
+	if len(term.Field) == 0 {
+		var param = this.param(dbutils.QuoteLike(term.Value))
+		return "(JSON_EXTRACT(content, '$.remoteAddr') LIKE " + param +
+			" OR JSON_EXTRACT(content, '$.requestURI') LIKE " + param +
+			" OR JSON_EXTRACT(content, '$.host') LIKE " + param +
+			" OR JSON_EXTRACT(content, '$.userAgent') LIKE " + param + ")", nil
+	}
+
+	var field = findQueryField(term.Field)
+	if field == nil {
+		return "", errors.New("unknown field '" + term.Field + "'")
+	}
+	var expr = queryFieldSQL(field, this.options)
+	var isNot = term.Operator == QueryOperatorNeq || term.Operator == QueryOperatorNoRegexp
+
+	var sql string
+	switch field.Kind {
+	case queryFieldKindInt:
+		if queryStatusClassReg.MatchString(term.Value) {
+			var class = types.Int(term.Value[:1])
+			sql = expr + " BETWEEN " + this.param(class*100) + " AND " + this.param(class*100+99)
+		} else if queryIntRangeReg.MatchString(term.Value) {
+			var matches = queryIntRangeReg.FindStringSubmatch(term.Value)
+			var from, to = types.Int64(matches[1]), types.Int64(matches[2])
+			if from > to {
+				from, to = to, from
+			}
+			sql = expr + " BETWEEN " + this.param(from) + " AND " + this.param(to)
+		} else {
+			return this.compareSQL(expr, term.Operator, types.Int64(term.Value))
+		}
+	case queryFieldKindDuration:
+		seconds, err := parseQueryDuration(term.Value)
+		if err != nil {
+			return "", err
+		}
+		return this.compareSQL(expr, term.Operator, seconds)
+	case queryFieldKindSize:
+		size, err := parseQuerySize(term.Value)
+		if err != nil {
+			return "", err
+		}
+		return this.compareSQL(expr, term.Operator, size)
+	case queryFieldKindString:
+		var value = term.Value
+		if field.Name == "host" {
+			value = this.asciiDomain(value)
+		}
+		switch term.Operator {
+		case QueryOperatorRegexp, QueryOperatorNoRegexp:
+			sql = expr + " REGEXP " + this.param(value)
+		default:
+			if strings.Contains(value, "*") {
+				var pieces = strings.Split(value, "*")
+				for index, piece := range pieces {
+					pieces[index] = dbutils.QuoteLikeKeyword(piece)
+				}
+				sql = expr + " LIKE " + this.param(strings.Join(pieces, "%"))
+			} else {
+				sql = expr + "=" + this.param(value)
+			}
+		}
+	case queryFieldKindIP:
+		ipFrom, ipTo, err := parseQueryIPRange(term.Value)
+		if err != nil {
+			return "", err
+		}
+		if ipFrom.Equal(ipTo) {
+			sql = expr + "=" + this.param(ipFrom.String())
+		} else if ipFrom.To4() != nil {
+			var from, to = binary.BigEndian.Uint32(ipFrom.To4()), binary.BigEndian.Uint32(ipTo.To4())
+			if from > to {
+				from, to = to, from
+			}
+			sql = "INET_ATON(" + expr + ") BETWEEN " + this.param(from) + " AND " + this.param(to)
+		} else {
+			var from, to = strings.ToUpper(hex.EncodeToString(ipFrom.To16())), strings.ToUpper(hex.EncodeToString(ipTo.To16()))
+			if from > to {
+				from, to = to, from
+			}
+			sql = "HEX(INET6_ATON(" + expr + ")) BETWEEN " + this.param(from) + " AND " + this.param(to)
+		}
+	case queryFieldKindTag:
+		tagJSON, err := json.Marshal(term.Value)
+		if err != nil {
+			return "", err
+		}
+		sql = "JSON_CONTAINS(content, " + this.param(string(tagJSON)) + ", '" + field.JSONPath + "')"
+	default:
+		return "", errors.New("invalid field '" + term.Field + "'")
+	}
+
+	if isNot {
+		return "NOT (" + sql + ")", nil
+	}
+	return sql, nil
+}
+
+func (this *querySQLCompiler) compareSQL(expr string, operator QueryOperator, value any) (string, error) {
+	switch operator {
+	case QueryOperatorEq, QueryOperatorNeq, QueryOperatorGt, QueryOperatorGte, QueryOperatorLt, QueryOperatorLte:
+		return expr + operator + this.param(value), nil
+	}
+	return "", errors.New("invalid operator '" + operator + "'")
+}
+
+// 中文域名转换为ASCII格式
+func (this *querySQLCompiler) asciiDomain(domain string) string {
+	if queryASCIIDomainReg.MatchString(domain) {
+		return domain
+	}
+	var pieces = strings.Split(domain, "*")
+	for index, piece := range pieces {
+		if len(piece) == 0 {
+			continue
+		}
+		asciiPiece, err := idna.ToASCII(strings.Trim(piece, "."))
+		if err != nil || len(asciiPiece) == 0 {
+			continue
+		}
+		var buf = &bytes.Buffer{}
+		if strings.HasPrefix(piece, ".") {
+			buf.WriteByte('.')
+		}
+		buf.WriteString(asciiPiece)
+		if strings.HasSuffix(piece, ".") {
+			buf.WriteByte('.')
+		}
+		pieces[index] = buf.String()
+	}
+	return strings.Join(pieces, "*")
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	for _, testCase := range []struct {
+		raw    string
+		result string
+	}{
+		{`status:>=500 AND host:*.example.com AND ua:~"curl" AND cost:>2s`, `(status:>="500" AND host:="*.example.com" AND ua:~"curl" AND cost:>"2s")`},
+		{`status:404 OR status:5xx`, `(status:="404" OR status:="5xx")`},
+		{`status:!=200`, `status:!="200"`},
+		{`status=5xx`, `"status=5xx"`},
+		{`(status:404 OR status:5xx) -method:head`, `((status:="404" OR status:="5xx") AND NOT method:="HEAD")`},
+		{`NOT ip:192.168.1.0/24 bytes:>1m`, `(NOT ip:="192.168.1.0/24" AND bytes:>"1m")`},
+		{`userAgent:"Mozilla/5.0 (Windows)"`, `ua:="Mozilla/5.0 (Windows)"`},
+		{`"hello world" || admin`, `("hello world" OR "admin")`},
+		{`https://example.com/login`, `"https://example.com/login"`},
+		{`tag:"bot \"x\""`, `tag:="bot \"x\""`},
+	} {
+		query, err := ParseQuery(testCase.raw)
+		if err != nil {
+			t.Fatal(testCase.raw, err)
+		}
+		if query.String() != testCase.result {
+			t.Fatal("parse '"+testCase.raw+"' failed, got:", query.String())
+		}
+	}
+}
+
+func TestParseQuery_Invalid(t *testing.T) {
+	for _, raw := range []string{
+		``,
+		`foo:bar`,
+		`status:abc`,
+		`status:~500`,
+		`host:>example.com`,
+		`ua:~"[a-"`,
+		`ip:1.2.3`,
+		`ip:1.2.3.4-::1`,
+		`cost:>fast`,
+		`(status:200`,
+		`status:200)`,
+		`status:200 AND`,
+		`ua:"curl`,
+		`status:`,
+	} {
+		_, err := ParseQuery(raw)
+		if err == nil {
+			t.Fatal("query '" + raw + "' should be invalid")
+		}
+		t.Log(raw, "=>", err)
+	}
+}
+
+func TestParseQuery_Limits(t *testing.T) {
+	var raw = ""
+	for i := 0; i <= QueryMaxTerms; i++ {
+		raw += "status:200 "
+	}
+	_, err := ParseQuery(raw)
+	if err == nil {
+		t.Fatal("too many terms should be invalid")
+	}
+
+	raw = ""
+	for i := 0; i <= QueryMaxDepth; i++ {
+		raw = "(" + raw + "status:200)"
+	}
+	_, err = ParseQuery(raw)
+	if err == nil {
+		t.Fatal("deep nesting should be invalid")
+	}
+}
+
+func TestQuery_AsSQL(t *testing.T) {
+	query, err := ParseQuery(`status:>=500 AND host:*.example.com AND ua:~"curl" AND cost:>2s`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	where, params, err := query.AsSQL(&QuerySQLOptions{HasDomainField: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if where != "(`status`>=:q0 AND `domain` LIKE :q1 AND JSON_UNQUOTE(JSON_EXTRACT(content, '$.userAgent')) REGEXP :q2 AND JSON_EXTRACT(content, '$.requestTime')>:q3)" {
+		t.Fatal("unexpected where:", where)
+	}
+	if params["q0"] != int64(500) || params["q1"] != "%.example.com" || params["q2"] != "curl" || params["q3"] != float64(2) {
+		t.Fatal("unexpected params:", params)
+	}
+
+	// 没有domain字段
+	where, _, err = query.AsSQL(&QuerySQLOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if where != "(`status`>=:q0 AND JSON_UNQUOTE(JSON_EXTRACT(content, '$.host')) LIKE :q1 AND JSON_UNQUOTE(JSON_EXTRACT(content, '$.userAgent')) REGEXP :q2 AND JSON_EXTRACT(content, '$.requestTime')>:q3)" {
+		t.Fatal("unexpected where:", where)
+	}
+}
+
+func TestQuery_AsSQL_Terms(t *testing.T) {
+	for _, testCase := range []struct {
+		raw    string
+		where  string
+		params map[string]any
+	}{
+		{`status:5xx`, "`status` BETWEEN :q0 AND :q1", map[string]any{"q0": 500, "q1": 599}},
+		{`status:!=400-404`, "NOT (`status` BETWEEN :q0 AND :q1)", map[string]any{"q0": int64(400), "q1": int64(404)}},
+		{`ip:192.168.1.0/24`, "INET_ATON(`remoteAddr`) BETWEEN :q0 AND :q1", map[string]any{"q0": uint32(3232235776), "q1": uint32(3232236031)}},
+		{`ip:!=[::1]`, "NOT (`remoteAddr`=:q0)", map[string]any{"q0": "::1"}},
+		{`ip:2001:db8::/127`, "HEX(INET6_ATON(`remoteAddr`)) BETWEEN :q0 AND :q1", map[string]any{"q0": "20010DB8000000000000000000000000", "q1": "20010DB8000000000000000000000001"}},
+		{`bytes:>=1.5k`, "JSON_EXTRACT(content, '$.bytesSent')>=:q0", map[string]any{"q0": int64(1536)}},
+		{`cost:<500ms`, "JSON_EXTRACT(content, '$.requestTime')<:q0", map[string]any{"q0": 0.5}},
+		{`path:"/a_b%"`, "JSON_UNQUOTE(JSON_EXTRACT(content, '$.requestPath'))=:q0", map[string]any{"q0": "/a_b%"}},
+		{`path:/static/*_x`, "JSON_UNQUOTE(JSON_EXTRACT(content, '$.requestPath')) LIKE :q0", map[string]any{"q0": `/static/%\_x`}},
+		{`tag:bot`, "JSON_CONTAINS(content, :q0, '$.tags')", map[string]any{"q0": `"bot"`}},
+		{`host:中文.com`, "JSON_UNQUOTE(JSON_EXTRACT(content, '$.host'))=:q0", map[string]any{"q0": "xn--fiq228c.com"}},
+		{`admin' OR 1=1`, "((JSON_EXTRACT(content, '$.remoteAddr') LIKE :q0 OR JSON_EXTRACT(content, '$.requestURI') LIKE :q0 OR JSON_EXTRACT(content, '$.host') LIKE :q0 OR JSON_EXTRACT(content, '$.userAgent') LIKE :q0) OR (JSON_EXTRACT(content, '$.remoteAddr') LIKE :q1 OR JSON_EXTRACT(content, '$.requestURI') LIKE :q1 OR JSON_EXTRACT(content, '$.host') LIKE :q1 OR JSON_EXTRACT(content, '$.userAgent') LIKE :q1))", map[string]any{"q0": "%admin'%", "q1": "%1=1%"}},
+	} {
+		query, err := ParseQuery(testCase.raw)
+		if err != nil {
+			t.Fatal(testCase.raw, err)
+		}
+		where, params, err := query.AsSQL(&QuerySQLOptions{HasRemoteAddrField: true})
+		if err != nil {
+			t.Fatal(testCase.raw, err)
+		}
+		if where != testCase.where {
+			t.Fatal("unexpected where for '"+testCase.raw+"':", where)
+		}
+		if len(params) != len(testCase.params) {
+			t.Fatal("unexpected params for '"+testCase.raw+"':", params)
+		}
+		for name, value := range testCase.params {
+			if params[name] != value {
+				t.Fatal("unexpected param '"+name+"' for '"+testCase.raw+"':", params[name])
+			}
+		}
+	}
+}
+
+func TestQueryGroupFieldSQL(t *testing.T) {
+	expr, err := QueryGroupFieldSQL("ip", &QuerySQLOptions{HasRemoteAddrField: true})
+	if err != nil || expr != "`remoteAddr`" {
+		t.Fatal("unexpected group expr:", expr, err)
+	}
+	expr, err = QueryGroupFieldSQL("Host", nil)
+	if err != nil || expr != "JSON_UNQUOTE(JSON_EXTRACT(content, '$.host'))" {
+		t.Fatal("unexpected group expr:", expr, err)
+	}
+	_, err = QueryGroupFieldSQL("cost", nil)
+	if err == nil {
+		t.Fatal("should not group by 'cost'")
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"github.com/iwind/TeaGo/maps"
+)
+
+type SinkType = string
+
+// 存储类型代号，和HTTPAccessLogPolicy.type对应
+const (
+	SinkTypeFile       SinkType = "file"       // 文件
+	SinkTypeSyslog     SinkType = "syslog"     // Syslog
+	SinkTypeES         SinkType = "es"         // Elasticsearch
+	SinkTypeKafka      SinkType = "kafka"      // Kafka
+	SinkTypeClickHouse SinkType = "clickhouse" // ClickHouse
+)
+
+// SinkInterface 访问日志存储接口
+type SinkInterface interface {
+	// Init 使用策略中的存储选项初始化
+	Init(optionsJSON []byte) error
+
+	// Write 写入一批访问日志
+	Write(accessLogs []*pb.HTTPAccessLog) error
+
+	// Close 关闭存储，释放连接和文件句柄
+	Close() error
+}
+
+// 存储类型注册表
+var sinkFactories = map[SinkType]func() SinkInterface{}
+
+// RegisterSink 注册存储类型
+func RegisterSink(sinkType SinkType, factory func() SinkInterface) {
+	sinkFactories[sinkType] = factory
+}
+
+func init() {
+	RegisterSink(SinkTypeFile, func() SinkInterface {
+		return NewFileSink()
+	})
+	RegisterSink(SinkTypeSyslog, func() SinkInterface {
+		return NewSyslogSink()
+	})
+	RegisterSink(SinkTypeES, func() SinkInterface {
+		return NewESSink()
+	})
+	RegisterSink(SinkTypeKafka, func() SinkInterface {
+		return NewKafkaSink()
+	})
+	RegisterSink(SinkTypeClickHouse, func() SinkInterface {
+		return NewClickHouseSink()
+	})
+}
+
+// NewSink 根据类型创建存储，类型不存在时返回nil
+func NewSink(sinkType SinkType) SinkInterface {
+	factory, ok := sinkFactories[sinkType]
+	if !ok {
+		return nil
+	}
+	return factory()
+}
+
+// FindAllSinkTypes 所有的存储类型
+func FindAllSinkTypes() []maps.Map {
+	return []maps.Map{
+		{
+			"name":        "文件",
+			"code":        SinkTypeFile,
+			"description": "将访问日志以JSON格式逐行写入本地文件，支持按文件尺寸自动轮转。",
+		},
+		{
+			"name":        "Syslog",
+			"code":        SinkTypeSyslog,
+			"description": "以RFC 5424格式通过UDP、TCP或TLS发送到Syslog服务器。",
+		},
+		{
+			"name":        "Elasticsearch",
+			"code":        SinkTypeES,
+			"description": "通过Bulk API批量写入Elasticsearch或兼容的服务（比如OpenSearch）。",
+		},
+		{
+			"name":        "Kafka",
+			"code":        SinkTypeKafka,
+			"description": "使用Kafka协议直接写入Kafka主题，支持SASL/PLAIN认证和TLS。",
+		},
+		{
+			"name":        "ClickHouse",
+			"code":        SinkTypeClickHouse,
+			"description": "使用ClickHouse原生协议批量插入数据表，支持LZ4/ZSTD压缩和TLS。",
+		},
+	}
+}
+
+// IsValidSinkType 检查存储类型是否有效
+func IsValidSinkType(sinkType SinkType) bool {
+	_, ok := sinkFactories[sinkType]
+	return ok
+}
@@ -0,0 +1,307 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultClickHouseTimeout = 30 // 默认超时时间（秒）
+)
+
+var clickHouseNameReg = regexp.MustCompile(`^\w+$`)
+
+// ClickHouse中可以写入的字段，和 ClickHouseSink.row() 中的字段一致
+var clickHouseColumnNames = []string{
+	"timestamp",
+	"requestId",
+	"serverId",
+	"nodeId",
+	"host",
+	"remoteAddr",
+	"requestMethod",
+	"requestURI",
+	"requestPath",
+	"scheme",
+	"proto",
+	"status",
+	"bytesSent",
+	"requestTime",
+	"userAgent",
+	"referer",
+	"firewallPolicyId",
+	"firewallRuleGroupId",
+	"firewallRuleSetId",
+	"firewallRuleId",
+	"content",
+}
+
+// ClickHouseSinkOptions ClickHouse存储选项
+type ClickHouseSinkOptions struct {
+	Addrs                 []string `json:"addrs"`                 // 服务器地址列表，格式为 host:port，使用原生协议端口（默认为9000，TLS为9440）
+	Database              string   `json:"database"`              // 数据库名，默认为default
+	Table                 string   `json:"table"`                 // 数据表名
+	Username              string   `json:"username"`              // 用户名，为空表示使用default用户
+	Password              string   `json:"password"`              // 密码
+	Compression           string   `json:"compression"`           // 压缩算法：lz4、zstd，为空表示不压缩
+	Timeout               int      `json:"timeout"`               // 连接和写入超时时间（秒）
+	TLS                   bool     `json:"tls"`                   // 是否使用TLS连接
+	TLSCACert             string   `json:"tlsCACert"`             // 用于校验服务器证书的CA证书（PEM）
+	TLSInsecureSkipVerify bool     `json:"tlsInsecureSkipVerify"` // 是否跳过证书校验
+}
+
+// ClickHouseSink 使用ClickHouse原生协议批量插入数据表
+//
+// 只写入数据表中存在的字段，其余字段使用数据表中定义的默认值，所以可以只创建需要的字段，比如：
+//
+//	CREATE TABLE edge_access_logs (
+//		timestamp DateTime,
+//		requestId String,
+//		serverId UInt64,
+//		nodeId UInt64,
+//		host String,
+//		remoteAddr String,
+//		requestMethod String,
+//		requestURI String,
+//		status UInt16,
+//		bytesSent UInt64,
+//		requestTime Float64,
+//		userAgent String,
+//		firewallPolicyId UInt64,
+//		content String
+//	) ENGINE = MergeTree ORDER BY (serverId, timestamp)
+type ClickHouseSink struct {
+	options *ClickHouseSinkOptions
+	conn    driver.Conn
+
+	columns []string // 需要写入的字段，第一次写入时从数据表结构中读取
+}
+
+func NewClickHouseSink() *ClickHouseSink {
+	return &ClickHouseSink{}
+}
+
+// Init 初始化
+func (this *ClickHouseSink) Init(optionsJSON []byte) error {
+	var options = &ClickHouseSinkOptions{
+		Timeout: DefaultClickHouseTimeout,
+	}
+	err := json.Unmarshal(optionsJSON, options)
+	if err != nil {
+		return errors.New("decode options failed: " + err.Error())
+	}
+
+	if len(options.Addrs) == 0 {
+		return errors.New("'addrs' should not be empty")
+	}
+	for _, addr := range options.Addrs {
+		_, _, err = net.SplitHostPort(addr)
+		if err != nil {
+			return errors.New("invalid addr '" + addr + "': " + err.Error())
+		}
+	}
+	if len(options.Database) == 0 {
+		options.Database = "default"
+	}
+	if !clickHouseNameReg.MatchString(options.Database) {
+		return errors.New("invalid database '" + options.Database + "'")
+	}
+	if !clickHouseNameReg.MatchString(options.Table) {
+		return errors.New("invalid table '" + options.Table + "'")
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultClickHouseTimeout
+	}
+
+	var compression *clickhouse.Compression
+	switch options.Compression {
+	case "":
+	case "lz4":
+		compression = &clickhouse.Compression{Method: clickhouse.CompressionLZ4}
+	case "zstd":
+		compression = &clickhouse.Compression{Method: clickhouse.CompressionZSTD}
+	default:
+		return errors.New("invalid compression '" + options.Compression + "'")
+	}
+
+	var tlsConfig *tls.Config
+	if options.TLS {
+		tlsConfig = &tls.Config{
+			InsecureSkipVerify: options.TLSInsecureSkipVerify,
+		}
+		if len(options.TLSCACert) > 0 {
+			var pool = x509.NewCertPool()
+			if !pool.AppendCertsFromPEM([]byte(options.TLSCACert)) {
+				return errors.New("invalid 'tlsCACert'")
+			}
+			tlsConfig.RootCAs = pool
+		}
+	}
+
+	var timeout = time.Duration(options.Timeout) * time.Second
+	conn, err := clickhouse.Open(&clickhouse.Options{
+		Protocol: clickhouse.Native,
+		Addr:     options.Addrs,
+		Auth: clickhouse.Auth{
+			Database: options.Database,
+			Username: options.Username,
+			Password: options.Password,
+		},
+		ClientInfo: clickhouse.ClientInfo{
+			Products: []struct {
+				Name    string
+				Version string
+			}{
+				{Name: "edge-api"},
+			},
+		},
+		TLS:         tlsConfig,
+		Compression: compression,
+		DialTimeout: timeout,
+		ReadTimeout: timeout,
+	})
+	if err != nil {
+		return errors.New("open connection failed: " + err.Error())
+	}
+
+	this.options = options
+	this.conn = conn
+	return nil
+}
+
+// Write 写入访问日志
+func (this *ClickHouseSink) Write(accessLogs []*pb.HTTPAccessLog) error {
+	if len(accessLogs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(this.options.Timeout)*time.Second)
+	defer cancel()
+
+	columns, err := this.findColumns(ctx)
+	if err != nil {
+		return err
+	}
+
+	var quotedColumns = []string{}
+	for _, column := range columns {
+		quotedColumns = append(quotedColumns, "`"+column+"`")
+	}
+	batch, err := this.conn.PrepareBatch(ctx, "INSERT INTO "+this.tableName()+" ("+strings.Join(quotedColumns, ", ")+")")
+	if err != nil {
+		// 数据表结构可能已经改变
+		this.columns = nil
+		return err
+	}
+
+	for _, accessLog := range accessLogs {
+		row, err := this.row(accessLog)
+		if err != nil {
+			_ = batch.Abort()
+			return err
+		}
+
+		var values = make([]any, 0, len(columns))
+		for _, column := range columns {
+			values = append(values, row[column])
+		}
+		err = batch.Append(values...)
+		if err != nil {
+			_ = batch.Abort()
+			this.columns = nil
+			return err
+		}
+	}
+
+	err = batch.Send()
+	if err != nil {
+		this.columns = nil
+		return err
+	}
+	return nil
+}
+
+// Close 关闭
+func (this *ClickHouseSink) Close() error {
+	if this.conn != nil {
+		return this.conn.Close()
+	}
+	return nil
+}
+
+// 查找数据表中可以写入的字段
+// 以不带字段列表的INSERT获取数据表结构，不发送任何数据
+func (this *ClickHouseSink) findColumns(ctx context.Context) ([]string, error) {
+	if len(this.columns) > 0 {
+		return this.columns, nil
+	}
+
+	batch, err := this.conn.PrepareBatch(ctx, "INSERT INTO "+this.tableName())
+	if err != nil {
+		return nil, err
+	}
+	var tableColumns = map[string]bool{}
+	for _, column := range batch.Columns() {
+		tableColumns[column.Name()] = true
+	}
+	_ = batch.Abort()
+
+	var columns = []string{}
+	for _, column := range clickHouseColumnNames {
+		if tableColumns[column] {
+			columns = append(columns, column)
+		}
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("table " + this.tableName() + " has no access log columns, available columns: " + strings.Join(clickHouseColumnNames, ", "))
+	}
+	this.columns = columns
+	return columns, nil
+}
+
+func (this *ClickHouseSink) tableName() string {
+	return "`" + this.options.Database + "`.`" + this.options.Table + "`"
+}
+
+// 将访问日志展开为一行，完整的日志放在content字段中
+func (this *ClickHouseSink) row(accessLog *pb.HTTPAccessLog) (map[string]any, error) {
+	content, err := json.Marshal(accessLog)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"timestamp":           accessLogTime(accessLog),
+		"requestId":           accessLog.RequestId,
+		"serverId":            accessLog.ServerId,
+		"nodeId":              accessLog.NodeId,
+		"host":                accessLog.Host,
+		"remoteAddr":          accessLog.RemoteAddr,
+		"requestMethod":       accessLog.RequestMethod,
+		"requestURI":          accessLog.RequestURI,
+		"requestPath":         accessLog.RequestPath,
+		"scheme":              accessLog.Scheme,
+		"proto":               accessLog.Proto,
+		"status":              accessLog.Status,
+		"bytesSent":           accessLog.BytesSent,
+		"requestTime":         accessLog.RequestTime,
+		"userAgent":           accessLog.UserAgent,
+		"referer":             accessLog.Referer,
+		"firewallPolicyId":    accessLog.FirewallPolicyId,
+		"firewallRuleGroupId": accessLog.FirewallRuleGroupId,
+		"firewallRuleSetId":   accessLog.FirewallRuleSetId,
+		"firewallRuleId":      accessLog.FirewallRuleId,
+		"content":             string(content),
+	}, nil
+}
@@ -0,0 +1,373 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"encoding/json"
+	"github.com/ClickHouse/ch-go/proto"
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testClickHouseRevision = 54451
+	testClickHouseTable    = "`edge`.`access_logs`"
+)
+
+// 模拟的数据表结构，region字段不在访问日志中
+var testClickHouseColumns = [][2]string{
+	{"timestamp", "DateTime"},
+	{"requestId", "String"},
+	{"serverId", "UInt64"},
+	{"region", "String"},
+	{"status", "UInt16"},
+	{"content", "String"},
+}
+
+// 模拟使用原生协议的ClickHouse，只支持INSERT
+type testClickHouseServer struct {
+	listener net.Listener
+	password string
+
+	queries []string
+	rows    []map[string]any
+	locker  sync.Mutex
+}
+
+func newTestClickHouseServer(t *testing.T, password string) *testClickHouseServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	var server = &testClickHouseServer{
+		listener: listener,
+		password: password,
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.handle(conn)
+		}
+	}()
+	return server
+}
+
+func (this *testClickHouseServer) Addr() string {
+	return this.listener.Addr().String()
+}
+
+func (this *testClickHouseServer) Queries() []string {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.queries
+}
+
+func (this *testClickHouseServer) Rows() []map[string]any {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.rows
+}
+
+func (this *testClickHouseServer) handle(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	var reader = proto.NewReader(conn)
+	var buf = &proto.Buffer{}
+
+	// 握手
+	code, err := reader.UVarInt()
+	if err != nil || proto.ClientCode(code) != proto.ClientCodeHello {
+		return
+	}
+	var hello = &proto.ClientHello{}
+	err = hello.Decode(reader)
+	if err != nil {
+		return
+	}
+	if hello.Password != this.password {
+		this.writeException(conn, buf, proto.ErrAuthenticationFailed, "Authentication failed")
+		return
+	}
+	var serverHello = &proto.ServerHello{
+		Name:     "ClickHouse",
+		Major:    23,
+		Minor:    8,
+		Revision: testClickHouseRevision,
+		Timezone: "UTC",
+	}
+	serverHello.EncodeAware(buf, testClickHouseRevision)
+	_, err = conn.Write(buf.Buf)
+	if err != nil {
+		return
+	}
+
+	for {
+		code, err = reader.UVarInt()
+		if err != nil {
+			return
+		}
+		switch proto.ClientCode(code) {
+		case proto.ClientCodePing:
+			buf.Reset()
+			buf.PutByte(byte(proto.ServerCodePong))
+			_, err = conn.Write(buf.Buf)
+			if err != nil {
+				return
+			}
+		case proto.ClientCodeQuery:
+			var query = &proto.Query{}
+			err = query.DecodeAware(reader, testClickHouseRevision)
+			if err != nil {
+				return
+			}
+			if !this.handleInsert(conn, reader, buf, query.Body) {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (this *testClickHouseServer) handleInsert(conn net.Conn, reader *proto.Reader, buf *proto.Buffer, query string) bool {
+	this.locker.Lock()
+	this.queries = append(this.queries, query)
+	this.locker.Unlock()
+
+	// 查询后面跟着一个空的数据块
+	_, err := this.readBlock(reader)
+	if err != nil {
+		return false
+	}
+
+	if !strings.HasPrefix(query, "INSERT INTO "+testClickHouseTable) {
+		this.writeException(conn, buf, proto.ErrUnknownTable, "Table "+query+" doesn't exist")
+		return false
+	}
+
+	// 返回需要写入的字段
+	var input = proto.Input{}
+	for _, column := range testClickHouseColumns {
+		var name = column[0]
+		if strings.Contains(query, "(") && !strings.Contains(query, "`"+name+"`") {
+			continue
+		}
+		var data = &proto.ColAuto{}
+		err = data.Infer(proto.ColumnType(column[1]))
+		if err != nil {
+			return false
+		}
+		input = append(input, proto.InputColumn{Name: name, Data: data.Data})
+	}
+	buf.Reset()
+	buf.PutByte(byte(proto.ServerCodeData))
+	buf.PutString("")
+	err = proto.Block{Columns: len(input)}.EncodeBlock(buf, testClickHouseRevision, input)
+	if err != nil {
+		return false
+	}
+	_, err = conn.Write(buf.Buf)
+	if err != nil {
+		return false
+	}
+
+	// 读取数据，直到空的数据块
+	for {
+		results, err := this.readBlock(reader)
+		if err != nil {
+			return false
+		}
+		if results.Rows() == 0 {
+			break
+		}
+
+		this.locker.Lock()
+		for i := 0; i < results.Rows(); i++ {
+			var row = map[string]any{}
+			for _, result := range results {
+				switch data := result.Data.(type) {
+				case *proto.ColStr:
+					row[result.Name] = data.Row(i)
+				case *proto.ColUInt16:
+					row[result.Name] = data.Row(i)
+				case *proto.ColUInt64:
+					row[result.Name] = data.Row(i)
+				case *proto.ColDateTime:
+					row[result.Name] = data.Row(i)
+				}
+			}
+			this.rows = append(this.rows, row)
+		}
+		this.locker.Unlock()
+	}
+
+	buf.Reset()
+	buf.PutByte(byte(proto.ServerCodeEndOfStream))
+	_, err = conn.Write(buf.Buf)
+	return err == nil
+}
+
+func (this *testClickHouseServer) readBlock(reader *proto.Reader) (proto.Results, error) {
+	code, err := reader.UVarInt()
+	if err != nil {
+		return nil, err
+	}
+	if proto.ClientCode(code) != proto.ClientCodeData {
+		return nil, net.ErrClosed
+	}
+	var data = &proto.ClientData{}
+	err = data.DecodeAware(reader, testClickHouseRevision)
+	if err != nil {
+		return nil, err
+	}
+	var block = &proto.Block{}
+	var results = proto.Results{}
+	err = block.DecodeBlock(reader, testClickHouseRevision, results.Auto())
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (this *testClickHouseServer) writeException(conn net.Conn, buf *proto.Buffer, code proto.Error, message string) {
+	buf.Reset()
+	buf.PutByte(byte(proto.ServerCodeException))
+	(&proto.Exception{
+		Code:    code,
+		Name:    "DB::Exception",
+		Message: message,
+	}).EncodeAware(buf, testClickHouseRevision)
+	_, _ = conn.Write(buf.Buf)
+}
+
+func TestClickHouseSink_Write(t *testing.T) {
+	var server = newTestClickHouseServer(t, "123456")
+
+	var sink = NewClickHouseSink()
+	err := sink.Init([]byte(`{"addrs": ["` + server.Addr() + `"], "username": "default", "password": "123456", "database": "edge", "table": "access_logs"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = sink.Close()
+	}()
+
+	err = sink.Write([]*pb.HTTPAccessLog{
+		{RequestId: "1", ServerId: 10, Status: 200, Timestamp: 1704196800},
+		{RequestId: "2", ServerId: 10, Status: 502, Timestamp: 1704196801},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sink.Write([]*pb.HTTPAccessLog{
+		{RequestId: "3", ServerId: 11, Status: 404, Timestamp: 1704196802},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一次写入时读取数据表结构，之后只写入数据表中存在的字段
+	var queries = server.Queries()
+	if len(queries) != 3 {
+		t.Fatal("expect 3 queries, got", len(queries), queries)
+	}
+	if queries[0] != "INSERT INTO "+testClickHouseTable+" VALUES" {
+		t.Fatal("unexpected query:", queries[0])
+	}
+	var insertQuery = "INSERT INTO " + testClickHouseTable + " (`timestamp`, `requestId`, `serverId`, `status`, `content`) VALUES"
+	if queries[1] != insertQuery || queries[2] != insertQuery {
+		t.Fatal("unexpected query:", queries[1:])
+	}
+
+	var rows = server.Rows()
+	if len(rows) != 3 {
+		t.Fatal("expect 3 rows, got", len(rows))
+	}
+	var row = rows[1]
+	if row["requestId"] != "2" || row["serverId"] != uint64(10) || row["status"] != uint16(502) {
+		t.Fatal("unexpected row:", row)
+	}
+	timestamp, ok := row["timestamp"].(time.Time)
+	if !ok || timestamp.Unix() != 1704196801 {
+		t.Fatal("unexpected timestamp:", row["timestamp"])
+	}
+	var accessLog = &pb.HTTPAccessLog{}
+	err = json.Unmarshal([]byte(row["content"].(string)), accessLog)
+	if err != nil || accessLog.RequestId != "2" {
+		t.Fatal("unexpected content:", row["content"])
+	}
+	if rows[2]["requestId"] != "3" {
+		t.Fatal("unexpected row:", rows[2])
+	}
+}
+
+func TestClickHouseSink_Write_Error(t *testing.T) {
+	var server = newTestClickHouseServer(t, "123456")
+
+	// 认证失败
+	var sink = NewClickHouseSink()
+	err := sink.Init([]byte(`{"addrs": ["` + server.Addr() + `"], "password": "654321", "database": "edge", "table": "access_logs"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sink.Write([]*pb.HTTPAccessLog{{RequestId: "1"}})
+	_ = sink.Close()
+	if err == nil {
+		t.Fatal("expect authentication error")
+	}
+
+	// 数据表不存在
+	sink = NewClickHouseSink()
+	err = sink.Init([]byte(`{"addrs": ["` + server.Addr() + `"], "password": "123456", "database": "edge", "table": "not_exists"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sink.Write([]*pb.HTTPAccessLog{{RequestId: "1"}})
+	_ = sink.Close()
+	if err == nil || !strings.Contains(err.Error(), "doesn't exist") {
+		t.Fatal("expect unknown table error, got", err)
+	}
+
+	if len(server.Rows()) != 0 {
+		t.Fatal("expect no rows")
+	}
+}
+
+func TestClickHouseSink_Init(t *testing.T) {
+	for _, optionsJSON := range []string{
+		`{"table": "access_logs"}`,
+		`{"addrs": ["127.0.0.1"], "table": "access_logs"}`,
+		`{"addrs": ["127.0.0.1:9000"], "table": "access_logs; DROP TABLE users"}`,
+		`{"addrs": ["127.0.0.1:9000"], "database": "a.b", "table": "access_logs"}`,
+		`{"addrs": ["127.0.0.1:9000"], "table": "access_logs", "compression": "gzip"}`,
+		`{"addrs": ["127.0.0.1:9000"], "table": "access_logs", "tls": true, "tlsCACert": "invalid"}`,
+	} {
+		err := NewClickHouseSink().Init([]byte(optionsJSON))
+		if err == nil {
+			t.Fatal("expect error for options: " + optionsJSON)
+		}
+	}
+
+	var sink = NewClickHouseSink()
+	err := sink.Init([]byte(`{"addrs": ["127.0.0.1:9000"], "table": "access_logs", "compression": "lz4"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = sink.Close()
+	if sink.options.Database != "default" || sink.options.Timeout != DefaultClickHouseTimeout {
+		t.Fatal("unexpected default options:", sink.options)
+	}
+}
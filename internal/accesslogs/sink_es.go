@@ -0,0 +1,161 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ESSinkOptions Elasticsearch存储选项
+type ESSinkOptions struct {
+	HTTPSinkOptions
+
+	Index        string `json:"index"`        // 索引名称，可以使用 ${date} 表示日志日期，比如 edge-access-${date}
+	APIKey       string `json:"apiKey"`       // API Key，设置后不再使用用户名和密码
+	IsDataStream bool   `json:"isDataStream"` // 索引是否为Data Stream
+}
+
+// ESSink 通过Bulk API写入Elasticsearch
+type ESSink struct {
+	options *ESSinkOptions
+	client  *http.Client
+}
+
+func NewESSink() *ESSink {
+	return &ESSink{}
+}
+
+// Init 初始化
+func (this *ESSink) Init(optionsJSON []byte) error {
+	var options = &ESSinkOptions{}
+	err := json.Unmarshal(optionsJSON, options)
+	if err != nil {
+		return errors.New("decode options failed: " + err.Error())
+	}
+	err = options.validate()
+	if err != nil {
+		return err
+	}
+	if len(options.Index) == 0 {
+		return errors.New("'index' should not be empty")
+	}
+	this.options = options
+	this.client = newHTTPSinkClient(&options.HTTPSinkOptions)
+	return nil
+}
+
+// Write 写入访问日志
+func (this *ESSink) Write(accessLogs []*pb.HTTPAccessLog) error {
+	// Data Stream只支持create操作
+	var action = "index"
+	if this.options.IsDataStream {
+		action = "create"
+	}
+
+	var buf = &bytes.Buffer{}
+	for _, accessLog := range accessLogs {
+		actionJSON, err := json.Marshal(map[string]any{
+			action: map[string]any{
+				"_index": this.indexName(accessLog),
+			},
+		})
+		if err != nil {
+			return err
+		}
+		buf.Write(actionJSON)
+		buf.WriteByte('\n')
+
+		docJSON, err := json.Marshal(accessLog)
+		if err != nil {
+			return err
+		}
+		buf.Write(this.withTimestamp(accessLog, docJSON))
+		buf.WriteByte('\n')
+	}
+
+	req, err := http.NewRequest(http.MethodPost, this.options.Endpoint+"/_bulk", buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if len(this.options.APIKey) > 0 {
+		req.Header.Set("Authorization", "ApiKey "+this.options.APIKey)
+	} else if len(this.options.Username) > 0 {
+		req.SetBasicAuth(this.options.Username, this.options.Password)
+	}
+
+	data, err := doHTTPSinkRequest(this.client, req)
+	if err != nil {
+		return err
+	}
+
+	// 检查每一条的写入结果
+	var resp = &esBulkResponse{}
+	err = json.Unmarshal(data, resp)
+	if err != nil {
+		return errors.New("decode response failed: " + err.Error())
+	}
+	if resp.Errors {
+		var countFailed = 0
+		var firstError string
+		for _, item := range resp.Items {
+			for _, result := range item {
+				if result.Error != nil {
+					countFailed++
+					if len(firstError) == 0 {
+						firstError = result.Error.Type + ": " + result.Error.Reason
+					}
+				}
+			}
+		}
+		return errors.New(strconv.Itoa(countFailed) + " of " + strconv.Itoa(len(accessLogs)) + " documents failed, first error: " + firstError)
+	}
+
+	return nil
+}
+
+// Close 关闭
+func (this *ESSink) Close() error {
+	if this.client != nil {
+		this.client.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (this *ESSink) indexName(accessLog *pb.HTTPAccessLog) string {
+	if !strings.Contains(this.options.Index, "${date}") {
+		return this.options.Index
+	}
+	return strings.ReplaceAll(this.options.Index, "${date}", accessLogTime(accessLog).Format("2006.01.02"))
+}
+
+// 在访问日志基础上增加Elasticsearch中常用的@timestamp字段
+func (this *ESSink) withTimestamp(accessLog *pb.HTTPAccessLog, docJSON []byte) []byte {
+	if len(docJSON) < 2 || docJSON[0] != '{' {
+		return docJSON
+	}
+
+	var prefix = `{"@timestamp":"` + accessLogTime(accessLog).UTC().Format(time.RFC3339) + `"`
+	if len(docJSON) > 2 {
+		prefix += ","
+	}
+	return append([]byte(prefix), docJSON[1:]...)
+}
+
+type esBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
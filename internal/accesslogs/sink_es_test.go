@@ -0,0 +1,84 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestESSink_Write(t *testing.T) {
+	var lines [][]byte
+	var server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/_bulk" || req.Header.Get("Content-Type") != "application/x-ndjson" {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		username, password, _ := req.BasicAuth()
+		if username != "elastic" || password != "123456" {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		data, _ := io.ReadAll(req.Body)
+		lines = bytes.Split(bytes.TrimSpace(data), []byte{'\n'})
+		_, _ = writer.Write([]byte(`{"took": 1, "errors": false, "items": []}`))
+	}))
+	defer server.Close()
+
+	var sink = NewESSink()
+	err := sink.Init([]byte(`{"endpoint": "` + server.URL + `/", "username": "elastic", "password": "123456", "index": "edge-access-${date}"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var timestamp = time.Date(2024, 1, 2, 12, 0, 0, 0, time.Local).Unix()
+	err = sink.Write([]*pb.HTTPAccessLog{
+		{RequestId: "1", Timestamp: timestamp},
+		{RequestId: "2", Timestamp: timestamp},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lines) != 4 {
+		t.Fatal("expect 4 lines, got", len(lines))
+	}
+	if string(lines[0]) != `{"index":{"_index":"edge-access-2024.01.02"}}` {
+		t.Fatal("unexpected action:", string(lines[0]))
+	}
+	var doc = map[string]any{}
+	err = json.Unmarshal(lines[3], &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc["requestId"] != "2" || !strings.HasPrefix(doc["@timestamp"].(string), "2024-01-02T") {
+		t.Fatal("unexpected document:", string(lines[3]))
+	}
+}
+
+func TestESSink_Write_Errors(t *testing.T) {
+	var server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "ApiKey abc" {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = writer.Write([]byte(`{"took": 1, "errors": true, "items": [{"create": {"status": 201}}, {"create": {"status": 400, "error": {"type": "mapper_parsing_exception", "reason": "failed to parse"}}}]}`))
+	}))
+	defer server.Close()
+
+	var sink = NewESSink()
+	err := sink.Init([]byte(`{"endpoint": "` + server.URL + `", "apiKey": "abc", "index": "logs-edge", "isDataStream": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sink.Write([]*pb.HTTPAccessLog{{RequestId: "1"}, {RequestId: "2"}})
+	if err == nil || !strings.Contains(err.Error(), "mapper_parsing_exception") {
+		t.Fatal("expect bulk item error, got:", err)
+	}
+}
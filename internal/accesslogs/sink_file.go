@@ -0,0 +1,163 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+const (
+	DefaultFileMaxSizeMB  = 256 // 默认单个文件最大尺寸
+	DefaultFileMaxBackups = 10  // 默认保留的轮转文件数量
+)
+
+// FileSinkOptions 文件存储选项
+type FileSinkOptions struct {
+	Path       string `json:"path"`       // 文件路径
+	AutoCreate bool   `json:"autoCreate"` // 是否自动创建目录
+	MaxSizeMB  int64  `json:"maxSizeMB"`  // 单个文件最大尺寸，超出后轮转
+	MaxBackups int    `json:"maxBackups"` // 保留的轮转文件数量，比如 access.log.1、access.log.2 ...
+}
+
+// FileSink 以NDJSON格式写入本地文件
+type FileSink struct {
+	options *FileSinkOptions
+
+	fp      *os.File
+	size    int64
+	maxSize int64
+
+	locker sync.Mutex
+}
+
+func NewFileSink() *FileSink {
+	return &FileSink{}
+}
+
+// Init 初始化
+func (this *FileSink) Init(optionsJSON []byte) error {
+	var options = &FileSinkOptions{}
+	err := json.Unmarshal(optionsJSON, options)
+	if err != nil {
+		return errors.New("decode options failed: " + err.Error())
+	}
+	if len(options.Path) == 0 {
+		return errors.New("'path' should not be empty")
+	}
+	if options.MaxSizeMB <= 0 {
+		options.MaxSizeMB = DefaultFileMaxSizeMB
+	}
+	if options.MaxBackups <= 0 {
+		options.MaxBackups = DefaultFileMaxBackups
+	}
+	this.options = options
+	this.maxSize = options.MaxSizeMB << 20
+
+	return this.open()
+}
+
+// Write 写入访问日志，每条日志一行
+func (this *FileSink) Write(accessLogs []*pb.HTTPAccessLog) error {
+	var buf = &bytes.Buffer{}
+	for _, accessLog := range accessLogs {
+		data, err := json.Marshal(accessLog)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	if this.fp == nil {
+		err := this.open()
+		if err != nil {
+			return err
+		}
+	}
+
+	n, err := this.fp.Write(buf.Bytes())
+	this.size += int64(n)
+	if err != nil {
+		// 下次写入时重新打开文件
+		_ = this.fp.Close()
+		this.fp = nil
+		return err
+	}
+
+	if this.size >= this.maxSize {
+		return this.rotate()
+	}
+	return nil
+}
+
+// Close 关闭文件
+func (this *FileSink) Close() error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	if this.fp == nil {
+		return nil
+	}
+	var err = this.fp.Close()
+	this.fp = nil
+	return err
+}
+
+func (this *FileSink) open() error {
+	if this.options.AutoCreate {
+		err := os.MkdirAll(filepath.Dir(this.options.Path), 0755)
+		if err != nil {
+			return err
+		}
+	}
+
+	fp, err := os.OpenFile(this.options.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	stat, err := fp.Stat()
+	if err != nil {
+		_ = fp.Close()
+		return err
+	}
+	this.fp = fp
+	this.size = stat.Size()
+	return nil
+}
+
+// 轮转文件：access.log -> access.log.1 -> access.log.2 ...
+func (this *FileSink) rotate() error {
+	if this.fp != nil {
+		_ = this.fp.Close()
+		this.fp = nil
+	}
+
+	var path = this.options.Path
+	_ = os.Remove(path + "." + strconv.Itoa(this.options.MaxBackups))
+	for i := this.options.MaxBackups - 1; i >= 1; i-- {
+		var oldPath = path + "." + strconv.Itoa(i)
+		_, err := os.Stat(oldPath)
+		if err == nil {
+			err = os.Rename(oldPath, path+"."+strconv.Itoa(i+1))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err := os.Rename(path, path+".1")
+	if err != nil {
+		return err
+	}
+
+	return this.open()
+}
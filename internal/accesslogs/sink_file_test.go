@@ -0,0 +1,85 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSink_Write(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "logs", "access.log")
+	var sink = NewFileSink()
+	err := sink.Init([]byte(`{"path": "` + path + `", "autoCreate": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sink.Write([]*pb.HTTPAccessLog{
+		{RequestId: "1", Host: "example.com", Status: 200},
+		{RequestId: "2", Host: "example.com", Status: 404},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = sink.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines = bytes.Split(bytes.TrimSpace(data), []byte{'\n'})
+	if len(lines) != 2 {
+		t.Fatal("expect 2 lines, got", len(lines))
+	}
+	var accessLog = &pb.HTTPAccessLog{}
+	err = json.Unmarshal(lines[1], accessLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accessLog.RequestId != "2" || accessLog.Status != 404 {
+		t.Fatal("unexpected access log:", string(lines[1]))
+	}
+}
+
+func TestFileSink_Rotate(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "access.log")
+	var sink = NewFileSink()
+	err := sink.Init([]byte(`{"path": "` + path + `", "maxBackups": 2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = sink.Close()
+	}()
+	sink.maxSize = 10
+
+	for i := 0; i < 4; i++ {
+		err = sink.Write([]*pb.HTTPAccessLog{{RequestId: "1"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, backupPath := range []string{path + ".1", path + ".2"} {
+		_, err = os.Stat(backupPath)
+		if err != nil {
+			t.Fatal("expect backup file '"+backupPath+"':", err)
+		}
+	}
+	_, err = os.Stat(path + ".3")
+	if !os.IsNotExist(err) {
+		t.Fatal("backup file beyond 'maxBackups' should be removed")
+	}
+}
+
+func TestFileSink_Init(t *testing.T) {
+	err := NewFileSink().Init([]byte(`{}`))
+	if err == nil {
+		t.Fatal("empty path should be invalid")
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"github.com/iwind/TeaGo/types"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"net"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultKafkaTimeout = 30 // 默认超时时间（秒）
+
+	kafkaBatchSize    = 10_000
+	kafkaBatchTimeout = 10 * time.Millisecond
+)
+
+var kafkaTopicReg = regexp.MustCompile(`^[\w.-]{1,249}$`)
+
+// KafkaSinkOptions Kafka存储选项
+type KafkaSinkOptions struct {
+	Brokers               []string `json:"brokers"`               // Broker地址列表，格式为 host:port
+	Topic                 string   `json:"topic"`                 // 主题
+	RequiredAcks          int      `json:"requiredAcks"`          // 需要的确认：-1 所有同步副本，1 只需要Leader，0 不需要确认；默认为 -1
+	Compression           string   `json:"compression"`           // 压缩算法：gzip、snappy、lz4、zstd，为空表示不压缩
+	Timeout               int      `json:"timeout"`               // 连接和写入超时时间（秒）
+	Username              string   `json:"username"`              // SASL/PLAIN用户名，为空表示不认证
+	Password              string   `json:"password"`              // SASL/PLAIN密码
+	TLS                   bool     `json:"tls"`                   // 是否使用TLS连接
+	TLSCACert             string   `json:"tlsCACert"`             // 用于校验服务器证书的CA证书（PEM）
+	TLSInsecureSkipVerify bool     `json:"tlsInsecureSkipVerify"` // 是否跳过证书校验
+}
+
+// KafkaSink 使用Kafka协议直接写入Kafka主题
+// 以网站ID作为消息Key，同一个网站的日志会写入同一个分区；写入失败时由策略负责重试
+type KafkaSink struct {
+	options *KafkaSinkOptions
+	writer  *kafka.Writer
+}
+
+func NewKafkaSink() *KafkaSink {
+	return &KafkaSink{}
+}
+
+// Init 初始化
+func (this *KafkaSink) Init(optionsJSON []byte) error {
+	var options = &KafkaSinkOptions{
+		RequiredAcks: int(kafka.RequireAll),
+		Timeout:      DefaultKafkaTimeout,
+	}
+	err := json.Unmarshal(optionsJSON, options)
+	if err != nil {
+		return errors.New("decode options failed: " + err.Error())
+	}
+
+	if len(options.Brokers) == 0 {
+		return errors.New("'brokers' should not be empty")
+	}
+	for _, broker := range options.Brokers {
+		_, _, err = net.SplitHostPort(broker)
+		if err != nil {
+			return errors.New("invalid broker '" + broker + "': " + err.Error())
+		}
+	}
+	if !kafkaTopicReg.MatchString(options.Topic) {
+		return errors.New("invalid topic '" + options.Topic + "'")
+	}
+	switch kafka.RequiredAcks(options.RequiredAcks) {
+	case kafka.RequireAll, kafka.RequireOne, kafka.RequireNone:
+	default:
+		return errors.New("invalid requiredAcks '" + strconv.Itoa(options.RequiredAcks) + "'")
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultKafkaTimeout
+	}
+
+	var compression kafka.Compression
+	switch options.Compression {
+	case "":
+	case "gzip":
+		compression = kafka.Gzip
+	case "snappy":
+		compression = kafka.Snappy
+	case "lz4":
+		compression = kafka.Lz4
+	case "zstd":
+		compression = kafka.Zstd
+	default:
+		return errors.New("invalid compression '" + options.Compression + "'")
+	}
+
+	var timeout = time.Duration(options.Timeout) * time.Second
+	var transport = &kafka.Transport{
+		Dial:        (&net.Dialer{Timeout: timeout}).DialContext,
+		DialTimeout: timeout,
+		ClientID:    "edge-api",
+	}
+	if len(options.Username) > 0 {
+		transport.SASL = plain.Mechanism{
+			Username: options.Username,
+			Password: options.Password,
+		}
+	}
+	if options.TLS {
+		transport.TLS = &tls.Config{
+			InsecureSkipVerify: options.TLSInsecureSkipVerify,
+		}
+		if len(options.TLSCACert) > 0 {
+			var pool = x509.NewCertPool()
+			if !pool.AppendCertsFromPEM([]byte(options.TLSCACert)) {
+				return errors.New("invalid 'tlsCACert'")
+			}
+			transport.TLS.RootCAs = pool
+		}
+	}
+
+	this.options = options
+	this.writer = &kafka.Writer{
+		Addr:         kafka.TCP(options.Brokers...),
+		Topic:        options.Topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequiredAcks(options.RequiredAcks),
+		Compression:  compression,
+		MaxAttempts:  1,
+		BatchSize:    kafkaBatchSize,
+		BatchTimeout: kafkaBatchTimeout,
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout,
+		Transport:    transport,
+	}
+	return nil
+}
+
+// Write 写入访问日志
+func (this *KafkaSink) Write(accessLogs []*pb.HTTPAccessLog) error {
+	var messages = make([]kafka.Message, 0, len(accessLogs))
+	for _, accessLog := range accessLogs {
+		valueJSON, err := json.Marshal(accessLog)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, kafka.Message{
+			Key:   []byte(types.String(accessLog.ServerId)),
+			Value: valueJSON,
+			Time:  accessLogTime(accessLog),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(this.options.Timeout)*time.Second)
+	defer cancel()
+
+	err := this.writer.WriteMessages(ctx, messages...)
+	if err != nil {
+		// 检查每一条的写入结果
+		writeErrors, ok := err.(kafka.WriteErrors)
+		if ok {
+			for _, writeErr := range writeErrors {
+				if writeErr != nil {
+					return errors.New(strconv.Itoa(writeErrors.Count()) + " of " + strconv.Itoa(len(accessLogs)) + " records failed, first error: " + writeErr.Error())
+				}
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// Close 关闭
+func (this *KafkaSink) Close() error {
+	if this.writer != nil {
+		return this.writer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/apiversions"
+	"github.com/segmentio/kafka-go/protocol/metadata"
+	"github.com/segmentio/kafka-go/protocol/produce"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testKafkaTopic = "edge-access-logs"
+
+type testKafkaRecord struct {
+	Key   string
+	Value []byte
+}
+
+// 模拟只有一个Broker和一个分区的Kafka
+type testKafkaBroker struct {
+	listener  net.Listener
+	errorCode int16 // Produce响应中返回的错误代码
+
+	records []testKafkaRecord
+	locker  sync.Mutex
+}
+
+func newTestKafkaBroker(t *testing.T, errorCode int16) *testKafkaBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	var broker = &testKafkaBroker{
+		listener:  listener,
+		errorCode: errorCode,
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go broker.handle(conn)
+		}
+	}()
+	return broker
+}
+
+func (this *testKafkaBroker) Addr() string {
+	return this.listener.Addr().String()
+}
+
+func (this *testKafkaBroker) Records() []testKafkaRecord {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.records
+}
+
+func (this *testKafkaBroker) handle(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	for {
+		apiVersion, correlationId, _, msg, err := protocol.ReadRequest(conn)
+		if err != nil {
+			return
+		}
+
+		var resp protocol.Message
+		switch req := msg.(type) {
+		case *apiversions.Request:
+			resp = &apiversions.Response{
+				ApiKeys: []apiversions.ApiKeyResponse{
+					{ApiKey: int16(protocol.ApiVersions), MaxVersion: 2},
+					{ApiKey: int16(protocol.Metadata), MaxVersion: 8},
+					{ApiKey: int16(protocol.Produce), MaxVersion: 8},
+				},
+			}
+		case *metadata.Request:
+			host, portString, _ := net.SplitHostPort(this.Addr())
+			port, _ := strconv.Atoi(portString)
+			resp = &metadata.Response{
+				Brokers:      []metadata.ResponseBroker{{NodeID: 1, Host: host, Port: int32(port)}},
+				ControllerID: 1,
+				Topics: []metadata.ResponseTopic{
+					{
+						Name:       testKafkaTopic,
+						Partitions: []metadata.ResponsePartition{{PartitionIndex: 0, LeaderID: 1, ReplicaNodes: []int32{1}, IsrNodes: []int32{1}}},
+					},
+				},
+			}
+		case *produce.Request:
+			resp, err = this.produce(req)
+			if err != nil {
+				return
+			}
+		default:
+			return
+		}
+
+		err = protocol.WriteResponse(conn, apiVersion, correlationId, resp)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (this *testKafkaBroker) produce(req *produce.Request) (*produce.Response, error) {
+	var resp = &produce.Response{}
+	for _, topic := range req.Topics {
+		var respTopic = produce.ResponseTopic{Topic: topic.Topic}
+		for _, partition := range topic.Partitions {
+			for {
+				record, err := partition.RecordSet.Records.ReadRecord()
+				if err != nil {
+					if errors.Is(err, io.EOF) {
+						break
+					}
+					return nil, err
+				}
+				key, err := protocol.ReadAll(record.Key)
+				if err != nil {
+					return nil, err
+				}
+				value, err := protocol.ReadAll(record.Value)
+				if err != nil {
+					return nil, err
+				}
+				this.locker.Lock()
+				this.records = append(this.records, testKafkaRecord{Key: string(key), Value: value})
+				this.locker.Unlock()
+			}
+			respTopic.Partitions = append(respTopic.Partitions, produce.ResponsePartition{
+				Partition: partition.Partition,
+				ErrorCode: this.errorCode,
+			})
+		}
+		resp.Topics = append(resp.Topics, respTopic)
+	}
+	return resp, nil
+}
+
+func TestKafkaSink_Write(t *testing.T) {
+	var broker = newTestKafkaBroker(t, 0)
+
+	var sink = NewKafkaSink()
+	err := sink.Init([]byte(`{"brokers": ["` + broker.Addr() + `"], "topic": "` + testKafkaTopic + `", "compression": "gzip"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = sink.Close()
+	}()
+
+	err = sink.Write([]*pb.HTTPAccessLog{
+		{RequestId: "1", ServerId: 10},
+		{RequestId: "2", ServerId: 11},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var records = broker.Records()
+	if len(records) != 2 {
+		t.Fatal("expect 2 records, got", len(records))
+	}
+	var record = records[1]
+	if record.Key != "11" {
+		t.Fatal("unexpected key:", record.Key)
+	}
+	var accessLog = &pb.HTTPAccessLog{}
+	err = json.Unmarshal(record.Value, accessLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accessLog.RequestId != "2" {
+		t.Fatal("unexpected value:", string(record.Value))
+	}
+}
+
+func TestKafkaSink_Write_Errors(t *testing.T) {
+	var broker = newTestKafkaBroker(t, 10) // MESSAGE_TOO_LARGE
+
+	var sink = NewKafkaSink()
+	err := sink.Init([]byte(`{"brokers": ["` + broker.Addr() + `"], "topic": "` + testKafkaTopic + `"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = sink.Close()
+	}()
+
+	err = sink.Write([]*pb.HTTPAccessLog{{RequestId: "1"}, {RequestId: "2"}})
+	if err == nil || !strings.Contains(err.Error(), "2 of 2 records failed") {
+		t.Fatal("expect record error, got:", err)
+	}
+}
+
+func TestKafkaSink_Init(t *testing.T) {
+	for _, optionsJSON := range []string{
+		`{"topic": "edge"}`,
+		`{"brokers": ["127.0.0.1"], "topic": "edge"}`,
+		`{"brokers": ["127.0.0.1:9092"], "topic": "edge/logs"}`,
+		`{"brokers": ["127.0.0.1:9092"], "topic": "edge", "requiredAcks": 2}`,
+		`{"brokers": ["127.0.0.1:9092"], "topic": "edge", "compression": "brotli"}`,
+		`{"brokers": ["127.0.0.1:9092"], "topic": "edge", "tls": true, "tlsCACert": "invalid"}`,
+	} {
+		if NewKafkaSink().Init([]byte(optionsJSON)) == nil {
+			t.Fatal("options should be invalid:", optionsJSON)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	SyslogProtocolUDP = "udp"
+	SyslogProtocolTCP = "tcp"
+	SyslogProtocolTLS = "tls"
+)
+
+const (
+	DefaultSyslogFacility = 16 // local0
+	DefaultSyslogSeverity = 6  // informational
+	DefaultSyslogAppName  = "edge-access-log"
+
+	syslogDialTimeout  = 10 * time.Second
+	syslogWriteTimeout = 30 * time.Second
+)
+
+// SyslogSinkOptions Syslog存储选项
+type SyslogSinkOptions struct {
+	Protocol              string `json:"protocol"`              // 协议：udp、tcp、tls
+	ServerAddr            string `json:"serverAddr"`            // 服务器地址，格式为 host:port
+	AppName               string `json:"appName"`               // 应用名称（APP-NAME）
+	Hostname              string `json:"hostname"`              // 主机名，为空时使用当前主机名
+	Facility              int    `json:"facility"`              // 设施代码，0-23
+	Severity              int    `json:"severity"`              // 日志级别，0-7
+	TLSCACert             string `json:"tlsCACert"`             // 用于校验服务器证书的CA证书（PEM）
+	TLSInsecureSkipVerify bool   `json:"tlsInsecureSkipVerify"` // 是否跳过证书校验
+}
+
+// SyslogSink 以RFC 5424格式发送到Syslog服务器
+// TCP和TLS使用RFC 6587中的octet-counting分帧方式
+type SyslogSink struct {
+	options   *SyslogSinkOptions
+	tlsConfig *tls.Config
+
+	conn   net.Conn
+	locker sync.Mutex
+}
+
+func NewSyslogSink() *SyslogSink {
+	return &SyslogSink{}
+}
+
+// Init 初始化
+func (this *SyslogSink) Init(optionsJSON []byte) error {
+	var options = &SyslogSinkOptions{
+		Facility: DefaultSyslogFacility,
+		Severity: DefaultSyslogSeverity,
+	}
+	err := json.Unmarshal(optionsJSON, options)
+	if err != nil {
+		return errors.New("decode options failed: " + err.Error())
+	}
+
+	switch options.Protocol {
+	case "":
+		options.Protocol = SyslogProtocolUDP
+	case SyslogProtocolUDP, SyslogProtocolTCP, SyslogProtocolTLS:
+	default:
+		return errors.New("invalid protocol '" + options.Protocol + "'")
+	}
+	if len(options.ServerAddr) == 0 {
+		return errors.New("'serverAddr' should not be empty")
+	}
+	_, _, err = net.SplitHostPort(options.ServerAddr)
+	if err != nil {
+		return errors.New("invalid 'serverAddr': " + err.Error())
+	}
+	if options.Facility < 0 || options.Facility > 23 {
+		return errors.New("invalid facility '" + strconv.Itoa(options.Facility) + "'")
+	}
+	if options.Severity < 0 || options.Severity > 7 {
+		return errors.New("invalid severity '" + strconv.Itoa(options.Severity) + "'")
+	}
+	if len(options.AppName) == 0 {
+		options.AppName = DefaultSyslogAppName
+	}
+	if len(options.Hostname) == 0 {
+		hostname, _ := os.Hostname()
+		if len(hostname) == 0 {
+			hostname = "-"
+		}
+		options.Hostname = hostname
+	}
+
+	if options.Protocol == SyslogProtocolTLS {
+		host, _, _ := net.SplitHostPort(options.ServerAddr)
+		this.tlsConfig = &tls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: options.TLSInsecureSkipVerify,
+		}
+		if len(options.TLSCACert) > 0 {
+			var pool = x509.NewCertPool()
+			if !pool.AppendCertsFromPEM([]byte(options.TLSCACert)) {
+				return errors.New("invalid 'tlsCACert'")
+			}
+			this.tlsConfig.RootCAs = pool
+		}
+	}
+
+	this.options = options
+	return nil
+}
+
+// Write 写入访问日志，每条日志一个Syslog消息
+func (this *SyslogSink) Write(accessLogs []*pb.HTTPAccessLog) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	if this.conn == nil {
+		err := this.dial()
+		if err != nil {
+			return err
+		}
+	}
+
+	_ = this.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout))
+
+	var buf = &bytes.Buffer{}
+	for _, accessLog := range accessLogs {
+		message, err := this.formatMessage(accessLog)
+		if err != nil {
+			return err
+		}
+
+		if this.options.Protocol == SyslogProtocolUDP {
+			// UDP每个数据报一条消息
+			_, err = this.conn.Write(message)
+			if err != nil {
+				this.closeConn()
+				return err
+			}
+			continue
+		}
+
+		buf.WriteString(strconv.Itoa(len(message)))
+		buf.WriteByte(' ')
+		buf.Write(message)
+	}
+
+	if buf.Len() > 0 {
+		_, err := this.conn.Write(buf.Bytes())
+		if err != nil {
+			this.closeConn()
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close 关闭连接
+func (this *SyslogSink) Close() error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	this.closeConn()
+	return nil
+}
+
+func (this *SyslogSink) dial() error {
+	var conn net.Conn
+	var err error
+	switch this.options.Protocol {
+	case SyslogProtocolTLS:
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: syslogDialTimeout}, "tcp", this.options.ServerAddr, this.tlsConfig)
+	default:
+		conn, err = net.DialTimeout(this.options.Protocol, this.options.ServerAddr, syslogDialTimeout)
+	}
+	if err != nil {
+		return err
+	}
+	this.conn = conn
+	return nil
+}
+
+func (this *SyslogSink) closeConn() {
+	if this.conn != nil {
+		_ = this.conn.Close()
+		this.conn = nil
+	}
+}
+
+// 构造RFC 5424消息：<PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (this *SyslogSink) formatMessage(accessLog *pb.HTTPAccessLog) ([]byte, error) {
+	data, err := json.Marshal(accessLog)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf = &bytes.Buffer{}
+	buf.WriteByte('<')
+	buf.WriteString(strconv.Itoa(this.options.Facility*8 + this.options.Severity))
+	buf.WriteString(">1 ")
+	buf.WriteString(accessLogTime(accessLog).Format(time.RFC3339))
+	buf.WriteByte(' ')
+	buf.WriteString(this.options.Hostname)
+	buf.WriteByte(' ')
+	buf.WriteString(this.options.AppName)
+	buf.WriteString(" - access - ")
+	buf.Write(data)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,197 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 读取octet-counting分帧的消息
+func testReadFramedMessages(t *testing.T, listener net.Listener, count int) []string {
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var reader = bufio.NewReader(conn)
+	var messages = []string{}
+	for i := 0; i < count; i++ {
+		lengthString, err := reader.ReadString(' ')
+		if err != nil {
+			t.Fatal(err)
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(lengthString))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var message = make([]byte, length)
+		_, err = io.ReadFull(reader, message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, string(message))
+	}
+	return messages
+}
+
+func testCheckSyslogMessage(t *testing.T, message string, requestId string) {
+	// <134> = local0(16) * 8 + info(6)
+	if !strings.HasPrefix(message, "<134>1 2024-01-02T") {
+		t.Fatal("unexpected header:", message)
+	}
+	if !strings.Contains(message, " test-host edge-test - access - {") {
+		t.Fatal("unexpected message:", message)
+	}
+	if !strings.Contains(message, `"requestId":"`+requestId+`"`) {
+		t.Fatal("unexpected body:", message)
+	}
+}
+
+var testSyslogAccessLogs = []*pb.HTTPAccessLog{
+	{RequestId: "1", Timestamp: time.Date(2024, 1, 2, 12, 0, 0, 0, time.Local).Unix()},
+	{RequestId: "2", Timestamp: time.Date(2024, 1, 2, 12, 0, 1, 0, time.Local).Unix()},
+}
+
+func TestSyslogSink_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	var sink = NewSyslogSink()
+	err = sink.Init([]byte(`{"protocol": "udp", "serverAddr": "` + conn.LocalAddr().String() + `", "hostname": "test-host", "appName": "edge-test"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = sink.Close()
+	}()
+
+	err = sink.Write(testSyslogAccessLogs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var buf = make([]byte, 4096)
+	for _, requestId := range []string{"1", "2"} {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testCheckSyslogMessage(t, string(buf[:n]), requestId)
+	}
+}
+
+func TestSyslogSink_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+
+	var sink = NewSyslogSink()
+	err = sink.Init([]byte(`{"protocol": "tcp", "serverAddr": "` + listener.Addr().String() + `", "hostname": "test-host", "appName": "edge-test"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = sink.Close()
+	}()
+
+	err = sink.Write(testSyslogAccessLogs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var messages = testReadFramedMessages(t, listener, 2)
+	testCheckSyslogMessage(t, messages[0], "1")
+	testCheckSyslogMessage(t, messages[1], "2")
+}
+
+func TestSyslogSink_TLS(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var template = &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, privateKey.Public(), privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{certDER}, PrivateKey: privateKey}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+
+	var sink = NewSyslogSink()
+	err = sink.Init([]byte(`{"protocol": "tls", "serverAddr": "` + listener.Addr().String() + `", "hostname": "test-host", "appName": "edge-test", "tlsInsecureSkipVerify": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = sink.Close()
+	}()
+
+	var errChan = make(chan error, 1)
+	go func() {
+		errChan <- sink.Write(testSyslogAccessLogs)
+	}()
+
+	var messages = testReadFramedMessages(t, listener, 2)
+	testCheckSyslogMessage(t, messages[0], "1")
+	testCheckSyslogMessage(t, messages[1], "2")
+
+	err = <-errChan
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyslogSink_Init(t *testing.T) {
+	for _, optionsJSON := range []string{
+		`{}`,
+		`{"protocol": "http", "serverAddr": "127.0.0.1:514"}`,
+		`{"serverAddr": "127.0.0.1"}`,
+		`{"serverAddr": "127.0.0.1:514", "facility": 24}`,
+	} {
+		if NewSyslogSink().Init([]byte(optionsJSON)) == nil {
+			t.Fatal("options should be invalid:", optionsJSON)
+		}
+	}
+}
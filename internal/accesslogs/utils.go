@@ -0,0 +1,103 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"github.com/iwind/TeaGo/types"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var variableReg = regexp.MustCompile(`\$\{([\w.-]+)}`)
+
+// 访问日志的时间，没有时间戳时使用当前时间
+func accessLogTime(accessLog *pb.HTTPAccessLog) time.Time {
+	if accessLog.Timestamp > 0 {
+		return time.Unix(accessLog.Timestamp, 0)
+	}
+	return time.Now()
+}
+
+// 使用访问日志中的数据替换条件中的变量，比如 ${status}、${header.User-Agent}
+func formatAccessLogVariables(accessLog *pb.HTTPAccessLog, source string) string {
+	if !strings.Contains(source, "${") {
+		return source
+	}
+	return variableReg.ReplaceAllStringFunc(source, func(s string) string {
+		var varName = s[2 : len(s)-1]
+		value, ok := accessLogVariable(accessLog, varName)
+		if !ok {
+			return s
+		}
+		return value
+	})
+}
+
+func accessLogVariable(accessLog *pb.HTTPAccessLog, varName string) (value string, ok bool) {
+	switch varName {
+	case "serverId":
+		return types.String(accessLog.ServerId), true
+	case "nodeId":
+		return types.String(accessLog.NodeId), true
+	case "host":
+		return accessLog.Host, true
+	case "remoteAddr":
+		return accessLog.RemoteAddr, true
+	case "rawRemoteAddr":
+		return accessLog.RawRemoteAddr, true
+	case "requestMethod":
+		return accessLog.RequestMethod, true
+	case "requestURI", "requestURL":
+		return accessLog.RequestURI, true
+	case "requestPath":
+		return accessLog.RequestPath, true
+	case "scheme":
+		return accessLog.Scheme, true
+	case "proto":
+		return accessLog.Proto, true
+	case "status":
+		return types.String(accessLog.Status), true
+	case "bytesSent":
+		return types.String(accessLog.BytesSent), true
+	case "userAgent":
+		return accessLog.UserAgent, true
+	case "referer":
+		return accessLog.Referer, true
+	case "contentType":
+		return accessLog.ContentType, true
+	case "serverName":
+		return accessLog.ServerName, true
+	case "serverPort":
+		return types.String(accessLog.ServerPort), true
+	case "args", "queryString":
+		return accessLog.QueryString, true
+	}
+
+	dotIndex := strings.Index(varName, ".")
+	if dotIndex <= 0 {
+		return "", false
+	}
+	var prefix = varName[:dotIndex]
+	var name = varName[dotIndex+1:]
+	switch prefix {
+	case "header":
+		for headerName, headerValues := range accessLog.Header {
+			if strings.EqualFold(headerName, name) && headerValues != nil {
+				return strings.Join(headerValues.Values, ", "), true
+			}
+		}
+		return "", true
+	case "cookie":
+		return accessLog.Cookie[name], true
+	case "arg":
+		query, err := url.ParseQuery(accessLog.QueryString)
+		if err != nil {
+			return "", true
+		}
+		return query.Get(name), true
+	}
+	return "", false
+}
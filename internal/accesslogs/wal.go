@@ -0,0 +1,512 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/dashenmiren/EdgeAPI/internal/goman"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultWALSegmentSize  int64 = 16 << 20 // 单个分段文件的最大尺寸
+	DefaultWALMaxSize      int64 = 1 << 30  // 所有分段文件的最大尺寸
+	DefaultWALSyncInterval       = 1 * time.Second
+
+	walRecordHeaderSize       = 8 // length(4) + crc32(4)
+	walMaxRecordSize          = 16 << 20
+	walSegmentExt             = ".wal"
+	walCheckpointFile         = "checkpoint"
+	walCheckpointSaveInterval = 1 * time.Second
+)
+
+var walCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+// WALOptions 预写日志选项
+type WALOptions struct {
+	Dir          string
+	SegmentSize  int64         // 单个分段文件的最大尺寸
+	MaxSize      int64         // 所有分段文件的最大尺寸，超出后新写入的记录会被丢弃
+	SyncInterval time.Duration // 刷新到磁盘的间隔
+}
+
+// WALStat 预写日志状态
+type WALStat struct {
+	Segments       int   // 分段文件数量
+	Size           int64 // 占用的磁盘空间
+	MaxSize        int64 // 最大磁盘空间
+	Records        int64 // 尚未读取的记录数
+	CountDropped   int64 // 因为超出最大尺寸而丢弃的记录数
+	CountCorrupted int64 // 因为校验失败而跳过的分段数
+}
+
+type walSegment struct {
+	id       int64
+	size     int64 // 有效数据的尺寸，读取到此位置为止
+	fileSize int64 // 文件实际尺寸
+}
+
+// WAL 基于本地磁盘的预写日志
+// 记录按照写入顺序保存在多个分段文件中，每条记录格式为：长度(4字节) + CRC32C(4字节) + 数据；
+// 读取后需要调用 Commit 确认，确认的位置保存在 checkpoint 文件中，重启后从此位置继续读取，
+// 所以在意外退出时可能会重复读取少量已确认的记录
+type WAL struct {
+	options *WALOptions
+
+	segments   []*walSegment // 按ID从小到大排列，最后一个为正在写入的分段
+	activeFile *os.File
+
+	readSegmentId    int64 // 已确认的读取位置
+	readOffset       int64
+	pendingSegmentId int64 // 已读取但尚未确认的位置
+	pendingOffset    int64
+	pendingCount     int64
+	readFile         *os.File
+	readFileId       int64
+
+	totalSize        int64
+	countRecords     int64
+	countDropped     int64
+	countCorrupted   int64
+	checkpointSaveAt time.Time
+
+	isClosed bool
+	stopChan chan struct{}
+	locker   sync.Mutex
+}
+
+// OpenWAL 打开预写日志，不存在时自动创建
+func OpenWAL(options *WALOptions) (*WAL, error) {
+	if options == nil || len(options.Dir) == 0 {
+		return nil, errors.New("'dir' should not be empty")
+	}
+	var walOptions = *options
+	if walOptions.SegmentSize <= 0 {
+		walOptions.SegmentSize = DefaultWALSegmentSize
+	}
+	if walOptions.MaxSize <= 0 {
+		walOptions.MaxSize = DefaultWALMaxSize
+	}
+	if walOptions.SyncInterval <= 0 {
+		walOptions.SyncInterval = DefaultWALSyncInterval
+	}
+
+	err := os.MkdirAll(walOptions.Dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	var wal = &WAL{
+		options:  &walOptions,
+		stopChan: make(chan struct{}),
+	}
+	err = wal.load()
+	if err != nil {
+		return nil, err
+	}
+
+	goman.New(func() {
+		wal.syncLoop()
+	})
+
+	return wal, nil
+}
+
+// Write 写入记录
+// 返回实际写入的记录数，超出最大尺寸的记录会被丢弃
+func (this *WAL) Write(records [][]byte) (count int, err error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	if this.isClosed {
+		return 0, errors.New("wal has been closed")
+	}
+
+	for index, record := range records {
+		var recordSize = int64(walRecordHeaderSize + len(record))
+		if len(record) > walMaxRecordSize || this.totalSize+recordSize > this.options.MaxSize {
+			this.countDropped += int64(len(records) - index)
+			return count, nil
+		}
+
+		var active = this.segments[len(this.segments)-1]
+		if active.fileSize > 0 && active.fileSize+recordSize > this.options.SegmentSize {
+			err = this.rotate()
+			if err != nil {
+				this.countDropped += int64(len(records) - index)
+				return count, err
+			}
+			active = this.segments[len(this.segments)-1]
+		}
+
+		var buf = make([]byte, recordSize)
+		binary.LittleEndian.PutUint32(buf, uint32(len(record)))
+		binary.LittleEndian.PutUint32(buf[4:], crc32.Checksum(record, walCRCTable))
+		copy(buf[walRecordHeaderSize:], record)
+		n, writeErr := this.activeFile.Write(buf)
+		active.fileSize += int64(n)
+		this.totalSize += int64(n)
+		if writeErr != nil {
+			// 写入不完整的记录会在读取时因为校验失败而跳过，这里切换到新的分段以免影响后续记录
+			active.size = active.fileSize
+			this.countDropped += int64(len(records) - index)
+			_ = this.rotate()
+			return count, writeErr
+		}
+		active.size = active.fileSize
+		this.countRecords++
+		count++
+	}
+	return count, nil
+}
+
+// Read 读取最多 size 条记录
+// 读取后需要调用 Commit 确认，否则下次会从上次确认的位置重新读取
+func (this *WAL) Read(size int) ([][]byte, error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	if this.isClosed {
+		return nil, errors.New("wal has been closed")
+	}
+
+	this.pendingSegmentId = this.readSegmentId
+	this.pendingOffset = this.readOffset
+	this.pendingCount = 0
+
+	var result = [][]byte{}
+	for len(result) < size {
+		var segmentIndex = this.findSegmentIndex(this.pendingSegmentId)
+		if segmentIndex < 0 {
+			break
+		}
+		var segment = this.segments[segmentIndex]
+		if this.pendingOffset >= segment.size {
+			if segmentIndex == len(this.segments)-1 {
+				// 已经读取到末尾，校正剩余记录数（跳过的损坏记录也会被计算在内）
+				this.pendingCount = this.countRecords
+				break
+			}
+			this.pendingSegmentId = this.segments[segmentIndex+1].id
+			this.pendingOffset = 0
+			continue
+		}
+
+		record, err := this.readRecord(segment, this.pendingOffset)
+		if err != nil {
+			// 跳过当前分段中剩余的内容
+			this.countCorrupted++
+			this.pendingOffset = segment.size
+			continue
+		}
+		result = append(result, record)
+		this.pendingOffset += int64(walRecordHeaderSize + len(record))
+		this.pendingCount++
+	}
+
+	return result, nil
+}
+
+// Commit 确认上次 Read 读取的记录，并删除已经读取完的分段
+func (this *WAL) Commit() error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	if this.isClosed {
+		return errors.New("wal has been closed")
+	}
+	if this.pendingSegmentId == 0 {
+		return nil
+	}
+
+	this.readSegmentId = this.pendingSegmentId
+	this.readOffset = this.pendingOffset
+	this.countRecords -= this.pendingCount
+	if this.countRecords < 0 {
+		this.countRecords = 0
+	}
+	this.pendingSegmentId = 0
+	this.pendingCount = 0
+
+	// 删除已经读取完的分段
+	var removed = false
+	for len(this.segments) > 1 && this.segments[0].id < this.readSegmentId {
+		var segment = this.segments[0]
+		if this.readFile != nil && this.readFileId == segment.id {
+			_ = this.readFile.Close()
+			this.readFile = nil
+		}
+		err := os.Remove(this.segmentPath(segment.id))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		this.totalSize -= segment.fileSize
+		this.segments = this.segments[1:]
+		removed = true
+	}
+
+	if removed || time.Since(this.checkpointSaveAt) >= walCheckpointSaveInterval {
+		return this.saveCheckpoint()
+	}
+	return nil
+}
+
+// Len 尚未确认读取的记录数
+func (this *WAL) Len() int64 {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.countRecords
+}
+
+// Stat 状态
+func (this *WAL) Stat() *WALStat {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return &WALStat{
+		Segments:       len(this.segments),
+		Size:           this.totalSize,
+		MaxSize:        this.options.MaxSize,
+		Records:        this.countRecords,
+		CountDropped:   this.countDropped,
+		CountCorrupted: this.countCorrupted,
+	}
+}
+
+// Close 关闭
+func (this *WAL) Close() error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	if this.isClosed {
+		return nil
+	}
+	this.isClosed = true
+	close(this.stopChan)
+
+	if this.readFile != nil {
+		_ = this.readFile.Close()
+		this.readFile = nil
+	}
+
+	var err = this.activeFile.Sync()
+	closeErr := this.activeFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	checkpointErr := this.saveCheckpoint()
+	if err == nil {
+		err = checkpointErr
+	}
+	return err
+}
+
+// 加载已有的分段，并创建新的分段用于写入
+func (this *WAL) load() error {
+	matches, err := filepath.Glob(filepath.Join(this.options.Dir, "*"+walSegmentExt))
+	if err != nil {
+		return err
+	}
+	var segmentIds = []int64{}
+	for _, match := range matches {
+		id, parseErr := strconv.ParseInt(strings.TrimSuffix(filepath.Base(match), walSegmentExt), 10, 64)
+		if parseErr != nil || id <= 0 {
+			continue
+		}
+		segmentIds = append(segmentIds, id)
+	}
+	sort.Slice(segmentIds, func(i, j int) bool {
+		return segmentIds[i] < segmentIds[j]
+	})
+
+	checkpointSegmentId, checkpointOffset := this.loadCheckpoint()
+
+	for _, segmentId := range segmentIds {
+		// 已经读取完的分段
+		if segmentId < checkpointSegmentId {
+			err = os.Remove(this.segmentPath(segmentId))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+
+		stat, err := os.Stat(this.segmentPath(segmentId))
+		if err != nil {
+			return err
+		}
+		var segment = &walSegment{
+			id:       segmentId,
+			size:     stat.Size(),
+			fileSize: stat.Size(),
+		}
+		this.segments = append(this.segments, segment)
+		this.totalSize += segment.fileSize
+
+		// 检查记录，不完整的尾部记录（比如意外退出时）不会被读取
+		var offset int64
+		if segmentId == checkpointSegmentId {
+			offset = checkpointOffset
+		}
+		for offset < segment.size {
+			record, err := this.readRecord(segment, offset)
+			if err != nil {
+				this.countCorrupted++
+				segment.size = offset
+				break
+			}
+			offset += int64(walRecordHeaderSize + len(record))
+			this.countRecords++
+		}
+	}
+
+	if len(this.segments) > 0 {
+		var firstSegment = this.segments[0]
+		this.readSegmentId = firstSegment.id
+		if firstSegment.id == checkpointSegmentId {
+			this.readOffset = checkpointOffset
+		}
+	}
+
+	// 每次打开时都写入新的分段，不修改已有的分段
+	// 新的分段ID需要大于checkpoint中的分段ID，以免被当作已经读取完的分段
+	var newSegmentId = checkpointSegmentId + 1
+	if len(this.segments) > 0 && this.segments[len(this.segments)-1].id >= newSegmentId {
+		newSegmentId = this.segments[len(this.segments)-1].id + 1
+	}
+	err = this.createSegment(newSegmentId)
+	if err != nil {
+		return err
+	}
+	if this.readSegmentId == 0 {
+		this.readSegmentId = newSegmentId
+	}
+	return this.saveCheckpoint()
+}
+
+// 切换到新的分段
+func (this *WAL) rotate() error {
+	_ = this.activeFile.Sync()
+	_ = this.activeFile.Close()
+	return this.createSegment(this.segments[len(this.segments)-1].id + 1)
+}
+
+func (this *WAL) createSegment(segmentId int64) error {
+	fp, err := os.OpenFile(this.segmentPath(segmentId), os.O_CREATE|os.O_WRONLY|os.O_TRUNC|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	this.activeFile = fp
+	this.segments = append(this.segments, &walSegment{
+		id: segmentId,
+	})
+	return nil
+}
+
+// 读取某个位置的记录
+func (this *WAL) readRecord(segment *walSegment, offset int64) ([]byte, error) {
+	if this.readFile == nil || this.readFileId != segment.id {
+		if this.readFile != nil {
+			_ = this.readFile.Close()
+			this.readFile = nil
+		}
+		fp, err := os.Open(this.segmentPath(segment.id))
+		if err != nil {
+			return nil, err
+		}
+		this.readFile = fp
+		this.readFileId = segment.id
+	}
+
+	if offset+walRecordHeaderSize > segment.size {
+		return nil, errors.New("incomplete record header")
+	}
+	var header = make([]byte, walRecordHeaderSize)
+	_, err := this.readFile.ReadAt(header, offset)
+	if err != nil {
+		return nil, err
+	}
+	var length = int64(binary.LittleEndian.Uint32(header))
+	if length > walMaxRecordSize || offset+walRecordHeaderSize+length > segment.size {
+		return nil, errors.New("incomplete record")
+	}
+	var record = make([]byte, length)
+	_, err = this.readFile.ReadAt(record, offset+walRecordHeaderSize)
+	if err != nil {
+		return nil, err
+	}
+	if crc32.Checksum(record, walCRCTable) != binary.LittleEndian.Uint32(header[4:]) {
+		return nil, errors.New("checksum mismatch")
+	}
+	return record, nil
+}
+
+func (this *WAL) findSegmentIndex(segmentId int64) int {
+	for index, segment := range this.segments {
+		if segment.id == segmentId {
+			return index
+		}
+	}
+	return -1
+}
+
+func (this *WAL) segmentPath(segmentId int64) string {
+	return filepath.Join(this.options.Dir, fmt.Sprintf("%016d", segmentId)+walSegmentExt)
+}
+
+// checkpoint 文件内容为：分段ID 偏移量
+func (this *WAL) loadCheckpoint() (segmentId int64, offset int64) {
+	data, err := os.ReadFile(filepath.Join(this.options.Dir, walCheckpointFile))
+	if err != nil {
+		return
+	}
+	var pieces = strings.Fields(string(data))
+	if len(pieces) != 2 {
+		return
+	}
+	segmentId, _ = strconv.ParseInt(pieces[0], 10, 64)
+	offset, _ = strconv.ParseInt(pieces[1], 10, 64)
+	if segmentId <= 0 || offset < 0 {
+		return 0, 0
+	}
+	return
+}
+
+func (this *WAL) saveCheckpoint() error {
+	this.checkpointSaveAt = time.Now()
+
+	var path = filepath.Join(this.options.Dir, walCheckpointFile)
+	var tmpPath = path + ".tmp"
+	err := os.WriteFile(tmpPath, []byte(strconv.FormatInt(this.readSegmentId, 10)+" "+strconv.FormatInt(this.readOffset, 10)), 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// 定时刷新到磁盘
+func (this *WAL) syncLoop() {
+	var ticker = time.NewTicker(this.options.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			this.locker.Lock()
+			if !this.isClosed {
+				_ = this.activeFile.Sync()
+			}
+			this.locker.Unlock()
+		case <-this.stopChan:
+			return
+		}
+	}
+}
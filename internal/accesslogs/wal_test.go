@@ -0,0 +1,234 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package accesslogs
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func testWALRecords(from int, count int) [][]byte {
+	var records = [][]byte{}
+	for i := from; i < from+count; i++ {
+		records = append(records, []byte("record-"+strconv.Itoa(i)))
+	}
+	return records
+}
+
+func TestWAL_WriteRead(t *testing.T) {
+	wal, err := OpenWAL(&WALOptions{
+		Dir:         t.TempDir(),
+		SegmentSize: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = wal.Close()
+	}()
+
+	count, err := wal.Write(testWALRecords(0, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 20 || wal.Len() != 20 {
+		t.Fatal("expect 20 records, got", count, wal.Len())
+	}
+	if wal.Stat().Segments <= 1 {
+		t.Fatal("expect multiple segments")
+	}
+
+	// 没有确认时重复读取
+	records, err := wal.Read(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 || string(records[0]) != "record-0" {
+		t.Fatal("unexpected records:", len(records))
+	}
+	records, err = wal.Read(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(records[0]) != "record-0" {
+		t.Fatal("should read from last commit")
+	}
+
+	var index = 0
+	for {
+		records, err = wal.Read(7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) == 0 {
+			break
+		}
+		for _, record := range records {
+			if string(record) != "record-"+strconv.Itoa(index) {
+				t.Fatal("unexpected record:", string(record))
+			}
+			index++
+		}
+		err = wal.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if index != 20 {
+		t.Fatal("expect 20 records, got", index)
+	}
+	if wal.Len() != 0 {
+		t.Fatal("expect empty wal, got", wal.Len())
+	}
+	if wal.Stat().Segments != 1 {
+		t.Fatal("read segments should be removed, got", wal.Stat().Segments)
+	}
+}
+
+func TestWAL_Reopen(t *testing.T) {
+	var dir = t.TempDir()
+	wal, err := OpenWAL(&WALOptions{
+		Dir:         dir,
+		SegmentSize: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = wal.Write(testWALRecords(0, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := wal.Read(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatal("expect 3 records")
+	}
+	err = wal.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = wal.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新打开后从确认的位置继续读取
+	wal, err = OpenWAL(&WALOptions{
+		Dir:         dir,
+		SegmentSize: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = wal.Close()
+	}()
+	if wal.Len() != 7 {
+		t.Fatal("expect 7 records, got", wal.Len())
+	}
+	_, err = wal.Write(testWALRecords(10, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err = wal.Read(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 8 || string(records[0]) != "record-3" || string(records[7]) != "record-10" {
+		t.Fatal("unexpected records:", len(records))
+	}
+}
+
+func TestWAL_Corrupted(t *testing.T) {
+	var dir = t.TempDir()
+	wal, err := OpenWAL(&WALOptions{
+		Dir:         dir,
+		SegmentSize: 1 << 20,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = wal.Write(testWALRecords(0, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = wal.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 修改第二条记录，并在末尾加上不完整的记录
+	var segmentFile = filepath.Join(dir, "0000000000000001"+walSegmentExt)
+	data, err := os.ReadFile(segmentFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recordSize = walRecordHeaderSize + len("record-0")
+	data[recordSize+walRecordHeaderSize] = 'R'
+	data = append(data, 1, 2, 3)
+	err = os.WriteFile(segmentFile, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wal, err = OpenWAL(&WALOptions{
+		Dir: dir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = wal.Close()
+	}()
+	_, err = wal.Write(testWALRecords(3, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := wal.Read(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || string(records[0]) != "record-0" || string(records[1]) != "record-3" {
+		t.Fatal("unexpected records:", len(records))
+	}
+	if wal.Stat().CountCorrupted == 0 {
+		t.Fatal("expect corrupted count")
+	}
+	err = wal.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wal.Len() != 0 {
+		t.Fatal("expect empty wal, got", wal.Len())
+	}
+}
+
+func TestWAL_MaxSize(t *testing.T) {
+	wal, err := OpenWAL(&WALOptions{
+		Dir:     t.TempDir(),
+		MaxSize: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = wal.Close()
+	}()
+
+	count, err := wal.Write(testWALRecords(0, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stat = wal.Stat()
+	if count >= 10 || stat.CountDropped != int64(10-count) {
+		t.Fatal("unexpected count:", count, "dropped:", stat.CountDropped)
+	}
+	if stat.Size > 100 {
+		t.Fatal("size should not exceed max size:", stat.Size)
+	}
+}
@@ -16,6 +16,7 @@ import (
 type DNSProvider struct {
 	raw       dnsclients.ProviderInterface
 	dnsDomain string
+	onPresent func(domain string)
 
 	locker             sync.Mutex
 	deletedRecordNames []string
@@ -29,6 +30,10 @@ func NewDNSProvider(raw dnsclients.ProviderInterface, dnsDomain string) *DNSProv
 }
 
 func (this *DNSProvider) Present(domain, token, keyAuth string) error {
+	if this.onPresent != nil {
+		this.onPresent(domain)
+	}
+
 	_ = os.Setenv("LEGO_DISABLE_CNAME_SUPPORT", "true")
 	var info = dns01.GetChallengeInfo(domain, keyAuth)
 
@@ -0,0 +1,70 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package acme
+
+import (
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/iwind/TeaGo/maps"
+)
+
+type KeyType = string
+
+// 证书私钥类型
+const (
+	KeyTypeRSA2048 KeyType = "RSA2048"
+	KeyTypeRSA4096 KeyType = "RSA4096"
+	KeyTypeEC256   KeyType = "EC256"
+	KeyTypeEC384   KeyType = "EC384"
+)
+
+// DefaultKeyType 默认私钥类型
+const DefaultKeyType = KeyTypeRSA2048
+
+// FindAllKeyTypes 所有支持的私钥类型
+func FindAllKeyTypes() []maps.Map {
+	return []maps.Map{
+		{
+			"name":        "RSA 2048",
+			"code":        KeyTypeRSA2048,
+			"description": "兼容性最好的私钥类型。",
+		},
+		{
+			"name":        "RSA 4096",
+			"code":        KeyTypeRSA4096,
+			"description": "更长的RSA私钥，握手时消耗更多的CPU。",
+		},
+		{
+			"name":        "ECDSA P-256",
+			"code":        KeyTypeEC256,
+			"description": "体积更小、握手更快的ECC私钥，一些很老的客户端可能不支持。",
+		},
+		{
+			"name":        "ECDSA P-384",
+			"code":        KeyTypeEC384,
+			"description": "更长的ECC私钥。",
+		},
+	}
+}
+
+// IsValidKeyType 检查私钥类型是否有效
+func IsValidKeyType(keyType KeyType) bool {
+	for _, keyTypeMap := range FindAllKeyTypes() {
+		if keyTypeMap.GetString("code") == keyType {
+			return true
+		}
+	}
+	return false
+}
+
+// 转换为lego中的私钥类型，无法识别时使用默认值
+func certKeyType(keyType KeyType) certcrypto.KeyType {
+	switch keyType {
+	case KeyTypeRSA4096:
+		return certcrypto.RSA4096
+	case KeyTypeEC256:
+		return certcrypto.EC256
+	case KeyTypeEC384:
+		return certcrypto.EC384
+	}
+	return certcrypto.RSA2048
+}
@@ -2,6 +2,12 @@
 
 package acme
 
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/remotelogs"
+	"sync"
+	"time"
+)
+
 const DefaultProviderCode = "letsencrypt"
 
 type Provider struct {
@@ -12,10 +18,84 @@ type Provider struct {
 	TestAPIURL     string `json:"testAPIURL"`
 	RequireEAB     bool   `json:"requireEAB"`
 	EABDescription string `json:"eabDescription"`
+
+	IsCustom       bool   `json:"isCustom"`   // 是否为管理员添加的服务商
+	UseTestAPI     bool   `json:"useTestAPI"` // 是否使用测试环境
+	CACertificates []byte `json:"-"`          // 自定义根证书，用于内部CA
 }
 
-func FindProviderWithCode(code string) *Provider {
+// DirectoryURL 当前使用的目录地址
+func (this *Provider) DirectoryURL() string {
+	if this.UseTestAPI && len(this.TestAPIURL) > 0 {
+		return this.TestAPIURL
+	}
+	return this.APIURL
+}
+
+// CustomProvidersCacheLife 自定义服务商缓存时间
+const CustomProvidersCacheLife = 30 * time.Second
+
+var customProvidersFunc func() ([]*Provider, error)
+var customProviders []*Provider
+var customProvidersCachedAt time.Time
+var customProvidersLocker = &sync.Mutex{}
+
+// SetCustomProvidersFunc 设置读取自定义服务商的函数
+func SetCustomProvidersFunc(f func() ([]*Provider, error)) {
+	customProvidersLocker.Lock()
+	customProvidersFunc = f
+	customProviders = nil
+	customProvidersCachedAt = time.Time{}
+	customProvidersLocker.Unlock()
+}
+
+// ResetCustomProvidersCache 清除自定义服务商缓存
+func ResetCustomProvidersCache() {
+	customProvidersLocker.Lock()
+	customProvidersCachedAt = time.Time{}
+	customProvidersLocker.Unlock()
+}
+
+// FindAllCustomProviders 查找所有自定义服务商
+func FindAllCustomProviders() []*Provider {
+	customProvidersLocker.Lock()
+	defer customProvidersLocker.Unlock()
+
+	if customProvidersFunc == nil {
+		return nil
+	}
+	if time.Since(customProvidersCachedAt) < CustomProvidersCacheLife {
+		return customProviders
+	}
+
+	providers, err := customProvidersFunc()
+	if err != nil {
+		// 读取失败时继续使用之前的缓存
+		remotelogs.Error("ACME", "load custom providers failed: "+err.Error())
+		return customProviders
+	}
+	customProviders = providers
+	customProvidersCachedAt = time.Now()
+	return customProviders
+}
+
+// FindAllAvailableProviders 查找内置和自定义的所有服务商
+func FindAllAvailableProviders() []*Provider {
+	return append(FindAllProviders(), FindAllCustomProviders()...)
+}
+
+// IsBuiltinProviderCode 判断是否为内置服务商代号
+func IsBuiltinProviderCode(code string) bool {
 	for _, provider := range FindAllProviders() {
+		if provider.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func FindProviderWithCode(code string) *Provider {
+	for _, provider := range FindAllAvailableProviders() {
 		if provider.Code == code {
 			return provider
 		}
@@ -0,0 +1,104 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package acme
+
+import (
+	"encoding/pem"
+	"errors"
+	"github.com/iwind/TeaGo/maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFindProviderWithCode_Custom(t *testing.T) {
+	var calls = 0
+	SetCustomProvidersFunc(func() ([]*Provider, error) {
+		calls++
+		return []*Provider{
+			{
+				Name:       "step-ca",
+				Code:       "step-ca",
+				APIURL:     "https://ca.internal/acme/acme/directory",
+				TestAPIURL: "https://ca-test.internal/acme/acme/directory",
+				UseTestAPI: true,
+				IsCustom:   true,
+			},
+		}, nil
+	})
+	defer SetCustomProvidersFunc(nil)
+
+	if FindProviderWithCode(DefaultProviderCode) == nil {
+		t.Fatal("builtin provider should be found")
+	}
+	var provider = FindProviderWithCode("step-ca")
+	if provider == nil {
+		t.Fatal("custom provider should be found")
+	}
+	if provider.DirectoryURL() != "https://ca-test.internal/acme/acme/directory" {
+		t.Fatal("should use test api url:", provider.DirectoryURL())
+	}
+	if len(FindAllAvailableProviders()) != len(FindAllProviders())+1 {
+		t.Fatal("custom providers should be listed with builtin providers")
+	}
+	if calls != 1 {
+		t.Fatal("custom providers should be cached, calls:", calls)
+	}
+
+	// 更换读取函数后不再使用旧的缓存
+	ResetCustomProvidersCache()
+	SetCustomProvidersFunc(func() ([]*Provider, error) {
+		return nil, errors.New("database is down")
+	})
+	if FindProviderWithCode("step-ca") != nil {
+		t.Fatal("cache should be cleared after changing function")
+	}
+	if !IsBuiltinProviderCode(DefaultProviderCode) || IsBuiltinProviderCode("step-ca") {
+		t.Fatal("unexpected builtin code check")
+	}
+}
+
+func TestRequest_CustomCACertificates(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		_, _ = writer.Write(maps.Map{
+			"newNonce":   server.URL + "/new-nonce",
+			"newAccount": server.URL + "/new-account",
+			"newOrder":   server.URL + "/new-order",
+			"revokeCert": server.URL + "/revoke-cert",
+			"keyChange":  server.URL + "/key-change",
+		}.AsJSON())
+	}))
+	defer server.Close()
+
+	// 不信任内部CA
+	var req = NewRequest(&Task{
+		Provider: &Provider{APIURL: server.URL + "/directory"},
+		User:     testACMEUser(t),
+	})
+	_, err := req.newClient()
+	if err == nil {
+		t.Fatal("should fail without custom CA certificates")
+	}
+
+	// 信任内部CA
+	var caCertificates = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	req = NewRequest(&Task{
+		Provider: &Provider{APIURL: server.URL + "/directory", CACertificates: caCertificates},
+		User:     testACMEUser(t),
+	})
+	_, err = req.newClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 无效的根证书
+	req = NewRequest(&Task{
+		Provider: &Provider{APIURL: server.URL + "/directory", CACertificates: []byte("invalid")},
+		User:     testACMEUser(t),
+	})
+	_, err = req.newClient()
+	if err == nil {
+		t.Fatal("should fail with invalid CA certificates")
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package acme
+
+import (
+	"errors"
+	"github.com/go-acme/lego/v4/acme/api"
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/certificate"
+	"math/rand"
+	"time"
+)
+
+// RenewalInfo ACME服务商建议的续期时间窗口（ARI）
+// 参考：https://datatracker.ietf.org/doc/draft-ietf-acme-ari/
+type RenewalInfo struct {
+	WindowStart    time.Time // 窗口开始时间
+	WindowEnd      time.Time // 窗口结束时间
+	ExplanationURL string    // 服务商对此时间窗口的说明
+}
+
+// RandomRenewAt 在时间窗口中随机选择一个续期时间，以便分散对服务商的请求
+func (this *RenewalInfo) RandomRenewAt() time.Time {
+	var window = this.WindowEnd.Sub(this.WindowStart)
+	if window <= 0 {
+		return this.WindowStart
+	}
+	return this.WindowStart.Add(time.Duration(rand.Int63n(int64(window))))
+}
+
+// GetRenewalInfo 查询证书的续期建议
+// 如果ACME服务商不支持ARI，则返回nil
+func (this *Request) GetRenewalInfo(certData []byte) (*RenewalInfo, error) {
+	if this.task.Provider == nil {
+		return nil, errors.New("provider should not be nil")
+	}
+	if this.task.User == nil {
+		return nil, errors.New("'user' must not be nil")
+	}
+
+	cert, err := certcrypto.ParsePEMCertificate(certData)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := this.newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Certificate.GetRenewalInfo(certificate.RenewalInfoRequest{
+		Cert: cert,
+	})
+	if err != nil {
+		if errors.Is(err, api.ErrNoARI) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var window = resp.SuggestedWindow
+	if window.Start.IsZero() || window.End.Before(window.Start) {
+		return nil, errors.New("invalid renewal info window")
+	}
+
+	return &RenewalInfo{
+		WindowStart:    window.Start,
+		WindowEnd:      window.End,
+		ExplanationURL: resp.ExplanationURL,
+	}, nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package acme
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/registration"
+	"github.com/iwind/TeaGo/maps"
+	"github.com/iwind/TeaGo/types"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 模拟ACME服务商的目录和ARI接口
+func testACMEServer(t *testing.T, supportsARI bool) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path == "/directory":
+			var directory = maps.Map{
+				"newNonce":   server.URL + "/new-nonce",
+				"newAccount": server.URL + "/new-account",
+				"newOrder":   server.URL + "/new-order",
+				"revokeCert": server.URL + "/revoke-cert",
+				"keyChange":  server.URL + "/key-change",
+			}
+			if supportsARI {
+				directory["renewalInfo"] = server.URL + "/renewal-info"
+			}
+			_, _ = writer.Write(directory.AsJSON())
+		case strings.HasPrefix(req.URL.Path, "/renewal-info/"):
+			_, _ = writer.Write([]byte(`{"suggestedWindow": {"start": "2024-03-01T00:00:00Z", "end": "2024-03-03T00:00:00Z"}, "explanationURL": "https://example.com/ari"}`))
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func testACMEUser(t *testing.T) *User {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewUser("test@example.com", privateKey, func(resource *registration.Resource) error {
+		return nil
+	})
+}
+
+func testCertData(t *testing.T) []byte {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var template = &x509.Certificate{
+		SerialNumber:   big.NewInt(1024),
+		Subject:        pkix.Name{CommonName: "example.com"},
+		DNSNames:       []string{"example.com"},
+		NotBefore:      time.Now(),
+		NotAfter:       time.Now().Add(90 * 24 * time.Hour),
+		AuthorityKeyId: []byte{1, 2, 3, 4},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, privateKey.Public(), privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+}
+
+func TestRequest_GetRenewalInfo(t *testing.T) {
+	var server = testACMEServer(t, true)
+	var req = NewRequest(&Task{
+		Provider: &Provider{APIURL: server.URL + "/directory"},
+		User:     testACMEUser(t),
+	})
+	info, err := req.GetRenewalInfo(testCertData(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info == nil {
+		t.Fatal("renewal info should not be nil")
+	}
+	if info.WindowStart.Format(time.RFC3339) != "2024-03-01T00:00:00Z" || info.WindowEnd.Format(time.RFC3339) != "2024-03-03T00:00:00Z" {
+		t.Fatal("unexpected window:", info.WindowStart, info.WindowEnd)
+	}
+	if info.ExplanationURL != "https://example.com/ari" {
+		t.Fatal("unexpected explanation url:", info.ExplanationURL)
+	}
+
+	for i := 0; i < 10; i++ {
+		var renewAt = info.RandomRenewAt()
+		if renewAt.Before(info.WindowStart) || renewAt.After(info.WindowEnd) {
+			t.Fatal("renew time out of window:", renewAt)
+		}
+	}
+}
+
+func TestRequest_GetRenewalInfo_NoARI(t *testing.T) {
+	var server = testACMEServer(t, false)
+	var req = NewRequest(&Task{
+		Provider: &Provider{APIURL: server.URL + "/directory"},
+		User:     testACMEUser(t),
+	})
+	info, err := req.GetRenewalInfo(testCertData(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info != nil {
+		t.Fatal("renewal info should be nil if server does not support ARI")
+	}
+}
+
+func TestIsValidKeyType(t *testing.T) {
+	for _, keyType := range []KeyType{KeyTypeRSA2048, KeyTypeRSA4096, KeyTypeEC256, KeyTypeEC384} {
+		if !IsValidKeyType(keyType) {
+			t.Fatal("key type '" + keyType + "' should be valid")
+		}
+	}
+	for _, keyType := range []KeyType{"", "Ed25519", "rsa2048"} {
+		if IsValidKeyType(keyType) {
+			t.Fatal("key type '" + keyType + "' should be invalid")
+		}
+	}
+	if certKeyType("") != certcrypto.RSA2048 {
+		t.Fatal("default key type should be RSA2048")
+	}
+}
+
+// 需要本地运行Pebble：
+//
+//	PEBBLE_VA_ALWAYS_VALID=1 pebble -config test/config/pebble-config.json
+//	LEGO_CA_CERTIFICATES=/path/to/pebble.minica.pem PEBBLE_DIRECTORY_URL=https://localhost:14000/dir go test -run TestRequest_Run_Pebble
+func TestRequest_Run_Pebble(t *testing.T) {
+	var directoryURL = os.Getenv("PEBBLE_DIRECTORY_URL")
+	if len(directoryURL) == 0 {
+		t.Skip("PEBBLE_DIRECTORY_URL is not set")
+	}
+
+	for _, keyType := range []KeyType{KeyTypeRSA2048, KeyTypeRSA4096, KeyTypeEC256, KeyTypeEC384} {
+		var req = NewRequest(&Task{
+			Provider: &Provider{Code: "pebble", APIURL: directoryURL},
+			User:     testACMEUser(t),
+			AuthType: AuthTypeHTTP,
+			Domains:  []string{"example.test"},
+			KeyType:  keyType,
+		})
+		certData, keyData, err := req.Run()
+		if err != nil {
+			t.Fatal(keyType, err)
+		}
+
+		privateKey, err := certcrypto.ParsePEMPrivateKey(keyData)
+		if err != nil {
+			t.Fatal(keyType, err)
+		}
+		var bits int
+		switch key := privateKey.(type) {
+		case *rsa.PrivateKey:
+			bits = key.N.BitLen()
+		case *ecdsa.PrivateKey:
+			bits = key.Curve.Params().BitSize
+		}
+		if !strings.HasSuffix(keyType, types.String(bits)) {
+			t.Fatal("key type '"+keyType+"' mismatch, got bits:", bits)
+		}
+
+		info, err := req.GetRenewalInfo(certData)
+		if err != nil {
+			t.Fatal(keyType, err)
+		}
+		if info != nil {
+			t.Log(keyType, "renewal window:", info.WindowStart, "-", info.WindowEnd)
+		}
+	}
+}
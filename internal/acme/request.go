@@ -1,29 +1,40 @@
 package acme
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	teaconst "github.com/dashenmiren/EdgeAPI/internal/const"
 	"github.com/dashenmiren/EdgeAPI/internal/errors"
-	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/acme/api"
 	"github.com/go-acme/lego/v4/certificate"
+	"github.com/go-acme/lego/v4/challenge/resolver"
 	"github.com/go-acme/lego/v4/lego"
 	acmelog "github.com/go-acme/lego/v4/log"
 	"github.com/go-acme/lego/v4/registration"
 	"github.com/iwind/TeaGo/Tea"
 	"io"
 	"log"
+	"net/http"
+	"sync"
 )
 
 type Request struct {
 	debug bool
 
-	task   *Task
-	onAuth AuthCallback
+	task          *Task
+	onAuth        AuthCallback
+	onTLSALPNAuth TLSALPNAuthCallback
+
+	challenges       map[string]AuthType
+	challengesLocker sync.Mutex
 }
 
 func NewRequest(task *Task) *Request {
 	return &Request{
-		task: task,
+		task:       task,
+		challenges: map[string]AuthType{},
 	}
 }
 
@@ -35,6 +46,24 @@ func (this *Request) OnAuth(onAuth AuthCallback) {
 	this.onAuth = onAuth
 }
 
+// OnTLSALPNAuth 设置TLS-ALPN-01认证证书回调
+func (this *Request) OnTLSALPNAuth(onAuth TLSALPNAuthCallback) {
+	this.onTLSALPNAuth = onAuth
+}
+
+// Challenges 本次申请中实际完成认证的域名及其认证方式
+// 服务商复用了之前有效的授权时，对应的域名不会出现在结果中
+func (this *Request) Challenges() map[string]AuthType {
+	this.challengesLocker.Lock()
+	defer this.challengesLocker.Unlock()
+
+	var result = map[string]AuthType{}
+	for domain, authType := range this.challenges {
+		result[domain] = authType
+	}
+	return result
+}
+
 func (this *Request) Run() (certData []byte, keyData []byte, err error) {
 	if this.task.Provider == nil {
 		err = errors.New("provider should not be nil")
@@ -45,18 +74,19 @@ func (this *Request) Run() (certData []byte, keyData []byte, err error) {
 		return
 	}
 
-	switch this.task.AuthType {
-	case AuthTypeDNS:
-		return this.runDNS()
-	case AuthTypeHTTP:
-		return this.runHTTP()
-	default:
+	if !IsValidAuthType(this.task.AuthType) {
 		err = errors.New("invalid task type '" + this.task.AuthType + "'")
 		return
 	}
+	err = this.task.ValidateAuthTypes()
+	if err != nil {
+		return
+	}
+
+	return this.run()
 }
 
-func (this *Request) runDNS() (certData []byte, keyData []byte, err error) {
+func (this *Request) run() (certData []byte, keyData []byte, err error) {
 	if !this.debug {
 		if !Tea.IsTesting() {
 			acmelog.Logger = log.New(io.Discard, "", log.LstdFlags)
@@ -67,65 +97,51 @@ func (this *Request) runDNS() (certData []byte, keyData []byte, err error) {
 		err = errors.New("'user' must not be nil")
 		return
 	}
-	if this.task.DNSProvider == nil {
-		err = errors.New("'dnsProvider' must not be nil")
-		return
-	}
-	if len(this.task.DNSDomain) == 0 {
-		err = errors.New("'dnsDomain' must not be empty")
-		return
-	}
 	if len(this.task.Domains) == 0 {
 		err = errors.New("'domains' must not be empty")
 		return
 	}
 
-	var config = lego.NewConfig(this.task.User)
-	config.Certificate.KeyType = certcrypto.RSA2048
-	config.CADirURL = this.task.Provider.APIURL
-	config.UserAgent = teaconst.ProductName + "/" + teaconst.Version
+	var authTypes = this.task.AuthTypes()
+	for _, authType := range authTypes {
+		if authType == AuthTypeDNS {
+			if this.task.DNSProvider == nil {
+				err = errors.New("'dnsProvider' must not be nil")
+				return
+			}
+			if len(this.task.DNSDomain) == 0 {
+				err = errors.New("'dnsDomain' must not be empty")
+				return
+			}
+		}
+	}
 
-	client, err := lego.NewClient(config)
+	client, err := this.newClient()
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// 注册用户
-	var resource = this.task.User.GetRegistration()
-	if resource != nil {
-		_, err = client.Registration.QueryRegistration()
-		if err != nil {
-			return nil, nil, err
-		}
-	} else {
-		if this.task.Provider.RequireEAB {
-			resource, err = client.Registration.RegisterWithExternalAccountBinding(registration.RegisterEABOptions{
-				TermsOfServiceAgreed: true,
-				Kid:                  this.task.Account.EABKid,
-				HmacEncoded:          this.task.Account.EABKey,
-			})
-			if err != nil {
-				return nil, nil, fmt.Errorf("register user failed: %w", err)
-			}
-			err = this.task.User.Register(resource)
-			if err != nil {
-				return nil, nil, err
-			}
-		} else {
-			resource, err = client.Registration.Register(registration.RegisterOptions{TermsOfServiceAgreed: true})
-			if err != nil {
-				return nil, nil, err
-			}
-			err = this.task.User.Register(resource)
+	err = this.register(client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 混合认证方式时，先按认证方式分组完成域名授权，之后申请证书时服务商会复用这些授权
+	if len(authTypes) > 1 {
+		for _, authType := range authTypes {
+			err = this.authorize(authType, this.task.DomainsWithAuthType(authType))
 			if err != nil {
-				return nil, nil, err
+				return nil, nil, fmt.Errorf("authorize with '%s' failed: %w", authType, err)
 			}
 		}
 	}
 
-	err = client.Challenge.SetDNS01Provider(NewDNSProvider(this.task.DNSProvider, this.task.DNSDomain))
-	if err != nil {
-		return nil, nil, err
+	for _, authType := range authTypes {
+		err = this.setChallengeProvider(client.Challenge, authType)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
 	// 申请证书
@@ -141,75 +157,161 @@ func (this *Request) runDNS() (certData []byte, keyData []byte, err error) {
 	return certResource.Certificate, certResource.PrivateKey, nil
 }
 
-func (this *Request) runHTTP() (certData []byte, keyData []byte, err error) {
-	if !this.debug {
-		if !Tea.IsTesting() {
-			acmelog.Logger = log.New(io.Discard, "", log.LstdFlags)
-		}
+// 注册用户
+func (this *Request) register(client *lego.Client) error {
+	var resource = this.task.User.GetRegistration()
+	if resource != nil {
+		_, err := client.Registration.QueryRegistration()
+		return err
 	}
 
-	if this.task.User == nil {
-		err = errors.New("'user' must not be nil")
-		return
+	var err error
+	if this.task.Provider.RequireEAB {
+		resource, err = client.Registration.RegisterWithExternalAccountBinding(registration.RegisterEABOptions{
+			TermsOfServiceAgreed: true,
+			Kid:                  this.task.Account.EABKid,
+			HmacEncoded:          this.task.Account.EABKey,
+		})
+		if err != nil {
+			return fmt.Errorf("register user failed: %w", err)
+		}
+	} else {
+		resource, err = client.Registration.Register(registration.RegisterOptions{TermsOfServiceAgreed: true})
+		if err != nil {
+			return err
+		}
 	}
+	return this.task.User.Register(resource)
+}
 
-	var config = lego.NewConfig(this.task.User)
-	config.Certificate.KeyType = certcrypto.RSA2048
-	config.CADirURL = this.task.Provider.APIURL
-	config.UserAgent = teaconst.ProductName + "/" + teaconst.Version
-
-	client, err := lego.NewClient(config)
+// 使用单一认证方式完成一组域名的授权
+func (this *Request) authorize(authType AuthType, domains []string) error {
+	config, err := this.newConfig()
 	if err != nil {
-		return nil, nil, err
+		return err
 	}
-
-	// 注册用户
+	var kid string
 	var resource = this.task.User.GetRegistration()
 	if resource != nil {
-		_, err = client.Registration.QueryRegistration()
+		kid = resource.URI
+	}
+	core, err := api.New(config.HTTPClient, config.UserAgent, config.CADirURL, kid, this.task.User.GetPrivateKey())
+	if err != nil {
+		return err
+	}
+
+	var solversManager = resolver.NewSolversManager(core)
+	err = this.setChallengeProvider(solversManager, authType)
+	if err != nil {
+		return err
+	}
+
+	order, err := core.Orders.New(domains)
+	if err != nil {
+		return err
+	}
+	var authorizations = []acme.Authorization{}
+	for _, authzURL := range order.Authorizations {
+		authz, err := core.Authorizations.Get(authzURL)
 		if err != nil {
-			return nil, nil, err
+			return err
 		}
-	} else {
-		if this.task.Provider.RequireEAB {
-			resource, err = client.Registration.RegisterWithExternalAccountBinding(registration.RegisterEABOptions{
-				TermsOfServiceAgreed: true,
-				Kid:                  this.task.Account.EABKid,
-				HmacEncoded:          this.task.Account.EABKey,
-			})
-			if err != nil {
-				return nil, nil, fmt.Errorf("register user failed: %w", err)
+		authorizations = append(authorizations, authz)
+	}
+	return resolver.NewProber(solversManager).Solve(authorizations)
+}
+
+// 设置认证方式对应的Provider
+func (this *Request) setChallengeProvider(solversManager *resolver.SolverManager, authType AuthType) error {
+	switch authType {
+	case AuthTypeDNS:
+		var dnsProvider = NewDNSProvider(this.task.DNSProvider, this.task.DNSDomain)
+		dnsProvider.onPresent = func(domain string) {
+			this.addChallenge(domain, AuthTypeDNS)
+		}
+		return solversManager.SetDNS01Provider(dnsProvider)
+	case AuthTypeHTTP:
+		return solversManager.SetHTTP01Provider(NewHTTPProvider(func(domain, token, keyAuth string) {
+			this.addChallenge(domain, AuthTypeHTTP)
+			if this.onAuth != nil {
+				this.onAuth(domain, token, keyAuth)
 			}
-			err = this.task.User.Register(resource)
-			if err != nil {
-				return nil, nil, err
+		}))
+	case AuthTypeTLSALPN:
+		return solversManager.SetTLSALPN01Provider(NewTLSALPNProvider(func(domain string, certData []byte, keyData []byte) {
+			if len(certData) > 0 {
+				this.addChallenge(domain, AuthTypeTLSALPN)
 			}
-		} else {
-			resource, err = client.Registration.Register(registration.RegisterOptions{TermsOfServiceAgreed: true})
-			if err != nil {
-				return nil, nil, err
-			}
-			err = this.task.User.Register(resource)
-			if err != nil {
-				return nil, nil, err
+			if this.onTLSALPNAuth != nil {
+				this.onTLSALPNAuth(domain, certData, keyData)
 			}
+		}))
+	}
+	return errors.New("invalid auth type '" + authType + "'")
+}
+
+func (this *Request) addChallenge(domain string, authType AuthType) {
+	this.challengesLocker.Lock()
+	this.challenges[domain] = authType
+	this.challengesLocker.Unlock()
+}
+
+// 客户端配置
+func (this *Request) newConfig() (*lego.Config, error) {
+	var config = lego.NewConfig(this.task.User)
+	config.Certificate.KeyType = certKeyType(this.task.KeyType)
+	config.CADirURL = this.task.Provider.DirectoryURL()
+	config.UserAgent = teaconst.ProductName + "/" + teaconst.Version
+
+	// 自定义根证书
+	if len(this.task.Provider.CACertificates) > 0 {
+		httpClient, err := newHTTPClientWithCAs(config.HTTPClient, this.task.Provider.CACertificates)
+		if err != nil {
+			return nil, err
 		}
+		config.HTTPClient = httpClient
 	}
 
-	err = client.Challenge.SetHTTP01Provider(NewHTTPProvider(this.onAuth))
+	return config, nil
+}
+
+// 创建客户端
+func (this *Request) newClient() (*lego.Client, error) {
+	config, err := this.newConfig()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	return lego.NewClient(config)
+}
+
+// 在默认HTTP客户端的基础上信任额外的根证书
+func newHTTPClientWithCAs(client *http.Client, caCertificates []byte) (*http.Client, error) {
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("unsupported http transport")
+	}
+	transport = transport.Clone()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
 	}
 
-	// 申请证书
-	var request = certificate.ObtainRequest{
-		Domains: this.task.Domains,
-		Bundle:  true,
+	var certPool = transport.TLSClientConfig.RootCAs
+	if certPool == nil {
+		systemPool, err := x509.SystemCertPool()
+		if err != nil {
+			systemPool = x509.NewCertPool()
+		}
+		certPool = systemPool
+	} else {
+		certPool = certPool.Clone()
 	}
-	certResource, err := client.Certificate.Obtain(request)
-	if err != nil {
-		return nil, nil, err
+	if !certPool.AppendCertsFromPEM(caCertificates) {
+		return nil, errors.New("invalid CA certificates")
 	}
+	transport.TLSClientConfig.RootCAs = certPool
 
-	return certResource.Certificate, certResource.PrivateKey, nil
+	return &http.Client{
+		Timeout:   client.Timeout,
+		Transport: transport,
+	}, nil
 }
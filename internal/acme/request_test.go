@@ -78,7 +78,7 @@ func TestRequest_Run_HTTP(t *testing.T) {
 		AuthType: AuthTypeHTTP,
 		Domains:  []string{"teaos.cn", "www.teaos.cn", "meloy.cn"},
 	})
-	certData, keyData, err := req.runHTTP()
+	certData, keyData, err := req.Run()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,83 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package acme
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/iwind/TeaGo/maps"
+)
+
+type RevokeReason = uint
+
+// 吊销原因，参考RFC 5280 5.3.1，只列出ACME服务商允许的原因
+const (
+	RevokeReasonUnspecified          RevokeReason = acme.CRLReasonUnspecified
+	RevokeReasonKeyCompromise        RevokeReason = acme.CRLReasonKeyCompromise
+	RevokeReasonAffiliationChanged   RevokeReason = acme.CRLReasonAffiliationChanged
+	RevokeReasonSuperseded           RevokeReason = acme.CRLReasonSuperseded
+	RevokeReasonCessationOfOperation RevokeReason = acme.CRLReasonCessationOfOperation
+)
+
+// FindAllRevokeReasons 所有吊销原因
+func FindAllRevokeReasons() []maps.Map {
+	return []maps.Map{
+		{
+			"name":        "未指定",
+			"code":        RevokeReasonUnspecified,
+			"description": "不说明具体的吊销原因。",
+		},
+		{
+			"name":        "私钥泄露",
+			"code":        RevokeReasonKeyCompromise,
+			"description": "证书私钥已经泄露或者可能已经泄露。",
+		},
+		{
+			"name":        "信息变更",
+			"code":        RevokeReasonAffiliationChanged,
+			"description": "证书中的域名或者相关信息已经变更。",
+		},
+		{
+			"name":        "已被替代",
+			"code":        RevokeReasonSuperseded,
+			"description": "证书已经被新的证书替代。",
+		},
+		{
+			"name":        "停止使用",
+			"code":        RevokeReasonCessationOfOperation,
+			"description": "证书中的域名已经不再使用。",
+		},
+	}
+}
+
+// IsValidRevokeReason 检查吊销原因是否有效
+func IsValidRevokeReason(reason RevokeReason) bool {
+	for _, reasonMap := range FindAllRevokeReasons() {
+		if reasonMap.GetUint("code") == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// Revoke 通过ACME服务商吊销证书
+func (this *Request) Revoke(certData []byte, reason RevokeReason) error {
+	if this.task.Provider == nil {
+		return errors.New("provider should not be nil")
+	}
+	if this.task.User == nil {
+		return errors.New("'user' must not be nil")
+	}
+	if this.task.User.GetRegistration() == nil {
+		return errors.New("'user' has not been registered")
+	}
+	if !IsValidRevokeReason(reason) {
+		return errors.New("invalid revoke reason")
+	}
+
+	client, err := this.newClient()
+	if err != nil {
+		return err
+	}
+	return client.Certificate.RevokeWithReason(certData, &reason)
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package acme
+
+import (
+	"os"
+	"testing"
+)
+
+func TestIsValidRevokeReason(t *testing.T) {
+	for _, reason := range []RevokeReason{RevokeReasonUnspecified, RevokeReasonKeyCompromise, RevokeReasonAffiliationChanged, RevokeReasonSuperseded, RevokeReasonCessationOfOperation} {
+		if !IsValidRevokeReason(reason) {
+			t.Fatal("reason", reason, "should be valid")
+		}
+	}
+
+	// CA不接受的原因
+	for _, reason := range []RevokeReason{2, 6, 7, 8, 9, 10} {
+		if IsValidRevokeReason(reason) {
+			t.Fatal("reason", reason, "should be invalid")
+		}
+	}
+}
+
+func TestRequest_Revoke_NotRegistered(t *testing.T) {
+	var req = NewRequest(&Task{
+		Provider: &Provider{APIURL: "https://localhost/directory"},
+		User:     testACMEUser(t),
+	})
+	err := req.Revoke(testCertData(t), RevokeReasonKeyCompromise)
+	if err == nil {
+		t.Fatal("should fail if user has not been registered")
+	}
+}
+
+// 需要本地运行Pebble，参考 TestRequest_Run_Pebble
+func TestRequest_Revoke_Pebble(t *testing.T) {
+	var directoryURL = os.Getenv("PEBBLE_DIRECTORY_URL")
+	if len(directoryURL) == 0 {
+		t.Skip("PEBBLE_DIRECTORY_URL is not set")
+	}
+
+	var req = NewRequest(&Task{
+		Provider: &Provider{Code: "pebble", APIURL: directoryURL},
+		User:     testACMEUser(t),
+		AuthType: AuthTypeHTTP,
+		Domains:  []string{"revoke.example.test"},
+	})
+	certData, _, err := req.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = req.Revoke(certData, RevokeReasonKeyCompromise)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 不能重复吊销
+	err = req.Revoke(certData, RevokeReasonKeyCompromise)
+	if err == nil {
+		t.Fatal("revoke twice should fail")
+	}
+}
@@ -1,22 +1,93 @@
 package acme
 
-import "github.com/dashenmiren/EdgeAPI/internal/dnsclients"
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/dnsclients"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"strings"
+)
 
 type AuthType = string
 
 const (
-	AuthTypeDNS  AuthType = "dns"
-	AuthTypeHTTP AuthType = "http"
+	AuthTypeDNS     AuthType = "dns"
+	AuthTypeHTTP    AuthType = "http"
+	AuthTypeTLSALPN AuthType = "tls-alpn"
 )
 
+// IsValidAuthType 检查认证方式是否有效
+func IsValidAuthType(authType AuthType) bool {
+	switch authType {
+	case AuthTypeDNS, AuthTypeHTTP, AuthTypeTLSALPN:
+		return true
+	}
+	return false
+}
+
 type Task struct {
-	Provider *Provider
-	Account  *Account
-	User     *User
-	AuthType AuthType
-	Domains  []string
+	Provider        *Provider
+	Account         *Account
+	User            *User
+	AuthType        AuthType
+	DomainAuthTypes map[string]AuthType // 单个域名使用的认证方式，没有设置的域名使用AuthType
+	Domains         []string
+	KeyType         KeyType // 证书私钥类型，为空时使用默认值
 
 	// DNS相关
 	DNSProvider dnsclients.ProviderInterface
 	DNSDomain   string
 }
+
+// DomainAuthType 取得某个域名使用的认证方式
+func (this *Task) DomainAuthType(domain string) AuthType {
+	authType, ok := this.DomainAuthTypes[domain]
+	if ok && len(authType) > 0 {
+		return authType
+	}
+	return this.AuthType
+}
+
+// AuthTypes 按域名顺序列出任务用到的所有认证方式
+func (this *Task) AuthTypes() []AuthType {
+	var result = []AuthType{}
+	for _, domain := range this.Domains {
+		var authType = this.DomainAuthType(domain)
+		var exists = false
+		for _, existAuthType := range result {
+			if existAuthType == authType {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			result = append(result, authType)
+		}
+	}
+	return result
+}
+
+// DomainsWithAuthType 使用某个认证方式的所有域名
+func (this *Task) DomainsWithAuthType(authType AuthType) []string {
+	var result = []string{}
+	for _, domain := range this.Domains {
+		if this.DomainAuthType(domain) == authType {
+			result = append(result, domain)
+		}
+	}
+	return result
+}
+
+// ValidateAuthTypes 检查每个域名的认证方式
+func (this *Task) ValidateAuthTypes() error {
+	for _, domain := range this.Domains {
+		var authType = this.DomainAuthType(domain)
+		if !IsValidAuthType(authType) {
+			return errors.New("invalid auth type '" + authType + "' for domain '" + domain + "'")
+		}
+
+		// 泛域名只能通过DNS认证
+		if strings.HasPrefix(domain, "*.") && authType != AuthTypeDNS {
+			return errors.New("wildcard domain '" + domain + "' can only be validated with DNS")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package acme
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+)
+
+func TestTask_AuthTypes(t *testing.T) {
+	var task = &Task{
+		AuthType: AuthTypeHTTP,
+		DomainAuthTypes: map[string]AuthType{
+			"*.example.com":   AuthTypeDNS,
+			"api.example.com": AuthTypeTLSALPN,
+		},
+		Domains: []string{"example.com", "*.example.com", "api.example.com", "www.example.com"},
+	}
+	err := task.ValidateAuthTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var authTypes = task.AuthTypes()
+	if strings.Join(authTypes, ",") != "http,dns,tls-alpn" {
+		t.Fatal("unexpected auth types:", authTypes)
+	}
+	if strings.Join(task.DomainsWithAuthType(AuthTypeHTTP), ",") != "example.com,www.example.com" {
+		t.Fatal("unexpected http domains:", task.DomainsWithAuthType(AuthTypeHTTP))
+	}
+	if task.DomainAuthType("*.example.com") != AuthTypeDNS {
+		t.Fatal("wildcard domain should use dns")
+	}
+}
+
+func TestTask_ValidateAuthTypes(t *testing.T) {
+	// 泛域名只能使用DNS认证
+	var task = &Task{
+		AuthType: AuthTypeHTTP,
+		Domains:  []string{"example.com", "*.example.com"},
+	}
+	if task.ValidateAuthTypes() == nil {
+		t.Fatal("wildcard domain with http auth should be invalid")
+	}
+
+	task.DomainAuthTypes = map[string]AuthType{"example.com": "smtp"}
+	task.Domains = []string{"example.com"}
+	if task.ValidateAuthTypes() == nil {
+		t.Fatal("unknown auth type should be invalid")
+	}
+}
+
+func TestTLSALPNProvider_Present(t *testing.T) {
+	var presentedDomain string
+	var presentedCertData []byte
+	var cleaned bool
+	var provider = NewTLSALPNProvider(func(domain string, certData []byte, keyData []byte) {
+		if len(certData) == 0 {
+			cleaned = true
+			return
+		}
+		presentedDomain = domain
+		presentedCertData = certData
+		if len(keyData) == 0 {
+			t.Fatal("key data should not be empty")
+		}
+	})
+	provider.wait = 0
+
+	err := provider.Present("example.com", "token", "keyAuth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if presentedDomain != "example.com" {
+		t.Fatal("unexpected domain:", presentedDomain)
+	}
+
+	block, _ := pem.Decode(presentedCertData)
+	if block == nil {
+		t.Fatal("invalid cert data")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cert.DNSNames) != 1 || cert.DNSNames[0] != "example.com" {
+		t.Fatal("unexpected dns names:", cert.DNSNames)
+	}
+
+	// acmeIdentifier扩展
+	var hasIdentifier = false
+	for _, extension := range cert.Extensions {
+		if extension.Id.String() == "1.3.6.1.5.5.7.1.31" {
+			hasIdentifier = true
+		}
+	}
+	if !hasIdentifier {
+		t.Fatal("challenge cert should contain acmeIdentifier extension")
+	}
+
+	err = provider.CleanUp("example.com", "token", "keyAuth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cleaned {
+		t.Fatal("clean up callback should be called")
+	}
+}
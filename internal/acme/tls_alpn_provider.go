@@ -0,0 +1,52 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package acme
+
+import (
+	"github.com/go-acme/lego/v4/challenge/tlsalpn01"
+	"time"
+)
+
+// TLSALPNPropagationWait 等待认证证书下发到边缘节点的时间，需要覆盖集群任务分解和节点同步的间隔
+var TLSALPNPropagationWait = 20 * time.Second
+
+// TLSALPNAuthCallback TLS-ALPN-01认证回调
+// certData和keyData为空时表示认证已结束，可以删除认证证书
+type TLSALPNAuthCallback func(domain string, certData []byte, keyData []byte)
+
+// TLSALPNProvider TLS-ALPN-01认证
+// 认证证书通过回调函数保存并下发到边缘节点，由边缘节点在acme-tls/1握手中使用
+type TLSALPNProvider struct {
+	onAuth TLSALPNAuthCallback
+	wait   time.Duration
+}
+
+func NewTLSALPNProvider(onAuth TLSALPNAuthCallback) *TLSALPNProvider {
+	return &TLSALPNProvider{
+		onAuth: onAuth,
+		wait:   TLSALPNPropagationWait,
+	}
+}
+
+func (this *TLSALPNProvider) Present(domain, token, keyAuth string) error {
+	certData, keyData, err := tlsalpn01.ChallengeBlocks(domain, keyAuth)
+	if err != nil {
+		return err
+	}
+	if this.onAuth != nil {
+		this.onAuth(domain, certData, keyData)
+	}
+
+	// 等待边缘节点更新认证证书
+	if this.wait > 0 {
+		time.Sleep(this.wait)
+	}
+	return nil
+}
+
+func (this *TLSALPNProvider) CleanUp(domain, token, keyAuth string) error {
+	if this.onAuth != nil {
+		this.onAuth(domain, nil, nil)
+	}
+	return nil
+}
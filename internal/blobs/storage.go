@@ -0,0 +1,87 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package blobs
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// 存储类型
+const (
+	StorageTypeDB   = "db"   // 数据库，保存在 edgeFileChunks.data 中
+	StorageTypeFile = "file" // 本地文件系统
+	StorageTypeS3   = "s3"   // S3兼容的对象存储
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Storage 文件内容存储
+// 对象键使用 / 分隔，例如 files/1/2，参考 ChunkKey()
+type Storage interface {
+	// Type 存储类型
+	Type() string
+
+	// Put 写入对象，size 为内容长度，已经存在的对象会被覆盖
+	Put(key string, reader io.Reader, size int64) error
+
+	// Open 打开对象用来读取，对象不存在时返回 ErrNotFound
+	Open(key string) (io.ReadCloser, error)
+
+	// Delete 删除对象，对象不存在时不返回错误
+	Delete(key string) error
+}
+
+// IsValidStorageType 判断存储类型是否有效
+func IsValidStorageType(storageType string) bool {
+	switch storageType {
+	case StorageTypeDB, StorageTypeFile, StorageTypeS3:
+		return true
+	}
+	return false
+}
+
+// ChunkKey 文件片段的对象键
+func ChunkKey(fileId int64, chunkId int64) string {
+	return "files/" + strconv.FormatInt(fileId, 10) + "/" + strconv.FormatInt(chunkId, 10)
+}
+
+// ParseChunkKey 从对象键中解析文件ID和片段ID
+func ParseChunkKey(key string) (fileId int64, chunkId int64, ok bool) {
+	var pieces = strings.Split(key, "/")
+	if len(pieces) != 3 || pieces[0] != "files" {
+		return 0, 0, false
+	}
+	fileId, err := strconv.ParseInt(pieces[1], 10, 64)
+	if err != nil || fileId <= 0 {
+		return 0, 0, false
+	}
+	chunkId, err = strconv.ParseInt(pieces[2], 10, 64)
+	if err != nil || chunkId <= 0 {
+		return 0, 0, false
+	}
+	return fileId, chunkId, true
+}
+
+// 校验对象键，防止写入到存储目录之外
+func validateKey(key string) error {
+	if len(key) == 0 || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return errors.New("invalid key '" + key + "'")
+	}
+	for _, piece := range strings.Split(key, "/") {
+		if len(piece) == 0 || piece == "." || piece == ".." {
+			return errors.New("invalid key '" + key + "'")
+		}
+	}
+	return nil
+}
+
+// 检查写入的长度是否和声明的一致
+func checkSize(key string, written int64, size int64) error {
+	if size >= 0 && written != size {
+		return errors.New("write '" + key + "' failed: expect " + strconv.FormatInt(size, 10) + " bytes, but got " + strconv.FormatInt(written, 10))
+	}
+	return nil
+}
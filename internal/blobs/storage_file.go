@@ -0,0 +1,117 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package blobs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FileStorage 本地文件系统存储
+// 有多个API节点时，存储目录需要是所有节点都能访问的共享目录
+type FileStorage struct {
+	dir string
+}
+
+func NewFileStorage(dir string) (*FileStorage, error) {
+	if len(dir) == 0 {
+		return nil, errors.New("'dir' should not be empty")
+	}
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &FileStorage{dir: dir}, nil
+}
+
+func (this *FileStorage) Type() string {
+	return StorageTypeFile
+}
+
+// Put 写入对象
+// 先写入临时文件再改名，避免读取到不完整的内容
+func (this *FileStorage) Put(key string, reader io.Reader, size int64) error {
+	path, err := this.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	fp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	var tmpPath = fp.Name()
+	var isOk = false
+	defer func() {
+		if !isOk {
+			_ = fp.Close()
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	written, err := io.Copy(fp, reader)
+	if err != nil {
+		return err
+	}
+	err = checkSize(key, written, size)
+	if err != nil {
+		return err
+	}
+	err = fp.Sync()
+	if err != nil {
+		return err
+	}
+	err = fp.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		return err
+	}
+	isOk = true
+	return nil
+}
+
+// Open 打开对象
+func (this *FileStorage) Open(key string) (io.ReadCloser, error) {
+	path, err := this.path(key)
+	if err != nil {
+		return nil, err
+	}
+	fp, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return fp, nil
+}
+
+// Delete 删除对象
+func (this *FileStorage) Delete(key string) error {
+	path, err := this.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (this *FileStorage) path(key string) (string, error) {
+	err := validateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(this.dir, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package blobs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileStorage_PutOpenDelete(t *testing.T) {
+	var dir = t.TempDir()
+	storage, err := NewFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var key = ChunkKey(1, 2)
+	err = storage.Put(key, strings.NewReader("hello world"), 11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "files", "1", "2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello world" {
+		t.Fatal("unexpected data:", string(data))
+	}
+
+	// 长度不一致时不会覆盖已有内容
+	err = storage.Put(key, strings.NewReader("hello"), 11)
+	if err == nil {
+		t.Fatal("size mismatch should fail")
+	}
+	reader, err := storage.Open(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ = io.ReadAll(reader)
+	_ = reader.Close()
+	if string(data) != "hello world" {
+		t.Fatal("unexpected data:", string(data))
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "files", "1", "*.tmp"))
+	if len(matches) > 0 {
+		t.Fatal("temporary files should be removed:", matches)
+	}
+
+	err = storage.Delete(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = storage.Open(key)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatal("expect not found, got", err)
+	}
+	err = storage.Delete(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileStorage_InvalidKey(t *testing.T) {
+	storage, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"", "/etc/passwd", "../a", "files/../../a", "files//1", "a\\b"} {
+		err = storage.Put(key, strings.NewReader("a"), 1)
+		if err == nil {
+			t.Fatal("expect error for key:", key)
+		}
+	}
+}
+
+func TestParseChunkKey(t *testing.T) {
+	fileId, chunkId, ok := ParseChunkKey(ChunkKey(12, 345))
+	if !ok || fileId != 12 || chunkId != 345 {
+		t.Fatal("unexpected:", fileId, chunkId, ok)
+	}
+	for _, key := range []string{"files/1", "files/a/1", "file/1/2", "files/0/1", "files/1/2/3"} {
+		_, _, ok = ParseChunkKey(key)
+		if ok {
+			t.Fatal("expect invalid:", key)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package blobs
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3DefaultRegion    = "us-east-1"
+	s3UnsignedPayload  = "UNSIGNED-PAYLOAD"
+	s3EmptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	s3Timeout          = 60 * time.Second
+)
+
+// S3Options S3兼容的对象存储选项
+type S3Options struct {
+	Endpoint           string // 接口地址，例如 https://s3.us-east-1.amazonaws.com 或 http://127.0.0.1:9000
+	Region             string // 区域，默认为 us-east-1
+	Bucket             string // 存储桶
+	AccessKeyId        string // AccessKey ID
+	AccessKeySecret    string // AccessKey Secret
+	Prefix             string // 对象键前缀
+	VirtualHostStyle   bool   // 是否使用 bucket.endpoint 形式的地址，默认使用 endpoint/bucket 形式，以兼容MinIO等服务
+	InsecureSkipVerify bool   // 是否跳过证书校验
+}
+
+// S3Storage S3兼容的对象存储
+// 使用 aws-sdk-go 的 Signature Version 4 签名，写入时不计算内容摘要（UNSIGNED-PAYLOAD），以便流式上传
+type S3Storage struct {
+	options  *S3Options
+	endpoint *url.URL
+	client   *http.Client
+	signer   *v4.Signer
+}
+
+func NewS3Storage(options *S3Options) (*S3Storage, error) {
+	if options == nil {
+		return nil, errors.New("'options' should not be nil")
+	}
+	if len(options.Endpoint) == 0 {
+		return nil, errors.New("'endpoint' should not be empty")
+	}
+	if len(options.Bucket) == 0 {
+		return nil, errors.New("'bucket' should not be empty")
+	}
+	if len(options.AccessKeyId) == 0 || len(options.AccessKeySecret) == 0 {
+		return nil, errors.New("'accessKeyId' and 'accessKeySecret' should not be empty")
+	}
+	endpoint, err := url.Parse(strings.TrimRight(options.Endpoint, "/"))
+	if err != nil {
+		return nil, errors.New("invalid 'endpoint': " + err.Error())
+	}
+	if (endpoint.Scheme != "http" && endpoint.Scheme != "https") || len(endpoint.Host) == 0 {
+		return nil, errors.New("'endpoint' should start with 'http://' or 'https://'")
+	}
+
+	var optionsCopy = *options
+	if len(optionsCopy.Region) == 0 {
+		optionsCopy.Region = s3DefaultRegion
+	}
+	if len(optionsCopy.Prefix) > 0 {
+		optionsCopy.Prefix = strings.Trim(optionsCopy.Prefix, "/") + "/"
+	}
+
+	return &S3Storage{
+		options:  &optionsCopy,
+		endpoint: endpoint,
+		signer: v4.NewSigner(credentials.NewStaticCredentials(options.AccessKeyId, options.AccessKeySecret, ""), func(signer *v4.Signer) {
+			// 路径已经由 s3EscapePath() 编码，S3不需要再次编码
+			signer.DisableURIPathEscaping = true
+			// 保留请求中的Body，以便流式上传
+			signer.DisableRequestBodyOverwrite = true
+		}),
+		client: &http.Client{
+			Timeout: s3Timeout,
+			Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				MaxIdleConnsPerHost: 16,
+				IdleConnTimeout:     90 * time.Second,
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: options.InsecureSkipVerify,
+				},
+			},
+		},
+	}, nil
+}
+
+func (this *S3Storage) Type() string {
+	return StorageTypeS3
+}
+
+// Put 写入对象
+func (this *S3Storage) Put(key string, reader io.Reader, size int64) error {
+	if size < 0 {
+		return errors.New("write '" + key + "' failed: size is required")
+	}
+	req, err := this.newRequest(http.MethodPut, key, reader)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	err = this.sign(req, s3UnsignedPayload, time.Now())
+	if err != nil {
+		return err
+	}
+
+	resp, err := this.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+	return this.checkResponse(resp)
+}
+
+// Open 打开对象
+func (this *S3Storage) Open(key string) (io.ReadCloser, error) {
+	req, err := this.newRequest(http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = this.sign(req, s3EmptyPayloadHash, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := this.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		_ = resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	err = this.checkResponse(resp)
+	if err != nil {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete 删除对象
+func (this *S3Storage) Delete(key string) error {
+	req, err := this.newRequest(http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	err = this.sign(req, s3EmptyPayloadHash, time.Now())
+	if err != nil {
+		return err
+	}
+
+	resp, err := this.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return this.checkResponse(resp)
+}
+
+func (this *S3Storage) newRequest(method string, key string, body io.Reader) (*http.Request, error) {
+	err := validateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var u = *this.endpoint
+	var objectPath = "/" + this.options.Prefix + key
+	if this.options.VirtualHostStyle {
+		u.Host = this.options.Bucket + "." + u.Host
+		u.Path = strings.TrimRight(u.Path, "/") + objectPath
+	} else {
+		u.Path = strings.TrimRight(u.Path, "/") + "/" + this.options.Bucket + objectPath
+	}
+	u.RawPath = s3EscapePath(u.Path)
+	return http.NewRequest(method, u.String(), body)
+}
+
+// 签名请求
+// payloadHash 为 X-Amz-Content-Sha256 的值，写入时使用 UNSIGNED-PAYLOAD 以便流式上传
+func (this *S3Storage) sign(req *http.Request, payloadHash string, now time.Time) error {
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	_, err := this.signer.Sign(req, nil, "s3", this.options.Region, now)
+	return err
+}
+
+// 状态码不是2xx时返回错误
+func (this *S3Storage) checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	var message = string(bytes.TrimSpace(data))
+	if len(message) > 256 {
+		message = message[:256] + "..."
+	}
+	return errors.New("unexpected response status '" + strconv.Itoa(resp.StatusCode) + "': " + message)
+}
+
+// 按照S3的规则编码路径，除了 / 之外的保留字符都需要编码
+func s3EscapePath(path string) string {
+	var builder = &strings.Builder{}
+	for i := 0; i < len(path); i++ {
+		var c = path[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			builder.WriteByte(c)
+		} else {
+			builder.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+		}
+	}
+	return builder.String()
+}
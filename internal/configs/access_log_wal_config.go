@@ -0,0 +1,12 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package configs
+
+// AccessLogWALConfig 访问日志队列的本地磁盘缓冲配置
+// 内存队列已满时，访问日志会先写入到本地磁盘，在数据库恢复或者API节点重启后继续写入数据库
+type AccessLogWALConfig struct {
+	IsOn          bool   `yaml:"isOn" json:"isOn"`                   // 是否启用
+	Dir           string `yaml:"dir" json:"dir"`                     // 存放目录，默认为 data/accesslogs-wal
+	MaxSizeMB     int64  `yaml:"maxSizeMB" json:"maxSizeMB"`         // 最大占用的磁盘空间，默认1024M
+	SegmentSizeMB int64  `yaml:"segmentSizeMB" json:"segmentSizeMB"` // 单个分段文件的最大尺寸，默认16M
+}
@@ -15,6 +15,14 @@ type APIConfig struct {
 	NodeId string `yaml:"nodeId" json:"nodeId"`
 	Secret string `yaml:"secret" json:"secret"`
 
+	Token        *TokenConfig        `yaml:"token,omitempty" json:"token"`               // 节点访问令牌配置
+	AccessLogWAL *AccessLogWALConfig `yaml:"accessLogWAL,omitempty" json:"accessLogWAL"` // 访问日志本地磁盘缓冲配置
+	PrimaryLease *PrimaryLeaseConfig `yaml:"primaryLease,omitempty" json:"primaryLease"` // 主节点租约配置
+	FileStorage  *FileStorageConfig  `yaml:"fileStorage,omitempty" json:"fileStorage"`   // 上传文件的存储配置
+	IPListFeed   *IPListFeedConfig   `yaml:"ipListFeed,omitempty" json:"ipListFeed"`     // IP名单订阅配置
+	Rest         *RestConfig         `yaml:"rest,omitempty" json:"rest"`                 // HTTP API配置
+	Metrics      *MetricsConfig      `yaml:"metrics,omitempty" json:"metrics"`           // 监控指标接口访问控制配置
+
 	numberId int64 // 数字ID
 }
 
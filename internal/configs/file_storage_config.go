@@ -0,0 +1,23 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package configs
+
+// FileStorageConfig 上传文件的存储配置
+// 所有API节点应该使用相同的设置；使用本地文件系统时，存放目录需要是所有API节点都能访问的共享目录
+type FileStorageConfig struct {
+	Type string           `yaml:"type" json:"type"`       // 存储类型：db|file|s3，默认为db
+	Dir  string           `yaml:"dir" json:"dir"`         // 本地文件系统的存放目录，默认为 data/files
+	S3   *S3StorageConfig `yaml:"s3,omitempty" json:"s3"` // S3兼容的对象存储配置
+}
+
+// S3StorageConfig S3兼容的对象存储配置
+type S3StorageConfig struct {
+	Endpoint           string `yaml:"endpoint" json:"endpoint"`                     // 接口地址，例如 http://127.0.0.1:9000
+	Region             string `yaml:"region" json:"region"`                         // 区域，默认为 us-east-1
+	Bucket             string `yaml:"bucket" json:"bucket"`                         // 存储桶
+	AccessKeyId        string `yaml:"accessKeyId" json:"accessKeyId"`               // AccessKey ID
+	AccessKeySecret    string `yaml:"accessKeySecret" json:"accessKeySecret"`       // AccessKey Secret
+	Prefix             string `yaml:"prefix" json:"prefix"`                         // 对象键前缀
+	VirtualHostStyle   bool   `yaml:"virtualHostStyle" json:"virtualHostStyle"`     // 是否使用 bucket.endpoint 形式的地址
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify" json:"insecureSkipVerify"` // 是否跳过证书校验
+}
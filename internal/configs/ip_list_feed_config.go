@@ -0,0 +1,10 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package configs
+
+// IPListFeedConfig IP名单订阅配置
+// 订阅地址由管理员填写，但是由API节点访问，所以默认只允许读取订阅目录中的文件和访问公网地址
+type IPListFeedConfig struct {
+	Dir          string `yaml:"dir" json:"dir"`                   // 本地订阅文件的存放目录，默认为 data/ip-list-feeds，只能读取此目录中的文件
+	AllowPrivate bool   `yaml:"allowPrivate" json:"allowPrivate"` // 是否允许访问本机、链路本地和内网地址
+}
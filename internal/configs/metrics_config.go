@@ -0,0 +1,86 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package configs
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net"
+	"strings"
+)
+
+// MetricsConfig 监控指标接口的访问控制配置
+// 没有设置令牌和允许的IP时，只允许本机访问
+type MetricsConfig struct {
+	Token    string   `yaml:"token" json:"token"`       // 访问令牌，设置后需要在请求中加入 Authorization: Bearer TOKEN
+	AllowIPs []string `yaml:"allowIPs" json:"allowIPs"` // 允许访问的IP或CIDR，为空时如果没有设置令牌，则只允许本机访问
+
+	ips    []net.IP
+	ipNets []*net.IPNet
+}
+
+// Init 初始化
+func (this *MetricsConfig) Init() error {
+	if this == nil {
+		return nil
+	}
+
+	this.ips = nil
+	this.ipNets = nil
+	for _, allowIP := range this.AllowIPs {
+		allowIP = strings.TrimSpace(allowIP)
+		if len(allowIP) == 0 {
+			continue
+		}
+		if strings.Contains(allowIP, "/") {
+			_, ipNet, err := net.ParseCIDR(allowIP)
+			if err != nil {
+				return errors.New("invalid cidr '" + allowIP + "'")
+			}
+			this.ipNets = append(this.ipNets, ipNet)
+		} else {
+			var ip = net.ParseIP(allowIP)
+			if ip == nil {
+				return errors.New("invalid ip '" + allowIP + "'")
+			}
+			this.ips = append(this.ips, ip)
+		}
+	}
+	return nil
+}
+
+// AllowIP 检查是否允许某个IP访问
+func (this *MetricsConfig) AllowIP(ipString string) bool {
+	var ip = net.ParseIP(ipString)
+	if ip == nil {
+		return false
+	}
+
+	if this == nil || (len(this.ips) == 0 && len(this.ipNets) == 0) {
+		// 设置了令牌时由令牌控制访问
+		if this != nil && len(this.Token) > 0 {
+			return true
+		}
+		return ip.IsLoopback()
+	}
+
+	for _, allowIP := range this.ips {
+		if allowIP.Equal(ip) {
+			return true
+		}
+	}
+	for _, ipNet := range this.ipNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowToken 检查请求中的令牌，没有设置令牌时总是返回 true
+func (this *MetricsConfig) AllowToken(token string) bool {
+	if this == nil || len(this.Token) == 0 {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(this.Token), []byte(token)) == 1
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package configs
+
+import "testing"
+
+func TestMetricsConfig_AllowIP(t *testing.T) {
+	// 默认只允许本机访问
+	{
+		var config *MetricsConfig
+		if !config.AllowIP("127.0.0.1") || !config.AllowIP("::1") {
+			t.Fatal("loopback should be allowed")
+		}
+		if config.AllowIP("192.168.1.100") {
+			t.Fatal("remote ip should not be allowed by default")
+		}
+		if !config.AllowToken("") {
+			t.Fatal("token should not be required by default")
+		}
+	}
+
+	// 只设置令牌
+	{
+		var config = &MetricsConfig{Token: "abc"}
+		err := config.Init()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !config.AllowIP("192.168.1.100") {
+			t.Fatal("remote ip should be allowed when token is set")
+		}
+		if config.AllowToken("") || config.AllowToken("abd") || !config.AllowToken("abc") {
+			t.Fatal("unexpected token result")
+		}
+	}
+
+	// 设置允许的IP
+	{
+		var config = &MetricsConfig{AllowIPs: []string{"10.0.0.0/8", "192.168.1.100"}}
+		err := config.Init()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for ip, allowed := range map[string]bool{
+			"10.1.2.3":      true,
+			"192.168.1.100": true,
+			"192.168.1.101": false,
+			"127.0.0.1":     false,
+			"invalid":       false,
+		} {
+			if config.AllowIP(ip) != allowed {
+				t.Fatal(ip, "expect", allowed)
+			}
+		}
+	}
+
+	// 错误的IP
+	{
+		var config = &MetricsConfig{AllowIPs: []string{"10.0.0.0/33"}}
+		if config.Init() == nil {
+			t.Fatal("invalid cidr should fail")
+		}
+	}
+}
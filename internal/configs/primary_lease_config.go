@@ -0,0 +1,32 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package configs
+
+import "time"
+
+const (
+	DefaultPrimaryLeaseTTL = 30 // 默认租约有效期（秒）
+	MinPrimaryLeaseTTL     = 6  // 最小租约有效期（秒）
+)
+
+// PrimaryLeaseConfig 主节点租约配置
+// 主节点需要定期续约，租约过期后由其他健康的API节点接管
+type PrimaryLeaseConfig struct {
+	TTL int64 `yaml:"ttl" json:"ttl"` // 租约有效期（秒），默认30秒，所有API节点应该使用相同的设置
+}
+
+// TTLSeconds 租约有效期
+func (this *PrimaryLeaseConfig) TTLSeconds() int64 {
+	if this == nil || this.TTL <= 0 {
+		return DefaultPrimaryLeaseTTL
+	}
+	if this.TTL < MinPrimaryLeaseTTL {
+		return MinPrimaryLeaseTTL
+	}
+	return this.TTL
+}
+
+// RenewInterval 续约间隔，为租约有效期的三分之一
+func (this *PrimaryLeaseConfig) RenewInterval() time.Duration {
+	return time.Duration(this.TTLSeconds()) * time.Second / 3
+}
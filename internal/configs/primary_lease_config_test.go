@@ -0,0 +1,32 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package configs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPrimaryLeaseConfig_TTLSeconds(t *testing.T) {
+	{
+		var config *PrimaryLeaseConfig
+		if config.TTLSeconds() != DefaultPrimaryLeaseTTL {
+			t.Fatal("expect default ttl, got", config.TTLSeconds())
+		}
+		if config.RenewInterval() != 10*time.Second {
+			t.Fatal("unexpected renew interval:", config.RenewInterval())
+		}
+	}
+	{
+		var config = &PrimaryLeaseConfig{TTL: 1}
+		if config.TTLSeconds() != MinPrimaryLeaseTTL {
+			t.Fatal("expect min ttl, got", config.TTLSeconds())
+		}
+	}
+	{
+		var config = &PrimaryLeaseConfig{TTL: 60}
+		if config.TTLSeconds() != 60 || config.RenewInterval() != 20*time.Second {
+			t.Fatal("unexpected:", config.TTLSeconds(), config.RenewInterval())
+		}
+	}
+}
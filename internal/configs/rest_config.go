@@ -0,0 +1,13 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package configs
+
+// RestConfig HTTP API配置
+type RestConfig struct {
+	PublicOpenAPI bool `yaml:"publicOpenAPI" json:"publicOpenAPI"` // 是否允许不带AccessToken访问 /openapi.json，默认需要有效的AccessToken
+}
+
+// IsPublicOpenAPI 是否公开API文档
+func (this *RestConfig) IsPublicOpenAPI() bool {
+	return this != nil && this.PublicOpenAPI
+}
@@ -0,0 +1,37 @@
+package configs
+
+import (
+	"time"
+)
+
+// TokenConfig 节点访问令牌配置
+type TokenConfig struct {
+	ClockSkew   int    `yaml:"clockSkew" json:"clockSkew"`     // 允许的时钟偏差（秒），默认300秒
+	DenyLegacy  bool   `yaml:"denyLegacy" json:"denyLegacy"`   // 是否拒绝旧的非认证加密令牌（aes-*-cfb）
+	LegacyUntil string `yaml:"legacyUntil" json:"legacyUntil"` // 兼容旧令牌的截止日期，格式为YYYY-MM-DD，为空表示不限制
+}
+
+// ClockSkewDuration 允许的时钟偏差
+func (this *TokenConfig) ClockSkewDuration() time.Duration {
+	if this == nil || this.ClockSkew <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(this.ClockSkew) * time.Second
+}
+
+// AllowLegacy 是否允许旧的令牌
+func (this *TokenConfig) AllowLegacy() bool {
+	if this == nil {
+		return true
+	}
+	if this.DenyLegacy {
+		return false
+	}
+	if len(this.LegacyUntil) > 0 {
+		deadline, err := time.ParseInLocation("2006-01-02", this.LegacyUntil, time.Local)
+		if err == nil && !time.Now().Before(deadline.AddDate(0, 0, 1)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,30 @@
+package configs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenConfig_AllowLegacy(t *testing.T) {
+	{
+		var config *TokenConfig
+		t.Log(config.AllowLegacy(), config.ClockSkewDuration())
+	}
+	{
+		var config = &TokenConfig{DenyLegacy: true}
+		t.Log(config.AllowLegacy())
+	}
+	{
+		var config = &TokenConfig{LegacyUntil: time.Now().AddDate(0, 0, -1).Format("2006-01-02")}
+		if config.AllowLegacy() {
+			t.Fatal("should not allow legacy token")
+		}
+	}
+	{
+		var config = &TokenConfig{LegacyUntil: time.Now().Format("2006-01-02"), ClockSkew: 60}
+		if !config.AllowLegacy() {
+			t.Fatal("should allow legacy token")
+		}
+		t.Log(config.ClockSkewDuration())
+	}
+}
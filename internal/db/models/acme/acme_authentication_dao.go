@@ -1,11 +1,17 @@
 package acme
 
 import (
+	acmeutils "github.com/dashenmiren/EdgeAPI/internal/acme"
+	"github.com/dashenmiren/EdgeAPI/internal/db/models"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/iwind/TeaGo/Tea"
 	"github.com/iwind/TeaGo/dbs"
+	"time"
 )
 
+// ACMETLSALPNAuthLife TLS-ALPN认证证书的有效时间（秒），超过此时间的认证证书不再下发
+const ACMETLSALPNAuthLife = 3600
+
 type ACMEAuthenticationDAO dbs.DAO
 
 func NewACMEAuthenticationDAO() *ACMEAuthenticationDAO {
@@ -34,6 +40,7 @@ func (this *ACMEAuthenticationDAO) CreateAuth(tx *dbs.Tx, taskId int64, domain s
 	op.Domain = domain
 	op.Token = token
 	op.Key = key
+	op.AuthType = acmeutils.AuthTypeHTTP
 	err := this.Save(tx, op)
 	return err
 }
@@ -52,3 +59,58 @@ func (this *ACMEAuthenticationDAO) FindAuthWithToken(tx *dbs.Tx, token string) (
 	}
 	return one.(*ACMEAuthentication), nil
 }
+
+// CreateTLSALPNAuth 创建TLS-ALPN认证信息，并通知边缘节点更新认证证书
+func (this *ACMEAuthenticationDAO) CreateTLSALPNAuth(tx *dbs.Tx, taskId int64, domain string, certData []byte, keyData []byte) error {
+	var op = NewACMEAuthenticationOperator()
+	op.TaskId = taskId
+	op.Domain = domain
+	op.AuthType = acmeutils.AuthTypeTLSALPN
+	op.CertData = certData
+	op.KeyData = keyData
+	err := this.Save(tx, op)
+	if err != nil {
+		return err
+	}
+	return this.NotifyTLSALPNUpdate(tx)
+}
+
+// DeleteTLSALPNAuth 删除某个域名的TLS-ALPN认证信息
+func (this *ACMEAuthenticationDAO) DeleteTLSALPNAuth(tx *dbs.Tx, taskId int64, domain string) error {
+	_, err := this.Query(tx).
+		Attr("taskId", taskId).
+		Attr("domain", domain).
+		Attr("authType", acmeutils.AuthTypeTLSALPN).
+		Delete()
+	if err != nil {
+		return err
+	}
+	return this.NotifyTLSALPNUpdate(tx)
+}
+
+// FindAllTLSALPNAuths 查找所有有效的TLS-ALPN认证信息
+func (this *ACMEAuthenticationDAO) FindAllTLSALPNAuths(tx *dbs.Tx) (result []*ACMEAuthentication, err error) {
+	_, err = this.Query(tx).
+		Attr("authType", acmeutils.AuthTypeTLSALPN).
+		Gt("createdAt", time.Now().Unix()-ACMETLSALPNAuthLife).
+		Result("id", "domain", "certData", "keyData", "createdAt").
+		Slice(&result).
+		DescPk().
+		FindAll()
+	return
+}
+
+// NotifyTLSALPNUpdate 通知边缘节点更新TLS-ALPN认证证书
+func (this *ACMEAuthenticationDAO) NotifyTLSALPNUpdate(tx *dbs.Tx) error {
+	clusterIds, err := models.SharedNodeClusterDAO.FindAllEnabledNodeClusterIds(tx)
+	if err != nil {
+		return err
+	}
+	for _, clusterId := range clusterIds {
+		err = models.SharedNodeClusterDAO.NotifyACMETLSALPNUpdate(tx, clusterId)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
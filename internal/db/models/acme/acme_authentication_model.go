@@ -8,6 +8,9 @@ type ACMEAuthentication struct {
 	Token     string `field:"token"`     // 令牌
 	Key       string `field:"key"`       // 密钥
 	CreatedAt uint64 `field:"createdAt"` // 创建时间
+	AuthType  string `field:"authType"`  // 认证方式
+	CertData  []byte `field:"certData"`  // TLS-ALPN认证证书
+	KeyData   []byte `field:"keyData"`   // TLS-ALPN认证证书私钥
 }
 
 type ACMEAuthenticationOperator struct {
@@ -17,6 +20,9 @@ type ACMEAuthenticationOperator struct {
 	Token     interface{} // 令牌
 	Key       interface{} // 密钥
 	CreatedAt interface{} // 创建时间
+	AuthType  interface{} // 认证方式
+	CertData  interface{} // TLS-ALPN认证证书
+	KeyData   interface{} // TLS-ALPN认证证书私钥
 }
 
 func NewACMEAuthenticationOperator() *ACMEAuthenticationOperator {
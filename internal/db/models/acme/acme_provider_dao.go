@@ -0,0 +1,160 @@
+package acme
+
+import (
+	acmeutils "github.com/dashenmiren/EdgeAPI/internal/acme"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/iwind/TeaGo/Tea"
+	"github.com/iwind/TeaGo/dbs"
+)
+
+const (
+	ACMEProviderStateEnabled  = 1 // 已启用
+	ACMEProviderStateDisabled = 0 // 已禁用
+)
+
+type ACMEProviderDAO dbs.DAO
+
+func NewACMEProviderDAO() *ACMEProviderDAO {
+	return dbs.NewDAO(&ACMEProviderDAO{
+		DAOObject: dbs.DAOObject{
+			DB:     Tea.Env,
+			Table:  "edgeACMEProviders",
+			Model:  new(ACMEProvider),
+			PkName: "id",
+		},
+	}).(*ACMEProviderDAO)
+}
+
+var SharedACMEProviderDAO *ACMEProviderDAO
+
+func init() {
+	dbs.OnReady(func() {
+		SharedACMEProviderDAO = NewACMEProviderDAO()
+
+		acmeutils.SetCustomProvidersFunc(func() ([]*acmeutils.Provider, error) {
+			providers, err := SharedACMEProviderDAO.FindAllAvailableProviders(nil)
+			if err != nil {
+				return nil, err
+			}
+			var result = []*acmeutils.Provider{}
+			for _, provider := range providers {
+				result = append(result, provider.ToProvider())
+			}
+			return result, nil
+		})
+	})
+}
+
+// DisableACMEProvider 禁用条目
+func (this *ACMEProviderDAO) DisableACMEProvider(tx *dbs.Tx, id int64) error {
+	_, err := this.Query(tx).
+		Pk(id).
+		Set("state", ACMEProviderStateDisabled).
+		Update()
+	if err != nil {
+		return err
+	}
+	acmeutils.ResetCustomProvidersCache()
+	return nil
+}
+
+// FindEnabledACMEProvider 查找启用中的条目
+func (this *ACMEProviderDAO) FindEnabledACMEProvider(tx *dbs.Tx, id int64) (*ACMEProvider, error) {
+	result, err := this.Query(tx).
+		Pk(id).
+		State(ACMEProviderStateEnabled).
+		Find()
+	if result == nil {
+		return nil, err
+	}
+	return result.(*ACMEProvider), err
+}
+
+// CreateProvider 创建服务商
+func (this *ACMEProviderDAO) CreateProvider(tx *dbs.Tx, name string, code string, description string, apiURL string, testAPIURL string, useTestAPI bool, requireEAB bool, eabDescription string, caCerts []byte) (int64, error) {
+	if acmeutils.IsBuiltinProviderCode(code) {
+		return 0, errors.New("code '" + code + "' is used by builtin provider")
+	}
+	exists, err := this.ExistProviderCode(tx, code)
+	if err != nil {
+		return 0, err
+	}
+	if exists {
+		return 0, errors.New("code '" + code + "' already exists")
+	}
+
+	var op = NewACMEProviderOperator()
+	op.Name = name
+	op.Code = code
+	op.Description = description
+	op.ApiURL = apiURL
+	op.TestAPIURL = testAPIURL
+	op.UseTestAPI = useTestAPI
+	op.RequireEAB = requireEAB
+	op.EabDescription = eabDescription
+	op.CaCerts = caCerts
+	op.IsOn = true
+	op.State = ACMEProviderStateEnabled
+	providerId, err := this.SaveInt64(tx, op)
+	if err != nil {
+		return 0, err
+	}
+	acmeutils.ResetCustomProvidersCache()
+	return providerId, nil
+}
+
+// UpdateProvider 修改服务商
+// 代号已经被ACME用户引用，所以不允许修改
+func (this *ACMEProviderDAO) UpdateProvider(tx *dbs.Tx, providerId int64, name string, description string, apiURL string, testAPIURL string, useTestAPI bool, requireEAB bool, eabDescription string, caCerts []byte, isOn bool) error {
+	if providerId <= 0 {
+		return errors.New("invalid providerId")
+	}
+
+	var op = NewACMEProviderOperator()
+	op.Id = providerId
+	op.Name = name
+	op.Description = description
+	op.ApiURL = apiURL
+	op.TestAPIURL = testAPIURL
+	op.UseTestAPI = useTestAPI
+	op.RequireEAB = requireEAB
+	op.EabDescription = eabDescription
+	op.CaCerts = caCerts
+	op.IsOn = isOn
+	err := this.Save(tx, op)
+	if err != nil {
+		return err
+	}
+	acmeutils.ResetCustomProvidersCache()
+	return nil
+}
+
+// ExistProviderCode 检查代号是否已存在
+func (this *ACMEProviderDAO) ExistProviderCode(tx *dbs.Tx, code string) (bool, error) {
+	return this.Query(tx).
+		State(ACMEProviderStateEnabled).
+		Attr("code", code).
+		Exist()
+}
+
+// FindAllEnabledProviders 查找所有服务商
+func (this *ACMEProviderDAO) FindAllEnabledProviders(tx *dbs.Tx) (result []*ACMEProvider, err error) {
+	_, err = this.Query(tx).
+		State(ACMEProviderStateEnabled).
+		AscPk().
+		Slice(&result).
+		FindAll()
+	return
+}
+
+// FindAllAvailableProviders 查找所有启用的服务商
+func (this *ACMEProviderDAO) FindAllAvailableProviders(tx *dbs.Tx) (result []*ACMEProvider, err error) {
+	_, err = this.Query(tx).
+		State(ACMEProviderStateEnabled).
+		Attr("isOn", true).
+		AscPk().
+		Slice(&result).
+		FindAll()
+	return
+}
@@ -0,0 +1,38 @@
+package acme
+
+// ACMEProvider 自定义ACME服务商
+type ACMEProvider struct {
+	Id             uint64 `field:"id"`             // ID
+	Name           string `field:"name"`           // 名称
+	Code           string `field:"code"`           // 代号
+	Description    string `field:"description"`    // 描述
+	ApiURL         string `field:"apiURL"`         // 目录地址
+	TestAPIURL     string `field:"testAPIURL"`     // 测试环境目录地址
+	UseTestAPI     bool   `field:"useTestAPI"`     // 是否使用测试环境
+	RequireEAB     bool   `field:"requireEAB"`     // 是否需要EAB
+	EabDescription string `field:"eabDescription"` // EAB说明
+	CaCerts        []byte `field:"caCerts"`        // 自定义根证书
+	IsOn           bool   `field:"isOn"`           // 是否启用
+	CreatedAt      uint64 `field:"createdAt"`      // 创建时间
+	State          uint8  `field:"state"`          // 状态
+}
+
+type ACMEProviderOperator struct {
+	Id             any // ID
+	Name           any // 名称
+	Code           any // 代号
+	Description    any // 描述
+	ApiURL         any // 目录地址
+	TestAPIURL     any // 测试环境目录地址
+	UseTestAPI     any // 是否使用测试环境
+	RequireEAB     any // 是否需要EAB
+	EabDescription any // EAB说明
+	CaCerts        any // 自定义根证书
+	IsOn           any // 是否启用
+	CreatedAt      any // 创建时间
+	State          any // 状态
+}
+
+func NewACMEProviderOperator() *ACMEProviderOperator {
+	return &ACMEProviderOperator{}
+}
@@ -0,0 +1,19 @@
+package acme
+
+import acmeutils "github.com/dashenmiren/EdgeAPI/internal/acme"
+
+// ToProvider 转换为ACME客户端使用的服务商
+func (this *ACMEProvider) ToProvider() *acmeutils.Provider {
+	return &acmeutils.Provider{
+		Name:           this.Name,
+		Code:           this.Code,
+		Description:    this.Description,
+		APIURL:         this.ApiURL,
+		TestAPIURL:     this.TestAPIURL,
+		RequireEAB:     this.RequireEAB,
+		EABDescription: this.EabDescription,
+		IsCustom:       true,
+		UseTestAPI:     this.UseTestAPI,
+		CACertificates: this.CaCerts,
+	}
+}
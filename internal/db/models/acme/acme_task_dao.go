@@ -187,7 +187,20 @@ func (this *ACMETaskDAO) ListEnabledACMETasks(tx *dbs.Tx, userId int64, isAvaila
 }
 
 // CreateACMETask 创建任务
-func (this *ACMETaskDAO) CreateACMETask(tx *dbs.Tx, adminId int64, userId int64, authType acmeutils.AuthType, acmeUserId int64, dnsProviderId int64, dnsDomain string, domains []string, autoRenew bool, authURL string) (int64, error) {
+func (this *ACMETaskDAO) CreateACMETask(tx *dbs.Tx, adminId int64, userId int64, authType acmeutils.AuthType, acmeUserId int64, dnsProviderId int64, dnsDomain string, domains []string, domainAuthTypes map[string]acmeutils.AuthType, keyType acmeutils.KeyType, autoRenew bool, authURL string) (int64, error) {
+	if len(keyType) == 0 {
+		keyType = acmeutils.DefaultKeyType
+	} else if !acmeutils.IsValidKeyType(keyType) {
+		return 0, errors.New("invalid keyType '" + keyType + "'")
+	}
+	if !acmeutils.IsValidAuthType(authType) {
+		return 0, errors.New("invalid authType '" + authType + "'")
+	}
+	domainAuthTypesJSON, err := this.encodeDomainAuthTypes(authType, domains, domainAuthTypes)
+	if err != nil {
+		return 0, err
+	}
+
 	var op = NewACMETaskOperator()
 	op.AdminId = adminId
 	op.UserId = userId
@@ -206,11 +219,13 @@ func (this *ACMETaskDAO) CreateACMETask(tx *dbs.Tx, adminId int64, userId int64,
 		op.Domains = "[]"
 	}
 
+	op.DomainAuthTypes = domainAuthTypesJSON
+	op.KeyType = keyType
 	op.AutoRenew = autoRenew
 	op.AuthURL = authURL
 	op.IsOn = true
 	op.State = ACMETaskStateEnabled
-	err := this.Save(tx, op)
+	err = this.Save(tx, op)
 	if err != nil {
 		return 0, err
 	}
@@ -218,10 +233,27 @@ func (this *ACMETaskDAO) CreateACMETask(tx *dbs.Tx, adminId int64, userId int64,
 }
 
 // UpdateACMETask 修改任务
-func (this *ACMETaskDAO) UpdateACMETask(tx *dbs.Tx, acmeTaskId int64, acmeUserId int64, dnsProviderId int64, dnsDomain string, domains []string, autoRenew bool, authURL string) error {
+func (this *ACMETaskDAO) UpdateACMETask(tx *dbs.Tx, acmeTaskId int64, acmeUserId int64, dnsProviderId int64, dnsDomain string, domains []string, domainAuthTypes map[string]acmeutils.AuthType, keyType acmeutils.KeyType, autoRenew bool, authURL string) error {
 	if acmeTaskId <= 0 {
 		return errors.New("invalid acmeTaskId")
 	}
+	if len(keyType) == 0 {
+		keyType = acmeutils.DefaultKeyType
+	} else if !acmeutils.IsValidKeyType(keyType) {
+		return errors.New("invalid keyType '" + keyType + "'")
+	}
+
+	authType, err := this.Query(tx).
+		Pk(acmeTaskId).
+		Result("authType").
+		FindStringCol("")
+	if err != nil {
+		return err
+	}
+	domainAuthTypesJSON, err := this.encodeDomainAuthTypes(authType, domains, domainAuthTypes)
+	if err != nil {
+		return err
+	}
 
 	var op = NewACMETaskOperator()
 	op.Id = acmeTaskId
@@ -239,12 +271,40 @@ func (this *ACMETaskDAO) UpdateACMETask(tx *dbs.Tx, acmeTaskId int64, acmeUserId
 		op.Domains = "[]"
 	}
 
+	op.DomainAuthTypes = domainAuthTypesJSON
+	op.KeyType = keyType
 	op.AutoRenew = autoRenew
 	op.AuthURL = authURL
-	err := this.Save(tx, op)
+	err = this.Save(tx, op)
 	return err
 }
 
+// 检查并编码单个域名的认证方式，只保存和任务默认认证方式不同的域名
+func (this *ACMETaskDAO) encodeDomainAuthTypes(authType acmeutils.AuthType, domains []string, domainAuthTypes map[string]acmeutils.AuthType) ([]byte, error) {
+	var result = map[string]acmeutils.AuthType{}
+	for _, domain := range domains {
+		domainAuthType, ok := domainAuthTypes[domain]
+		if ok && len(domainAuthType) > 0 && domainAuthType != authType {
+			result[domain] = domainAuthType
+		}
+	}
+
+	var task = &acmeutils.Task{
+		AuthType:        authType,
+		DomainAuthTypes: result,
+		Domains:         domains,
+	}
+	err := task.ValidateAuthTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return []byte("null"), nil
+	}
+	return json.Marshal(result)
+}
+
 // CheckUserACMETask 检查用户权限
 func (this *ACMETaskDAO) CheckUserACMETask(tx *dbs.Tx, userId int64, acmeTaskId int64) (bool, error) {
 	var query = this.Query(tx)
@@ -281,10 +341,10 @@ func (this *ACMETaskDAO) UpdateACMETaskCert(tx *dbs.Tx, taskId int64, certId int
 
 // RunTask 执行任务并记录日志
 func (this *ACMETaskDAO) RunTask(tx *dbs.Tx, taskId int64) (isOk bool, errMsg string, resultCertId int64) {
-	isOk, errMsg, resultCertId = this.runTaskWithoutLog(tx, taskId)
+	isOk, errMsg, resultCertId, challenges := this.runTaskWithoutLog(tx, taskId)
 
 	// 记录日志
-	err := SharedACMETaskLogDAO.CreateACMETaskLog(tx, taskId, isOk, errMsg)
+	err := SharedACMETaskLogDAO.CreateACMETaskLog(tx, taskId, isOk, errMsg, challenges)
 	if err != nil {
 		logs.Error(err)
 	}
@@ -293,7 +353,7 @@ func (this *ACMETaskDAO) RunTask(tx *dbs.Tx, taskId int64) (isOk bool, errMsg st
 }
 
 // 执行任务但并不记录日志
-func (this *ACMETaskDAO) runTaskWithoutLog(tx *dbs.Tx, taskId int64) (isOk bool, errMsg string, resultCertId int64) {
+func (this *ACMETaskDAO) runTaskWithoutLog(tx *dbs.Tx, taskId int64) (isOk bool, errMsg string, resultCertId int64, challenges []*ACMETaskLogChallenge) {
 	task, err := this.FindEnabledACMETask(tx, taskId)
 	if err != nil {
 		errMsg = "查询任务信息时出错：" + err.Error()
@@ -308,69 +368,18 @@ func (this *ACMETaskDAO) runTaskWithoutLog(tx *dbs.Tx, taskId int64) (isOk bool,
 		return
 	}
 
-	// ACME用户
-	user, err := SharedACMEUserDAO.FindEnabledACMEUser(tx, int64(task.AcmeUserId))
-	if err != nil {
-		errMsg = "查询ACME用户时出错：" + err.Error()
-		return
-	}
-	if user == nil {
-		errMsg = "找不到ACME用户"
+	remoteUser, acmeProvider, acmeAccount, errMsg := this.findACMEClientInfo(tx, task)
+	if len(errMsg) > 0 {
 		return
 	}
 
-	// 服务商
-	if len(user.ProviderCode) == 0 {
-		user.ProviderCode = acmeutils.DefaultProviderCode
+	var acmeTask = &acmeutils.Task{
+		User:            remoteUser,
+		AuthType:        task.AuthType,
+		DomainAuthTypes: task.DecodeDomainAuthTypes(),
+		Domains:         task.DecodeDomains(),
 	}
-	var acmeProvider = acmeutils.FindProviderWithCode(user.ProviderCode)
-	if acmeProvider == nil {
-		errMsg = "服务商已不可用"
-		return
-	}
-
-	// 账号
-	var acmeAccount *acmeutils.Account
-	if user.AccountId > 0 {
-		account, err := SharedACMEProviderAccountDAO.FindEnabledACMEProviderAccount(tx, int64(user.AccountId))
-		if err != nil {
-			errMsg = "查询ACME账号时出错：" + err.Error()
-			return
-		}
-		if account != nil {
-			acmeAccount = &acmeutils.Account{
-				EABKid: account.EabKid,
-				EABKey: account.EabKey,
-			}
-		}
-	}
-
-	privateKey, err := acmeutils.ParsePrivateKeyFromBase64(user.PrivateKey)
-	if err != nil {
-		errMsg = "解析私钥时出错：" + err.Error()
-		return
-	}
-
-	var remoteUser = acmeutils.NewUser(user.Email, privateKey, func(resource *registration.Resource) error {
-		resourceJSON, err := json.Marshal(resource)
-		if err != nil {
-			return err
-		}
-
-		err = SharedACMEUserDAO.UpdateACMEUserRegistration(tx, int64(user.Id), resourceJSON)
-		return err
-	})
-
-	if len(user.Registration) > 0 {
-		err = remoteUser.SetRegistration(user.Registration)
-		if err != nil {
-			errMsg = "设置注册信息时出错：" + err.Error()
-			return
-		}
-	}
-
-	var acmeTask *acmeutils.Task = nil
-	if task.AuthType == acmeutils.AuthTypeDNS {
+	if task.UsesAuthType(acmeutils.AuthTypeDNS) {
 		// DNS服务商
 		dnsProvider, err := dns.SharedDNSProviderDAO.FindEnabledDNSProvider(tx, int64(task.DnsProviderId))
 		if err != nil {
@@ -398,22 +407,12 @@ func (this *ACMETaskDAO) runTaskWithoutLog(tx *dbs.Tx, taskId int64) (isOk bool,
 			return
 		}
 
-		acmeTask = &acmeutils.Task{
-			User:        remoteUser,
-			AuthType:    acmeutils.AuthTypeDNS,
-			DNSProvider: providerInterface,
-			DNSDomain:   task.DnsDomain,
-			Domains:     task.DecodeDomains(),
-		}
-	} else if task.AuthType == acmeutils.AuthTypeHTTP {
-		acmeTask = &acmeutils.Task{
-			User:     remoteUser,
-			AuthType: acmeutils.AuthTypeHTTP,
-			Domains:  task.DecodeDomains(),
-		}
+		acmeTask.DNSProvider = providerInterface
+		acmeTask.DNSDomain = task.DnsDomain
 	}
 	acmeTask.Provider = acmeProvider
 	acmeTask.Account = acmeAccount
+	acmeTask.KeyType = task.DecodeKeyType()
 
 	var acmeRequest = acmeutils.NewRequest(acmeTask)
 	acmeRequest.OnAuth(func(domain, token, keyAuth string) {
@@ -449,7 +448,31 @@ func (this *ACMETaskDAO) runTaskWithoutLog(tx *dbs.Tx, taskId int64) (isOk bool,
 			}
 		}
 	})
+	acmeRequest.OnTLSALPNAuth(func(domain string, certData []byte, keyData []byte) {
+		var err error
+		if len(certData) > 0 {
+			err = SharedACMEAuthenticationDAO.CreateTLSALPNAuth(tx, taskId, domain, certData, keyData)
+		} else {
+			err = SharedACMEAuthenticationDAO.DeleteTLSALPNAuth(tx, taskId, domain)
+		}
+		if err != nil {
+			remotelogs.Error("ACME", "write tls-alpn authentication to database error: "+err.Error())
+		}
+	})
 	certData, keyData, err := acmeRequest.Run()
+
+	// 记录每个域名使用的认证方式
+	var challengeMap = acmeRequest.Challenges()
+	for _, domain := range acmeTask.Domains {
+		authType, ok := challengeMap[domain]
+		if ok {
+			challenges = append(challenges, &ACMETaskLogChallenge{
+				Domain:   domain,
+				AuthType: authType,
+			})
+		}
+	}
+
 	if err != nil {
 		errMsg = "证书生成失败：" + err.Error()
 		return
@@ -512,6 +535,187 @@ func (this *ACMETaskDAO) runTaskWithoutLog(tx *dbs.Tx, taskId int64) (isOk bool,
 		}
 	}
 
+	// 清除旧证书的续期信息
+	err = this.UpdateACMETaskRenewalInfo(tx, taskId, nil)
+	if err != nil {
+		errMsg = "证书生成成功，清除续期信息时出错：" + err.Error()
+		return
+	}
+
 	isOk = true
 	return
 }
+
+// CheckACMETaskRenewalInfo 从ACME服务商查询证书的ARI续期信息并保存
+func (this *ACMETaskDAO) CheckACMETaskRenewalInfo(tx *dbs.Tx, taskId int64, certData []byte) (*ACMETaskRenewalInfo, error) {
+	task, err := this.FindEnabledACMETask(tx, taskId)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return nil, errors.New("can not find task '" + types.String(taskId) + "'")
+	}
+
+	remoteUser, acmeProvider, acmeAccount, errMsg := this.findACMEClientInfo(tx, task)
+	if len(errMsg) > 0 {
+		return nil, errors.New(errMsg)
+	}
+
+	renewalInfo, err := acmeutils.NewRequest(&acmeutils.Task{
+		Provider: acmeProvider,
+		Account:  acmeAccount,
+		User:     remoteUser,
+		KeyType:  task.DecodeKeyType(),
+	}).GetRenewalInfo(certData)
+	if err != nil {
+		return nil, err
+	}
+
+	var result = &ACMETaskRenewalInfo{
+		CheckedAt: time.Now().Unix(),
+	}
+	if renewalInfo != nil {
+		result.IsSupported = true
+		result.WindowStart = renewalInfo.WindowStart.Unix()
+		result.WindowEnd = renewalInfo.WindowEnd.Unix()
+		result.ExplanationURL = renewalInfo.ExplanationURL
+
+		// 时间窗口没有变化时沿用之前选定的续期时间
+		var oldInfo = task.DecodeRenewalInfo()
+		if oldInfo != nil && oldInfo.RenewAt > 0 && oldInfo.WindowStart == result.WindowStart && oldInfo.WindowEnd == result.WindowEnd {
+			result.RenewAt = oldInfo.RenewAt
+		} else {
+			result.RenewAt = renewalInfo.RandomRenewAt().Unix()
+		}
+	}
+
+	err = this.UpdateACMETaskRenewalInfo(tx, taskId, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RevokeACMETaskCert 使用任务对应的ACME账号吊销证书
+func (this *ACMETaskDAO) RevokeACMETaskCert(tx *dbs.Tx, taskId int64, certData []byte, reason acmeutils.RevokeReason) error {
+	task, err := this.FindEnabledACMETask(tx, taskId)
+	if err != nil {
+		return err
+	}
+	if task == nil {
+		return errors.New("can not find task '" + types.String(taskId) + "'")
+	}
+
+	remoteUser, acmeProvider, acmeAccount, errMsg := this.findACMEClientInfo(tx, task)
+	if len(errMsg) > 0 {
+		return errors.New(errMsg)
+	}
+
+	return acmeutils.NewRequest(&acmeutils.Task{
+		Provider: acmeProvider,
+		Account:  acmeAccount,
+		User:     remoteUser,
+	}).Revoke(certData, reason)
+}
+
+// UpdateACMETaskRenewalInfo 修改ARI续期信息
+func (this *ACMETaskDAO) UpdateACMETaskRenewalInfo(tx *dbs.Tx, taskId int64, renewalInfo *ACMETaskRenewalInfo) error {
+	if taskId <= 0 {
+		return errors.New("invalid taskId")
+	}
+
+	var op = NewACMETaskOperator()
+	op.Id = taskId
+	if renewalInfo == nil {
+		op.RenewalInfo = "null"
+	} else {
+		renewalInfoJSON, err := json.Marshal(renewalInfo)
+		if err != nil {
+			return err
+		}
+		op.RenewalInfo = renewalInfoJSON
+	}
+	return this.Save(tx, op)
+}
+
+// PostponeACMETaskRenewal 推迟ARI续期时间，用于续期失败后重试
+func (this *ACMETaskDAO) PostponeACMETaskRenewal(tx *dbs.Tx, taskId int64, renewAt int64) error {
+	task, err := this.FindEnabledACMETask(tx, taskId)
+	if err != nil {
+		return err
+	}
+	if task == nil {
+		return nil
+	}
+	var renewalInfo = task.DecodeRenewalInfo()
+	if renewalInfo == nil {
+		return nil
+	}
+	renewalInfo.RenewAt = renewAt
+	return this.UpdateACMETaskRenewalInfo(tx, taskId, renewalInfo)
+}
+
+// 查找任务对应的ACME用户、服务商和账号
+func (this *ACMETaskDAO) findACMEClientInfo(tx *dbs.Tx, task *ACMETask) (remoteUser *acmeutils.User, acmeProvider *acmeutils.Provider, acmeAccount *acmeutils.Account, errMsg string) {
+	// ACME用户
+	user, err := SharedACMEUserDAO.FindEnabledACMEUser(tx, int64(task.AcmeUserId))
+	if err != nil {
+		errMsg = "查询ACME用户时出错：" + err.Error()
+		return
+	}
+	if user == nil {
+		errMsg = "找不到ACME用户"
+		return
+	}
+
+	// 服务商
+	if len(user.ProviderCode) == 0 {
+		user.ProviderCode = acmeutils.DefaultProviderCode
+	}
+	acmeProvider = acmeutils.FindProviderWithCode(user.ProviderCode)
+	if acmeProvider == nil {
+		errMsg = "服务商已不可用"
+		return
+	}
+
+	// 账号
+	if user.AccountId > 0 {
+		account, err := SharedACMEProviderAccountDAO.FindEnabledACMEProviderAccount(tx, int64(user.AccountId))
+		if err != nil {
+			errMsg = "查询ACME账号时出错：" + err.Error()
+			return
+		}
+		if account != nil {
+			acmeAccount = &acmeutils.Account{
+				EABKid: account.EabKid,
+				EABKey: account.EabKey,
+			}
+		}
+	}
+
+	privateKey, err := acmeutils.ParsePrivateKeyFromBase64(user.PrivateKey)
+	if err != nil {
+		errMsg = "解析私钥时出错：" + err.Error()
+		return
+	}
+
+	remoteUser = acmeutils.NewUser(user.Email, privateKey, func(resource *registration.Resource) error {
+		resourceJSON, err := json.Marshal(resource)
+		if err != nil {
+			return err
+		}
+
+		err = SharedACMEUserDAO.UpdateACMEUserRegistration(tx, int64(user.Id), resourceJSON)
+		return err
+	})
+
+	if len(user.Registration) > 0 {
+		err = remoteUser.SetRegistration(user.Registration)
+		if err != nil {
+			errMsg = "设置注册信息时出错：" + err.Error()
+			return
+		}
+	}
+
+	return
+}
@@ -1,6 +1,7 @@
 package acme
 
 import (
+	"encoding/json"
 	"github.com/dashenmiren/EdgeAPI/internal/utils"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/iwind/TeaGo/Tea"
@@ -29,11 +30,19 @@ func init() {
 }
 
 // CreateACMETaskLog 生成日志
-func (this *ACMETaskLogDAO) CreateACMETaskLog(tx *dbs.Tx, taskId int64, isOk bool, errMsg string) error {
+func (this *ACMETaskLogDAO) CreateACMETaskLog(tx *dbs.Tx, taskId int64, isOk bool, errMsg string, challenges []*ACMETaskLogChallenge) error {
 	var op = NewACMETaskLogOperator()
 	op.TaskId = taskId
 	op.Error = utils.LimitString(errMsg, 1024)
 	op.IsOk = isOk
+
+	if len(challenges) > 0 {
+		challengesJSON, err := json.Marshal(challenges)
+		if err != nil {
+			return err
+		}
+		op.Challenges = challengesJSON
+	}
 	err := this.Save(tx, op)
 	return err
 }
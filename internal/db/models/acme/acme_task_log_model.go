@@ -1,20 +1,24 @@
 package acme
 
+import "github.com/iwind/TeaGo/dbs"
+
 // ACMETaskLog ACME任务运行日志
 type ACMETaskLog struct {
-	Id        uint64 `field:"id"`        // ID
-	TaskId    uint64 `field:"taskId"`    // 任务ID
-	IsOk      bool   `field:"isOk"`      // 是否成功
-	Error     string `field:"error"`     // 错误信息
-	CreatedAt uint64 `field:"createdAt"` // 运行时间
+	Id         uint64   `field:"id"`         // ID
+	TaskId     uint64   `field:"taskId"`     // 任务ID
+	IsOk       bool     `field:"isOk"`       // 是否成功
+	Error      string   `field:"error"`      // 错误信息
+	CreatedAt  uint64   `field:"createdAt"`  // 运行时间
+	Challenges dbs.JSON `field:"challenges"` // 每个域名使用的认证方式
 }
 
 type ACMETaskLogOperator struct {
-	Id        interface{} // ID
-	TaskId    interface{} // 任务ID
-	IsOk      interface{} // 是否成功
-	Error     interface{} // 错误信息
-	CreatedAt interface{} // 运行时间
+	Id         interface{} // ID
+	TaskId     interface{} // 任务ID
+	IsOk       interface{} // 是否成功
+	Error      interface{} // 错误信息
+	CreatedAt  interface{} // 运行时间
+	Challenges interface{} // 每个域名使用的认证方式
 }
 
 func NewACMETaskLogOperator() *ACMETaskLogOperator {
@@ -1 +1,26 @@
 package acme
+
+import (
+	"encoding/json"
+	"github.com/dashenmiren/EdgeAPI/internal/db/models"
+	"github.com/iwind/TeaGo/logs"
+)
+
+// ACMETaskLogChallenge 域名认证记录
+type ACMETaskLogChallenge struct {
+	Domain   string `json:"domain"`   // 域名
+	AuthType string `json:"authType"` // 认证方式
+}
+
+// DecodeChallenges 解析域名认证记录
+func (this *ACMETaskLog) DecodeChallenges() []*ACMETaskLogChallenge {
+	var result = []*ACMETaskLogChallenge{}
+	if models.IsNull(this.Challenges) {
+		return result
+	}
+	err := json.Unmarshal(this.Challenges, &result)
+	if err != nil {
+		logs.Error(err)
+	}
+	return result
+}
@@ -2,6 +2,8 @@ package acme
 
 import (
 	"encoding/json"
+	acmeutils "github.com/dashenmiren/EdgeAPI/internal/acme"
+	"github.com/dashenmiren/EdgeAPI/internal/db/models"
 	"github.com/iwind/TeaGo/logs"
 )
 
@@ -18,3 +20,61 @@ func (this *ACMETask) DecodeDomains() []string {
 	}
 	return result
 }
+
+// ACMETaskRenewalInfo 从ACME服务商查询到的ARI续期信息
+type ACMETaskRenewalInfo struct {
+	IsSupported    bool   `json:"isSupported"`    // 服务商是否支持ARI
+	WindowStart    int64  `json:"windowStart"`    // 建议续期时间窗口开始时间
+	WindowEnd      int64  `json:"windowEnd"`      // 建议续期时间窗口结束时间
+	RenewAt        int64  `json:"renewAt"`        // 在时间窗口中选定的续期时间
+	ExplanationURL string `json:"explanationURL"` // 服务商的说明
+	CheckedAt      int64  `json:"checkedAt"`      // 上次查询时间
+}
+
+// DecodeRenewalInfo 解析ARI续期信息
+func (this *ACMETask) DecodeRenewalInfo() *ACMETaskRenewalInfo {
+	if models.IsNull(this.RenewalInfo) {
+		return nil
+	}
+	var info = &ACMETaskRenewalInfo{}
+	err := json.Unmarshal(this.RenewalInfo, info)
+	if err != nil {
+		logs.Error(err)
+		return nil
+	}
+	return info
+}
+
+// DecodeKeyType 私钥类型
+func (this *ACMETask) DecodeKeyType() string {
+	if len(this.KeyType) == 0 {
+		return acmeutils.DefaultKeyType
+	}
+	return this.KeyType
+}
+
+// DecodeDomainAuthTypes 解析单个域名的认证类型
+func (this *ACMETask) DecodeDomainAuthTypes() map[string]acmeutils.AuthType {
+	var result = map[string]acmeutils.AuthType{}
+	if models.IsNull(this.DomainAuthTypes) {
+		return result
+	}
+	err := json.Unmarshal(this.DomainAuthTypes, &result)
+	if err != nil {
+		logs.Error(err)
+	}
+	return result
+}
+
+// UsesAuthType 检查任务中是否有域名使用某个认证类型
+func (this *ACMETask) UsesAuthType(authType acmeutils.AuthType) bool {
+	if this.AuthType == authType {
+		return true
+	}
+	for _, domainAuthType := range this.DecodeDomainAuthTypes() {
+		if domainAuthType == authType {
+			return true
+		}
+	}
+	return false
+}
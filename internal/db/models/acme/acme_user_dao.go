@@ -7,6 +7,7 @@ import (
 	"crypto/x509"
 	"encoding/base64"
 	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/dashenmiren/EdgeAPI/internal/secrets"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/iwind/TeaGo/Tea"
 	"github.com/iwind/TeaGo/dbs"
@@ -18,6 +19,8 @@ const (
 	ACMEUserStateDisabled = 0 // 已禁用
 )
 
+const acmeUserPrivateKeySecretContext = "acmeUser.privateKey"
+
 type ACMEUserDAO dbs.DAO
 
 func NewACMEUserDAO() *ACMEUserDAO {
@@ -63,10 +66,15 @@ func (this *ACMEUserDAO) FindEnabledACMEUser(tx *dbs.Tx, id int64) (*ACMEUser, e
 		Pk(id).
 		Attr("state", ACMEUserStateEnabled).
 		Find()
-	if result == nil {
+	if err != nil || result == nil {
+		return nil, err
+	}
+	var user = result.(*ACMEUser)
+	err = this.decodePrivateKey(user)
+	if err != nil {
 		return nil, err
 	}
-	return result.(*ACMEUser), err
+	return user, nil
 }
 
 // CreateACMEUser 创建用户
@@ -81,7 +89,10 @@ func (this *ACMEUserDAO) CreateACMEUser(tx *dbs.Tx, adminId int64, userId int64,
 	if err != nil {
 		return 0, err
 	}
-	privateKeyText := base64.StdEncoding.EncodeToString(privateKeyData)
+	privateKeyText, err := secrets.EncryptString(acmeUserPrivateKeySecretContext, base64.StdEncoding.EncodeToString(privateKeyData))
+	if err != nil {
+		return 0, err
+	}
 
 	var op = NewACMEUserOperator()
 	op.AdminId = adminId
@@ -162,6 +173,15 @@ func (this *ACMEUserDAO) ListACMEUsers(tx *dbs.Tx, adminId int64, userId int64,
 		Slice(&result).
 		DescPk().
 		FindAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range result {
+		err = this.decodePrivateKey(user)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return
 }
 
@@ -187,6 +207,15 @@ func (this *ACMEUserDAO) FindAllACMEUsers(tx *dbs.Tx, adminId int64, userId int6
 		Slice(&result).
 		DescPk().
 		FindAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range result {
+		err = this.decodePrivateKey(user)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return
 }
 
@@ -209,3 +238,44 @@ func (this *ACMEUserDAO) CheckACMEUser(tx *dbs.Tx, acmeUserId int64, adminId int
 		State(ACMEUserStateEnabled).
 		Exist()
 }
+
+// ResealSecrets 使用当前主密钥加密所有用户的私钥
+// rotate 为 true 时同时将使用旧主密钥加密的数据密钥重新加密
+func (this *ACMEUserDAO) ResealSecrets(tx *dbs.Tx, keyring *secrets.Keyring, rotate bool) (count int64, err error) {
+	ones, err := this.Query(tx).
+		Result("id", "privateKey").
+		AscPk().
+		FindAll()
+	if err != nil {
+		return 0, err
+	}
+	for _, one := range ones {
+		var user = one.(*ACMEUser)
+		newPrivateKey, changed, err := keyring.Reseal(acmeUserPrivateKeySecretContext, user.PrivateKey, rotate)
+		if err != nil {
+			return count, errors.New("acme user '" + types.String(user.Id) + "': " + err.Error())
+		}
+		if !changed {
+			continue
+		}
+		err = this.Query(tx).
+			Pk(user.Id).
+			Set("privateKey", newPrivateKey).
+			UpdateQuickly()
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return
+}
+
+// 解密私钥
+func (this *ACMEUserDAO) decodePrivateKey(user *ACMEUser) error {
+	privateKey, err := secrets.DecryptString(acmeUserPrivateKeySecretContext, user.PrivateKey)
+	if err != nil {
+		return errors.New("decrypt private key of acme user '" + types.String(user.Id) + "' failed: " + err.Error())
+	}
+	user.PrivateKey = privateKey
+	return nil
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+)
+
+// 只读方法前缀
+var apiReadOnlyMethodPrefixes = []string{"Find", "List", "Count", "Check", "Exists", "Compose", "Read", "Lookup", "Sum", "Explain"}
+
+// APIAccessScope AccessKey和AccessToken的权限范围
+type APIAccessScope struct {
+	Methods  []*APIAccessScopeMethod `json:"methods"`  // 允许调用的服务和方法，为空表示不限制
+	AllowIPs []string                `json:"allowIPs"` // 允许访问的IP或CIDR，为空表示不限制
+
+	ipNets []*net.IPNet
+	ips    []net.IP
+}
+
+// APIAccessScopeMethod 允许调用的服务和方法
+type APIAccessScopeMethod struct {
+	Service  string `json:"service"`  // 服务名，比如 HTTPCacheTaskService，"*" 表示所有服务
+	Method   string `json:"method"`   // 方法名，比如 CreateHTTPCacheTask，"*" 或为空表示所有方法
+	ReadOnly bool   `json:"readOnly"` // 是否只允许只读方法
+}
+
+// DecodeAPIAccessScope 解析权限范围
+// 如果JSON为空，则返回nil，表示不限制
+func DecodeAPIAccessScope(scopeJSON []byte) (*APIAccessScope, error) {
+	if IsNull(scopeJSON) {
+		return nil, nil
+	}
+	var scope = &APIAccessScope{}
+	err := json.Unmarshal(scopeJSON, scope)
+	if err != nil {
+		return nil, err
+	}
+	err = scope.Init()
+	if err != nil {
+		return nil, err
+	}
+	return scope, nil
+}
+
+// Init 初始化
+func (this *APIAccessScope) Init() error {
+	this.ipNets = nil
+	this.ips = nil
+
+	for _, allowIP := range this.AllowIPs {
+		allowIP = strings.TrimSpace(allowIP)
+		if len(allowIP) == 0 {
+			continue
+		}
+		if strings.Contains(allowIP, "/") {
+			_, ipNet, err := net.ParseCIDR(allowIP)
+			if err != nil {
+				return errors.New("invalid cidr '" + allowIP + "'")
+			}
+			this.ipNets = append(this.ipNets, ipNet)
+		} else {
+			var ip = net.ParseIP(allowIP)
+			if ip == nil {
+				return errors.New("invalid ip '" + allowIP + "'")
+			}
+			this.ips = append(this.ips, ip)
+		}
+	}
+
+	for _, method := range this.Methods {
+		if method == nil || len(method.Service) == 0 {
+			return errors.New("'service' should not be empty")
+		}
+	}
+
+	return nil
+}
+
+// AllowMethod 检查是否允许调用某个方法
+func (this *APIAccessScope) AllowMethod(serviceName string, methodName string) bool {
+	if this == nil || len(this.Methods) == 0 {
+		return true
+	}
+
+	for _, method := range this.Methods {
+		if method == nil {
+			continue
+		}
+		if method.Service != "*" && method.Service != serviceName {
+			continue
+		}
+		if len(method.Method) > 0 && method.Method != "*" && method.Method != methodName {
+			continue
+		}
+		if method.ReadOnly && !IsAPIReadOnlyMethod(methodName) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// AllowIP 检查是否允许某个IP访问
+func (this *APIAccessScope) AllowIP(ipString string) bool {
+	if this == nil || len(this.AllowIPs) == 0 {
+		return true
+	}
+
+	var ip = net.ParseIP(ipString)
+	if ip == nil {
+		return false
+	}
+	for _, allowIP := range this.ips {
+		if allowIP.Equal(ip) {
+			return true
+		}
+	}
+	for _, ipNet := range this.ipNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAPIReadOnlyMethod 判断是否为只读方法
+func IsAPIReadOnlyMethod(methodName string) bool {
+	for _, prefix := range apiReadOnlyMethodPrefixes {
+		if strings.HasPrefix(methodName, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package models_test
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/db/models"
+	"testing"
+)
+
+func TestDecodeAPIAccessScope(t *testing.T) {
+	{
+		scope, err := models.DecodeAPIAccessScope(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Log(scope.AllowMethod("NodeService", "DeleteNode"), scope.AllowIP("127.0.0.1"))
+	}
+
+	{
+		scope, err := models.DecodeAPIAccessScope([]byte(`{
+	"methods": [
+		{ "service": "HTTPCacheTaskService", "method": "CreateHTTPCacheTask" },
+		{ "service": "ServerService", "readOnly": true }
+	],
+	"allowIPs": [ "192.168.1.0/24", "10.0.0.1" ]
+}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, item := range [][2]string{
+			{"HTTPCacheTaskService", "CreateHTTPCacheTask"},
+			{"HTTPCacheTaskService", "DeleteHTTPCacheTask"},
+			{"ServerService", "FindEnabledServer"},
+			{"ServerService", "DeleteServer"},
+			{"NodeService", "FindEnabledNode"},
+		} {
+			t.Log(item[0], item[1], scope.AllowMethod(item[0], item[1]))
+		}
+		for _, ip := range []string{"192.168.1.100", "192.168.2.1", "10.0.0.1", "10.0.0.2", "abc"} {
+			t.Log(ip, scope.AllowIP(ip))
+		}
+
+		if scope.AllowMethod("ServerService", "DeleteServer") {
+			t.Fatal("'DeleteServer' should not be allowed")
+		}
+		if !scope.AllowIP("192.168.1.100") {
+			t.Fatal("'192.168.1.100' should be allowed")
+		}
+	}
+
+	{
+		_, err := models.DecodeAPIAccessScope([]byte(`{ "allowIPs": [ "192.168.1.0/33" ] }`))
+		if err == nil {
+			t.Fatal("invalid cidr should be reported")
+		}
+		t.Log("expected error:", err)
+	}
+}
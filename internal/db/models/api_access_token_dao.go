@@ -31,7 +31,8 @@ func init() {
 }
 
 // GenerateAccessToken 生成AccessToken
-func (this *APIAccessTokenDAO) GenerateAccessToken(tx *dbs.Tx, adminId int64, userId int64) (token string, expiresAt int64, err error) {
+// 每个AccessKey对应一个AccessToken，AccessToken继承AccessKey的权限范围
+func (this *APIAccessTokenDAO) GenerateAccessToken(tx *dbs.Tx, adminId int64, userId int64, accessKeyId int64, scopeJSON []byte) (token string, expiresAt int64, err error) {
 	if adminId <= 0 && userId <= 0 {
 		err = errors.New("either 'adminId' or 'userId' should not be zero")
 		return
@@ -48,6 +49,7 @@ func (this *APIAccessTokenDAO) GenerateAccessToken(tx *dbs.Tx, adminId int64, us
 	accessToken, err := this.Query(tx).
 		Attr("adminId", adminId).
 		Attr("userId", userId).
+		Attr("accessKeyId", accessKeyId).
 		Find()
 	if err != nil {
 		return "", 0, err
@@ -64,7 +66,13 @@ func (this *APIAccessTokenDAO) GenerateAccessToken(tx *dbs.Tx, adminId int64, us
 
 	op.AdminId = adminId
 	op.UserId = userId
+	op.AccessKeyId = accessKeyId
 	op.Token = token
+	if IsNull(scopeJSON) {
+		op.Scope = dbs.SQL("NULL")
+	} else {
+		op.Scope = scopeJSON
+	}
 	op.CreatedAt = time.Now().Unix()
 	op.ExpiredAt = expiresAt
 	err = this.Save(tx, op)
@@ -82,6 +90,26 @@ func (this *APIAccessTokenDAO) FindAccessToken(tx *dbs.Tx, token string) (*APIAc
 	return one.(*APIAccessToken), nil
 }
 
+// RevokeAccessToken 吊销令牌
+func (this *APIAccessTokenDAO) RevokeAccessToken(tx *dbs.Tx, token string) error {
+	if len(token) == 0 {
+		return nil
+	}
+	return this.Query(tx).
+		Attr("token", token).
+		DeleteQuickly()
+}
+
+// RevokeAccessTokensWithAccessKeyId 吊销某个AccessKey生成的所有令牌
+func (this *APIAccessTokenDAO) RevokeAccessTokensWithAccessKeyId(tx *dbs.Tx, accessKeyId int64) error {
+	if accessKeyId <= 0 {
+		return nil
+	}
+	return this.Query(tx).
+		Attr("accessKeyId", accessKeyId).
+		DeleteQuickly()
+}
+
 // DeleteAccessTokens 删除用户的令牌
 func (this *APIAccessTokenDAO) DeleteAccessTokens(tx *dbs.Tx, adminId int64, userId int64) error {
 	var query = this.Query(tx)
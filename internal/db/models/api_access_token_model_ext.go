@@ -1 +1,6 @@
 package models
+
+// DecodeScope 解析权限范围
+func (this *APIAccessToken) DecodeScope() (*APIAccessScope, error) {
+	return DecodeAPIAccessScope(this.Scope)
+}
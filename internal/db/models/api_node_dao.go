@@ -3,7 +3,6 @@ package models
 import (
 	"encoding/json"
 	"errors"
-	"github.com/dashenmiren/EdgeAPI/internal/configs"
 	"github.com/dashenmiren/EdgeAPI/internal/remotelogs"
 	"github.com/dashenmiren/EdgeAPI/internal/utils"
 	"github.com/dashenmiren/EdgeCommon/pkg/nodeconfigs"
@@ -331,6 +330,24 @@ func (this *APINodeDAO) UpdateAPINodeStatus(tx *dbs.Tx, apiNodeId int64, statusJ
 	return err
 }
 
+// UpdateAPINodeMetricsHTTP 设置API节点监控指标HTTP配置
+func (this *APINodeDAO) UpdateAPINodeMetricsHTTP(tx *dbs.Tx, apiNodeId int64, metricsHTTPJSON []byte) error {
+	if apiNodeId <= 0 {
+		return errors.New("invalid apiNodeId")
+	}
+	if len(metricsHTTPJSON) == 0 {
+		metricsHTTPJSON = []byte("{}")
+	}
+	err := this.Query(tx).
+		Pk(apiNodeId).
+		Set("metricsHTTP", metricsHTTPJSON).
+		UpdateQuickly()
+	if err != nil {
+		return err
+	}
+	return this.NotifyUpdate(tx, apiNodeId)
+}
+
 // CountAllLowerVersionNodes 计算所有节点中低于某个版本的节点数量
 func (this *APINodeDAO) CountAllLowerVersionNodes(tx *dbs.Tx, version string) (int64, error) {
 	return this.Query(tx).
@@ -407,43 +424,9 @@ func (this *APINodeDAO) FindAllEnabledAPIAccessIPs(tx *dbs.Tx, cacheMap *utils.C
 }
 
 // CheckAPINodeIsPrimary 检查当前节点是否为Primary节点
+// 持有未过期主节点租约的节点为Primary节点，参考 APINodePrimaryElector
 func (this *APINodeDAO) CheckAPINodeIsPrimary(tx *dbs.Tx) (bool, error) {
-	config, err := configs.SharedAPIConfig()
-	if err != nil {
-		return false, err
-	}
-
-	isPrimary, err := this.Query(tx).
-		State(APINodeStateEnabled).
-		Attr("uniqueId", config.NodeId).
-		Attr("isPrimary", true).
-		Exist()
-	if err != nil {
-		return false, err
-	}
-
-	if isPrimary {
-		return true, nil
-	}
-
-	// 检查是否有别的Primary节点
-	count, err := this.Query(tx).
-		State(APINodeStateEnabled).
-		Attr("isOn", true).
-		Attr("isPrimary", true).
-		Count()
-	if err != nil {
-		return false, err
-	}
-
-	if count == 0 {
-		err = this.ResetPrimaryAPINode(tx)
-		if err != nil {
-			return false, err
-		}
-		return true, nil
-	}
-	return false, nil
+	return SharedAPINodePrimaryElector.IsPrimary(), nil
 }
 
 // CheckAPINodeIsPrimaryWithoutErr 检查当前节点是否为Primary节点，并忽略错误
@@ -452,51 +435,14 @@ func (this *APINodeDAO) CheckAPINodeIsPrimaryWithoutErr() bool {
 	return b && err == nil
 }
 
-// ResetPrimaryAPINode 重置Primary节点
-func (this *APINodeDAO) ResetPrimaryAPINode(tx *dbs.Tx) error {
-	// 当前是否有Primary节点
-	apiNode, err := this.Query(tx).
-		State(APINodeStateEnabled).
-		Attr("isOn", true).
-		Attr("isPrimary", true).
-		Find()
-	if err != nil {
-		return err
-	}
-	if apiNode == nil {
-		// 选择一个作为Primary
-		// TODO 将来需要考虑API节点离线的情况
-		apiNodeId, err := this.Query(tx).
-			State(APINodeStateEnabled).
-			Attr("isOn", true).
-			ResultPk().
-			FindInt64Col(0)
-		if err != nil {
-			return err
-		}
-		if apiNodeId > 0 {
-			err = this.Query(tx).
-				Pk(apiNodeId).
-				Set("isPrimary", true).
-				UpdateQuickly()
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return nil
-}
-
 // NotifyUpdate 通知变更
+// 主节点由租约决定：当前没有指定主节点时，不再自动指定，由获取到租约的节点在 APINodePrimaryElector 中标记自己，
+// 避免把离线的节点指定为主节点，导致正常持有租约的节点交出租约
 func (this *APINodeDAO) NotifyUpdate(tx *dbs.Tx, apiNodeId int64) error {
 	// suppress IDE warning
+	_ = tx
 	_ = apiNodeId
 
-	err := this.ResetPrimaryAPINode(tx)
-	if err != nil {
-		return err
-	}
-
 	return nil
 }
 
@@ -24,6 +24,7 @@ type APINode struct {
 	Weight      uint32   `field:"weight"`      // 权重
 	Status      dbs.JSON `field:"status"`      // 运行状态
 	IsPrimary   bool     `field:"isPrimary"`   // 是否为主API节点
+	MetricsHTTP dbs.JSON `field:"metricsHTTP"` // 监控指标HTTP配置
 }
 
 type APINodeOperator struct {
@@ -47,6 +48,7 @@ type APINodeOperator struct {
 	Weight      interface{} // 权重
 	Status      interface{} // 运行状态
 	IsPrimary   interface{} // 是否为主API节点
+	MetricsHTTP interface{} // 监控指标HTTP配置
 }
 
 func NewAPINodeOperator() *APINodeOperator {
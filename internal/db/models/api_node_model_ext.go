@@ -119,6 +119,25 @@ func (this *APINode) DecodeRestHTTP() (*serverconfigs.HTTPProtocolConfig, error)
 	return config, nil
 }
 
+// DecodeMetricsHTTP 解析监控指标HTTP配置
+func (this *APINode) DecodeMetricsHTTP() (*serverconfigs.HTTPProtocolConfig, error) {
+	if !IsNotNull(this.MetricsHTTP) {
+		return nil, nil
+	}
+	config := &serverconfigs.HTTPProtocolConfig{}
+	err := json.Unmarshal(this.MetricsHTTP, config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = config.Init()
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
 // DecodeRestHTTPS 解析HTTPS配置
 func (this *APINode) DecodeRestHTTPS(tx *dbs.Tx, cacheMap *utils.CacheMap) (*serverconfigs.HTTPSProtocolConfig, error) {
 	if cacheMap == nil {
@@ -0,0 +1,291 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package models
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/remotelogs"
+	"github.com/iwind/TeaGo/dbs"
+	"github.com/iwind/TeaGo/types"
+	"sync"
+	"time"
+)
+
+// 主节点租约在 edgeSysLockers 中的键值
+const APINodePrimaryLeaseKey = "API_NODE_PRIMARY_LEASE"
+
+// 主节点变更原因
+const (
+	APINodePrimaryReasonElected  = "elected"  // 租约过期或者被释放后当选
+	APINodePrimaryReasonLost     = "lost"     // 未能及时续约，租约已被其他节点获取或者已过期
+	APINodePrimaryReasonHandover = "handover" // 管理员指定了其他主节点，或者当前节点被停用
+)
+
+var SharedAPINodePrimaryElector = NewAPINodePrimaryElector()
+
+// APINodePrimaryElector 基于租约的主节点选举
+// 每个API节点定期尝试获取或者续约同一个租约，持有租约的节点为主节点；主节点离线后租约过期，由其他健康的节点接管。
+// 管理员设置的 isPrimary 节点优先获取租约，其他节点要在租约过期一个有效期之后才能获取。
+// 每次获取租约时版本号加1，作为防护令牌（fencing token）交给需要在主节点上运行的任务，用来判断执行过程中是否发生过主节点切换。
+type APINodePrimaryElector struct {
+	nodeId int64
+	ttl    int64
+
+	locker    sync.RWMutex
+	token     int64
+	expiresAt time.Time // 本地判断租约是否有效的截止时间
+
+	isStarted bool
+	isStopped bool
+	stopChan  chan struct{}
+
+	tickLocker sync.Mutex // 保证 Stop() 释放租约之后不会再有 Tick() 获取租约
+}
+
+func NewAPINodePrimaryElector() *APINodePrimaryElector {
+	return &APINodePrimaryElector{
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start 开始选举
+// nodeId 为当前API节点的数字ID，ttlSeconds 为租约有效期
+func (this *APINodePrimaryElector) Start(nodeId int64, ttlSeconds int64, renewInterval time.Duration) {
+	this.locker.Lock()
+	if this.isStarted {
+		this.locker.Unlock()
+		return
+	}
+	this.isStarted = true
+	this.nodeId = nodeId
+	this.ttl = ttlSeconds
+	this.locker.Unlock()
+
+	this.Tick()
+
+	var ticker = time.NewTicker(renewInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			this.Tick()
+		case <-this.stopChan:
+			return
+		}
+	}
+}
+
+// Stop 停止当前节点时释放租约
+// 如果当前节点就是管理员指定的主节点，或者没有指定主节点，则所有节点都可以立即接管，不需要等待一个租约有效期；
+// 否则由指定的主节点立即接管
+// 停止后不再获取或者续约租约
+func (this *APINodePrimaryElector) Stop() {
+	this.tickLocker.Lock()
+	defer this.tickLocker.Unlock()
+
+	if this.isStopped {
+		return
+	}
+	this.isStopped = true
+	close(this.stopChan)
+
+	token, ok := this.Token()
+	if !ok {
+		return
+	}
+	preferredNodeId, err := this.findPreferredNodeId(nil)
+	if err != nil {
+		remotelogs.Error("API_NODE_PRIMARY", "find primary node failed: "+err.Error())
+	}
+	var releaseToAll = preferredNodeId <= 0 || preferredNodeId == this.nodeId
+	err = this.release(nil, token, releaseToAll, "node stopped")
+	if err != nil {
+		remotelogs.Error("API_NODE_PRIMARY", "release primary lease failed: "+err.Error())
+	}
+}
+
+// Tick 执行一次获取或者续约
+func (this *APINodePrimaryElector) Tick() {
+	this.tickLocker.Lock()
+	defer this.tickLocker.Unlock()
+
+	if this.isStopped {
+		return
+	}
+
+	err := this.tick(nil)
+	if err != nil {
+		remotelogs.Error("API_NODE_PRIMARY", err.Error())
+	}
+}
+
+// IsPrimary 当前节点是否持有未过期的租约
+func (this *APINodePrimaryElector) IsPrimary() bool {
+	_, ok := this.Token()
+	return ok
+}
+
+// Token 读取当前持有的防护令牌
+func (this *APINodePrimaryElector) Token() (token int64, ok bool) {
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+	if this.token <= 0 || !time.Now().Before(this.expiresAt) {
+		return 0, false
+	}
+	return this.token, true
+}
+
+// IsToken 检查防护令牌是否为当前节点持有的有效令牌
+func (this *APINodePrimaryElector) IsToken(token int64) bool {
+	currentToken, ok := this.Token()
+	return ok && token > 0 && currentToken == token
+}
+
+// CheckToken 在数据库中检查防护令牌是否仍然持有租约，用于执行重要操作之前的校验
+func (this *APINodePrimaryElector) CheckToken(tx *dbs.Tx, token int64) (bool, error) {
+	if !this.IsToken(token) {
+		return false, nil
+	}
+	return SharedSysLockerDAO.CheckLease(tx, APINodePrimaryLeaseKey, token)
+}
+
+func (this *APINodePrimaryElector) tick(tx *dbs.Tx) error {
+	// 以发起请求的时间作为本地租约的起点，保证本地判断的截止时间不晚于数据库中的过期时间
+	var startTime = time.Now()
+
+	this.locker.RLock()
+	var token = this.token
+	this.locker.RUnlock()
+
+	node, err := SharedAPINodeDAO.Query(tx).
+		Pk(this.nodeId).
+		Result("id", "isOn", "isPrimary", "state").
+		Find()
+	if err != nil {
+		return err
+	}
+	var isAvailable = node != nil && node.(*APINode).IsOn && node.(*APINode).State == APINodeStateEnabled
+
+	// 续约
+	if token > 0 {
+		if !isAvailable {
+			return this.release(tx, token, false, "node is disabled")
+		}
+
+		preferredNodeId, err := this.findPreferredNodeId(tx)
+		if err != nil {
+			return err
+		}
+		if preferredNodeId > 0 && preferredNodeId != this.nodeId {
+			return this.release(tx, token, false, "primary node has been changed to '"+types.String(preferredNodeId)+"'")
+		}
+
+		ok, err := SharedSysLockerDAO.RenewLease(tx, APINodePrimaryLeaseKey, token, this.ttl)
+		if err != nil {
+			// 数据库错误时，在本地租约过期之前仍然认为是主节点
+			return err
+		}
+		if ok {
+			this.setToken(token, startTime)
+			return nil
+		}
+
+		this.setToken(0, startTime)
+		remotelogs.Warn("API_NODE_PRIMARY", "lost primary lease, token: "+types.String(token))
+		return SharedSysEventDAO.CreateEvent(tx, &APINodePrimaryChangedEvent{
+			NodeId:    this.nodeId,
+			Token:     token,
+			Reason:    APINodePrimaryReasonLost,
+			CreatedAt: time.Now().Unix(),
+		})
+	}
+
+	// 获取
+	if !isAvailable {
+		return nil
+	}
+	preferredNodeId, err := this.findPreferredNodeId(tx)
+	if err != nil {
+		return err
+	}
+	var graceSeconds int64 = 0
+	if preferredNodeId > 0 && preferredNodeId != this.nodeId {
+		graceSeconds = this.ttl
+	}
+	newToken, err := SharedSysLockerDAO.AcquireLease(tx, APINodePrimaryLeaseKey, this.ttl, graceSeconds)
+	if err != nil {
+		return err
+	}
+	if newToken <= 0 {
+		return nil
+	}
+	this.setToken(newToken, startTime)
+
+	// 标记为主节点
+	if preferredNodeId != this.nodeId {
+		err = SharedAPINodeDAO.Query(tx).
+			Neq("id", this.nodeId).
+			Attr("isPrimary", true).
+			Set("isPrimary", false).
+			UpdateQuickly()
+		if err != nil {
+			return err
+		}
+		err = SharedAPINodeDAO.Query(tx).
+			Pk(this.nodeId).
+			Set("isPrimary", true).
+			UpdateQuickly()
+		if err != nil {
+			return err
+		}
+	}
+
+	remotelogs.Println("API_NODE_PRIMARY", "became primary node, token: "+types.String(newToken))
+	return SharedSysEventDAO.CreateEvent(tx, &APINodePrimaryChangedEvent{
+		NodeId:    this.nodeId,
+		OldNodeId: preferredNodeId,
+		Token:     newToken,
+		Reason:    APINodePrimaryReasonElected,
+		CreatedAt: time.Now().Unix(),
+	})
+}
+
+// 释放租约
+// releaseToAll 参考 SysLockerDAO.ReleaseLease()
+func (this *APINodePrimaryElector) release(tx *dbs.Tx, token int64, releaseToAll bool, reason string) error {
+	this.setToken(0, time.Now())
+
+	err := SharedSysLockerDAO.ReleaseLease(tx, APINodePrimaryLeaseKey, token, releaseToAll)
+	if err != nil {
+		return err
+	}
+
+	remotelogs.Println("API_NODE_PRIMARY", "release primary lease: "+reason)
+	return SharedSysEventDAO.CreateEvent(tx, &APINodePrimaryChangedEvent{
+		NodeId:    this.nodeId,
+		Token:     token,
+		Reason:    APINodePrimaryReasonHandover,
+		Message:   reason,
+		CreatedAt: time.Now().Unix(),
+	})
+}
+
+// 查找管理员指定的主节点
+func (this *APINodePrimaryElector) findPreferredNodeId(tx *dbs.Tx) (int64, error) {
+	return SharedAPINodeDAO.Query(tx).
+		State(APINodeStateEnabled).
+		Attr("isOn", true).
+		Attr("isPrimary", true).
+		ResultPk().
+		FindInt64Col(0)
+}
+
+func (this *APINodePrimaryElector) setToken(token int64, startTime time.Time) {
+	this.locker.Lock()
+	this.token = token
+	if token > 0 {
+		this.expiresAt = startTime.Add(time.Duration(this.ttl) * time.Second)
+	} else {
+		this.expiresAt = time.Time{}
+	}
+	this.locker.Unlock()
+}
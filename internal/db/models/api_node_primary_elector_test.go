@@ -0,0 +1,29 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAPINodePrimaryElector_Stop(t *testing.T) {
+	var elector = NewAPINodePrimaryElector()
+	elector.Stop()
+	elector.Stop() // 可以重复调用
+
+	// 停止之后 Start() 中的循环应该立即退出，也不会再获取租约
+	var done = make(chan struct{})
+	go func() {
+		elector.Start(1, 30, 10*time.Millisecond)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Start() should return after Stop()")
+	}
+	if elector.IsPrimary() {
+		t.Fatal("stopped elector should not be primary")
+	}
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	teaconst "github.com/dashenmiren/EdgeAPI/internal/const"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/iwind/TeaGo/Tea"
+	"github.com/iwind/TeaGo/dbs"
+	timeutil "github.com/iwind/TeaGo/utils/time"
+)
+
+type APIStreamStatDAO dbs.DAO
+
+func NewAPIStreamStatDAO() *APIStreamStatDAO {
+	return dbs.NewDAO(&APIStreamStatDAO{
+		DAOObject: dbs.DAOObject{
+			DB:     Tea.Env,
+			Table:  "edgeAPIStreamStats",
+			Model:  new(APIStreamStat),
+			PkName: "id",
+		},
+	}).(*APIStreamStatDAO)
+}
+
+var SharedAPIStreamStatDAO *APIStreamStatDAO
+
+func init() {
+	dbs.OnReady(func() {
+		SharedAPIStreamStatDAO = NewAPIStreamStatDAO()
+	})
+}
+
+// CreateStat 记录一个Stream结束后的统计数据
+func (this *APIStreamStatDAO) CreateStat(tx *dbs.Tx, method string, role string, lifetimeSeconds float64, countRecvMessages int64, countSentMessages int64, recvBytes int64, sentBytes int64) error {
+	var day = timeutil.Format("Ymd")
+	return this.Query(tx).
+		Param("lifetimeSeconds", lifetimeSeconds).
+		Param("countRecvMessages", countRecvMessages).
+		Param("countSentMessages", countSentMessages).
+		Param("recvBytes", recvBytes).
+		Param("sentBytes", sentBytes).
+		InsertOrUpdateQuickly(map[string]interface{}{
+			"apiNodeId":         teaconst.NodeId,
+			"method":            method,
+			"role":              role,
+			"countStreams":      1,
+			"lifetimeSeconds":   lifetimeSeconds,
+			"countRecvMessages": countRecvMessages,
+			"countSentMessages": countSentMessages,
+			"recvBytes":         recvBytes,
+			"sentBytes":         sentBytes,
+			"day":               day,
+		}, map[string]interface{}{
+			"countStreams":      dbs.SQL("countStreams+1"),
+			"lifetimeSeconds":   dbs.SQL("lifetimeSeconds+:lifetimeSeconds"),
+			"countRecvMessages": dbs.SQL("countRecvMessages+:countRecvMessages"),
+			"countSentMessages": dbs.SQL("countSentMessages+:countSentMessages"),
+			"recvBytes":         dbs.SQL("recvBytes+:recvBytes"),
+			"sentBytes":         dbs.SQL("sentBytes+:sentBytes"),
+		})
+}
+
+// FindAllStatsWithDay 查询某天的统计
+func (this *APIStreamStatDAO) FindAllStatsWithDay(tx *dbs.Tx, day string) (result []*APIStreamStat, err error) {
+	_, err = this.Query(tx).
+		Attr("day", day).
+		Asc("method").
+		Asc("role").
+		Slice(&result).
+		FindAll()
+	return
+}
+
+// Clean 清理数据
+func (this *APIStreamStatDAO) Clean(tx *dbs.Tx) error {
+	var day = timeutil.Format("Ymd")
+	_, err := this.Query(tx).
+		Param("day", day).
+		Where("day<:day").
+		Delete()
+	return err
+}
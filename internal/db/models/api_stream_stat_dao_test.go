@@ -0,0 +1,19 @@
+package models
+
+import (
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/iwind/TeaGo/bootstrap"
+	"github.com/iwind/TeaGo/dbs"
+	"testing"
+)
+
+func TestAPIStreamStatDAO_CreateStat(t *testing.T) {
+	var dao = NewAPIStreamStatDAO()
+	var tx *dbs.Tx
+
+	err := dao.CreateStat(tx, "/pb.NodeService/NodeStream", "node", 3600, 10, 20, 1024, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log("ok")
+}
@@ -0,0 +1,34 @@
+package models
+
+// APIStreamStat API Stream统计
+type APIStreamStat struct {
+	Id                uint64  `field:"id"`                // ID
+	ApiNodeId         uint32  `field:"apiNodeId"`         // API节点ID
+	Method            string  `field:"method"`            // 方法
+	Role              string  `field:"role"`              // 角色
+	CountStreams      uint64  `field:"countStreams"`      // Stream数量
+	LifetimeSeconds   float64 `field:"lifetimeSeconds"`   // 总连接时长（秒）
+	CountRecvMessages uint64  `field:"countRecvMessages"` // 接收的消息数
+	CountSentMessages uint64  `field:"countSentMessages"` // 发送的消息数
+	RecvBytes         uint64  `field:"recvBytes"`         // 接收的字节数
+	SentBytes         uint64  `field:"sentBytes"`         // 发送的字节数
+	Day               string  `field:"day"`               // 日期
+}
+
+type APIStreamStatOperator struct {
+	Id                interface{} // ID
+	ApiNodeId         interface{} // API节点ID
+	Method            interface{} // 方法
+	Role              interface{} // 角色
+	CountStreams      interface{} // Stream数量
+	LifetimeSeconds   interface{} // 总连接时长（秒）
+	CountRecvMessages interface{} // 接收的消息数
+	CountSentMessages interface{} // 发送的消息数
+	RecvBytes         interface{} // 接收的字节数
+	SentBytes         interface{} // 发送的字节数
+	Day               interface{} // 日期
+}
+
+func NewAPIStreamStatOperator() *APIStreamStatOperator {
+	return &APIStreamStatOperator{}
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/base64"
 	"github.com/dashenmiren/EdgeAPI/internal/encrypt"
 	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/dashenmiren/EdgeAPI/internal/remotelogs"
+	"github.com/dashenmiren/EdgeAPI/internal/secrets"
 	"github.com/dashenmiren/EdgeCommon/pkg/nodeconfigs"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/iwind/TeaGo/Tea"
@@ -21,6 +23,8 @@ type DBNodeDAO dbs.DAO
 
 const DBNodePasswordEncodedPrefix = "EDGE_ENCODED:"
 
+const dbNodePasswordSecretContext = "dbNode.password"
+
 func NewDBNodeDAO() *DBNodeDAO {
 	return dbs.NewDAO(&DBNodeDAO{
 		DAOObject: dbs.DAOObject{
@@ -118,9 +122,13 @@ func (this *DBNodeDAO) CreateDBNode(tx *dbs.Tx, isOn bool, name string, descript
 	op.Port = port
 	op.Database = database
 	op.Username = username
-	op.Password = this.EncodePassword(password)
+	encodedPassword, err := this.EncodePassword(password)
+	if err != nil {
+		return 0, err
+	}
+	op.Password = encodedPassword
 	op.Charset = charset
-	err := this.Save(tx, op)
+	err = this.Save(tx, op)
 	if err != nil {
 		return 0, err
 	}
@@ -141,9 +149,13 @@ func (this *DBNodeDAO) UpdateNode(tx *dbs.Tx, nodeId int64, isOn bool, name stri
 	op.Port = port
 	op.Database = database
 	op.Username = username
-	op.Password = this.EncodePassword(password)
+	encodedPassword, err := this.EncodePassword(password)
+	if err != nil {
+		return err
+	}
+	op.Password = encodedPassword
 	op.Charset = charset
-	err := this.Save(tx, op)
+	err = this.Save(tx, op)
 	return err
 }
 
@@ -162,16 +174,35 @@ func (this *DBNodeDAO) FindAllEnabledAndOnDBNodes(tx *dbs.Tx) (result []*DBNode,
 }
 
 // EncodePassword 加密密码
-func (this *DBNodeDAO) EncodePassword(password string) string {
-	if strings.HasPrefix(password, DBNodePasswordEncodedPrefix) {
-		return password
+// 设置了主密钥时使用信封加密，否则使用内置密钥编码；加密失败时返回错误，不会降级为内置密钥编码
+func (this *DBNodeDAO) EncodePassword(password string) (string, error) {
+	if strings.HasPrefix(password, DBNodePasswordEncodedPrefix) || secrets.IsEncrypted(password) {
+		return password, nil
+	}
+
+	encryptedPassword, err := secrets.EncryptString(dbNodePasswordSecretContext, password)
+	if err != nil {
+		return "", errors.New("encrypt password failed: " + err.Error())
+	}
+	if secrets.IsEncrypted(encryptedPassword) {
+		return encryptedPassword, nil
 	}
+
 	encodedString := base64.StdEncoding.EncodeToString(encrypt.MagicKeyEncode([]byte(password)))
-	return DBNodePasswordEncodedPrefix + encodedString
+	return DBNodePasswordEncodedPrefix + encodedString, nil
 }
 
 // DecodePassword 解密密码
 func (this *DBNodeDAO) DecodePassword(password string) string {
+	if secrets.IsEncrypted(password) {
+		decryptedPassword, err := secrets.DecryptString(dbNodePasswordSecretContext, password)
+		if err != nil {
+			remotelogs.Error("DB_NODE_DAO", "decrypt password failed: "+err.Error())
+			return password
+		}
+		return decryptedPassword
+	}
+
 	if !strings.HasPrefix(password, DBNodePasswordEncodedPrefix) {
 		return password
 	}
@@ -183,6 +214,41 @@ func (this *DBNodeDAO) DecodePassword(password string) string {
 	return string(encrypt.MagicKeyDecode(data))
 }
 
+// ResealSecrets 使用当前主密钥加密所有数据库节点的密码
+// 使用内置密钥编码的密码会先解码再加密；rotate 为 true 时同时将使用旧主密钥加密的数据密钥重新加密
+func (this *DBNodeDAO) ResealSecrets(tx *dbs.Tx, keyring *secrets.Keyring, rotate bool) (count int64, err error) {
+	ones, err := this.Query(tx).
+		Result("id", "password").
+		AscPk().
+		FindAll()
+	if err != nil {
+		return 0, err
+	}
+	for _, one := range ones {
+		var node = one.(*DBNode)
+		var password = node.Password
+		if strings.HasPrefix(password, DBNodePasswordEncodedPrefix) {
+			password = this.DecodePassword(password)
+		}
+		newPassword, changed, err := keyring.Reseal(dbNodePasswordSecretContext, password, rotate)
+		if err != nil {
+			return count, errors.New("db node '" + types.String(node.Id) + "': " + err.Error())
+		}
+		if !changed {
+			continue
+		}
+		err = this.Query(tx).
+			Pk(node.Id).
+			Set("password", newPassword).
+			UpdateQuickly()
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return
+}
+
 // CheckNodeIsOn 检查节点是否已经启用
 func (this *DBNodeDAO) CheckNodeIsOn(tx *dbs.Tx, nodeId int64) (bool, error) {
 	isOn, err := this.Query(tx).
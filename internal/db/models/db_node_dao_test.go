@@ -14,7 +14,10 @@ func TestDBNodeDAO_EncodePassword(t *testing.T) {
 		"$%#@!@(*))*&^&=]{|",
 		"中文",
 	} {
-		encoded := dao.EncodePassword(password)
+		encoded, err := dao.EncodePassword(password)
+		if err != nil {
+			t.Fatal(err)
+		}
 		decoded := dao.DecodePassword(encoded)
 		if decoded != password {
 			t.Fatal(decoded, password)
@@ -25,7 +28,10 @@ func TestDBNodeDAO_EncodePassword(t *testing.T) {
 func TestDBNodeDAO_EncodePassword_Encoded(t *testing.T) {
 	dao := NewDBNodeDAO()
 	password := DBNodePasswordEncodedPrefix + "123456"
-	encoded := dao.EncodePassword(password)
+	encoded, err := dao.EncodePassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
 	if encoded != password {
 		t.Fatal()
 	}
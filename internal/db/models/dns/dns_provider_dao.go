@@ -1,8 +1,10 @@
 package dns
 
 import (
+	"encoding/json"
 	dbutils "github.com/dashenmiren/EdgeAPI/internal/db/utils"
 	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/dashenmiren/EdgeAPI/internal/secrets"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/iwind/TeaGo/Tea"
 	"github.com/iwind/TeaGo/dbs"
@@ -15,6 +17,8 @@ const (
 	DNSProviderStateDisabled = 0 // 已禁用
 )
 
+const dnsProviderAPIParamsSecretContext = "dnsProvider.apiParams"
+
 type DNSProviderDAO dbs.DAO
 
 func NewDNSProviderDAO() *DNSProviderDAO {
@@ -60,10 +64,15 @@ func (this *DNSProviderDAO) FindEnabledDNSProvider(tx *dbs.Tx, id int64) (*DNSPr
 		Pk(id).
 		Attr("state", DNSProviderStateEnabled).
 		Find()
-	if result == nil {
+	if err != nil || result == nil {
+		return nil, err
+	}
+	var provider = result.(*DNSProvider)
+	err = this.decodeAPIParams(provider)
+	if err != nil {
 		return nil, err
 	}
-	return result.(*DNSProvider), err
+	return provider, nil
 }
 
 // CreateDNSProvider 创建服务商
@@ -74,7 +83,11 @@ func (this *DNSProviderDAO) CreateDNSProvider(tx *dbs.Tx, adminId int64, userId
 	op.Type = providerType
 	op.Name = name
 	if len(apiParamsJSON) > 0 {
-		op.ApiParams = apiParamsJSON
+		encodedParamsJSON, err := this.encodeAPIParams(apiParamsJSON)
+		if err != nil {
+			return 0, err
+		}
+		op.ApiParams = encodedParamsJSON
 	}
 
 	if minTTL >= 0 {
@@ -101,7 +114,11 @@ func (this *DNSProviderDAO) UpdateDNSProvider(tx *dbs.Tx, dnsProviderId int64, n
 
 	// 如果留空则表示不修改
 	if len(apiParamsJSON) > 0 {
-		op.ApiParams = apiParamsJSON
+		encodedParamsJSON, err := this.encodeAPIParams(apiParamsJSON)
+		if err != nil {
+			return err
+		}
+		op.ApiParams = encodedParamsJSON
 	}
 
 	if minTTL >= 0 {
@@ -157,6 +174,10 @@ func (this *DNSProviderDAO) ListEnabledDNSProviders(tx *dbs.Tx, adminId int64, u
 		DescPk().
 		Slice(&result).
 		FindAll()
+	if err != nil {
+		return nil, err
+	}
+	err = this.decodeAPIParamsList(result)
 	return
 }
 
@@ -167,6 +188,10 @@ func (this *DNSProviderDAO) FindAllEnabledDNSProviders(tx *dbs.Tx, adminId int64
 		DescPk().
 		Slice(&result).
 		FindAll()
+	if err != nil {
+		return nil, err
+	}
+	err = this.decodeAPIParamsList(result)
 	return
 }
 
@@ -178,6 +203,10 @@ func (this *DNSProviderDAO) FindAllEnabledDNSProvidersWithType(tx *dbs.Tx, provi
 		DescPk().
 		Slice(&result).
 		FindAll()
+	if err != nil {
+		return nil, err
+	}
+	err = this.decodeAPIParamsList(result)
 	return
 }
 
@@ -189,3 +218,96 @@ func (this *DNSProviderDAO) UpdateProviderDataUpdatedTime(tx *dbs.Tx, providerId
 		Update()
 	return err
 }
+
+// ResealSecrets 使用当前主密钥加密所有服务商的API参数
+// rotate 为 true 时同时将使用旧主密钥加密的数据密钥重新加密
+func (this *DNSProviderDAO) ResealSecrets(tx *dbs.Tx, keyring *secrets.Keyring, rotate bool) (count int64, err error) {
+	ones, err := this.Query(tx).
+		Result("id", "apiParams").
+		AscPk().
+		FindAll()
+	if err != nil {
+		return 0, err
+	}
+	for _, one := range ones {
+		var provider = one.(*DNSProvider)
+		if len(provider.ApiParams) == 0 {
+			continue
+		}
+
+		var value = string(provider.ApiParams)
+		encryptedValue, ok := this.decodeEncryptedAPIParams(provider.ApiParams)
+		if ok {
+			value = encryptedValue
+		}
+		newValue, changed, err := keyring.Reseal(dnsProviderAPIParamsSecretContext, value, rotate)
+		if err != nil {
+			return count, errors.New("dns provider '" + types.String(provider.Id) + "': " + err.Error())
+		}
+		if !changed {
+			continue
+		}
+		newParamsJSON, err := json.Marshal(newValue)
+		if err != nil {
+			return count, err
+		}
+		err = this.Query(tx).
+			Pk(provider.Id).
+			Set("apiParams", newParamsJSON).
+			UpdateQuickly()
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return
+}
+
+// 加密API参数
+// 加密后的值以JSON字符串的形式保存，以保证字段中的内容仍然是合法的JSON
+func (this *DNSProviderDAO) encodeAPIParams(apiParamsJSON []byte) ([]byte, error) {
+	value, err := secrets.EncryptString(dnsProviderAPIParamsSecretContext, string(apiParamsJSON))
+	if err != nil {
+		return nil, err
+	}
+	if !secrets.IsEncrypted(value) {
+		return apiParamsJSON, nil
+	}
+	return json.Marshal(value)
+}
+
+// 解密API参数
+func (this *DNSProviderDAO) decodeAPIParams(provider *DNSProvider) error {
+	value, ok := this.decodeEncryptedAPIParams(provider.ApiParams)
+	if !ok {
+		return nil
+	}
+	apiParamsJSON, err := secrets.DecryptString(dnsProviderAPIParamsSecretContext, value)
+	if err != nil {
+		return errors.New("decrypt api params of dns provider '" + types.String(provider.Id) + "' failed: " + err.Error())
+	}
+	provider.ApiParams = []byte(apiParamsJSON)
+	return nil
+}
+
+func (this *DNSProviderDAO) decodeAPIParamsList(providers []*DNSProvider) error {
+	for _, provider := range providers {
+		err := this.decodeAPIParams(provider)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 从JSON字符串中读取加密的API参数
+func (this *DNSProviderDAO) decodeEncryptedAPIParams(apiParamsJSON []byte) (value string, ok bool) {
+	if len(apiParamsJSON) == 0 || apiParamsJSON[0] != '"' {
+		return "", false
+	}
+	err := json.Unmarshal(apiParamsJSON, &value)
+	if err != nil || !secrets.IsEncrypted(value) {
+		return "", false
+	}
+	return value, true
+}
@@ -148,6 +148,21 @@ func (this *DNSTaskDAO) ExistErrorTasks(tx *dbs.Tx) (bool, error) {
 		Exist()
 }
 
+// CountDoingTasks 计算正在执行的任务数量
+func (this *DNSTaskDAO) CountDoingTasks(tx *dbs.Tx) (int64, error) {
+	return this.Query(tx).
+		Attr("isDone", 0).
+		Count()
+}
+
+// CountErrorTasks 计算错误的任务数量
+func (this *DNSTaskDAO) CountErrorTasks(tx *dbs.Tx) (int64, error) {
+	return this.Query(tx).
+		Attr("isDone", 1).
+		Attr("isOk", 0).
+		Count()
+}
+
 // DeleteDNSTask 删除任务
 func (this *DNSTaskDAO) DeleteDNSTask(tx *dbs.Tx, taskId int64) error {
 	_, err := this.Query(tx).
@@ -201,6 +216,22 @@ func (this *DNSTaskDAO) UpdateDNSTaskDone(tx *dbs.Tx, taskId int64, taskVersion
 	op.IsOk = true
 	op.CountFails = 0
 	op.Error = ""
+	op.FailedRecords = "null"
+	return this.Save(tx, op)
+}
+
+// UpdateDNSTaskFailedRecords 设置任务中执行失败的记录
+func (this *DNSTaskDAO) UpdateDNSTaskFailedRecords(tx *dbs.Tx, taskId int64, failedRecordsJSON []byte) error {
+	if taskId <= 0 {
+		return errors.New("invalid taskId")
+	}
+	var op = NewDNSTaskOperator()
+	op.Id = taskId
+	if len(failedRecordsJSON) == 0 {
+		op.FailedRecords = "null"
+	} else {
+		op.FailedRecords = failedRecordsJSON
+	}
 	return this.Save(tx, op)
 }
 
@@ -223,6 +254,7 @@ func (this *DNSTaskDAO) UpdateClusterDNSTasksDone(tx *dbs.Tx, clusterId int64, m
 		Set("isOk", true).
 		Set("error", "").
 		Set("countFails", 0).
+		Set("failedRecords", "null").
 		UpdateQuickly()
 }
 
@@ -1,11 +1,14 @@
 package models
 
 import (
+	"bytes"
 	"errors"
+	"github.com/dashenmiren/EdgeAPI/internal/blobs"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/iwind/TeaGo/Tea"
 	"github.com/iwind/TeaGo/dbs"
 	"github.com/iwind/TeaGo/types"
+	"io"
 )
 
 type FileChunkDAO dbs.DAO
@@ -30,15 +33,62 @@ func init() {
 }
 
 // CreateFileChunk 创建文件Chunk
+// 内容写入到API节点配置的存储中，保存在数据库以外的存储时，先创建片段记录再写入内容
 func (this *FileChunkDAO) CreateFileChunk(tx *dbs.Tx, fileId int64, data []byte) (int64, error) {
+	storage, err := CurrentFileChunkStorage()
+	if err != nil {
+		return 0, err
+	}
+
 	var op = NewFileChunkOperator()
 	op.FileId = fileId
-	op.Data = data
-	err := this.Save(tx, op)
+	op.Storage = storage.Type()
+	if storage.Type() == blobs.StorageTypeDB {
+		op.Data = data
+	} else {
+		op.Data = ""
+	}
+	err = this.Save(tx, op)
 	if err != nil {
 		return 0, err
 	}
-	return types.Int64(op.Id), nil
+	var chunkId = types.Int64(op.Id)
+
+	if storage.Type() != blobs.StorageTypeDB {
+		err = storage.Put(blobs.ChunkKey(fileId, chunkId), bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			_, _ = this.Query(tx).
+				Pk(chunkId).
+				Delete()
+			return 0, err
+		}
+	}
+
+	return chunkId, nil
+}
+
+// CreateFileChunksFromReader 从 reader 中读取内容并依次创建片段
+func (this *FileChunkDAO) CreateFileChunksFromReader(tx *dbs.Tx, fileId int64, reader io.Reader, chunkSize int) (countChunks int, err error) {
+	if chunkSize <= 0 {
+		chunkSize = 256 * 1024
+	}
+	var buf = make([]byte, chunkSize)
+	for {
+		n, readErr := io.ReadFull(reader, buf)
+		if n > 0 {
+			_, err = this.CreateFileChunk(tx, fileId, buf[:n])
+			if err != nil {
+				return countChunks, err
+			}
+			countChunks++
+		}
+		if readErr != nil {
+			if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+				return countChunks, nil
+			}
+			return countChunks, readErr
+		}
+	}
 }
 
 // FindAllFileChunks 列出所有的文件Chunk
@@ -73,7 +123,30 @@ func (this *FileChunkDAO) DeleteFileChunks(tx *dbs.Tx, fileId int64) error {
 	if fileId <= 0 {
 		return errors.New("invalid fileId")
 	}
-	_, err := this.Query(tx).
+
+	// 删除数据库以外的存储中的内容
+	ones, err := this.Query(tx).
+		Result("id", "fileId", "storage").
+		Attr("fileId", fileId).
+		Neq("storage", blobs.StorageTypeDB).
+		Neq("storage", "").
+		FindAll()
+	if err != nil {
+		return err
+	}
+	for _, one := range ones {
+		var chunk = one.(*FileChunk)
+		storage, err := FindFileChunkStorage(chunk.StorageType())
+		if err != nil {
+			return err
+		}
+		err = storage.Delete(blobs.ChunkKey(int64(chunk.FileId), int64(chunk.Id)))
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = this.Query(tx).
 		Attr("fileId", fileId).
 		Delete()
 	return err
@@ -90,5 +163,155 @@ func (this *FileChunkDAO) FindFileChunk(tx *dbs.Tx, chunkId int64) (*FileChunk,
 	if one == nil {
 		return nil, nil
 	}
-	return one.(*FileChunk), nil
+	var chunk = one.(*FileChunk)
+
+	// 从数据库以外的存储中读取内容
+	if chunk.StorageType() != blobs.StorageTypeDB {
+		reader, err := this.OpenFileChunk(tx, chunk)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = reader.Close()
+		}()
+		chunk.Data, err = io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return chunk, nil
+}
+
+// OpenFileChunk 打开片段内容用来读取
+func (this *FileChunkDAO) OpenFileChunk(tx *dbs.Tx, chunk *FileChunk) (io.ReadCloser, error) {
+	if chunk.StorageType() == blobs.StorageTypeDB {
+		if chunk.Data != nil {
+			return io.NopCloser(bytes.NewReader(chunk.Data)), nil
+		}
+		data, err := this.Query(tx).
+			Pk(chunk.Id).
+			Result("data").
+			FindBytesCol()
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	storage, err := FindFileChunkStorage(chunk.StorageType())
+	if err != nil {
+		return nil, err
+	}
+	reader, err := storage.Open(blobs.ChunkKey(int64(chunk.FileId), int64(chunk.Id)))
+	if err != nil {
+		if errors.Is(err, blobs.ErrNotFound) {
+			return nil, errors.New("can not find content of file chunk '" + types.String(chunk.Id) + "' in storage '" + chunk.StorageType() + "'")
+		}
+		return nil, err
+	}
+	return reader, nil
+}
+
+// ReadFileTo 按顺序读取文件所有片段的内容并写入到 writer
+// 每次只读取一个片段，不会将整个文件读入内存
+func (this *FileChunkDAO) ReadFileTo(tx *dbs.Tx, fileId int64, writer io.Writer) error {
+	ones, err := this.Query(tx).
+		Result("id", "fileId", "storage").
+		Attr("fileId", fileId).
+		AscPk().
+		FindAll()
+	if err != nil {
+		return err
+	}
+	for _, one := range ones {
+		var chunk = one.(*FileChunk)
+		reader, err := this.OpenFileChunk(tx, chunk)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(writer, reader)
+		_ = reader.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateFileChunks 将所有片段的内容迁移到指定的存储
+// 先写入新的存储，再修改片段记录，最后删除原存储中的内容，中断后可以重新执行
+func (this *FileChunkDAO) MigrateFileChunks(tx *dbs.Tx, storageType string, onMigrate func(chunk *FileChunk)) (count int64, err error) {
+	target, err := FindFileChunkStorage(storageType)
+	if err != nil {
+		return 0, err
+	}
+
+	var lastId int64
+	for {
+		ones, err := this.Query(tx).
+			Result("id", "fileId", "storage").
+			Gt("id", lastId).
+			AscPk().
+			Limit(100).
+			FindAll()
+		if err != nil {
+			return count, err
+		}
+		if len(ones) == 0 {
+			return count, nil
+		}
+
+		for _, one := range ones {
+			var chunk = one.(*FileChunk)
+			lastId = int64(chunk.Id)
+			var sourceType = chunk.StorageType()
+			if sourceType == target.Type() {
+				continue
+			}
+
+			var key = blobs.ChunkKey(int64(chunk.FileId), int64(chunk.Id))
+			source, err := FindFileChunkStorage(sourceType)
+			if err != nil {
+				return count, err
+			}
+			reader, err := this.OpenFileChunk(tx, chunk)
+			if err != nil {
+				return count, err
+			}
+			data, err := io.ReadAll(reader)
+			_ = reader.Close()
+			if err != nil {
+				return count, err
+			}
+
+			err = target.Put(key, bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				return count, err
+			}
+
+			var query = this.Query(tx).
+				Pk(chunk.Id).
+				Set("storage", target.Type())
+			if target.Type() != blobs.StorageTypeDB {
+				query.Set("data", "")
+			}
+			err = query.UpdateQuickly()
+			if err != nil {
+				return count, err
+			}
+
+			// 数据库中的内容已经在上一步中清除
+			if sourceType != blobs.StorageTypeDB {
+				err = source.Delete(key)
+				if err != nil {
+					return count, err
+				}
+			}
+
+			count++
+			if onMigrate != nil {
+				onMigrate(chunk)
+			}
+		}
+	}
 }
@@ -1,5 +1,42 @@
 package models
 
 import (
+	"bytes"
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/iwind/TeaGo/dbs"
+	"strings"
+	"testing"
 )
+
+func TestFileChunkDAO_ReadFileTo(t *testing.T) {
+	dbs.NotifyReady()
+
+	var tx *dbs.Tx
+	fileId, err := SharedFileDAO.CreateFile(tx, 0, 0, "test", "", "test.txt", 11, "text/plain", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		err = SharedFileChunkDAO.DeleteFileChunks(tx, fileId)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	countChunks, err := SharedFileChunkDAO.CreateFileChunksFromReader(tx, fileId, strings.NewReader("hello world"), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if countChunks != 3 {
+		t.Fatal("expect 3 chunks, got", countChunks)
+	}
+
+	var buf = &bytes.Buffer{}
+	err = SharedFileChunkDAO.ReadFileTo(tx, fileId, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "hello world" {
+		t.Fatal("unexpected content:", buf.String())
+	}
+}
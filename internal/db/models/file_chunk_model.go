@@ -1,16 +1,17 @@
 package models
 
-//
 type FileChunk struct {
-	Id     uint32 `field:"id"`     // ID
-	FileId uint32 `field:"fileId"` // 文件ID
-	Data   []byte `field:"data"`   // 分块内容
+	Id      uint32 `field:"id"`      // ID
+	FileId  uint32 `field:"fileId"`  // 文件ID
+	Data    []byte `field:"data"`    // 分块内容
+	Storage string `field:"storage"` // 存储类型
 }
 
 type FileChunkOperator struct {
-	Id     interface{} // ID
-	FileId interface{} // 文件ID
-	Data   interface{} // 分块内容
+	Id      interface{} // ID
+	FileId  interface{} // 文件ID
+	Data    interface{} // 分块内容
+	Storage interface{} // 存储类型
 }
 
 func NewFileChunkOperator() *FileChunkOperator {
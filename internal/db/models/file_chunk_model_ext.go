@@ -1 +1,11 @@
 package models
+
+import "github.com/dashenmiren/EdgeAPI/internal/blobs"
+
+// StorageType 存储类型，没有设置存储类型的片段保存在数据库中
+func (this *FileChunk) StorageType() string {
+	if len(this.Storage) == 0 {
+		return blobs.StorageTypeDB
+	}
+	return this.Storage
+}
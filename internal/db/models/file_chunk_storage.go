@@ -0,0 +1,174 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package models
+
+import (
+	"bytes"
+	"errors"
+	"github.com/dashenmiren/EdgeAPI/internal/blobs"
+	"github.com/dashenmiren/EdgeAPI/internal/configs"
+	"github.com/dashenmiren/EdgeAPI/internal/remotelogs"
+	"github.com/iwind/TeaGo/Tea"
+	"io"
+	"sync"
+)
+
+var fileChunkStorageLocker = &sync.Mutex{}
+var fileChunkStorages map[string]blobs.Storage // type => storage
+var fileChunkStorageType string                // 新的片段使用的存储类型
+var fileChunkStorageErr error
+
+// FileChunkDBStorage 将片段内容保存在 edgeFileChunks.data 中
+// 对象键必须是 blobs.ChunkKey() 生成的键
+type FileChunkDBStorage struct {
+}
+
+func NewFileChunkDBStorage() *FileChunkDBStorage {
+	return &FileChunkDBStorage{}
+}
+
+func (this *FileChunkDBStorage) Type() string {
+	return blobs.StorageTypeDB
+}
+
+// Put 写入片段内容，片段记录需要已经存在
+func (this *FileChunkDBStorage) Put(key string, reader io.Reader, size int64) error {
+	_, chunkId, ok := blobs.ParseChunkKey(key)
+	if !ok {
+		return errors.New("invalid key '" + key + "'")
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if size >= 0 && int64(len(data)) != size {
+		return errors.New("write '" + key + "' failed: unexpected size")
+	}
+	return SharedFileChunkDAO.Query(nil).
+		Pk(chunkId).
+		Set("data", data).
+		UpdateQuickly()
+}
+
+// Open 读取片段内容
+func (this *FileChunkDBStorage) Open(key string) (io.ReadCloser, error) {
+	_, chunkId, ok := blobs.ParseChunkKey(key)
+	if !ok {
+		return nil, errors.New("invalid key '" + key + "'")
+	}
+	one, err := SharedFileChunkDAO.Query(nil).
+		Pk(chunkId).
+		Result("data").
+		Find()
+	if err != nil {
+		return nil, err
+	}
+	if one == nil {
+		return nil, blobs.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(one.(*FileChunk).Data)), nil
+}
+
+// Delete 清除片段内容
+func (this *FileChunkDBStorage) Delete(key string) error {
+	_, chunkId, ok := blobs.ParseChunkKey(key)
+	if !ok {
+		return errors.New("invalid key '" + key + "'")
+	}
+	return SharedFileChunkDAO.Query(nil).
+		Pk(chunkId).
+		Set("data", "").
+		UpdateQuickly()
+}
+
+// 根据API节点配置加载存储
+// 加载失败时仍然可以使用数据库存储
+func loadFileChunkStorages() {
+	fileChunkStorages = map[string]blobs.Storage{
+		blobs.StorageTypeDB: NewFileChunkDBStorage(),
+	}
+	fileChunkStorageType = blobs.StorageTypeDB
+
+	apiConfig, err := configs.SharedAPIConfig()
+	if err != nil || apiConfig.FileStorage == nil {
+		return
+	}
+	var config = apiConfig.FileStorage
+
+	// 本地文件系统
+	var dir = config.Dir
+	if len(dir) == 0 {
+		dir = Tea.Root + "/data/files"
+	}
+	if config.Type == blobs.StorageTypeFile || len(config.Dir) > 0 {
+		storage, err := blobs.NewFileStorage(dir)
+		if err != nil {
+			fileChunkStorageErr = errors.New("init file storage '" + dir + "' failed: " + err.Error())
+			remotelogs.Error("FILE_STORAGE", fileChunkStorageErr.Error())
+		} else {
+			fileChunkStorages[blobs.StorageTypeFile] = storage
+		}
+	}
+
+	// S3
+	if config.S3 != nil {
+		storage, err := blobs.NewS3Storage(&blobs.S3Options{
+			Endpoint:           config.S3.Endpoint,
+			Region:             config.S3.Region,
+			Bucket:             config.S3.Bucket,
+			AccessKeyId:        config.S3.AccessKeyId,
+			AccessKeySecret:    config.S3.AccessKeySecret,
+			Prefix:             config.S3.Prefix,
+			VirtualHostStyle:   config.S3.VirtualHostStyle,
+			InsecureSkipVerify: config.S3.InsecureSkipVerify,
+		})
+		if err != nil {
+			fileChunkStorageErr = errors.New("init s3 storage failed: " + err.Error())
+			remotelogs.Error("FILE_STORAGE", fileChunkStorageErr.Error())
+		} else {
+			fileChunkStorages[blobs.StorageTypeS3] = storage
+		}
+	}
+
+	// 配置错误时不能回退到数据库存储，以免写入到非预期的位置
+	if len(config.Type) > 0 {
+		if !blobs.IsValidStorageType(config.Type) {
+			fileChunkStorageErr = errors.New("invalid file storage type '" + config.Type + "'")
+			remotelogs.Error("FILE_STORAGE", fileChunkStorageErr.Error())
+		}
+		fileChunkStorageType = config.Type
+	}
+}
+
+// FindFileChunkStorage 查找某个类型的存储
+func FindFileChunkStorage(storageType string) (blobs.Storage, error) {
+	fileChunkStorageLocker.Lock()
+	defer fileChunkStorageLocker.Unlock()
+
+	if fileChunkStorages == nil {
+		loadFileChunkStorages()
+	}
+	if len(storageType) == 0 {
+		storageType = blobs.StorageTypeDB
+	}
+	storage, ok := fileChunkStorages[storageType]
+	if !ok {
+		if fileChunkStorageErr != nil {
+			return nil, fileChunkStorageErr
+		}
+		return nil, errors.New("file storage '" + storageType + "' has not been configured in 'api.yaml'")
+	}
+	return storage, nil
+}
+
+// CurrentFileChunkStorage 新的片段使用的存储
+func CurrentFileChunkStorage() (blobs.Storage, error) {
+	fileChunkStorageLocker.Lock()
+	if fileChunkStorages == nil {
+		loadFileChunkStorages()
+	}
+	var storageType = fileChunkStorageType
+	fileChunkStorageLocker.Unlock()
+
+	return FindFileChunkStorage(storageType)
+}
@@ -3,8 +3,11 @@ package models
 import (
 	"bytes"
 	"encoding/json"
+	"github.com/dashenmiren/EdgeAPI/internal/accesslogs"
+	"github.com/dashenmiren/EdgeAPI/internal/configs"
 	dbutils "github.com/dashenmiren/EdgeAPI/internal/db/utils"
 	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/dashenmiren/EdgeAPI/internal/events"
 	"github.com/dashenmiren/EdgeAPI/internal/goman"
 	"github.com/dashenmiren/EdgeAPI/internal/remotelogs"
 	"github.com/dashenmiren/EdgeAPI/internal/zero"
@@ -29,6 +32,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -49,12 +53,60 @@ var (
 
 	accessLogEnableAutoPartial       = true    // 是否启用自动分表
 	accessLogRowsPerTable      int64 = 500_000 // 自动分表的单表最大值
+
+	accessLogWAL          *accesslogs.WAL // 本地磁盘缓冲，内存队列已满时使用，未启用时为nil
+	accessLogCountDropped int64           // 因为队列已满而丢弃的日志数量
 )
 
+// AccessLogQueuePercent 访问日志采样率
 func AccessLogQueuePercent() int {
 	return accessLogQueuePercent
 }
 
+// HTTPAccessLogQueueStatus 访问日志队列状态
+type HTTPAccessLogQueueStatus struct {
+	Percent        int                 // 采样率
+	QueueLength    int                 // 内存队列中的日志数量
+	QueueMaxLength int                 // 内存队列最大长度
+	CountDropped   int64               // 因为内存队列和磁盘缓冲均已满而丢弃的日志数量
+	WAL            *accesslogs.WALStat // 磁盘缓冲状态，未启用时为nil
+}
+
+// AccessLogQueueStatus 访问日志队列状态
+func AccessLogQueueStatus() *HTTPAccessLogQueueStatus {
+	var status = &HTTPAccessLogQueueStatus{
+		Percent:        accessLogQueuePercent,
+		QueueLength:    len(oldAccessLogQueue) + len(accessLogQueue),
+		QueueMaxLength: accessLogQueueMaxLength,
+		CountDropped:   atomic.LoadInt64(&accessLogCountDropped),
+	}
+	var wal = accessLogWAL
+	if wal != nil {
+		status.WAL = wal.Stat()
+	}
+	return status
+}
+
+// 这里正则表达式中的括号不能轻易变更，因为后面有引用
+var (
+	accessLogStatusPrefixReg = regexp.MustCompile(`status:\s*(\d{3})\b`)
+	accessLogStatusRangeReg  = regexp.MustCompile(`status:\s*(\d{3})-(\d{3})\b`)
+	accessLogURLReg          = regexp.MustCompile(`^(http|https)://`)
+	accessLogRequestPathReg  = regexp.MustCompile(`requestPath:(\S+)`)
+	accessLogProtoReg        = regexp.MustCompile(`proto:(\S+)`)
+	accessLogSchemeReg       = regexp.MustCompile(`scheme:(\S+)`)
+	accessLogMethodReg       = regexp.MustCompile(`(?:method|requestMethod):(\S+)`)
+	accessLogRefererReg      = regexp.MustCompile(`referer:(\S+)`)
+)
+
+// 分组统计时每个分表最多返回的分组数
+const accessLogAggregationTableLimit = 10_000
+
+const (
+	accessLogExportMaxDays   = 366  // 单次导出最多跨越的天数
+	accessLogExportBatchSize = 1000 // 导出时每批读取的最大条数
+)
+
 type accessLogTableQuery struct {
 	daoWrapper         *HTTPAccessLogDAOWrapper
 	name               string
@@ -69,6 +121,14 @@ func init() {
 
 	// 队列相关
 	dbs.OnReadyDone(func() {
+		// 本地磁盘缓冲，需要在导出队列内容之前打开，以便重放上次未写入的日志
+		SharedHTTPAccessLogDAO.SetupWAL()
+
+		// 退出时将内存队列中的日志写入到磁盘缓冲
+		events.On(events.EventQuit, func() {
+			SharedHTTPAccessLogDAO.CloseWAL()
+		})
+
 		// 检查队列变化
 		goman.New(func() {
 			var ticker = time.NewTicker(60 * time.Second)
@@ -134,11 +194,12 @@ func NewHTTPAccessLogDAO() *HTTPAccessLogDAO {
 func (this *HTTPAccessLogDAO) CreateHTTPAccessLogs(tx *dbs.Tx, accessLogs []*pb.HTTPAccessLog) error {
 	// 写入队列
 	var queue = accessLogQueue // 这样写非常重要，防止在写入过程中队列有切换
+	var overflowAccessLogs []*pb.HTTPAccessLog
 	for _, accessLog := range accessLogs {
 		if accessLog.FirewallPolicyId == 0 { // 如果是非WAF记录，则采取采样率
 			// 采样率
 			if accessLogQueuePercent <= 0 {
-				return nil
+				break
 			}
 			if accessLogQueuePercent < 100 && rands.Int(1, 100) > accessLogQueuePercent {
 				continue
@@ -148,40 +209,36 @@ func (this *HTTPAccessLogDAO) CreateHTTPAccessLogs(tx *dbs.Tx, accessLogs []*pb.
 		select {
 		case queue <- accessLog:
 		default:
-			// 超出的丢弃
+			// 超出的写入磁盘缓冲，未启用磁盘缓冲时丢弃
+			overflowAccessLogs = append(overflowAccessLogs, accessLog)
 		}
 	}
 
+	if len(overflowAccessLogs) > 0 {
+		this.spillAccessLogs(overflowAccessLogs)
+	}
+
 	return nil
 }
 
 // DumpAccessLogsFromQueue 从队列导入访问日志
+// 内存队列为空时再从磁盘缓冲中导入
 func (this *HTTPAccessLogDAO) DumpAccessLogsFromQueue(size int) (hasMore bool, err error) {
 	if size <= 0 {
 		size = 100
 	}
 
+	var wal = accessLogWAL
 	if len(oldAccessLogQueue) == 0 && len(accessLogQueue) == 0 {
-		return false, nil
-	}
-
-	var dao = randomHTTPAccessLogDAO()
-	if dao == nil {
-		dao = &HTTPAccessLogDAOWrapper{
-			DAO:    SharedHTTPAccessLogDAO,
-			NodeId: 0,
+		if wal == nil || wal.Len() == 0 {
+			return false, nil
 		}
+		return this.dumpAccessLogsFromWAL(wal, size)
+	}
 
-		// 检查本地数据库空间
-		if dbutils.IsLocalDatabase && !dbutils.HasFreeSpace {
-			return false, errors.New("dump accesslog failed: there is no enough space left for database (" + dbutils.LocalDatabaseDataDir + ")")
-		}
-	} else if dao.IsLocal {
-		// 检查本地数据库空间
-		// 我们假定本地只能安装一个数据库，访问日志中的数据库和当前API连接的数据库一致
-		if !dbutils.HasFreeSpace {
-			return true, errors.New("dump accesslog failed: there is no enough space left for database (" + dbutils.LocalDatabaseDataDir + ")")
-		}
+	dao, err := this.findDumpDAO()
+	if err != nil {
+		return dao != nil, err
 	}
 
 	// 开始事务
@@ -230,6 +287,154 @@ Loop:
 	return hasMore, nil
 }
 
+// 从磁盘缓冲导入访问日志，只有在全部写入成功后才确认读取，失败时下次会重新读取
+func (this *HTTPAccessLogDAO) dumpAccessLogsFromWAL(wal *accesslogs.WAL, size int) (hasMore bool, err error) {
+	dao, err := this.findDumpDAO()
+	if err != nil {
+		return dao != nil, err
+	}
+
+	records, err := wal.Read(size)
+	if err != nil {
+		return false, err
+	}
+	if len(records) == 0 {
+		return false, wal.Commit()
+	}
+
+	tx, err := dao.DAO.Instance.Begin()
+	if err != nil {
+		return false, err
+	}
+	for _, record := range records {
+		var accessLog = &pb.HTTPAccessLog{}
+		err = json.Unmarshal(record, accessLog)
+		if err != nil {
+			// 无法解析的记录直接跳过
+			remotelogs.Error("HTTP_ACCESS_LOG_QUEUE", "decode access log from wal failed: "+err.Error())
+			continue
+		}
+		err = this.CreateHTTPAccessLog(tx, dao.DAO, accessLog)
+		if err != nil {
+			_ = tx.Rollback()
+			return false, err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return false, err
+	}
+
+	return len(records) >= size, wal.Commit()
+}
+
+// 选择要写入的数据库
+// 返回错误时如果dao不为nil，表示可以稍后重试
+func (this *HTTPAccessLogDAO) findDumpDAO() (*HTTPAccessLogDAOWrapper, error) {
+	var dao = randomHTTPAccessLogDAO()
+	if dao == nil {
+		dao = &HTTPAccessLogDAOWrapper{
+			DAO:    SharedHTTPAccessLogDAO,
+			NodeId: 0,
+		}
+
+		// 检查本地数据库空间
+		if dbutils.IsLocalDatabase && !dbutils.HasFreeSpace {
+			return nil, errors.New("dump accesslog failed: there is no enough space left for database (" + dbutils.LocalDatabaseDataDir + ")")
+		}
+	} else if dao.IsLocal {
+		// 检查本地数据库空间
+		// 我们假定本地只能安装一个数据库，访问日志中的数据库和当前API连接的数据库一致
+		if !dbutils.HasFreeSpace {
+			return dao, errors.New("dump accesslog failed: there is no enough space left for database (" + dbutils.LocalDatabaseDataDir + ")")
+		}
+	}
+	return dao, nil
+}
+
+// 将内存队列中放不下的日志写入磁盘缓冲
+func (this *HTTPAccessLogDAO) spillAccessLogs(accessLogs []*pb.HTTPAccessLog) {
+	var wal = accessLogWAL
+	if wal == nil {
+		atomic.AddInt64(&accessLogCountDropped, int64(len(accessLogs)))
+		return
+	}
+
+	var records = make([][]byte, 0, len(accessLogs))
+	for _, accessLog := range accessLogs {
+		data, err := json.Marshal(accessLog)
+		if err != nil {
+			atomic.AddInt64(&accessLogCountDropped, 1)
+			continue
+		}
+		records = append(records, data)
+	}
+	count, err := wal.Write(records)
+	if count < len(records) {
+		atomic.AddInt64(&accessLogCountDropped, int64(len(records)-count))
+	}
+	if err != nil {
+		remotelogs.Error("HTTP_ACCESS_LOG_QUEUE", "write access logs to wal failed: "+err.Error())
+	}
+}
+
+// SetupWAL 根据API节点配置打开本地磁盘缓冲
+func (this *HTTPAccessLogDAO) SetupWAL() {
+	apiConfig, err := configs.SharedAPIConfig()
+	if err != nil || apiConfig.AccessLogWAL == nil || !apiConfig.AccessLogWAL.IsOn {
+		return
+	}
+
+	var config = apiConfig.AccessLogWAL
+	var dir = config.Dir
+	if len(dir) == 0 {
+		dir = Tea.Root + "/data/accesslogs-wal"
+	}
+	wal, err := accesslogs.OpenWAL(&accesslogs.WALOptions{
+		Dir:         dir,
+		SegmentSize: config.SegmentSizeMB << 20,
+		MaxSize:     config.MaxSizeMB << 20,
+	})
+	if err != nil {
+		remotelogs.Error("HTTP_ACCESS_LOG_QUEUE", "open wal '"+dir+"' failed: "+err.Error())
+		return
+	}
+	accessLogWAL = wal
+
+	if wal.Len() > 0 {
+		remotelogs.Println("HTTP_ACCESS_LOG_QUEUE", "replay "+types.String(wal.Len())+" access logs from wal")
+	}
+}
+
+// CloseWAL 将内存队列中剩余的日志写入磁盘缓冲，并关闭磁盘缓冲
+func (this *HTTPAccessLogDAO) CloseWAL() {
+	var wal = accessLogWAL
+	if wal == nil {
+		return
+	}
+
+	for _, queue := range []chan *pb.HTTPAccessLog{oldAccessLogQueue, accessLogQueue} {
+		var accessLogs = []*pb.HTTPAccessLog{}
+	Loop:
+		for {
+			select {
+			case accessLog := <-queue:
+				accessLogs = append(accessLogs, accessLog)
+			default:
+				break Loop
+			}
+		}
+		if len(accessLogs) > 0 {
+			this.spillAccessLogs(accessLogs)
+		}
+	}
+
+	err := wal.Close()
+	if err != nil {
+		remotelogs.Error("HTTP_ACCESS_LOG_QUEUE", "close wal failed: "+err.Error())
+	}
+}
+
 // CreateHTTPAccessLog 写入单条访问日志
 func (this *HTTPAccessLogDAO) CreateHTTPAccessLog(tx *dbs.Tx, dao *HTTPAccessLogDAO, accessLog *pb.HTTPAccessLog) error {
 	var day string
@@ -310,6 +515,29 @@ func (this *HTTPAccessLogDAO) CreateHTTPAccessLog(tx *dbs.Tx, dao *HTTPAccessLog
 	return nil
 }
 
+// HTTPAccessLogFilter 访问日志查询条件
+type HTTPAccessLogFilter struct {
+	ClusterId           int64
+	NodeId              int64
+	ServerId            int64
+	HasError            bool
+	FirewallPolicyId    int64
+	FirewallRuleGroupId int64
+	FirewallRuleSetId   int64
+	HasFirewallPolicy   bool
+	UserId              int64
+	Keyword             string
+	IP                  string
+	Domain              string
+	Query               *accesslogs.Query // 结构化查询，比如 status:>=500 AND host:*.example.com
+}
+
+// HTTPAccessLogAggregation 访问日志分组统计结果
+type HTTPAccessLogAggregation struct {
+	Key   string
+	Count int64
+}
+
 // ListAccessLogs 读取往前的 单页访问日志
 func (this *HTTPAccessLogDAO) ListAccessLogs(tx *dbs.Tx,
 	partition int32,
@@ -331,88 +559,303 @@ func (this *HTTPAccessLogDAO) ListAccessLogs(tx *dbs.Tx,
 	keyword string,
 	ip string,
 	domain string) (result []*HTTPAccessLog, nextLastRequestId string, hasMore bool, err error) {
+	return this.ListAccessLogsWithFilter(tx, partition, lastRequestId, size, day, hourFrom, hourTo, reverse, &HTTPAccessLogFilter{
+		ClusterId:           clusterId,
+		NodeId:              nodeId,
+		ServerId:            serverId,
+		HasError:            hasError,
+		FirewallPolicyId:    firewallPolicyId,
+		FirewallRuleGroupId: firewallRuleGroupId,
+		FirewallRuleSetId:   firewallRuleSetId,
+		HasFirewallPolicy:   hasFirewallPolicy,
+		UserId:              userId,
+		Keyword:             keyword,
+		IP:                  ip,
+		Domain:              domain,
+	})
+}
+
+// ListAccessLogsWithFilter 根据查询条件读取往前的单页访问日志
+func (this *HTTPAccessLogDAO) ListAccessLogsWithFilter(tx *dbs.Tx,
+	partition int32,
+	lastRequestId string,
+	size int64,
+	day string,
+	hourFrom string,
+	hourTo string,
+	reverse bool,
+	filter *HTTPAccessLogFilter) (result []*HTTPAccessLog, nextLastRequestId string, hasMore bool, err error) {
 	if len(day) != 8 {
 		return
 	}
+	if filter == nil {
+		filter = &HTTPAccessLogFilter{}
+	}
 
 	// 限制能查询的最大条数，防止占用内存过多
 	if size > 1000 {
 		size = 1000
 	}
 
-	result, nextLastRequestId, err = this.listAccessLogs(tx, partition, lastRequestId, size, day, hourFrom, hourTo, clusterId, nodeId, serverId, reverse, hasError, firewallPolicyId, firewallRuleGroupId, firewallRuleSetId, hasFirewallPolicy, userId, keyword, ip, domain)
+	result, nextLastRequestId, err = this.listAccessLogs(tx, partition, lastRequestId, size, day, hourFrom, hourTo, reverse, filter)
 	if err != nil || int64(len(result)) < size {
 		return
 	}
 
-	moreResult, _, _ := this.listAccessLogs(tx, partition, nextLastRequestId, 1, day, hourFrom, hourTo, clusterId, nodeId, serverId, reverse, hasError, firewallPolicyId, firewallRuleGroupId, firewallRuleSetId, hasFirewallPolicy, userId, keyword, ip, domain)
+	moreResult, _, _ := this.listAccessLogs(tx, partition, nextLastRequestId, 1, day, hourFrom, hourTo, reverse, filter)
 	hasMore = len(moreResult) > 0
 	return
 }
 
-// 读取往前的单页访问日志
-func (this *HTTPAccessLogDAO) listAccessLogs(tx *dbs.Tx,
-	partition int32,
-	lastRequestId string,
-	size int64,
+// AggregateAccessLogs 对某天所有分表中的访问日志进行分组统计
+// groupBy 为分组字段，比如 status、host、ip，按数量倒序返回前 size 个分组
+func (this *HTTPAccessLogDAO) AggregateAccessLogs(tx *dbs.Tx,
 	day string,
 	hourFrom string,
 	hourTo string,
-	clusterId int64,
-	nodeId int64,
-	serverId int64,
-	reverse bool,
-	hasError bool,
-	firewallPolicyId int64,
-	firewallRuleGroupId int64,
-	firewallRuleSetId int64,
-	hasFirewallPolicy bool,
-	userId int64,
-	keyword string,
-	ip string,
-	domain string) (result []*HTTPAccessLog, nextLastRequestId string, err error) {
-	if size <= 0 {
-		return nil, lastRequestId, nil
+	filter *HTTPAccessLogFilter,
+	groupBy string,
+	size int64) (result []*HTTPAccessLogAggregation, err error) {
+	if len(day) != 8 {
+		return nil, errors.New("invalid day '" + day + "'")
+	}
+	if filter == nil {
+		filter = &HTTPAccessLogFilter{}
+	}
+	_, err = accesslogs.QueryGroupFieldSQL(groupBy, nil)
+	if err != nil {
+		return nil, err
+	}
+	if size <= 0 || size > 1000 {
+		size = 1000
+	}
+
+	nodeIds, serverIds, ok, err := this.prepareAccessLogFilter(tx, filter)
+	if err != nil || !ok {
+		return nil, err
 	}
 
-	var serverIds = []int64{}
-	if userId > 0 {
-		serverIds, err = SharedServerDAO.FindAllEnabledServerIdsWithUserId(tx, userId)
+	var countMap = map[string]int64{} // key => count
+	for _, daoWrapper := range this.findAccessLogDAOList() {
+		defs, err := SharedHTTPAccessLogManager.FindTables(daoWrapper.DAO.Instance, day)
 		if err != nil {
-			return
+			return nil, err
 		}
-		if len(serverIds) == 0 {
-			return
+		for _, def := range defs {
+			var tableQuery = &accessLogTableQuery{
+				daoWrapper:         daoWrapper,
+				name:               def.Name,
+				hasRemoteAddrField: def.HasRemoteAddr,
+				hasDomainField:     def.HasDomain,
+			}
+			groupExpr, err := accesslogs.QueryGroupFieldSQL(groupBy, &accesslogs.QuerySQLOptions{
+				HasRemoteAddrField: def.HasRemoteAddr,
+				HasDomainField:     def.HasDomain,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			var query = daoWrapper.DAO.Query(tx).
+				Table(def.Name)
+			ok, err := this.applyAccessLogFilter(query, tableQuery, filter, nodeIds, serverIds, day, hourFrom, hourTo)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+
+			ones, _, err := query.
+				Result(groupExpr+" AS groupKey", "COUNT(*) AS groupCount").
+				Group("groupKey").
+				Desc("groupCount").
+				Limit(accessLogAggregationTableLimit).
+				FindOnes()
+			if err != nil {
+				return nil, err
+			}
+			for _, one := range ones {
+				countMap[one.GetString("groupKey")] += one.GetInt64("groupCount")
+			}
 		}
 	}
 
-	accessLogLocker.RLock()
-	var daoList = []*HTTPAccessLogDAOWrapper{}
-	for _, daoWrapper := range httpAccessLogDAOMapping {
-		daoList = append(daoList, daoWrapper)
+	for key, count := range countMap {
+		result = append(result, &HTTPAccessLogAggregation{
+			Key:   key,
+			Count: count,
+		})
 	}
-	accessLogLocker.RUnlock()
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count == result[j].Count {
+			return result[i].Key < result[j].Key
+		}
+		return result[i].Count > result[j].Count
+	})
+	if int64(len(result)) > size {
+		result = result[:size]
+	}
+	return
+}
 
-	if len(daoList) == 0 {
-		daoList = []*HTTPAccessLogDAOWrapper{{
-			DAO:    SharedHTTPAccessLogDAO,
-			NodeId: 0,
-		}}
+// ExportAccessLogs 按照requestId从小到大导出某个时间范围内的访问日志
+// 依次遍历每一天所有数据库中的所有分表，每读取一批就调用一次 callback，
+// callback 中的 cursor 表示已导出的位置，可以用来从中断处继续导出
+func (this *HTTPAccessLogDAO) ExportAccessLogs(tx *dbs.Tx,
+	timeFrom int64,
+	timeTo int64,
+	filter *HTTPAccessLogFilter,
+	cursor *accesslogs.ExportCursor,
+	batchSize int64,
+	callback func(accessLogs []*HTTPAccessLog, cursor *accesslogs.ExportCursor) error) error {
+	if timeFrom <= 0 || timeTo < timeFrom {
+		return errors.New("invalid time range")
+	}
+	if filter == nil {
+		filter = &HTTPAccessLogFilter{}
+	}
+	if batchSize <= 0 || batchSize > accessLogExportBatchSize {
+		batchSize = accessLogExportBatchSize
 	}
 
-	// 查询某个集群下的节点
-	var nodeIds = []int64{}
-	if clusterId > 0 {
-		nodeIds, err = SharedNodeDAO.FindAllEnabledNodeIdsWithClusterId(tx, clusterId)
-		if err != nil {
-			remotelogs.Error("DB_NODE", err.Error())
-			return
+	// 需要遍历的日期
+	var days = []string{}
+	var dayTime = time.Unix(timeFrom, 0)
+	var lastDay = timeutil.FormatTime("Ymd", timeTo)
+	for {
+		var day = timeutil.Format("Ymd", dayTime)
+		if day > lastDay {
+			break
 		}
-		sort.Slice(nodeIds, func(i, j int) bool {
-			return nodeIds[i] < nodeIds[j]
-		})
+		if len(days) >= accessLogExportMaxDays {
+			return errors.New("time range should not exceed " + types.String(accessLogExportMaxDays) + " days")
+		}
+		days = append(days, day)
+		dayTime = time.Date(dayTime.Year(), dayTime.Month(), dayTime.Day()+1, 0, 0, 0, 0, time.Local)
 	}
 
+	if cursor != nil && (len(cursor.Day) != 8 || cursor.Day < days[0] || cursor.Day > days[len(days)-1]) {
+		return errors.New("cursor is out of time range")
+	}
+
+	nodeIds, serverIds, ok, err := this.prepareAccessLogFilter(tx, filter)
+	if err != nil || !ok {
+		return err
+	}
+
+	var daoList = this.findAccessLogDAOList()
+	for _, day := range days {
+		var lastRequestId = ""
+		if cursor != nil {
+			if day < cursor.Day {
+				continue
+			}
+			if day == cursor.Day {
+				lastRequestId = cursor.RequestId
+			}
+		}
+
+		var tableQueries = []*accessLogTableQuery{}
+		for _, daoWrapper := range daoList {
+			defs, err := SharedHTTPAccessLogManager.FindTables(daoWrapper.DAO.Instance, day)
+			if err != nil {
+				return err
+			}
+			for _, def := range defs {
+				tableQueries = append(tableQueries, &accessLogTableQuery{
+					daoWrapper:         daoWrapper,
+					name:               def.Name,
+					hasRemoteAddrField: def.HasRemoteAddr,
+					hasDomainField:     def.HasDomain,
+				})
+			}
+		}
+		if len(tableQueries) == 0 {
+			continue
+		}
+
+		for {
+			var result = []*HTTPAccessLog{}
+			for _, tableQuery := range tableQueries {
+				var query = tableQuery.daoWrapper.DAO.Query(tx).
+					Table(tableQuery.name).
+					Result("id", "serverId", "nodeId", "status", "createdAt", "content", "requestId", "firewallPolicyId", "firewallRuleGroupId", "firewallRuleSetId", "firewallRuleId", "remoteAddr", "domain")
+				ok, err := this.applyAccessLogFilter(query, tableQuery, filter, nodeIds, serverIds, day, "", "")
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return nil
+				}
+				if len(lastRequestId) > 0 {
+					query.Where("requestId>:requestId").
+						Param("requestId", lastRequestId)
+				}
+				ones, err := query.
+					Between("createdAt", timeFrom, timeTo).
+					Asc("requestId").
+					Limit(batchSize).
+					FindAll()
+				if err != nil {
+					return err
+				}
+				for _, one := range ones {
+					result = append(result, one.(*HTTPAccessLog))
+				}
+			}
+
+			if len(result) == 0 {
+				break
+			}
+
+			sort.Slice(result, func(i, j int) bool {
+				return result[i].RequestId < result[j].RequestId
+			})
+			var isLastBatch = int64(len(result)) < batchSize
+			if int64(len(result)) > batchSize {
+				result = result[:batchSize]
+			}
+			lastRequestId = result[len(result)-1].RequestId
+
+			err = callback(result, &accesslogs.ExportCursor{
+				Day:       day,
+				RequestId: lastRequestId,
+			})
+			if err != nil {
+				return err
+			}
+
+			if isLastBatch {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// 读取往前的单页访问日志
+func (this *HTTPAccessLogDAO) listAccessLogs(tx *dbs.Tx,
+	partition int32,
+	lastRequestId string,
+	size int64,
+	day string,
+	hourFrom string,
+	hourTo string,
+	reverse bool,
+	filter *HTTPAccessLogFilter) (result []*HTTPAccessLog, nextLastRequestId string, err error) {
+	if size <= 0 {
+		return nil, lastRequestId, nil
+	}
+
+	nodeIds, serverIds, ok, err := this.prepareAccessLogFilter(tx, filter)
+	if err != nil || !ok {
+		return
+	}
+
+	var daoList = this.findAccessLogDAOList()
+
 	// 准备查询
 	var tableQueries = []*accessLogTableQuery{}
 	var maxTableName = ""
@@ -456,22 +899,11 @@ func (this *HTTPAccessLogDAO) listAccessLogs(tx *dbs.Tx,
 
 	var locker = sync.Mutex{}
 
-	// 这里正则表达式中的括号不能轻易变更，因为后面有引用
-	// TODO 支持多个查询条件的组合，比如 status:200 proto:HTTP/1.1
-	var statusPrefixReg = regexp.MustCompile(`status:\s*(\d{3})\b`)
-	var statusRangeReg = regexp.MustCompile(`status:\s*(\d{3})-(\d{3})\b`)
-	var urlReg = regexp.MustCompile(`^(http|https)://`)
-	var requestPathReg = regexp.MustCompile(`requestPath:(\S+)`)
-	var protoReg = regexp.MustCompile(`proto:(\S+)`)
-	var schemeReg = regexp.MustCompile(`scheme:(\S+)`)
-	var methodReg = regexp.MustCompile(`(?:method|requestMethod):(\S+)`)
-	var refererReg = regexp.MustCompile(`referer:(\S+)`)
-
 	var count = len(tableQueries)
 	var wg = &sync.WaitGroup{}
 	wg.Add(count)
 	for _, tableQuery := range tableQueries {
-		go func(tableQuery *accessLogTableQuery, keyword string) {
+		go func(tableQuery *accessLogTableQuery) {
 			defer wg.Done()
 
 			var dao = tableQuery.daoWrapper.DAO
@@ -479,231 +911,13 @@ func (this *HTTPAccessLogDAO) listAccessLogs(tx *dbs.Tx,
 			query.Result("id", "serverId", "nodeId", "status", "createdAt", "content", "requestId", "firewallPolicyId", "firewallRuleGroupId", "firewallRuleSetId", "firewallRuleId", "remoteAddr", "domain")
 
 			// 条件
-			if nodeId > 0 {
-				query.Attr("nodeId", nodeId)
-			} else if clusterId > 0 {
-				if len(nodeIds) > 0 {
-					var nodeIdStrings = []string{}
-					for _, subNodeId := range nodeIds {
-						nodeIdStrings = append(nodeIdStrings, types.String(subNodeId))
-					}
-
-					query.Where("nodeId IN (" + strings.Join(nodeIdStrings, ",") + ")")
-					query.Reuse(false)
-				} else {
-					// 如果没有节点，则直接返回空
-					return
-				}
-			}
-			if serverId > 0 {
-				query.Attr("serverId", serverId)
-			} else if userId > 0 && len(serverIds) > 0 {
-				query.Attr("serverId", serverIds).
-					Reuse(false)
-			}
-			if hasError {
-				query.Where("status>=400")
-			}
-			if firewallPolicyId > 0 {
-				query.Attr("firewallPolicyId", firewallPolicyId)
-			}
-			if firewallRuleGroupId > 0 {
-				query.Attr("firewallRuleGroupId", firewallRuleGroupId)
-			}
-			if firewallRuleSetId > 0 {
-				query.Attr("firewallRuleSetId", firewallRuleSetId)
-			}
-			if hasFirewallPolicy {
-				query.Where("firewallPolicyId>0")
-				query.UseIndex("firewallPolicyId")
-			}
-
-			// keyword
-			if len(ip) > 0 {
-				if tableQuery.hasRemoteAddrField {
-					// IP格式
-					if strings.Contains(ip, ",") || strings.Contains(ip, "-") {
-						rangeConfig, parseErr := shared.ParseIPRange(ip)
-						if parseErr == nil {
-							if len(rangeConfig.IPFrom) > 0 && len(rangeConfig.IPTo) > 0 {
-								if iputils.IsIPv6(rangeConfig.IPFrom) || iputils.IsIPv6(rangeConfig.IPTo) {
-									var ipFromHex = iputils.ToHex(rangeConfig.IPFrom)
-									var ipToHex = iputils.ToHex(rangeConfig.IPTo)
-									if ipFromHex > ipToHex {
-										ipFromHex, ipToHex = ipToHex, ipFromHex
-									}
-									query.Between("HEX(INET6_ATON(remoteAddr))", ipFromHex, ipToHex)
-								} else {
-									var ipFromLong = iputils.ToLong(rangeConfig.IPFrom)
-									var ipToLong = iputils.ToLong(rangeConfig.IPTo)
-									if ipFromLong > ipToLong {
-										ipFromLong, ipToLong = ipToLong, ipFromLong
-									}
-									query.Between("INET_ATON(remoteAddr)", ipFromLong, ipToLong)
-								}
-							}
-						}
-					} else {
-						// 去掉IPv6的[]
-						ip = strings.Trim(ip, "[]")
-
-						query.Attr("remoteAddr", ip)
-						query.UseIndex("remoteAddr")
-					}
-				} else {
-					query.Where("JSON_EXTRACT(content, '$.remoteAddr')=:ip1").
-						Param("ip1", ip)
-				}
-			}
-			if len(domain) > 0 {
-				if tableQuery.hasDomainField {
-					if strings.Contains(domain, "*") {
-						domain = strings.ReplaceAll(domain, "*", "%")
-						domain = regexp.MustCompile(`[^a-zA-Z0-9-.%]`).ReplaceAllString(domain, "")
-						query.Where("domain LIKE :host2").
-							Param("host2", domain)
-					} else {
-						// 中文域名
-						if !regexp.MustCompile(`^[a-zA-Z0-9-.]+$`).MatchString(domain) {
-							unicodeDomain, err := idna.ToASCII(domain)
-							if err == nil && len(unicodeDomain) > 0 {
-								domain = unicodeDomain
-							}
-						}
-
-						query.Attr("domain", domain)
-						query.UseIndex("domain")
-					}
-				} else {
-					query.Where("JSON_EXTRACT(content, '$.host')=:host1").
-						Param("host1", domain)
-				}
-			}
-
-			if len(keyword) > 0 {
-				var isSpecialKeyword = false
-
-				if tableQuery.hasRemoteAddrField && net.ParseIP(keyword) != nil { // ip
-					isSpecialKeyword = true
-					query.Attr("remoteAddr", keyword)
-				} else if tableQuery.hasRemoteAddrField && regexp.MustCompile(`^ip:.+`).MatchString(keyword) { // ip:x.x.x.x
-					isSpecialKeyword = true
-					keyword = keyword[3:]
-					pieces := strings.SplitN(keyword, ",", 2)
-					if len(pieces) == 1 || len(pieces[1]) == 0 || pieces[0] == pieces[1] {
-						query.Attr("remoteAddr", pieces[0])
-					} else {
-						query.Between("INET_ATON(remoteAddr)", iputils.ToLong(pieces[0]), iputils.ToLong(pieces[1]))
-					}
-				} else if statusRangeReg.MatchString(keyword) { // status:200-400
-					isSpecialKeyword = true
-					var matches = statusRangeReg.FindStringSubmatch(keyword)
-					query.Between("status", types.Int(matches[1]), types.Int(matches[2]))
-				} else if statusPrefixReg.MatchString(keyword) { // status:200
-					isSpecialKeyword = true
-					var matches = statusPrefixReg.FindStringSubmatch(keyword)
-					query.Attr("status", matches[1])
-				} else if requestPathReg.MatchString(keyword) {
-					isSpecialKeyword = true
-					var matches = requestPathReg.FindStringSubmatch(keyword)
-					query.Where("JSON_EXTRACT(content, '$.requestPath')=:keyword").
-						Param("keyword", matches[1])
-				} else if protoReg.MatchString(keyword) {
-					isSpecialKeyword = true
-					var matches = protoReg.FindStringSubmatch(keyword)
-					query.Where("JSON_EXTRACT(content, '$.proto')=:keyword").
-						Param("keyword", strings.ToUpper(matches[1]))
-				} else if schemeReg.MatchString(keyword) {
-					isSpecialKeyword = true
-					var matches = schemeReg.FindStringSubmatch(keyword)
-					query.Where("JSON_EXTRACT(content, '$.scheme')=:keyword").
-						Param("keyword", strings.ToLower(matches[1]))
-				} else if urlReg.MatchString(keyword) { // https://xxx/yyy
-					u, err := url.Parse(keyword)
-					if err == nil {
-						isSpecialKeyword = true
-						query.Attr("domain", u.Host)
-						query.Where("JSON_EXTRACT(content, '$.requestURI') LIKE :keyword").
-							Param("keyword", dbutils.QuoteLikePrefix("\""+u.RequestURI()))
-					}
-				} else if methodReg.MatchString(keyword) { // method|requestMethod:xxx
-					isSpecialKeyword = true
-					var matches = methodReg.FindStringSubmatch(keyword)
-					query.Where("JSON_EXTRACT(content, '$.requestMethod')=:keyword").
-						Param("keyword", strings.ToUpper(matches[1]))
-				} else if refererReg.MatchString(keyword) {
-					isSpecialKeyword = true
-					var matches = refererReg.FindStringSubmatch(keyword)
-					query.Where("JSON_EXTRACT(content, '$.referer') LIKE :keyword").
-						Param("keyword", dbutils.QuoteLike(matches[1]))
-				}
-				if !isSpecialKeyword {
-					if regexp.MustCompile(`^ip:.+`).MatchString(keyword) {
-						keyword = keyword[3:]
-					}
-
-					var useOriginKeyword = false
-
-					where := "JSON_EXTRACT(content, '$.remoteAddr') LIKE :keyword OR JSON_EXTRACT(content, '$.requestURI') LIKE :keyword OR JSON_EXTRACT(content, '$.host') LIKE :keyword OR JSON_EXTRACT(content, '$.userAgent') LIKE :keyword"
-
-					jsonKeyword, err := json.Marshal(keyword)
-					if err == nil {
-						where += " OR JSON_CONTAINS(content, :jsonKeyword, '$.tags')"
-						query.Param("jsonKeyword", jsonKeyword)
-					}
-
-					// 请求方法
-					if keyword == http.MethodGet ||
-						keyword == http.MethodPost ||
-						keyword == http.MethodHead ||
-						keyword == http.MethodConnect ||
-						keyword == http.MethodPut ||
-						keyword == http.MethodTrace ||
-						keyword == http.MethodOptions ||
-						keyword == http.MethodDelete ||
-						keyword == http.MethodPatch {
-						where += " OR JSON_EXTRACT(content, '$.requestMethod')=:originKeyword"
-						useOriginKeyword = true
-					}
-
-					// 响应状态码
-					if regexp.MustCompile(`^\d{3}$`).MatchString(keyword) {
-						where += " OR status=:intKeyword"
-						query.Param("intKeyword", types.Int(keyword))
-					}
-
-					if regexp.MustCompile(`^\d{3}-\d{3}$`).MatchString(keyword) {
-						pieces := strings.Split(keyword, "-")
-						where += " OR status BETWEEN :intKeyword1 AND :intKeyword2"
-						query.Param("intKeyword1", types.Int(pieces[0]))
-						query.Param("intKeyword2", types.Int(pieces[1]))
-					}
-
-					if regexp.MustCompile(`^\d{20,}\s*\.?$`).MatchString(keyword) {
-						where += " OR requestId=:requestId"
-						query.Param("requestId", strings.TrimRight(keyword, ". "))
-					}
-
-					query.Where("("+where+")").
-						Param("keyword", dbutils.QuoteLike(keyword))
-					if useOriginKeyword {
-						query.Param("originKeyword", keyword)
-					}
-				}
+			ok, err := this.applyAccessLogFilter(query, tableQuery, filter, nodeIds, serverIds, day, hourFrom, hourTo)
+			if err != nil {
+				remotelogs.Println("DB_NODE", err.Error())
+				return
 			}
-
-			// hourFrom - hourTo
-			if len(hourFrom) > 0 && len(hourTo) > 0 {
-				var hourFromInt = types.Int(hourFrom)
-				var hourToInt = types.Int(hourTo)
-				if hourFromInt >= 0 && hourFromInt <= 23 && hourToInt >= hourFromInt && hourToInt <= 23 {
-					var y = types.Int(day[:4])
-					var m = types.Int(day[4:6])
-					var d = types.Int(day[6:])
-					var timeFrom = time.Date(y, time.Month(m), d, hourFromInt, 0, 0, 0, time.Local)
-					var timeTo = time.Date(y, time.Month(m), d, hourToInt, 59, 59, 0, time.Local)
-					query.Between("createdAt", timeFrom.Unix(), timeTo.Unix())
-				}
+			if !ok {
+				return
 			}
 
 			// offset
@@ -739,7 +953,7 @@ func (this *HTTPAccessLogDAO) listAccessLogs(tx *dbs.Tx,
 				result = append(result, accessLog)
 			}
 			locker.Unlock()
-		}(tableQuery, keyword)
+		}(tableQuery)
 	}
 	wg.Wait()
 
@@ -772,6 +986,313 @@ func (this *HTTPAccessLogDAO) listAccessLogs(tx *dbs.Tx,
 	}
 }
 
+// 所有的访问日志数据库
+func (this *HTTPAccessLogDAO) findAccessLogDAOList() []*HTTPAccessLogDAOWrapper {
+	accessLogLocker.RLock()
+	var daoList = []*HTTPAccessLogDAOWrapper{}
+	for _, daoWrapper := range httpAccessLogDAOMapping {
+		daoList = append(daoList, daoWrapper)
+	}
+	accessLogLocker.RUnlock()
+
+	if len(daoList) == 0 {
+		daoList = []*HTTPAccessLogDAOWrapper{{
+			DAO:    SharedHTTPAccessLogDAO,
+			NodeId: 0,
+		}}
+	}
+	return daoList
+}
+
+// 查询用户的网站和集群的节点，如果确定没有结果则ok为false
+func (this *HTTPAccessLogDAO) prepareAccessLogFilter(tx *dbs.Tx, filter *HTTPAccessLogFilter) (nodeIds []int64, serverIds []int64, ok bool, err error) {
+	if filter.UserId > 0 {
+		serverIds, err = SharedServerDAO.FindAllEnabledServerIdsWithUserId(tx, filter.UserId)
+		if err != nil {
+			return
+		}
+		if len(serverIds) == 0 {
+			return
+		}
+	}
+
+	// 查询某个集群下的节点
+	if filter.ClusterId > 0 {
+		nodeIds, err = SharedNodeDAO.FindAllEnabledNodeIdsWithClusterId(tx, filter.ClusterId)
+		if err != nil {
+			remotelogs.Error("DB_NODE", err.Error())
+			return nil, nil, false, nil
+		}
+		sort.Slice(nodeIds, func(i, j int) bool {
+			return nodeIds[i] < nodeIds[j]
+		})
+	}
+
+	return nodeIds, serverIds, true, nil
+}
+
+// 将查询条件应用到某个分表的查询上，如果确定没有结果则ok为false
+func (this *HTTPAccessLogDAO) applyAccessLogFilter(query *dbs.Query, tableQuery *accessLogTableQuery, filter *HTTPAccessLogFilter, nodeIds []int64, serverIds []int64, day string, hourFrom string, hourTo string) (ok bool, err error) {
+	var clusterId = filter.ClusterId
+	var nodeId = filter.NodeId
+	var serverId = filter.ServerId
+	var userId = filter.UserId
+	var hasError = filter.HasError
+	var firewallPolicyId = filter.FirewallPolicyId
+	var firewallRuleGroupId = filter.FirewallRuleGroupId
+	var firewallRuleSetId = filter.FirewallRuleSetId
+	var hasFirewallPolicy = filter.HasFirewallPolicy
+	var keyword = filter.Keyword
+	var ip = filter.IP
+	var domain = filter.Domain
+
+	// 条件
+	if nodeId > 0 {
+		query.Attr("nodeId", nodeId)
+	} else if clusterId > 0 {
+		if len(nodeIds) > 0 {
+			var nodeIdStrings = []string{}
+			for _, subNodeId := range nodeIds {
+				nodeIdStrings = append(nodeIdStrings, types.String(subNodeId))
+			}
+
+			query.Where("nodeId IN (" + strings.Join(nodeIdStrings, ",") + ")")
+			query.Reuse(false)
+		} else {
+			// 如果没有节点，则直接返回空
+			return false, nil
+		}
+	}
+	if serverId > 0 {
+		query.Attr("serverId", serverId)
+	} else if userId > 0 && len(serverIds) > 0 {
+		query.Attr("serverId", serverIds).
+			Reuse(false)
+	}
+	if hasError {
+		query.Where("status>=400")
+	}
+	if firewallPolicyId > 0 {
+		query.Attr("firewallPolicyId", firewallPolicyId)
+	}
+	if firewallRuleGroupId > 0 {
+		query.Attr("firewallRuleGroupId", firewallRuleGroupId)
+	}
+	if firewallRuleSetId > 0 {
+		query.Attr("firewallRuleSetId", firewallRuleSetId)
+	}
+	if hasFirewallPolicy {
+		query.Where("firewallPolicyId>0")
+		query.UseIndex("firewallPolicyId")
+	}
+
+	// keyword
+	if len(ip) > 0 {
+		if tableQuery.hasRemoteAddrField {
+			// IP格式
+			if strings.Contains(ip, ",") || strings.Contains(ip, "-") {
+				rangeConfig, parseErr := shared.ParseIPRange(ip)
+				if parseErr == nil {
+					if len(rangeConfig.IPFrom) > 0 && len(rangeConfig.IPTo) > 0 {
+						if iputils.IsIPv6(rangeConfig.IPFrom) || iputils.IsIPv6(rangeConfig.IPTo) {
+							var ipFromHex = iputils.ToHex(rangeConfig.IPFrom)
+							var ipToHex = iputils.ToHex(rangeConfig.IPTo)
+							if ipFromHex > ipToHex {
+								ipFromHex, ipToHex = ipToHex, ipFromHex
+							}
+							query.Between("HEX(INET6_ATON(remoteAddr))", ipFromHex, ipToHex)
+						} else {
+							var ipFromLong = iputils.ToLong(rangeConfig.IPFrom)
+							var ipToLong = iputils.ToLong(rangeConfig.IPTo)
+							if ipFromLong > ipToLong {
+								ipFromLong, ipToLong = ipToLong, ipFromLong
+							}
+							query.Between("INET_ATON(remoteAddr)", ipFromLong, ipToLong)
+						}
+					}
+				}
+			} else {
+				// 去掉IPv6的[]
+				ip = strings.Trim(ip, "[]")
+
+				query.Attr("remoteAddr", ip)
+				query.UseIndex("remoteAddr")
+			}
+		} else {
+			query.Where("JSON_EXTRACT(content, '$.remoteAddr')=:ip1").
+				Param("ip1", ip)
+		}
+	}
+	if len(domain) > 0 {
+		if tableQuery.hasDomainField {
+			if strings.Contains(domain, "*") {
+				domain = strings.ReplaceAll(domain, "*", "%")
+				domain = regexp.MustCompile(`[^a-zA-Z0-9-.%]`).ReplaceAllString(domain, "")
+				query.Where("domain LIKE :host2").
+					Param("host2", domain)
+			} else {
+				// 中文域名
+				if !regexp.MustCompile(`^[a-zA-Z0-9-.]+$`).MatchString(domain) {
+					unicodeDomain, err := idna.ToASCII(domain)
+					if err == nil && len(unicodeDomain) > 0 {
+						domain = unicodeDomain
+					}
+				}
+
+				query.Attr("domain", domain)
+				query.UseIndex("domain")
+			}
+		} else {
+			query.Where("JSON_EXTRACT(content, '$.host')=:host1").
+				Param("host1", domain)
+		}
+	}
+
+	if len(keyword) > 0 {
+		var isSpecialKeyword = false
+
+		if tableQuery.hasRemoteAddrField && net.ParseIP(keyword) != nil { // ip
+			isSpecialKeyword = true
+			query.Attr("remoteAddr", keyword)
+		} else if tableQuery.hasRemoteAddrField && regexp.MustCompile(`^ip:.+`).MatchString(keyword) { // ip:x.x.x.x
+			isSpecialKeyword = true
+			keyword = keyword[3:]
+			pieces := strings.SplitN(keyword, ",", 2)
+			if len(pieces) == 1 || len(pieces[1]) == 0 || pieces[0] == pieces[1] {
+				query.Attr("remoteAddr", pieces[0])
+			} else {
+				query.Between("INET_ATON(remoteAddr)", iputils.ToLong(pieces[0]), iputils.ToLong(pieces[1]))
+			}
+		} else if accessLogStatusRangeReg.MatchString(keyword) { // status:200-400
+			isSpecialKeyword = true
+			var matches = accessLogStatusRangeReg.FindStringSubmatch(keyword)
+			query.Between("status", types.Int(matches[1]), types.Int(matches[2]))
+		} else if accessLogStatusPrefixReg.MatchString(keyword) { // status:200
+			isSpecialKeyword = true
+			var matches = accessLogStatusPrefixReg.FindStringSubmatch(keyword)
+			query.Attr("status", matches[1])
+		} else if accessLogRequestPathReg.MatchString(keyword) {
+			isSpecialKeyword = true
+			var matches = accessLogRequestPathReg.FindStringSubmatch(keyword)
+			query.Where("JSON_EXTRACT(content, '$.requestPath')=:keyword").
+				Param("keyword", matches[1])
+		} else if accessLogProtoReg.MatchString(keyword) {
+			isSpecialKeyword = true
+			var matches = accessLogProtoReg.FindStringSubmatch(keyword)
+			query.Where("JSON_EXTRACT(content, '$.proto')=:keyword").
+				Param("keyword", strings.ToUpper(matches[1]))
+		} else if accessLogSchemeReg.MatchString(keyword) {
+			isSpecialKeyword = true
+			var matches = accessLogSchemeReg.FindStringSubmatch(keyword)
+			query.Where("JSON_EXTRACT(content, '$.scheme')=:keyword").
+				Param("keyword", strings.ToLower(matches[1]))
+		} else if accessLogURLReg.MatchString(keyword) { // https://xxx/yyy
+			u, err := url.Parse(keyword)
+			if err == nil {
+				isSpecialKeyword = true
+				query.Attr("domain", u.Host)
+				query.Where("JSON_EXTRACT(content, '$.requestURI') LIKE :keyword").
+					Param("keyword", dbutils.QuoteLikePrefix("\""+u.RequestURI()))
+			}
+		} else if accessLogMethodReg.MatchString(keyword) { // method|requestMethod:xxx
+			isSpecialKeyword = true
+			var matches = accessLogMethodReg.FindStringSubmatch(keyword)
+			query.Where("JSON_EXTRACT(content, '$.requestMethod')=:keyword").
+				Param("keyword", strings.ToUpper(matches[1]))
+		} else if accessLogRefererReg.MatchString(keyword) {
+			isSpecialKeyword = true
+			var matches = accessLogRefererReg.FindStringSubmatch(keyword)
+			query.Where("JSON_EXTRACT(content, '$.referer') LIKE :keyword").
+				Param("keyword", dbutils.QuoteLike(matches[1]))
+		}
+		if !isSpecialKeyword {
+			if regexp.MustCompile(`^ip:.+`).MatchString(keyword) {
+				keyword = keyword[3:]
+			}
+
+			var useOriginKeyword = false
+
+			where := "JSON_EXTRACT(content, '$.remoteAddr') LIKE :keyword OR JSON_EXTRACT(content, '$.requestURI') LIKE :keyword OR JSON_EXTRACT(content, '$.host') LIKE :keyword OR JSON_EXTRACT(content, '$.userAgent') LIKE :keyword"
+
+			jsonKeyword, err := json.Marshal(keyword)
+			if err == nil {
+				where += " OR JSON_CONTAINS(content, :jsonKeyword, '$.tags')"
+				query.Param("jsonKeyword", jsonKeyword)
+			}
+
+			// 请求方法
+			if keyword == http.MethodGet ||
+				keyword == http.MethodPost ||
+				keyword == http.MethodHead ||
+				keyword == http.MethodConnect ||
+				keyword == http.MethodPut ||
+				keyword == http.MethodTrace ||
+				keyword == http.MethodOptions ||
+				keyword == http.MethodDelete ||
+				keyword == http.MethodPatch {
+				where += " OR JSON_EXTRACT(content, '$.requestMethod')=:originKeyword"
+				useOriginKeyword = true
+			}
+
+			// 响应状态码
+			if regexp.MustCompile(`^\d{3}$`).MatchString(keyword) {
+				where += " OR status=:intKeyword"
+				query.Param("intKeyword", types.Int(keyword))
+			}
+
+			if regexp.MustCompile(`^\d{3}-\d{3}$`).MatchString(keyword) {
+				pieces := strings.Split(keyword, "-")
+				where += " OR status BETWEEN :intKeyword1 AND :intKeyword2"
+				query.Param("intKeyword1", types.Int(pieces[0]))
+				query.Param("intKeyword2", types.Int(pieces[1]))
+			}
+
+			if regexp.MustCompile(`^\d{20,}\s*\.?$`).MatchString(keyword) {
+				where += " OR requestId=:requestId"
+				query.Param("requestId", strings.TrimRight(keyword, ". "))
+			}
+
+			query.Where("("+where+")").
+				Param("keyword", dbutils.QuoteLike(keyword))
+			if useOriginKeyword {
+				query.Param("originKeyword", keyword)
+			}
+		}
+	}
+
+	// hourFrom - hourTo
+	if len(hourFrom) > 0 && len(hourTo) > 0 {
+		var hourFromInt = types.Int(hourFrom)
+		var hourToInt = types.Int(hourTo)
+		if hourFromInt >= 0 && hourFromInt <= 23 && hourToInt >= hourFromInt && hourToInt <= 23 {
+			var y = types.Int(day[:4])
+			var m = types.Int(day[4:6])
+			var d = types.Int(day[6:])
+			var timeFrom = time.Date(y, time.Month(m), d, hourFromInt, 0, 0, 0, time.Local)
+			var timeTo = time.Date(y, time.Month(m), d, hourToInt, 59, 59, 0, time.Local)
+			query.Between("createdAt", timeFrom.Unix(), timeTo.Unix())
+		}
+	}
+
+	// 结构化查询
+	if filter.Query != nil {
+		where, params, err := filter.Query.AsSQL(&accesslogs.QuerySQLOptions{
+			HasRemoteAddrField: tableQuery.hasRemoteAddrField,
+			HasDomainField:     tableQuery.hasDomainField,
+			ParamPrefix:        "query",
+		})
+		if err != nil {
+			return false, err
+		}
+		query.Where(where)
+		for name, value := range params {
+			query.Param(name, value)
+		}
+	}
+
+	return true, nil
+}
+
 // FindAccessLogWithRequestId 根据请求ID获取访问日志
 func (this *HTTPAccessLogDAO) FindAccessLogWithRequestId(tx *dbs.Tx, requestId string) (*HTTPAccessLog, error) {
 	if !regexp.MustCompile(`^\d{11,}`).MatchString(requestId) {
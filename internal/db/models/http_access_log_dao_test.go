@@ -2,6 +2,8 @@ package models
 
 import (
 	"encoding/json"
+	"errors"
+	"github.com/dashenmiren/EdgeAPI/internal/accesslogs"
 	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/iwind/TeaGo/bootstrap"
@@ -206,6 +208,38 @@ func TestHTTPAccessLogDAO_ListAccessLogs_Page_NotExists(t *testing.T) {
 	}
 }
 
+func TestHTTPAccessLogDAO_ExportAccessLogs(t *testing.T) {
+	var tx *dbs.Tx
+
+	err := NewDBNodeInitializer().loop()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var timeTo = time.Now().Unix()
+	var timeFrom = timeTo - 2*86400
+	var lastRequestId = ""
+	var count = 0
+	err = SharedHTTPAccessLogDAO.ExportAccessLogs(tx, timeFrom, timeTo, nil, nil, 100, func(accessLogs []*HTTPAccessLog, cursor *accesslogs.ExportCursor) error {
+		for _, accessLog := range accessLogs {
+			if accessLog.RequestId <= lastRequestId {
+				t.Fatal("requestId should be in ascending order")
+			}
+			lastRequestId = accessLog.RequestId
+		}
+		count += len(accessLogs)
+		t.Log("cursor:", cursor.Day, cursor.RequestId, "count:", count)
+		if count >= 1000 {
+			return errors.New("stop")
+		}
+		return nil
+	})
+	if err != nil && err.Error() != "stop" {
+		t.Fatal(err)
+	}
+	t.Log("exported:", count)
+}
+
 func BenchmarkHTTPAccessLogDAO_JSONEncode(b *testing.B) {
 	var accessLog = &pb.HTTPAccessLog{
 		RequestPath: "/hello/world",
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"github.com/dashenmiren/EdgeAPI/internal/accesslogs"
 	"github.com/dashenmiren/EdgeAPI/internal/errors"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/iwind/TeaGo/Tea"
@@ -30,6 +31,18 @@ var SharedHTTPAccessLogPolicyDAO *HTTPAccessLogPolicyDAO
 func init() {
 	dbs.OnReady(func() {
 		SharedHTTPAccessLogPolicyDAO = NewHTTPAccessLogPolicyDAO()
+
+		accesslogs.SharedManager.SetPoliciesLoader(func() ([]*accesslogs.PolicyConfig, error) {
+			policies, err := SharedHTTPAccessLogPolicyDAO.FindAllEnabledAndOnPolicies(nil)
+			if err != nil {
+				return nil, err
+			}
+			var result = []*accesslogs.PolicyConfig{}
+			for _, policy := range policies {
+				result = append(result, policy.ToPolicyConfig())
+			}
+			return result, nil
+		})
 	})
 }
 
@@ -1 +1,19 @@
 package models
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/accesslogs"
+)
+
+// ToPolicyConfig 转换为访问日志策略配置
+func (this *HTTPAccessLogPolicy) ToPolicyConfig() *accesslogs.PolicyConfig {
+	return &accesslogs.PolicyConfig{
+		Id:               int64(this.Id),
+		Version:          int(this.Version),
+		Type:             this.Type,
+		OptionsJSON:      this.Options,
+		CondsJSON:        this.Conds,
+		IsPublic:         this.IsPublic,
+		FirewallOnly:     this.FirewallOnly == 1,
+		DisableDefaultDB: this.DisableDefaultDB,
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package models
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/utils/iprange"
+	"github.com/iwind/TeaGo/dbs"
+	"github.com/iwind/TeaGo/types"
+	"sort"
+	"time"
+)
+
+// IPItemCompactResult 合并结果
+type IPItemCompactResult struct {
+	CountLists   int `json:"countLists"`   // 检查的名单数
+	CountItems   int `json:"countItems"`   // 检查的条目数
+	CountMerged  int `json:"countMerged"`  // 被合并删除的条目数
+	CountCreated int `json:"countCreated"` // 合并后新创建的条目数
+}
+
+// CompactIPItems 合并名单中重叠或者相邻的IP范围
+// 只合并过期时间、级别和有效范围都相同的手动添加条目，订阅导入的条目和"所有IP"类型的条目保持不变；
+// listId 为0时处理所有名单，dryRun 为 true 时只计算结果而不修改数据
+func (this *IPItemDAO) CompactIPItems(tx *dbs.Tx, listId int64, dryRun bool) (*IPItemCompactResult, error) {
+	var listIds = []int64{}
+	if listId > 0 {
+		listIds = append(listIds, listId)
+	} else {
+		ones, err := this.Query(tx).
+			Result("DISTINCT(listId) AS listId").
+			State(IPItemStateEnabled).
+			FindAll()
+		if err != nil {
+			return nil, err
+		}
+		for _, one := range ones {
+			listIds = append(listIds, int64(one.(*IPItem).ListId))
+		}
+	}
+
+	var result = &IPItemCompactResult{}
+	for _, compactListId := range listIds {
+		err := this.compactListItems(tx, compactListId, dryRun, result)
+		if err != nil {
+			return result, err
+		}
+		result.CountLists++
+	}
+	return result, nil
+}
+
+type ipItemCompactEntry struct {
+	item *IPItem
+	r    iprange.Range
+}
+
+func (this *IPItemDAO) compactListItems(tx *dbs.Tx, listId int64, dryRun bool, result *IPItemCompactResult) error {
+	// 按照可以合并的属性分组
+	var groups = map[string][]*ipItemCompactEntry{}
+	var now = time.Now().Unix()
+	var lastId int64
+	for {
+		var items []*IPItem
+		_, err := this.Query(tx).
+			Attr("listId", listId).
+			Attr("sourceFeedId", 0).
+			State(IPItemStateEnabled).
+			Gt("id", lastId).
+			AscPk().
+			Limit(1000).
+			Slice(&items).
+			FindAll()
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			break
+		}
+		for _, item := range items {
+			lastId = int64(item.Id)
+			if item.Type == IPItemTypeAll || (item.ExpiredAt > 0 && int64(item.ExpiredAt) <= now) || ipItemSource(item) != IPItemSourceManual {
+				continue
+			}
+			r, err := iprange.ParseRange(item.IpFrom, item.IpTo)
+			if err != nil {
+				continue
+			}
+			result.CountItems++
+
+			var key = types.String(item.ExpiredAt) + "|" + item.EventLevel + "|" + types.String(item.NodeId) + "|" + types.String(item.ServerId)
+			groups[key] = append(groups[key], &ipItemCompactEntry{item: item, r: r})
+		}
+	}
+
+	var lastItemId int64
+	for _, entries := range groups {
+		if len(entries) < 2 {
+			continue
+		}
+
+		var ranges = []iprange.Range{}
+		for _, entry := range entries {
+			ranges = append(ranges, entry.r)
+		}
+		var mergedRanges = iprange.Merge(ranges)
+		if len(mergedRanges) == len(entries) {
+			continue
+		}
+
+		// 将条目归入合并后的范围
+		var members = make([][]*ipItemCompactEntry, len(mergedRanges))
+		for _, entry := range entries {
+			var index = sort.Search(len(mergedRanges), func(i int) bool {
+				var r = mergedRanges[i]
+				if r.From.BitLen() != entry.r.From.BitLen() {
+					return r.From.BitLen() > entry.r.From.BitLen()
+				}
+				return !r.To.Less(entry.r.From)
+			})
+			if index < len(mergedRanges) && mergedRanges[index].Contains(entry.r.From) {
+				members[index] = append(members[index], entry)
+			}
+		}
+
+		for index, mergedRange := range mergedRanges {
+			var rangeMembers = members[index]
+			if len(rangeMembers) < 2 {
+				continue
+			}
+			result.CountMerged += len(rangeMembers)
+			result.CountCreated++
+			if dryRun {
+				continue
+			}
+
+			var first = rangeMembers[0].item
+			var value = mergedRange.String()
+			var prefixes = mergedRange.Prefixes()
+			if len(prefixes) == 1 && !mergedRange.IsSingle() {
+				value = prefixes[0].String()
+			}
+			var ipTo = mergedRange.To.String()
+			if mergedRange.IsSingle() {
+				ipTo = ""
+			}
+
+			itemId, err := this.CreateIPItem(tx, listId, value, mergedRange.From.String(), ipTo, int64(first.ExpiredAt), first.Reason, "", first.EventLevel, int64(first.NodeId), int64(first.ServerId), 0, 0, 0, 0, 0, false)
+			if err != nil {
+				return err
+			}
+			lastItemId = itemId
+			for _, member := range rangeMembers {
+				err = this.disableItem(tx, int64(member.item.Id))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if lastItemId > 0 {
+		return this.NotifyUpdate(tx, lastItemId)
+	}
+	return nil
+}
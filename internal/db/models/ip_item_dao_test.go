@@ -5,6 +5,7 @@ import (
 	"github.com/dashenmiren/EdgeCommon/pkg/serverconfigs/firewallconfigs"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/iwind/TeaGo/dbs"
+	"github.com/iwind/TeaGo/logs"
 	"github.com/iwind/TeaGo/rands"
 	"github.com/iwind/TeaGo/types"
 	"testing"
@@ -88,3 +89,28 @@ func TestIPItemDAO_ParseIPValue(t *testing.T) {
 	t.Log(dao.ParseIPValue("192.168.1.200/256"))
 	t.Log(dao.ParseIPValue("192.168.1.200-"))
 }
+
+func TestIPItemDAO_ExplainIP(t *testing.T) {
+	dbs.NotifyReady()
+
+	var tx *dbs.Tx
+	explanations, err := models.SharedIPItemDAO.ExplainIP(tx, "192.168.1.100", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, explanation := range explanations {
+		t.Log(explanation.Item.Id, explanation.Item.ComposeValue(), explanation.List.Name, explanation.Scope, explanation.Source)
+	}
+	t.Log(models.SharedIPItemIndex.Len(), "items in index")
+}
+
+func TestIPItemDAO_CompactIPItems(t *testing.T) {
+	dbs.NotifyReady()
+
+	var tx *dbs.Tx
+	result, err := models.SharedIPItemDAO.CompactIPItems(tx, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs.PrintAsJSON(result, t)
+}
@@ -0,0 +1,162 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package models
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/utils"
+	"github.com/dashenmiren/EdgeCommon/pkg/serverconfigs/firewallconfigs"
+	"github.com/dashenmiren/EdgeCommon/pkg/serverconfigs/ipconfigs"
+	"github.com/iwind/TeaGo/dbs"
+	"sort"
+	"time"
+)
+
+// IP名单作用范围
+const (
+	IPListScopeGlobal = "global" // 全局名单，对所有网站有效
+	IPListScopePublic = "public" // 公共名单，被WAF策略引用后生效
+	IPListScopeServer = "server" // 网站名单
+	IPListScopeUser   = "user"   // 用户名单
+	IPListScopePolicy = "policy" // WAF策略自身的名单
+)
+
+// IP条目来源
+const (
+	IPItemSourceManual = "manual" // 手动添加或者导入
+	IPItemSourceWAF    = "waf"    // WAF拦截事件自动添加
+	IPItemSourceFeed   = "feed"   // 名单订阅
+)
+
+// IPItemExplanation 影响某个IP的条目
+type IPItemExplanation struct {
+	Item   *IPItem
+	List   *IPList
+	Policy *HTTPFirewallPolicy // 引用名单的WAF策略，只有非公共名单才会查找
+	Scope  string              // 名单作用范围
+	Source string              // 条目来源
+}
+
+// ExplainIP 查找影响某个IP的所有有效条目
+// 结果按照作用范围、名单类型（白名单、黑名单、灰名单）和条目ID排序；userId 大于0时只返回此用户可以查看的条目
+func (this *IPItemDAO) ExplainIP(tx *dbs.Tx, ip string, userId int64) ([]*IPItemExplanation, error) {
+	itemIds, err := SharedIPItemIndex.FindItemIdsContainsIP(tx, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	var result = []*IPItemExplanation{}
+	var cacheMap = utils.NewCacheMap()
+	var now = time.Now().Unix()
+	for _, itemId := range itemIds {
+		item, err := this.FindEnabledIPItem(tx, itemId)
+		if err != nil {
+			return nil, err
+		}
+		if item == nil || (item.ExpiredAt > 0 && int64(item.ExpiredAt) <= now) {
+			continue
+		}
+
+		list, err := SharedIPListDAO.FindEnabledIPList(tx, int64(item.ListId), cacheMap)
+		if err != nil {
+			return nil, err
+		}
+		if list == nil {
+			continue
+		}
+
+		if userId > 0 {
+			if firewallconfigs.IsGlobalListId(int64(list.Id)) {
+				if int64(item.SourceUserId) != userId {
+					continue
+				}
+			} else if int64(list.UserId) != userId {
+				continue
+			}
+		}
+
+		// 非公共名单只有被WAF策略引用时才会生效
+		var policy *HTTPFirewallPolicy
+		if !list.IsPublic && !firewallconfigs.IsGlobalListId(int64(list.Id)) {
+			policy, err = SharedHTTPFirewallPolicyDAO.FindEnabledFirewallPolicyWithIPListId(tx, int64(list.Id))
+			if err != nil {
+				return nil, err
+			}
+			if policy == nil {
+				continue
+			}
+		}
+
+		result = append(result, &IPItemExplanation{
+			Item:   item,
+			List:   list,
+			Policy: policy,
+			Scope:  ipListScope(list, policy),
+			Source: ipItemSource(item),
+		})
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		var e1, e2 = result[i], result[j]
+		var s1, s2 = ipListScopeOrder(e1.Scope), ipListScopeOrder(e2.Scope)
+		if s1 != s2 {
+			return s1 < s2
+		}
+		var t1, t2 = ipListTypeOrder(e1.List.Type), ipListTypeOrder(e2.List.Type)
+		if t1 != t2 {
+			return t1 < t2
+		}
+		return e1.Item.Id < e2.Item.Id
+	})
+	return result, nil
+}
+
+// 名单作用范围
+func ipListScope(list *IPList, policy *HTTPFirewallPolicy) string {
+	switch {
+	case list.IsGlobal || firewallconfigs.IsGlobalListId(int64(list.Id)):
+		return IPListScopeGlobal
+	case list.ServerId > 0 || (policy != nil && policy.ServerId > 0):
+		return IPListScopeServer
+	case list.IsPublic:
+		return IPListScopePublic
+	case list.UserId > 0:
+		return IPListScopeUser
+	}
+	return IPListScopePolicy
+}
+
+func ipListScopeOrder(scope string) int {
+	switch scope {
+	case IPListScopeGlobal:
+		return 0
+	case IPListScopePublic:
+		return 1
+	case IPListScopePolicy:
+		return 2
+	case IPListScopeUser:
+		return 3
+	}
+	return 4
+}
+
+// 和节点的检查顺序一致：白名单、黑名单、灰名单
+func ipListTypeOrder(listType string) int {
+	switch listType {
+	case ipconfigs.IPListTypeWhite:
+		return 0
+	case ipconfigs.IPListTypeBlack:
+		return 1
+	}
+	return 2
+}
+
+// 条目来源
+func ipItemSource(item *IPItem) string {
+	if item.SourceFeedId > 0 {
+		return IPItemSourceFeed
+	}
+	if item.SourceHTTPFirewallPolicyId > 0 || item.SourceHTTPFirewallRuleSetId > 0 || item.SourceNodeId > 0 {
+		return IPItemSourceWAF
+	}
+	return IPItemSourceManual
+}
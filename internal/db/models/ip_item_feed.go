@@ -0,0 +1,138 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package models
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/iwind/TeaGo/dbs"
+)
+
+// SyncFeedItems 将订阅中的条目同步到名单
+// 只处理从此订阅导入的条目：增加新出现的条目，删除订阅中已经不存在的条目，未变化的条目保持原有版本号
+func (this *IPItemDAO) SyncFeedItems(tx *dbs.Tx, listId int64, feedId int64, items []*IPItemImportItem, reason string) (countAdded int, countRemoved int, err error) {
+	if listId <= 0 || feedId <= 0 {
+		return 0, 0, errors.New("invalid listId or feedId")
+	}
+
+	// 当前已导入的条目
+	var existingItemIds = map[string][]int64{} // key => [itemId1, ...]
+	var lastId int64
+	for {
+		var ones []*IPItem
+		_, err = this.Query(tx).
+			Result("id", "ipFrom", "ipTo").
+			Attr("listId", listId).
+			Attr("sourceFeedId", feedId).
+			State(IPItemStateEnabled).
+			Gt("id", lastId).
+			AscPk().
+			Limit(1000).
+			Slice(&ones).
+			FindAll()
+		if err != nil {
+			return 0, 0, err
+		}
+		if len(ones) == 0 {
+			break
+		}
+		for _, one := range ones {
+			var key = ipItemRangeKey(one.IpFrom, one.IpTo)
+			existingItemIds[key] = append(existingItemIds[key], int64(one.Id))
+			lastId = int64(one.Id)
+		}
+	}
+
+	var lastItemId int64
+
+	// 增加
+	var desiredKeys = map[string]bool{}
+	for _, item := range items {
+		var key = item.Key()
+		if desiredKeys[key] {
+			continue
+		}
+		desiredKeys[key] = true
+		if len(existingItemIds[key]) > 0 {
+			continue
+		}
+
+		var itemReason = item.Reason
+		if len(itemReason) == 0 {
+			itemReason = reason
+		}
+		itemId, err := this.CreateIPItem(tx, listId, item.Value, item.IPFrom, item.IPTo, item.ExpiredAt, itemReason, "", item.EventLevel, 0, 0, 0, 0, 0, 0, 0, false)
+		if err != nil {
+			return 0, 0, err
+		}
+		err = this.Query(tx).
+			Pk(itemId).
+			Set("sourceFeedId", feedId).
+			UpdateQuickly()
+		if err != nil {
+			return 0, 0, err
+		}
+		lastItemId = itemId
+		countAdded++
+	}
+
+	// 删除
+	for key, itemIds := range existingItemIds {
+		var keepOne = desiredKeys[key]
+		for index, itemId := range itemIds {
+			// 保留一个，删除多余的重复条目
+			if keepOne && index == 0 {
+				continue
+			}
+			err = this.disableItem(tx, itemId)
+			if err != nil {
+				return 0, 0, err
+			}
+			lastItemId = itemId
+			countRemoved++
+		}
+	}
+
+	if lastItemId > 0 {
+		err = this.NotifyUpdate(tx, lastItemId)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return
+}
+
+// DisableIPItemsWithFeedId 删除从某个订阅导入的所有条目
+func (this *IPItemDAO) DisableIPItemsWithFeedId(tx *dbs.Tx, feedId int64) error {
+	if feedId <= 0 {
+		return nil
+	}
+
+	var lastItemId int64
+	for {
+		ones, err := this.Query(tx).
+			ResultPk().
+			Attr("sourceFeedId", feedId).
+			State(IPItemStateEnabled).
+			Limit(1000).
+			FindAll()
+		if err != nil {
+			return err
+		}
+		if len(ones) == 0 {
+			break
+		}
+		for _, one := range ones {
+			var itemId = int64(one.(*IPItem).Id)
+			err = this.disableItem(tx, itemId)
+			if err != nil {
+				return err
+			}
+			lastItemId = itemId
+		}
+	}
+
+	if lastItemId > 0 {
+		return this.NotifyUpdate(tx, lastItemId)
+	}
+	return nil
+}
@@ -0,0 +1,466 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/dashenmiren/EdgeAPI/internal/utils/iprange"
+	"github.com/iwind/TeaGo/dbs"
+	"github.com/iwind/TeaGo/types"
+	"io"
+	"strings"
+	"time"
+)
+
+// IP条目导入导出格式
+const (
+	IPItemFormatText = "text" // 每行一个IP、IP范围或CIDR，支持 # 和 ; 注释，兼容 Spamhaus DROP 和 FireHOL netset 等格式
+	IPItemFormatCIDR = "cidr" // 每行一个CIDR，导出时IP范围会拆分成多个CIDR
+	IPItemFormatCSV  = "csv"  // value,expiredAt,reason,eventLevel，第一行为 value 开头的表头时按照表头读取
+	IPItemFormatJSON = "json" // [{"value": "...", "expiredAt": 0, "reason": "", "eventLevel": ""}, ...]
+)
+
+// 导入冲突原因
+const (
+	IPItemConflictInvalid   = "invalid"   // 格式错误
+	IPItemConflictDuplicate = "duplicate" // 和导入内容中前面的条目重复
+	IPItemConflictExists    = "exists"    // 名单中已经存在
+)
+
+// IPItemImportItem 导入的条目
+type IPItemImportItem struct {
+	Line       int    `json:"-"`          // 所在行，JSON格式中为条目序号，从1开始
+	Value      string `json:"value"`      // 原始值
+	IPFrom     string `json:"ipFrom"`     // 开始IP
+	IPTo       string `json:"ipTo"`       // 结束IP
+	ExpiredAt  int64  `json:"expiredAt"`  // 过期时间
+	Reason     string `json:"reason"`     // 加入说明
+	EventLevel string `json:"eventLevel"` // 事件级别
+}
+
+// Key 用于去重的键，单个IP和 ip/32 等只包含一个IP的CIDR视为相同
+func (this *IPItemImportItem) Key() string {
+	return ipItemRangeKey(this.IPFrom, this.IPTo)
+}
+
+// IPItemImportConflict 导入冲突
+type IPItemImportConflict struct {
+	Line   int    `json:"line"`   // 所在行
+	Value  string `json:"value"`  // 原始值
+	Reason string `json:"reason"` // 冲突原因
+	ItemId int64  `json:"itemId"` // 已经存在的条目ID
+}
+
+// IPItemImportResult 导入结果
+type IPItemImportResult struct {
+	CountCreated int                     `json:"countCreated"` // 新创建的条目数
+	CountUpdated int                     `json:"countUpdated"` // 覆盖的条目数
+	CountSkipped int                     `json:"countSkipped"` // 跳过的条目数，包括格式错误、重复和已经存在的条目
+	Conflicts    []*IPItemImportConflict `json:"conflicts"`    // 冲突
+}
+
+// IsValidIPItemFormat 判断导入导出格式是否有效
+func IsValidIPItemFormat(format string) bool {
+	switch format {
+	case IPItemFormatText, IPItemFormatCIDR, IPItemFormatCSV, IPItemFormatJSON:
+		return true
+	}
+	return false
+}
+
+// ParseIPItems 解析导入的内容
+// 格式错误和重复的条目作为冲突返回，只有内容无法解析时才返回错误
+func (this *IPItemDAO) ParseIPItems(format string, data []byte) (items []*IPItemImportItem, conflicts []*IPItemImportConflict, err error) {
+	var rawItems []*IPItemImportItem
+	switch format {
+	case IPItemFormatText, IPItemFormatCIDR:
+		rawItems, err = this.parseIPItemsText(data)
+	case IPItemFormatCSV:
+		rawItems, err = this.parseIPItemsCSV(data)
+	case IPItemFormatJSON:
+		rawItems, err = this.parseIPItemsJSON(data)
+	default:
+		return nil, nil, errors.New("invalid format '" + format + "'")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var lineMap = map[string]int{} // key => line
+	for _, item := range rawItems {
+		// 根据开始和结束IP组合原始值
+		if len(item.Value) == 0 && len(item.IPFrom) > 0 {
+			item.Value = item.IPFrom
+			if len(item.IPTo) > 0 && item.IPTo != item.IPFrom {
+				item.Value += "-" + item.IPTo
+			}
+		}
+
+		if format == IPItemFormatCIDR && !strings.Contains(item.Value, "/") {
+			conflicts = append(conflicts, &IPItemImportConflict{Line: item.Line, Value: item.Value, Reason: IPItemConflictInvalid})
+			continue
+		}
+
+		newValue, ipFrom, ipTo, ok := this.ParseIPValue(item.Value)
+		if !ok {
+			conflicts = append(conflicts, &IPItemImportConflict{Line: item.Line, Value: item.Value, Reason: IPItemConflictInvalid})
+			continue
+		}
+		item.Value = newValue
+		item.IPFrom = ipFrom
+		item.IPTo = ipTo
+		if item.ExpiredAt < 0 {
+			item.ExpiredAt = 0
+		}
+
+		var key = item.Key()
+		_, exists := lineMap[key]
+		if exists {
+			conflicts = append(conflicts, &IPItemImportConflict{Line: item.Line, Value: item.Value, Reason: IPItemConflictDuplicate})
+			continue
+		}
+		lineMap[key] = item.Line
+		items = append(items, item)
+	}
+	return
+}
+
+// ImportIPItems 导入IP条目
+// overwrite 为 true 时覆盖名单中已经存在的相同条目，否则跳过并作为冲突返回；导入完成后只通知一次更新
+func (this *IPItemDAO) ImportIPItems(tx *dbs.Tx, listId int64, items []*IPItemImportItem, overwrite bool) (*IPItemImportResult, error) {
+	if listId <= 0 {
+		return nil, errors.New("invalid listId")
+	}
+
+	var result = &IPItemImportResult{}
+	var lastItemId int64
+	for _, item := range items {
+		existingItemIds, err := this.findEnabledItemIdsWithRange(tx, listId, item.IPFrom, item.IPTo)
+		if err != nil {
+			return nil, err
+		}
+		if len(existingItemIds) > 0 {
+			if !overwrite {
+				result.CountSkipped++
+				result.Conflicts = append(result.Conflicts, &IPItemImportConflict{
+					Line:   item.Line,
+					Value:  item.Value,
+					Reason: IPItemConflictExists,
+					ItemId: existingItemIds[0],
+				})
+				continue
+			}
+			for _, itemId := range existingItemIds {
+				err = this.disableItem(tx, itemId)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		itemId, err := this.CreateIPItem(tx, listId, item.Value, item.IPFrom, item.IPTo, item.ExpiredAt, item.Reason, "", item.EventLevel, 0, 0, 0, 0, 0, 0, 0, false)
+		if err != nil {
+			return nil, err
+		}
+		lastItemId = itemId
+		if len(existingItemIds) > 0 {
+			result.CountUpdated++
+		} else {
+			result.CountCreated++
+		}
+	}
+
+	if lastItemId > 0 {
+		err := this.NotifyUpdate(tx, lastItemId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// ExportIPItems 导出名单中所有有效的IP条目
+// text 和 cidr 格式无法表示"所有IP"类型的条目，导出时会跳过
+func (this *IPItemDAO) ExportIPItems(tx *dbs.Tx, listId int64, format string, writer io.Writer) (count int64, err error) {
+	if !IsValidIPItemFormat(format) {
+		return 0, errors.New("invalid format '" + format + "'")
+	}
+
+	var bufWriter = bufio.NewWriter(writer)
+	var csvWriter *csv.Writer
+	switch format {
+	case IPItemFormatCSV:
+		csvWriter = csv.NewWriter(bufWriter)
+		err = csvWriter.Write([]string{"value", "ipFrom", "ipTo", "type", "expiredAt", "reason", "eventLevel"})
+		if err != nil {
+			return 0, err
+		}
+	case IPItemFormatJSON:
+		_, err = bufWriter.WriteString("[")
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var lastId int64
+	for {
+		var items []*IPItem
+		_, err = this.Query(tx).
+			Attr("listId", listId).
+			State(IPItemStateEnabled).
+			Where("(expiredAt=0 OR expiredAt>:now)").
+			Param("now", time.Now().Unix()).
+			Gt("id", lastId).
+			AscPk().
+			Limit(1000).
+			Slice(&items).
+			FindAll()
+		if err != nil {
+			return count, err
+		}
+		if len(items) == 0 {
+			break
+		}
+
+		for _, item := range items {
+			lastId = int64(item.Id)
+
+			switch format {
+			case IPItemFormatText:
+				if item.Type == IPItemTypeAll {
+					continue
+				}
+				_, err = bufWriter.WriteString(item.ComposeValue() + "\n")
+			case IPItemFormatCIDR:
+				if item.Type == IPItemTypeAll {
+					continue
+				}
+				var cidrs []string
+				cidrs, err = ipItemCIDRs(item)
+				if err != nil {
+					return count, err
+				}
+				for _, cidr := range cidrs {
+					_, err = bufWriter.WriteString(cidr + "\n")
+					if err != nil {
+						return count, err
+					}
+				}
+			case IPItemFormatCSV:
+				err = csvWriter.Write([]string{item.ComposeValue(), item.IpFrom, item.IpTo, item.Type, types.String(item.ExpiredAt), item.Reason, item.EventLevel})
+			case IPItemFormatJSON:
+				if count > 0 {
+					_, err = bufWriter.WriteString(",")
+					if err != nil {
+						return count, err
+					}
+				}
+				var itemJSON []byte
+				itemJSON, err = json.Marshal(map[string]any{
+					"value":      item.ComposeValue(),
+					"ipFrom":     item.IpFrom,
+					"ipTo":       item.IpTo,
+					"type":       item.Type,
+					"expiredAt":  item.ExpiredAt,
+					"reason":     item.Reason,
+					"eventLevel": item.EventLevel,
+				})
+				if err != nil {
+					return count, err
+				}
+				_, err = bufWriter.Write(itemJSON)
+			}
+			if err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+
+	switch format {
+	case IPItemFormatCSV:
+		csvWriter.Flush()
+		err = csvWriter.Error()
+		if err != nil {
+			return count, err
+		}
+	case IPItemFormatJSON:
+		_, err = bufWriter.WriteString("]")
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, bufWriter.Flush()
+}
+
+// 查找名单中和IP范围相同的条目
+func (this *IPItemDAO) findEnabledItemIdsWithRange(tx *dbs.Tx, listId int64, ipFrom string, ipTo string) ([]int64, error) {
+	ones, err := this.Query(tx).
+		Result("id", "ipFrom", "ipTo").
+		UseIndex("ipFrom").
+		Attr("listId", listId).
+		Attr("ipFrom", ipFrom).
+		State(IPItemStateEnabled).
+		FindAll()
+	if err != nil {
+		return nil, err
+	}
+	var key = ipItemRangeKey(ipFrom, ipTo)
+	var itemIds = []int64{}
+	for _, one := range ones {
+		var item = one.(*IPItem)
+		if ipItemRangeKey(item.IpFrom, item.IpTo) == key {
+			itemIds = append(itemIds, int64(item.Id))
+		}
+	}
+	return itemIds, nil
+}
+
+// 禁用单个条目，并增加版本号以便节点同步删除
+func (this *IPItemDAO) disableItem(tx *dbs.Tx, itemId int64) error {
+	version, err := SharedIPListDAO.IncreaseVersion(tx)
+	if err != nil {
+		return err
+	}
+	return this.Query(tx).
+		Pk(itemId).
+		Set("version", version).
+		Set("state", IPItemStateDisabled).
+		UpdateQuickly()
+}
+
+func (this *IPItemDAO) parseIPItemsText(data []byte) (items []*IPItemImportItem, err error) {
+	var scanner = bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var line = 0
+	for scanner.Scan() {
+		line++
+		var text = scanner.Text()
+
+		// 去除注释
+		var commentIndex = strings.IndexAny(text, "#;")
+		if commentIndex >= 0 {
+			text = text[:commentIndex]
+		}
+		var fields = strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+
+		// ip1 - ip2
+		var value = fields[0]
+		if len(fields) >= 3 && fields[1] == "-" {
+			value = fields[0] + "-" + fields[2]
+		}
+		items = append(items, &IPItemImportItem{
+			Line:  line,
+			Value: value,
+		})
+	}
+	return items, scanner.Err()
+}
+
+func (this *IPItemDAO) parseIPItemsCSV(data []byte) (items []*IPItemImportItem, err error) {
+	var reader = csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	var columns = []string{"value", "expiredAt", "reason", "eventLevel"}
+	var isFirst = true
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		// 表头
+		if isFirst {
+			isFirst = false
+			if len(record) > 0 && strings.TrimSpace(record[0]) == "value" {
+				columns = []string{}
+				for _, column := range record {
+					columns = append(columns, strings.TrimSpace(column))
+				}
+				continue
+			}
+		}
+
+		var item = &IPItemImportItem{Line: line}
+		for index, column := range columns {
+			if index >= len(record) {
+				break
+			}
+			var value = strings.TrimSpace(record[index])
+			switch column {
+			case "value":
+				item.Value = value
+			case "ipFrom":
+				item.IPFrom = value
+			case "ipTo":
+				item.IPTo = value
+			case "expiredAt":
+				item.ExpiredAt = types.Int64(value)
+			case "reason":
+				item.Reason = value
+			case "eventLevel":
+				item.EventLevel = value
+			}
+		}
+		if len(item.Value) == 0 && len(item.IPFrom) == 0 {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (this *IPItemDAO) parseIPItemsJSON(data []byte) (items []*IPItemImportItem, err error) {
+	err = json.Unmarshal(data, &items)
+	if err != nil {
+		return nil, errors.New("decode json failed: " + err.Error())
+	}
+	for index, item := range items {
+		if item == nil {
+			items[index] = &IPItemImportItem{}
+			item = items[index]
+		}
+		item.Line = index + 1
+	}
+	return items, nil
+}
+
+// 组合IP范围的键，结束IP为空或者和开始IP相同时只使用开始IP
+func ipItemRangeKey(ipFrom string, ipTo string) string {
+	if len(ipTo) == 0 || ipTo == ipFrom {
+		return ipFrom
+	}
+	return ipFrom + "-" + ipTo
+}
+
+// 将条目转换为CIDR列表
+func ipItemCIDRs(item *IPItem) ([]string, error) {
+	var value = item.ComposeValue()
+	if strings.Contains(value, "/") {
+		return []string{value}, nil
+	}
+
+	r, err := iprange.ParseRange(item.IpFrom, item.IpTo)
+	if err != nil {
+		return nil, errors.New(err.Error() + " in item '" + types.String(item.Id) + "'")
+	}
+
+	var result = []string{}
+	for _, prefix := range r.Prefixes() {
+		result = append(result, prefix.String())
+	}
+	return result, nil
+}
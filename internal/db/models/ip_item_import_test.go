@@ -0,0 +1,96 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package models
+
+import (
+	"testing"
+)
+
+func TestIPItemDAO_ParseIPItems_Text(t *testing.T) {
+	var dao = NewIPItemDAO()
+	items, conflicts, err := dao.ParseIPItems(IPItemFormatText, []byte(`; Spamhaus DROP List
+1.10.16.0/20 ; SBL256894
+# FireHOL netset
+192.168.1.1
+192.168.1.1/32
+10.0.0.10 - 10.0.0.1
+abc
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatal("expect 3 items, got", len(items))
+	}
+	if items[0].IPFrom != "1.10.16.0" || items[0].IPTo != "1.10.31.255" || items[0].Line != 2 {
+		t.Fatal("unexpected item:", items[0])
+	}
+	if items[2].IPFrom != "10.0.0.1" || items[2].IPTo != "10.0.0.10" {
+		t.Fatal("unexpected item:", items[2])
+	}
+	if len(conflicts) != 2 ||
+		conflicts[0].Line != 5 || conflicts[0].Reason != IPItemConflictDuplicate ||
+		conflicts[1].Line != 7 || conflicts[1].Reason != IPItemConflictInvalid {
+		t.Fatal("unexpected conflicts")
+	}
+}
+
+func TestIPItemDAO_ParseIPItems_CIDR(t *testing.T) {
+	var dao = NewIPItemDAO()
+	items, conflicts, err := dao.ParseIPItems(IPItemFormatCIDR, []byte("1.2.3.0/24\n1.2.3.4\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || len(conflicts) != 1 || conflicts[0].Reason != IPItemConflictInvalid {
+		t.Fatal("unexpected result")
+	}
+}
+
+func TestIPItemDAO_ParseIPItems_CSV(t *testing.T) {
+	var dao = NewIPItemDAO()
+	items, conflicts, err := dao.ParseIPItems(IPItemFormatCSV, []byte(`value,reason,expiredAt
+1.1.1.1,spam,1700000000
+2.2.2.2-2.2.2.3,,
+1.1.1.1,dup,
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || len(conflicts) != 1 {
+		t.Fatal("unexpected result:", len(items), len(conflicts))
+	}
+	if items[0].Reason != "spam" || items[0].ExpiredAt != 1700000000 || items[0].Line != 2 {
+		t.Fatal("unexpected item:", items[0])
+	}
+	if conflicts[0].Line != 4 || conflicts[0].Reason != IPItemConflictDuplicate {
+		t.Fatal("unexpected conflict:", conflicts[0])
+	}
+
+	// 无表头
+	items, _, err = dao.ParseIPItems(IPItemFormatCSV, []byte("3.3.3.3,0,test,warning\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Reason != "test" || items[0].EventLevel != "warning" {
+		t.Fatal("unexpected items")
+	}
+}
+
+func TestIPItemDAO_ParseIPItems_JSON(t *testing.T) {
+	var dao = NewIPItemDAO()
+	items, conflicts, err := dao.ParseIPItems(IPItemFormatJSON, []byte(`[{"value": "1.1.1.1"}, {"ipFrom": "1.1.1.1"}, {"value": "::1", "reason": "local"}, {"value": ""}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || len(conflicts) != 2 {
+		t.Fatal("unexpected result:", len(items), len(conflicts))
+	}
+	if conflicts[0].Line != 2 || conflicts[0].Reason != IPItemConflictDuplicate {
+		t.Fatal("unexpected conflict:", conflicts[0])
+	}
+
+	_, _, err = dao.ParseIPItems(IPItemFormatJSON, []byte("{"))
+	if err == nil {
+		t.Fatal("expect error")
+	}
+}
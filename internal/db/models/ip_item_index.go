@@ -0,0 +1,167 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package models
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/remotelogs"
+	"github.com/dashenmiren/EdgeAPI/internal/utils/iprange"
+	"github.com/iwind/TeaGo/dbs"
+	"github.com/iwind/TeaGo/types"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+const (
+	ipItemIndexBatchSize      = 1000
+	ipItemIndexReloadInterval = 10 * time.Minute // 完整重新加载的间隔，用于修正多个API节点分配版本号时可能出现的乱序
+)
+
+var SharedIPItemIndex = NewIPItemIndex()
+
+// IPItemIndex API节点内存中的IP条目索引
+// 第一次查找时加载所有有效的条目，之后每次查找前根据条目版本号增量同步，和边缘节点通过 ListIPItemsAfterVersion 同步的方式相同
+type IPItemIndex struct {
+	index *iprange.Index
+
+	version      int64
+	isLoaded     bool
+	lastLoadedAt time.Time
+
+	locker sync.Mutex
+}
+
+func NewIPItemIndex() *IPItemIndex {
+	return &IPItemIndex{
+		index: iprange.NewIndex(),
+	}
+}
+
+// FindItemIdsContainsIP 查找包含某个IP的条目ID
+// 返回的条目可能已经过期，调用者需要自行检查
+func (this *IPItemIndex) FindItemIdsContainsIP(tx *dbs.Tx, ip string) ([]int64, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil, nil
+	}
+
+	err = this.Sync(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var itemIds = []int64{}
+	for _, itemId := range this.index.Lookup(addr) {
+		itemIds = append(itemIds, int64(itemId))
+	}
+	return itemIds, nil
+}
+
+// Sync 同步条目变化
+func (this *IPItemIndex) Sync(tx *dbs.Tx) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	if !this.isLoaded || time.Since(this.lastLoadedAt) > ipItemIndexReloadInterval {
+		err := this.load(tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		items, err := SharedIPItemDAO.ListIPItemsAfterVersion(tx, this.version, ipItemIndexBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			this.update(item)
+			this.version = int64(item.Version)
+		}
+		if len(items) < ipItemIndexBatchSize {
+			break
+		}
+	}
+	return nil
+}
+
+// Len 索引中的条目数量
+func (this *IPItemIndex) Len() int {
+	return this.index.Len()
+}
+
+// 加载所有有效的条目
+func (this *IPItemIndex) load(tx *dbs.Tx) error {
+	var before = time.Now()
+
+	// 先读取版本号，加载过程中发生的变化会在之后的增量同步中再次处理
+	version, err := SharedIPItemDAO.Query(tx).
+		Result("MAX(version)").
+		FindInt64Col(0)
+	if err != nil {
+		return err
+	}
+
+	var ranges = map[uint64]iprange.Range{}
+	var allIds = []uint64{}
+	var lastId int64
+	for {
+		var items []*IPItem
+		_, err = SharedIPItemDAO.Query(tx).
+			Result("id", "type", "ipFrom", "ipTo", "expiredAt").
+			State(IPItemStateEnabled).
+			Gt("id", lastId).
+			AscPk().
+			Limit(ipItemIndexBatchSize).
+			Slice(&items).
+			FindAll()
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			break
+		}
+		var now = time.Now().Unix()
+		for _, item := range items {
+			lastId = int64(item.Id)
+			if item.ExpiredAt > 0 && int64(item.ExpiredAt) <= now {
+				continue
+			}
+			if item.Type == IPItemTypeAll {
+				allIds = append(allIds, item.Id)
+				continue
+			}
+			r, err := iprange.ParseRange(item.IpFrom, item.IpTo)
+			if err != nil {
+				continue
+			}
+			ranges[item.Id] = r
+		}
+	}
+
+	this.index.Load(ranges, allIds)
+	this.version = version
+	this.isLoaded = true
+	this.lastLoadedAt = time.Now()
+
+	remotelogs.Println("IP_ITEM_INDEX", "loaded "+types.String(len(ranges)+len(allIds))+" items, cost: "+types.String(time.Since(before).Seconds()*1000)+"ms")
+	return nil
+}
+
+// 更新单个条目
+func (this *IPItemIndex) update(item *IPItem) {
+	if item.State != IPItemStateEnabled || (item.ExpiredAt > 0 && int64(item.ExpiredAt) <= time.Now().Unix()) {
+		this.index.Remove(item.Id)
+		return
+	}
+	if item.Type == IPItemTypeAll {
+		this.index.AddAll(item.Id)
+		return
+	}
+	r, err := iprange.ParseRange(item.IpFrom, item.IpTo)
+	if err != nil {
+		this.index.Remove(item.Id)
+		return
+	}
+	this.index.Add(item.Id, r)
+}
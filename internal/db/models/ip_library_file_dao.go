@@ -11,7 +11,6 @@ import (
 	"github.com/iwind/TeaGo/Tea"
 	"github.com/iwind/TeaGo/dbs"
 	"github.com/iwind/TeaGo/types"
-	"io"
 	"os"
 	"strings"
 	"time"
@@ -557,21 +556,10 @@ func (this *IPLibraryFileDAO) GenerateIPLibrary(tx *dbs.Tx, libraryFileId int64)
 	if err != nil {
 		return fmt.Errorf("open generated file failed: %w", err)
 	}
-	var buf = make([]byte, 256*1024)
-	for {
-		n, err := fp.Read(buf)
-		if n > 0 {
-			_, err = SharedFileChunkDAO.CreateFileChunk(tx, generatedFileId, buf[:n])
-			if err != nil {
-				return err
-			}
-		}
-		if err != nil {
-			if err != io.EOF {
-				return err
-			}
-			break
-		}
+	_, err = SharedFileChunkDAO.CreateFileChunksFromReader(tx, generatedFileId, fp, 256*1024)
+	_ = fp.Close()
+	if err != nil {
+		return err
 	}
 	err = SharedFileDAO.UpdateFileIsFinished(tx, generatedFileId)
 	if err != nil {
@@ -0,0 +1,496 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package models
+
+import (
+	"errors"
+	"github.com/dashenmiren/EdgeAPI/internal/configs"
+	"github.com/dashenmiren/EdgeAPI/internal/remotelogs"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/iwind/TeaGo/Tea"
+	"github.com/iwind/TeaGo/dbs"
+	"github.com/iwind/TeaGo/types"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	IPListFeedStateEnabled  = 1 // 已启用
+	IPListFeedStateDisabled = 0 // 已禁用
+)
+
+const (
+	IPListFeedDefaultInterval = 3600     // 默认同步间隔（秒）
+	IPListFeedMinInterval     = 300      // 最小同步间隔（秒）
+	ipListFeedMaxSize         = 64 << 20 // 订阅内容的最大尺寸
+	ipListFeedTimeout         = 60 * time.Second
+	ipListFeedDialTimeout     = 10 * time.Second
+)
+
+var errIPListFeedPrivateAddress = errors.New("access to loopback, link-local, private or other non-public address is not allowed")
+
+// 不能从公网访问的特殊用途地址段，参考 IANA IPv4/IPv6 Special-Purpose Address Registry
+// 环回、链路本地、组播、未指定地址和 RFC 1918/4193 内网地址由 isIPListFeedPrivateIP() 单独判断
+var ipListFeedReservedIPNets = func() []*net.IPNet {
+	var result = []*net.IPNet{}
+	for _, cidr := range []string{
+		"0.0.0.0/8",       // 本网络
+		"100.64.0.0/10",   // 运营商级NAT（CGNAT），很多云服务商VPC内部可以访问
+		"192.0.0.0/24",    // IETF协议分配
+		"192.0.2.0/24",    // 文档示例 TEST-NET-1
+		"192.88.99.0/24",  // 6to4中继
+		"198.18.0.0/15",   // 基准测试
+		"198.51.100.0/24", // 文档示例 TEST-NET-2
+		"203.0.113.0/24",  // 文档示例 TEST-NET-3
+		"240.0.0.0/4",     // 保留地址，包括广播地址
+		"64:ff9b::/96",    // NAT64，可以映射到任意IPv4地址
+		"64:ff9b:1::/48",  // 本地NAT64
+		"100::/64",        // 丢弃地址
+		"2001::/23",       // IETF协议分配，包括Teredo
+		"2001:db8::/32",   // 文档示例
+		"2002::/16",       // 6to4，可以映射到任意IPv4地址
+	} {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		result = append(result, ipNet)
+	}
+	return result
+}()
+
+// 订阅同步错误
+// Error() 只返回可以展示给用户的信息，不包含订阅内容和底层错误细节，底层错误只记录到日志中
+type ipListFeedError struct {
+	message string
+	err     error
+}
+
+func newIPListFeedError(message string, err error) *ipListFeedError {
+	return &ipListFeedError{
+		message: message,
+		err:     err,
+	}
+}
+
+func (this *ipListFeedError) Error() string {
+	return this.message
+}
+
+func (this *ipListFeedError) Unwrap() error {
+	return this.err
+}
+
+type IPListFeedDAO dbs.DAO
+
+func NewIPListFeedDAO() *IPListFeedDAO {
+	return dbs.NewDAO(&IPListFeedDAO{
+		DAOObject: dbs.DAOObject{
+			DB:     Tea.Env,
+			Table:  "edgeIPListFeeds",
+			Model:  new(IPListFeed),
+			PkName: "id",
+		},
+	}).(*IPListFeedDAO)
+}
+
+var SharedIPListFeedDAO *IPListFeedDAO
+
+func init() {
+	dbs.OnReady(func() {
+		SharedIPListFeedDAO = NewIPListFeedDAO()
+	})
+}
+
+// IPListFeedSyncResult 同步结果
+type IPListFeedSyncResult struct {
+	CountItems     int // 订阅中有效的条目数
+	CountAdded     int // 增加的条目数
+	CountRemoved   int // 删除的条目数
+	CountConflicts int // 格式错误或者重复的条目数
+}
+
+// CreateFeed 创建订阅
+func (this *IPListFeedDAO) CreateFeed(tx *dbs.Tx, listId int64, name string, url string, format string, interval int32, isOn bool) (int64, error) {
+	err := this.validateFeed(listId, url, format)
+	if err != nil {
+		return 0, err
+	}
+
+	var op = NewIPListFeedOperator()
+	op.ListId = listId
+	op.Name = name
+	op.Url = url
+	op.Format = format
+	op.Interval = this.normalizeInterval(interval)
+	op.IsOn = isOn
+	op.CreatedAt = time.Now().Unix()
+	op.State = IPListFeedStateEnabled
+	err = this.Save(tx, op)
+	if err != nil {
+		return 0, err
+	}
+	return types.Int64(op.Id), nil
+}
+
+// UpdateFeed 修改订阅
+// 修改后在下一次检查时立即同步
+func (this *IPListFeedDAO) UpdateFeed(tx *dbs.Tx, feedId int64, name string, url string, format string, interval int32, isOn bool) error {
+	if feedId <= 0 {
+		return errors.New("invalid feedId")
+	}
+	feed, err := this.FindEnabledFeed(tx, feedId)
+	if err != nil {
+		return err
+	}
+	if feed == nil {
+		return ErrNotFound
+	}
+	err = this.validateFeed(int64(feed.ListId), url, format)
+	if err != nil {
+		return err
+	}
+
+	var op = NewIPListFeedOperator()
+	op.Id = feedId
+	op.Name = name
+	op.Url = url
+	op.Format = format
+	op.Interval = this.normalizeInterval(interval)
+	op.IsOn = isOn
+	op.LastSyncAt = 0
+	return this.Save(tx, op)
+}
+
+// DisableFeed 删除订阅，同时删除从订阅中导入的条目
+func (this *IPListFeedDAO) DisableFeed(tx *dbs.Tx, feedId int64) error {
+	err := this.Query(tx).
+		Pk(feedId).
+		Set("state", IPListFeedStateDisabled).
+		UpdateQuickly()
+	if err != nil {
+		return err
+	}
+	return SharedIPItemDAO.DisableIPItemsWithFeedId(tx, feedId)
+}
+
+// FindEnabledFeed 查找订阅
+func (this *IPListFeedDAO) FindEnabledFeed(tx *dbs.Tx, feedId int64) (*IPListFeed, error) {
+	one, err := this.Query(tx).
+		Pk(feedId).
+		State(IPListFeedStateEnabled).
+		Find()
+	if one == nil {
+		return nil, err
+	}
+	return one.(*IPListFeed), err
+}
+
+// FindAllEnabledFeedsWithListId 查找名单的所有订阅
+func (this *IPListFeedDAO) FindAllEnabledFeedsWithListId(tx *dbs.Tx, listId int64) (result []*IPListFeed, err error) {
+	_, err = this.Query(tx).
+		Attr("listId", listId).
+		State(IPListFeedStateEnabled).
+		AscPk().
+		Slice(&result).
+		FindAll()
+	return
+}
+
+// FindAllDueFeedIds 查找需要同步的订阅
+func (this *IPListFeedDAO) FindAllDueFeedIds(tx *dbs.Tx) ([]int64, error) {
+	ones, err := this.Query(tx).
+		ResultPk().
+		State(IPListFeedStateEnabled).
+		Attr("isOn", true).
+		Where("lastSyncAt+`interval`<=:now").
+		Param("now", time.Now().Unix()).
+		AscPk().
+		FindAll()
+	if err != nil {
+		return nil, err
+	}
+	var feedIds = []int64{}
+	for _, one := range ones {
+		feedIds = append(feedIds, int64(one.(*IPListFeed).Id))
+	}
+	return feedIds, nil
+}
+
+// SyncFeed 同步订阅
+// 下载订阅内容后和从此订阅导入的条目比较，只增加新出现的条目、删除已经不存在的条目，每个变化都会增加版本号，
+// 以便节点通过 ListIPItemsAfterVersion 只同步变化的部分
+func (this *IPListFeedDAO) SyncFeed(tx *dbs.Tx, feedId int64) (*IPListFeedSyncResult, error) {
+	feed, err := this.FindEnabledFeed(tx, feedId)
+	if err != nil {
+		return nil, err
+	}
+	if feed == nil {
+		return nil, ErrNotFound
+	}
+
+	result, err := this.syncFeed(tx, feed)
+	if err != nil {
+		var logMessage = err.Error()
+		var feedErr *ipListFeedError
+		if errors.As(err, &feedErr) && feedErr.err != nil {
+			logMessage += ": " + feedErr.err.Error()
+		}
+		remotelogs.Error("IP_LIST_FEED", "sync feed '"+feed.Name+"' ("+types.String(feed.Id)+") failed: "+logMessage)
+		updateErr := this.updateFeedSyncResult(tx, feedId, nil, err.Error())
+		if updateErr != nil {
+			return nil, updateErr
+		}
+		return nil, err
+	}
+	err = this.updateFeedSyncResult(tx, feedId, result, "")
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (this *IPListFeedDAO) syncFeed(tx *dbs.Tx, feed *IPListFeed) (*IPListFeedSyncResult, error) {
+	data, err := this.fetchFeed(feed.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	items, conflicts, err := SharedIPItemDAO.ParseIPItems(feed.Format, data)
+	if err != nil {
+		return nil, newIPListFeedError("parse feed failed, please check the feed format", err)
+	}
+
+	// 防止订阅源异常时删除所有条目
+	if len(items) == 0 {
+		return nil, errors.New("no valid items found in feed")
+	}
+
+	countAdded, countRemoved, err := SharedIPItemDAO.SyncFeedItems(tx, int64(feed.ListId), int64(feed.Id), items, "订阅："+feed.Name)
+	if err != nil {
+		return nil, newIPListFeedError("save feed items failed", err)
+	}
+	return &IPListFeedSyncResult{
+		CountItems:     len(items),
+		CountAdded:     countAdded,
+		CountRemoved:   countRemoved,
+		CountConflicts: len(conflicts),
+	}, nil
+}
+
+// 读取订阅内容，支持 http(s):// 地址和订阅目录中的本地文件
+func (this *IPListFeedDAO) fetchFeed(feedURL string) ([]byte, error) {
+	var dir, allowPrivate = this.feedConfig()
+
+	var reader io.Reader
+	if strings.HasPrefix(feedURL, "http://") || strings.HasPrefix(feedURL, "https://") {
+		req, err := http.NewRequest(http.MethodGet, feedURL, nil)
+		if err != nil {
+			return nil, newIPListFeedError("invalid feed url", err)
+		}
+		req.Header.Set("User-Agent", "GoEdge-IPListFeed")
+		resp, err := this.httpClient(allowPrivate).Do(req)
+		if err != nil {
+			if errors.Is(err, errIPListFeedPrivateAddress) {
+				return nil, newIPListFeedError(errIPListFeedPrivateAddress.Error(), err)
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return nil, newIPListFeedError("request feed timeout", err)
+			}
+			return nil, newIPListFeedError("request feed failed", err)
+		}
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+		if resp.StatusCode != http.StatusOK {
+			return nil, errors.New("unexpected response status '" + strconv.Itoa(resp.StatusCode) + "'")
+		}
+		reader = resp.Body
+	} else {
+		path, err := this.feedFilePath(dir, feedURL)
+		if err != nil {
+			return nil, err
+		}
+
+		// 检查符号链接指向的真实路径
+		realDir, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			return nil, newIPListFeedError("feed directory is not available", err)
+		}
+		realPath, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, newIPListFeedError("feed file not found", err)
+			}
+			return nil, newIPListFeedError("read feed file failed", err)
+		}
+		_, err = this.feedFilePath(realDir, realPath)
+		if err != nil {
+			return nil, err
+		}
+
+		fp, err := os.Open(realPath)
+		if err != nil {
+			return nil, newIPListFeedError("read feed file failed", err)
+		}
+		defer func() {
+			_ = fp.Close()
+		}()
+		stat, err := fp.Stat()
+		if err != nil || !stat.Mode().IsRegular() {
+			return nil, newIPListFeedError("feed file should be a regular file", err)
+		}
+		reader = fp
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, ipListFeedMaxSize+1))
+	if err != nil {
+		return nil, newIPListFeedError("read feed failed", err)
+	}
+	if len(data) > ipListFeedMaxSize {
+		return nil, errors.New("feed is too large, max size: " + types.String(ipListFeedMaxSize>>20) + "MiB")
+	}
+	return data, nil
+}
+
+// 读取订阅配置
+func (this *IPListFeedDAO) feedConfig() (dir string, allowPrivate bool) {
+	dir = Tea.Root + "/data/ip-list-feeds"
+	apiConfig, err := configs.SharedAPIConfig()
+	if err == nil && apiConfig != nil && apiConfig.IPListFeed != nil {
+		if len(apiConfig.IPListFeed.Dir) > 0 {
+			dir = apiConfig.IPListFeed.Dir
+		}
+		allowPrivate = apiConfig.IPListFeed.AllowPrivate
+	}
+	absDir, err := filepath.Abs(dir)
+	if err == nil {
+		dir = absDir
+	}
+	return
+}
+
+// 访问订阅地址使用的客户端
+// 不使用环境变量中的代理，并在建立连接时检查实际连接的IP，以防止通过域名解析或者跳转访问内网地址
+func (this *IPListFeedDAO) httpClient(allowPrivate bool) *http.Client {
+	var dialer = &net.Dialer{
+		Timeout: ipListFeedDialTimeout,
+	}
+	if !allowPrivate {
+		dialer.Control = func(network string, address string, conn syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			var ip = net.ParseIP(host)
+			if ip == nil || isIPListFeedPrivateIP(ip) {
+				return errIPListFeedPrivateAddress
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout: ipListFeedTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: ipListFeedDialTimeout,
+		},
+	}
+}
+
+// 检查本地订阅文件是否在订阅目录中，返回清理后的路径
+func (this *IPListFeedDAO) feedFilePath(dir string, feedURL string) (string, error) {
+	var path = filepath.Clean(strings.TrimPrefix(feedURL, "file://"))
+	if !filepath.IsAbs(path) {
+		return "", errors.New("feed file path should be absolute")
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.New("feed file should be in directory '" + dir + "'")
+	}
+	return path, nil
+}
+
+// 判断是否为本机、链路本地、内网或者其他非公网地址
+// 只允许公网单播地址
+func isIPListFeedPrivateIP(ip net.IP) bool {
+	// IsGlobalUnicast() 排除了环回、链路本地、组播、未指定和广播地址
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return true
+	}
+	for _, ipNet := range ipListFeedReservedIPNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// 记录同步结果
+func (this *IPListFeedDAO) updateFeedSyncResult(tx *dbs.Tx, feedId int64, result *IPListFeedSyncResult, errString string) error {
+	var query = this.Query(tx).
+		Pk(feedId).
+		Set("lastSyncAt", time.Now().Unix()).
+		Set("lastError", errString)
+	if result != nil {
+		query.Set("lastCountItems", result.CountItems).
+			Set("lastCountAdded", result.CountAdded).
+			Set("lastCountRemoved", result.CountRemoved)
+	}
+	return query.UpdateQuickly()
+}
+
+func (this *IPListFeedDAO) validateFeed(listId int64, feedURL string, format string) error {
+	if listId <= 0 {
+		return errors.New("invalid listId")
+	}
+	if len(feedURL) == 0 {
+		return errors.New("'url' should not be empty")
+	}
+
+	var dir, allowPrivate = this.feedConfig()
+	if strings.HasPrefix(feedURL, "http://") || strings.HasPrefix(feedURL, "https://") {
+		u, err := url.Parse(feedURL)
+		if err != nil || len(u.Hostname()) == 0 {
+			return errors.New("invalid 'url'")
+		}
+
+		// 这里只检查直接使用IP的地址，域名在连接时检查
+		if !allowPrivate {
+			var ip = net.ParseIP(u.Hostname())
+			if strings.EqualFold(u.Hostname(), "localhost") || (ip != nil && isIPListFeedPrivateIP(ip)) {
+				return errIPListFeedPrivateAddress
+			}
+		}
+	} else if strings.HasPrefix(feedURL, "file://") || strings.HasPrefix(feedURL, "/") {
+		_, err := this.feedFilePath(dir, feedURL)
+		if err != nil {
+			return err
+		}
+	} else {
+		return errors.New("'url' should be a http(s) url or an absolute file path")
+	}
+	if !IsValidIPItemFormat(format) {
+		return errors.New("invalid format '" + format + "'")
+	}
+	return nil
+}
+
+func (this *IPListFeedDAO) normalizeInterval(interval int32) int32 {
+	if interval <= 0 {
+		return IPListFeedDefaultInterval
+	}
+	if interval < IPListFeedMinInterval {
+		return IPListFeedMinInterval
+	}
+	return interval
+}
@@ -0,0 +1,127 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package models
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIPListFeedDAO_FeedFilePath(t *testing.T) {
+	var dao = NewIPListFeedDAO()
+	var dir = "/var/lib/edge/ip-list-feeds"
+	for url, ok := range map[string]bool{
+		"/var/lib/edge/ip-list-feeds/drop.txt":               true,
+		"file:///var/lib/edge/ip-list-feeds/sub/drop.txt":    true,
+		"/var/lib/edge/ip-list-feeds":                        false,
+		"/var/lib/edge/ip-list-feeds/../../../../etc/passwd": false,
+		"file:///etc/passwd":                                 false,
+		"/var/lib/edge/ip-list-feeds-other/drop.txt":         false,
+		"file://var/lib/edge/ip-list-feeds/drop.txt":         false,
+	} {
+		_, err := dao.feedFilePath(dir, url)
+		if (err == nil) != ok {
+			t.Fatal(url, "expect", ok, "but got error:", err)
+		}
+	}
+}
+
+func TestIPListFeedDAO_FetchFeed_Private(t *testing.T) {
+	var server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		_, _ = writer.Write([]byte("192.168.1.1\n"))
+	}))
+	defer server.Close()
+
+	var dao = NewIPListFeedDAO()
+	_, err := dao.fetchFeed(server.URL)
+	if !errors.Is(err, errIPListFeedPrivateAddress) {
+		t.Fatal("expect private address error, got:", err)
+	}
+
+	resp, err := dao.httpClient(true).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	err = dao.validateFeed(1, server.URL, IPItemFormatText)
+	if !errors.Is(err, errIPListFeedPrivateAddress) {
+		t.Fatal("expect private address error, got:", err)
+	}
+
+	// 其他非公网地址，在连接之前就会被拒绝
+	for _, feedURL := range []string{
+		"http://100.64.0.1/feed.txt", // CGNAT
+		"http://100.127.255.254/feed.txt",
+		"http://192.0.0.8/feed.txt",  // IETF协议分配
+		"http://198.18.0.1/feed.txt", // 基准测试
+		"http://198.19.255.1/feed.txt",
+		"http://[fd00::1]/feed.txt",
+		"http://[64:ff9b::a00:1]/feed.txt", // NAT64映射的10.0.0.1
+	} {
+		err = dao.validateFeed(1, feedURL, IPItemFormatText)
+		if !errors.Is(err, errIPListFeedPrivateAddress) {
+			t.Fatal(feedURL, "expect private address error, got:", err)
+		}
+		_, err = dao.fetchFeed(feedURL)
+		if !errors.Is(err, errIPListFeedPrivateAddress) {
+			t.Fatal(feedURL, "expect private address error, got:", err)
+		}
+	}
+}
+
+func TestIPListFeedError(t *testing.T) {
+	// 错误信息中不能包含订阅内容
+	var dao = NewIPItemDAO()
+	_, _, parseErr := dao.ParseIPItems(IPItemFormatJSON, []byte("root:x:0:0"))
+	if parseErr == nil {
+		t.Fatal("expect parse error")
+	}
+	var feedErr = newIPListFeedError("parse feed failed, please check the feed format", parseErr)
+	if strings.Contains(feedErr.Error(), "root") {
+		t.Fatal("error should not contain feed content:", feedErr.Error())
+	}
+	if !errors.Is(feedErr, parseErr) {
+		t.Fatal("should unwrap to the original error")
+	}
+}
+
+func TestIsIPListFeedPrivateIP(t *testing.T) {
+	for ip, isPrivate := range map[string]bool{
+		"127.0.0.1":            true,
+		"::1":                  true,
+		"10.1.2.3":             true,
+		"172.16.0.1":           true,
+		"192.168.1.1":          true,
+		"169.254.169.254":      true,
+		"fe80::1":              true,
+		"fd00::1":              true,
+		"0.0.0.0":              true,
+		"::ffff:127.0.0.1":     true,
+		"100.64.0.1":           true,
+		"100.127.255.254":      true,
+		"192.0.0.8":            true,
+		"198.18.0.1":           true,
+		"198.19.255.255":       true,
+		"203.0.113.10":         true,
+		"240.0.0.1":            true,
+		"255.255.255.255":      true,
+		"224.0.0.1":            true,
+		"64:ff9b::a00:1":       true,
+		"2001:db8::1":          true,
+		"2002:a00:1::1":        true,
+		"100.63.255.255":       false,
+		"100.128.0.1":          false,
+		"198.20.0.1":           false,
+		"8.8.8.8":              false,
+		"2001:4860:4860::8888": false,
+	} {
+		if isIPListFeedPrivateIP(net.ParseIP(ip)) != isPrivate {
+			t.Fatal(ip, "expect", isPrivate)
+		}
+	}
+}
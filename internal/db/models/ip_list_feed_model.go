@@ -0,0 +1,42 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package models
+
+// IPListFeed IP名单订阅
+type IPListFeed struct {
+	Id               uint64 `field:"id"`               // ID
+	ListId           uint32 `field:"listId"`           // 名单ID
+	Name             string `field:"name"`             // 名称
+	Url              string `field:"url"`              // 订阅地址或本地文件路径
+	Format           string `field:"format"`           // 格式
+	Interval         uint32 `field:"interval"`         // 同步间隔（秒）
+	IsOn             bool   `field:"isOn"`             // 是否启用
+	LastSyncAt       uint64 `field:"lastSyncAt"`       // 上次同步时间
+	LastError        string `field:"lastError"`        // 上次同步错误
+	LastCountItems   uint32 `field:"lastCountItems"`   // 上次同步后的条目数
+	LastCountAdded   uint32 `field:"lastCountAdded"`   // 上次同步增加的条目数
+	LastCountRemoved uint32 `field:"lastCountRemoved"` // 上次同步删除的条目数
+	CreatedAt        uint64 `field:"createdAt"`        // 创建时间
+	State            uint8  `field:"state"`            // 状态
+}
+
+type IPListFeedOperator struct {
+	Id               any // ID
+	ListId           any // 名单ID
+	Name             any // 名称
+	Url              any // 订阅地址或本地文件路径
+	Format           any // 格式
+	Interval         any // 同步间隔（秒）
+	IsOn             any // 是否启用
+	LastSyncAt       any // 上次同步时间
+	LastError        any // 上次同步错误
+	LastCountItems   any // 上次同步后的条目数
+	LastCountAdded   any // 上次同步增加的条目数
+	LastCountRemoved any // 上次同步删除的条目数
+	CreatedAt        any // 创建时间
+	State            any // 状态
+}
+
+func NewIPListFeedOperator() *IPListFeedOperator {
+	return &IPListFeedOperator{}
+}
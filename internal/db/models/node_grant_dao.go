@@ -1,12 +1,18 @@
 package models
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	dbutils "github.com/dashenmiren/EdgeAPI/internal/db/utils"
+	"github.com/dashenmiren/EdgeAPI/internal/remotelogs"
+	"github.com/dashenmiren/EdgeAPI/internal/secrets"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/iwind/TeaGo/Tea"
 	"github.com/iwind/TeaGo/dbs"
 	"github.com/iwind/TeaGo/types"
+	"sync"
+	"time"
 )
 
 const (
@@ -14,8 +20,17 @@ const (
 	NodeGrantStateDisabled = 0 // 已禁用
 )
 
+// 加密字段的用途
+const (
+	nodeGrantPasswordSecretContext   = "nodeGrant.password"
+	nodeGrantPrivateKeySecretContext = "nodeGrant.privateKey"
+	nodeGrantPassphraseSecretContext = "nodeGrant.passphrase"
+)
+
 type NodeGrantDAO dbs.DAO
 
+var nodeGrantHostKeysLocker = &sync.Mutex{}
+
 func NewNodeGrantDAO() *NodeGrantDAO {
 	return dbs.NewDAO(&NodeGrantDAO{
 		DAOObject: dbs.DAOObject{
@@ -58,10 +73,15 @@ func (this *NodeGrantDAO) FindEnabledNodeGrant(tx *dbs.Tx, id int64) (*NodeGrant
 		Pk(id).
 		Attr("state", NodeGrantStateEnabled).
 		Find()
-	if result == nil {
+	if err != nil || result == nil {
 		return nil, err
 	}
-	return result.(*NodeGrant), err
+	var grant = result.(*NodeGrant)
+	err = this.decodeSecrets(grant)
+	if err != nil {
+		return nil, err
+	}
+	return grant, nil
 }
 
 // FindNodeGrantName 根据主键查找名称
@@ -83,11 +103,20 @@ func (this *NodeGrantDAO) CreateGrant(tx *dbs.Tx, adminId int64, name string, me
 	switch method {
 	case "user":
 		op.Username = username
-		op.Password = password
+		op.Password, err = secrets.EncryptString(nodeGrantPasswordSecretContext, password)
+		if err != nil {
+			return 0, err
+		}
 	case "privateKey":
 		op.Username = username
-		op.PrivateKey = privateKey
-		op.Passphrase = passphrase
+		op.PrivateKey, err = secrets.EncryptString(nodeGrantPrivateKeySecretContext, privateKey)
+		if err != nil {
+			return 0, err
+		}
+		op.Passphrase, err = secrets.EncryptString(nodeGrantPassphraseSecretContext, passphrase)
+		if err != nil {
+			return 0, err
+		}
 	}
 	if username != "root" { // only for non-root user
 		op.Su = su
@@ -110,14 +139,24 @@ func (this *NodeGrantDAO) UpdateGrant(tx *dbs.Tx, grantId int64, name string, me
 	op.Name = name
 	op.Method = method
 
+	var err error
 	switch method {
 	case "user":
 		op.Username = username
-		op.Password = password
+		op.Password, err = secrets.EncryptString(nodeGrantPasswordSecretContext, password)
+		if err != nil {
+			return err
+		}
 	case "privateKey":
 		op.Username = username
-		op.PrivateKey = privateKey
-		op.Passphrase = passphrase
+		op.PrivateKey, err = secrets.EncryptString(nodeGrantPrivateKeySecretContext, privateKey)
+		if err != nil {
+			return err
+		}
+		op.Passphrase, err = secrets.EncryptString(nodeGrantPassphraseSecretContext, passphrase)
+		if err != nil {
+			return err
+		}
 	}
 	if username != "root" { // only for non-root user
 		op.Su = su
@@ -126,7 +165,7 @@ func (this *NodeGrantDAO) UpdateGrant(tx *dbs.Tx, grantId int64, name string, me
 	}
 	op.Description = description
 	op.NodeId = nodeId
-	err := this.Save(tx, op)
+	err = this.Save(tx, op)
 	return err
 }
 
@@ -155,6 +194,10 @@ func (this *NodeGrantDAO) ListEnabledGrants(tx *dbs.Tx, keyword string, offset i
 		DescPk().
 		Slice(&result).
 		FindAll()
+	if err != nil {
+		return nil, err
+	}
+	this.decodeListSecrets(result)
 	return
 }
 
@@ -165,5 +208,233 @@ func (this *NodeGrantDAO) FindAllEnabledGrants(tx *dbs.Tx) (result []*NodeGrant,
 		DescPk().
 		Slice(&result).
 		FindAll()
+	if err != nil {
+		return nil, err
+	}
+	this.decodeListSecrets(result)
 	return
 }
+
+// UpdateGrantSSHOptions 修改SSH主机公钥校验策略和跳板机
+func (this *NodeGrantDAO) UpdateGrantSSHOptions(tx *dbs.Tx, grantId int64, hostKeyPolicy string, jumpHosts []*NodeGrantJumpHost) error {
+	if grantId <= 0 {
+		return errors.New("invalid grantId")
+	}
+	if !IsValidNodeGrantHostKeyPolicy(hostKeyPolicy) {
+		return errors.New("invalid host key policy '" + hostKeyPolicy + "'")
+	}
+
+	if jumpHosts == nil {
+		jumpHosts = []*NodeGrantJumpHost{}
+	}
+	if len(jumpHosts) > NodeGrantMaxJumpHosts {
+		return errors.New("too many jump hosts, max: " + types.String(NodeGrantMaxJumpHosts))
+	}
+	for index, jumpHost := range jumpHosts {
+		if len(jumpHost.Host) == 0 {
+			return fmt.Errorf("jump host #%d: 'host' should not be empty", index+1)
+		}
+		if jumpHost.Port <= 0 || jumpHost.Port > 65535 {
+			return fmt.Errorf("jump host #%d: invalid port '%d'", index+1, jumpHost.Port)
+		}
+		if jumpHost.GrantId <= 0 || jumpHost.GrantId == grantId {
+			return fmt.Errorf("jump host #%d: invalid grantId '%d'", index+1, jumpHost.GrantId)
+		}
+	}
+	jumpHostsJSON, err := json.Marshal(jumpHosts)
+	if err != nil {
+		return err
+	}
+
+	var op = NewNodeGrantOperator()
+	op.Id = grantId
+	op.HostKeyPolicy = hostKeyPolicy
+	op.JumpHosts = jumpHostsJSON
+	return this.Save(tx, op)
+}
+
+// VerifyHostKey 校验主机公钥
+// host 格式为 host:port；首次连接的主机根据授权的策略自动信任或者记录为待确认
+func (this *NodeGrantDAO) VerifyHostKey(tx *dbs.Tx, grantId int64, host string, keyType string, fingerprint string) error {
+	nodeGrantHostKeysLocker.Lock()
+	defer nodeGrantHostKeysLocker.Unlock()
+
+	grant, err := this.FindEnabledNodeGrant(tx, grantId)
+	if err != nil {
+		return err
+	}
+	if grant == nil {
+		return errors.New("can not find grant with id '" + types.String(grantId) + "'")
+	}
+	hostKeys, err := grant.DecodeHostKeys()
+	if err != nil {
+		return fmt.Errorf("decode host keys failed: %w", err)
+	}
+
+	newHostKeys, verifyErr := verifyNodeGrantHostKey(hostKeys, grant.FindHostKeyPolicy(), host, keyType, fingerprint)
+	if newHostKeys != nil {
+		err = this.updateHostKeys(tx, grantId, newHostKeys)
+		if err != nil {
+			return err
+		}
+	}
+	if verifyErr != nil {
+		return fmt.Errorf("%w: %s %s %s", verifyErr, host, keyType, fingerprint)
+	}
+	return nil
+}
+
+// ApproveHostKey 信任主机公钥
+// 同一个主机的其他公钥会被删除
+func (this *NodeGrantDAO) ApproveHostKey(tx *dbs.Tx, grantId int64, host string, fingerprint string) error {
+	if len(host) == 0 || len(fingerprint) == 0 {
+		return errors.New("'host' and 'fingerprint' should not be empty")
+	}
+
+	nodeGrantHostKeysLocker.Lock()
+	defer nodeGrantHostKeysLocker.Unlock()
+
+	grant, err := this.FindEnabledNodeGrant(tx, grantId)
+	if err != nil {
+		return err
+	}
+	if grant == nil {
+		return nil
+	}
+	hostKeys, err := grant.DecodeHostKeys()
+	if err != nil {
+		return fmt.Errorf("decode host keys failed: %w", err)
+	}
+
+	var approvedHostKey = &NodeGrantHostKey{
+		Host:        host,
+		Fingerprint: fingerprint,
+		CreatedAt:   time.Now().Unix(),
+	}
+	var newHostKeys = []*NodeGrantHostKey{}
+	for _, hostKey := range hostKeys {
+		if hostKey.Host != host {
+			newHostKeys = append(newHostKeys, hostKey)
+			continue
+		}
+		if hostKey.Fingerprint == fingerprint {
+			approvedHostKey = hostKey
+		}
+	}
+	approvedHostKey.State = NodeGrantHostKeyStateApproved
+	newHostKeys = append(newHostKeys, approvedHostKey)
+	return this.updateHostKeys(tx, grantId, newHostKeys)
+}
+
+// DeleteHostKeys 删除某个主机的所有公钥
+func (this *NodeGrantDAO) DeleteHostKeys(tx *dbs.Tx, grantId int64, host string) error {
+	nodeGrantHostKeysLocker.Lock()
+	defer nodeGrantHostKeysLocker.Unlock()
+
+	grant, err := this.FindEnabledNodeGrant(tx, grantId)
+	if err != nil {
+		return err
+	}
+	if grant == nil {
+		return nil
+	}
+	hostKeys, err := grant.DecodeHostKeys()
+	if err != nil {
+		return fmt.Errorf("decode host keys failed: %w", err)
+	}
+
+	var newHostKeys = []*NodeGrantHostKey{}
+	for _, hostKey := range hostKeys {
+		if hostKey.Host != host {
+			newHostKeys = append(newHostKeys, hostKey)
+		}
+	}
+	return this.updateHostKeys(tx, grantId, newHostKeys)
+}
+
+// 修改主机公钥
+func (this *NodeGrantDAO) updateHostKeys(tx *dbs.Tx, grantId int64, hostKeys []*NodeGrantHostKey) error {
+	hostKeysJSON, err := json.Marshal(hostKeys)
+	if err != nil {
+		return err
+	}
+	return this.Query(tx).
+		Pk(grantId).
+		Set("hostKeys", hostKeysJSON).
+		UpdateQuickly()
+}
+
+// ResealSecrets 使用当前主密钥加密所有认证信息中的敏感字段
+// rotate 为 true 时同时将使用旧主密钥加密的数据密钥重新加密
+func (this *NodeGrantDAO) ResealSecrets(tx *dbs.Tx, keyring *secrets.Keyring, rotate bool) (count int64, err error) {
+	ones, err := this.Query(tx).
+		Result("id", "password", "privateKey", "passphrase").
+		AscPk().
+		FindAll()
+	if err != nil {
+		return 0, err
+	}
+	for _, one := range ones {
+		var grant = one.(*NodeGrant)
+		var isChanged = false
+		var query = this.Query(tx).Pk(grant.Id)
+		for _, field := range []struct {
+			name    string
+			context string
+			value   string
+		}{
+			{"password", nodeGrantPasswordSecretContext, grant.Password},
+			{"privateKey", nodeGrantPrivateKeySecretContext, grant.PrivateKey},
+			{"passphrase", nodeGrantPassphraseSecretContext, grant.Passphrase},
+		} {
+			newValue, changed, err := keyring.Reseal(field.context, field.value, rotate)
+			if err != nil {
+				return count, errors.New("grant '" + types.String(grant.Id) + "': " + field.name + ": " + err.Error())
+			}
+			if changed {
+				query.Set(field.name, newValue)
+				isChanged = true
+			}
+		}
+		if isChanged {
+			err = query.UpdateQuickly()
+			if err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+	return
+}
+
+// 解密列表中的敏感字段
+// 单条认证信息解密失败时只清空其敏感字段并记录日志，不影响列表中的其他认证信息
+func (this *NodeGrantDAO) decodeListSecrets(grants []*NodeGrant) {
+	for _, grant := range grants {
+		err := this.decodeSecrets(grant)
+		if err != nil {
+			remotelogs.Error("NODE_GRANT_DAO", err.Error())
+			grant.Password = ""
+			grant.PrivateKey = ""
+			grant.Passphrase = ""
+		}
+	}
+}
+
+// 解密认证信息中的敏感字段
+func (this *NodeGrantDAO) decodeSecrets(grant *NodeGrant) error {
+	var err error
+	grant.Password, err = secrets.DecryptString(nodeGrantPasswordSecretContext, grant.Password)
+	if err != nil {
+		return errors.New("decrypt grant '" + types.String(grant.Id) + "' failed: " + err.Error())
+	}
+	grant.PrivateKey, err = secrets.DecryptString(nodeGrantPrivateKeySecretContext, grant.PrivateKey)
+	if err != nil {
+		return errors.New("decrypt grant '" + types.String(grant.Id) + "' failed: " + err.Error())
+	}
+	grant.Passphrase, err = secrets.DecryptString(nodeGrantPassphraseSecretContext, grant.Passphrase)
+	if err != nil {
+		return errors.New("decrypt grant '" + types.String(grant.Id) + "' failed: " + err.Error())
+	}
+	return nil
+}
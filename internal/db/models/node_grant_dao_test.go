@@ -1,5 +1,58 @@
 package models
 
 import (
+	"errors"
 	_ "github.com/go-sql-driver/mysql"
+	"testing"
 )
+
+func TestVerifyNodeGrantHostKey(t *testing.T) {
+	// 首次连接自动信任
+	hostKeys, err := verifyNodeGrantHostKey(nil, NodeGrantHostKeyPolicyTOFU, "192.168.1.100:22", "ssh-ed25519", "SHA256:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hostKeys) != 1 || hostKeys[0].State != NodeGrantHostKeyStateApproved {
+		t.Fatal("expect approved host key")
+	}
+
+	// 相同的公钥
+	newHostKeys, err := verifyNodeGrantHostKey(hostKeys, NodeGrantHostKeyPolicyStrict, "192.168.1.100:22", "ssh-ed25519", "SHA256:a")
+	if err != nil || newHostKeys != nil {
+		t.Fatal("expect verified without changes")
+	}
+
+	// 公钥已变化
+	newHostKeys, err = verifyNodeGrantHostKey(hostKeys, NodeGrantHostKeyPolicyTOFU, "192.168.1.100:22", "ssh-ed25519", "SHA256:b")
+	if !errors.Is(err, ErrNodeGrantHostKeyMismatch) {
+		t.Fatal("expect mismatch error, got", err)
+	}
+	if len(newHostKeys) != 2 || newHostKeys[1].State != NodeGrantHostKeyStatePending {
+		t.Fatal("expect pending host key")
+	}
+	hostKeys = newHostKeys
+
+	// 重复的待确认公钥不需要修改
+	newHostKeys, err = verifyNodeGrantHostKey(hostKeys, NodeGrantHostKeyPolicyTOFU, "192.168.1.100:22", "ssh-ed25519", "SHA256:b")
+	if !errors.Is(err, ErrNodeGrantHostKeyMismatch) || newHostKeys != nil {
+		t.Fatal("expect mismatch error without changes")
+	}
+
+	// 只保留最后一个待确认公钥
+	newHostKeys, err = verifyNodeGrantHostKey(hostKeys, NodeGrantHostKeyPolicyTOFU, "192.168.1.100:22", "ssh-ed25519", "SHA256:c")
+	if !errors.Is(err, ErrNodeGrantHostKeyMismatch) {
+		t.Fatal("expect mismatch error, got", err)
+	}
+	if len(newHostKeys) != 2 || newHostKeys[1].Fingerprint != "SHA256:c" {
+		t.Fatal("expect replaced pending host key")
+	}
+
+	// 严格模式下新主机需要确认
+	newHostKeys, err = verifyNodeGrantHostKey(hostKeys, NodeGrantHostKeyPolicyStrict, "192.168.1.101:22", "ssh-ed25519", "SHA256:d")
+	if !errors.Is(err, ErrNodeGrantHostKeyNotApproved) {
+		t.Fatal("expect not approved error, got", err)
+	}
+	if len(newHostKeys) != 3 || newHostKeys[2].State != NodeGrantHostKeyStatePending {
+		t.Fatal("expect pending host key")
+	}
+}
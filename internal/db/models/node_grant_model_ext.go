@@ -1 +1,127 @@
 package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	NodeGrantHostKeyPolicyTOFU   = "tofu"   // 首次连接时自动信任
+	NodeGrantHostKeyPolicyStrict = "strict" // 需要管理员确认后才信任
+
+	NodeGrantHostKeyStateApproved = "approved" // 已信任
+	NodeGrantHostKeyStatePending  = "pending"  // 等待确认
+
+	NodeGrantMaxJumpHosts = 8 // 最多跳板机数量
+)
+
+var ErrNodeGrantHostKeyMismatch = errors.New("ssh host key mismatch")
+var ErrNodeGrantHostKeyNotApproved = errors.New("ssh host key has not been approved")
+
+// NodeGrantHostKey 主机公钥
+type NodeGrantHostKey struct {
+	Host        string `json:"host"`        // 主机地址，格式为 host:port
+	KeyType     string `json:"keyType"`     // 公钥类型
+	Fingerprint string `json:"fingerprint"` // SHA256指纹
+	State       string `json:"state"`       // 状态
+	CreatedAt   int64  `json:"createdAt"`   // 创建时间
+}
+
+// NodeGrantJumpHost 跳板机
+type NodeGrantJumpHost struct {
+	Host    string `json:"host"`    // 主机地址
+	Port    int    `json:"port"`    // 端口
+	GrantId int64  `json:"grantId"` // 登录跳板机使用的认证
+}
+
+// IsValidNodeGrantHostKeyPolicy 检查主机公钥校验策略是否正确
+func IsValidNodeGrantHostKeyPolicy(policy string) bool {
+	return policy == NodeGrantHostKeyPolicyTOFU || policy == NodeGrantHostKeyPolicyStrict
+}
+
+// FindHostKeyPolicy 获取主机公钥校验策略
+func (this *NodeGrant) FindHostKeyPolicy() string {
+	if len(this.HostKeyPolicy) == 0 {
+		return NodeGrantHostKeyPolicyTOFU
+	}
+	return this.HostKeyPolicy
+}
+
+// DecodeHostKeys 解析主机公钥
+func (this *NodeGrant) DecodeHostKeys() ([]*NodeGrantHostKey, error) {
+	var hostKeys = []*NodeGrantHostKey{}
+	if !IsNotNull(this.HostKeys) {
+		return hostKeys, nil
+	}
+	err := json.Unmarshal(this.HostKeys, &hostKeys)
+	if err != nil {
+		return nil, err
+	}
+	return hostKeys, nil
+}
+
+// DecodeJumpHosts 解析跳板机
+func (this *NodeGrant) DecodeJumpHosts() ([]*NodeGrantJumpHost, error) {
+	var jumpHosts = []*NodeGrantJumpHost{}
+	if !IsNotNull(this.JumpHosts) {
+		return jumpHosts, nil
+	}
+	err := json.Unmarshal(this.JumpHosts, &jumpHosts)
+	if err != nil {
+		return nil, err
+	}
+	return jumpHosts, nil
+}
+
+// 校验主机公钥
+// 返回新的公钥列表，如果公钥列表不需要修改，则返回 nil
+func verifyNodeGrantHostKey(hostKeys []*NodeGrantHostKey, policy string, host string, keyType string, fingerprint string) (newHostKeys []*NodeGrantHostKey, err error) {
+	var hasApproved = false
+	for _, hostKey := range hostKeys {
+		if hostKey.Host != host || hostKey.State != NodeGrantHostKeyStateApproved {
+			continue
+		}
+		if hostKey.Fingerprint == fingerprint {
+			return nil, nil
+		}
+		hasApproved = true
+	}
+
+	// 首次连接时自动信任
+	if !hasApproved && policy != NodeGrantHostKeyPolicyStrict {
+		newHostKeys = append(newHostKeys, hostKeys...)
+		newHostKeys = append(newHostKeys, &NodeGrantHostKey{
+			Host:        host,
+			KeyType:     keyType,
+			Fingerprint: fingerprint,
+			State:       NodeGrantHostKeyStateApproved,
+			CreatedAt:   time.Now().Unix(),
+		})
+		return newHostKeys, nil
+	}
+
+	err = ErrNodeGrantHostKeyNotApproved
+	if hasApproved {
+		err = ErrNodeGrantHostKeyMismatch
+	}
+
+	// 记录为待确认，每个主机只保留最后一个待确认的公钥
+	for _, hostKey := range hostKeys {
+		if hostKey.Host == host && hostKey.State == NodeGrantHostKeyStatePending {
+			if hostKey.Fingerprint == fingerprint {
+				return nil, err
+			}
+			continue
+		}
+		newHostKeys = append(newHostKeys, hostKey)
+	}
+	newHostKeys = append(newHostKeys, &NodeGrantHostKey{
+		Host:        host,
+		KeyType:     keyType,
+		Fingerprint: fingerprint,
+		State:       NodeGrantHostKeyStatePending,
+		CreatedAt:   time.Now().Unix(),
+	})
+	return newHostKeys, err
+}
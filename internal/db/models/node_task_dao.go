@@ -35,6 +35,7 @@ const (
 	NodeTaskTypeUpdatingServers              NodeTaskType = "updatingServers"              // 更新一组服务
 	NodeTaskTypeTOAChanged                   NodeTaskType = "toaChanged"                   // TOA配置变化
 	NodeTaskTypePlanChanged                  NodeTaskType = "planChanged"                  // 套餐变化
+	NodeTaskTypeACMETLSALPNChanged           NodeTaskType = "acmeTLSALPNChanged"           // ACME TLS-ALPN认证证书变化
 
 	// NS相关
 
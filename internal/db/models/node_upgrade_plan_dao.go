@@ -0,0 +1,186 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package models
+
+import (
+	"encoding/json"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/iwind/TeaGo/Tea"
+	"github.com/iwind/TeaGo/dbs"
+	"github.com/iwind/TeaGo/types"
+	"time"
+)
+
+const (
+	NodeUpgradePlanStateEnabled  = 1 // 已启用
+	NodeUpgradePlanStateDisabled = 0 // 已禁用
+)
+
+type NodeUpgradePlanDAO dbs.DAO
+
+func NewNodeUpgradePlanDAO() *NodeUpgradePlanDAO {
+	return dbs.NewDAO(&NodeUpgradePlanDAO{
+		DAOObject: dbs.DAOObject{
+			DB:     Tea.Env,
+			Table:  "edgeNodeUpgradePlans",
+			Model:  new(NodeUpgradePlan),
+			PkName: "id",
+		},
+	}).(*NodeUpgradePlanDAO)
+}
+
+var SharedNodeUpgradePlanDAO *NodeUpgradePlanDAO
+
+func init() {
+	dbs.OnReady(func() {
+		SharedNodeUpgradePlanDAO = NewNodeUpgradePlanDAO()
+	})
+}
+
+// FindEnabledPlan 查找升级计划
+func (this *NodeUpgradePlanDAO) FindEnabledPlan(tx *dbs.Tx, planId int64) (*NodeUpgradePlan, error) {
+	result, err := this.Query(tx).
+		Pk(planId).
+		State(NodeUpgradePlanStateEnabled).
+		Find()
+	if result == nil {
+		return nil, err
+	}
+	return result.(*NodeUpgradePlan), err
+}
+
+// CreatePlan 创建升级计划，创建后立即开始执行
+func (this *NodeUpgradePlanDAO) CreatePlan(tx *dbs.Tx, adminId int64, clusterId int64, canaryPercent int32, batchSize int32, waitSeconds int32, gates *NodeUpgradePlanGates, nodes []*NodeUpgradePlanNode) (int64, error) {
+	if clusterId <= 0 {
+		return 0, errors.New("invalid clusterId")
+	}
+	if len(nodes) == 0 {
+		return 0, errors.New("no nodes to upgrade")
+	}
+	if canaryPercent < 0 || canaryPercent > 100 {
+		return 0, errors.New("'canaryPercent' should be between 0 and 100")
+	}
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	if waitSeconds < 0 {
+		waitSeconds = 0
+	}
+	if gates == nil {
+		gates = &NodeUpgradePlanGates{}
+	}
+
+	// 每个集群同时只能有一个进行中的计划
+	activePlan, err := this.FindActivePlanWithClusterId(tx, clusterId)
+	if err != nil {
+		return 0, err
+	}
+	if activePlan != nil {
+		return 0, errors.New("there is an active upgrade plan '" + types.String(activePlan.Id) + "' in the cluster")
+	}
+
+	AssignNodeUpgradeBatches(nodes, int(canaryPercent), int(batchSize))
+	for _, node := range nodes {
+		node.Status = NodeUpgradePlanNodeStatusPending
+	}
+
+	gatesJSON, err := json.Marshal(gates)
+	if err != nil {
+		return 0, err
+	}
+	nodesJSON, err := json.Marshal(nodes)
+	if err != nil {
+		return 0, err
+	}
+
+	var op = NewNodeUpgradePlanOperator()
+	op.AdminId = adminId
+	op.ClusterId = clusterId
+	op.CanaryPercent = canaryPercent
+	op.BatchSize = batchSize
+	op.WaitSeconds = waitSeconds
+	op.Gates = gatesJSON
+	op.Nodes = nodesJSON
+	op.Status = NodeUpgradePlanStatusRunning
+	op.UpdatedAt = time.Now().Unix()
+	op.State = NodeUpgradePlanStateEnabled
+	return this.SaveInt64(tx, op)
+}
+
+// FindActivePlanWithClusterId 查找集群中正在进行的升级计划
+func (this *NodeUpgradePlanDAO) FindActivePlanWithClusterId(tx *dbs.Tx, clusterId int64) (*NodeUpgradePlan, error) {
+	result, err := this.Query(tx).
+		State(NodeUpgradePlanStateEnabled).
+		Attr("clusterId", clusterId).
+		Attr("status", []string{NodeUpgradePlanStatusRunning, NodeUpgradePlanStatusPaused}).
+		DescPk().
+		Find()
+	if result == nil {
+		return nil, err
+	}
+	return result.(*NodeUpgradePlan), err
+}
+
+// FindLatestPlanWithClusterId 查找集群中最近的升级计划
+func (this *NodeUpgradePlanDAO) FindLatestPlanWithClusterId(tx *dbs.Tx, clusterId int64) (*NodeUpgradePlan, error) {
+	result, err := this.Query(tx).
+		State(NodeUpgradePlanStateEnabled).
+		Attr("clusterId", clusterId).
+		DescPk().
+		Find()
+	if result == nil {
+		return nil, err
+	}
+	return result.(*NodeUpgradePlan), err
+}
+
+// FindAllRunningPlanIds 查找所有需要执行的升级计划
+func (this *NodeUpgradePlanDAO) FindAllRunningPlanIds(tx *dbs.Tx) (planIds []int64, err error) {
+	ones, err := this.Query(tx).
+		State(NodeUpgradePlanStateEnabled).
+		Attr("status", NodeUpgradePlanStatusRunning).
+		ResultPk().
+		AscPk().
+		FindAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, one := range ones {
+		planIds = append(planIds, int64(one.(*NodeUpgradePlan).Id))
+	}
+	return
+}
+
+// UpdatePlanStatus 修改计划状态
+// fromStatuses 不为空时，只有当前状态在其中时才会修改
+func (this *NodeUpgradePlanDAO) UpdatePlanStatus(tx *dbs.Tx, planId int64, status string, errString string, fromStatuses ...string) (ok bool, err error) {
+	var query = this.Query(tx).
+		Pk(planId).
+		State(NodeUpgradePlanStateEnabled)
+	if len(fromStatuses) > 0 {
+		query.Attr("status", fromStatuses)
+	}
+	rows, err := query.
+		Set("status", status).
+		Set("error", errString).
+		Set("updatedAt", time.Now().Unix()).
+		Update()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// UpdatePlanNodes 修改节点升级进度
+func (this *NodeUpgradePlanDAO) UpdatePlanNodes(tx *dbs.Tx, planId int64, nodes []*NodeUpgradePlanNode) error {
+	nodesJSON, err := json.Marshal(nodes)
+	if err != nil {
+		return err
+	}
+	return this.Query(tx).
+		Pk(planId).
+		Set("nodes", nodesJSON).
+		Set("updatedAt", time.Now().Unix()).
+		UpdateQuickly()
+}
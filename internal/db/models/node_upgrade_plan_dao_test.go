@@ -0,0 +1,63 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package models
+
+import (
+	"github.com/dashenmiren/EdgeCommon/pkg/nodeconfigs"
+	_ "github.com/go-sql-driver/mysql"
+	"testing"
+)
+
+func TestAssignNodeUpgradeBatches(t *testing.T) {
+	for _, testCase := range []struct {
+		countNodes    int
+		canaryPercent int
+		batchSize     int
+		batches       []int
+	}{
+		{10, 10, 3, []int{0, 1, 1, 1, 2, 2, 2, 3, 3, 3}},
+		{10, 15, 4, []int{0, 0, 1, 1, 1, 1, 2, 2, 2, 2}},
+		{5, 0, 2, []int{0, 0, 1, 1, 2}},
+		{3, 1, 0, []int{0, 1, 2}},
+		{2, 100, 1, []int{0, 0}},
+	} {
+		var nodes = []*NodeUpgradePlanNode{}
+		for i := 0; i < testCase.countNodes; i++ {
+			nodes = append(nodes, &NodeUpgradePlanNode{NodeId: int64(i + 1)})
+		}
+		AssignNodeUpgradeBatches(nodes, testCase.canaryPercent, testCase.batchSize)
+		for index, node := range nodes {
+			if node.Batch != testCase.batches[index] {
+				t.Fatal("canary:", testCase.canaryPercent, "batchSize:", testCase.batchSize, "node:", index, "expect batch", testCase.batches[index], "got", node.Batch)
+			}
+		}
+	}
+}
+
+func TestNodeUpgradePlanGates_CheckNodeStatus(t *testing.T) {
+	var gates = &NodeUpgradePlanGates{
+		MaxCPUUsage: 0.8,
+	}
+	var status = &nodeconfigs.NodeStatus{
+		IsActive:     true,
+		BuildVersion: "1.3.0",
+		CPUUsage:     0.5,
+		UpdatedAt:    200,
+	}
+	if err := gates.CheckNodeStatus(status, "1.3.0", 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := gates.CheckNodeStatus(status, "1.3.0", 300); err == nil {
+		t.Fatal("expect error for stale status")
+	}
+	if err := gates.CheckNodeStatus(status, "1.3.1", 100); err == nil {
+		t.Fatal("expect error for version mismatch")
+	}
+	if err := gates.CheckNodeStatus(nil, "1.3.0", 100); err == nil {
+		t.Fatal("expect error for empty status")
+	}
+	status.CPUUsage = 0.9
+	if err := gates.CheckNodeStatus(status, "1.3.0", 100); err == nil {
+		t.Fatal("expect error for cpu usage")
+	}
+}
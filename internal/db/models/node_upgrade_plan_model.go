@@ -0,0 +1,42 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package models
+
+import "github.com/iwind/TeaGo/dbs"
+
+// NodeUpgradePlan 集群节点升级计划
+type NodeUpgradePlan struct {
+	Id            uint64   `field:"id"`            // ID
+	AdminId       uint32   `field:"adminId"`       // 管理员ID
+	ClusterId     uint32   `field:"clusterId"`     // 集群ID
+	CanaryPercent uint32   `field:"canaryPercent"` // 金丝雀节点比例
+	BatchSize     uint32   `field:"batchSize"`     // 每批节点数
+	WaitSeconds   uint32   `field:"waitSeconds"`   // 每批升级后等待时间
+	Gates         dbs.JSON `field:"gates"`         // 健康检查条件
+	Nodes         dbs.JSON `field:"nodes"`         // 节点升级进度
+	Status        string   `field:"status"`        // 计划状态
+	Error         string   `field:"error"`         // 错误信息
+	CreatedAt     uint64   `field:"createdAt"`     // 创建时间
+	UpdatedAt     uint64   `field:"updatedAt"`     // 修改时间
+	State         uint8    `field:"state"`         // 状态
+}
+
+type NodeUpgradePlanOperator struct {
+	Id            any // ID
+	AdminId       any // 管理员ID
+	ClusterId     any // 集群ID
+	CanaryPercent any // 金丝雀节点比例
+	BatchSize     any // 每批节点数
+	WaitSeconds   any // 每批升级后等待时间
+	Gates         any // 健康检查条件
+	Nodes         any // 节点升级进度
+	Status        any // 计划状态
+	Error         any // 错误信息
+	CreatedAt     any // 创建时间
+	UpdatedAt     any // 修改时间
+	State         any // 状态
+}
+
+func NewNodeUpgradePlanOperator() *NodeUpgradePlanOperator {
+	return &NodeUpgradePlanOperator{}
+}
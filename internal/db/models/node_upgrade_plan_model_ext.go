@@ -0,0 +1,130 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/dashenmiren/EdgeCommon/pkg/nodeconfigs"
+	"math"
+)
+
+// 升级计划状态
+const (
+	NodeUpgradePlanStatusRunning  = "running"  // 升级中
+	NodeUpgradePlanStatusPaused   = "paused"   // 已暂停
+	NodeUpgradePlanStatusAborted  = "aborted"  // 已终止
+	NodeUpgradePlanStatusFailed   = "failed"   // 健康检查失败，已停止并回滚
+	NodeUpgradePlanStatusFinished = "finished" // 已完成
+)
+
+// 计划中单个节点的升级状态
+const (
+	NodeUpgradePlanNodeStatusPending        = "pending"        // 等待升级
+	NodeUpgradePlanNodeStatusUpgrading      = "upgrading"      // 升级中
+	NodeUpgradePlanNodeStatusChecking       = "checking"       // 已升级，等待健康检查
+	NodeUpgradePlanNodeStatusOk             = "ok"             // 已通过健康检查
+	NodeUpgradePlanNodeStatusFailed         = "failed"         // 升级或健康检查失败
+	NodeUpgradePlanNodeStatusRolledBack     = "rolledBack"     // 已回滚
+	NodeUpgradePlanNodeStatusRollbackFailed = "rollbackFailed" // 回滚失败
+)
+
+// NodeUpgradePlanGates 每批节点升级后需要满足的条件
+type NodeUpgradePlanGates struct {
+	HealthCheck    bool    `json:"healthCheck"`    // 是否要求通过集群的健康检查
+	MaxCPUUsage    float64 `json:"maxCPUUsage"`    // 最大CPU使用比例，0-1，0表示不限制
+	MaxMemoryUsage float64 `json:"maxMemoryUsage"` // 最大内存使用比例，0-1，0表示不限制
+	MaxLoad1m      float64 `json:"maxLoad1m"`      // 最大1分钟负载，0表示不限制
+}
+
+// CheckNodeStatus 检查节点升级后上报的状态
+// sinceTime 为节点升级完成的时间，节点需要在此之后重新上报状态
+func (this *NodeUpgradePlanGates) CheckNodeStatus(status *nodeconfigs.NodeStatus, version string, sinceTime int64) error {
+	if status == nil || status.UpdatedAt < sinceTime {
+		return errors.New("node status has not been reported since upgrade")
+	}
+	if !status.IsActive {
+		return errors.New("node is not active")
+	}
+	if status.BuildVersion != version {
+		return errors.New("node version '" + status.BuildVersion + "' is not expected version '" + version + "'")
+	}
+	if this.MaxCPUUsage > 0 && status.CPUUsage > this.MaxCPUUsage {
+		return fmt.Errorf("cpu usage %.2f exceeds %.2f", status.CPUUsage, this.MaxCPUUsage)
+	}
+	if this.MaxMemoryUsage > 0 && status.MemoryUsage > this.MaxMemoryUsage {
+		return fmt.Errorf("memory usage %.2f exceeds %.2f", status.MemoryUsage, this.MaxMemoryUsage)
+	}
+	if this.MaxLoad1m > 0 && status.Load1m > this.MaxLoad1m {
+		return fmt.Errorf("load1m %.2f exceeds %.2f", status.Load1m, this.MaxLoad1m)
+	}
+	return nil
+}
+
+// NodeUpgradePlanNode 计划中的节点
+type NodeUpgradePlanNode struct {
+	NodeId     int64  `json:"nodeId"`     // 节点ID
+	Batch      int    `json:"batch"`      // 批次，从0开始，如果设置了金丝雀比例，则第0批为金丝雀节点
+	OS         string `json:"os"`         // 操作系统
+	Arch       string `json:"arch"`       // 架构
+	OldVersion string `json:"oldVersion"` // 升级前的版本，用于回滚
+	NewVersion string `json:"newVersion"` // 要升级到的版本
+	Status     string `json:"status"`     // 状态
+	Error      string `json:"error"`      // 错误信息
+	UpdatedAt  int64  `json:"updatedAt"`  // 修改时间
+}
+
+// IsActive 检查计划是否仍在进行中
+func (this *NodeUpgradePlan) IsActive() bool {
+	return this.Status == NodeUpgradePlanStatusRunning || this.Status == NodeUpgradePlanStatusPaused
+}
+
+// DecodeGates 解析健康检查条件
+func (this *NodeUpgradePlan) DecodeGates() *NodeUpgradePlanGates {
+	var gates = &NodeUpgradePlanGates{}
+	if IsNotNull(this.Gates) {
+		_ = json.Unmarshal(this.Gates, gates)
+	}
+	return gates
+}
+
+// DecodeNodes 解析节点升级进度
+func (this *NodeUpgradePlan) DecodeNodes() ([]*NodeUpgradePlanNode, error) {
+	var nodes = []*NodeUpgradePlanNode{}
+	if !IsNotNull(this.Nodes) {
+		return nodes, nil
+	}
+	err := json.Unmarshal(this.Nodes, &nodes)
+	if err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// AssignNodeUpgradeBatches 为节点分配批次
+// 先按照金丝雀比例选出第一批节点，剩余节点按每批节点数分批
+func AssignNodeUpgradeBatches(nodes []*NodeUpgradePlanNode, canaryPercent int, batchSize int) {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	if canaryPercent > 100 {
+		canaryPercent = 100
+	}
+
+	var countCanary = 0
+	if canaryPercent > 0 {
+		countCanary = int(math.Ceil(float64(len(nodes)*canaryPercent) / 100))
+	}
+
+	for index, node := range nodes {
+		switch {
+		case index < countCanary:
+			node.Batch = 0
+		case countCanary > 0:
+			node.Batch = 1 + (index-countCanary)/batchSize
+		default:
+			node.Batch = index / batchSize
+		}
+	}
+}
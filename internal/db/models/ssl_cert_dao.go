@@ -144,7 +144,9 @@ func (this *SSLCertDAO) UpdateCert(tx *dbs.Tx,
 		return errors.New("invalid certId")
 	}
 
-	oldOne, err := this.Query(tx).Find()
+	oldOne, err := this.Query(tx).
+		Pk(certId).
+		Find()
 	if err != nil {
 		return err
 	}
@@ -193,6 +195,11 @@ func (this *SSLCertDAO) UpdateCert(tx *dbs.Tx,
 		op.OcspError = ""
 		op.OcspTries = 0
 		op.OcspExpiresAt = 0
+
+		// 新的证书不再是已吊销状态
+		op.IsRevoked = false
+		op.RevokedAt = 0
+		op.RevokedReason = 0
 	}
 
 	err = this.Save(tx, op)
@@ -414,6 +421,44 @@ func (this *SSLCertDAO) FindAllExpiringCerts(tx *dbs.Tx, days int) (result []*SS
 	return
 }
 
+// FindAllExpiringACMECerts 查找在未来N天内过期、由ACME任务生成的证书
+func (this *SSLCertDAO) FindAllExpiringACMECerts(tx *dbs.Tx, days int) (result []*SSLCert, err error) {
+	if days < 0 {
+		days = 0
+	}
+
+	var now = time.Now().Unix()
+	_, err = this.Query(tx).
+		State(SSLCertStateEnabled).
+		Attr("isOn", true).
+		Gt("acmeTaskId", 0).
+		Gt("timeEndAt", now).
+		Lte("timeEndAt", now+int64(days*86400)).
+		Result("id", "adminId", "userId", "timeEndAt", "name", "dnsNames", "certData", "acmeTaskId").
+		Slice(&result).
+		AscPk().
+		FindAll()
+	return
+}
+
+// UpdateCertRevoked 设置证书为已吊销
+func (this *SSLCertDAO) UpdateCertRevoked(tx *dbs.Tx, certId int64, reason uint8) error {
+	if certId <= 0 {
+		return errors.New("invalid certId")
+	}
+
+	var op = NewSSLCertOperator()
+	op.Id = certId
+	op.IsRevoked = true
+	op.RevokedAt = time.Now().Unix()
+	op.RevokedReason = reason
+	err := this.Save(tx, op)
+	if err != nil {
+		return err
+	}
+	return this.NotifyUpdate(tx, certId)
+}
+
 // UpdateCertNotifiedAt 设置当前证书事件通知时间
 func (this *SSLCertDAO) UpdateCertNotifiedAt(tx *dbs.Tx, certId int64) error {
 	_, err := this.Query(tx).
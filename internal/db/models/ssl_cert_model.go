@@ -32,6 +32,9 @@ type SSLCert struct {
 	OcspUpdatedVersion uint64   `field:"ocspUpdatedVersion"` // OCSP更新版本
 	OcspExpiresAt      uint64   `field:"ocspExpiresAt"`      // OCSP过期时间(UTC)
 	OcspTries          uint32   `field:"ocspTries"`          // OCSP尝试次数
+	IsRevoked          bool     `field:"isRevoked"`          // 是否已吊销
+	RevokedAt          uint64   `field:"revokedAt"`          // 吊销时间
+	RevokedReason      uint8    `field:"revokedReason"`      // 吊销原因
 }
 
 type SSLCertOperator struct {
@@ -63,6 +66,9 @@ type SSLCertOperator struct {
 	OcspUpdatedVersion interface{} // OCSP更新版本
 	OcspExpiresAt      interface{} // OCSP过期时间(UTC)
 	OcspTries          interface{} // OCSP尝试次数
+	IsRevoked          interface{} // 是否已吊销
+	RevokedAt          interface{} // 吊销时间
+	RevokedReason      interface{} // 吊销原因
 }
 
 func NewSSLCertOperator() *SSLCertOperator {
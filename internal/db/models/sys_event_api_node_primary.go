@@ -0,0 +1,54 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package models
+
+import (
+	"github.com/dashenmiren/EdgeCommon/pkg/nodeconfigs"
+	"github.com/iwind/TeaGo/types"
+	"time"
+)
+
+// APINodePrimaryChangedEvent 主节点变更事件
+type APINodePrimaryChangedEvent struct {
+	NodeId    int64  `json:"nodeId"`    // 发生变更的API节点ID
+	OldNodeId int64  `json:"oldNodeId"` // 当选前被指定的主节点ID
+	Token     int64  `json:"token"`     // 租约的防护令牌
+	Reason    string `json:"reason"`    // 变更原因
+	Message   string `json:"message"`   // 附加说明
+	CreatedAt int64  `json:"createdAt"` // 发生时间
+}
+
+func (this *APINodePrimaryChangedEvent) Type() string {
+	return "apiNodePrimaryChanged"
+}
+
+// Run 将变更记录到API节点的运行日志中
+func (this *APINodePrimaryChangedEvent) Run() error {
+	var level = LevelInfo
+	var description string
+	switch this.Reason {
+	case APINodePrimaryReasonElected:
+		level = LevelSuccess
+		description = "became primary api node"
+		if this.OldNodeId > 0 && this.OldNodeId != this.NodeId {
+			description += ", took over from api node '" + types.String(this.OldNodeId) + "'"
+		}
+	case APINodePrimaryReasonLost:
+		level = LevelWarning
+		description = "lost primary lease"
+	case APINodePrimaryReasonHandover:
+		description = "released primary lease"
+	default:
+		description = "primary changed: " + this.Reason
+	}
+	description += " (token: " + types.String(this.Token) + ")"
+	if len(this.Message) > 0 {
+		description += ": " + this.Message
+	}
+
+	var createdAt = this.CreatedAt
+	if createdAt <= 0 {
+		createdAt = time.Now().Unix()
+	}
+	return SharedNodeLogDAO.CreateLog(nil, nodeconfigs.NodeRoleAPI, this.NodeId, 0, 0, level, "PRIMARY", description, createdAt, "", nil)
+}
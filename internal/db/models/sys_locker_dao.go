@@ -198,3 +198,89 @@ func (this *SysLockerDAO) Read(tx *dbs.Tx, key string) (int64, error) {
 		Result("version").
 		FindInt64Col(0)
 }
+
+// AcquireLease 尝试获取租约
+// 只有租约已经过期 graceSeconds 秒之后才能获取；获取成功后返回新的版本号，作为防护令牌（fencing token），失败时返回0
+// 过期时间使用数据库时间计算，以避免各个API节点之间的时钟偏差
+func (this *SysLockerDAO) AcquireLease(tx *dbs.Tx, key string, ttlSeconds int64, graceSeconds int64) (token int64, err error) {
+	exists, err := this.Query(tx).
+		Attr("key", key).
+		Exist()
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		var op = NewSysLockerOperator()
+		op.Key = key
+		op.Version = 0
+		op.TimeoutAt = 0
+		err = this.Save(tx, op)
+		if err != nil && !CheckSQLDuplicateErr(err) {
+			return 0, err
+		}
+	}
+
+	rows, err := this.Query(tx).
+		Attr("key", key).
+		Where("timeoutAt<UNIX_TIMESTAMP()-:graceSeconds").
+		Param("graceSeconds", graceSeconds).
+		Increase("version", 1).
+		Set("timeoutAt", dbs.SQL("UNIX_TIMESTAMP()+"+types.String(ttlSeconds))).
+		Update()
+	if err != nil {
+		return 0, err
+	}
+	if rows == 0 {
+		return 0, nil
+	}
+	return this.Read(tx, key)
+}
+
+// RenewLease 续约
+// 只有防护令牌和当前版本号一致并且租约未过期时才能续约
+func (this *SysLockerDAO) RenewLease(tx *dbs.Tx, key string, token int64, ttlSeconds int64) (ok bool, err error) {
+	if token <= 0 {
+		return false, nil
+	}
+	rows, err := this.Query(tx).
+		Attr("key", key).
+		Attr("version", token).
+		Where("timeoutAt>=UNIX_TIMESTAMP()").
+		Set("timeoutAt", dbs.SQL("UNIX_TIMESTAMP()+"+types.String(ttlSeconds))).
+		Update()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// ReleaseLease 释放租约
+// releaseToAll 为 true 时租约设置为很早之前已经过期，所有节点（包括需要等待 graceSeconds 的节点）都可以立即获取；
+// 否则设置为刚刚过期，只有不需要等待的节点可以立即获取
+func (this *SysLockerDAO) ReleaseLease(tx *dbs.Tx, key string, token int64, releaseToAll bool) error {
+	if token <= 0 {
+		return nil
+	}
+	var timeoutAt = dbs.SQL("UNIX_TIMESTAMP()-1")
+	if releaseToAll {
+		timeoutAt = dbs.SQL("0")
+	}
+	_, err := this.Query(tx).
+		Attr("key", key).
+		Attr("version", token).
+		Set("timeoutAt", timeoutAt).
+		Update()
+	return err
+}
+
+// CheckLease 检查防护令牌是否仍然持有租约
+func (this *SysLockerDAO) CheckLease(tx *dbs.Tx, key string, token int64) (bool, error) {
+	if token <= 0 {
+		return false, nil
+	}
+	return this.Query(tx).
+		Attr("key", key).
+		Attr("version", token).
+		Where("timeoutAt>=UNIX_TIMESTAMP()").
+		Exist()
+}
@@ -190,3 +190,85 @@ func BenchmarkSysLockerDAO_Increase(b *testing.B) {
 		}
 	})
 }
+
+func TestSysLockerDAO_Lease(t *testing.T) {
+	var dao = NewSysLockerDAO()
+	var key = "LEASE" + types.String(time.Now().UnixNano())
+
+	token, err := dao.AcquireLease(nil, key, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token <= 0 {
+		t.Fatal("should acquire lease")
+	}
+	t.Log("token:", token)
+
+	// 未过期时不能再次获取
+	token2, err := dao.AcquireLease(nil, key, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token2 != 0 {
+		t.Fatal("should not acquire lease twice")
+	}
+
+	ok, err := dao.RenewLease(nil, key, token, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("should renew lease")
+	}
+
+	// 错误的令牌
+	ok, err = dao.RenewLease(nil, key, token+1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("should not renew lease with wrong token")
+	}
+
+	// 释放后不需要等待的节点立即可以获取，令牌递增
+	err = dao.ReleaseLease(nil, key, token, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenWithGrace, err := dao.AcquireLease(nil, key, 10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokenWithGrace != 0 {
+		t.Fatal("should wait for grace seconds")
+	}
+	token3, err := dao.AcquireLease(nil, key, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token3 <= token {
+		t.Fatal("token should be increased, got", token3)
+	}
+
+	// 旧令牌已失效
+	ok, err = dao.CheckLease(nil, key, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("old token should be invalid")
+	}
+
+	// 释放给所有节点后，需要等待的节点也可以立即获取
+	err = dao.ReleaseLease(nil, key, token3, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token4, err := dao.AcquireLease(nil, key, 10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token4 <= token3 {
+		t.Fatal("should acquire lease released to all nodes, got", token4)
+	}
+}
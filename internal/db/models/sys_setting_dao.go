@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/dashenmiren/EdgeAPI/internal/remotelogs"
 	"github.com/dashenmiren/EdgeAPI/internal/utils"
+	"github.com/dashenmiren/EdgeAPI/internal/utils/ratelimit"
 	"github.com/dashenmiren/EdgeAPI/internal/zero"
 	"github.com/dashenmiren/EdgeCommon/pkg/nodeconfigs"
 	"github.com/dashenmiren/EdgeCommon/pkg/systemconfigs"
@@ -17,6 +18,10 @@ import (
 	"time"
 )
 
+const (
+	SettingCodeAPIRateLimitConfig = "apiRateLimitConfig" // API限流配置
+)
+
 type SysSettingDAO dbs.DAO
 
 func NewSysSettingDAO() *SysSettingDAO {
@@ -193,6 +198,12 @@ func (this *SysSettingDAO) NotifyUpdate(tx *dbs.Tx, code string) error {
 	switch code {
 	case systemconfigs.SettingCodeAccessLogQueue:
 		accessLogQueueChanged <- zero.New()
+	case SettingCodeAPIRateLimitConfig:
+		config, err := this.ReadAPIRateLimitConfig(tx)
+		if err != nil {
+			return err
+		}
+		ratelimit.SharedLimiter.UpdateConfig(config)
 	case systemconfigs.SettingCodeAdminUIConfig:
 		// 修改当前时区
 		config, err := this.ReadAdminUIConfig(nil, nil)
@@ -261,3 +272,25 @@ func (this *SysSettingDAO) ReadDatabaseConfig(tx *dbs.Tx) (config *systemconfigs
 	}
 	return config, nil
 }
+
+// ReadAPIRateLimitConfig 读取API限流配置
+func (this *SysSettingDAO) ReadAPIRateLimitConfig(tx *dbs.Tx) (*ratelimit.Config, error) {
+	valueJSON, err := this.ReadSetting(tx, SettingCodeAPIRateLimitConfig)
+	if err != nil {
+		return nil, err
+	}
+	if len(valueJSON) == 0 {
+		return ratelimit.DefaultConfig(), nil
+	}
+
+	var config = ratelimit.DefaultConfig()
+	err = json.Unmarshal(valueJSON, config)
+	if err != nil {
+		return nil, err
+	}
+	err = config.Init()
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
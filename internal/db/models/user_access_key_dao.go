@@ -50,7 +50,12 @@ func (this *UserAccessKeyDAO) DisableUserAccessKey(tx *dbs.Tx, id int64) error {
 		Pk(id).
 		Set("state", UserAccessKeyStateDisabled).
 		Update()
-	return err
+	if err != nil {
+		return err
+	}
+
+	// 吊销已经生成的令牌
+	return SharedAPIAccessTokenDAO.RevokeAccessTokensWithAccessKeyId(tx, id)
 }
 
 // FindEnabledUserAccessKey 查找启用中的条目
@@ -112,7 +117,46 @@ func (this *UserAccessKeyDAO) UpdateAccessKeyIsOn(tx *dbs.Tx, accessKeyId int64,
 		Pk(accessKeyId).
 		Set("isOn", isOn).
 		Update()
-	return err
+	if err != nil {
+		return err
+	}
+
+	// 停用时吊销已经生成的令牌
+	if !isOn {
+		return SharedAPIAccessTokenDAO.RevokeAccessTokensWithAccessKeyId(tx, accessKeyId)
+	}
+	return nil
+}
+
+// UpdateAccessKeyScope 修改权限范围
+// 修改后已经生成的令牌会被吊销，需要重新获取
+func (this *UserAccessKeyDAO) UpdateAccessKeyScope(tx *dbs.Tx, accessKeyId int64, scopeJSON []byte) error {
+	if accessKeyId <= 0 {
+		return errors.New("invalid accessKeyId")
+	}
+
+	if IsNull(scopeJSON) {
+		scopeJSON = nil
+	} else {
+		_, err := DecodeAPIAccessScope(scopeJSON)
+		if err != nil {
+			return errors.New("decode scope failed: " + err.Error())
+		}
+	}
+
+	var query = this.Query(tx).
+		Pk(accessKeyId)
+	if scopeJSON == nil {
+		query.Set("scope", dbs.SQL("NULL"))
+	} else {
+		query.Set("scope", scopeJSON)
+	}
+	err := query.UpdateQuickly()
+	if err != nil {
+		return err
+	}
+
+	return SharedAPIAccessTokenDAO.RevokeAccessTokensWithAccessKeyId(tx, accessKeyId)
 }
 
 // FindAccessKeyWithUniqueId 根据UniqueId查找AccessKey
@@ -0,0 +1,15 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package cloudflare
+
+// BatchDNSRecordsResponse 批量修改记录
+type BatchDNSRecordsResponse struct {
+	BaseResponse
+
+	Result struct {
+		Deletes []*DNSRecord `json:"deletes"`
+		Patches []*DNSRecord `json:"patches"`
+		Puts    []*DNSRecord `json:"puts"`
+		Posts   []*DNSRecord `json:"posts"`
+	} `json:"result"`
+}
@@ -5,13 +5,32 @@ package cloudflare
 type GetDNSRecordsResponse struct {
 	BaseResponse
 
-	Result []struct {
-		Id       string `json:"id"`
-		Type     string `json:"type"`
-		Name     string `json:"name"`
-		Content  string `json:"content"`
-		Ttl      int    `json:"ttl"`
-		ZoneId   string `json:"zoneId"`
-		ZoneName string `json:"zoneName"`
-	} `json:"result"`
+	Result []*DNSRecord `json:"result"`
+}
+
+// DNSRecord 单个记录
+type DNSRecord struct {
+	Id       string         `json:"id"`
+	Type     string         `json:"type"`
+	Name     string         `json:"name"`
+	Content  string         `json:"content"`
+	Priority int            `json:"priority"` // MX记录优先级
+	Data     *DNSRecordData `json:"data"`     // SRV、CAA等记录的结构化数据
+	Ttl      int            `json:"ttl"`
+	ZoneId   string         `json:"zoneId"`
+	ZoneName string         `json:"zoneName"`
+}
+
+// DNSRecordData 记录结构化数据
+type DNSRecordData struct {
+	// SRV
+	Priority int    `json:"priority"`
+	Weight   int    `json:"weight"`
+	Port     int    `json:"port"`
+	Target   string `json:"target"`
+
+	// CAA
+	Flags int    `json:"flags"`
+	Tag   string `json:"tag"`
+	Value string `json:"value"`
 }
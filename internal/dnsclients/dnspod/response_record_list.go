@@ -19,5 +19,6 @@ type RecordListResponse struct {
 		Line   string `json:"line"`
 		LineId string `json:"line_id"`
 		TTL    string `json:"ttl"`
+		MX     string `json:"mx"`
 	} `json:"records"`
 }
@@ -0,0 +1,31 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package dnstypes
+
+import "errors"
+
+// UnsupportedRecordTypeError 服务商不支持的记录类型
+type UnsupportedRecordTypeError struct {
+	Provider   string
+	RecordType RecordType
+}
+
+func NewUnsupportedRecordTypeError(provider string, recordType RecordType) *UnsupportedRecordTypeError {
+	return &UnsupportedRecordTypeError{
+		Provider:   provider,
+		RecordType: recordType,
+	}
+}
+
+func (this *UnsupportedRecordTypeError) Error() string {
+	return "record type '" + this.RecordType + "' is not supported by '" + this.Provider + "'"
+}
+
+// IsUnsupportedRecordTypeError 判断是否为不支持的记录类型错误
+func IsUnsupportedRecordTypeError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var e *UnsupportedRecordTypeError
+	return errors.As(err, &e)
+}
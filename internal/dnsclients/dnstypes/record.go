@@ -7,25 +7,56 @@ const (
 	RecordTypeAAAA  RecordType = "AAAA"
 	RecordTypeCNAME RecordType = "CNAME"
 	RecordTypeTXT   RecordType = "TXT"
+	RecordTypeMX    RecordType = "MX"
+	RecordTypeSRV   RecordType = "SRV"
+	RecordTypeCAA   RecordType = "CAA"
+	RecordTypeNS    RecordType = "NS"
+	RecordTypePTR   RecordType = "PTR"
 )
 
+// FindAllRecordTypes 所有支持的记录类型
+func FindAllRecordTypes() []RecordType {
+	return []RecordType{
+		RecordTypeA,
+		RecordTypeAAAA,
+		RecordTypeCNAME,
+		RecordTypeTXT,
+		RecordTypeMX,
+		RecordTypeSRV,
+		RecordTypeCAA,
+		RecordTypeNS,
+		RecordTypePTR,
+	}
+}
+
 type Record struct {
 	Id    string     `json:"id"`
 	Name  string     `json:"name"`
 	Type  RecordType `json:"type"`
-	Value string     `json:"value"`
+	Value string     `json:"value"` // 记录值，MX、SRV、CNAME、NS、PTR记录中为主机名，CAA记录中为Tag对应的值
 	Route string     `json:"route"`
 	TTL   int32      `json:"ttl"`
+
+	Priority int32  `json:"priority,omitempty"` // 优先级，用于MX和SRV记录
+	Weight   int32  `json:"weight,omitempty"`   // 权重，用于SRV记录
+	Port     int32  `json:"port,omitempty"`     // 端口，用于SRV记录
+	Flags    int32  `json:"flags,omitempty"`    // 标记，用于CAA记录
+	Tag      string `json:"tag,omitempty"`      // 标签，用于CAA记录，比如 issue、issuewild、iodef
 }
 
 func (this *Record) Clone() *Record {
 	return &Record{
-		Id:    this.Id,
-		Name:  this.Name,
-		Type:  this.Type,
-		Value: this.Value,
-		Route: this.Route,
-		TTL:   this.TTL,
+		Id:       this.Id,
+		Name:     this.Name,
+		Type:     this.Type,
+		Value:    this.Value,
+		Route:    this.Route,
+		TTL:      this.TTL,
+		Priority: this.Priority,
+		Weight:   this.Weight,
+		Port:     this.Port,
+		Flags:    this.Flags,
+		Tag:      this.Tag,
 	}
 }
 
@@ -39,4 +70,9 @@ func (this *Record) Copy(anotherRecord *Record) {
 	this.Value = anotherRecord.Value
 	this.Route = anotherRecord.Route
 	this.TTL = anotherRecord.TTL
+	this.Priority = anotherRecord.Priority
+	this.Weight = anotherRecord.Weight
+	this.Port = anotherRecord.Port
+	this.Flags = anotherRecord.Flags
+	this.Tag = anotherRecord.Tag
 }
@@ -0,0 +1,105 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package dnstypes
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// IsHostRecordType 记录值是否为主机名
+func IsHostRecordType(recordType RecordType) bool {
+	switch recordType {
+	case RecordTypeCNAME, RecordTypeMX, RecordTypeSRV, RecordTypeNS, RecordTypePTR:
+		return true
+	}
+	return false
+}
+
+// HostValue 补全主机名末尾的点
+func (this *Record) HostValue() string {
+	if len(this.Value) == 0 || strings.HasSuffix(this.Value, ".") {
+		return this.Value
+	}
+	return this.Value + "."
+}
+
+// FormatContent 将记录值格式化为区域文件中的RDATA格式
+//   - MX: PRIORITY HOST
+//   - SRV: PRIORITY WEIGHT PORT TARGET
+//   - CAA: FLAGS TAG "VALUE"
+//
+// 主机名会补全末尾的点，TXT等其他类型的记录直接返回记录值
+func (this *Record) FormatContent() string {
+	switch this.Type {
+	case RecordTypeCNAME, RecordTypeNS, RecordTypePTR:
+		return this.HostValue()
+	case RecordTypeMX:
+		return strconv.Itoa(int(this.Priority)) + " " + this.HostValue()
+	case RecordTypeSRV:
+		return strconv.Itoa(int(this.Priority)) + " " + strconv.Itoa(int(this.Weight)) + " " + strconv.Itoa(int(this.Port)) + " " + this.HostValue()
+	case RecordTypeCAA:
+		return strconv.Itoa(int(this.Flags)) + " " + this.Tag + " " + strconv.Quote(this.Value)
+	}
+	return this.Value
+}
+
+// ParseContent 从区域文件中的RDATA格式中解析记录值
+// 需要事先设置记录类型
+func (this *Record) ParseContent(content string) error {
+	content = strings.TrimSpace(content)
+
+	switch this.Type {
+	case RecordTypeMX:
+		var pieces = strings.Fields(content)
+		if len(pieces) != 2 {
+			return errors.New("invalid MX content '" + content + "'")
+		}
+		priority, err := strconv.ParseUint(pieces[0], 10, 16)
+		if err != nil {
+			return errors.New("invalid MX priority '" + pieces[0] + "'")
+		}
+		this.Priority = int32(priority)
+		this.Value = pieces[1]
+	case RecordTypeSRV:
+		var pieces = strings.Fields(content)
+		if len(pieces) != 4 {
+			return errors.New("invalid SRV content '" + content + "'")
+		}
+		var values = []int32{}
+		for _, piece := range pieces[:3] {
+			value, err := strconv.ParseUint(piece, 10, 16)
+			if err != nil {
+				return errors.New("invalid SRV content '" + content + "'")
+			}
+			values = append(values, int32(value))
+		}
+		this.Priority = values[0]
+		this.Weight = values[1]
+		this.Port = values[2]
+		this.Value = pieces[3]
+	case RecordTypeCAA:
+		var pieces = strings.SplitN(content, " ", 3)
+		if len(pieces) != 3 {
+			return errors.New("invalid CAA content '" + content + "'")
+		}
+		flags, err := strconv.ParseUint(pieces[0], 10, 8)
+		if err != nil {
+			return errors.New("invalid CAA flags '" + pieces[0] + "'")
+		}
+		this.Flags = int32(flags)
+		this.Tag = pieces[1]
+		var value = strings.TrimSpace(pieces[2])
+		if strings.HasPrefix(value, "\"") {
+			unquotedValue, err := strconv.Unquote(value)
+			if err == nil {
+				value = unquotedValue
+			}
+		}
+		this.Value = value
+	default:
+		this.Value = content
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package dnstypes_test
+
+import (
+	"fmt"
+	"github.com/dashenmiren/EdgeAPI/internal/dnsclients/dnstypes"
+	"testing"
+)
+
+func TestRecord_FormatContent(t *testing.T) {
+	for _, testCase := range []struct {
+		record  *dnstypes.Record
+		content string
+	}{
+		{&dnstypes.Record{Type: dnstypes.RecordTypeA, Value: "192.168.1.100"}, "192.168.1.100"},
+		{&dnstypes.Record{Type: dnstypes.RecordTypeCNAME, Value: "www.example.com"}, "www.example.com."},
+		{&dnstypes.Record{Type: dnstypes.RecordTypeNS, Value: "ns1.example.com."}, "ns1.example.com."},
+		{&dnstypes.Record{Type: dnstypes.RecordTypeMX, Value: "mail.example.com", Priority: 10}, "10 mail.example.com."},
+		{&dnstypes.Record{Type: dnstypes.RecordTypeSRV, Value: "sip.example.com", Priority: 10, Weight: 5, Port: 5060}, "10 5 5060 sip.example.com."},
+		{&dnstypes.Record{Type: dnstypes.RecordTypeCAA, Value: "letsencrypt.org", Tag: "issue"}, `0 issue "letsencrypt.org"`},
+		{&dnstypes.Record{Type: dnstypes.RecordTypeTXT, Value: "hello world"}, "hello world"},
+	} {
+		var content = testCase.record.FormatContent()
+		if content != testCase.content {
+			t.Fatal("expect '" + testCase.content + "', but got '" + content + "'")
+		}
+	}
+}
+
+func TestRecord_ParseContent(t *testing.T) {
+	{
+		var record = &dnstypes.Record{Type: dnstypes.RecordTypeMX}
+		err := record.ParseContent("10 mail.example.com.")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.Priority != 10 || record.Value != "mail.example.com." {
+			t.Fatal("unexpected record:", fmt.Sprintf("%+v", record))
+		}
+	}
+	{
+		var record = &dnstypes.Record{Type: dnstypes.RecordTypeSRV}
+		err := record.ParseContent("10 5 5060 sip.example.com.")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.Priority != 10 || record.Weight != 5 || record.Port != 5060 || record.Value != "sip.example.com." {
+			t.Fatal("unexpected record:", fmt.Sprintf("%+v", record))
+		}
+	}
+	{
+		var record = &dnstypes.Record{Type: dnstypes.RecordTypeCAA}
+		err := record.ParseContent(`128 issuewild "ca.example.net; account=230123"`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.Flags != 128 || record.Tag != "issuewild" || record.Value != "ca.example.net; account=230123" {
+			t.Fatal("unexpected record:", fmt.Sprintf("%+v", record))
+		}
+	}
+	{
+		var record = &dnstypes.Record{Type: dnstypes.RecordTypeMX}
+		err := record.ParseContent("mail.example.com.")
+		if err == nil {
+			t.Fatal("should return error for invalid MX content")
+		}
+		t.Log(err)
+	}
+	{
+		var record = &dnstypes.Record{Type: dnstypes.RecordTypeSRV}
+		err := record.ParseContent("10 5 port sip.example.com.")
+		if err == nil {
+			t.Fatal("should return error for invalid SRV content")
+		}
+		t.Log(err)
+	}
+}
+
+func TestIsUnsupportedRecordTypeError(t *testing.T) {
+	var err error = dnstypes.NewUnsupportedRecordTypeError("test", dnstypes.RecordTypeCAA)
+	if !dnstypes.IsUnsupportedRecordTypeError(fmt.Errorf("record operation failed: %w", err)) {
+		t.Fatal("should be unsupported record type error")
+	}
+	if dnstypes.IsUnsupportedRecordTypeError(nil) {
+		t.Fatal("nil should not be unsupported record type error")
+	}
+	t.Log(err)
+}
@@ -0,0 +1,22 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package edgeapi
+
+// NSRecord 记录信息
+type NSRecord struct {
+	Id          int64  `json:"id"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Value       string `json:"value"`
+	MxPriority  int32  `json:"mxPriority"`
+	SrvPriority int32  `json:"srvPriority"`
+	SrvWeight   int32  `json:"srvWeight"`
+	SrvPort     int32  `json:"srvPort"`
+	CaaFlag     int32  `json:"caaFlag"`
+	CaaTag      string `json:"caaTag"`
+	TTL         int32  `json:"ttl"`
+	NSRoutes    []struct {
+		Name string `json:"name"`
+		Code string `json:"code"`
+	} `json:"nsRoutes"`
+}
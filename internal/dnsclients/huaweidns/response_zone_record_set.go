@@ -0,0 +1,13 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package huaweidns
+
+// ZoneRecordSetResponse 单个记录集
+type ZoneRecordSetResponse struct {
+	Id      string   `json:"id"`
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Ttl     int      `json:"ttl"`
+	Line    string   `json:"line"`
+	Records []string `json:"records"`
+}
@@ -0,0 +1,14 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package huaweidns
+
+// ZonesBatchRecordSetsResponse 批量创建、修改、删除记录集
+type ZonesBatchRecordSetsResponse struct {
+	RecordSets []struct {
+		Id      string   `json:"id"`
+		Name    string   `json:"name"`
+		Type    string   `json:"type"`
+		Line    string   `json:"line"`
+		Records []string `json:"records"`
+	} `json:"recordsets"`
+}
@@ -0,0 +1,17 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package powerdns
+
+// ErrorResponse 错误响应
+type ErrorResponse struct {
+	Error  string   `json:"error"`
+	Errors []string `json:"errors"`
+}
+
+func (this *ErrorResponse) IsOk() bool {
+	return len(this.Error) == 0
+}
+
+func (this *ErrorResponse) LastError() string {
+	return this.Error
+}
@@ -0,0 +1,104 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package powerdns_test
+
+import (
+	"encoding/json"
+	"github.com/dashenmiren/EdgeAPI/internal/dnsclients/powerdns"
+	"testing"
+)
+
+func TestZonesResponse(t *testing.T) {
+	var data = `[
+  {"account": "", "dnssec": false, "edited_serial": 2024010101, "id": "example.com.", "kind": "Native", "last_check": 0, "masters": [], "name": "example.com.", "notified_serial": 0, "serial": 2024010101, "url": "/api/v1/servers/localhost/zones/example.com."},
+  {"account": "", "dnssec": false, "edited_serial": 1, "id": "=2Fslash.example.", "kind": "Master", "last_check": 0, "masters": [], "name": "/slash.example.", "notified_serial": 0, "serial": 1, "url": "/api/v1/servers/localhost/zones/=2Fslash.example."}
+]`
+
+	var resp = powerdns.ZonesResponse{}
+	err := json.Unmarshal([]byte(data), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp) != 2 {
+		t.Fatal("expect 2 zones")
+	}
+	if resp[0].Id != "example.com." || resp[0].Name != "example.com." || resp[0].Kind != "Native" || resp[0].Serial != 2024010101 {
+		t.Fatal("invalid zone", resp[0])
+	}
+	if resp[1].Id != "=2Fslash.example." {
+		t.Fatal("invalid zone id", resp[1].Id)
+	}
+}
+
+func TestZoneResponse(t *testing.T) {
+	var data = `{
+  "id": "example.com.",
+  "name": "example.com.",
+  "kind": "Native",
+  "serial": 2024010102,
+  "rrsets": [
+    {"comments": [], "name": "example.com.", "records": [{"content": "ns1.example.com. hostmaster.example.com. 2024010102 10800 3600 604800 3600", "disabled": false}], "ttl": 3600, "type": "SOA"},
+    {"comments": [], "name": "www.example.com.", "records": [{"content": "192.0.2.1", "disabled": false}, {"content": "192.0.2.2", "disabled": true}], "ttl": 300, "type": "A"},
+    {"comments": [], "name": "txt.example.com.", "records": [{"content": "\"hello world\"", "disabled": false}], "ttl": 60, "type": "TXT"}
+  ]
+}`
+
+	var resp = &powerdns.ZoneResponse{}
+	err := json.Unmarshal([]byte(data), resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.RRSets) != 3 {
+		t.Fatal("expect 3 rrsets")
+	}
+	var rrset = resp.RRSets[1]
+	if rrset.Name != "www.example.com." || rrset.Type != "A" || rrset.TTL != 300 || len(rrset.Records) != 2 {
+		t.Fatal("invalid rrset", rrset)
+	}
+	if rrset.Records[0].Disabled || !rrset.Records[1].Disabled {
+		t.Fatal("invalid disabled flag")
+	}
+	if resp.RRSets[2].Records[0].Content != `"hello world"` {
+		t.Fatal("invalid txt content")
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	var data = `{"error": "RRset test.example.com. IN A: Conflicts with pre-existing RRset"}`
+	var resp = &powerdns.ErrorResponse{}
+	err := json.Unmarshal([]byte(data), resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsOk() || resp.LastError() != "RRset test.example.com. IN A: Conflicts with pre-existing RRset" {
+		t.Fatal("invalid error")
+	}
+}
+
+func TestPatchZoneRequest(t *testing.T) {
+	var req = &powerdns.PatchZoneRequest{
+		RRSets: []powerdns.RRSet{
+			{
+				Name:       "www.example.com.",
+				Type:       "A",
+				TTL:        300,
+				ChangeType: powerdns.ChangeTypeReplace,
+				Records:    []powerdns.Record{{Content: "192.0.2.1"}},
+			},
+			{
+				Name:       "old.example.com.",
+				Type:       "A",
+				ChangeType: powerdns.ChangeTypeDelete,
+				Records:    []powerdns.Record{},
+			},
+		},
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(string(data))
+	if string(data) != `{"rrsets":[{"name":"www.example.com.","type":"A","ttl":300,"changetype":"REPLACE","records":[{"content":"192.0.2.1","disabled":false}]},{"name":"old.example.com.","type":"A","changetype":"DELETE","records":[]}]}` {
+		t.Fatal("invalid request json")
+	}
+}
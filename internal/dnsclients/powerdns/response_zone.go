@@ -0,0 +1,12 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package powerdns
+
+// ZoneResponse 域名详情
+type ZoneResponse struct {
+	Id     string  `json:"id"`
+	Name   string  `json:"name"`
+	Kind   string  `json:"kind"`
+	Serial int64   `json:"serial"`
+	RRSets []RRSet `json:"rrsets"`
+}
@@ -0,0 +1,12 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package powerdns
+
+// ZonesResponse 域名列表
+type ZonesResponse []struct {
+	Id     string `json:"id"`
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	URL    string `json:"url"`
+	Serial int64  `json:"serial"`
+}
@@ -0,0 +1,25 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package powerdns
+
+type ChangeType = string
+
+const (
+	ChangeTypeReplace ChangeType = "REPLACE"
+	ChangeTypeDelete  ChangeType = "DELETE"
+)
+
+// Record 记录值
+type Record struct {
+	Content  string `json:"content"`
+	Disabled bool   `json:"disabled"`
+}
+
+// RRSet 记录集
+type RRSet struct {
+	Name       string     `json:"name"`
+	Type       string     `json:"type"`
+	TTL        int        `json:"ttl,omitempty"`
+	ChangeType ChangeType `json:"changetype,omitempty"`
+	Records    []Record   `json:"records"`
+}
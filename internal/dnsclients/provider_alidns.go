@@ -100,14 +100,23 @@ func (this *AliDNSProvider) GetRecords(domain string) (records []*dnstypes.Recor
 				record.Value += "."
 			}
 
-			records = append(records, &dnstypes.Record{
+			var dnsRecord = &dnstypes.Record{
 				Id:    record.RecordId,
 				Name:  record.RR,
 				Type:  record.Type,
 				Value: record.Value,
 				Route: record.Line,
 				TTL:   types.Int32(record.TTL),
-			})
+			}
+			switch record.Type {
+			case dnstypes.RecordTypeMX:
+				dnsRecord.Priority = types.Int32(record.Priority)
+			case dnstypes.RecordTypeSRV, dnstypes.RecordTypeCAA:
+				if dnsRecord.ParseContent(record.Value) != nil {
+					continue
+				}
+			}
+			records = append(records, dnsRecord)
 		}
 
 		pageNumber++
@@ -190,19 +199,27 @@ func (this *AliDNSProvider) QueryRecords(domain string, name string, recordType
 
 // AddRecord 设置记录
 func (this *AliDNSProvider) AddRecord(domain string, newRecord *dnstypes.Record) error {
+	value, err := this.formatValue(newRecord)
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
+	}
+
 	var req = alidns.CreateAddDomainRecordRequest()
 	req.RR = newRecord.Name
 	req.Type = newRecord.Type
-	req.Value = newRecord.Value
+	req.Value = value
 	req.DomainName = domain
 	req.Line = newRecord.Route
+	if newRecord.Type == dnstypes.RecordTypeMX {
+		req.Priority = requests.NewInteger(types.Int(newRecord.Priority))
+	}
 
 	if newRecord.TTL > 0 {
 		req.TTL = requests.NewInteger(types.Int(newRecord.TTL))
 	}
 
 	var resp = alidns.CreateAddDomainRecordResponse()
-	err := this.doAPI(req, resp)
+	err = this.doAPI(req, resp)
 	if err != nil {
 		return this.WrapError(err, domain, newRecord)
 	}
@@ -222,19 +239,27 @@ func (this *AliDNSProvider) AddRecord(domain string, newRecord *dnstypes.Record)
 
 // UpdateRecord 修改记录
 func (this *AliDNSProvider) UpdateRecord(domain string, record *dnstypes.Record, newRecord *dnstypes.Record) error {
+	value, err := this.formatValue(newRecord)
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
+	}
+
 	var req = alidns.CreateUpdateDomainRecordRequest()
 	req.RecordId = record.Id
 	req.RR = newRecord.Name
 	req.Type = newRecord.Type
-	req.Value = newRecord.Value
+	req.Value = value
 	req.Line = newRecord.Route
+	if newRecord.Type == dnstypes.RecordTypeMX {
+		req.Priority = requests.NewInteger(types.Int(newRecord.Priority))
+	}
 
 	if newRecord.TTL > 0 {
 		req.TTL = requests.NewInteger(types.Int(newRecord.TTL))
 	}
 
 	var resp = alidns.CreateUpdateDomainRecordResponse()
-	err := this.doAPI(req, resp)
+	err = this.doAPI(req, resp)
 	if err != nil {
 		return this.WrapError(err, domain, newRecord)
 	}
@@ -273,6 +298,18 @@ func (this *AliDNSProvider) DefaultRoute() string {
 	return "default"
 }
 
+// 格式化记录值
+// MX记录的优先级通过单独的参数设置，SRV和CAA记录使用完整的记录内容
+func (this *AliDNSProvider) formatValue(record *dnstypes.Record) (string, error) {
+	switch record.Type {
+	case dnstypes.RecordTypeA, dnstypes.RecordTypeAAAA, dnstypes.RecordTypeCNAME, dnstypes.RecordTypeTXT, dnstypes.RecordTypeNS, dnstypes.RecordTypeMX:
+		return record.Value, nil
+	case dnstypes.RecordTypeSRV, dnstypes.RecordTypeCAA, dnstypes.RecordTypePTR:
+		return record.FormatContent(), nil
+	}
+	return "", dnstypes.NewUnsupportedRecordTypeError(ProviderTypeAliDNS, record.Type)
+}
+
 // 执行请求
 func (this *AliDNSProvider) doAPI(req requests.AcsRequest, resp responses.AcsResponse) error {
 	req.SetScheme("https")
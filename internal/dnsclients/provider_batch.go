@@ -0,0 +1,166 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package dnsclients
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/dnsclients/dnstypes"
+	"time"
+)
+
+// 逐个执行记录修改时的默认参数
+var (
+	SequentialApplyInterval   = 200 * time.Millisecond // 每次操作之间的间隔，用来避免触发服务商的频率限制
+	SequentialApplyMaxRetries = 2                      // 单个记录操作失败后的最大重试次数
+)
+
+type RecordAction = string
+
+const (
+	RecordActionAdd    RecordAction = "add"
+	RecordActionUpdate RecordAction = "update"
+	RecordActionDelete RecordAction = "delete"
+)
+
+// RecordChanges 一组记录修改
+type RecordChanges struct {
+	Adds    []*dnstypes.Record // 要添加的记录
+	Updates []*RecordUpdate    // 要修改的记录
+	Deletes []*dnstypes.Record // 要删除的记录
+}
+
+// IsEmpty 判断是否没有任何修改
+func (this *RecordChanges) IsEmpty() bool {
+	return this == nil || len(this.Adds)+len(this.Updates)+len(this.Deletes) == 0
+}
+
+// Count 修改的记录数量
+func (this *RecordChanges) Count() int {
+	if this == nil {
+		return 0
+	}
+	return len(this.Adds) + len(this.Updates) + len(this.Deletes)
+}
+
+// RecordUpdate 单个记录修改
+type RecordUpdate struct {
+	Record    *dnstypes.Record // 原记录
+	NewRecord *dnstypes.Record // 新记录
+}
+
+// RecordFailure 单个记录操作失败信息
+type RecordFailure struct {
+	Action RecordAction     `json:"action"` // 操作
+	Record *dnstypes.Record `json:"record"` // 记录，修改操作时为新记录
+	Error  string           `json:"error"`  // 错误信息
+}
+
+func NewRecordFailure(action RecordAction, record *dnstypes.Record, err error) *RecordFailure {
+	var failure = &RecordFailure{
+		Action: action,
+		Record: record,
+	}
+	if err != nil {
+		failure.Error = err.Error()
+	}
+	return failure
+}
+
+// BatchProviderInterface 支持批量修改记录的DNS服务商接口
+type BatchProviderInterface interface {
+	// ApplyRecords 批量应用记录修改
+	// failures 为单个记录操作失败的信息，err 为整体无法执行时的错误
+	ApplyRecords(domain string, changes *RecordChanges) (failures []*RecordFailure, err error)
+}
+
+// ApplyRecordChanges 应用一组记录修改
+// 如果服务商支持批量操作则使用批量操作，否则逐个执行，并在操作之间限速、失败时重试
+func ApplyRecordChanges(provider ProviderInterface, domain string, changes *RecordChanges) (failures []*RecordFailure, err error) {
+	if changes.IsEmpty() {
+		return nil, nil
+	}
+
+	batchProvider, ok := provider.(BatchProviderInterface)
+	if ok {
+		return batchProvider.ApplyRecords(domain, changes)
+	}
+
+	return NewSequentialApplier(provider).Apply(domain, changes), nil
+}
+
+// SequentialApplier 逐个执行记录修改
+type SequentialApplier struct {
+	provider ProviderInterface
+
+	Interval   time.Duration // 每次操作之间的间隔
+	MaxRetries int           // 失败后的最大重试次数
+
+	count int
+}
+
+func NewSequentialApplier(provider ProviderInterface) *SequentialApplier {
+	return &SequentialApplier{
+		provider:   provider,
+		Interval:   SequentialApplyInterval,
+		MaxRetries: SequentialApplyMaxRetries,
+	}
+}
+
+// Apply 执行修改，返回失败的记录
+// 先添加和修改再删除，以免在执行过程中出现没有可用记录的情况
+func (this *SequentialApplier) Apply(domain string, changes *RecordChanges) (failures []*RecordFailure) {
+	if changes.IsEmpty() {
+		return nil
+	}
+
+	for _, record := range changes.Adds {
+		err := this.do(func() error {
+			return this.provider.AddRecord(domain, record)
+		})
+		if err != nil {
+			failures = append(failures, NewRecordFailure(RecordActionAdd, record, err))
+		}
+	}
+
+	for _, update := range changes.Updates {
+		err := this.do(func() error {
+			return this.provider.UpdateRecord(domain, update.Record, update.NewRecord)
+		})
+		if err != nil {
+			failures = append(failures, NewRecordFailure(RecordActionUpdate, update.NewRecord, err))
+		}
+	}
+
+	for _, record := range changes.Deletes {
+		err := this.do(func() error {
+			return this.provider.DeleteRecord(domain, record)
+		})
+		if err != nil {
+			failures = append(failures, NewRecordFailure(RecordActionDelete, record, err))
+		}
+	}
+
+	return
+}
+
+// 执行单个操作
+func (this *SequentialApplier) do(f func() error) error {
+	var err error
+	for i := 0; i <= this.MaxRetries; i++ {
+		// 限速，重试时逐渐增加间隔
+		if this.count > 0 && this.Interval > 0 {
+			time.Sleep(this.Interval * time.Duration(i+1))
+		}
+		this.count++
+
+		err = f()
+		if err == nil {
+			return nil
+		}
+
+		// 不支持的记录类型无需重试
+		if dnstypes.IsUnsupportedRecordTypeError(err) {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,263 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package dnsclients_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/dashenmiren/EdgeAPI/internal/dnsclients"
+	"github.com/dashenmiren/EdgeAPI/internal/dnsclients/dnstypes"
+	"github.com/iwind/TeaGo/maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// 只支持逐个修改记录的服务商
+type testSequentialProvider struct {
+	dnsclients.BaseProvider
+
+	calls     []string
+	failTimes map[string]int // value => 剩余失败次数
+}
+
+func (this *testSequentialProvider) Auth(params maps.Map) error { return nil }
+func (this *testSequentialProvider) MaskParams(params maps.Map) {}
+func (this *testSequentialProvider) GetDomains() ([]string, error) {
+	return nil, nil
+}
+func (this *testSequentialProvider) GetRecords(domain string) ([]*dnstypes.Record, error) {
+	return nil, nil
+}
+func (this *testSequentialProvider) GetRoutes(domain string) ([]*dnstypes.Route, error) {
+	return nil, nil
+}
+func (this *testSequentialProvider) QueryRecord(domain string, name string, recordType dnstypes.RecordType) (*dnstypes.Record, error) {
+	return nil, nil
+}
+func (this *testSequentialProvider) QueryRecords(domain string, name string, recordType dnstypes.RecordType) ([]*dnstypes.Record, error) {
+	return nil, nil
+}
+func (this *testSequentialProvider) AddRecord(domain string, newRecord *dnstypes.Record) error {
+	if newRecord.Type == dnstypes.RecordTypePTR {
+		return dnstypes.NewUnsupportedRecordTypeError("test", newRecord.Type)
+	}
+	return this.call("add", newRecord.Value)
+}
+func (this *testSequentialProvider) UpdateRecord(domain string, record *dnstypes.Record, newRecord *dnstypes.Record) error {
+	return this.call("update", newRecord.Value)
+}
+func (this *testSequentialProvider) DeleteRecord(domain string, record *dnstypes.Record) error {
+	return this.call("delete", record.Value)
+}
+func (this *testSequentialProvider) DefaultRoute() string { return "" }
+
+func (this *testSequentialProvider) call(action string, value string) error {
+	this.calls = append(this.calls, action+":"+value)
+	if this.failTimes[value] != 0 {
+		this.failTimes[value]--
+		return errors.New("rate limited")
+	}
+	return nil
+}
+
+// 支持批量修改记录的服务商
+type testBatchProvider struct {
+	testSequentialProvider
+
+	changes *dnsclients.RecordChanges
+}
+
+func (this *testBatchProvider) ApplyRecords(domain string, changes *dnsclients.RecordChanges) ([]*dnsclients.RecordFailure, error) {
+	this.changes = changes
+	return nil, nil
+}
+
+func TestApplyRecordChanges_Sequential(t *testing.T) {
+	var provider = &testSequentialProvider{
+		failTimes: map[string]int{
+			"10.0.0.2": 1,  // 重试后成功
+			"10.0.0.3": -1, // 一直失败
+		},
+	}
+
+	var applier = dnsclients.NewSequentialApplier(provider)
+	applier.Interval = 0
+	failures := applier.Apply("example.com", &dnsclients.RecordChanges{
+		Adds: []*dnstypes.Record{
+			{Name: "www", Type: dnstypes.RecordTypeA, Value: "10.0.0.1"},
+			{Name: "www", Type: dnstypes.RecordTypeA, Value: "10.0.0.2"},
+			{Name: "ptr", Type: dnstypes.RecordTypePTR, Value: "www.example.com."},
+		},
+		Updates: []*dnsclients.RecordUpdate{
+			{
+				Record:    &dnstypes.Record{Id: "1", Name: "api", Type: dnstypes.RecordTypeA, Value: "10.0.1.1"},
+				NewRecord: &dnstypes.Record{Name: "api", Type: dnstypes.RecordTypeA, Value: "10.0.1.2"},
+			},
+		},
+		Deletes: []*dnstypes.Record{
+			{Id: "2", Name: "www", Type: dnstypes.RecordTypeA, Value: "10.0.0.3"},
+		},
+	})
+
+	var expectedCalls = []string{
+		"add:10.0.0.1",
+		"add:10.0.0.2",
+		"add:10.0.0.2",
+		"update:10.0.1.2",
+		"delete:10.0.0.3",
+		"delete:10.0.0.3",
+		"delete:10.0.0.3",
+	}
+	if strings.Join(provider.calls, ",") != strings.Join(expectedCalls, ",") {
+		t.Fatal("unexpected calls:", provider.calls)
+	}
+
+	if len(failures) != 2 {
+		t.Fatal("expect 2 failures, but got", len(failures))
+	}
+	if failures[0].Action != dnsclients.RecordActionAdd || failures[0].Record.Type != dnstypes.RecordTypePTR {
+		t.Fatal("unexpected failure:", failures[0].Action, failures[0].Record.Type)
+	}
+	if failures[1].Action != dnsclients.RecordActionDelete || failures[1].Record.Value != "10.0.0.3" || failures[1].Error != "rate limited" {
+		t.Fatal("unexpected failure:", failures[1].Action, failures[1].Record.Value, failures[1].Error)
+	}
+
+	// 失败信息可以保存为JSON
+	failuresJSON, err := json.Marshal(failures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(string(failuresJSON))
+}
+
+func TestApplyRecordChanges_Batch(t *testing.T) {
+	var provider = &testBatchProvider{}
+	var changes = &dnsclients.RecordChanges{
+		Adds: []*dnstypes.Record{
+			{Name: "www", Type: dnstypes.RecordTypeA, Value: "10.0.0.1"},
+		},
+	}
+	failures, err := dnsclients.ApplyRecordChanges(provider, "example.com", changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) != 0 {
+		t.Fatal("expect no failures")
+	}
+	if provider.changes != changes {
+		t.Fatal("changes should be applied with batch provider")
+	}
+	if len(provider.calls) != 0 {
+		t.Fatal("should not call single record methods:", provider.calls)
+	}
+
+	// 没有修改
+	failures, err = dnsclients.ApplyRecordChanges(&testSequentialProvider{}, "example.com", &dnsclients.RecordChanges{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) != 0 {
+		t.Fatal("expect no failures")
+	}
+}
+
+// 模拟华为云DNS批量接口
+type testHuaweiDNSBatchServer struct {
+	requests []string
+	bodies   []maps.Map
+	locker   sync.Mutex
+}
+
+func (this *testHuaweiDNSBatchServer) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	var body = maps.Map{}
+	_ = json.NewDecoder(req.Body).Decode(&body)
+	this.requests = append(this.requests, req.Method+" "+req.URL.Path)
+	this.bodies = append(this.bodies, body)
+
+	switch {
+	case req.Method == http.MethodGet && req.URL.Path == "/v2/zones":
+		_, _ = writer.Write([]byte(`{"zones": [{"id": "zone1", "name": "example.com."}]}`))
+	case req.Method == http.MethodPost && req.URL.Path == "/v2.1/zones/zone1/recordsets/batch/lines":
+		_, _ = writer.Write([]byte(`{"recordsets": [{"id": "rs1", "line": "default_view", "records": ["10.0.0.1", "10.0.0.2"]}, {"id": "rs2", "line": "Dianxin", "records": ["10.0.0.3"]}]}`))
+	case req.Method == http.MethodPut && req.URL.Path == "/v2.1/zones/zone1/recordsets":
+		writer.WriteHeader(http.StatusBadRequest)
+		_, _ = writer.Write([]byte(`{"code": "DNS.0303", "message": "record set is locked"}`))
+	case req.Method == http.MethodDelete && req.URL.Path == "/v2.1/zones/zone1/recordsets":
+		_, _ = writer.Write([]byte(`{"recordsets": []}`))
+	default:
+		writer.WriteHeader(http.StatusNotFound)
+		_, _ = writer.Write([]byte(`{}`))
+	}
+}
+
+func TestHuaweiDNSProvider_ApplyRecords(t *testing.T) {
+	var s = &testHuaweiDNSBatchServer{}
+	var server = httptest.NewServer(s)
+	defer server.Close()
+
+	var provider = &dnsclients.HuaweiDNSProvider{}
+	err := provider.Auth(maps.Map{
+		"accessKeyId":     "test",
+		"accessKeySecret": "test",
+		"endpoint":        server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var adds = []*dnstypes.Record{
+		{Name: "www", Type: dnstypes.RecordTypeA, Value: "10.0.0.1", Route: "default_view"},
+		{Name: "www", Type: dnstypes.RecordTypeA, Value: "10.0.0.2", Route: "default_view"},
+		{Name: "www", Type: dnstypes.RecordTypeA, Value: "10.0.0.3", Route: "Dianxin"},
+	}
+	var update = &dnsclients.RecordUpdate{
+		Record:    &dnstypes.Record{Id: "rs3@10.0.1.1", Name: "api", Type: dnstypes.RecordTypeA, Value: "10.0.1.1"},
+		NewRecord: &dnstypes.Record{Name: "api", Type: dnstypes.RecordTypeA, Value: "10.0.1.2"},
+	}
+	failures, err := provider.ApplyRecords("example.com", &dnsclients.RecordChanges{
+		Adds:    adds,
+		Updates: []*dnsclients.RecordUpdate{update},
+		Deletes: []*dnstypes.Record{
+			{Id: "rs4@10.0.2.1", Name: "old", Type: dnstypes.RecordTypeA, Value: "10.0.2.1"},
+			{Id: "rs4@10.0.2.2", Name: "old", Type: dnstypes.RecordTypeA, Value: "10.0.2.2"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 添加的记录合并为一次请求
+	var expectedRequests = []string{
+		"GET /v2/zones",
+		"POST /v2.1/zones/zone1/recordsets/batch/lines",
+		"PUT /v2.1/zones/zone1/recordsets",
+		"DELETE /v2.1/zones/zone1/recordsets",
+	}
+	if strings.Join(s.requests, ",") != strings.Join(expectedRequests, ",") {
+		t.Fatal("unexpected requests:", s.requests)
+	}
+	if len(s.bodies[1].GetSlice("lines")) != 2 {
+		t.Fatal("expect 2 lines, but got", s.bodies[1].GetSlice("lines"))
+	}
+	if len(s.bodies[3].GetSlice("recordset_ids")) != 1 {
+		t.Fatal("record set should be deleted only once:", s.bodies[3].GetSlice("recordset_ids"))
+	}
+
+	// 新记录ID
+	for index, expectedId := range []string{"rs1@10.0.0.1", "rs1@10.0.0.2", "rs2@10.0.0.3"} {
+		if adds[index].Id != expectedId {
+			t.Fatal("expect record id '"+expectedId+"', but got", adds[index].Id)
+		}
+	}
+
+	// 修改失败
+	if len(failures) != 1 || failures[0].Action != dnsclients.RecordActionUpdate || failures[0].Record != update.NewRecord {
+		t.Fatal("expect update failure, but got", len(failures))
+	}
+}
@@ -24,6 +24,7 @@ import (
 
 const CloudFlareAPIEndpoint = "https://api.cloudflare.com/client/v4/"
 const CloudFlareDefaultRoute = "default"
+const CloudFlareMaxBatchSize = 200 // 单次批量操作最多包含的记录数
 
 var cloudFlareHTTPClient = &http.Client{
 	Timeout: 10 * time.Second,
@@ -119,20 +120,7 @@ func (this *CloudFlareProvider) GetRecords(domain string) (records []*dnstypes.R
 		}
 
 		for _, record := range resp.Result {
-			// 修正Record
-			if record.Type == dnstypes.RecordTypeCNAME && !strings.HasSuffix(record.Content, ".") {
-				record.Content += "."
-			}
-
-			record.Name = strings.TrimSuffix(record.Name, "."+domain)
-
-			records = append(records, &dnstypes.Record{
-				Id:    record.Id,
-				Name:  record.Name,
-				Type:  record.Type,
-				Value: record.Content,
-				Route: CloudFlareDefaultRoute,
-			})
+			records = append(records, this.convertRecord(domain, record))
 		}
 	}
 
@@ -167,23 +155,7 @@ func (this *CloudFlareProvider) QueryRecord(domain string, name string, recordTy
 		return nil, nil
 	}
 
-	var record = resp.Result[0]
-
-	// 修正Record
-	if record.Type == dnstypes.RecordTypeCNAME && !strings.HasSuffix(record.Content, ".") {
-		record.Content += "."
-	}
-
-	record.Name = strings.TrimSuffix(record.Name, "."+domain)
-
-	return &dnstypes.Record{
-		Id:    record.Id,
-		Name:  record.Name,
-		Type:  record.Type,
-		Value: record.Content,
-		TTL:   types.Int32(record.Ttl),
-		Route: CloudFlareDefaultRoute,
-	}, nil
+	return this.convertRecord(domain, resp.Result[0]), nil
 }
 
 // QueryRecords 查询多个记录
@@ -207,21 +179,7 @@ func (this *CloudFlareProvider) QueryRecords(domain string, name string, recordT
 	}
 
 	for _, record := range resp.Result {
-		// 修正Record
-		if record.Type == dnstypes.RecordTypeCNAME && !strings.HasSuffix(record.Content, ".") {
-			record.Content += "."
-		}
-
-		record.Name = strings.TrimSuffix(record.Name, "."+domain)
-
-		records = append(records, &dnstypes.Record{
-			Id:    record.Id,
-			Name:  record.Name,
-			Type:  record.Type,
-			Value: record.Content,
-			TTL:   types.Int32(record.Ttl),
-			Route: CloudFlareDefaultRoute,
-		})
+		records = append(records, this.convertRecord(domain, record))
 	}
 	return records, nil
 }
@@ -233,19 +191,13 @@ func (this *CloudFlareProvider) AddRecord(domain string, newRecord *dnstypes.Rec
 		return this.WrapError(err, domain, newRecord)
 	}
 
-	resp := new(cloudflare.CreateDNSRecordResponse)
-
-	var ttl = newRecord.TTL
-	if ttl <= 0 {
-		ttl = 1 // 自动默认
+	body, err := this.recordBody(domain, newRecord)
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
 	}
 
-	err = this.doAPI(http.MethodPost, "zones/"+zoneId+"/dns_records", nil, maps.Map{
-		"type":    newRecord.Type,
-		"name":    newRecord.Name + "." + domain,
-		"content": newRecord.Value,
-		"ttl":     ttl,
-	}, resp)
+	resp := new(cloudflare.CreateDNSRecordResponse)
+	err = this.doAPI(http.MethodPost, "zones/"+zoneId+"/dns_records", nil, body, resp)
 	if err != nil {
 		return this.WrapError(err, domain, newRecord)
 	}
@@ -259,18 +211,13 @@ func (this *CloudFlareProvider) UpdateRecord(domain string, record *dnstypes.Rec
 		return this.WrapError(err, domain, newRecord)
 	}
 
-	var ttl = newRecord.TTL
-	if ttl <= 0 {
-		ttl = 1 // 自动默认
+	body, err := this.recordBody(domain, newRecord)
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
 	}
 
 	resp := new(cloudflare.UpdateDNSRecordResponse)
-	return this.doAPI(http.MethodPut, "zones/"+zoneId+"/dns_records/"+record.Id, nil, maps.Map{
-		"type":    newRecord.Type,
-		"name":    newRecord.Name + "." + domain,
-		"content": newRecord.Value,
-		"ttl":     ttl,
-	}, resp)
+	return this.doAPI(http.MethodPut, "zones/"+zoneId+"/dns_records/"+record.Id, nil, body, resp)
 }
 
 // DeleteRecord 删除记录
@@ -288,11 +235,190 @@ func (this *CloudFlareProvider) DeleteRecord(domain string, record *dnstypes.Rec
 	return nil
 }
 
+// ApplyRecords 批量应用记录修改
+// 同一批中的修改要么全部成功要么全部失败，接口文档：https://developers.cloudflare.com/api/resources/dns/subresources/records/methods/batch/
+func (this *CloudFlareProvider) ApplyRecords(domain string, changes *RecordChanges) (failures []*RecordFailure, err error) {
+	if changes.IsEmpty() {
+		return nil, nil
+	}
+
+	zoneId, err := this.findZoneIdWithDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	type batchOp struct {
+		action RecordAction
+		record *dnstypes.Record
+		body   maps.Map
+	}
+
+	var ops = []*batchOp{}
+	for _, record := range changes.Deletes {
+		ops = append(ops, &batchOp{
+			action: RecordActionDelete,
+			record: record,
+			body:   maps.Map{"id": record.Id},
+		})
+	}
+	for _, update := range changes.Updates {
+		body, bodyErr := this.recordBody(domain, update.NewRecord)
+		if bodyErr != nil {
+			failures = append(failures, NewRecordFailure(RecordActionUpdate, update.NewRecord, bodyErr))
+			continue
+		}
+		body["id"] = update.Record.Id
+		ops = append(ops, &batchOp{
+			action: RecordActionUpdate,
+			record: update.NewRecord,
+			body:   body,
+		})
+	}
+	for _, record := range changes.Adds {
+		body, bodyErr := this.recordBody(domain, record)
+		if bodyErr != nil {
+			failures = append(failures, NewRecordFailure(RecordActionAdd, record, bodyErr))
+			continue
+		}
+		ops = append(ops, &batchOp{
+			action: RecordActionAdd,
+			record: record,
+			body:   body,
+		})
+	}
+
+	for len(ops) > 0 {
+		var size = CloudFlareMaxBatchSize
+		if size > len(ops) {
+			size = len(ops)
+		}
+		var batchOps = ops[:size]
+		ops = ops[size:]
+
+		var deletes = []maps.Map{}
+		var puts = []maps.Map{}
+		var posts = []maps.Map{}
+		var addedRecords = []*dnstypes.Record{}
+		for _, op := range batchOps {
+			switch op.action {
+			case RecordActionDelete:
+				deletes = append(deletes, op.body)
+			case RecordActionUpdate:
+				puts = append(puts, op.body)
+			case RecordActionAdd:
+				posts = append(posts, op.body)
+				addedRecords = append(addedRecords, op.record)
+			}
+		}
+
+		var resp = new(cloudflare.BatchDNSRecordsResponse)
+		batchErr := this.doAPI(http.MethodPost, "zones/"+zoneId+"/dns_records/batch", nil, maps.Map{
+			"deletes": deletes,
+			"puts":    puts,
+			"posts":   posts,
+		}, resp)
+		if batchErr == nil && !resp.IsOk() {
+			_, message := resp.LastError()
+			batchErr = errors.New("batch failed: " + message)
+		}
+		if batchErr != nil {
+			for _, op := range batchOps {
+				failures = append(failures, NewRecordFailure(op.action, op.record, batchErr))
+			}
+			continue
+		}
+
+		// 新记录ID
+		for index, record := range addedRecords {
+			if index < len(resp.Result.Posts) && resp.Result.Posts[index] != nil {
+				record.Id = resp.Result.Posts[index].Id
+			}
+		}
+	}
+
+	return failures, nil
+}
+
 // DefaultRoute 默认线路
 func (this *CloudFlareProvider) DefaultRoute() string {
 	return CloudFlareDefaultRoute
 }
 
+// 转换记录
+func (this *CloudFlareProvider) convertRecord(domain string, record *cloudflare.DNSRecord) *dnstypes.Record {
+	// 修正Record
+	if record.Type == dnstypes.RecordTypeCNAME && !strings.HasSuffix(record.Content, ".") {
+		record.Content += "."
+	}
+
+	var result = &dnstypes.Record{
+		Id:    record.Id,
+		Name:  strings.TrimSuffix(record.Name, "."+domain),
+		Type:  record.Type,
+		Value: record.Content,
+		TTL:   types.Int32(record.Ttl),
+		Route: CloudFlareDefaultRoute,
+	}
+
+	switch record.Type {
+	case dnstypes.RecordTypeMX:
+		result.Priority = int32(record.Priority)
+	case dnstypes.RecordTypeSRV:
+		if record.Data != nil {
+			result.Priority = int32(record.Data.Priority)
+			result.Weight = int32(record.Data.Weight)
+			result.Port = int32(record.Data.Port)
+			result.Value = record.Data.Target
+		}
+	case dnstypes.RecordTypeCAA:
+		if record.Data != nil {
+			result.Flags = int32(record.Data.Flags)
+			result.Tag = record.Data.Tag
+			result.Value = record.Data.Value
+		}
+	}
+	return result
+}
+
+// 创建和修改记录时的参数
+// SRV和CAA记录需要使用data参数
+func (this *CloudFlareProvider) recordBody(domain string, record *dnstypes.Record) (maps.Map, error) {
+	var ttl = record.TTL
+	if ttl <= 0 {
+		ttl = 1 // 自动默认
+	}
+
+	var body = maps.Map{
+		"type": record.Type,
+		"name": record.Name + "." + domain,
+		"ttl":  ttl,
+	}
+
+	switch record.Type {
+	case dnstypes.RecordTypeA, dnstypes.RecordTypeAAAA, dnstypes.RecordTypeCNAME, dnstypes.RecordTypeTXT, dnstypes.RecordTypeNS, dnstypes.RecordTypePTR:
+		body["content"] = record.Value
+	case dnstypes.RecordTypeMX:
+		body["content"] = record.Value
+		body["priority"] = record.Priority
+	case dnstypes.RecordTypeSRV:
+		body["data"] = maps.Map{
+			"priority": record.Priority,
+			"weight":   record.Weight,
+			"port":     record.Port,
+			"target":   record.Value,
+		}
+	case dnstypes.RecordTypeCAA:
+		body["data"] = maps.Map{
+			"flags": record.Flags,
+			"tag":   record.Tag,
+			"value": record.Value,
+		}
+	default:
+		return nil, dnstypes.NewUnsupportedRecordTypeError(ProviderTypeCloudFlare, record.Type)
+	}
+	return body, nil
+}
+
 // 执行API
 func (this *CloudFlareProvider) doAPI(method string, apiPath string, args map[string]string, bodyMap maps.Map, respPtr cloudflare.ResponseInterface) error {
 	apiURL := CloudFlareAPIEndpoint + strings.TrimLeft(apiPath, "/")
@@ -138,14 +138,23 @@ func (this *DNSPodProvider) GetRecords(domain string) (records []*dnstypes.Recor
 
 		// 记录
 		for _, record := range resp.Records {
-			records = append(records, &dnstypes.Record{
+			var dnsRecord = &dnstypes.Record{
 				Id:    types.String(record.Id),
 				Name:  record.Name,
 				Type:  record.Type,
 				Value: record.Value,
 				Route: record.Line,
 				TTL:   types.Int32(record.TTL),
-			})
+			}
+			switch record.Type {
+			case dnstypes.RecordTypeMX:
+				dnsRecord.Priority = types.Int32(record.MX)
+			case dnstypes.RecordTypeSRV, dnstypes.RecordTypeCAA:
+				if dnsRecord.ParseContent(record.Value) != nil {
+					continue
+				}
+			}
+			records = append(records, dnsRecord)
 		}
 
 		// 检查是否到头
@@ -317,18 +326,26 @@ func (this *DNSPodProvider) AddRecord(domain string, newRecord *dnstypes.Record)
 		newRecord.Value += "."
 	}
 
+	value, err := this.formatValue(newRecord)
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
+	}
+
 	var args = map[string]string{
 		"domain":      domain,
 		"sub_domain":  newRecord.Name,
 		"record_type": newRecord.Type,
-		"value":       newRecord.Value,
+		"value":       value,
 		"record_line": newRecord.Route,
 	}
 	if newRecord.TTL > 0 && newRecord.TTL <= DNSPodMaxTTL {
 		args["ttl"] = types.String(newRecord.TTL)
 	}
+	if newRecord.Type == dnstypes.RecordTypeMX {
+		args["mx"] = types.String(newRecord.Priority)
+	}
 	var resp = new(dnspod.RecordCreateResponse)
-	err := this.doAPI("/Record.Create", args, resp)
+	err = this.doAPI("/Record.Create", args, resp)
 	if err != nil {
 		return this.WrapError(err, domain, newRecord)
 	}
@@ -360,19 +377,27 @@ func (this *DNSPodProvider) UpdateRecord(domain string, record *dnstypes.Record,
 		newRecord.Value += "."
 	}
 
+	value, err := this.formatValue(newRecord)
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
+	}
+
 	var args = map[string]string{
 		"domain":      domain,
 		"record_id":   record.Id,
 		"sub_domain":  newRecord.Name,
 		"record_type": newRecord.Type,
-		"value":       newRecord.Value,
+		"value":       value,
 		"record_line": newRecord.Route,
 	}
 	if newRecord.TTL > 0 && newRecord.TTL <= DNSPodMaxTTL {
 		args["ttl"] = types.String(newRecord.TTL)
 	}
+	if newRecord.Type == dnstypes.RecordTypeMX {
+		args["mx"] = types.String(newRecord.Priority)
+	}
 	var resp = new(dnspod.RecordModifyResponse)
-	err := this.doAPI("/Record.Modify", args, resp)
+	err = this.doAPI("/Record.Modify", args, resp)
 	if err != nil {
 		return this.WrapError(err, domain, newRecord)
 	}
@@ -415,6 +440,28 @@ func (this *DNSPodProvider) DeleteRecord(domain string, record *dnstypes.Record)
 	return nil
 }
 
+// ApplyRecords 批量应用记录修改
+// 使用腾讯云API时使用批量任务接口，否则逐个执行
+func (this *DNSPodProvider) ApplyRecords(domain string, changes *RecordChanges) (failures []*RecordFailure, err error) {
+	if this.tencentDNSProvider != nil {
+		return this.tencentDNSProvider.ApplyRecords(domain, changes)
+	}
+
+	return NewSequentialApplier(this).Apply(domain, changes), nil
+}
+
+// 格式化记录值
+// MX记录的优先级通过单独的参数设置，SRV和CAA记录使用完整的记录内容
+func (this *DNSPodProvider) formatValue(record *dnstypes.Record) (string, error) {
+	switch record.Type {
+	case dnstypes.RecordTypeA, dnstypes.RecordTypeAAAA, dnstypes.RecordTypeCNAME, dnstypes.RecordTypeTXT, dnstypes.RecordTypeNS, dnstypes.RecordTypeMX:
+		return record.Value, nil
+	case dnstypes.RecordTypeSRV, dnstypes.RecordTypeCAA, dnstypes.RecordTypePTR:
+		return record.FormatContent(), nil
+	}
+	return "", dnstypes.NewUnsupportedRecordTypeError(ProviderTypeDNSPod, record.Type)
+}
+
 // 发送请求
 func (this *DNSPodProvider) doAPI(path string, params map[string]string, respPtr dnspod.ResponseInterface) error {
 	var apiHost = "https://dnsapi.cn"
@@ -137,19 +137,7 @@ func (this *EdgeDNSAPIProvider) GetRecords(domain string) (records []*dnstypes.R
 
 		var nsRecords = recordsResp.Data.NSRecords
 		for _, record := range nsRecords {
-			var routeCode = this.DefaultRoute()
-			if len(record.NSRoutes) > 0 {
-				routeCode = record.NSRoutes[0].Code
-			}
-
-			records = append(records, &dnstypes.Record{
-				Id:    types.String(record.Id),
-				Name:  record.Name,
-				Type:  record.Type,
-				Value: record.Value,
-				Route: routeCode,
-				TTL:   record.TTL,
-			})
+			records = append(records, this.convertRecord(record))
 		}
 
 		if len(nsRecords) < size {
@@ -280,19 +268,7 @@ func (this *EdgeDNSAPIProvider) QueryRecord(domain string, name string, recordTy
 		return nil, nil
 	}
 
-	var routeCode = this.DefaultRoute()
-	if len(record.NSRoutes) > 0 {
-		routeCode = record.NSRoutes[0].Code
-	}
-
-	return &dnstypes.Record{
-		Id:    types.String(record.Id),
-		Name:  record.Name,
-		Type:  record.Type,
-		Value: record.Value,
-		Route: routeCode,
-		TTL:   record.TTL,
-	}, nil
+	return this.convertRecord(&record), nil
 }
 
 // QueryRecords 查询多个记录
@@ -326,38 +302,22 @@ func (this *EdgeDNSAPIProvider) QueryRecords(domain string, name string, recordT
 			return nil, nil
 		}
 
-		var routeCode = this.DefaultRoute()
-		if len(record.NSRoutes) > 0 {
-			routeCode = record.NSRoutes[0].Code
-		}
-
-		result = append(result, &dnstypes.Record{
-			Id:    types.String(record.Id),
-			Name:  record.Name,
-			Type:  record.Type,
-			Value: record.Value,
-			Route: routeCode,
-			TTL:   record.TTL,
-		})
+		result = append(result, this.convertRecord(record))
 	}
 	return result, nil
 }
 
 // AddRecord 设置记录
 func (this *EdgeDNSAPIProvider) AddRecord(domain string, newRecord *dnstypes.Record) error {
-	var domainResp = &edgeapi.FindDomainWithNameResponse{}
-	err := this.doAPI("/NSDomainService/FindNSDomainWithName", map[string]any{
-		"name": domain,
-	}, domainResp)
+	domainId, err := this.findDomainId(domain)
 	if err != nil {
 		return err
 	}
+	return this.addRecord(domainId, domain, newRecord)
+}
 
-	var domainId = domainResp.Data.NSDomain.Id
-	if domainId == 0 {
-		return errors.New("can not find domain '" + domain + "'")
-	}
-
+// 在某个域名中添加记录
+func (this *EdgeDNSAPIProvider) addRecord(domainId int64, domain string, newRecord *dnstypes.Record) error {
 	if newRecord.Type == dnstypes.RecordTypeCNAME && !strings.HasSuffix(newRecord.Value, ".") {
 		newRecord.Value += "."
 	}
@@ -367,14 +327,19 @@ func (this *EdgeDNSAPIProvider) AddRecord(domain string, newRecord *dnstypes.Rec
 	if len(newRecord.Route) > 0 {
 		routes = []string{newRecord.Route}
 	}
-	err = this.doAPI("/NSRecordService/CreateNSRecord", map[string]any{
+	var params = map[string]any{
 		"nsDomainId":   domainId,
 		"name":         newRecord.Name,
 		"type":         strings.ToUpper(newRecord.Type),
 		"value":        newRecord.Value,
 		"ttl":          newRecord.TTL,
 		"nsRouteCodes": routes,
-	}, createResp)
+	}
+	err := this.addRecordParams(params, newRecord)
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
+	}
+	err = this.doAPI("/NSRecordService/CreateNSRecord", params, createResp)
 
 	if err != nil {
 		return err
@@ -396,7 +361,7 @@ func (this *EdgeDNSAPIProvider) UpdateRecord(domain string, record *dnstypes.Rec
 	if len(newRecord.Route) > 0 {
 		routes = []string{newRecord.Route}
 	}
-	err := this.doAPI("/NSRecordService/UpdateNSRecord", map[string]any{
+	var params = map[string]any{
 		"nsRecordId":   types.Int64(record.Id),
 		"name":         newRecord.Name,
 		"type":         strings.ToUpper(newRecord.Type),
@@ -404,7 +369,12 @@ func (this *EdgeDNSAPIProvider) UpdateRecord(domain string, record *dnstypes.Rec
 		"ttl":          newRecord.TTL,
 		"nsRouteCodes": routes,
 		"isOn":         true, // important
-	}, createResp)
+	}
+	err := this.addRecordParams(params, newRecord)
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
+	}
+	err = this.doAPI("/NSRecordService/UpdateNSRecord", params, createResp)
 
 	return err
 }
@@ -418,11 +388,109 @@ func (this *EdgeDNSAPIProvider) DeleteRecord(domain string, record *dnstypes.Rec
 	return err
 }
 
+// ApplyRecords 批量应用记录修改
+// EdgeDNS API没有批量修改记录的接口，这里只查询一次域名ID，然后逐个修改
+func (this *EdgeDNSAPIProvider) ApplyRecords(domain string, changes *RecordChanges) (failures []*RecordFailure, err error) {
+	if changes.IsEmpty() {
+		return nil, nil
+	}
+
+	domainId, err := this.findDomainId(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range changes.Adds {
+		addErr := this.addRecord(domainId, domain, record)
+		if addErr != nil {
+			failures = append(failures, NewRecordFailure(RecordActionAdd, record, addErr))
+		}
+	}
+	for _, update := range changes.Updates {
+		updateErr := this.UpdateRecord(domain, update.Record, update.NewRecord)
+		if updateErr != nil {
+			failures = append(failures, NewRecordFailure(RecordActionUpdate, update.NewRecord, updateErr))
+		}
+	}
+	for _, record := range changes.Deletes {
+		deleteErr := this.DeleteRecord(domain, record)
+		if deleteErr != nil {
+			failures = append(failures, NewRecordFailure(RecordActionDelete, record, deleteErr))
+		}
+	}
+	return failures, nil
+}
+
 // DefaultRoute 默认线路
 func (this *EdgeDNSAPIProvider) DefaultRoute() string {
 	return "default"
 }
 
+// 查找域名ID
+func (this *EdgeDNSAPIProvider) findDomainId(domain string) (int64, error) {
+	var domainResp = &edgeapi.FindDomainWithNameResponse{}
+	err := this.doAPI("/NSDomainService/FindNSDomainWithName", map[string]any{
+		"name": domain,
+	}, domainResp)
+	if err != nil {
+		return 0, err
+	}
+
+	var domainId = domainResp.Data.NSDomain.Id
+	if domainId == 0 {
+		return 0, errors.New("can not find domain '" + domain + "'")
+	}
+	return domainId, nil
+}
+
+// 转换记录
+func (this *EdgeDNSAPIProvider) convertRecord(record *edgeapi.NSRecord) *dnstypes.Record {
+	var routeCode = this.DefaultRoute()
+	if len(record.NSRoutes) > 0 {
+		routeCode = record.NSRoutes[0].Code
+	}
+
+	var result = &dnstypes.Record{
+		Id:    types.String(record.Id),
+		Name:  record.Name,
+		Type:  record.Type,
+		Value: record.Value,
+		Route: routeCode,
+		TTL:   record.TTL,
+	}
+	switch record.Type {
+	case dnstypes.RecordTypeMX:
+		result.Priority = record.MxPriority
+	case dnstypes.RecordTypeSRV:
+		result.Priority = record.SrvPriority
+		result.Weight = record.SrvWeight
+		result.Port = record.SrvPort
+	case dnstypes.RecordTypeCAA:
+		result.Flags = record.CaaFlag
+		result.Tag = record.CaaTag
+	}
+	return result
+}
+
+// 加入MX、SRV、CAA记录相关参数
+func (this *EdgeDNSAPIProvider) addRecordParams(params map[string]any, record *dnstypes.Record) error {
+	switch record.Type {
+	case dnstypes.RecordTypeA, dnstypes.RecordTypeAAAA, dnstypes.RecordTypeCNAME, dnstypes.RecordTypeTXT, dnstypes.RecordTypeNS:
+	case dnstypes.RecordTypeMX:
+		params["mxPriority"] = record.Priority
+	case dnstypes.RecordTypeSRV:
+		params["srvPriority"] = record.Priority
+		params["srvWeight"] = record.Weight
+		params["srvPort"] = record.Port
+	case dnstypes.RecordTypeCAA:
+		params["caaFlag"] = record.Flags
+		params["caaTag"] = record.Tag
+	default:
+		return dnstypes.NewUnsupportedRecordTypeError(ProviderTypeEdgeDNSAPI, record.Type)
+	}
+	return nil
+}
+
 func (this *EdgeDNSAPIProvider) doAPI(path string, params map[string]any, respPtr edgeapi.ResponseInterface) error {
 	accessToken, err := this.getToken()
 	if err != nil {
@@ -0,0 +1,36 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package dnsclients
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/dnsclients/dnstypes"
+	"testing"
+)
+
+func TestProvider_FormatValue(t *testing.T) {
+	var formatters = map[string]func(record *dnstypes.Record) (string, error){
+		ProviderTypeAliDNS: (&AliDNSProvider{}).formatValue,
+		ProviderTypeDNSPod: (&DNSPodProvider{}).formatValue,
+	}
+
+	for providerType, formatValue := range formatters {
+		for _, item := range []struct {
+			record *dnstypes.Record
+			value  string
+		}{
+			{&dnstypes.Record{Type: dnstypes.RecordTypeA, Value: "192.168.1.100"}, "192.168.1.100"},
+			{&dnstypes.Record{Type: dnstypes.RecordTypeMX, Value: "mx.example.com", Priority: 10}, "mx.example.com"},
+			{&dnstypes.Record{Type: dnstypes.RecordTypeSRV, Value: "sip.example.com", Priority: 10, Weight: 5, Port: 5060}, "10 5 5060 sip.example.com."},
+			{&dnstypes.Record{Type: dnstypes.RecordTypePTR, Value: "www.example.com"}, "www.example.com."},
+			{&dnstypes.Record{Type: dnstypes.RecordTypePTR, Value: "www.example.com."}, "www.example.com."},
+		} {
+			value, err := formatValue(item.record)
+			if err != nil {
+				t.Fatal(providerType, item.record.Type, err)
+			}
+			if value != item.value {
+				t.Fatal(providerType, item.record.Type, "expect '"+item.value+"', but got '"+value+"'")
+			}
+		}
+	}
+}
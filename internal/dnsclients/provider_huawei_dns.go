@@ -28,6 +28,9 @@ import (
 // 所有Endpoints：https://developer.huaweicloud.com/endpoint?DNS
 const HuaweiDNSDefaultEndpoint = "https://dns.cn-north-4.myhuaweicloud.com/"
 
+// HuaweiDNSMaxBatchSize 单次批量操作最多包含的记录集数
+const HuaweiDNSMaxBatchSize = 100
+
 var huaweiDNSHTTPClient = &http.Client{
 	Timeout: 10 * time.Second,
 	Transport: &http.Transport{
@@ -118,14 +121,18 @@ func (this *HuaweiDNSProvider) GetRecords(domain string) (records []*dnstypes.Re
 			for _, value := range recordSet.Records {
 				name := strings.TrimSuffix(recordSet.Name, "."+domain+".")
 
-				records = append(records, &dnstypes.Record{
+				var record = &dnstypes.Record{
 					Id:    recordSet.Id + "@" + value,
 					Name:  name,
 					Type:  recordSet.Type,
 					Value: value,
 					Route: recordSet.Line,
 					TTL:   types.Int32(recordSet.Ttl),
-				})
+				}
+				if this.decodeValue(record) != nil {
+					continue
+				}
+				records = append(records, record)
 			}
 		}
 	}
@@ -2276,14 +2283,19 @@ func (this *HuaweiDNSProvider) QueryRecord(domain string, name string, recordTyp
 		return nil, nil
 	}
 
-	return &dnstypes.Record{
+	var record = &dnstypes.Record{
 		Id:    recordSet.Id + "@" + recordSet.Records[0],
 		Name:  name,
 		Type:  recordType,
 		Value: recordSet.Records[0],
 		Route: recordSet.Line,
 		TTL:   types.Int32(recordSet.Ttl),
-	}, nil
+	}
+	err = this.decodeValue(record)
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
 }
 
 // QueryRecords 查询多个记录
@@ -2307,15 +2319,19 @@ func (this *HuaweiDNSProvider) QueryRecords(domain string, name string, recordTy
 			continue
 		}
 
-		for _, record := range recordSet.Records {
-			result = append(result, &dnstypes.Record{
-				Id:    recordSet.Id + "@" + record,
+		for _, value := range recordSet.Records {
+			var record = &dnstypes.Record{
+				Id:    recordSet.Id + "@" + value,
 				Name:  name,
 				Type:  recordType,
-				Value: record,
+				Value: value,
 				Route: recordSet.Line,
 				TTL:   types.Int32(recordSet.Ttl),
-			})
+			}
+			if this.decodeValue(record) != nil {
+				continue
+			}
+			result = append(result, record)
 		}
 	}
 	return result, nil
@@ -2339,11 +2355,16 @@ func (this *HuaweiDNSProvider) AddRecord(domain string, newRecord *dnstypes.Reco
 		newRecord.Value = "\"" + strings.Trim(newRecord.Value, "\"") + "\""
 	}
 
+	value, err := this.encodeValue(newRecord)
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
+	}
+
 	err = this.doAPI(http.MethodPost, "/v2.1/zones/"+zoneId+"/recordsets", map[string]string{}, maps.Map{
 		"name":        newRecord.Name + "." + domain + ".",
 		"description": "CDN系统自动创建",
 		"type":        newRecord.Type,
-		"records":     []string{newRecord.Value},
+		"records":     []string{value},
 		"line":        newRecord.Route,
 		"ttl":         ttl,
 	}, resp)
@@ -2351,7 +2372,7 @@ func (this *HuaweiDNSProvider) AddRecord(domain string, newRecord *dnstypes.Reco
 		return this.WrapError(err, domain, newRecord)
 	}
 
-	newRecord.Id = resp.Id + "@" + newRecord.Value
+	newRecord.Id = resp.Id + "@" + value
 
 	return nil
 }
@@ -2381,12 +2402,17 @@ func (this *HuaweiDNSProvider) UpdateRecord(domain string, record *dnstypes.Reco
 		newRecord.Value = "\"" + strings.Trim(newRecord.Value, "\"") + "\""
 	}
 
+	value, err := this.encodeValue(newRecord)
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
+	}
+
 	var resp = new(huaweidns.ZonesUpdateRecordSetResponse)
 	err = this.doAPI(http.MethodPut, "/v2.1/zones/"+zoneId+"/recordsets/"+recordId, map[string]string{}, maps.Map{
 		"name":        newRecord.Name + "." + domain + ".",
 		"description": "CDN系统自动创建",
 		"type":        newRecord.Type,
-		"records":     []string{newRecord.Value},
+		"records":     []string{value},
 		"line":        newRecord.Route, // TODO 华为云此API无法修改线路，API地址：https://support.huaweicloud.com/api-dns/dns_api_65006.html
 		"ttl":         ttl,
 	}, resp)
@@ -2421,11 +2447,295 @@ func (this *HuaweiDNSProvider) DeleteRecord(domain string, record *dnstypes.Reco
 	return nil
 }
 
+// ApplyRecords 批量应用记录修改
+// 相同名称和类型的新记录会按线路合并到记录集中一次性创建，修改和删除使用批量接口
+func (this *HuaweiDNSProvider) ApplyRecords(domain string, changes *RecordChanges) (failures []*RecordFailure, err error) {
+	if changes.IsEmpty() {
+		return nil, nil
+	}
+
+	zoneId, err := this.findZoneIdWithDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	// 添加
+	// 接口文档：https://support.huaweicloud.com/api-dns/BatchCreateRecordSetWithLine.html
+	var addGroupKeys = []string{}
+	var addGroups = map[string][]*dnstypes.Record{} // name@type@ttl => records
+	for _, record := range changes.Adds {
+		// 华为云TXT需要加引号
+		if record.Type == dnstypes.RecordTypeTXT {
+			record.Value = "\"" + strings.Trim(record.Value, "\"") + "\""
+		}
+		var key = record.Name + "@" + record.Type + "@" + types.String(this.recordTTL(record))
+		_, ok := addGroups[key]
+		if !ok {
+			addGroupKeys = append(addGroupKeys, key)
+		}
+		addGroups[key] = append(addGroups[key], record)
+	}
+	for _, key := range addGroupKeys {
+		var records = addGroups[key]
+		var lineKeys = []string{}
+		var lineRecords = map[string][]*dnstypes.Record{} // line => records
+		var lineValues = map[string][]string{}            // line => values
+		for _, record := range records {
+			value, valueErr := this.encodeValue(record)
+			if valueErr != nil {
+				failures = append(failures, NewRecordFailure(RecordActionAdd, record, valueErr))
+				continue
+			}
+			_, ok := lineRecords[record.Route]
+			if !ok {
+				lineKeys = append(lineKeys, record.Route)
+			}
+			lineRecords[record.Route] = append(lineRecords[record.Route], record)
+			lineValues[record.Route] = append(lineValues[record.Route], value)
+		}
+		if len(lineKeys) == 0 {
+			continue
+		}
+
+		var lines = []maps.Map{}
+		for _, line := range lineKeys {
+			lines = append(lines, maps.Map{
+				"line":    line,
+				"records": lineValues[line],
+			})
+		}
+
+		var resp = new(huaweidns.ZonesBatchRecordSetsResponse)
+		apiErr := this.doAPI(http.MethodPost, "/v2.1/zones/"+zoneId+"/recordsets/batch/lines", map[string]string{}, maps.Map{
+			"name":        records[0].Name + "." + domain + ".",
+			"type":        records[0].Type,
+			"ttl":         this.recordTTL(records[0]),
+			"description": "CDN系统自动创建",
+			"lines":       lines,
+		}, resp)
+		if apiErr != nil {
+			for _, line := range lineKeys {
+				for _, record := range lineRecords[line] {
+					failures = append(failures, NewRecordFailure(RecordActionAdd, record, apiErr))
+				}
+			}
+			continue
+		}
+
+		// 新记录ID
+		for _, recordSet := range resp.RecordSets {
+			for index, record := range lineRecords[recordSet.Line] {
+				record.Id = recordSet.Id + "@" + lineValues[recordSet.Line][index]
+			}
+		}
+	}
+
+	// 修改
+	// 接口文档：https://support.huaweicloud.com/api-dns/BatchUpdateRecordSetWithLine.html
+	var updates = []*RecordUpdate{}
+	var updateRecordSets = []maps.Map{}
+	for _, update := range changes.Updates {
+		var newRecord = update.NewRecord
+
+		// 华为云TXT需要加引号
+		if newRecord.Type == dnstypes.RecordTypeTXT {
+			newRecord.Value = "\"" + strings.Trim(newRecord.Value, "\"") + "\""
+		}
+
+		value, valueErr := this.encodeValue(newRecord)
+		if valueErr != nil {
+			failures = append(failures, NewRecordFailure(RecordActionUpdate, newRecord, valueErr))
+			continue
+		}
+		updates = append(updates, update)
+		updateRecordSets = append(updateRecordSets, maps.Map{
+			"id":          this.recordSetId(update.Record.Id),
+			"name":        newRecord.Name + "." + domain + ".",
+			"description": "CDN系统自动创建",
+			"type":        newRecord.Type,
+			"records":     []string{value},
+			"ttl":         this.recordTTL(newRecord),
+		})
+	}
+	for len(updates) > 0 {
+		var size = HuaweiDNSMaxBatchSize
+		if size > len(updates) {
+			size = len(updates)
+		}
+
+		var resp = new(huaweidns.ZonesBatchRecordSetsResponse)
+		apiErr := this.doAPI(http.MethodPut, "/v2.1/zones/"+zoneId+"/recordsets", map[string]string{}, maps.Map{
+			"recordsets": updateRecordSets[:size],
+		}, resp)
+		if apiErr != nil {
+			for _, update := range updates[:size] {
+				failures = append(failures, NewRecordFailure(RecordActionUpdate, update.NewRecord, apiErr))
+			}
+		}
+
+		updates = updates[size:]
+		updateRecordSets = updateRecordSets[size:]
+	}
+
+	// 删除
+	// 记录ID为 recordSetId@value，同一个记录集中只删除部分值时使用剩余的值修改记录集，没有剩余的值时才删除整个记录集
+	// 接口文档：https://support.huaweicloud.com/api-dns/BatchDeleteRecordSetWithLine.html
+	var deleteSetIds = []string{}
+	var deleteRecords = map[string][]*dnstypes.Record{} // recordSetId => records
+	for _, record := range changes.Deletes {
+		var recordSetId = this.recordSetId(record.Id)
+		_, ok := deleteRecords[recordSetId]
+		if !ok {
+			deleteSetIds = append(deleteSetIds, recordSetId)
+		}
+		deleteRecords[recordSetId] = append(deleteRecords[recordSetId], record)
+	}
+
+	var deleteIds = []string{}
+	var shrinkIds = []string{}
+	var shrinkRecordSets = []maps.Map{}
+	for _, recordSetId := range deleteSetIds {
+		var records = deleteRecords[recordSetId]
+		var removingValues = map[string]bool{}
+		var removeAll = false
+		for _, record := range records {
+			var atIndex = strings.Index(record.Id, "@")
+			if atIndex <= 0 {
+				removeAll = true
+				break
+			}
+			removingValues[record.Id[atIndex+1:]] = true
+		}
+		if removeAll {
+			deleteIds = append(deleteIds, recordSetId)
+			continue
+		}
+
+		// 读取记录集中现有的值
+		var recordSet = new(huaweidns.ZoneRecordSetResponse)
+		apiErr := this.doAPI(http.MethodGet, "/v2.1/zones/"+zoneId+"/recordsets/"+recordSetId, map[string]string{}, maps.Map{}, recordSet)
+		if apiErr != nil {
+			for _, record := range records {
+				failures = append(failures, NewRecordFailure(RecordActionDelete, record, apiErr))
+			}
+			continue
+		}
+		var remainingValues = []string{}
+		for _, value := range recordSet.Records {
+			if !removingValues[value] {
+				remainingValues = append(remainingValues, value)
+			}
+		}
+		if len(remainingValues) == 0 {
+			deleteIds = append(deleteIds, recordSetId)
+			continue
+		}
+		if len(remainingValues) == len(recordSet.Records) {
+			// 要删除的值已经不存在
+			continue
+		}
+		shrinkIds = append(shrinkIds, recordSetId)
+		shrinkRecordSets = append(shrinkRecordSets, maps.Map{
+			"id":          recordSetId,
+			"name":        recordSet.Name,
+			"description": "CDN系统自动创建",
+			"type":        recordSet.Type,
+			"records":     remainingValues,
+			"ttl":         recordSet.Ttl,
+		})
+	}
+
+	// 修改只删除部分值的记录集
+	for len(shrinkIds) > 0 {
+		var size = HuaweiDNSMaxBatchSize
+		if size > len(shrinkIds) {
+			size = len(shrinkIds)
+		}
+
+		var resp = new(huaweidns.ZonesBatchRecordSetsResponse)
+		apiErr := this.doAPI(http.MethodPut, "/v2.1/zones/"+zoneId+"/recordsets", map[string]string{}, maps.Map{
+			"recordsets": shrinkRecordSets[:size],
+		}, resp)
+		if apiErr != nil {
+			for _, recordSetId := range shrinkIds[:size] {
+				for _, record := range deleteRecords[recordSetId] {
+					failures = append(failures, NewRecordFailure(RecordActionDelete, record, apiErr))
+				}
+			}
+		}
+
+		shrinkIds = shrinkIds[size:]
+		shrinkRecordSets = shrinkRecordSets[size:]
+	}
+
+	// 删除没有剩余值的记录集
+	for len(deleteIds) > 0 {
+		var size = HuaweiDNSMaxBatchSize
+		if size > len(deleteIds) {
+			size = len(deleteIds)
+		}
+
+		var resp = new(huaweidns.ZonesBatchRecordSetsResponse)
+		apiErr := this.doAPI(http.MethodDelete, "/v2.1/zones/"+zoneId+"/recordsets", map[string]string{}, maps.Map{
+			"recordset_ids": deleteIds[:size],
+		}, resp)
+		if apiErr != nil {
+			for _, recordSetId := range deleteIds[:size] {
+				for _, record := range deleteRecords[recordSetId] {
+					failures = append(failures, NewRecordFailure(RecordActionDelete, record, apiErr))
+				}
+			}
+		}
+
+		deleteIds = deleteIds[size:]
+	}
+
+	return failures, nil
+}
+
 // DefaultRoute 默认线路
 func (this *HuaweiDNSProvider) DefaultRoute() string {
 	return "default_view"
 }
 
+// 记录的TTL，未设置时使用默认值
+func (this *HuaweiDNSProvider) recordTTL(record *dnstypes.Record) int32 {
+	if record.TTL <= 0 {
+		return 300
+	}
+	return record.TTL
+}
+
+// 从记录ID中读取记录集ID，记录ID格式为 recordSetId@value
+func (this *HuaweiDNSProvider) recordSetId(recordId string) string {
+	var atIndex = strings.Index(recordId, "@")
+	if atIndex > 0 {
+		return recordId[:atIndex]
+	}
+	return recordId
+}
+
+// 将记录转换为华为云记录集中的值
+// 华为云记录集中的MX、SRV、CAA记录值为完整的记录内容
+func (this *HuaweiDNSProvider) encodeValue(record *dnstypes.Record) (string, error) {
+	switch record.Type {
+	case dnstypes.RecordTypeA, dnstypes.RecordTypeAAAA, dnstypes.RecordTypeCNAME, dnstypes.RecordTypeTXT, dnstypes.RecordTypeNS:
+		return record.Value, nil
+	case dnstypes.RecordTypeMX, dnstypes.RecordTypeSRV, dnstypes.RecordTypeCAA:
+		return record.FormatContent(), nil
+	}
+	return "", dnstypes.NewUnsupportedRecordTypeError(ProviderTypeHuaweiDNS, record.Type)
+}
+
+// 从华为云记录集中的值解析记录
+func (this *HuaweiDNSProvider) decodeValue(record *dnstypes.Record) error {
+	switch record.Type {
+	case dnstypes.RecordTypeMX, dnstypes.RecordTypeSRV, dnstypes.RecordTypeCAA:
+		return record.ParseContent(record.Value)
+	}
+	return nil
+}
+
 func (this *HuaweiDNSProvider) doAPI(method string, apiPath string, args map[string]string, bodyMap maps.Map, respPtr interface{}) error {
 	var endpoint = HuaweiDNSDefaultEndpoint
 	if len(this.endpoint) > 0 {
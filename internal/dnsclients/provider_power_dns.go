@@ -0,0 +1,485 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package dnsclients
+
+import (
+	"bytes"
+	"encoding/json"
+	teaconst "github.com/dashenmiren/EdgeAPI/internal/const"
+	"github.com/dashenmiren/EdgeAPI/internal/dnsclients/dnstypes"
+	"github.com/dashenmiren/EdgeAPI/internal/dnsclients/powerdns"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/iwind/TeaGo/maps"
+	"github.com/iwind/TeaGo/types"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	PowerDNSDefaultServerId = "localhost"
+	PowerDNSDefaultTTL      = 300
+)
+
+var powerDNSHTTPClient = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+// PowerDNSProvider 通过PowerDNS HTTP API管理的DNS服务器
+// 相关文档链接：https://doc.powerdns.com/authoritative/http-api/
+type PowerDNSProvider struct {
+	BaseProvider
+
+	ProviderId int64
+
+	apiURL   string
+	apiKey   string
+	serverId string
+
+	zoneMap    map[string]string // domain => zoneId
+	zoneLocker sync.Mutex
+}
+
+// Auth 认证
+// 参数：
+//   - apiURL API地址，比如 http://127.0.0.1:8081
+//   - apiKey API密钥
+//   - serverId 可选，服务器ID，默认为 localhost
+func (this *PowerDNSProvider) Auth(params maps.Map) error {
+	this.apiURL = strings.TrimSuffix(params.GetString("apiURL"), "/")
+	if len(this.apiURL) == 0 {
+		return errors.New("'apiURL' should not be empty")
+	}
+	u, err := url.Parse(this.apiURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.New("invalid 'apiURL' '" + this.apiURL + "'")
+	}
+
+	this.apiKey = params.GetString("apiKey")
+	if len(this.apiKey) == 0 {
+		return errors.New("'apiKey' should not be empty")
+	}
+
+	this.serverId = params.GetString("serverId")
+	if len(this.serverId) == 0 {
+		this.serverId = PowerDNSDefaultServerId
+	}
+
+	this.zoneMap = map[string]string{}
+
+	return nil
+}
+
+// MaskParams 对参数进行掩码
+func (this *PowerDNSProvider) MaskParams(params maps.Map) {
+	if params == nil {
+		return
+	}
+	params["apiKey"] = MaskString(params.GetString("apiKey"))
+}
+
+// GetDomains 获取所有域名列表
+func (this *PowerDNSProvider) GetDomains() (domains []string, err error) {
+	var resp = powerdns.ZonesResponse{}
+	err = this.doAPI(http.MethodGet, "/zones", nil, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	this.zoneLocker.Lock()
+	for _, zone := range resp {
+		var domain = strings.TrimSuffix(zone.Name, ".")
+		this.zoneMap[domain] = zone.Id
+		domains = append(domains, domain)
+	}
+	this.zoneLocker.Unlock()
+	return
+}
+
+// GetRecords 获取域名解析记录列表
+func (this *PowerDNSProvider) GetRecords(domain string) (records []*dnstypes.Record, err error) {
+	zone, err := this.findZone(domain, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, rrset := range zone.RRSets {
+		records = append(records, this.convertRRSet(domain, rrset)...)
+	}
+	return
+}
+
+// GetRoutes 读取域名支持的线路数据
+// PowerDNS不支持线路
+func (this *PowerDNSProvider) GetRoutes(domain string) (routes []*dnstypes.Route, err error) {
+	return nil, nil
+}
+
+// QueryRecord 查询单个记录
+func (this *PowerDNSProvider) QueryRecord(domain string, name string, recordType dnstypes.RecordType) (*dnstypes.Record, error) {
+	records, err := this.QueryRecords(domain, name, recordType)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > 0 {
+		return records[0], nil
+	}
+	return nil, nil
+}
+
+// QueryRecords 查询多个记录
+func (this *PowerDNSProvider) QueryRecords(domain string, name string, recordType dnstypes.RecordType) ([]*dnstypes.Record, error) {
+	rrset, err := this.findRRSet(domain, this.fqdn(domain, name), recordType)
+	if err != nil {
+		return nil, err
+	}
+	if rrset == nil {
+		return nil, nil
+	}
+	return this.convertRRSet(domain, *rrset), nil
+}
+
+// AddRecord 设置记录
+// 如果记录集已经存在，则将新的值加入到记录集中
+func (this *PowerDNSProvider) AddRecord(domain string, newRecord *dnstypes.Record) error {
+	rrset, err := this.addRRSet(domain, newRecord, nil)
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
+	}
+	err = this.patchRRSets(domain, []powerdns.RRSet{*rrset})
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
+	}
+	newRecord.Id = this.recordId(newRecord)
+	return nil
+}
+
+// UpdateRecord 修改记录
+func (this *PowerDNSProvider) UpdateRecord(domain string, record *dnstypes.Record, newRecord *dnstypes.Record) error {
+	var rrsets = []powerdns.RRSet{}
+	if this.fqdn(domain, record.Name) == this.fqdn(domain, newRecord.Name) && record.Type == newRecord.Type {
+		rrset, err := this.addRRSet(domain, newRecord, record)
+		if err != nil {
+			return this.WrapError(err, domain, newRecord)
+		}
+		rrsets = append(rrsets, *rrset)
+	} else {
+		removeRRSet, err := this.removeRRSet(domain, record)
+		if err != nil {
+			return this.WrapError(err, domain, record)
+		}
+		if removeRRSet != nil {
+			rrsets = append(rrsets, *removeRRSet)
+		}
+		addRRSet, err := this.addRRSet(domain, newRecord, nil)
+		if err != nil {
+			return this.WrapError(err, domain, newRecord)
+		}
+		rrsets = append(rrsets, *addRRSet)
+	}
+
+	err := this.patchRRSets(domain, rrsets)
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
+	}
+	newRecord.Id = this.recordId(newRecord)
+	return nil
+}
+
+// DeleteRecord 删除记录
+func (this *PowerDNSProvider) DeleteRecord(domain string, record *dnstypes.Record) error {
+	rrset, err := this.removeRRSet(domain, record)
+	if err != nil {
+		return this.WrapError(err, domain, record)
+	}
+	if rrset == nil {
+		return nil
+	}
+	return this.WrapError(this.patchRRSets(domain, []powerdns.RRSet{*rrset}), domain, record)
+}
+
+// DefaultRoute 默认线路
+func (this *PowerDNSProvider) DefaultRoute() string {
+	return ""
+}
+
+// 加入一个值后的记录集
+// replaceRecord 不为空时，从记录集中替换此记录的值
+func (this *PowerDNSProvider) addRRSet(domain string, newRecord *dnstypes.Record, replaceRecord *dnstypes.Record) (*powerdns.RRSet, error) {
+	if !this.isSupportedType(newRecord.Type) {
+		return nil, dnstypes.NewUnsupportedRecordTypeError(ProviderTypePowerDNS, newRecord.Type)
+	}
+
+	var fqdn = this.fqdn(domain, newRecord.Name)
+	oldRRSet, err := this.findRRSet(domain, fqdn, newRecord.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	var ttl = int(newRecord.TTL)
+	if ttl <= 0 {
+		ttl = PowerDNSDefaultTTL
+	}
+	if this.MinTTL() > 0 && ttl < int(this.MinTTL()) {
+		ttl = int(this.MinTTL())
+	}
+
+	var content = this.formatContent(newRecord)
+	var replaceContent = ""
+	if replaceRecord != nil {
+		replaceContent = this.formatContent(replaceRecord)
+	}
+
+	var records = []powerdns.Record{}
+	if oldRRSet != nil {
+		for _, record := range oldRRSet.Records {
+			if record.Content == content || (len(replaceContent) > 0 && record.Content == replaceContent) {
+				continue
+			}
+			records = append(records, record)
+		}
+	}
+	records = append(records, powerdns.Record{Content: content})
+
+	return &powerdns.RRSet{
+		Name:       fqdn,
+		Type:       newRecord.Type,
+		TTL:        ttl,
+		ChangeType: powerdns.ChangeTypeReplace,
+		Records:    records,
+	}, nil
+}
+
+// 删除一个值后的记录集，如果没有需要修改的则返回nil
+func (this *PowerDNSProvider) removeRRSet(domain string, record *dnstypes.Record) (*powerdns.RRSet, error) {
+	var fqdn = this.fqdn(domain, record.Name)
+	oldRRSet, err := this.findRRSet(domain, fqdn, record.Type)
+	if err != nil {
+		return nil, err
+	}
+	if oldRRSet == nil {
+		return nil, nil
+	}
+
+	var content = this.formatContent(record)
+	var records = []powerdns.Record{}
+	for _, oldRecord := range oldRRSet.Records {
+		if oldRecord.Content != content {
+			records = append(records, oldRecord)
+		}
+	}
+	if len(records) == len(oldRRSet.Records) {
+		return nil, nil
+	}
+
+	if len(records) == 0 {
+		return &powerdns.RRSet{
+			Name:       fqdn,
+			Type:       record.Type,
+			ChangeType: powerdns.ChangeTypeDelete,
+			Records:    []powerdns.Record{},
+		}, nil
+	}
+	return &powerdns.RRSet{
+		Name:       fqdn,
+		Type:       record.Type,
+		TTL:        oldRRSet.TTL,
+		ChangeType: powerdns.ChangeTypeReplace,
+		Records:    records,
+	}, nil
+}
+
+// 查找记录集
+func (this *PowerDNSProvider) findRRSet(domain string, fqdn string, recordType string) (*powerdns.RRSet, error) {
+	// rrset_name和rrset_type参数在PowerDNS 4.6+中支持，老版本会忽略这两个参数
+	zone, err := this.findZone(domain, url.Values{
+		"rrset_name": []string{fqdn},
+		"rrset_type": []string{recordType},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, rrset := range zone.RRSets {
+		if strings.EqualFold(rrset.Name, fqdn) && rrset.Type == recordType {
+			return &rrset, nil
+		}
+	}
+	return nil, nil
+}
+
+// 提交修改
+func (this *PowerDNSProvider) patchRRSets(domain string, rrsets []powerdns.RRSet) error {
+	if len(rrsets) == 0 {
+		return nil
+	}
+	zoneId, err := this.findZoneIdWithDomain(domain)
+	if err != nil {
+		return err
+	}
+	return this.doAPI(http.MethodPatch, "/zones/"+url.PathEscape(zoneId), nil, &powerdns.PatchZoneRequest{RRSets: rrsets}, nil)
+}
+
+// 查找域名详情
+func (this *PowerDNSProvider) findZone(domain string, query url.Values) (*powerdns.ZoneResponse, error) {
+	zoneId, err := this.findZoneIdWithDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+	var resp = &powerdns.ZoneResponse{}
+	err = this.doAPI(http.MethodGet, "/zones/"+url.PathEscape(zoneId), query, nil, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// 将记录集转换为记录，忽略已禁用的记录
+func (this *PowerDNSProvider) convertRRSet(domain string, rrset powerdns.RRSet) []*dnstypes.Record {
+	if !this.isSupportedType(rrset.Type) {
+		return nil
+	}
+
+	var result = []*dnstypes.Record{}
+	var name = this.relativeName(domain, rrset.Name)
+	for _, powerRecord := range rrset.Records {
+		if powerRecord.Disabled {
+			continue
+		}
+		var record = &dnstypes.Record{
+			Name: name,
+			Type: rrset.Type,
+			TTL:  types.Int32(rrset.TTL),
+		}
+		if rrset.Type == dnstypes.RecordTypeTXT {
+			record.Value = unquoteTXT(powerRecord.Content)
+		} else if record.ParseContent(powerRecord.Content) != nil {
+			continue
+		}
+		record.Id = this.recordId(record)
+		result = append(result, record)
+	}
+	return result
+}
+
+// 记录ID，格式为 TYPE$NAME$CONTENT
+func (this *PowerDNSProvider) recordId(record *dnstypes.Record) string {
+	return record.Type + "$" + record.Name + "$" + record.FormatContent()
+}
+
+// 格式化记录内容
+func (this *PowerDNSProvider) formatContent(record *dnstypes.Record) string {
+	if record.Type == dnstypes.RecordTypeTXT {
+		return strconv.Quote(unquoteTXT(record.Value))
+	}
+	return record.FormatContent()
+}
+
+// 是否为支持的记录类型
+func (this *PowerDNSProvider) isSupportedType(recordType dnstypes.RecordType) bool {
+	switch recordType {
+	case dnstypes.RecordTypeA, dnstypes.RecordTypeAAAA, dnstypes.RecordTypeCNAME, dnstypes.RecordTypeTXT,
+		dnstypes.RecordTypeMX, dnstypes.RecordTypeSRV, dnstypes.RecordTypeCAA, dnstypes.RecordTypeNS, dnstypes.RecordTypePTR:
+		return true
+	}
+	return false
+}
+
+// 完整域名
+func (this *PowerDNSProvider) fqdn(domain string, name string) string {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if len(name) == 0 || name == "@" {
+		return domain + "."
+	}
+	return strings.ToLower(name) + "." + domain + "."
+}
+
+// 相对于域名的记录名
+func (this *PowerDNSProvider) relativeName(domain string, fqdn string) string {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	var name = strings.ToLower(strings.TrimSuffix(fqdn, "."))
+	if name == domain {
+		return "@"
+	}
+	return strings.TrimSuffix(name, "."+domain)
+}
+
+func (this *PowerDNSProvider) findZoneIdWithDomain(domain string) (string, error) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+
+	this.zoneLocker.Lock()
+	zoneId, ok := this.zoneMap[domain]
+	this.zoneLocker.Unlock()
+	if ok {
+		return zoneId, nil
+	}
+
+	_, err := this.GetDomains()
+	if err != nil {
+		return "", err
+	}
+
+	this.zoneLocker.Lock()
+	zoneId, ok = this.zoneMap[domain]
+	this.zoneLocker.Unlock()
+	if ok {
+		return zoneId, nil
+	}
+	return "", errors.New("can not find zone id for '" + domain + "'")
+}
+
+// 调用API
+func (this *PowerDNSProvider) doAPI(method string, apiPath string, query url.Values, body any, respPtr any) error {
+	var apiURL = this.apiURL + "/api/v1/servers/" + url.PathEscape(this.serverId) + apiPath
+	if len(query) > 0 {
+		apiURL += "?" + query.Encode()
+	}
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyData, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		bodyReader = bytes.NewReader(bodyData)
+	}
+
+	req, err := http.NewRequest(method, apiURL, bodyReader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-API-Key", this.apiKey)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", teaconst.ProductName+"/"+teaconst.Version)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := powerDNSHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errorResp = &powerdns.ErrorResponse{}
+		if json.Unmarshal(data, errorResp) == nil && !errorResp.IsOk() {
+			return errors.New("API error: " + errorResp.LastError())
+		}
+		return errors.New("invalid response status '" + strconv.Itoa(resp.StatusCode) + "', response '" + string(data) + "'")
+	}
+
+	if respPtr == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, respPtr)
+}
@@ -0,0 +1,381 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package dnsclients_test
+
+import (
+	"encoding/json"
+	"github.com/dashenmiren/EdgeAPI/internal/dnsclients"
+	"github.com/dashenmiren/EdgeAPI/internal/dnsclients/dnstypes"
+	"github.com/dashenmiren/EdgeAPI/internal/dnsclients/powerdns"
+	"github.com/iwind/TeaGo/maps"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testPowerDNSAPIKey = "secret-api-key"
+
+// 模拟PowerDNS HTTP API
+type testPowerDNSServer struct {
+	rrsets []powerdns.RRSet
+	locker sync.Mutex
+}
+
+func newTestPowerDNSProvider(t *testing.T) (*dnsclients.PowerDNSProvider, *testPowerDNSServer) {
+	var s = &testPowerDNSServer{
+		rrsets: []powerdns.RRSet{
+			{
+				Name:    "example.com.",
+				Type:    "SOA",
+				TTL:     3600,
+				Records: []powerdns.Record{{Content: "ns1.example.com. admin.example.com. 1 3600 600 86400 600"}},
+			},
+			{
+				Name: "old.example.com.",
+				Type: dnstypes.RecordTypeA,
+				TTL:  300,
+				Records: []powerdns.Record{
+					{Content: "10.0.0.1"},
+					{Content: "10.0.0.2", Disabled: true},
+				},
+			},
+		},
+	}
+	var server = httptest.NewServer(s)
+	t.Cleanup(server.Close)
+
+	var provider = &dnsclients.PowerDNSProvider{}
+	err := provider.Auth(maps.Map{
+		"apiURL": server.URL,
+		"apiKey": testPowerDNSAPIKey,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider, s
+}
+
+func (this *testPowerDNSServer) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
+	if req.Header.Get("X-API-Key") != testPowerDNSAPIKey {
+		this.writeError(writer, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	switch {
+	case req.Method == http.MethodGet && req.URL.Path == "/api/v1/servers/localhost/zones":
+		_, _ = writer.Write([]byte(`[{"id": "example.com.", "name": "example.com.", "kind": "Native", "url": "/api/v1/servers/localhost/zones/example.com.", "serial": 1}]`))
+	case req.Method == http.MethodGet && req.URL.Path == "/api/v1/servers/localhost/zones/example.com.":
+		var resp = &powerdns.ZoneResponse{
+			Id:     "example.com.",
+			Name:   "example.com.",
+			Kind:   "Native",
+			RRSets: []powerdns.RRSet{},
+		}
+		var rrsetName = req.URL.Query().Get("rrset_name")
+		var rrsetType = req.URL.Query().Get("rrset_type")
+		for _, rrset := range this.rrsets {
+			if len(rrsetName) > 0 && rrset.Name != rrsetName {
+				continue
+			}
+			if len(rrsetType) > 0 && rrset.Type != rrsetType {
+				continue
+			}
+			resp.RRSets = append(resp.RRSets, rrset)
+		}
+		data, _ := json.Marshal(resp)
+		_, _ = writer.Write(data)
+	case req.Method == http.MethodPatch && req.URL.Path == "/api/v1/servers/localhost/zones/example.com.":
+		var patchReq = &powerdns.PatchZoneRequest{}
+		err := json.NewDecoder(req.Body).Decode(patchReq)
+		if err != nil {
+			this.writeError(writer, http.StatusBadRequest, err.Error())
+			return
+		}
+		for _, rrset := range patchReq.RRSets {
+			var index = -1
+			for i, existRRSet := range this.rrsets {
+				if existRRSet.Name == rrset.Name && existRRSet.Type == rrset.Type {
+					index = i
+					break
+				}
+			}
+			switch rrset.ChangeType {
+			case powerdns.ChangeTypeReplace:
+				if rrset.TTL <= 0 {
+					this.writeError(writer, http.StatusUnprocessableEntity, "TTL is required")
+					return
+				}
+				rrset.ChangeType = ""
+				if index >= 0 {
+					this.rrsets[index] = rrset
+				} else {
+					this.rrsets = append(this.rrsets, rrset)
+				}
+			case powerdns.ChangeTypeDelete:
+				if index >= 0 {
+					this.rrsets = append(this.rrsets[:index], this.rrsets[index+1:]...)
+				}
+			default:
+				this.writeError(writer, http.StatusUnprocessableEntity, "invalid changetype")
+				return
+			}
+		}
+		writer.WriteHeader(http.StatusNoContent)
+	default:
+		this.writeError(writer, http.StatusNotFound, "Not Found")
+	}
+}
+
+func (this *testPowerDNSServer) writeError(writer http.ResponseWriter, statusCode int, message string) {
+	writer.WriteHeader(statusCode)
+	data, _ := json.Marshal(&powerdns.ErrorResponse{Error: message})
+	_, _ = writer.Write(data)
+}
+
+func (this *testPowerDNSServer) findRRSet(name string, recordType string) *powerdns.RRSet {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	for _, rrset := range this.rrsets {
+		if rrset.Name == name && rrset.Type == recordType {
+			return &rrset
+		}
+	}
+	return nil
+}
+
+func TestPowerDNSProvider_GetDomains(t *testing.T) {
+	provider, _ := newTestPowerDNSProvider(t)
+	domains, err := provider.GetDomains()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(domains) != 1 || domains[0] != "example.com" {
+		t.Fatal("unexpected domains:", domains)
+	}
+}
+
+func TestPowerDNSProvider_GetRecords(t *testing.T) {
+	provider, _ := newTestPowerDNSProvider(t)
+	records, err := provider.GetRecords("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// SOA和禁用的记录会被忽略
+	if len(records) != 1 {
+		t.Fatal("expect 1 record, but got", len(records))
+	}
+	if records[0].Name != "old" || records[0].Value != "10.0.0.1" || records[0].TTL != 300 {
+		t.Fatal("unexpected record:", records[0])
+	}
+}
+
+func TestPowerDNSProvider_Records(t *testing.T) {
+	provider, server := newTestPowerDNSProvider(t)
+
+	// 添加
+	for _, record := range []*dnstypes.Record{
+		{Name: "www", Type: dnstypes.RecordTypeA, Value: "192.168.1.100", TTL: 60},
+		{Name: "www", Type: dnstypes.RecordTypeA, Value: "192.168.1.101", TTL: 60},
+		{Name: "cdn", Type: dnstypes.RecordTypeCNAME, Value: "www.example.com"},
+		{Name: "@", Type: dnstypes.RecordTypeTXT, Value: "hello world", TTL: 60},
+	} {
+		err := provider.AddRecord("example.com", record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(record.Id) == 0 {
+			t.Fatal("record id should not be empty")
+		}
+	}
+
+	{
+		var rrset = server.findRRSet("cdn.example.com.", dnstypes.RecordTypeCNAME)
+		if rrset == nil || rrset.TTL != dnsclients.PowerDNSDefaultTTL || rrset.Records[0].Content != "www.example.com." {
+			t.Fatal("unexpected rrset:", rrset)
+		}
+	}
+	{
+		var rrset = server.findRRSet("example.com.", dnstypes.RecordTypeTXT)
+		if rrset == nil || rrset.Records[0].Content != `"hello world"` {
+			t.Fatal("unexpected rrset:", rrset)
+		}
+	}
+
+	// 查询
+	{
+		records, err := provider.QueryRecords("example.com", "www", dnstypes.RecordTypeA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 2 {
+			t.Fatal("expect 2 records, but got", len(records))
+		}
+	}
+	{
+		record, err := provider.QueryRecord("example.com", "@", dnstypes.RecordTypeTXT)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record == nil || record.Name != "@" || record.Value != "hello world" {
+			t.Fatal("unexpected record:", record)
+		}
+	}
+	{
+		record, err := provider.QueryRecord("example.com", "none", dnstypes.RecordTypeA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record != nil {
+			t.Fatal("record should be nil")
+		}
+	}
+
+	// 修改
+	{
+		var record = &dnstypes.Record{Name: "www", Type: dnstypes.RecordTypeA, Value: "192.168.1.100"}
+		var newRecord = &dnstypes.Record{Name: "www", Type: dnstypes.RecordTypeA, Value: "192.168.1.102", TTL: 60}
+		err := provider.UpdateRecord("example.com", record, newRecord)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := provider.QueryRecords("example.com", "www", dnstypes.RecordTypeA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var values = []string{}
+		for _, record := range records {
+			values = append(values, record.Value)
+		}
+		sort.Strings(values)
+		if strings.Join(values, ",") != "192.168.1.101,192.168.1.102" {
+			t.Fatal("unexpected values:", values)
+		}
+	}
+
+	// 修改名称
+	{
+		var record = &dnstypes.Record{Name: "cdn", Type: dnstypes.RecordTypeCNAME, Value: "www.example.com"}
+		var newRecord = &dnstypes.Record{Name: "cdn2", Type: dnstypes.RecordTypeCNAME, Value: "www.example.com", TTL: 60}
+		err := provider.UpdateRecord("example.com", record, newRecord)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if server.findRRSet("cdn.example.com.", dnstypes.RecordTypeCNAME) != nil {
+			t.Fatal("old rrset should be deleted")
+		}
+		if server.findRRSet("cdn2.example.com.", dnstypes.RecordTypeCNAME) == nil {
+			t.Fatal("new rrset should be created")
+		}
+	}
+
+	// 删除
+	{
+		err := provider.DeleteRecord("example.com", &dnstypes.Record{Name: "www", Type: dnstypes.RecordTypeA, Value: "192.168.1.101"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var rrset = server.findRRSet("www.example.com.", dnstypes.RecordTypeA)
+		if rrset == nil || len(rrset.Records) != 1 {
+			t.Fatal("unexpected rrset:", rrset)
+		}
+
+		err = provider.DeleteRecord("example.com", &dnstypes.Record{Name: "www", Type: dnstypes.RecordTypeA, Value: "192.168.1.102"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if server.findRRSet("www.example.com.", dnstypes.RecordTypeA) != nil {
+			t.Fatal("rrset should be deleted")
+		}
+
+		// 删除不存在的记录
+		err = provider.DeleteRecord("example.com", &dnstypes.Record{Name: "www", Type: dnstypes.RecordTypeA, Value: "192.168.1.102"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPowerDNSProvider_StructuredRecords(t *testing.T) {
+	provider, server := newTestPowerDNSProvider(t)
+
+	for _, record := range []*dnstypes.Record{
+		{Name: "@", Type: dnstypes.RecordTypeMX, Value: "mx1.example.com", Priority: 10},
+		{Name: "@", Type: dnstypes.RecordTypeMX, Value: "mx2.example.com", Priority: 20},
+		{Name: "_sip._tcp", Type: dnstypes.RecordTypeSRV, Value: "sip.example.com", Priority: 10, Weight: 5, Port: 5060},
+		{Name: "@", Type: dnstypes.RecordTypeCAA, Value: "letsencrypt.org", Tag: "issue"},
+	} {
+		err := provider.AddRecord("example.com", record)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	{
+		var rrset = server.findRRSet("example.com.", dnstypes.RecordTypeCAA)
+		if rrset == nil || rrset.Records[0].Content != `0 issue "letsencrypt.org"` {
+			t.Fatal("unexpected rrset:", rrset)
+		}
+	}
+
+	{
+		records, err := provider.QueryRecords("example.com", "@", dnstypes.RecordTypeMX)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 2 || records[0].Priority != 10 || records[1].Priority != 20 {
+			t.Fatal("unexpected MX records:", records)
+		}
+	}
+	{
+		record, err := provider.QueryRecord("example.com", "_sip._tcp", dnstypes.RecordTypeSRV)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record == nil || record.Priority != 10 || record.Weight != 5 || record.Port != 5060 || record.Value != "sip.example.com." {
+			t.Fatal("unexpected SRV record:", record)
+		}
+	}
+
+	// 修改MX优先级
+	err := provider.UpdateRecord("example.com", &dnstypes.Record{Name: "@", Type: dnstypes.RecordTypeMX, Value: "mx1.example.com", Priority: 10}, &dnstypes.Record{Name: "@", Type: dnstypes.RecordTypeMX, Value: "mx1.example.com", Priority: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rrset = server.findRRSet("example.com.", dnstypes.RecordTypeMX)
+	if rrset == nil || len(rrset.Records) != 2 || rrset.Records[1].Content != "30 mx1.example.com." {
+		t.Fatal("unexpected rrset:", rrset)
+	}
+}
+
+func TestPowerDNSProvider_Error(t *testing.T) {
+	var server = httptest.NewServer(&testPowerDNSServer{})
+	defer server.Close()
+
+	var provider = &dnsclients.PowerDNSProvider{}
+	err := provider.Auth(maps.Map{
+		"apiURL": server.URL,
+		"apiKey": "invalid-key",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = provider.GetDomains()
+	if err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Fatal("expect 'Unauthorized' error, but got", err)
+	}
+
+	err = provider.Auth(maps.Map{
+		"apiURL": "ftp://127.0.0.1",
+		"apiKey": testPowerDNSAPIKey,
+	})
+	if err == nil {
+		t.Fatal("should return error for invalid api url")
+	}
+}
@@ -0,0 +1,473 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package dnsclients
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/dnsclients/dnstypes"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/iwind/TeaGo/maps"
+	"github.com/iwind/TeaGo/types"
+	"github.com/miekg/dns"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	RFC2136DefaultTTL     = 600
+	RFC2136DefaultTimeout = 10 * time.Second
+	RFC2136TSIGFudge      = 300
+)
+
+// RFC2136Provider 使用RFC 2136动态更新协议管理的DNS服务器，比如BIND、Knot、PowerDNS等
+type RFC2136Provider struct {
+	BaseProvider
+
+	ProviderId int64
+
+	server       string   // 服务器地址，host:port
+	network      string   // udp|tcp
+	domains      []string // 管理的域名
+	keyName      string   // TSIG密钥名称
+	algorithm    string   // TSIG算法
+	secret       string   // TSIG密钥，Base64编码
+	defaultRoute string   // 默认线路
+	timeout      time.Duration
+}
+
+// Auth 认证
+// 参数：
+//   - server 服务器地址，比如 ns1.example.com:53
+//   - network 网络协议，udp或tcp，默认为udp
+//   - domains 管理的域名，可以是数组或用逗号、换行分隔的字符串
+//   - tsigKeyName TSIG密钥名称
+//   - tsigAlgorithm TSIG算法，hmac-sha256或hmac-sha512，默认为hmac-sha256
+//   - tsigSecret TSIG密钥，Base64编码
+//   - defaultRoute 默认线路
+//   - timeout 超时时间，单位为秒
+func (this *RFC2136Provider) Auth(params maps.Map) error {
+	var server = strings.TrimSpace(params.GetString("server"))
+	if len(server) == 0 {
+		return errors.New("'server' should not be empty")
+	}
+	_, _, err := net.SplitHostPort(server)
+	if err != nil {
+		server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+	}
+	this.server = server
+
+	this.network = params.GetString("network")
+	switch this.network {
+	case "":
+		this.network = "udp"
+	case "udp", "tcp":
+	default:
+		return errors.New("invalid network '" + this.network + "'")
+	}
+
+	this.domains = this.decodeDomains(params.Get("domains"))
+	if len(this.domains) == 0 {
+		return errors.New("'domains' should not be empty")
+	}
+
+	this.keyName = strings.TrimSpace(params.GetString("tsigKeyName"))
+	this.secret = strings.TrimSpace(params.GetString("tsigSecret"))
+	if len(this.keyName) > 0 {
+		if len(this.secret) == 0 {
+			return errors.New("'tsigSecret' should not be empty")
+		}
+		this.keyName = dns.Fqdn(this.keyName)
+
+		switch strings.ToLower(strings.TrimSuffix(params.GetString("tsigAlgorithm"), ".")) {
+		case "", "hmac-sha256":
+			this.algorithm = dns.HmacSHA256
+		case "hmac-sha512":
+			this.algorithm = dns.HmacSHA512
+		default:
+			return errors.New("invalid 'tsigAlgorithm' '" + params.GetString("tsigAlgorithm") + "'")
+		}
+	}
+
+	this.defaultRoute = params.GetString("defaultRoute")
+
+	this.timeout = RFC2136DefaultTimeout
+	var timeoutSeconds = params.GetInt("timeout")
+	if timeoutSeconds > 0 {
+		this.timeout = time.Duration(timeoutSeconds) * time.Second
+	}
+
+	return nil
+}
+
+// MaskParams 对参数进行掩码
+func (this *RFC2136Provider) MaskParams(params maps.Map) {
+	if params == nil {
+		return
+	}
+	params["tsigSecret"] = MaskString(params.GetString("tsigSecret"))
+}
+
+// GetDomains 获取所有域名列表
+// RFC 2136 不支持列出域名，所以返回参数中设置的域名
+func (this *RFC2136Provider) GetDomains() (domains []string, err error) {
+	return this.domains, nil
+}
+
+// GetRecords 获取域名解析记录列表
+// 通过AXFR获取，需要服务器允许当前密钥进行区域传送
+func (this *RFC2136Provider) GetRecords(domain string) (records []*dnstypes.Record, err error) {
+	var msg = &dns.Msg{}
+	msg.SetAxfr(dns.Fqdn(domain))
+	this.signMsg(msg)
+
+	var transfer = &dns.Transfer{
+		DialTimeout:  this.timeout,
+		ReadTimeout:  this.timeout,
+		WriteTimeout: this.timeout,
+		TsigSecret:   this.tsigSecret(),
+	}
+	envelopes, err := transfer.In(msg, this.server)
+	if err != nil {
+		return nil, err
+	}
+	for envelope := range envelopes {
+		if envelope.Error != nil {
+			return nil, envelope.Error
+		}
+		for _, rr := range envelope.RR {
+			var record = this.convertRR(domain, rr)
+			if record != nil {
+				records = append(records, record)
+			}
+		}
+	}
+	return
+}
+
+// GetRoutes 读取域名支持的线路数据
+func (this *RFC2136Provider) GetRoutes(domain string) (routes []*dnstypes.Route, err error) {
+	if len(this.defaultRoute) == 0 {
+		return nil, nil
+	}
+	return []*dnstypes.Route{
+		{
+			Name: "默认",
+			Code: this.defaultRoute,
+		},
+	}, nil
+}
+
+// QueryRecord 查询单个记录
+func (this *RFC2136Provider) QueryRecord(domain string, name string, recordType dnstypes.RecordType) (*dnstypes.Record, error) {
+	records, err := this.QueryRecords(domain, name, recordType)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > 0 {
+		return records[0], nil
+	}
+	return nil, nil
+}
+
+// QueryRecords 查询多个记录
+func (this *RFC2136Provider) QueryRecords(domain string, name string, recordType dnstypes.RecordType) ([]*dnstypes.Record, error) {
+	rrType, ok := dns.StringToType[recordType]
+	if !ok {
+		return nil, dnstypes.NewUnsupportedRecordTypeError(ProviderTypeRFC2136, recordType)
+	}
+
+	var msg = &dns.Msg{}
+	msg.SetQuestion(this.fqdn(domain, name), rrType)
+	msg.RecursionDesired = false
+
+	resp, err := this.exchange(msg, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var result = []*dnstypes.Record{}
+	for _, rr := range resp.Answer {
+		if rr.Header().Rrtype != rrType {
+			continue
+		}
+		var record = this.convertRR(domain, rr)
+		if record != nil {
+			result = append(result, record)
+		}
+	}
+	return result, nil
+}
+
+// AddRecord 设置记录
+func (this *RFC2136Provider) AddRecord(domain string, newRecord *dnstypes.Record) error {
+	rr, err := this.buildRR(domain, newRecord)
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
+	}
+
+	var msg = &dns.Msg{}
+	msg.SetUpdate(dns.Fqdn(domain))
+	msg.Insert([]dns.RR{rr})
+
+	_, err = this.exchange(msg, false)
+	return this.WrapError(err, domain, newRecord)
+}
+
+// UpdateRecord 修改记录
+// 在同一个UPDATE消息中删除老的记录并加入新的记录
+func (this *RFC2136Provider) UpdateRecord(domain string, record *dnstypes.Record, newRecord *dnstypes.Record) error {
+	oldRR, err := this.buildRR(domain, record)
+	if err != nil {
+		return this.WrapError(err, domain, record)
+	}
+	newRR, err := this.buildRR(domain, newRecord)
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
+	}
+
+	var msg = &dns.Msg{}
+	msg.SetUpdate(dns.Fqdn(domain))
+	msg.Remove([]dns.RR{oldRR})
+	msg.Insert([]dns.RR{newRR})
+
+	_, err = this.exchange(msg, false)
+	return this.WrapError(err, domain, newRecord)
+}
+
+// DeleteRecord 删除记录
+func (this *RFC2136Provider) DeleteRecord(domain string, record *dnstypes.Record) error {
+	rr, err := this.buildRR(domain, record)
+	if err != nil {
+		return this.WrapError(err, domain, record)
+	}
+
+	var msg = &dns.Msg{}
+	msg.SetUpdate(dns.Fqdn(domain))
+	msg.Remove([]dns.RR{rr})
+
+	_, err = this.exchange(msg, false)
+	return this.WrapError(err, domain, record)
+}
+
+// DefaultRoute 默认线路
+func (this *RFC2136Provider) DefaultRoute() string {
+	return this.defaultRoute
+}
+
+// 发送消息
+func (this *RFC2136Provider) exchange(msg *dns.Msg, allowNXDomain bool) (*dns.Msg, error) {
+	this.signMsg(msg)
+
+	var client = &dns.Client{
+		Net:        this.network,
+		Timeout:    this.timeout,
+		TsigSecret: this.tsigSecret(),
+	}
+	resp, _, err := client.Exchange(msg, this.server)
+	if err != nil {
+		return nil, err
+	}
+
+	// UDP响应被截断时使用TCP重试
+	if resp.Truncated && this.network == "udp" {
+		client.Net = "tcp"
+		resp, _, err = client.Exchange(msg, this.server)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.Rcode == dns.RcodeSuccess || (allowNXDomain && resp.Rcode == dns.RcodeNameError) {
+		return resp, nil
+	}
+	return nil, errors.New("server responded with '" + dns.RcodeToString[resp.Rcode] + "'")
+}
+
+// 使用TSIG签名消息
+func (this *RFC2136Provider) signMsg(msg *dns.Msg) {
+	if len(this.keyName) == 0 {
+		return
+	}
+	msg.SetTsig(this.keyName, this.algorithm, RFC2136TSIGFudge, time.Now().Unix())
+}
+
+func (this *RFC2136Provider) tsigSecret() map[string]string {
+	if len(this.keyName) == 0 {
+		return nil
+	}
+	return map[string]string{
+		this.keyName: this.secret,
+	}
+}
+
+// 将记录转换为RR
+func (this *RFC2136Provider) buildRR(domain string, record *dnstypes.Record) (dns.RR, error) {
+	if record == nil {
+		return nil, errors.New("record should not be nil")
+	}
+
+	var ttl = record.TTL
+	if ttl <= 0 {
+		ttl = RFC2136DefaultTTL
+	}
+	if this.MinTTL() > 0 && ttl < this.MinTTL() {
+		ttl = this.MinTTL()
+	}
+
+	var header = dns.RR_Header{
+		Name:  this.fqdn(domain, record.Name),
+		Class: dns.ClassINET,
+		Ttl:   uint32(ttl),
+	}
+
+	switch record.Type {
+	case dnstypes.RecordTypeA:
+		var ip = net.ParseIP(record.Value)
+		if ip == nil || ip.To4() == nil {
+			return nil, errors.New("invalid ipv4 '" + record.Value + "'")
+		}
+		header.Rrtype = dns.TypeA
+		return &dns.A{Hdr: header, A: ip.To4()}, nil
+	case dnstypes.RecordTypeAAAA:
+		var ip = net.ParseIP(record.Value)
+		if ip == nil || ip.To4() != nil {
+			return nil, errors.New("invalid ipv6 '" + record.Value + "'")
+		}
+		header.Rrtype = dns.TypeAAAA
+		return &dns.AAAA{Hdr: header, AAAA: ip}, nil
+	case dnstypes.RecordTypeCNAME:
+		header.Rrtype = dns.TypeCNAME
+		return &dns.CNAME{Hdr: header, Target: dns.Fqdn(record.Value)}, nil
+	case dnstypes.RecordTypeTXT:
+		header.Rrtype = dns.TypeTXT
+		return &dns.TXT{Hdr: header, Txt: this.splitTXT(record.Value)}, nil
+	case dnstypes.RecordTypeMX:
+		header.Rrtype = dns.TypeMX
+		return &dns.MX{Hdr: header, Preference: uint16(record.Priority), Mx: dns.Fqdn(record.Value)}, nil
+	case dnstypes.RecordTypeSRV:
+		header.Rrtype = dns.TypeSRV
+		return &dns.SRV{Hdr: header, Priority: uint16(record.Priority), Weight: uint16(record.Weight), Port: uint16(record.Port), Target: dns.Fqdn(record.Value)}, nil
+	case dnstypes.RecordTypeCAA:
+		if len(record.Tag) == 0 {
+			return nil, errors.New("'tag' should not be empty for CAA record")
+		}
+		header.Rrtype = dns.TypeCAA
+		return &dns.CAA{Hdr: header, Flag: uint8(record.Flags), Tag: record.Tag, Value: record.Value}, nil
+	case dnstypes.RecordTypeNS:
+		header.Rrtype = dns.TypeNS
+		return &dns.NS{Hdr: header, Ns: dns.Fqdn(record.Value)}, nil
+	case dnstypes.RecordTypePTR:
+		header.Rrtype = dns.TypePTR
+		return &dns.PTR{Hdr: header, Ptr: dns.Fqdn(record.Value)}, nil
+	}
+	return nil, dnstypes.NewUnsupportedRecordTypeError(ProviderTypeRFC2136, record.Type)
+}
+
+// 将RR转换为记录，不支持的类型返回nil
+func (this *RFC2136Provider) convertRR(domain string, rr dns.RR) *dnstypes.Record {
+	var header = rr.Header()
+	var record = &dnstypes.Record{
+		Name:  this.relativeName(domain, header.Name),
+		Route: this.defaultRoute,
+		TTL:   types.Int32(header.Ttl),
+	}
+
+	switch v := rr.(type) {
+	case *dns.A:
+		record.Type = dnstypes.RecordTypeA
+		record.Value = v.A.String()
+	case *dns.AAAA:
+		record.Type = dnstypes.RecordTypeAAAA
+		record.Value = v.AAAA.String()
+	case *dns.CNAME:
+		record.Type = dnstypes.RecordTypeCNAME
+		record.Value = v.Target
+	case *dns.TXT:
+		record.Type = dnstypes.RecordTypeTXT
+		record.Value = strings.Join(v.Txt, "")
+	case *dns.MX:
+		record.Type = dnstypes.RecordTypeMX
+		record.Value = v.Mx
+		record.Priority = int32(v.Preference)
+	case *dns.SRV:
+		record.Type = dnstypes.RecordTypeSRV
+		record.Value = v.Target
+		record.Priority = int32(v.Priority)
+		record.Weight = int32(v.Weight)
+		record.Port = int32(v.Port)
+	case *dns.CAA:
+		record.Type = dnstypes.RecordTypeCAA
+		record.Value = v.Value
+		record.Flags = int32(v.Flag)
+		record.Tag = v.Tag
+	case *dns.NS:
+		record.Type = dnstypes.RecordTypeNS
+		record.Value = v.Ns
+	case *dns.PTR:
+		record.Type = dnstypes.RecordTypePTR
+		record.Value = v.Ptr
+	default:
+		return nil
+	}
+
+	// RFC 2136 中没有记录ID，这里使用记录内容作为ID
+	record.Id = record.Name + "$" + record.Type + "$" + record.FormatContent()
+	return record
+}
+
+// 完整域名
+func (this *RFC2136Provider) fqdn(domain string, name string) string {
+	domain = strings.TrimSuffix(domain, ".")
+	if len(name) == 0 || name == "@" {
+		return dns.Fqdn(domain)
+	}
+	return dns.Fqdn(name + "." + domain)
+}
+
+// 相对于域名的记录名，顶级记录使用 @
+func (this *RFC2136Provider) relativeName(domain string, fqdn string) string {
+	var name = strings.TrimSuffix(strings.ToLower(fqdn), ".")
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	if name == domain {
+		return "@"
+	}
+	return strings.TrimSuffix(name, "."+domain)
+}
+
+// TXT记录中单个字符串最长255字节
+func (this *RFC2136Provider) splitTXT(value string) []string {
+	var result = []string{}
+	for len(value) > 255 {
+		result = append(result, value[:255])
+		value = value[255:]
+	}
+	return append(result, value)
+}
+
+func (this *RFC2136Provider) decodeDomains(value any) []string {
+	var result = []string{}
+	var add = func(domain string) {
+		domain = strings.TrimSuffix(strings.TrimSpace(domain), ".")
+		if len(domain) > 0 {
+			result = append(result, domain)
+		}
+	}
+
+	switch v := value.(type) {
+	case []string:
+		for _, domain := range v {
+			add(domain)
+		}
+	case []any:
+		for _, domain := range v {
+			add(types.String(domain))
+		}
+	case string:
+		for _, domain := range strings.FieldsFunc(v, func(r rune) bool {
+			return r == ',' || r == '\n' || r == '\r' || r == ' '
+		}) {
+			add(domain)
+		}
+	}
+	return result
+}
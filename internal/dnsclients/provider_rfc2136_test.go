@@ -0,0 +1,314 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package dnsclients_test
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/dnsclients"
+	"github.com/dashenmiren/EdgeAPI/internal/dnsclients/dnstypes"
+	"github.com/iwind/TeaGo/maps"
+	"github.com/miekg/dns"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testRFC2136Zone      = "example.com."
+	testRFC2136KeyName   = "edge-key."
+	testRFC2136KeySecret = "c2VjcmV0LXNlY3JldC1zZWNyZXQtc2VjcmV0LXNlY3JldA=="
+)
+
+// 模拟支持RFC 2136的DNS服务器
+type testRFC2136Server struct {
+	server *dns.Server
+	addr   string
+
+	records []dns.RR
+	locker  sync.Mutex
+}
+
+func newTestRFC2136Server(t *testing.T) *testRFC2136Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var s = &testRFC2136Server{
+		addr: listener.Addr().String(),
+	}
+	soa, _ := dns.NewRR(testRFC2136Zone + " 3600 IN SOA ns1.example.com. admin.example.com. 1 3600 600 86400 600")
+	s.records = append(s.records, soa)
+
+	var started = make(chan bool)
+	s.server = &dns.Server{
+		Listener:          listener,
+		Handler:           s,
+		TsigSecret:        map[string]string{testRFC2136KeyName: testRFC2136KeySecret},
+		NotifyStartedFunc: func() { close(started) },
+		MsgAcceptFunc: func(dh dns.Header) dns.MsgAcceptAction {
+			return dns.MsgAccept // 默认不接受UPDATE消息
+		},
+	}
+	go func() {
+		_ = s.server.ActivateAndServe()
+	}()
+	<-started
+
+	t.Cleanup(func() {
+		_ = s.server.Shutdown()
+	})
+	return s
+}
+
+func (this *testRFC2136Server) ServeDNS(writer dns.ResponseWriter, req *dns.Msg) {
+	var resp = &dns.Msg{}
+	resp.SetReply(req)
+
+	if req.IsTsig() == nil || writer.TsigStatus() != nil {
+		resp.Rcode = dns.RcodeNotAuth
+		_ = writer.WriteMsg(resp)
+		return
+	}
+	resp.SetTsig(testRFC2136KeyName, req.IsTsig().Algorithm, 300, time.Now().Unix())
+
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	if req.Opcode == dns.OpcodeUpdate {
+		for _, rr := range req.Ns {
+			switch rr.Header().Class {
+			case dns.ClassINET:
+				this.records = append(this.records, rr)
+			case dns.ClassNONE:
+				var newRecords = []dns.RR{}
+				for _, record := range this.records {
+					var copyRR = dns.Copy(rr)
+					copyRR.Header().Class = dns.ClassINET
+					if !dns.IsDuplicate(record, copyRR) {
+						newRecords = append(newRecords, record)
+					}
+				}
+				this.records = newRecords
+			}
+		}
+		_ = writer.WriteMsg(resp)
+		return
+	}
+
+	var question = req.Question[0]
+	if question.Qtype == dns.TypeAXFR {
+		resp.Answer = append(resp.Answer, this.records...)
+		resp.Answer = append(resp.Answer, this.records[0])
+		_ = writer.WriteMsg(resp)
+		return
+	}
+
+	for _, record := range this.records {
+		if record.Header().Name == question.Name && record.Header().Rrtype == question.Qtype {
+			resp.Answer = append(resp.Answer, record)
+		}
+	}
+	_ = writer.WriteMsg(resp)
+}
+
+func testRFC2136Provider(t *testing.T, addr string, secret string) *dnsclients.RFC2136Provider {
+	var provider = &dnsclients.RFC2136Provider{}
+	err := provider.Auth(maps.Map{
+		"server":        addr,
+		"network":       "tcp",
+		"domains":       "example.com",
+		"tsigKeyName":   "edge-key",
+		"tsigAlgorithm": "hmac-sha256",
+		"tsigSecret":    secret,
+		"defaultRoute":  "default",
+		"timeout":       5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestRFC2136Provider_Records(t *testing.T) {
+	var server = newTestRFC2136Server(t)
+	var provider = testRFC2136Provider(t, server.addr, testRFC2136KeySecret)
+
+	domains, err := provider.GetDomains()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(domains) != 1 || domains[0] != "example.com" {
+		t.Fatal("invalid domains", domains)
+	}
+
+	// add
+	err = provider.AddRecord("example.com", &dnstypes.Record{
+		Name:  "www",
+		Type:  dnstypes.RecordTypeA,
+		Value: "192.168.1.100",
+		TTL:   300,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = provider.AddRecord("example.com", &dnstypes.Record{
+		Name:  "cdn",
+		Type:  dnstypes.RecordTypeCNAME,
+		Value: "cdn.example.net",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// query
+	record, err := provider.QueryRecord("example.com", "www", dnstypes.RecordTypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record == nil || record.Value != "192.168.1.100" || record.TTL != 300 || record.Route != "default" {
+		t.Fatal("invalid record", record)
+	}
+
+	// update
+	err = provider.UpdateRecord("example.com", record, &dnstypes.Record{
+		Name:  "www",
+		Type:  dnstypes.RecordTypeA,
+		Value: "192.168.1.101",
+		TTL:   300,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// axfr
+	records, err := provider.GetRecords("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range records {
+		t.Log(r.Name, r.Type, r.Value, r.TTL)
+	}
+	if len(records) != 2 {
+		t.Fatal("expect 2 records, but got", len(records))
+	}
+	if records[0].Value != "cdn.example.net." || records[1].Value != "192.168.1.101" {
+		t.Fatal("invalid records")
+	}
+
+	// delete
+	err = provider.DeleteRecord("example.com", records[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err = provider.QueryRecords("example.com", "www", dnstypes.RecordTypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Fatal("record should be deleted")
+	}
+}
+
+func TestRFC2136Provider_StructuredRecords(t *testing.T) {
+	var server = newTestRFC2136Server(t)
+	var provider = testRFC2136Provider(t, server.addr, testRFC2136KeySecret)
+
+	for _, record := range []*dnstypes.Record{
+		{Name: "@", Type: dnstypes.RecordTypeMX, Value: "mail.example.com", Priority: 10},
+		{Name: "_sip._tcp", Type: dnstypes.RecordTypeSRV, Value: "sip.example.com", Priority: 10, Weight: 5, Port: 5060},
+		{Name: "@", Type: dnstypes.RecordTypeCAA, Value: "letsencrypt.org", Tag: "issue"},
+		{Name: "sub", Type: dnstypes.RecordTypeNS, Value: "ns1.example.net"},
+	} {
+		err := provider.AddRecord("example.com", record)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mxRecord, err := provider.QueryRecord("example.com", "@", dnstypes.RecordTypeMX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mxRecord == nil || mxRecord.Priority != 10 || mxRecord.Value != "mail.example.com." {
+		t.Fatal("invalid MX record", mxRecord)
+	}
+
+	srvRecord, err := provider.QueryRecord("example.com", "_sip._tcp", dnstypes.RecordTypeSRV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srvRecord == nil || srvRecord.Priority != 10 || srvRecord.Weight != 5 || srvRecord.Port != 5060 || srvRecord.Value != "sip.example.com." {
+		t.Fatal("invalid SRV record", srvRecord)
+	}
+
+	caaRecord, err := provider.QueryRecord("example.com", "@", dnstypes.RecordTypeCAA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caaRecord == nil || caaRecord.Flags != 0 || caaRecord.Tag != "issue" || caaRecord.Value != "letsencrypt.org" {
+		t.Fatal("invalid CAA record", caaRecord)
+	}
+
+	// 修改MX优先级
+	err = provider.UpdateRecord("example.com", mxRecord, &dnstypes.Record{Name: "@", Type: dnstypes.RecordTypeMX, Value: "mail.example.com", Priority: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mxRecords, err := provider.QueryRecords("example.com", "@", dnstypes.RecordTypeMX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mxRecords) != 1 || mxRecords[0].Priority != 20 {
+		t.Fatal("invalid MX records", mxRecords)
+	}
+
+	records, err := provider.GetRecords("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Fatal("expect 4 records, but got", len(records))
+	}
+
+	// CAA记录必须有Tag
+	err = provider.AddRecord("example.com", &dnstypes.Record{Name: "@", Type: dnstypes.RecordTypeCAA, Value: "letsencrypt.org"})
+	if err == nil {
+		t.Fatal("should return error for CAA record without tag")
+	}
+
+	// 不支持的记录类型
+	err = provider.AddRecord("example.com", &dnstypes.Record{Name: "@", Type: "SSHFP", Value: "1 1 123456789abcdef67890123456789abcdef67890"})
+	if !dnstypes.IsUnsupportedRecordTypeError(err) {
+		t.Fatal("expect unsupported record type error, but got", err)
+	}
+}
+
+func TestRFC2136Provider_BadSecret(t *testing.T) {
+	var server = newTestRFC2136Server(t)
+	var provider = testRFC2136Provider(t, server.addr, "YmFkLXNlY3JldA==")
+
+	err := provider.AddRecord("example.com", &dnstypes.Record{
+		Name:  "www",
+		Type:  dnstypes.RecordTypeA,
+		Value: "192.168.1.100",
+	})
+	if err == nil {
+		t.Fatal("should be failed with bad secret")
+	}
+	t.Log(err)
+}
+
+func TestRFC2136Provider_Routes(t *testing.T) {
+	var provider = testRFC2136Provider(t, "127.0.0.1", testRFC2136KeySecret)
+	if provider.DefaultRoute() != "default" {
+		t.Fatal("invalid default route")
+	}
+	routes, err := provider.GetRoutes("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 1 || routes[0].Code != "default" {
+		t.Fatal("invalid routes")
+	}
+}
@@ -0,0 +1,739 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package dnsclients
+
+import (
+	"bytes"
+	"encoding/xml"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	teaconst "github.com/dashenmiren/EdgeAPI/internal/const"
+	"github.com/dashenmiren/EdgeAPI/internal/dnsclients/dnstypes"
+	"github.com/dashenmiren/EdgeAPI/internal/dnsclients/route53"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/iwind/TeaGo/maps"
+	"github.com/iwind/TeaGo/types"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	Route53DefaultEndpoint = "https://route53.amazonaws.com"
+	Route53DefaultRoute    = "default"
+	Route53DefaultTTL      = 300
+	Route53APIVersion      = "2013-04-01"
+)
+
+var route53HTTPClient = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+// Route53Provider AWS Route 53以及兼容Route 53 API的DNS服务
+// 线路代号：
+//   - default 简单路由
+//   - latency:REGION 延迟路由，比如 latency:ap-northeast-1
+//   - geo:continent:CODE 按大洲路由，比如 geo:continent:AS
+//   - geo:country:CODE[:SUBDIVISION] 按国家/地区路由，比如 geo:country:CN、geo:country:US:CA
+//   - geo:* 地理位置路由中的默认位置
+//   - weight:N 加权路由
+type Route53Provider struct {
+	BaseProvider
+
+	ProviderId int64
+
+	accessKeyId     string
+	accessKeySecret string
+	endpoint        string
+	region          string
+	signer          *v4.Signer
+
+	zoneMap    map[string]string // domain => zoneId
+	zoneLocker sync.Mutex
+}
+
+// Auth 认证
+// 参数：
+//   - accessKeyId
+//   - accessKeySecret
+//   - endpoint 可选，兼容Route 53 API的服务地址
+//   - region 可选，签名使用的区域，默认为 us-east-1
+func (this *Route53Provider) Auth(params maps.Map) error {
+	this.accessKeyId = params.GetString("accessKeyId")
+	if len(this.accessKeyId) == 0 {
+		return errors.New("'accessKeyId' should not be empty")
+	}
+	this.accessKeySecret = params.GetString("accessKeySecret")
+	if len(this.accessKeySecret) == 0 {
+		return errors.New("'accessKeySecret' should not be empty")
+	}
+
+	this.endpoint = strings.TrimSuffix(params.GetString("endpoint"), "/")
+	if len(this.endpoint) == 0 {
+		this.endpoint = Route53DefaultEndpoint
+	}
+	_, err := url.Parse(this.endpoint)
+	if err != nil {
+		return errors.New("invalid endpoint '" + this.endpoint + "'")
+	}
+
+	this.region = params.GetString("region")
+	if len(this.region) == 0 {
+		this.region = route53.DefaultRegion
+	}
+
+	this.signer = v4.NewSigner(credentials.NewStaticCredentials(this.accessKeyId, this.accessKeySecret, ""))
+
+	this.zoneMap = map[string]string{}
+
+	return nil
+}
+
+// MaskParams 对参数进行掩码
+func (this *Route53Provider) MaskParams(params maps.Map) {
+	if params == nil {
+		return
+	}
+	params["accessKeySecret"] = MaskString(params.GetString("accessKeySecret"))
+}
+
+// GetDomains 获取所有域名列表
+func (this *Route53Provider) GetDomains() (domains []string, err error) {
+	var marker = ""
+	for i := 0; i < 1000; i++ {
+		var query = url.Values{}
+		if len(marker) > 0 {
+			query.Set("marker", marker)
+		}
+		var resp = new(route53.ListHostedZonesResponse)
+		err = this.doAPI(http.MethodGet, "/hostedzone", query, nil, resp)
+		if err != nil {
+			return nil, err
+		}
+
+		this.zoneLocker.Lock()
+		for _, zone := range resp.HostedZones {
+			if zone.Config.PrivateZone {
+				continue
+			}
+			var domain = strings.TrimSuffix(zone.Name, ".")
+			this.zoneMap[domain] = this.trimZoneId(zone.Id)
+			domains = append(domains, domain)
+		}
+		this.zoneLocker.Unlock()
+
+		if !resp.IsTruncated || len(resp.NextMarker) == 0 {
+			break
+		}
+		marker = resp.NextMarker
+	}
+	return
+}
+
+// GetRecords 获取域名解析记录列表
+func (this *Route53Provider) GetRecords(domain string) (records []*dnstypes.Record, err error) {
+	zoneId, err := this.findZoneIdWithDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	err = this.walkRecordSets(zoneId, "", "", "", func(recordSet *route53.ResourceRecordSet) bool {
+		records = append(records, this.convertRecordSet(domain, recordSet)...)
+		return true
+	})
+	return
+}
+
+// GetRoutes 读取域名支持的线路数据
+func (this *Route53Provider) GetRoutes(domain string) (routes []*dnstypes.Route, err error) {
+	routes = []*dnstypes.Route{
+		{Name: "默认", Code: Route53DefaultRoute},
+		{Name: "地理位置：默认", Code: "geo:*"},
+	}
+
+	// 大洲
+	for _, continent := range [][2]string{
+		{"AF", "非洲"},
+		{"AN", "南极洲"},
+		{"AS", "亚洲"},
+		{"EU", "欧洲"},
+		{"OC", "大洋洲"},
+		{"NA", "北美洲"},
+		{"SA", "南美洲"},
+	} {
+		routes = append(routes, &dnstypes.Route{
+			Name: "地理位置：" + continent[1],
+			Code: "geo:continent:" + continent[0],
+		})
+	}
+
+	// 常用国家/地区
+	for _, country := range [][2]string{
+		{"CN", "中国"},
+		{"HK", "中国香港"},
+		{"MO", "中国澳门"},
+		{"TW", "中国台湾"},
+		{"JP", "日本"},
+		{"KR", "韩国"},
+		{"SG", "新加坡"},
+		{"IN", "印度"},
+		{"US", "美国"},
+		{"CA", "加拿大"},
+		{"BR", "巴西"},
+		{"GB", "英国"},
+		{"DE", "德国"},
+		{"FR", "法国"},
+		{"RU", "俄罗斯"},
+		{"AU", "澳大利亚"},
+	} {
+		routes = append(routes, &dnstypes.Route{
+			Name: "地理位置：" + country[1],
+			Code: "geo:country:" + country[0],
+		})
+	}
+
+	// 延迟路由区域
+	for _, region := range [][2]string{
+		{"us-east-1", "美国东部（弗吉尼亚北部）"},
+		{"us-east-2", "美国东部（俄亥俄）"},
+		{"us-west-1", "美国西部（加利福尼亚北部）"},
+		{"us-west-2", "美国西部（俄勒冈）"},
+		{"ca-central-1", "加拿大（中部）"},
+		{"sa-east-1", "南美洲（圣保罗）"},
+		{"eu-west-1", "欧洲（爱尔兰）"},
+		{"eu-west-2", "欧洲（伦敦）"},
+		{"eu-west-3", "欧洲（巴黎）"},
+		{"eu-central-1", "欧洲（法兰克福）"},
+		{"eu-north-1", "欧洲（斯德哥尔摩）"},
+		{"ap-east-1", "亚太地区（香港）"},
+		{"ap-northeast-1", "亚太地区（东京）"},
+		{"ap-northeast-2", "亚太地区（首尔）"},
+		{"ap-northeast-3", "亚太地区（大阪）"},
+		{"ap-southeast-1", "亚太地区（新加坡）"},
+		{"ap-southeast-2", "亚太地区（悉尼）"},
+		{"ap-south-1", "亚太地区（孟买）"},
+		{"me-south-1", "中东（巴林）"},
+		{"af-south-1", "非洲（开普敦）"},
+		{"cn-north-1", "中国（北京）"},
+		{"cn-northwest-1", "中国（宁夏）"},
+	} {
+		routes = append(routes, &dnstypes.Route{
+			Name: "延迟：" + region[1],
+			Code: "latency:" + region[0],
+		})
+	}
+
+	return routes, nil
+}
+
+// QueryRecord 查询单个记录
+func (this *Route53Provider) QueryRecord(domain string, name string, recordType dnstypes.RecordType) (*dnstypes.Record, error) {
+	records, err := this.QueryRecords(domain, name, recordType)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > 0 {
+		return records[0], nil
+	}
+	return nil, nil
+}
+
+// QueryRecords 查询多个记录
+func (this *Route53Provider) QueryRecords(domain string, name string, recordType dnstypes.RecordType) ([]*dnstypes.Record, error) {
+	zoneId, err := this.findZoneIdWithDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var fqdn = this.fqdn(domain, name)
+	var result = []*dnstypes.Record{}
+	err = this.walkRecordSets(zoneId, fqdn, recordType, "", func(recordSet *route53.ResourceRecordSet) bool {
+		if this.unescapeName(recordSet.Name) != fqdn || recordSet.Type != recordType {
+			return false
+		}
+		result = append(result, this.convertRecordSet(domain, recordSet)...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// AddRecord 设置记录
+// 如果记录集已经存在，则将新的值加入到记录集中
+func (this *Route53Provider) AddRecord(domain string, newRecord *dnstypes.Record) error {
+	zoneId, err := this.findZoneIdWithDomain(domain)
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
+	}
+
+	changes, err := this.addChanges(zoneId, domain, newRecord, nil)
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
+	}
+	err = this.changeRecordSets(zoneId, changes)
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
+	}
+
+	newRecord.Id = this.recordId(newRecord, this.routeSetIdentifier(newRecord.Route))
+	return nil
+}
+
+// UpdateRecord 修改记录
+// 删除老的值和加入新的值在同一个批次中提交
+func (this *Route53Provider) UpdateRecord(domain string, record *dnstypes.Record, newRecord *dnstypes.Record) error {
+	zoneId, err := this.findZoneIdWithDomain(domain)
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
+	}
+
+	var changes = []route53.Change{}
+	var oldSetIdentifier = this.setIdentifier(record)
+	var newSetIdentifier = this.routeSetIdentifier(newRecord.Route)
+	if record.Name == newRecord.Name && record.Type == newRecord.Type && oldSetIdentifier == newSetIdentifier {
+		// 同一个记录集中替换值
+		changes, err = this.addChanges(zoneId, domain, newRecord, record)
+		if err != nil {
+			return this.WrapError(err, domain, newRecord)
+		}
+	} else {
+		removeChanges, err := this.removeChanges(zoneId, domain, record)
+		if err != nil {
+			return this.WrapError(err, domain, record)
+		}
+		addChanges, err := this.addChanges(zoneId, domain, newRecord, nil)
+		if err != nil {
+			return this.WrapError(err, domain, newRecord)
+		}
+		changes = append(removeChanges, addChanges...)
+	}
+
+	err = this.changeRecordSets(zoneId, changes)
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
+	}
+
+	newRecord.Id = this.recordId(newRecord, newSetIdentifier)
+	return nil
+}
+
+// DeleteRecord 删除记录
+func (this *Route53Provider) DeleteRecord(domain string, record *dnstypes.Record) error {
+	zoneId, err := this.findZoneIdWithDomain(domain)
+	if err != nil {
+		return this.WrapError(err, domain, record)
+	}
+
+	changes, err := this.removeChanges(zoneId, domain, record)
+	if err != nil {
+		return this.WrapError(err, domain, record)
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return this.WrapError(this.changeRecordSets(zoneId, changes), domain, record)
+}
+
+// DefaultRoute 默认线路
+func (this *Route53Provider) DefaultRoute() string {
+	return Route53DefaultRoute
+}
+
+// 加入一个值的修改
+// replaceRecord 不为空时，从记录集中替换此记录的值
+func (this *Route53Provider) addChanges(zoneId string, domain string, newRecord *dnstypes.Record, replaceRecord *dnstypes.Record) ([]route53.Change, error) {
+	if !this.isSupportedType(newRecord.Type) {
+		return nil, dnstypes.NewUnsupportedRecordTypeError(ProviderTypeRoute53, newRecord.Type)
+	}
+
+	var value = this.formatValue(newRecord)
+	var ttl = int64(newRecord.TTL)
+	if ttl <= 0 {
+		ttl = Route53DefaultTTL
+	}
+	if this.MinTTL() > 0 && ttl < int64(this.MinTTL()) {
+		ttl = int64(this.MinTTL())
+	}
+
+	var setIdentifier = this.routeSetIdentifier(newRecord.Route)
+	recordSet, err := this.findRecordSet(zoneId, this.fqdn(domain, newRecord.Name), newRecord.Type, setIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	if recordSet == nil {
+		recordSet, err = this.newRecordSet(domain, newRecord, setIdentifier)
+		if err != nil {
+			return nil, err
+		}
+		recordSet.TTL = ttl
+		recordSet.ResourceRecords = []route53.ResourceRecord{{Value: value}}
+		return []route53.Change{{Action: route53.ChangeActionCreate, ResourceRecordSet: *recordSet}}, nil
+	}
+
+	var values = []route53.ResourceRecord{}
+	var replaceValue = ""
+	if replaceRecord != nil {
+		replaceValue = this.formatValue(replaceRecord)
+	}
+	for _, resourceRecord := range recordSet.ResourceRecords {
+		if resourceRecord.Value == value || (len(replaceValue) > 0 && resourceRecord.Value == replaceValue) {
+			continue
+		}
+		values = append(values, resourceRecord)
+	}
+	values = append(values, route53.ResourceRecord{Value: value})
+	recordSet.ResourceRecords = values
+	recordSet.TTL = ttl
+	return []route53.Change{{Action: route53.ChangeActionUpsert, ResourceRecordSet: *recordSet}}, nil
+}
+
+// 删除一个值的修改
+// 记录集中没有其他值时删除整个记录集
+func (this *Route53Provider) removeChanges(zoneId string, domain string, record *dnstypes.Record) ([]route53.Change, error) {
+	recordSet, err := this.findRecordSet(zoneId, this.fqdn(domain, record.Name), record.Type, this.setIdentifier(record))
+	if err != nil {
+		return nil, err
+	}
+	if recordSet == nil {
+		return nil, nil
+	}
+
+	var value = this.formatValue(record)
+	var values = []route53.ResourceRecord{}
+	for _, resourceRecord := range recordSet.ResourceRecords {
+		if resourceRecord.Value != value {
+			values = append(values, resourceRecord)
+		}
+	}
+	if len(values) == len(recordSet.ResourceRecords) {
+		return nil, nil
+	}
+	if len(values) == 0 {
+		return []route53.Change{{Action: route53.ChangeActionDelete, ResourceRecordSet: *recordSet}}, nil
+	}
+	recordSet.ResourceRecords = values
+	return []route53.Change{{Action: route53.ChangeActionUpsert, ResourceRecordSet: *recordSet}}, nil
+}
+
+// 提交修改
+func (this *Route53Provider) changeRecordSets(zoneId string, changes []route53.Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	var resp = new(route53.ChangeResourceRecordSetsResponse)
+	return this.doAPI(http.MethodPost, "/hostedzone/"+zoneId+"/rrset/", nil, route53.NewChangeResourceRecordSetsRequest("", changes), resp)
+}
+
+// 查找记录集
+func (this *Route53Provider) findRecordSet(zoneId string, fqdn string, recordType string, setIdentifier string) (*route53.ResourceRecordSet, error) {
+	var result *route53.ResourceRecordSet
+	err := this.walkRecordSets(zoneId, fqdn, recordType, setIdentifier, func(recordSet *route53.ResourceRecordSet) bool {
+		if this.unescapeName(recordSet.Name) != fqdn || recordSet.Type != recordType {
+			return false
+		}
+		if recordSet.SetIdentifier == setIdentifier {
+			result = recordSet
+			return false
+		}
+		return true
+	})
+	return result, err
+}
+
+// 遍历记录集，如果回调函数返回false则停止
+func (this *Route53Provider) walkRecordSets(zoneId string, startName string, startType string, startIdentifier string, f func(recordSet *route53.ResourceRecordSet) bool) error {
+	for i := 0; i < 10000; i++ {
+		var query = url.Values{}
+		if len(startName) > 0 {
+			query.Set("name", startName)
+			if len(startType) > 0 {
+				query.Set("type", startType)
+				if len(startIdentifier) > 0 {
+					query.Set("identifier", startIdentifier)
+				}
+			}
+		}
+		var resp = new(route53.ListResourceRecordSetsResponse)
+		err := this.doAPI(http.MethodGet, "/hostedzone/"+zoneId+"/rrset", query, nil, resp)
+		if err != nil {
+			return err
+		}
+		for index := range resp.ResourceRecordSets {
+			if !f(&resp.ResourceRecordSets[index]) {
+				return nil
+			}
+		}
+		if !resp.IsTruncated {
+			break
+		}
+		startName = resp.NextRecordName
+		startType = resp.NextRecordType
+		startIdentifier = resp.NextRecordIdentifier
+	}
+	return nil
+}
+
+// 根据线路创建新的记录集
+func (this *Route53Provider) newRecordSet(domain string, record *dnstypes.Record, setIdentifier string) (*route53.ResourceRecordSet, error) {
+	var recordSet = &route53.ResourceRecordSet{
+		Name:          this.fqdn(domain, record.Name),
+		Type:          record.Type,
+		SetIdentifier: setIdentifier,
+	}
+
+	var route = record.Route
+	switch {
+	case len(route) == 0 || route == Route53DefaultRoute:
+		recordSet.SetIdentifier = ""
+	case strings.HasPrefix(route, "latency:"):
+		recordSet.Region = strings.TrimPrefix(route, "latency:")
+	case route == "geo:*":
+		recordSet.GeoLocation = &route53.GeoLocation{CountryCode: "*"}
+	case strings.HasPrefix(route, "geo:continent:"):
+		recordSet.GeoLocation = &route53.GeoLocation{ContinentCode: strings.TrimPrefix(route, "geo:continent:")}
+	case strings.HasPrefix(route, "geo:country:"):
+		var pieces = strings.SplitN(strings.TrimPrefix(route, "geo:country:"), ":", 2)
+		recordSet.GeoLocation = &route53.GeoLocation{CountryCode: pieces[0]}
+		if len(pieces) == 2 {
+			recordSet.GeoLocation.SubdivisionCode = pieces[1]
+		}
+	case strings.HasPrefix(route, "weight:"):
+		weight, err := strconv.ParseInt(strings.TrimPrefix(route, "weight:"), 10, 64)
+		if err != nil || weight < 0 {
+			return nil, errors.New("invalid route '" + route + "'")
+		}
+		recordSet.Weight = &weight
+	default:
+		return nil, errors.New("invalid route '" + route + "'")
+	}
+	return recordSet, nil
+}
+
+// 将记录集转换为记录
+func (this *Route53Provider) convertRecordSet(domain string, recordSet *route53.ResourceRecordSet) []*dnstypes.Record {
+	// 暂不支持别名记录
+	if recordSet.AliasTarget != nil {
+		return nil
+	}
+
+	if !this.isSupportedType(recordSet.Type) {
+		return nil
+	}
+
+	var route = this.routeCode(recordSet)
+	var name = this.relativeName(domain, this.unescapeName(recordSet.Name))
+	var result = []*dnstypes.Record{}
+	for _, resourceRecord := range recordSet.ResourceRecords {
+		var record = &dnstypes.Record{
+			Name:  name,
+			Type:  recordSet.Type,
+			Route: route,
+			TTL:   types.Int32(recordSet.TTL),
+		}
+		if recordSet.Type == dnstypes.RecordTypeTXT {
+			record.Value = unquoteTXT(resourceRecord.Value)
+		} else if record.ParseContent(resourceRecord.Value) != nil {
+			continue
+		}
+		record.Id = this.recordId(record, recordSet.SetIdentifier)
+		result = append(result, record)
+	}
+	return result
+}
+
+// 记录集对应的线路代号
+func (this *Route53Provider) routeCode(recordSet *route53.ResourceRecordSet) string {
+	switch {
+	case len(recordSet.Region) > 0:
+		return "latency:" + recordSet.Region
+	case recordSet.GeoLocation != nil:
+		var geo = recordSet.GeoLocation
+		if geo.CountryCode == "*" {
+			return "geo:*"
+		}
+		if len(geo.ContinentCode) > 0 {
+			return "geo:continent:" + geo.ContinentCode
+		}
+		if len(geo.SubdivisionCode) > 0 {
+			return "geo:country:" + geo.CountryCode + ":" + geo.SubdivisionCode
+		}
+		return "geo:country:" + geo.CountryCode
+	case recordSet.Weight != nil:
+		return "weight:" + types.String(*recordSet.Weight)
+	}
+	return Route53DefaultRoute
+}
+
+// 记录ID，格式为 TYPE$NAME$SET_IDENTIFIER$CONTENT
+func (this *Route53Provider) recordId(record *dnstypes.Record, setIdentifier string) string {
+	return record.Type + "$" + record.Name + "$" + setIdentifier + "$" + record.FormatContent()
+}
+
+// 已有记录对应的记录集标识
+// 优先使用记录ID中的标识，以便修改其他方式创建的记录集
+func (this *Route53Provider) setIdentifier(record *dnstypes.Record) string {
+	if len(record.Id) > 0 {
+		var pieces = strings.SplitN(record.Id, "$", 4)
+		if len(pieces) == 4 && pieces[0] == record.Type {
+			return pieces[2]
+		}
+	}
+	return this.routeSetIdentifier(record.Route)
+}
+
+// 新记录集使用线路代号作为标识
+func (this *Route53Provider) routeSetIdentifier(route string) string {
+	if len(route) == 0 || route == Route53DefaultRoute {
+		return ""
+	}
+	return route
+}
+
+// 格式化记录值
+func (this *Route53Provider) formatValue(record *dnstypes.Record) string {
+	if record.Type == dnstypes.RecordTypeTXT {
+		return strconv.Quote(unquoteTXT(record.Value))
+	}
+	return record.FormatContent()
+}
+
+// 是否为支持的记录类型
+func (this *Route53Provider) isSupportedType(recordType dnstypes.RecordType) bool {
+	switch recordType {
+	case dnstypes.RecordTypeA, dnstypes.RecordTypeAAAA, dnstypes.RecordTypeCNAME, dnstypes.RecordTypeTXT,
+		dnstypes.RecordTypeMX, dnstypes.RecordTypeSRV, dnstypes.RecordTypeCAA, dnstypes.RecordTypeNS, dnstypes.RecordTypePTR:
+		return true
+	}
+	return false
+}
+
+// Route 53中的名称会将一些特殊字符转义，比如 * 转义为 \052
+func (this *Route53Provider) unescapeName(name string) string {
+	if !strings.Contains(name, "\\") {
+		return strings.ToLower(name)
+	}
+	var buf = &bytes.Buffer{}
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+3 < len(name) {
+			code, err := strconv.ParseUint(name[i+1:i+4], 8, 8)
+			if err == nil {
+				buf.WriteByte(byte(code))
+				i += 3
+				continue
+			}
+		}
+		buf.WriteByte(name[i])
+	}
+	return strings.ToLower(buf.String())
+}
+
+// 完整域名
+func (this *Route53Provider) fqdn(domain string, name string) string {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if len(name) == 0 || name == "@" {
+		return domain + "."
+	}
+	return strings.ToLower(name) + "." + domain + "."
+}
+
+// 相对于域名的记录名
+func (this *Route53Provider) relativeName(domain string, fqdn string) string {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	var name = strings.TrimSuffix(fqdn, ".")
+	if name == domain {
+		return "@"
+	}
+	return strings.TrimSuffix(name, "."+domain)
+}
+
+// 去除ID中的 /hostedzone/ 前缀
+func (this *Route53Provider) trimZoneId(zoneId string) string {
+	return strings.TrimPrefix(zoneId, "/hostedzone/")
+}
+
+func (this *Route53Provider) findZoneIdWithDomain(domain string) (string, error) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+
+	this.zoneLocker.Lock()
+	zoneId, ok := this.zoneMap[domain]
+	this.zoneLocker.Unlock()
+	if ok {
+		return zoneId, nil
+	}
+
+	_, err := this.GetDomains()
+	if err != nil {
+		return "", err
+	}
+
+	this.zoneLocker.Lock()
+	zoneId, ok = this.zoneMap[domain]
+	this.zoneLocker.Unlock()
+	if ok {
+		return zoneId, nil
+	}
+	return "", errors.New("can not find zone id for '" + domain + "'")
+}
+
+// 调用API
+func (this *Route53Provider) doAPI(method string, apiPath string, query url.Values, body any, respPtr any) error {
+	var apiURL = this.endpoint + "/" + Route53APIVersion + apiPath
+	if len(query) > 0 {
+		apiURL += "?" + query.Encode()
+	}
+
+	var bodyData []byte
+	if body != nil {
+		var err error
+		bodyData, err = xml.Marshal(body)
+		if err != nil {
+			return err
+		}
+		bodyData = append([]byte(xml.Header), bodyData...)
+	}
+
+	req, err := http.NewRequest(method, apiURL, bytes.NewReader(bodyData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", teaconst.ProductName+"/"+teaconst.Version)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+	_, err = this.signer.Sign(req, bytes.NewReader(bodyData), route53.ServiceName, this.region, time.Now())
+	if err != nil {
+		return err
+	}
+
+	resp, err := route53HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errorResponses = []route53.ResponseInterface{&route53.ErrorResponse{}, &route53.InvalidChangeBatchResponse{}}
+		for _, errorResp := range errorResponses {
+			if xml.Unmarshal(data, errorResp) == nil && !errorResp.IsOk() {
+				code, message := errorResp.LastError()
+				return errors.New("API error: " + code + ": " + message)
+			}
+		}
+		return errors.New("invalid response status '" + strconv.Itoa(resp.StatusCode) + "', response '" + string(data) + "'")
+	}
+
+	return xml.Unmarshal(data, respPtr)
+}
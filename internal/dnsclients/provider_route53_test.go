@@ -0,0 +1,374 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package dnsclients_test
+
+import (
+	"encoding/xml"
+	"github.com/dashenmiren/EdgeAPI/internal/dnsclients"
+	"github.com/dashenmiren/EdgeAPI/internal/dnsclients/dnstypes"
+	"github.com/dashenmiren/EdgeAPI/internal/dnsclients/route53"
+	"github.com/iwind/TeaGo/maps"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testRoute53ZoneId = "Z0000000000EXAMPLE"
+
+// 模拟Route 53 API
+type testRoute53Server struct {
+	recordSets []route53.ResourceRecordSet
+	locker     sync.Mutex
+}
+
+func newTestRoute53Provider(t *testing.T) (*dnsclients.Route53Provider, *testRoute53Server) {
+	var s = &testRoute53Server{}
+	var server = httptest.NewServer(s)
+	t.Cleanup(server.Close)
+
+	var provider = &dnsclients.Route53Provider{}
+	err := provider.Auth(maps.Map{
+		"accessKeyId":     "AKIDEXAMPLE",
+		"accessKeySecret": "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		"endpoint":        server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider, s
+}
+
+func (this *testRoute53Server) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
+	if !strings.HasPrefix(req.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") {
+		this.writeError(writer, http.StatusForbidden, "InvalidSignatureException", "invalid signature")
+		return
+	}
+
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	switch {
+	case req.Method == http.MethodGet && req.URL.Path == "/2013-04-01/hostedzone":
+		_, _ = writer.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<ListHostedZonesResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">
+  <HostedZones>
+    <HostedZone>
+      <Id>/hostedzone/` + testRoute53ZoneId + `</Id>
+      <Name>example.com.</Name>
+      <Config><PrivateZone>false</PrivateZone></Config>
+    </HostedZone>
+    <HostedZone>
+      <Id>/hostedzone/ZPRIVATE</Id>
+      <Name>internal.example.</Name>
+      <Config><PrivateZone>true</PrivateZone></Config>
+    </HostedZone>
+  </HostedZones>
+  <IsTruncated>false</IsTruncated>
+  <MaxItems>100</MaxItems>
+</ListHostedZonesResponse>`))
+	case req.Method == http.MethodGet && req.URL.Path == "/2013-04-01/hostedzone/"+testRoute53ZoneId+"/rrset":
+		var startName = req.URL.Query().Get("name")
+		var startType = req.URL.Query().Get("type")
+		var resp = &route53.ListResourceRecordSetsResponse{}
+		for _, recordSet := range this.recordSets {
+			if recordSet.Name < startName || (recordSet.Name == startName && recordSet.Type < startType) {
+				continue
+			}
+			resp.ResourceRecordSets = append(resp.ResourceRecordSets, recordSet)
+		}
+		data, _ := xml.Marshal(resp)
+		_, _ = writer.Write(data)
+	case req.Method == http.MethodPost && req.URL.Path == "/2013-04-01/hostedzone/"+testRoute53ZoneId+"/rrset/":
+		data, _ := io.ReadAll(req.Body)
+		var changeReq = &route53.ChangeResourceRecordSetsRequest{}
+		err := xml.Unmarshal(data, changeReq)
+		if err != nil {
+			this.writeError(writer, http.StatusBadRequest, "InvalidInput", err.Error())
+			return
+		}
+		for _, change := range changeReq.ChangeBatch.Changes {
+			var index = this.indexOf(change.ResourceRecordSet)
+			switch change.Action {
+			case route53.ChangeActionCreate:
+				if index >= 0 {
+					this.writeError(writer, http.StatusBadRequest, "InvalidChangeBatch", "record set already exists")
+					return
+				}
+				this.recordSets = append(this.recordSets, change.ResourceRecordSet)
+			case route53.ChangeActionUpsert:
+				if index >= 0 {
+					this.recordSets[index] = change.ResourceRecordSet
+				} else {
+					this.recordSets = append(this.recordSets, change.ResourceRecordSet)
+				}
+			case route53.ChangeActionDelete:
+				if index < 0 {
+					this.writeError(writer, http.StatusBadRequest, "InvalidChangeBatch", "record set not found")
+					return
+				}
+				this.recordSets = append(this.recordSets[:index], this.recordSets[index+1:]...)
+			}
+		}
+		sort.Slice(this.recordSets, func(i, j int) bool {
+			if this.recordSets[i].Name == this.recordSets[j].Name {
+				return this.recordSets[i].Type < this.recordSets[j].Type
+			}
+			return this.recordSets[i].Name < this.recordSets[j].Name
+		})
+		_, _ = writer.Write([]byte(`<ChangeResourceRecordSetsResponse><ChangeInfo><Id>/change/C1</Id><Status>PENDING</Status></ChangeInfo></ChangeResourceRecordSetsResponse>`))
+	default:
+		this.writeError(writer, http.StatusNotFound, "NoSuchHostedZone", "not found")
+	}
+}
+
+func (this *testRoute53Server) indexOf(recordSet route53.ResourceRecordSet) int {
+	for index, existRecordSet := range this.recordSets {
+		if existRecordSet.Name == recordSet.Name && existRecordSet.Type == recordSet.Type && existRecordSet.SetIdentifier == recordSet.SetIdentifier {
+			return index
+		}
+	}
+	return -1
+}
+
+func (this *testRoute53Server) writeError(writer http.ResponseWriter, statusCode int, code string, message string) {
+	writer.WriteHeader(statusCode)
+	_, _ = writer.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>` + code + `</Code><Message>` + message + `</Message></Error><RequestId>1</RequestId></ErrorResponse>`))
+}
+
+func TestRoute53Provider_GetDomains(t *testing.T) {
+	provider, _ := newTestRoute53Provider(t)
+	domains, err := provider.GetDomains()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(domains) != 1 || domains[0] != "example.com" {
+		t.Fatal("unexpected domains:", domains)
+	}
+}
+
+func TestRoute53Provider_Records(t *testing.T) {
+	provider, server := newTestRoute53Provider(t)
+
+	// 添加
+	for _, record := range []*dnstypes.Record{
+		{Name: "www", Type: dnstypes.RecordTypeA, Value: "192.168.1.100", Route: provider.DefaultRoute(), TTL: 60},
+		{Name: "www", Type: dnstypes.RecordTypeA, Value: "192.168.1.101", Route: provider.DefaultRoute(), TTL: 60},
+		{Name: "www", Type: dnstypes.RecordTypeA, Value: "192.168.2.100", Route: "geo:country:CN", TTL: 60},
+		{Name: "cdn", Type: dnstypes.RecordTypeCNAME, Value: "www.example.com", Route: "latency:ap-northeast-1", TTL: 60},
+		{Name: "@", Type: dnstypes.RecordTypeTXT, Value: "hello world", TTL: 60},
+	} {
+		err := provider.AddRecord("example.com", record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(record.Id) == 0 {
+			t.Fatal("record id should not be empty")
+		}
+	}
+	if len(server.recordSets) != 4 {
+		t.Fatal("expect 4 record sets, but got", len(server.recordSets))
+	}
+
+	records, err := provider.GetRecords("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 {
+		t.Fatal("expect 5 records, but got", len(records))
+	}
+	for _, record := range records {
+		t.Log(record.Id, record.Name, record.Type, record.Value, record.Route)
+	}
+
+	// 查询
+	{
+		records, err := provider.QueryRecords("example.com", "www", dnstypes.RecordTypeA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 3 {
+			t.Fatal("expect 3 records, but got", len(records))
+		}
+	}
+	{
+		record, err := provider.QueryRecord("example.com", "cdn", dnstypes.RecordTypeCNAME)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record == nil || record.Value != "www.example.com." || record.Route != "latency:ap-northeast-1" {
+			t.Fatal("unexpected record:", record)
+		}
+	}
+	{
+		record, err := provider.QueryRecord("example.com", "@", dnstypes.RecordTypeTXT)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record == nil || record.Value != "hello world" {
+			t.Fatal("unexpected record:", record)
+		}
+	}
+
+	// 修改
+	{
+		var record = &dnstypes.Record{Name: "www", Type: dnstypes.RecordTypeA, Value: "192.168.1.100", Route: provider.DefaultRoute()}
+		var newRecord = &dnstypes.Record{Name: "www", Type: dnstypes.RecordTypeA, Value: "192.168.1.102", Route: provider.DefaultRoute(), TTL: 60}
+		err = provider.UpdateRecord("example.com", record, newRecord)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := provider.QueryRecords("example.com", "www", dnstypes.RecordTypeA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var values = []string{}
+		for _, record := range records {
+			values = append(values, record.Value)
+		}
+		sort.Strings(values)
+		if strings.Join(values, ",") != "192.168.1.101,192.168.1.102,192.168.2.100" {
+			t.Fatal("unexpected values:", values)
+		}
+	}
+
+	// 修改线路
+	{
+		var record = &dnstypes.Record{Name: "www", Type: dnstypes.RecordTypeA, Value: "192.168.2.100", Route: "geo:country:CN"}
+		var newRecord = &dnstypes.Record{Name: "www", Type: dnstypes.RecordTypeA, Value: "192.168.2.100", Route: "geo:continent:AS", TTL: 60}
+		err = provider.UpdateRecord("example.com", record, newRecord)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, recordSet := range server.recordSets {
+			if recordSet.SetIdentifier == "geo:country:CN" {
+				t.Fatal("old record set should be deleted")
+			}
+		}
+	}
+
+	// 删除
+	{
+		err = provider.DeleteRecord("example.com", &dnstypes.Record{Name: "www", Type: dnstypes.RecordTypeA, Value: "192.168.1.101", Route: provider.DefaultRoute()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = provider.DeleteRecord("example.com", &dnstypes.Record{Name: "www", Type: dnstypes.RecordTypeA, Value: "192.168.1.102", Route: provider.DefaultRoute()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := provider.QueryRecords("example.com", "www", dnstypes.RecordTypeA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 1 || records[0].Route != "geo:continent:AS" {
+			t.Fatal("unexpected records:", records)
+		}
+	}
+}
+
+func TestRoute53Provider_StructuredRecords(t *testing.T) {
+	provider, server := newTestRoute53Provider(t)
+
+	for _, record := range []*dnstypes.Record{
+		{Name: "@", Type: dnstypes.RecordTypeMX, Value: "mx1.example.com", Priority: 10},
+		{Name: "@", Type: dnstypes.RecordTypeMX, Value: "mx2.example.com", Priority: 20},
+		{Name: "_sip._tcp", Type: dnstypes.RecordTypeSRV, Value: "sip.example.com", Priority: 10, Weight: 5, Port: 5060},
+		{Name: "@", Type: dnstypes.RecordTypeCAA, Value: "letsencrypt.org", Tag: "issue"},
+	} {
+		err := provider.AddRecord("example.com", record)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	{
+		var index = server.indexOf(route53.ResourceRecordSet{Name: "example.com.", Type: dnstypes.RecordTypeMX})
+		if index < 0 {
+			t.Fatal("MX record set should exist")
+		}
+		var values = []string{}
+		for _, resourceRecord := range server.recordSets[index].ResourceRecords {
+			values = append(values, resourceRecord.Value)
+		}
+		if strings.Join(values, ",") != "10 mx1.example.com.,20 mx2.example.com." {
+			t.Fatal("unexpected MX values:", values)
+		}
+	}
+
+	{
+		records, err := provider.QueryRecords("example.com", "@", dnstypes.RecordTypeMX)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 2 || records[0].Priority != 10 || records[1].Value != "mx2.example.com." || records[0].Id == records[1].Id {
+			t.Fatal("unexpected MX records:", records)
+		}
+	}
+	{
+		record, err := provider.QueryRecord("example.com", "_sip._tcp", dnstypes.RecordTypeSRV)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record == nil || record.Priority != 10 || record.Weight != 5 || record.Port != 5060 || record.Value != "sip.example.com." {
+			t.Fatal("unexpected SRV record:", record)
+		}
+	}
+	{
+		record, err := provider.QueryRecord("example.com", "@", dnstypes.RecordTypeCAA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record == nil || record.Tag != "issue" || record.Value != "letsencrypt.org" {
+			t.Fatal("unexpected CAA record:", record)
+		}
+	}
+
+	// 删除其中一个MX值
+	err := provider.DeleteRecord("example.com", &dnstypes.Record{Name: "@", Type: dnstypes.RecordTypeMX, Value: "mx1.example.com", Priority: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := provider.QueryRecords("example.com", "@", dnstypes.RecordTypeMX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Priority != 20 {
+		t.Fatal("unexpected MX records:", records)
+	}
+
+	// 不支持的记录类型
+	err = provider.AddRecord("example.com", &dnstypes.Record{Name: "@", Type: "SSHFP", Value: "1 1 123456789abcdef67890123456789abcdef67890"})
+	if !dnstypes.IsUnsupportedRecordTypeError(err) {
+		t.Fatal("expect unsupported record type error, but got", err)
+	}
+}
+
+func TestRoute53Provider_Error(t *testing.T) {
+	provider, _ := newTestRoute53Provider(t)
+	err := provider.AddRecord("example.org", &dnstypes.Record{Name: "www", Type: dnstypes.RecordTypeA, Value: "192.168.1.100"})
+	if err == nil {
+		t.Fatal("should return error for unknown zone")
+	}
+	t.Log(err)
+
+	err = provider.AddRecord("example.com", &dnstypes.Record{Name: "www", Type: dnstypes.RecordTypeA, Value: "192.168.1.100", Route: "invalid"})
+	if err == nil {
+		t.Fatal("should return error for invalid route")
+	}
+	t.Log(err)
+}
+
+func TestRoute53Provider_GetRoutes(t *testing.T) {
+	provider, _ := newTestRoute53Provider(t)
+	routes, err := provider.GetRoutes("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) == 0 || routes[0].Code != provider.DefaultRoute() {
+		t.Fatal("default route should be the first route")
+	}
+}
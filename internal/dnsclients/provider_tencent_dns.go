@@ -12,6 +12,13 @@ import (
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
 	dnspod "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/dnspod/v20210323"
 	"strings"
+	"time"
+)
+
+const (
+	TencentDNSMaxBatchSize         = 100 // 单个批量任务最多包含的记录数
+	TencentDNSBatchTaskMaxChecks   = 30  // 检查批量任务状态的最大次数
+	TencentDNSBatchTaskCheckPeriod = time.Second
 )
 
 // TencentDNSProvider 腾讯云DNS云解析
@@ -104,14 +111,10 @@ func (this *TencentDNSProvider) GetRecords(domain string) (records []*dnstypes.R
 			break
 		}
 		for _, recordObj := range resp.Response.RecordList {
-			records = append(records, &dnstypes.Record{
-				Id:    types.String(*recordObj.RecordId),
-				Name:  *recordObj.Name,
-				Type:  *recordObj.Type,
-				Value: this.fixCNAME(*recordObj.Type, *recordObj.Value),
-				Route: *recordObj.LineId,
-				TTL:   types.Int32(*recordObj.TTL),
-			})
+			var record = this.convertRecord(recordObj)
+			if record != nil {
+				records = append(records, record)
+			}
 		}
 		offset += uint64(countRecords)
 	}
@@ -201,14 +204,10 @@ func (this *TencentDNSProvider) QueryRecord(domain string, name string, recordTy
 	}
 	for _, recordObj := range resp.Response.RecordList {
 		if *recordObj.Name == name && *recordObj.Type == recordType {
-			return &dnstypes.Record{
-				Id:    types.String(*recordObj.RecordId),
-				Name:  *recordObj.Name,
-				Type:  *recordObj.Type,
-				Value: this.fixCNAME(*recordObj.Type, *recordObj.Value),
-				Route: *recordObj.LineId,
-				TTL:   types.Int32(*recordObj.TTL),
-			}, nil
+			var record = this.convertRecord(recordObj)
+			if record != nil {
+				return record, nil
+			}
 		}
 	}
 
@@ -247,14 +246,10 @@ func (this *TencentDNSProvider) QueryRecords(domain string, name string, recordT
 			break
 		}
 		for _, recordObj := range resp.Response.RecordList {
-			records = append(records, &dnstypes.Record{
-				Id:    types.String(*recordObj.RecordId),
-				Name:  *recordObj.Name,
-				Type:  *recordObj.Type,
-				Value: this.fixCNAME(*recordObj.Type, *recordObj.Value),
-				Route: *recordObj.LineId,
-				TTL:   types.Int32(*recordObj.TTL),
-			})
+			var record = this.convertRecord(recordObj)
+			if record != nil {
+				records = append(records, record)
+			}
 		}
 		offset += uint64(countRecords)
 	}
@@ -273,6 +268,11 @@ func (this *TencentDNSProvider) AddRecord(domain string, newRecord *dnstypes.Rec
 		newRecord.Value += "."
 	}
 
+	value, err := this.formatValue(newRecord)
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
+	}
+
 	var ttl = newRecord.TTL
 	if ttl <= 0 {
 		ttl = 600
@@ -285,7 +285,10 @@ func (this *TencentDNSProvider) AddRecord(domain string, newRecord *dnstypes.Rec
 	req.TTL = this.uint64Val(uint64(ttl))
 	req.RecordLine = this.stringVal(this.DefaultRouteName()) // 默认必填项，但以RecordLineId优先
 	req.RecordLineId = this.stringVal(newRecord.Route)
-	req.Value = this.stringVal(newRecord.Value)
+	req.Value = this.stringVal(value)
+	if newRecord.Type == dnstypes.RecordTypeMX {
+		req.MX = this.uint64Val(uint64(newRecord.Priority))
+	}
 	resp, respErr := this.client.CreateRecord(req)
 	if respErr != nil {
 		return respErr
@@ -314,6 +317,11 @@ func (this *TencentDNSProvider) UpdateRecord(domain string, record *dnstypes.Rec
 		newRecord.Value += "."
 	}
 
+	value, err := this.formatValue(newRecord)
+	if err != nil {
+		return this.WrapError(err, domain, newRecord)
+	}
+
 	var newRoute = newRecord.Route
 	if len(newRoute) == 0 {
 		newRoute = this.DefaultRoute()
@@ -332,7 +340,10 @@ func (this *TencentDNSProvider) UpdateRecord(domain string, record *dnstypes.Rec
 	req.TTL = this.uint64Val(uint64(ttl))
 	req.RecordLine = this.stringVal(this.DefaultRouteName()) // 默认必填项，但以RecordLineId优先
 	req.RecordLineId = this.stringVal(newRecord.Route)
-	req.Value = this.stringVal(newRecord.Value)
+	req.Value = this.stringVal(value)
+	if newRecord.Type == dnstypes.RecordTypeMX {
+		req.MX = this.uint64Val(uint64(newRecord.Priority))
+	}
 	_, respErr := this.client.ModifyRecord(req)
 	if respErr != nil {
 		return respErr
@@ -371,6 +382,152 @@ func (this *TencentDNSProvider) DeleteRecord(domain string, record *dnstypes.Rec
 	return nil
 }
 
+// ApplyRecords 批量应用记录修改
+// 添加和删除使用批量任务接口，修改仍然逐个执行
+func (this *TencentDNSProvider) ApplyRecords(domain string, changes *RecordChanges) (failures []*RecordFailure, err error) {
+	if changes.IsEmpty() {
+		return nil, nil
+	}
+
+	// 添加
+	if len(changes.Adds) > 0 {
+		domainId, err := this.findDomainId(domain)
+		if err != nil {
+			return nil, err
+		}
+
+		var addRecords = []*dnstypes.Record{}
+		var reqRecords = []*dnspod.AddRecordBatch{}
+		for _, record := range changes.Adds {
+			// 在CHANGE记录后面加入点
+			if record.Type == dnstypes.RecordTypeCNAME && !strings.HasSuffix(record.Value, ".") {
+				record.Value += "."
+			}
+
+			value, valueErr := this.formatValue(record)
+			if valueErr != nil {
+				failures = append(failures, NewRecordFailure(RecordActionAdd, record, valueErr))
+				continue
+			}
+
+			var ttl = record.TTL
+			if ttl <= 0 {
+				ttl = 600
+			}
+
+			var reqRecord = &dnspod.AddRecordBatch{
+				RecordType: this.stringVal(record.Type),
+				Value:      this.stringVal(value),
+				SubDomain:  this.stringVal(record.Name),
+				TTL:        this.uint64Val(uint64(ttl)),
+			}
+			if len(record.Route) > 0 {
+				reqRecord.RecordLineId = this.stringVal(record.Route)
+			} else {
+				reqRecord.RecordLine = this.stringVal(this.DefaultRouteName())
+			}
+			if record.Type == dnstypes.RecordTypeMX {
+				reqRecord.MX = this.uint64Val(uint64(record.Priority))
+			}
+			addRecords = append(addRecords, record)
+			reqRecords = append(reqRecords, reqRecord)
+		}
+
+		for len(addRecords) > 0 {
+			var size = TencentDNSMaxBatchSize
+			if size > len(addRecords) {
+				size = len(addRecords)
+			}
+			var batchRecords = addRecords[:size]
+
+			var req = dnspod.NewCreateRecordBatchRequest()
+			req.DomainIdList = []*string{this.stringVal(types.String(domainId))}
+			req.RecordList = reqRecords[:size]
+			resp, respErr := this.client.CreateRecordBatch(req)
+			var recordInfos []*dnspod.BatchRecordInfo
+			if respErr == nil {
+				recordInfos, respErr = this.waitBatchTask(resp.Response.JobId)
+			}
+			if respErr != nil {
+				for _, record := range batchRecords {
+					failures = append(failures, NewRecordFailure(RecordActionAdd, record, respErr))
+				}
+			} else {
+				for _, record := range batchRecords {
+					var recordInfo = this.findBatchRecordInfo(recordInfos, record)
+					if recordInfo == nil {
+						continue
+					}
+					if recordInfo.ErrMsg != nil && len(*recordInfo.ErrMsg) > 0 {
+						failures = append(failures, NewRecordFailure(RecordActionAdd, record, errors.New(*recordInfo.ErrMsg)))
+						continue
+					}
+					if recordInfo.RecordId != nil {
+						record.Id = types.String(*recordInfo.RecordId)
+
+						// 加入缓存
+						if this.ProviderId > 0 {
+							sharedDomainRecordsCache.AddDomainRecord(this.ProviderId, domain, record)
+						}
+					}
+				}
+			}
+
+			addRecords = addRecords[size:]
+			reqRecords = reqRecords[size:]
+		}
+	}
+
+	// 修改
+	if len(changes.Updates) > 0 {
+		failures = append(failures, NewSequentialApplier(this).Apply(domain, &RecordChanges{
+			Updates: changes.Updates,
+		})...)
+	}
+
+	// 删除
+	var deleteRecords = changes.Deletes
+	for len(deleteRecords) > 0 {
+		var size = TencentDNSMaxBatchSize
+		if size > len(deleteRecords) {
+			size = len(deleteRecords)
+		}
+		var batchRecords = deleteRecords[:size]
+		deleteRecords = deleteRecords[size:]
+
+		var req = dnspod.NewDeleteRecordBatchRequest()
+		for _, record := range batchRecords {
+			req.RecordIdList = append(req.RecordIdList, this.uint64Val(types.Uint64(record.Id)))
+		}
+		resp, respErr := this.client.DeleteRecordBatch(req)
+		var recordInfos []*dnspod.BatchRecordInfo
+		if respErr == nil {
+			recordInfos, respErr = this.waitBatchTask(resp.Response.JobId)
+		}
+		if respErr != nil {
+			for _, record := range batchRecords {
+				failures = append(failures, NewRecordFailure(RecordActionDelete, record, respErr))
+			}
+			continue
+		}
+
+		for _, record := range batchRecords {
+			var recordInfo = this.findBatchRecordInfo(recordInfos, record)
+			if recordInfo != nil && recordInfo.ErrMsg != nil && len(*recordInfo.ErrMsg) > 0 {
+				failures = append(failures, NewRecordFailure(RecordActionDelete, record, errors.New(*recordInfo.ErrMsg)))
+				continue
+			}
+
+			// 删除缓存
+			if this.ProviderId > 0 {
+				sharedDomainRecordsCache.DeleteDomainRecord(this.ProviderId, domain, record.Id)
+			}
+		}
+	}
+
+	return failures, nil
+}
+
 // DefaultRoute 默认线路
 func (this *TencentDNSProvider) DefaultRoute() string {
 	return "0"
@@ -380,6 +537,108 @@ func (this *TencentDNSProvider) DefaultRouteName() string {
 	return "默认"
 }
 
+// 转换记录，无法解析的记录返回nil
+func (this *TencentDNSProvider) convertRecord(recordObj *dnspod.RecordListItem) *dnstypes.Record {
+	var record = &dnstypes.Record{
+		Id:    types.String(*recordObj.RecordId),
+		Name:  *recordObj.Name,
+		Type:  *recordObj.Type,
+		Value: this.fixCNAME(*recordObj.Type, *recordObj.Value),
+		Route: *recordObj.LineId,
+		TTL:   types.Int32(*recordObj.TTL),
+	}
+	switch record.Type {
+	case dnstypes.RecordTypeMX:
+		if recordObj.MX != nil {
+			record.Priority = types.Int32(*recordObj.MX)
+		}
+	case dnstypes.RecordTypeSRV, dnstypes.RecordTypeCAA:
+		if record.ParseContent(record.Value) != nil {
+			return nil
+		}
+	}
+	return record
+}
+
+// 格式化记录值
+// MX记录的优先级通过单独的参数设置，SRV和CAA记录使用完整的记录内容
+func (this *TencentDNSProvider) formatValue(record *dnstypes.Record) (string, error) {
+	switch record.Type {
+	case dnstypes.RecordTypeA, dnstypes.RecordTypeAAAA, dnstypes.RecordTypeCNAME, dnstypes.RecordTypeTXT, dnstypes.RecordTypeNS, dnstypes.RecordTypeMX:
+		return record.Value, nil
+	case dnstypes.RecordTypeSRV, dnstypes.RecordTypeCAA:
+		return record.FormatContent(), nil
+	}
+	return "", dnstypes.NewUnsupportedRecordTypeError(ProviderTypeTencentDNS, record.Type)
+}
+
+// 查找域名ID
+func (this *TencentDNSProvider) findDomainId(domain string) (uint64, error) {
+	var req = dnspod.NewDescribeDomainRequest()
+	req.Domain = this.stringVal(domain)
+	resp, respErr := this.client.DescribeDomain(req)
+	if respErr != nil {
+		return 0, respErr
+	}
+	if resp.Response.DomainInfo == nil || resp.Response.DomainInfo.DomainId == nil {
+		return 0, errors.New("can not find domain '" + domain + "'")
+	}
+	return *resp.Response.DomainInfo.DomainId, nil
+}
+
+// 等待批量任务完成，并返回任务中的记录信息
+func (this *TencentDNSProvider) waitBatchTask(jobId *uint64) ([]*dnspod.BatchRecordInfo, error) {
+	if jobId == nil {
+		return nil, errors.New("invalid batch job id")
+	}
+
+	var req = dnspod.NewDescribeBatchTaskRequest()
+	req.JobId = jobId
+	for i := 0; i < TencentDNSBatchTaskMaxChecks; i++ {
+		time.Sleep(TencentDNSBatchTaskCheckPeriod)
+
+		resp, respErr := this.client.DescribeBatchTask(req)
+		if respErr != nil {
+			return nil, respErr
+		}
+		if resp.Response.TotalCount == nil || resp.Response.SuccessCount == nil || resp.Response.FailCount == nil {
+			continue
+		}
+		if *resp.Response.SuccessCount+*resp.Response.FailCount < *resp.Response.TotalCount {
+			continue
+		}
+
+		var result = []*dnspod.BatchRecordInfo{}
+		for _, detail := range resp.Response.DetailList {
+			result = append(result, detail.RecordList...)
+		}
+		return result, nil
+	}
+	return nil, errors.New("wait for batch job '" + types.String(*jobId) + "' timeout")
+}
+
+// 从批量任务结果中查找和记录对应的信息
+func (this *TencentDNSProvider) findBatchRecordInfo(recordInfos []*dnspod.BatchRecordInfo, record *dnstypes.Record) *dnspod.BatchRecordInfo {
+	for _, recordInfo := range recordInfos {
+		if len(record.Id) > 0 {
+			if recordInfo.RecordId != nil && types.String(*recordInfo.RecordId) == record.Id {
+				return recordInfo
+			}
+			continue
+		}
+
+		if recordInfo.SubDomain == nil || recordInfo.RecordType == nil || recordInfo.Value == nil {
+			continue
+		}
+		if *recordInfo.SubDomain == record.Name &&
+			*recordInfo.RecordType == record.Type &&
+			this.fixCNAME(*recordInfo.RecordType, *recordInfo.Value) == record.Value {
+			return recordInfo
+		}
+	}
+	return nil
+}
+
 func (this *TencentDNSProvider) fixCNAME(recordType string, recordValue string) string {
 	// 修正Record
 	if strings.ToUpper(recordType) == dnstypes.RecordTypeCNAME && !strings.HasSuffix(recordValue, ".") {
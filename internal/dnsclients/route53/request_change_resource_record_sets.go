@@ -0,0 +1,40 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package route53
+
+import "encoding/xml"
+
+const XMLNamespace = "https://route53.amazonaws.com/doc/2013-04-01/"
+
+type ChangeAction = string
+
+const (
+	ChangeActionCreate ChangeAction = "CREATE"
+	ChangeActionDelete ChangeAction = "DELETE"
+	ChangeActionUpsert ChangeAction = "UPSERT"
+)
+
+// Change 单个修改
+type Change struct {
+	Action            ChangeAction      `xml:"Action"`
+	ResourceRecordSet ResourceRecordSet `xml:"ResourceRecordSet"`
+}
+
+// ChangeResourceRecordSetsRequest 批量修改记录集
+type ChangeResourceRecordSetsRequest struct {
+	XMLName     xml.Name `xml:"ChangeResourceRecordSetsRequest"`
+	Xmlns       string   `xml:"xmlns,attr"`
+	ChangeBatch struct {
+		Comment string   `xml:"Comment,omitempty"`
+		Changes []Change `xml:"Changes>Change"`
+	} `xml:"ChangeBatch"`
+}
+
+func NewChangeResourceRecordSetsRequest(comment string, changes []Change) *ChangeResourceRecordSetsRequest {
+	var req = &ChangeResourceRecordSetsRequest{
+		Xmlns: XMLNamespace,
+	}
+	req.ChangeBatch.Comment = comment
+	req.ChangeBatch.Changes = changes
+	return req
+}
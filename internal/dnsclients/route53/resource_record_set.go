@@ -0,0 +1,31 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package route53
+
+// GeoLocation 地理位置
+type GeoLocation struct {
+	ContinentCode   string `xml:"ContinentCode,omitempty"`
+	CountryCode     string `xml:"CountryCode,omitempty"`
+	SubdivisionCode string `xml:"SubdivisionCode,omitempty"`
+}
+
+// ResourceRecord 单个记录值
+type ResourceRecord struct {
+	Value string `xml:"Value"`
+}
+
+// ResourceRecordSet 记录集
+type ResourceRecordSet struct {
+	Name            string           `xml:"Name"`
+	Type            string           `xml:"Type"`
+	SetIdentifier   string           `xml:"SetIdentifier,omitempty"`
+	Weight          *int64           `xml:"Weight,omitempty"`
+	Region          string           `xml:"Region,omitempty"`
+	GeoLocation     *GeoLocation     `xml:"GeoLocation,omitempty"`
+	TTL             int64            `xml:"TTL,omitempty"`
+	ResourceRecords []ResourceRecord `xml:"ResourceRecords>ResourceRecord"`
+	AliasTarget     *struct {
+		HostedZoneId string `xml:"HostedZoneId"`
+		DNSName      string `xml:"DNSName"`
+	} `xml:"AliasTarget,omitempty"`
+}
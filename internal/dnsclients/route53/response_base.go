@@ -0,0 +1,41 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package route53
+
+import "encoding/xml"
+
+// ErrorResponse 错误响应
+type ErrorResponse struct {
+	XMLName xml.Name `xml:"ErrorResponse"`
+	Error   struct {
+		Type    string `xml:"Type"`
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	} `xml:"Error"`
+	RequestId string `xml:"RequestId"`
+}
+
+func (this *ErrorResponse) IsOk() bool {
+	return len(this.Error.Code) == 0
+}
+
+func (this *ErrorResponse) LastError() (code string, message string) {
+	return this.Error.Code, this.Error.Message
+}
+
+// InvalidChangeBatchResponse 修改记录失败时的响应
+type InvalidChangeBatchResponse struct {
+	XMLName  xml.Name `xml:"InvalidChangeBatch"`
+	Messages []string `xml:"Messages>Message"`
+}
+
+func (this *InvalidChangeBatchResponse) IsOk() bool {
+	return len(this.Messages) == 0
+}
+
+func (this *InvalidChangeBatchResponse) LastError() (code string, message string) {
+	if len(this.Messages) == 0 {
+		return "", ""
+	}
+	return "InvalidChangeBatch", this.Messages[0]
+}
@@ -0,0 +1,14 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package route53
+
+import "encoding/xml"
+
+type ChangeResourceRecordSetsResponse struct {
+	XMLName    xml.Name `xml:"ChangeResourceRecordSetsResponse"`
+	ChangeInfo struct {
+		Id          string `xml:"Id"`
+		Status      string `xml:"Status"`
+		SubmittedAt string `xml:"SubmittedAt"`
+	} `xml:"ChangeInfo"`
+}
@@ -0,0 +1,8 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package route53
+
+type ResponseInterface interface {
+	IsOk() bool
+	LastError() (code string, message string)
+}
@@ -0,0 +1,21 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package route53
+
+import "encoding/xml"
+
+type ListHostedZonesResponse struct {
+	XMLName     xml.Name `xml:"ListHostedZonesResponse"`
+	HostedZones []struct {
+		Id     string `xml:"Id"`
+		Name   string `xml:"Name"`
+		Config struct {
+			PrivateZone bool `xml:"PrivateZone"`
+		} `xml:"Config"`
+		ResourceRecordSetCount int `xml:"ResourceRecordSetCount"`
+	} `xml:"HostedZones>HostedZone"`
+	IsTruncated bool   `xml:"IsTruncated"`
+	Marker      string `xml:"Marker"`
+	NextMarker  string `xml:"NextMarker"`
+	MaxItems    int    `xml:"MaxItems"`
+}
@@ -0,0 +1,15 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package route53
+
+import "encoding/xml"
+
+type ListResourceRecordSetsResponse struct {
+	XMLName              xml.Name            `xml:"ListResourceRecordSetsResponse"`
+	ResourceRecordSets   []ResourceRecordSet `xml:"ResourceRecordSets>ResourceRecordSet"`
+	IsTruncated          bool                `xml:"IsTruncated"`
+	NextRecordName       string              `xml:"NextRecordName"`
+	NextRecordType       string              `xml:"NextRecordType"`
+	NextRecordIdentifier string              `xml:"NextRecordIdentifier"`
+	MaxItems             int                 `xml:"MaxItems"`
+}
@@ -0,0 +1,227 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package route53_test
+
+import (
+	"encoding/xml"
+	"github.com/dashenmiren/EdgeAPI/internal/dnsclients/route53"
+	"strings"
+	"testing"
+)
+
+func TestListHostedZonesResponse(t *testing.T) {
+	var data = `<?xml version="1.0" encoding="UTF-8"?>
+<ListHostedZonesResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">
+   <HostedZones>
+      <HostedZone>
+         <Id>/hostedzone/Z1D633PJN98FT9</Id>
+         <Name>example.com.</Name>
+         <CallerReference>2017-03-01T11:22:14Z</CallerReference>
+         <Config>
+            <PrivateZone>false</PrivateZone>
+         </Config>
+         <ResourceRecordSetCount>17</ResourceRecordSetCount>
+      </HostedZone>
+      <HostedZone>
+         <Id>/hostedzone/Z1D633PJRANDOM</Id>
+         <Name>example.net.</Name>
+         <Config>
+            <PrivateZone>true</PrivateZone>
+         </Config>
+         <ResourceRecordSetCount>3</ResourceRecordSetCount>
+      </HostedZone>
+   </HostedZones>
+   <IsTruncated>true</IsTruncated>
+   <NextMarker>Z222222VVVVVVV</NextMarker>
+   <MaxItems>2</MaxItems>
+</ListHostedZonesResponse>`
+
+	var resp = &route53.ListHostedZonesResponse{}
+	err := xml.Unmarshal([]byte(data), resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.HostedZones) != 2 {
+		t.Fatal("expect 2 zones")
+	}
+	if resp.HostedZones[0].Id != "/hostedzone/Z1D633PJN98FT9" || resp.HostedZones[0].Name != "example.com." || resp.HostedZones[0].ResourceRecordSetCount != 17 {
+		t.Fatal("invalid zone", resp.HostedZones[0])
+	}
+	if !resp.HostedZones[1].Config.PrivateZone {
+		t.Fatal("zone should be private")
+	}
+	if !resp.IsTruncated || resp.NextMarker != "Z222222VVVVVVV" {
+		t.Fatal("invalid pagination")
+	}
+}
+
+func TestListResourceRecordSetsResponse(t *testing.T) {
+	var data = `<?xml version="1.0" encoding="UTF-8"?>
+<ListResourceRecordSetsResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">
+   <ResourceRecordSets>
+      <ResourceRecordSet>
+         <Name>example.com.</Name>
+         <Type>SOA</Type>
+         <TTL>900</TTL>
+         <ResourceRecords>
+            <ResourceRecord>
+               <Value>ns-2048.awsdns-64.net. hostmaster.awsdns.com. 1 7200 900 1209600 86400</Value>
+            </ResourceRecord>
+         </ResourceRecords>
+      </ResourceRecordSet>
+      <ResourceRecordSet>
+         <Name>www.example.com.</Name>
+         <Type>A</Type>
+         <SetIdentifier>latency:ap-northeast-1</SetIdentifier>
+         <Region>ap-northeast-1</Region>
+         <TTL>300</TTL>
+         <ResourceRecords>
+            <ResourceRecord>
+               <Value>192.0.2.1</Value>
+            </ResourceRecord>
+            <ResourceRecord>
+               <Value>192.0.2.2</Value>
+            </ResourceRecord>
+         </ResourceRecords>
+      </ResourceRecordSet>
+      <ResourceRecordSet>
+         <Name>\052.example.com.</Name>
+         <Type>TXT</Type>
+         <SetIdentifier>geo:country:CN</SetIdentifier>
+         <GeoLocation>
+            <CountryCode>CN</CountryCode>
+         </GeoLocation>
+         <TTL>60</TTL>
+         <ResourceRecords>
+            <ResourceRecord>
+               <Value>"hello world"</Value>
+            </ResourceRecord>
+         </ResourceRecords>
+      </ResourceRecordSet>
+   </ResourceRecordSets>
+   <IsTruncated>true</IsTruncated>
+   <NextRecordName>xyz.example.com.</NextRecordName>
+   <NextRecordType>A</NextRecordType>
+   <MaxItems>3</MaxItems>
+</ListResourceRecordSetsResponse>`
+
+	var resp = &route53.ListResourceRecordSetsResponse{}
+	err := xml.Unmarshal([]byte(data), resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.ResourceRecordSets) != 3 {
+		t.Fatal("expect 3 record sets")
+	}
+
+	var latencySet = resp.ResourceRecordSets[1]
+	if latencySet.Region != "ap-northeast-1" || latencySet.SetIdentifier != "latency:ap-northeast-1" || latencySet.TTL != 300 || len(latencySet.ResourceRecords) != 2 {
+		t.Fatal("invalid latency record set", latencySet)
+	}
+
+	var geoSet = resp.ResourceRecordSets[2]
+	if geoSet.GeoLocation == nil || geoSet.GeoLocation.CountryCode != "CN" {
+		t.Fatal("invalid geo record set")
+	}
+	if geoSet.Name != `\052.example.com.` {
+		t.Fatal("invalid name", geoSet.Name)
+	}
+
+	if !resp.IsTruncated || resp.NextRecordName != "xyz.example.com." || resp.NextRecordType != "A" {
+		t.Fatal("invalid pagination")
+	}
+}
+
+func TestChangeResourceRecordSetsResponse(t *testing.T) {
+	var data = `<?xml version="1.0" encoding="UTF-8"?>
+<ChangeResourceRecordSetsResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">
+   <ChangeInfo>
+      <Id>/change/C2682N5HXP0BZ4</Id>
+      <Status>PENDING</Status>
+      <SubmittedAt>2017-03-10T01:36:41.958Z</SubmittedAt>
+   </ChangeInfo>
+</ChangeResourceRecordSetsResponse>`
+
+	var resp = &route53.ChangeResourceRecordSetsResponse{}
+	err := xml.Unmarshal([]byte(data), resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ChangeInfo.Id != "/change/C2682N5HXP0BZ4" || resp.ChangeInfo.Status != "PENDING" {
+		t.Fatal("invalid change info")
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	var data = `<?xml version="1.0"?>
+<ErrorResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">
+  <Error>
+    <Type>Sender</Type>
+    <Code>NoSuchHostedZone</Code>
+    <Message>No hosted zone found with ID: Z1D633PJN98FT9</Message>
+  </Error>
+  <RequestId>a1b2c3d4-5678-90ab-cdef-EXAMPLE11111</RequestId>
+</ErrorResponse>`
+
+	var resp = &route53.ErrorResponse{}
+	err := xml.Unmarshal([]byte(data), resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsOk() {
+		t.Fatal("should not be ok")
+	}
+	code, message := resp.LastError()
+	if code != "NoSuchHostedZone" || !strings.Contains(message, "Z1D633PJN98FT9") {
+		t.Fatal("invalid error", code, message)
+	}
+}
+
+func TestInvalidChangeBatchResponse(t *testing.T) {
+	var data = `<?xml version="1.0"?>
+<InvalidChangeBatch xmlns="https://route53.amazonaws.com/doc/2013-04-01/">
+  <Messages>
+    <Message>Tried to create resource record set [name='www.example.com.', type='A'] but it already exists</Message>
+  </Messages>
+  <RequestId>b25f48e8-84fd-11e6-80d9-574e0c4664cb</RequestId>
+</InvalidChangeBatch>`
+
+	var resp = &route53.InvalidChangeBatchResponse{}
+	err := xml.Unmarshal([]byte(data), resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, message := resp.LastError()
+	if resp.IsOk() || code != "InvalidChangeBatch" || !strings.Contains(message, "already exists") {
+		t.Fatal("invalid error", code, message)
+	}
+}
+
+func TestChangeResourceRecordSetsRequest(t *testing.T) {
+	var weight int64 = 10
+	var req = route53.NewChangeResourceRecordSetsRequest("test", []route53.Change{
+		{
+			Action: route53.ChangeActionUpsert,
+			ResourceRecordSet: route53.ResourceRecordSet{
+				Name:          "www.example.com.",
+				Type:          "A",
+				SetIdentifier: "weight:10",
+				Weight:        &weight,
+				TTL:           300,
+				ResourceRecords: []route53.ResourceRecord{
+					{Value: "192.0.2.1"},
+				},
+			},
+		},
+	})
+	data, err := xml.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(string(data))
+
+	var expected = `<ChangeResourceRecordSetsRequest xmlns="https://route53.amazonaws.com/doc/2013-04-01/"><ChangeBatch><Comment>test</Comment><Changes><Change><Action>UPSERT</Action><ResourceRecordSet><Name>www.example.com.</Name><Type>A</Type><SetIdentifier>weight:10</SetIdentifier><Weight>10</Weight><TTL>300</TTL><ResourceRecords><ResourceRecord><Value>192.0.2.1</Value></ResourceRecord></ResourceRecords></ResourceRecordSet></Change></Changes></ChangeBatch></ChangeResourceRecordSetsRequest>`
+	if string(data) != expected {
+		t.Fatal("invalid request xml")
+	}
+}
@@ -9,12 +9,16 @@ type ProviderType = string
 // 服务商代号
 const (
 	ProviderTypeDNSPod       ProviderType = "dnspod"       // DNSPod
+	ProviderTypeTencentDNS   ProviderType = "tencentDNS"   // 腾讯云DNS
 	ProviderTypeAliDNS       ProviderType = "alidns"       // 阿里云DNS
 	ProviderTypeHuaweiDNS    ProviderType = "huaweiDNS"    // 华为DNS
 	ProviderTypeCloudFlare   ProviderType = "cloudFlare"   // CloudFlare DNS
+	ProviderTypeRoute53      ProviderType = "route53"      // AWS Route 53
 	ProviderTypeLocalEdgeDNS ProviderType = "localEdgeDNS" // 和当前系统集成的EdgeDNS
 	ProviderTypeEdgeDNSAPI   ProviderType = "edgeDNSAPI"   // 通过API连接的EdgeDNS
 	ProviderTypeCustomHTTP   ProviderType = "customHTTP"   // 自定义HTTP接口
+	ProviderTypeRFC2136      ProviderType = "rfc2136"      // 支持RFC 2136动态更新的DNS服务器
+	ProviderTypePowerDNS     ProviderType = "powerDNS"     // PowerDNS HTTP API
 )
 
 // FindAllProviderTypes 所有的服务商类型
@@ -30,6 +34,11 @@ func FindAllProviderTypes() []maps.Map {
 			"code":        ProviderTypeDNSPod,
 			"description": "DNSPod提供的DNS服务。",
 		},
+		{
+			"name":        "腾讯云DNS",
+			"code":        ProviderTypeTencentDNS,
+			"description": "通过腾讯云API密钥（SecretId和SecretKey）管理腾讯云DNS解析。",
+		},
 		{
 			"name":        "华为云DNS",
 			"code":        ProviderTypeHuaweiDNS,
@@ -40,6 +49,11 @@ func FindAllProviderTypes() []maps.Map {
 			"code":        ProviderTypeCloudFlare,
 			"description": "CloudFlare提供的DNS服务。",
 		},
+		{
+			"name":        "AWS Route 53",
+			"code":        ProviderTypeRoute53,
+			"description": "AWS Route 53以及兼容Route 53 API的DNS服务，支持地理位置、延迟和加权路由线路。",
+		},
 		{
 			"name":        "EdgeDNS API",
 			"code":        ProviderTypeEdgeDNSAPI,
@@ -49,6 +63,18 @@ func FindAllProviderTypes() []maps.Map {
 
 	typeMaps = filterTypeMaps(typeMaps)
 
+	typeMaps = append(typeMaps, maps.Map{
+		"name":        "RFC 2136 DNS",
+		"code":        ProviderTypeRFC2136,
+		"description": "通过RFC 2136动态更新协议和TSIG签名管理自建的BIND、Knot、PowerDNS等权威DNS服务器，需要服务器允许当前密钥进行区域传送（AXFR）。",
+	})
+
+	typeMaps = append(typeMaps, maps.Map{
+		"name":        "PowerDNS",
+		"code":        ProviderTypePowerDNS,
+		"description": "通过PowerDNS Authoritative Server提供的HTTP API管理自建的PowerDNS服务器，需要在服务器上启用API。",
+	})
+
 	typeMaps = append(typeMaps, maps.Map{
 		"name":        "自定义HTTP DNS",
 		"code":        ProviderTypeCustomHTTP,
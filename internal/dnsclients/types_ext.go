@@ -14,6 +14,10 @@ func FindProvider(providerType ProviderType, providerId int64) ProviderInterface
 		return &DNSPodProvider{
 			ProviderId: providerId,
 		}
+	case ProviderTypeTencentDNS:
+		return &TencentDNSProvider{
+			ProviderId: providerId,
+		}
 	case ProviderTypeAliDNS:
 		return &AliDNSProvider{
 			ProviderId: providerId,
@@ -26,6 +30,10 @@ func FindProvider(providerType ProviderType, providerId int64) ProviderInterface
 		return &CloudFlareProvider{
 			ProviderId: providerId,
 		}
+	case ProviderTypeRoute53:
+		return &Route53Provider{
+			ProviderId: providerId,
+		}
 	case ProviderTypeCustomHTTP:
 		return &CustomHTTPProvider{
 			ProviderId: providerId,
@@ -34,6 +42,14 @@ func FindProvider(providerType ProviderType, providerId int64) ProviderInterface
 		return &EdgeDNSAPIProvider{
 			ProviderId: providerId,
 		}
+	case ProviderTypeRFC2136:
+		return &RFC2136Provider{
+			ProviderId: providerId,
+		}
+	case ProviderTypePowerDNS:
+		return &PowerDNSProvider{
+			ProviderId: providerId,
+		}
 	}
 
 	return nil
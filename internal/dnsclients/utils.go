@@ -5,6 +5,7 @@ package dnsclients
 import (
 	"encoding/json"
 	"github.com/iwind/TeaGo/maps"
+	"strconv"
 	"strings"
 )
 
@@ -65,3 +66,25 @@ func UnmaskAPIParams(oldParamsJSON []byte, newParamsJSON []byte) (resultJSON []b
 	resultJSON, err = json.Marshal(newParams)
 	return
 }
+
+// 去除TXT记录值中的引号，多段值合并为一个
+func unquoteTXT(value string) string {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "\"") {
+		return value
+	}
+	var result = ""
+	for len(value) > 0 {
+		unquoted, err := strconv.QuotedPrefix(value)
+		if err != nil {
+			return result + value
+		}
+		s, err := strconv.Unquote(unquoted)
+		if err != nil {
+			return result + value
+		}
+		result += s
+		value = strings.TrimSpace(value[len(unquoted):])
+	}
+	return result
+}
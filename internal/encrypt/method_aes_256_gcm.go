@@ -0,0 +1,63 @@
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+)
+
+// AES256GCMMethod AES-256-GCM认证加密
+// 密文格式为：nonce + ciphertext + tag，iv作为附加认证数据（AAD）参与校验
+type AES256GCMMethod struct {
+	aead           cipher.AEAD
+	additionalData []byte
+}
+
+func (this *AES256GCMMethod) Init(key, iv []byte) error {
+	// 使用SHA256生成32位长度的key
+	var sum = sha256.Sum256(key)
+
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	this.aead = aead
+	this.additionalData = iv
+
+	return nil
+}
+
+func (this *AES256GCMMethod) Encrypt(src []byte) (dst []byte, err error) {
+	if len(src) == 0 {
+		return
+	}
+
+	var nonce = make([]byte, this.aead.NonceSize(), this.aead.NonceSize()+len(src)+this.aead.Overhead())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	dst = this.aead.Seal(nonce, nonce, src, this.additionalData)
+	return
+}
+
+func (this *AES256GCMMethod) Decrypt(dst []byte) (src []byte, err error) {
+	if len(dst) == 0 {
+		return
+	}
+
+	var nonceSize = this.aead.NonceSize()
+	if len(dst) < nonceSize+this.aead.Overhead() {
+		return nil, errors.New("invalid ciphertext")
+	}
+
+	return this.aead.Open(nil, dst[:nonceSize], dst[nonceSize:], this.additionalData)
+}
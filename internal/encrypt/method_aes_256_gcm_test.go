@@ -0,0 +1,66 @@
+package encrypt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAES256GCMMethod_Encrypt(t *testing.T) {
+	method, err := NewMethodInstance("aes-256-gcm", "abc", "123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var src = []byte("Hello, World")
+	dst, err := method.Encrypt(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log("dst:", len(dst))
+
+	src2, err := method.Decrypt(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, src2) {
+		t.Fatal("decrypt failed")
+	}
+	t.Log("src:", string(src2))
+}
+
+func TestAES256GCMMethod_Tamper(t *testing.T) {
+	method, err := NewMethodInstance("aes-256-gcm", "abc", "123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err := method.Encrypt([]byte("Hello, World"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst[len(dst)-1] ^= 1
+	_, err = method.Decrypt(dst)
+	if err == nil {
+		t.Fatal("tampered data should not be decrypted")
+	}
+	t.Log("expected error:", err)
+}
+
+func TestAES256GCMMethod_AdditionalData(t *testing.T) {
+	method, err := NewMethodInstance("aes-256-gcm", "abc", "123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err := method.Encrypt([]byte("Hello, World"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	method2, err := NewMethodInstance("aes-256-gcm", "abc", "456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = method2.Decrypt(dst)
+	if err == nil {
+		t.Fatal("data with different iv should not be decrypted")
+	}
+	t.Log("expected error:", err)
+}
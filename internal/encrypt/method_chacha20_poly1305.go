@@ -0,0 +1,58 @@
+package encrypt
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// ChaCha20Poly1305Method ChaCha20-Poly1305认证加密
+// 密文格式为：nonce + ciphertext + tag，iv作为附加认证数据（AAD）参与校验
+type ChaCha20Poly1305Method struct {
+	aead           cipher.AEAD
+	additionalData []byte
+}
+
+func (this *ChaCha20Poly1305Method) Init(key, iv []byte) error {
+	// 使用SHA256生成32位长度的key
+	var sum = sha256.Sum256(key)
+
+	aead, err := chacha20poly1305.New(sum[:])
+	if err != nil {
+		return err
+	}
+	this.aead = aead
+	this.additionalData = iv
+
+	return nil
+}
+
+func (this *ChaCha20Poly1305Method) Encrypt(src []byte) (dst []byte, err error) {
+	if len(src) == 0 {
+		return
+	}
+
+	var nonce = make([]byte, this.aead.NonceSize(), this.aead.NonceSize()+len(src)+this.aead.Overhead())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	dst = this.aead.Seal(nonce, nonce, src, this.additionalData)
+	return
+}
+
+func (this *ChaCha20Poly1305Method) Decrypt(dst []byte) (src []byte, err error) {
+	if len(dst) == 0 {
+		return
+	}
+
+	var nonceSize = this.aead.NonceSize()
+	if len(dst) < nonceSize+this.aead.Overhead() {
+		return nil, errors.New("invalid ciphertext")
+	}
+
+	return this.aead.Open(nil, dst[:nonceSize], dst[nonceSize:], this.additionalData)
+}
@@ -0,0 +1,51 @@
+package encrypt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestChaCha20Poly1305Method_Encrypt(t *testing.T) {
+	method, err := NewMethodInstance("chacha20-poly1305", "abc", "123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var src = []byte("Hello, World")
+	dst, err := method.Encrypt(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log("dst:", len(dst))
+
+	src2, err := method.Decrypt(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, src2) {
+		t.Fatal("decrypt failed")
+	}
+	t.Log("src:", string(src2))
+
+	// 篡改
+	dst[0] ^= 1
+	_, err = method.Decrypt(dst)
+	if err == nil {
+		t.Fatal("tampered data should not be decrypted")
+	}
+}
+
+func TestIsAEADMethod(t *testing.T) {
+	for method, isAEAD := range map[string]bool{
+		"raw":               false,
+		"aes-128-cfb":       false,
+		"aes-256-cfb":       false,
+		"aes-256-gcm":       true,
+		"chacha20-poly1305": true,
+		"":                  false,
+		"AES-256-GCM":       false,
+	} {
+		if IsAEADMethod(method) != isAEAD {
+			t.Fatal("method '"+method+"': expect", isAEAD, "got", !isAEAD)
+		}
+	}
+}
@@ -10,8 +10,15 @@ var methods = map[string]reflect.Type{
 	"aes-128-cfb": reflect.TypeOf(new(AES128CFBMethod)).Elem(),
 	"aes-192-cfb": reflect.TypeOf(new(AES192CFBMethod)).Elem(),
 	"aes-256-cfb": reflect.TypeOf(new(AES256CFBMethod)).Elem(),
+
+	// 认证加密（AEAD）
+	"aes-256-gcm":       reflect.TypeOf(new(AES256GCMMethod)).Elem(),
+	"chacha20-poly1305": reflect.TypeOf(new(ChaCha20Poly1305Method)).Elem(),
 }
 
+// 认证加密方法
+var aeadMethods = []string{"aes-256-gcm", "chacha20-poly1305"}
+
 func NewMethodInstance(method string, key string, iv string) (MethodInterface, error) {
 	valueType, ok := methods[method]
 	if !ok {
@@ -25,6 +32,16 @@ func NewMethodInstance(method string, key string, iv string) (MethodInterface, e
 	return instance, err
 }
 
+// IsAEADMethod 判断是否为认证加密方法
+func IsAEADMethod(method string) bool {
+	for _, aeadMethod := range aeadMethods {
+		if aeadMethod == method {
+			return true
+		}
+	}
+	return false
+}
+
 func RecoverMethodPanic(err interface{}) error {
 	if err != nil {
 		s, ok := err.(string)
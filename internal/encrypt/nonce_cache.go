@@ -0,0 +1,92 @@
+package encrypt
+
+import (
+	"sync"
+	"time"
+)
+
+type nonceCacheEntry struct {
+	nonce     string
+	expiresAt int64
+}
+
+// NonceCache 短期随机数缓存，用来拒绝重放的令牌
+// 缓存已满时先清理过期的数据，仍然不够时淘汰最早加入的数据，以免拒绝正常的请求
+type NonceCache struct {
+	m        map[string]int64  // nonce => expiresAt
+	queue    []nonceCacheEntry // 按加入顺序排列
+	head     int               // queue 中第一个有效数据的位置
+	maxItems int
+
+	locker sync.Mutex
+}
+
+func NewNonceCache(maxItems int) *NonceCache {
+	if maxItems <= 0 {
+		maxItems = 1_000_000
+	}
+	return &NonceCache{
+		m:        map[string]int64{},
+		maxItems: maxItems,
+	}
+}
+
+// Add 添加随机数，如果已经存在则返回false
+func (this *NonceCache) Add(nonce string, ttl time.Duration) (ok bool) {
+	var now = time.Now().Unix()
+	var expiresAt = now + int64(ttl.Seconds())
+	if expiresAt <= now {
+		expiresAt = now + 1
+	}
+
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	// 清理过期的数据
+	for this.head < len(this.queue) && this.queue[this.head].expiresAt < now {
+		this.pop()
+	}
+
+	existsExpiresAt, exists := this.m[nonce]
+	if exists && existsExpiresAt >= now {
+		return false
+	}
+
+	// 淘汰最早加入的数据
+	for len(this.m) >= this.maxItems && this.head < len(this.queue) {
+		this.pop()
+	}
+
+	this.m[nonce] = expiresAt
+	this.queue = append(this.queue, nonceCacheEntry{
+		nonce:     nonce,
+		expiresAt: expiresAt,
+	})
+	return true
+}
+
+// Count 缓存数量
+func (this *NonceCache) Count() int {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return len(this.m)
+}
+
+// 删除最早加入的数据
+func (this *NonceCache) pop() {
+	var entry = this.queue[this.head]
+	this.queue[this.head] = nonceCacheEntry{}
+	this.head++
+
+	// 同一个随机数过期后可能被再次加入，只删除和队列中过期时间一致的数据
+	expiresAt, ok := this.m[entry.nonce]
+	if ok && expiresAt == entry.expiresAt {
+		delete(this.m, entry.nonce)
+	}
+
+	// 压缩队列
+	if this.head >= 1024 && this.head*2 >= len(this.queue) {
+		this.queue = append([]nonceCacheEntry{}, this.queue[this.head:]...)
+		this.head = 0
+	}
+}
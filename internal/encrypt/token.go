@@ -0,0 +1,136 @@
+package encrypt
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/iwind/TeaGo/maps"
+	"time"
+)
+
+const (
+	DefaultTokenClockSkew = 5 * time.Minute // 默认允许的时钟偏差
+)
+
+var (
+	ErrTokenInvalid  = errors.New("invalid token")
+	ErrTokenExpired  = errors.New("token expired or clock skew too large")
+	ErrTokenReplayed = errors.New("token has been used")
+	ErrTokenMethod   = errors.New("token encrypt method not allowed")
+)
+
+var SharedNonceCache = NewNonceCache(0)
+
+// TokenOptions 令牌校验选项
+type TokenOptions struct {
+	ClockSkew   time.Duration // 允许的时钟偏差
+	AllowLegacy bool          // 是否允许非认证加密的旧令牌
+	NonceCache  *NonceCache   // 随机数缓存，为空时使用 SharedNonceCache
+}
+
+// EncodeToken 生成令牌
+// 使用认证加密方法时会自动加入 timestamp 和 nonce
+func EncodeToken(method string, secret string, nodeId string, payload maps.Map) (string, error) {
+	if payload == nil {
+		payload = maps.Map{}
+	}
+	if !payload.Has("timestamp") {
+		payload["timestamp"] = time.Now().Unix()
+	}
+	if IsAEADMethod(method) && len(payload.GetString("nonce")) == 0 {
+		var nonce = make([]byte, 16)
+		_, err := rand.Read(nonce)
+		if err != nil {
+			return "", err
+		}
+		payload["nonce"] = hex.EncodeToString(nonce)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	instance, err := NewMethodInstance(method, secret, nodeId)
+	if err != nil {
+		return "", err
+	}
+	data, err = instance.Encrypt(data)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// DecodeToken 解析并校验令牌
+func DecodeToken(method string, secret string, nodeId string, token string, options *TokenOptions) (maps.Map, error) {
+	if options == nil {
+		options = &TokenOptions{AllowLegacy: true}
+	}
+
+	var isAEAD = IsAEADMethod(method)
+	if !isAEAD && !options.AllowLegacy {
+		return nil, ErrTokenMethod
+	}
+
+	data, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := NewMethodInstance(method, secret, nodeId)
+	if err != nil {
+		return nil, err
+	}
+	data, err = instance.Decrypt(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, ErrTokenInvalid
+	}
+
+	var m = maps.Map{}
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		return nil, errors.New("decode token error: " + err.Error())
+	}
+
+	var clockSkew = options.ClockSkew
+	if clockSkew <= 0 {
+		clockSkew = DefaultTokenClockSkew
+	}
+
+	// 检查时间戳，旧格式的令牌保持原有的行为，不检查时钟偏差
+	if isAEAD {
+		var delta = time.Now().Unix() - m.GetInt64("timestamp")
+		if delta < 0 {
+			delta = -delta
+		}
+		if delta > int64(clockSkew.Seconds()) {
+			return nil, ErrTokenExpired
+		}
+	}
+
+	// 检查随机数
+	if isAEAD {
+		var nonce = m.GetString("nonce")
+		if len(nonce) < 16 {
+			return nil, ErrTokenInvalid
+		}
+
+		var nonceCache = options.NonceCache
+		if nonceCache == nil {
+			nonceCache = SharedNonceCache
+		}
+
+		// 令牌在时间窗口两侧都可能有效，所以缓存时间为两倍的时钟偏差
+		if !nonceCache.Add(nodeId+"@"+nonce, 2*clockSkew) {
+			return nil, ErrTokenReplayed
+		}
+	}
+
+	return m, nil
+}
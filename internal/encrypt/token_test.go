@@ -0,0 +1,149 @@
+package encrypt
+
+import (
+	"github.com/iwind/TeaGo/maps"
+	"testing"
+	"time"
+)
+
+func TestEncodeToken_AEAD(t *testing.T) {
+	var options = &TokenOptions{
+		NonceCache: NewNonceCache(100),
+	}
+
+	token, err := EncodeToken("aes-256-gcm", "secret", "node1", maps.Map{"type": "node"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := DecodeToken("aes-256-gcm", "secret", "node1", token, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(m)
+	if m.GetString("type") != "node" {
+		t.Fatal("invalid payload")
+	}
+
+	// 重放
+	_, err = DecodeToken("aes-256-gcm", "secret", "node1", token, options)
+	if err != ErrTokenReplayed {
+		t.Fatal("expect replayed error, but got:", err)
+	}
+
+	// 其他节点
+	_, err = DecodeToken("aes-256-gcm", "secret", "node2", token, options)
+	if err == nil {
+		t.Fatal("token should be bound to node")
+	}
+}
+
+func TestEncodeToken_Expired(t *testing.T) {
+	var options = &TokenOptions{
+		ClockSkew:  10 * time.Second,
+		NonceCache: NewNonceCache(100),
+	}
+
+	token, err := EncodeToken("chacha20-poly1305", "secret", "node1", maps.Map{
+		"type":      "node",
+		"timestamp": time.Now().Unix() - 60,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = DecodeToken("chacha20-poly1305", "secret", "node1", token, options)
+	if err != ErrTokenExpired {
+		t.Fatal("expect expired error, but got:", err)
+	}
+}
+
+func TestEncodeToken_Legacy(t *testing.T) {
+	token, err := EncodeToken("aes-256-cfb", "secret", "node1", maps.Map{"type": "node"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 兼容期间可以重复使用
+	for i := 0; i < 2; i++ {
+		_, err = DecodeToken("aes-256-cfb", "secret", "node1", token, &TokenOptions{AllowLegacy: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 旧格式的令牌不检查时钟偏差
+	token, err = EncodeToken("aes-256-cfb", "secret", "node1", maps.Map{
+		"type":      "node",
+		"timestamp": time.Now().Unix() - 3600,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = DecodeToken("aes-256-cfb", "secret", "node1", token, &TokenOptions{AllowLegacy: true, ClockSkew: 10 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = DecodeToken("aes-256-cfb", "secret", "node1", token, &TokenOptions{AllowLegacy: false})
+	if err != ErrTokenMethod {
+		t.Fatal("expect method error, but got:", err)
+	}
+}
+
+func TestNonceCache_Add(t *testing.T) {
+	var cache = NewNonceCache(2)
+	if !cache.Add("a", time.Minute) {
+		t.Fatal("'a' should be added")
+	}
+	if cache.Add("a", time.Minute) {
+		t.Fatal("repeated nonce should be rejected")
+	}
+	if !cache.Add("b", time.Minute) {
+		t.Fatal("'b' should be added")
+	}
+
+	// 缓存已满时淘汰最早的数据
+	if !cache.Add("c", time.Minute) {
+		t.Fatal("'c' should be added when cache is full")
+	}
+	if cache.Count() != 2 {
+		t.Fatal("expect 2 items, got", cache.Count())
+	}
+	if cache.Add("b", time.Minute) || cache.Add("c", time.Minute) {
+		t.Fatal("recent nonces should be kept")
+	}
+	if !cache.Add("a", time.Minute) {
+		t.Fatal("evicted nonce should be added again")
+	}
+}
+
+func TestNonceCache_Full(t *testing.T) {
+	var cache = NewNonceCache(100)
+	var options = &TokenOptions{NonceCache: cache}
+	for i := 0; i < 1000; i++ {
+		token, err := EncodeToken("aes-256-gcm", "secret", "node1", maps.Map{"type": "node"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = DecodeToken("aes-256-gcm", "secret", "node1", token, options)
+		if err != nil {
+			t.Fatal(i, err)
+		}
+	}
+	if cache.Count() != 100 {
+		t.Fatal("expect 100 items, got", cache.Count())
+	}
+}
+
+func TestNonceCache_Expired(t *testing.T) {
+	var cache = NewNonceCache(10)
+	cache.m["a"] = time.Now().Unix() - 1
+	cache.queue = append(cache.queue, nonceCacheEntry{nonce: "a", expiresAt: cache.m["a"]})
+	if !cache.Add("a", time.Minute) {
+		t.Fatal("expired nonce should be added again")
+	}
+	if !cache.Add("b", time.Minute) || cache.Count() != 2 {
+		t.Fatal("unexpected count", cache.Count())
+	}
+}
@@ -1,5 +1,13 @@
 package installers
 
+import (
+	"errors"
+	"github.com/dashenmiren/EdgeAPI/internal/db/models"
+	"github.com/iwind/TeaGo/types"
+	"golang.org/x/crypto/ssh"
+	"net"
+)
+
 type Credentials struct {
 	Host       string
 	Port       int
@@ -9,4 +17,55 @@ type Credentials struct {
 	Passphrase string
 	Method     string
 	Sudo       bool
+
+	HostKeyCallback ssh.HostKeyCallback // 主机公钥校验，为空时不校验
+	JumpHosts       []*Credentials      // 跳板机，按顺序连接
+}
+
+// NewGrantCredentials 根据认证信息构造登录信息
+// 包含认证中设置的跳板机，跳板机的认证中设置的跳板机不会被使用
+func NewGrantCredentials(grant *models.NodeGrant, host string, port int) (*Credentials, error) {
+	var credentials = newGrantCredentials(grant, host, port)
+
+	jumpHosts, err := grant.DecodeJumpHosts()
+	if err != nil {
+		return nil, newGrantError("decode jump hosts failed: " + err.Error())
+	}
+	if len(jumpHosts) > models.NodeGrantMaxJumpHosts {
+		return nil, newGrantError("too many jump hosts, max: " + types.String(models.NodeGrantMaxJumpHosts))
+	}
+	for _, jumpHost := range jumpHosts {
+		jumpGrant, err := models.SharedNodeGrantDAO.FindEnabledNodeGrant(nil, jumpHost.GrantId)
+		if err != nil {
+			return nil, err
+		}
+		if jumpGrant == nil {
+			return nil, newGrantError("can not find grant with id '" + types.String(jumpHost.GrantId) + "' for jump host '" + jumpHost.Host + "'")
+		}
+		credentials.JumpHosts = append(credentials.JumpHosts, newGrantCredentials(jumpGrant, jumpHost.Host, jumpHost.Port))
+	}
+
+	return credentials, nil
+}
+
+// IsHostKeyError 判断是否为主机公钥校验错误
+func IsHostKeyError(err error) bool {
+	return errors.Is(err, models.ErrNodeGrantHostKeyMismatch) || errors.Is(err, models.ErrNodeGrantHostKeyNotApproved)
+}
+
+func newGrantCredentials(grant *models.NodeGrant, host string, port int) *Credentials {
+	var grantId = int64(grant.Id)
+	return &Credentials{
+		Host:       host,
+		Port:       port,
+		Username:   grant.Username,
+		Password:   grant.Password,
+		PrivateKey: grant.PrivateKey,
+		Passphrase: grant.Passphrase,
+		Method:     grant.Method,
+		Sudo:       grant.Su == 1,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			return models.SharedNodeGrantDAO.VerifyHostKey(nil, grantId, hostname, key.Type(), ssh.FingerprintSHA256(key))
+		},
+	}
 }
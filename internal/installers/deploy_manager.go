@@ -86,6 +86,25 @@ func (this *DeployManager) FindNodeFile(os string, arch string) *DeployFile {
 	return nil
 }
 
+// FindNodeFileWithVersion 查找特别平台某个版本的节点文件
+// 用于回滚到以前的版本，所以不只在最新版本的文件中查找
+func (this *DeployManager) FindNodeFileWithVersion(os string, arch string, version string) *DeployFile {
+	var name = "edge-node-" + os + "-" + arch + "-v" + version + ".zip"
+	if !regexp.MustCompile(`^edge-node-(\w+)-(\w+)-v([0-9.]+)\.zip$`).MatchString(name) {
+		return nil
+	}
+	var file = files.NewFile(this.dir + "/" + name)
+	if !file.IsFile() {
+		return nil
+	}
+	return &DeployFile{
+		OS:      os,
+		Arch:    arch,
+		Version: version,
+		Path:    file.Path(),
+	}
+}
+
 // LoadNSNodeFiles 加载所有NS节点安装文件
 func (this *DeployManager) LoadNSNodeFiles() []*DeployFile {
 	this.locker.Lock()
@@ -46,13 +46,22 @@ func (this *NodeInstaller) Install(dir string, params interface{}, installStatus
 	}
 
 	// 上传安装文件
-	var filePrefix = "edge-node-" + env.OS + "-" + env.Arch
-	zipFile, err := this.LookupLatestInstaller(filePrefix)
-	if err != nil {
-		return err
-	}
-	if len(zipFile) == 0 {
-		return errors.New("can not find installer file for " + env.OS + "/" + env.Arch)
+	var zipFile string
+	if len(nodeParams.Version) > 0 {
+		var deployFile = SharedDeployManager.FindNodeFileWithVersion(env.OS, env.Arch, nodeParams.Version)
+		if deployFile == nil {
+			return errors.New("can not find installer file for " + env.OS + "/" + env.Arch + " v" + nodeParams.Version)
+		}
+		zipFile = deployFile.Path
+	} else {
+		var filePrefix = "edge-node-" + env.OS + "-" + env.Arch
+		zipFile, err = this.LookupLatestInstaller(filePrefix)
+		if err != nil {
+			return err
+		}
+		if len(zipFile) == 0 {
+			return errors.New("can not find installer file for " + env.OS + "/" + env.Arch)
+		}
 	}
 	var targetZip = ""
 	var firstCopyErr error
@@ -9,7 +9,8 @@ type NodeParams struct {
 	Endpoints   []string
 	NodeId      string
 	Secret      string
-	IsUpgrading bool // 是否为升级
+	IsUpgrading bool   // 是否为升级
+	Version     string // 指定安装的版本，为空时安装最新版本
 }
 
 func (this *NodeParams) Validate() error {
@@ -27,6 +27,11 @@ func SharedNodeQueue() *NodeQueue {
 
 // InstallNodeProcess 安装边缘节点流程控制
 func (this *NodeQueue) InstallNodeProcess(nodeId int64, isUpgrading bool) error {
+	return this.InstallNodeProcessWithVersion(nodeId, isUpgrading, "")
+}
+
+// InstallNodeProcessWithVersion 安装某个版本的边缘节点，version 为空时安装最新版本
+func (this *NodeQueue) InstallNodeProcessWithVersion(nodeId int64, isUpgrading bool, version string) error {
 	var installStatus = models.NewNodeInstallStatus()
 	installStatus.IsRunning = true
 	installStatus.UpdatedAt = time.Now().Unix()
@@ -53,7 +58,7 @@ func (this *NodeQueue) InstallNodeProcess(nodeId int64, isUpgrading bool) error
 	}()
 
 	// 开始安装
-	err = this.InstallNode(nodeId, installStatus, isUpgrading)
+	err = this.installNode(nodeId, installStatus, isUpgrading, version)
 
 	// 安装结束
 	installStatus.IsRunning = false
@@ -81,6 +86,10 @@ func (this *NodeQueue) InstallNodeProcess(nodeId int64, isUpgrading bool) error
 
 // InstallNode 安装边缘节点
 func (this *NodeQueue) InstallNode(nodeId int64, installStatus *models.NodeInstallStatus, isUpgrading bool) error {
+	return this.installNode(nodeId, installStatus, isUpgrading, "")
+}
+
+func (this *NodeQueue) installNode(nodeId int64, installStatus *models.NodeInstallStatus, isUpgrading bool, version string) error {
 	node, err := models.SharedNodeDAO.FindEnabledNode(nil, nodeId)
 	if err != nil {
 		return err
@@ -179,21 +188,25 @@ func (this *NodeQueue) InstallNode(nodeId int64, installStatus *models.NodeInsta
 		NodeId:      node.UniqueId,
 		Secret:      node.Secret,
 		IsUpgrading: isUpgrading,
+		Version:     version,
+	}
+
+	credentials, err := NewGrantCredentials(grant, loginParams.Host, loginParams.Port)
+	if err != nil {
+		if IsGrantError(err) {
+			installStatus.ErrorCode = "EMPTY_GRANT"
+		}
+		return err
 	}
 
 	var installer = &NodeInstaller{}
-	err = installer.Login(&Credentials{
-		Host:       loginParams.Host,
-		Port:       loginParams.Port,
-		Username:   grant.Username,
-		Password:   grant.Password,
-		PrivateKey: grant.PrivateKey,
-		Passphrase: grant.Passphrase,
-		Method:     grant.Method,
-		Sudo:       grant.Su == 1,
-	})
+	err = installer.Login(credentials)
 	if err != nil {
-		installStatus.ErrorCode = "SSH_LOGIN_FAILED"
+		if IsHostKeyError(err) {
+			installStatus.ErrorCode = "SSH_HOST_KEY_NOT_TRUSTED"
+		} else {
+			installStatus.ErrorCode = "SSH_LOGIN_FAILED"
+		}
 		return err
 	}
 	defer func() {
@@ -290,17 +303,13 @@ func (this *NodeQueue) StartNode(nodeId int64) error {
 		return newGrantError("can not find user grant with id '" + numberutils.FormatInt64(loginParams.GrantId) + "'")
 	}
 
+	credentials, err := NewGrantCredentials(grant, loginParams.Host, loginParams.Port)
+	if err != nil {
+		return err
+	}
+
 	var installer = &NodeInstaller{}
-	err = installer.Login(&Credentials{
-		Host:       loginParams.Host,
-		Port:       loginParams.Port,
-		Username:   grant.Username,
-		Password:   grant.Password,
-		PrivateKey: grant.PrivateKey,
-		Passphrase: grant.Passphrase,
-		Method:     grant.Method,
-		Sudo:       grant.Su == 1,
-	})
+	err = installer.Login(credentials)
 	if err != nil {
 		return err
 	}
@@ -400,17 +409,13 @@ func (this *NodeQueue) StopNode(nodeId int64) error {
 		return errors.New("can not find user grant with id '" + numberutils.FormatInt64(loginParams.GrantId) + "'")
 	}
 
+	credentials, err := NewGrantCredentials(grant, loginParams.Host, loginParams.Port)
+	if err != nil {
+		return err
+	}
+
 	var installer = &NodeInstaller{}
-	err = installer.Login(&Credentials{
-		Host:       loginParams.Host,
-		Port:       loginParams.Port,
-		Username:   grant.Username,
-		Password:   grant.Password,
-		PrivateKey: grant.PrivateKey,
-		Passphrase: grant.Passphrase,
-		Method:     grant.Method,
-		Sudo:       grant.Su == 1,
-	})
+	err = installer.Login(credentials)
 	if err != nil {
 		return err
 	}
@@ -510,17 +515,13 @@ func (this *NodeQueue) UninstallNode(nodeId int64) error {
 		return errors.New("can not find user grant with id '" + numberutils.FormatInt64(loginParams.GrantId) + "'")
 	}
 
+	credentials, err := NewGrantCredentials(grant, loginParams.Host, loginParams.Port)
+	if err != nil {
+		return err
+	}
+
 	var installer = &NodeInstaller{}
-	err = installer.Login(&Credentials{
-		Host:       loginParams.Host,
-		Port:       loginParams.Port,
-		Username:   grant.Username,
-		Password:   grant.Password,
-		PrivateKey: grant.PrivateKey,
-		Passphrase: grant.Passphrase,
-		Method:     grant.Method,
-		Sudo:       grant.Su == 1,
-	})
+	err = installer.Login(credentials)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,167 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package installers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dashenmiren/EdgeCommon/pkg/configutils"
+	"golang.org/x/crypto/ssh"
+	"net"
+	"strconv"
+	"time"
+)
+
+const sshDialTimeout = 5 * time.Second // TODO 后期可以设置这个超时时间
+
+// SSHConnection SSH连接，可能经过多个跳板机
+type SSHConnection struct {
+	Client *ssh.Client
+
+	jumpClients []*ssh.Client
+}
+
+// DialSSH 连接SSH服务
+// 如果设置了跳板机，则依次通过跳板机连接到目标主机
+func DialSSH(credentials *Credentials) (*SSHConnection, error) {
+	var hops = append(append([]*Credentials{}, credentials.JumpHosts...), credentials)
+	var conn = &SSHConnection{}
+	for index, hop := range hops {
+		config, err := sshClientConfig(hop)
+		if err != nil {
+			_ = conn.Close()
+			if index < len(hops)-1 {
+				return nil, fmt.Errorf("jump host #%d: %w", index+1, err)
+			}
+			return nil, err
+		}
+
+		var addr = configutils.QuoteIP(hop.Host) + ":" + strconv.Itoa(hop.Port)
+		var client *ssh.Client
+		if conn.Client == nil {
+			client, err = ssh.Dial("tcp", addr, config)
+		} else {
+			client, err = dialSSHThrough(conn.Client, addr, config)
+		}
+		if err != nil {
+			_ = conn.Close()
+			if index < len(hops)-1 {
+				return nil, fmt.Errorf("connect jump host '%s' failed: %w", addr, err)
+			}
+			return nil, err
+		}
+		if conn.Client != nil {
+			conn.jumpClients = append(conn.jumpClients, conn.Client)
+		}
+		conn.Client = client
+	}
+	return conn, nil
+}
+
+// Close 关闭连接
+func (this *SSHConnection) Close() error {
+	var err error
+	if this.Client != nil {
+		err = this.Client.Close()
+	}
+	this.closeJumpClients()
+	return err
+}
+
+// 从后往前关闭跳板机连接
+func (this *SSHConnection) closeJumpClients() {
+	for i := len(this.jumpClients) - 1; i >= 0; i-- {
+		_ = this.jumpClients[i].Close()
+	}
+	this.jumpClients = nil
+}
+
+// 通过跳板机连接
+func dialSSHThrough(jumpClient *ssh.Client, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	netConn, err := jumpClient.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// 跳板机通道不支持设置Deadline，这里超时后直接关闭
+	var timer = time.AfterFunc(config.Timeout, func() {
+		_ = netConn.Close()
+	})
+	clientConn, chans, reqs, err := ssh.NewClientConn(netConn, addr, config)
+	if !timer.Stop() && err == nil {
+		_ = clientConn.Close()
+		return nil, errors.New("ssh: handshake timeout")
+	}
+	if err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(clientConn, chans, reqs), nil
+}
+
+// 构造SSH客户端配置
+func sshClientConfig(credentials *Credentials) (*ssh.ClientConfig, error) {
+	// 检查参数
+	if len(credentials.Host) == 0 {
+		return nil, errors.New("'host' should not be empty")
+	}
+	if credentials.Port <= 0 {
+		return nil, errors.New("'port' should be greater than 0")
+	}
+	if len(credentials.Password) == 0 && len(credentials.PrivateKey) == 0 {
+		return nil, errors.New("require user 'password' or 'privateKey'")
+	}
+
+	// 没有设置时不校验主机公钥
+	var hostKeyCallback = credentials.HostKeyCallback
+	if hostKeyCallback == nil {
+		hostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			return nil
+		}
+	}
+
+	// 认证
+	var methods = []ssh.AuthMethod{}
+	if credentials.Method == "user" {
+		{
+			var authMethod = ssh.Password(credentials.Password)
+			methods = append(methods, authMethod)
+		}
+
+		{
+			authMethod := ssh.KeyboardInteractive(func(user, instruction string, questions []string, echos []bool) (answers []string, err error) {
+				if len(questions) == 0 {
+					return []string{}, nil
+				}
+				return []string{credentials.Password}, nil
+			})
+			methods = append(methods, authMethod)
+		}
+	} else if credentials.Method == "privateKey" {
+		var signer ssh.Signer
+		var err error
+		if len(credentials.Passphrase) > 0 {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(credentials.PrivateKey), []byte(credentials.Passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey([]byte(credentials.PrivateKey))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse private key: %w", err)
+		}
+		authMethod := ssh.PublicKeys(signer)
+		methods = append(methods, authMethod)
+	} else {
+		return nil, errors.New("invalid method '" + credentials.Method + "'")
+	}
+
+	// SSH客户端
+	if len(credentials.Username) == 0 {
+		credentials.Username = "root"
+	}
+	return &ssh.ClientConfig{
+		User:            credentials.Username,
+		Auth:            methods,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         sshDialTimeout,
+	}, nil
+}
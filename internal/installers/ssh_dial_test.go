@@ -0,0 +1,248 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package installers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const testSSHPassword = "123456"
+
+// 测试用的SSH服务，支持sftp和端口转发
+type testSSHServer struct {
+	Host    string
+	Port    int
+	HostKey ssh.PublicKey
+
+	countForwards int32
+}
+
+func newTestSSHServer(t *testing.T) *testSSHServer {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config = &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == "root" && string(password) == testSSHPassword {
+				return nil, nil
+			}
+			return nil, errors.New("invalid password")
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	var server = &testSSHServer{
+		Host:    "127.0.0.1",
+		Port:    listener.Addr().(*net.TCPAddr).Port,
+		HostKey: signer.PublicKey(),
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn, config)
+		}
+	}()
+	return server
+}
+
+func (this *testSSHServer) Addr() string {
+	return this.Host + ":" + strconv.Itoa(this.Port)
+}
+
+func (this *testSSHServer) Credentials() *Credentials {
+	return &Credentials{
+		Host:     this.Host,
+		Port:     this.Port,
+		Username: "root",
+		Password: testSSHPassword,
+		Method:   "user",
+	}
+}
+
+func (this *testSSHServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		switch newChannel.ChannelType() {
+		case "session":
+			channel, requests, err := newChannel.Accept()
+			if err != nil {
+				continue
+			}
+			go func() {
+				for req := range requests {
+					var isSFTP = req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+					_ = req.Reply(isSFTP, nil)
+					if isSFTP {
+						sftpServer, err := sftp.NewServer(channel)
+						if err == nil {
+							_ = sftpServer.Serve()
+						}
+						_ = channel.Close()
+					}
+				}
+			}()
+		case "direct-tcpip":
+			var payload = struct {
+				Host       string
+				Port       uint32
+				OriginHost string
+				OriginPort uint32
+			}{}
+			err = ssh.Unmarshal(newChannel.ExtraData(), &payload)
+			if err != nil {
+				_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			targetConn, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+			if err != nil {
+				_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			channel, requests, err := newChannel.Accept()
+			if err != nil {
+				_ = targetConn.Close()
+				continue
+			}
+			atomic.AddInt32(&this.countForwards, 1)
+			go ssh.DiscardRequests(requests)
+			go func() {
+				_, _ = io.Copy(targetConn, channel)
+				_ = targetConn.Close()
+			}()
+			go func() {
+				_, _ = io.Copy(channel, targetConn)
+				_ = channel.Close()
+			}()
+		default:
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+		}
+	}
+}
+
+// 只信任某个公钥，同时记录校验过的主机
+func testFixedHostKeyCallback(hostKey ssh.PublicKey, hosts *[]string) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		*hosts = append(*hosts, hostname)
+		if ssh.FingerprintSHA256(key) != ssh.FingerprintSHA256(hostKey) {
+			return errTestHostKeyMismatch
+		}
+		return nil
+	}
+}
+
+var errTestHostKeyMismatch = errors.New("host key mismatch")
+
+func TestDialSSH_HostKey(t *testing.T) {
+	var server = newTestSSHServer(t)
+	var otherServer = newTestSSHServer(t)
+
+	var hosts = []string{}
+	var credentials = server.Credentials()
+	credentials.HostKeyCallback = testFixedHostKeyCallback(server.HostKey, &hosts)
+	conn, err := DialSSH(credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.Close()
+	if len(hosts) != 1 || hosts[0] != server.Addr() {
+		t.Fatal("unexpected verified hosts:", hosts)
+	}
+
+	// 主机公钥不匹配
+	credentials = server.Credentials()
+	credentials.HostKeyCallback = testFixedHostKeyCallback(otherServer.HostKey, &hosts)
+	_, err = DialSSH(credentials)
+	if !errors.Is(err, errTestHostKeyMismatch) {
+		t.Fatal("expect host key mismatch error, got", err)
+	}
+}
+
+func TestBaseInstaller_Login_JumpHosts(t *testing.T) {
+	var jumpServer1 = newTestSSHServer(t)
+	var jumpServer2 = newTestSSHServer(t)
+	var server = newTestSSHServer(t)
+
+	var hosts = []string{}
+	var credentials = server.Credentials()
+	credentials.HostKeyCallback = testFixedHostKeyCallback(server.HostKey, &hosts)
+	for _, jumpServer := range []*testSSHServer{jumpServer1, jumpServer2} {
+		var jumpCredentials = jumpServer.Credentials()
+		jumpCredentials.HostKeyCallback = testFixedHostKeyCallback(jumpServer.HostKey, &hosts)
+		credentials.JumpHosts = append(credentials.JumpHosts, jumpCredentials)
+	}
+
+	var installer = &BaseInstaller{}
+	err := installer.Login(credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = installer.client.sftp.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = installer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(hosts, ",") != strings.Join([]string{jumpServer1.Addr(), jumpServer2.Addr(), server.Addr()}, ",") {
+		t.Fatal("unexpected verified hosts:", hosts)
+	}
+	if atomic.LoadInt32(&jumpServer1.countForwards) != 1 || atomic.LoadInt32(&jumpServer2.countForwards) != 1 {
+		t.Fatal("expect forwarded through jump hosts")
+	}
+}
+
+func TestDialSSH_JumpHostKeyMismatch(t *testing.T) {
+	var jumpServer = newTestSSHServer(t)
+	var server = newTestSSHServer(t)
+
+	var hosts = []string{}
+	var credentials = server.Credentials()
+	var jumpCredentials = jumpServer.Credentials()
+	jumpCredentials.HostKeyCallback = testFixedHostKeyCallback(server.HostKey, &hosts)
+	credentials.JumpHosts = []*Credentials{jumpCredentials}
+
+	_, err := DialSSH(credentials)
+	if !errors.Is(err, errTestHostKeyMismatch) {
+		t.Fatal("expect host key mismatch error, got", err)
+	}
+	if !strings.Contains(err.Error(), "jump host") {
+		t.Fatal("error should mention jump host:", err)
+	}
+	if atomic.LoadInt32(&server.countForwards) != 0 || atomic.LoadInt32(&jumpServer.countForwards) != 0 {
+		t.Fatal("should not connect to target host")
+	}
+}
@@ -14,6 +14,8 @@ import (
 	"github.com/dashenmiren/EdgeAPI/internal/goman"
 	"github.com/dashenmiren/EdgeAPI/internal/remotelogs"
 	"github.com/dashenmiren/EdgeAPI/internal/rpc"
+	rpcutils "github.com/dashenmiren/EdgeAPI/internal/rpc/utils"
+	"github.com/dashenmiren/EdgeAPI/internal/secrets"
 	"github.com/dashenmiren/EdgeAPI/internal/setup"
 	"github.com/dashenmiren/EdgeAPI/internal/utils"
 	"github.com/dashenmiren/EdgeCommon/pkg/iplibrary"
@@ -27,7 +29,10 @@ import (
 	"github.com/iwind/TeaGo/types"
 	"github.com/iwind/gosock/pkg/gosock"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 	"log"
 	"net"
@@ -172,10 +177,28 @@ func (this *APINode) Start() {
 	}
 	config.SetNumberId(int64(apiNode.Id))
 
+	// 主密钥
+	err = this.loadSecrets()
+	if err != nil {
+		var errString = "load secrets master key failed: " + err.Error()
+		logs.Println("[API_NODE]" + errString)
+		this.addStartIssue("secrets", errString, "请检查环境变量`"+secrets.EnvKey+"`或者`configs/"+secrets.KeyFileName+"`中的主密钥，格式为`id:base64(32字节)`")
+		return
+	}
+
 	// 清除上一次启动错误
 	// 这个错误文件可能不存在，不需要处理错误
 	_ = os.Remove(this.issuesFile)
 
+	// 主节点选举
+	goman.New(func() {
+		var leaseConfig = config.PrimaryLease
+		models.SharedAPINodePrimaryElector.Start(int64(apiNode.Id), leaseConfig.TTLSeconds(), leaseConfig.RenewInterval())
+	})
+	events.On(events.EventQuit, func() {
+		models.SharedAPINodePrimaryElector.Stop()
+	})
+
 	// 设置rlimit
 	_ = utils.SetRLimit(1024 * 1024)
 
@@ -188,6 +211,12 @@ func (this *APINode) Start() {
 	this.setProgress("ACCESS_LOG_STORAGES", "正在启动访问日志存储器")
 	this.startAccessLogStorages()
 
+	// 初始化REST服务
+	this.setupRestServices()
+
+	// 加载限流配置
+	this.loadRateLimitConfig()
+
 	// 监听RPC服务
 	this.setProgress("LISTEN_PORT", "正在启动监听端口")
 	remotelogs.Println("API_NODE", "starting RPC server ...")
@@ -277,6 +306,7 @@ func (this *APINode) listenRPC(listener net.Listener, tlsConfig *tls.Config) err
 		grpc.MaxRecvMsgSize(512 << 20),
 		grpc.MaxSendMsgSize(512 << 20),
 		grpc.UnaryInterceptor(this.unaryInterceptor),
+		grpc.StreamInterceptor(this.streamInterceptor),
 	}
 
 	if tlsConfig == nil {
@@ -685,6 +715,50 @@ func (this *APINode) listenPorts(apiNode *models.APINode) (isListening bool) {
 		}
 	}
 
+	// Metrics HTTP
+	metricsHTTPConfig, err := apiNode.DecodeMetricsHTTP()
+	if err != nil {
+		remotelogs.Error("API_NODE", "decode metrics http config: "+err.Error())
+		return
+	}
+	if metricsHTTPConfig != nil && metricsHTTPConfig.IsOn && len(metricsHTTPConfig.Listen) > 0 {
+		var metricsConfig *configs.MetricsConfig
+		if sharedAPIConfig != nil {
+			metricsConfig = sharedAPIConfig.Metrics
+		}
+		err = metricsConfig.Init()
+		if err != nil {
+			remotelogs.Error("API_NODE", "init metrics config failed: "+err.Error())
+			return
+		}
+
+		for _, listen := range metricsHTTPConfig.Listen {
+			for _, addr := range listen.Addresses() {
+				// 收集Port
+				_, portString, _ := net.SplitHostPort(addr)
+				var port = types.Int(portString)
+				if port > 0 && !lists.ContainsInt(ports, port) {
+					ports = append(ports, port)
+				}
+
+				listener, err := net.Listen("tcp", addr)
+				if err != nil {
+					remotelogs.Error("API_NODE", "listening metrics 'http://"+addr+"' failed: "+err.Error())
+					continue
+				}
+				goman.New(func() {
+					remotelogs.Println("API_NODE", "listening metrics http://"+addr+"/metrics ...")
+					server := NewMetricsServer(metricsConfig)
+					err := server.Listen(listener)
+					if err != nil {
+						remotelogs.Error("API_NODE", "listening metrics 'http://"+addr+"' failed: "+err.Error())
+						return
+					}
+				})
+			}
+		}
+	}
+
 	// add to local firewall
 	if len(ports) > 0 {
 		go utils.AddPortsToFirewall(ports)
@@ -798,6 +872,62 @@ func (this *APINode) listenSock() error {
 						"code": teaconst.InstanceCode,
 					},
 				})
+			case "secrets.migrate", "secrets.rotate": // 加密或轮换数据库中的敏感信息
+				result, err := this.resealSecrets(cmd.Code == "secrets.rotate")
+				if err != nil {
+					_ = cmd.Reply(&gosock.Command{
+						Params: map[string]any{
+							"isOk": false,
+							"err":  err.Error(),
+						},
+					})
+				} else {
+					_ = cmd.Reply(&gosock.Command{
+						Params: map[string]any{
+							"isOk":   true,
+							"result": result,
+						},
+					})
+				}
+			case "files.migrate": // 迁移文件片段到其他存储
+				count, err := this.migrateFiles(maps.NewMap(cmd.Params).GetString("storage"))
+				if err != nil {
+					_ = cmd.Reply(&gosock.Command{
+						Params: map[string]any{
+							"isOk":  false,
+							"err":   err.Error(),
+							"count": count,
+						},
+					})
+				} else {
+					_ = cmd.Reply(&gosock.Command{
+						Params: map[string]any{
+							"isOk":  true,
+							"count": count,
+						},
+					})
+				}
+			case "ipItems.compact": // 合并IP名单中重叠的IP范围
+				var params = maps.NewMap(cmd.Params)
+				result, err := this.compactIPItems(params.GetInt64("listId"), params.GetBool("dryRun"))
+				if err != nil {
+					_ = cmd.Reply(&gosock.Command{
+						Params: map[string]any{
+							"isOk": false,
+							"err":  err.Error(),
+						},
+					})
+				} else {
+					_ = cmd.Reply(&gosock.Command{
+						Params: map[string]any{
+							"isOk":         true,
+							"countLists":   result.CountLists,
+							"countItems":   result.CountItems,
+							"countMerged":  result.CountMerged,
+							"countCreated": result.CountCreated,
+						},
+					})
+				}
 			case "lookupToken":
 				var role = maps.NewMap(cmd.Params).GetString("role")
 				switch role {
@@ -852,6 +982,26 @@ func (this *APINode) listenSock() error {
 
 // 服务过滤器
 func (this *APINode) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	// 监控指标
+	var startTime = time.Now()
+	defer func() {
+		recordRPCMetrics(info.FullMethod, startTime, err)
+	}()
+
+	// 使用AccessToken调用
+	var originCtx = ctx
+	ctx, err = this.accessTokenContext(ctx, info.FullMethod)
+	if err != nil {
+		return nil, status.Error(codes.PermissionDenied, "'"+info.FullMethod+"()' says: "+err.Error())
+	}
+
+	// 限流
+	ctx, role, identity := this.callerIdentity(ctx)
+	err = this.checkRateLimit(originCtx, role, identity, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
 	if teaconst.Debug {
 		var before = time.Now()
 		var traceCtx = rpc.NewContext(ctx)
@@ -885,6 +1035,43 @@ func (this *APINode) unaryInterceptor(ctx context.Context, req any, info *grpc.U
 	return result, err
 }
 
+// 如果请求中带有AccessToken，则校验令牌及其权限范围，并转换为对应角色的上下文
+func (this *APINode) accessTokenContext(ctx context.Context, fullMethod string) (context.Context, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx, nil
+	}
+	var tokens = md.Get("x-edge-access-token")
+	if len(tokens) == 0 || len(tokens[0]) == 0 {
+		return ctx, nil
+	}
+
+	// fullMethod: /pb.NodeService/FindEnabledNode
+	var serviceName = ""
+	var methodName = ""
+	var pieces = strings.Split(strings.TrimPrefix(fullMethod, "/"), "/")
+	if len(pieces) == 2 {
+		serviceName = pieces[0]
+		var index = strings.LastIndex(serviceName, ".")
+		if index >= 0 {
+			serviceName = serviceName[index+1:]
+		}
+		methodName = pieces[1]
+	}
+
+	var remoteIP = ""
+	p, ok := peer.FromContext(ctx)
+	if ok && p.Addr != nil {
+		remoteIP, _, _ = net.SplitHostPort(p.Addr.String())
+	}
+
+	plainCtx, err := rpcutils.ValidateAccessToken(tokens[0], serviceName, methodName, remoteIP)
+	if err != nil {
+		return nil, err
+	}
+	return plainCtx, nil
+}
+
 // 添加启动相关的Issue
 func (this *APINode) addStartIssue(code string, message string, suggestion string) {
 	this.issues = append(this.issues, NewStartIssue(code, message, suggestion))
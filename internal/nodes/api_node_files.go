@@ -0,0 +1,38 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package nodes
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/db/models"
+	"github.com/dashenmiren/EdgeAPI/internal/remotelogs"
+	"github.com/iwind/TeaGo/types"
+	"sync"
+)
+
+var migrateFilesLocker = &sync.Mutex{}
+
+// 将文件片段迁移到指定的存储，storageType 为空时迁移到当前配置的存储
+func (this *APINode) migrateFiles(storageType string) (count int64, err error) {
+	migrateFilesLocker.Lock()
+	defer migrateFilesLocker.Unlock()
+
+	if len(storageType) == 0 {
+		storage, err := models.CurrentFileChunkStorage()
+		if err != nil {
+			return 0, err
+		}
+		storageType = storage.Type()
+	}
+
+	remotelogs.Println("FILE_STORAGE", "migrating file chunks to '"+storageType+"' ...")
+	count, err = models.SharedFileChunkDAO.MigrateFileChunks(nil, storageType, func(chunk *models.FileChunk) {
+		if chunk.Id%1000 == 0 {
+			remotelogs.Println("FILE_STORAGE", "migrated file chunk '"+types.String(chunk.Id)+"'")
+		}
+	})
+	if err != nil {
+		return count, err
+	}
+	remotelogs.Println("FILE_STORAGE", "migrated "+types.String(count)+" file chunks to '"+storageType+"'")
+	return count, nil
+}
@@ -0,0 +1,30 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package nodes
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/db/models"
+	"github.com/dashenmiren/EdgeAPI/internal/remotelogs"
+	"github.com/iwind/TeaGo/types"
+	"sync"
+)
+
+var compactIPItemsLocker = &sync.Mutex{}
+
+// 合并IP名单中重叠或者相邻的IP范围，listId 为0时处理所有名单
+func (this *APINode) compactIPItems(listId int64, dryRun bool) (*models.IPItemCompactResult, error) {
+	compactIPItemsLocker.Lock()
+	defer compactIPItemsLocker.Unlock()
+
+	if !dryRun {
+		remotelogs.Println("IP_ITEM", "compacting ip items, list: '"+types.String(listId)+"' ...")
+	}
+	result, err := models.SharedIPItemDAO.CompactIPItems(nil, listId, dryRun)
+	if err != nil {
+		return result, err
+	}
+	if !dryRun {
+		remotelogs.Println("IP_ITEM", "compacted "+types.String(result.CountMerged)+" ip items into "+types.String(result.CountCreated)+" items")
+	}
+	return result, nil
+}
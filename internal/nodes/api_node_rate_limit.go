@@ -0,0 +1,104 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"github.com/dashenmiren/EdgeAPI/internal/db/models"
+	"github.com/dashenmiren/EdgeAPI/internal/goman"
+	"github.com/dashenmiren/EdgeAPI/internal/remotelogs"
+	rpcutils "github.com/dashenmiren/EdgeAPI/internal/rpc/utils"
+	"github.com/dashenmiren/EdgeAPI/internal/utils/ratelimit"
+	"github.com/iwind/TeaGo/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"math"
+	"net"
+	"time"
+)
+
+// 加载限流配置
+// 因为配置可能在其他API节点上修改，所以需要定时重新加载
+func (this *APINode) loadRateLimitConfig() {
+	var load = func() {
+		config, err := models.SharedSysSettingDAO.ReadAPIRateLimitConfig(nil)
+		if err != nil {
+			remotelogs.Error("API_NODE", "load rate limit config failed: "+err.Error())
+			return
+		}
+		ratelimit.SharedLimiter.UpdateConfig(config)
+	}
+
+	load()
+
+	goman.New(func() {
+		var ticker = time.NewTicker(1 * time.Minute)
+		for range ticker.C {
+			load()
+		}
+	})
+}
+
+// 检查GRPC调用限流
+// originCtx 用来设置响应Header，role 和 identity 为 callerIdentity() 返回的已认证身份
+func (this *APINode) checkRateLimit(originCtx context.Context, role string, identity string, fullMethod string) error {
+	var config = ratelimit.SharedLimiter.Config()
+	if config == nil || !config.IsOn {
+		return nil
+	}
+
+	ok, retryAfter := ratelimit.SharedLimiter.Allow(role, identity, fullMethod)
+	if ok {
+		return nil
+	}
+
+	var retryAfterSeconds = this.retryAfterSeconds(retryAfter)
+	_ = grpc.SetHeader(originCtx, metadata.Pairs("retry-after", types.String(retryAfterSeconds)))
+	return status.Error(codes.ResourceExhausted, fmt.Sprintf("'%s()' says: too many requests, please retry after %d seconds", fullMethod, retryAfterSeconds))
+}
+
+// 获取调用者的角色和身份
+// 只使用校验通过的AccessToken或节点令牌中的身份；未认证的调用由 rateLimitIdentity() 使用客户端IP作为身份
+// 返回的上下文中保存了令牌校验结果，供后续 rpcutils.ValidateRequest() 使用
+func (this *APINode) callerIdentity(ctx context.Context) (resultCtx context.Context, role string, identity string) {
+	plainCtx, ok := ctx.(*rpcutils.PlainContext)
+	if ok {
+		role, identity = rateLimitIdentity(plainCtx, "")
+		return ctx, role, identity
+	}
+
+	resultCtx, tokenIdentity, err := rpcutils.VerifyTokenContext(ctx)
+	if err == nil && tokenIdentity != nil {
+		return resultCtx, tokenIdentity.Role, tokenIdentity.NodeId
+	}
+
+	var remoteAddr = ""
+	p, ok := peer.FromContext(ctx)
+	if ok && p.Addr != nil {
+		remoteAddr = p.Addr.String()
+	}
+	role, identity = rateLimitIdentity(nil, remoteAddr)
+	return ctx, role, identity
+}
+
+// 限流使用的角色和身份，GRPC和REST调用共用，同一个客户端在两种接口中使用相同的令牌桶
+// 已认证的调用使用用户类型和用户ID；未认证的调用角色为 rpcutils.UserTypeNone，身份为 "ip:"+客户端IP
+func rateLimitIdentity(plainCtx *rpcutils.PlainContext, remoteAddr string) (role string, identity string) {
+	if plainCtx != nil {
+		return plainCtx.UserType, types.String(plainCtx.UserId)
+	}
+
+	remoteIP, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		remoteIP = remoteAddr
+	}
+	return rpcutils.UserTypeNone, "ip:" + remoteIP
+}
+
+func (this *APINode) retryAfterSeconds(retryAfter time.Duration) int64 {
+	return int64(math.Max(1, math.Ceil(retryAfter.Seconds())))
+}
@@ -0,0 +1,27 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package nodes
+
+import (
+	rpcutils "github.com/dashenmiren/EdgeAPI/internal/rpc/utils"
+	"testing"
+)
+
+func TestRateLimitIdentity(t *testing.T) {
+	for _, item := range []struct {
+		plainCtx   *rpcutils.PlainContext
+		remoteAddr string
+		role       string
+		identity   string
+	}{
+		{nil, "192.168.1.100:12345", rpcutils.UserTypeNone, "ip:192.168.1.100"},
+		{nil, "[::1]:12345", rpcutils.UserTypeNone, "ip:::1"},
+		{nil, "192.168.1.100", rpcutils.UserTypeNone, "ip:192.168.1.100"},
+		{rpcutils.NewPlainContext(rpcutils.UserTypeAdmin, 1), "192.168.1.100:12345", rpcutils.UserTypeAdmin, "1"},
+	} {
+		role, identity := rateLimitIdentity(item.plainCtx, item.remoteAddr)
+		if role != item.role || identity != item.identity {
+			t.Fatal(item.remoteAddr, "expect", item.role, item.identity, "but got", role, identity)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package nodes
+
+import (
+	"errors"
+	"github.com/dashenmiren/EdgeAPI/internal/db/models"
+	"github.com/dashenmiren/EdgeAPI/internal/db/models/acme"
+	"github.com/dashenmiren/EdgeAPI/internal/db/models/dns"
+	"github.com/dashenmiren/EdgeAPI/internal/remotelogs"
+	"github.com/dashenmiren/EdgeAPI/internal/secrets"
+	"github.com/iwind/TeaGo/dbs"
+	"github.com/iwind/TeaGo/maps"
+	"sync"
+)
+
+var resealSecretsLocker = &sync.Mutex{}
+
+// 加载主密钥
+func (this *APINode) loadSecrets() error {
+	keyring, err := secrets.SharedKeyring()
+	if err != nil {
+		return err
+	}
+	if keyring.IsConfigured() {
+		remotelogs.Println("API_NODE", "secrets master key: '"+keyring.CurrentKeyId()+"'")
+	}
+	return nil
+}
+
+// 使用当前主密钥加密数据库中保存的敏感信息
+// 执行前重新加载主密钥，以便在不重启的情况下加入新的主密钥；rotate 为 true 时同时用当前主密钥重新加密数据密钥
+func (this *APINode) resealSecrets(rotate bool) (maps.Map, error) {
+	resealSecretsLocker.Lock()
+	defer resealSecretsLocker.Unlock()
+
+	keyring, err := secrets.ReloadSharedKeyring()
+	if err != nil {
+		return nil, err
+	}
+	if !keyring.IsConfigured() {
+		return nil, errors.New("no master key found, please set '" + secrets.EnvKey + "' or 'configs/" + secrets.KeyFileName + "' first")
+	}
+
+	var result = maps.Map{
+		"keyId": keyring.CurrentKeyId(),
+	}
+	for _, table := range []struct {
+		name   string
+		reseal func(tx *dbs.Tx, keyring *secrets.Keyring, rotate bool) (int64, error)
+	}{
+		{"nodeGrants", models.SharedNodeGrantDAO.ResealSecrets},
+		{"dbNodes", models.SharedDBNodeDAO.ResealSecrets},
+		{"dnsProviders", dns.SharedDNSProviderDAO.ResealSecrets},
+		{"acmeUsers", acme.SharedACMEUserDAO.ResealSecrets},
+	} {
+		count, err := table.reseal(nil, keyring, rotate)
+		if err != nil {
+			return nil, errors.New(table.name + ": " + err.Error())
+		}
+		result[table.name] = count
+	}
+	return result, nil
+}
@@ -44,6 +44,11 @@ func (this *APINode) registerServices(server *grpc.Server) {
 		pb.RegisterNodeServiceServer(server, instance)
 		this.rest(instance)
 	}
+	{
+		var instance = this.serviceInstance(&services.NodeUpgradePlanService{}).(*services.NodeUpgradePlanService)
+		pb.RegisterNodeUpgradePlanServiceServer(server, instance)
+		this.rest(instance)
+	}
 	{
 		var instance = this.serviceInstance(&services.NodeClusterService{}).(*services.NodeClusterService)
 		pb.RegisterNodeClusterServiceServer(server, instance)
@@ -259,6 +264,11 @@ func (this *APINode) registerServices(server *grpc.Server) {
 		pb.RegisterIPListServiceServer(server, instance)
 		this.rest(instance)
 	}
+	{
+		var instance = this.serviceInstance(&services.IPListFeedService{}).(*services.IPListFeedService)
+		pb.RegisterIPListFeedServiceServer(server, instance)
+		this.rest(instance)
+	}
 	{
 		var instance = this.serviceInstance(&services.IPItemService{}).(*services.IPItemService)
 		pb.RegisterIPItemServiceServer(server, instance)
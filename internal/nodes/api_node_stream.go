@@ -0,0 +1,152 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package nodes
+
+import (
+	"context"
+	"errors"
+	"github.com/dashenmiren/EdgeAPI/internal/db/models"
+	"github.com/dashenmiren/EdgeAPI/internal/remotelogs"
+	"github.com/dashenmiren/EdgeAPI/internal/rpc"
+	rpcutils "github.com/dashenmiren/EdgeAPI/internal/rpc/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"strings"
+)
+
+// 统计Stream的消息数和字节数
+type apiServerStream struct {
+	grpc.ServerStream
+
+	ctx  context.Context
+	info *rpc.StreamInfo
+}
+
+func (this *apiServerStream) Context() context.Context {
+	return this.ctx
+}
+
+func (this *apiServerStream) RecvMsg(m any) error {
+	err := this.ServerStream.RecvMsg(m)
+	if err == nil {
+		this.info.AddRecv(this.messageSize(m))
+	}
+	return err
+}
+
+func (this *apiServerStream) SendMsg(m any) error {
+	err := this.ServerStream.SendMsg(m)
+	if err == nil {
+		this.info.AddSent(this.messageSize(m))
+	}
+	return err
+}
+
+func (this *apiServerStream) messageSize(m any) int64 {
+	message, ok := m.(proto.Message)
+	if ok {
+		return int64(proto.Size(message))
+	}
+	return 0
+}
+
+// Stream拦截器
+// 记录连接时长、消息数、字节数，并标记认证后的节点身份
+func (this *APINode) streamInterceptor(srv any, serverStream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	// 使用AccessToken调用
+	var ctx, err = this.accessTokenContext(serverStream.Context(), info.FullMethod)
+	if err != nil {
+		return status.Error(codes.PermissionDenied, "'"+info.FullMethod+"()' says: "+err.Error())
+	}
+
+	// 限流
+	ctx, role, identity := this.callerIdentity(ctx)
+	err = this.checkRateLimit(serverStream.Context(), role, identity, info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	var streamInfo = this.streamIdentity(info.FullMethod, role, identity)
+	var streamId = rpc.SharedStreamRegistry.Add(streamInfo)
+
+	err = handler(srv, &apiServerStream{
+		ServerStream: serverStream,
+		ctx:          ctx,
+		info:         streamInfo,
+	})
+
+	rpc.SharedStreamRegistry.Remove(streamId)
+
+	// 记录统计数据
+	countRecvMessages, countSentMessages, recvBytes, sentBytes := streamInfo.Counters()
+	statErr := models.SharedAPIStreamStatDAO.CreateStat(nil, info.FullMethod, streamInfo.Role, streamInfo.Lifetime().Seconds(), countRecvMessages, countSentMessages, recvBytes, sentBytes)
+	if statErr != nil {
+		remotelogs.Error("API_NODE", "create stream stat failed: "+statErr.Error())
+	}
+
+	if err != nil {
+		// 审计认证失败的连接
+		if this.isStreamAuthError(err) {
+			var remoteAddr = ""
+			p, ok := peer.FromContext(ctx)
+			if ok && p.Addr != nil {
+				remoteAddr = p.Addr.String()
+			}
+			remotelogs.Warn("API_NODE", "stream '"+info.FullMethod+"' from '"+remoteAddr+"' (role: '"+streamInfo.Role+"', nodeId: '"+streamInfo.NodeId+"') rejected: "+err.Error())
+		}
+
+		statusErr, ok := status.FromError(err)
+		if ok {
+			err = status.Error(statusErr.Code(), "'"+info.FullMethod+"()' says: "+err.Error())
+		} else {
+			err = errors.New("'" + info.FullMethod + "()' says: " + err.Error())
+		}
+	}
+	return err
+}
+
+// 获取Stream对应的身份
+// role 和 identity 为 callerIdentity() 返回的已认证身份，未认证的连接不记录节点ID
+func (this *APINode) streamIdentity(fullMethod string, role string, identity string) *rpc.StreamInfo {
+	var streamInfo = &rpc.StreamInfo{
+		Method: fullMethod,
+		Role:   role,
+	}
+	if role == rpcutils.UserTypeNone {
+		return streamInfo
+	}
+
+	var nodeId = identity
+	streamInfo.NodeId = nodeId
+	if role == rpcutils.UserTypeNode && len(nodeId) > 0 {
+		nodeIntId, err := models.SharedNodeDAO.FindEnabledNodeIdWithUniqueId(nil, nodeId)
+		if err != nil {
+			remotelogs.Error("API_NODE", "find stream node failed: "+err.Error())
+			return streamInfo
+		}
+		if nodeIntId > 0 {
+			streamInfo.NodeIntId = nodeIntId
+			clusterId, err := models.SharedNodeDAO.FindNodeClusterId(nil, nodeIntId)
+			if err != nil {
+				remotelogs.Error("API_NODE", "find stream node cluster failed: "+err.Error())
+				return streamInfo
+			}
+			streamInfo.ClusterId = clusterId
+		}
+	}
+
+	return streamInfo
+}
+
+// 判断是否为认证失败错误
+// rpcutils.ValidateRequest() 返回的错误以 "context:" 开头
+func (this *APINode) isStreamAuthError(err error) bool {
+	statusErr, ok := status.FromError(err)
+	if ok && (statusErr.Code() == codes.Unauthenticated || statusErr.Code() == codes.PermissionDenied) {
+		return true
+	}
+	return strings.HasPrefix(err.Error(), "context:")
+}
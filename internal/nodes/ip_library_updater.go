@@ -70,25 +70,7 @@ func (this *IPLibraryUpdater) DownloadFile(fileId int64, writer io.Writer) error
 		return errors.New("invalid fileId: " + types.String(fileId))
 	}
 
-	var tx *dbs.Tx
-	chunkIds, err := models.SharedFileChunkDAO.FindAllFileChunkIds(tx, fileId)
-	if err != nil {
-		return err
-	}
-	for _, chunkId := range chunkIds {
-		chunk, err := models.SharedFileChunkDAO.FindFileChunk(tx, chunkId)
-		if err != nil {
-			return err
-		}
-		if chunk == nil {
-			return errors.New("can not find file chunk with chunk id '" + types.String(chunkId) + "'")
-		}
-		_, err = writer.Write(chunk.Data)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	return models.SharedFileChunkDAO.ReadFileTo(nil, fileId, writer)
 }
 
 // LogInfo 普通日志
@@ -0,0 +1,161 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package nodes
+
+import (
+	"bytes"
+	"github.com/dashenmiren/EdgeAPI/internal/configs"
+	teaconst "github.com/dashenmiren/EdgeAPI/internal/const"
+	dnsmodels "github.com/dashenmiren/EdgeAPI/internal/db/models/dns"
+	"github.com/dashenmiren/EdgeAPI/internal/goman"
+	"github.com/dashenmiren/EdgeAPI/internal/rpc"
+	"github.com/dashenmiren/EdgeAPI/internal/utils/openmetrics"
+	"github.com/iwind/TeaGo/dbs"
+	"github.com/iwind/TeaGo/types"
+	"google.golang.org/grpc/status"
+	"net"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// MetricsServer 以OpenMetrics格式输出API节点自身的监控指标
+type MetricsServer struct {
+	config *configs.MetricsConfig // 访问控制配置，为nil时只允许本机访问
+}
+
+func NewMetricsServer(config *configs.MetricsConfig) *MetricsServer {
+	return &MetricsServer{
+		config: config,
+	}
+}
+
+func (this *MetricsServer) Listen(listener net.Listener) error {
+	var mux = http.NewServeMux()
+	mux.HandleFunc("/metrics", this.handle)
+	var server = &http.Server{}
+	server.Handler = mux
+	return server.Serve(listener)
+}
+
+func (this *MetricsServer) handle(writer http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// 访问控制
+	remoteIP, _, _ := net.SplitHostPort(req.RemoteAddr)
+	if !this.config.AllowIP(remoteIP) {
+		writer.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if !this.config.AllowToken(strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")) {
+		writer.Header().Set("WWW-Authenticate", "Bearer")
+		writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// 抓取时才计算的指标
+	var registry = openmetrics.NewRegistry()
+	this.collectRuntime(registry)
+	this.collectDB(registry)
+	this.collectStreams(registry)
+	this.collectDNSTasks(registry)
+
+	var buf = &bytes.Buffer{}
+	_, _ = openmetrics.SharedRegistry.WriteTo(buf)
+	_, _ = registry.WriteTo(buf)
+	_ = openmetrics.WriteEOF(buf)
+
+	writer.Header().Set("Content-Type", openmetrics.ContentType)
+	writer.WriteHeader(http.StatusOK)
+	_, _ = writer.Write(buf.Bytes())
+}
+
+// 运行时和goroutine
+func (this *MetricsServer) collectRuntime(registry *openmetrics.Registry) {
+	registry.SetGauge("edge_api_info", "API node information.", openmetrics.Labels{
+		"version": teaconst.Version,
+		"nodeId":  types.String(teaconst.NodeId),
+	}, 1)
+	registry.SetGauge("edge_api_goroutines", "Number of goroutines.", nil, float64(runtime.NumGoroutine()))
+
+	// 按照 goman 创建位置分组
+	var positionMap = map[string]int{}
+	for _, instance := range goman.List() {
+		positionMap[filepath.Base(instance.File)+":"+types.String(instance.Line)]++
+	}
+	for position, count := range positionMap {
+		registry.SetGauge("edge_api_goman_goroutines", "Number of goroutines created by goman, grouped by position.", openmetrics.Labels{"position": position}, float64(count))
+	}
+}
+
+// 数据库连接池和prepared语句
+func (this *MetricsServer) collectDB(registry *openmetrics.Registry) {
+	db, err := dbs.Default()
+	if err != nil || db == nil {
+		return
+	}
+
+	registry.SetGauge("edge_api_db_prepared_statements", "Number of cached prepared statements.", nil, float64(db.StmtManager().Len()))
+
+	var rawDB = db.Raw()
+	if rawDB == nil {
+		return
+	}
+	var stats = rawDB.Stats()
+	registry.SetGauge("edge_api_db_max_open_connections", "Maximum number of open connections to the database.", nil, float64(stats.MaxOpenConnections))
+	registry.SetGauge("edge_api_db_open_connections", "Number of established connections.", nil, float64(stats.OpenConnections))
+	registry.SetGauge("edge_api_db_in_use_connections", "Number of connections currently in use.", nil, float64(stats.InUse))
+	registry.SetGauge("edge_api_db_idle_connections", "Number of idle connections.", nil, float64(stats.Idle))
+	registry.AddCounter("edge_api_db_wait", "Total number of connections waited for.", nil, float64(stats.WaitCount))
+	registry.AddCounter("edge_api_db_wait_duration_seconds", "Total time blocked waiting for a new connection.", nil, stats.WaitDuration.Seconds())
+}
+
+// 当前连接的Stream
+func (this *MetricsServer) collectStreams(registry *openmetrics.Registry) {
+	var countMap = map[string]map[string]int{} // method => { role => count }
+	for _, info := range rpc.SharedStreamRegistry.FindAll() {
+		roleMap, ok := countMap[info.Method]
+		if !ok {
+			roleMap = map[string]int{}
+			countMap[info.Method] = roleMap
+		}
+		roleMap[info.Role]++
+	}
+	for method, roleMap := range countMap {
+		for role, count := range roleMap {
+			registry.SetGauge("edge_api_rpc_streams", "Number of connected RPC streams.", openmetrics.Labels{"method": method, "role": role}, float64(count))
+		}
+	}
+}
+
+// DNS任务队列
+func (this *MetricsServer) collectDNSTasks(registry *openmetrics.Registry) {
+	if dnsmodels.SharedDNSTaskDAO == nil {
+		return
+	}
+
+	countDoing, err := dnsmodels.SharedDNSTaskDAO.CountDoingTasks(nil)
+	if err == nil {
+		registry.SetGauge("edge_api_dns_tasks", "Number of DNS tasks in backlog.", openmetrics.Labels{"state": "doing"}, float64(countDoing))
+	}
+	countErrors, err := dnsmodels.SharedDNSTaskDAO.CountErrorTasks(nil)
+	if err == nil {
+		registry.SetGauge("edge_api_dns_tasks", "Number of DNS tasks in backlog.", openmetrics.Labels{"state": "error"}, float64(countErrors))
+	}
+}
+
+// 记录RPC调用次数和耗时
+func recordRPCMetrics(fullMethod string, startTime time.Time, err error) {
+	openmetrics.SharedRegistry.AddCounter("edge_api_rpc_requests", "Number of unary RPC calls.", openmetrics.Labels{
+		"method": fullMethod,
+		"code":   status.Code(err).String(),
+	}, 1)
+	openmetrics.SharedRegistry.Observe("edge_api_rpc_request_duration_seconds", "Latency of unary RPC calls.", openmetrics.Labels{
+		"method": fullMethod,
+	}, time.Since(startTime).Seconds(), nil)
+}
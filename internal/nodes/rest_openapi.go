@@ -0,0 +1,181 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package nodes
+
+import (
+	teaconst "github.com/dashenmiren/EdgeAPI/internal/const"
+	"github.com/iwind/TeaGo/maps"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"reflect"
+	"strings"
+)
+
+// RestOpenAPIGenerator 根据REST注册表生成OpenAPI文档
+type RestOpenAPIGenerator struct {
+	schemas maps.Map
+}
+
+func NewRestOpenAPIGenerator() *RestOpenAPIGenerator {
+	return &RestOpenAPIGenerator{
+		schemas: maps.Map{},
+	}
+}
+
+// Generate 生成文档
+func (this *RestOpenAPIGenerator) Generate(methods []*RestMethod) maps.Map {
+	var paths = maps.Map{}
+	for _, method := range methods {
+		var requestSchema = this.typeSchema(method.RequestType)
+		var responseSchema = this.typeSchema(method.ResponseType)
+
+		paths["/"+method.Service+"/"+this.lowerFirst(method.Name)] = maps.Map{
+			"post": maps.Map{
+				"tags":        []string{method.Service},
+				"operationId": method.Service + "_" + method.Name,
+				"requestBody": maps.Map{
+					"required": true,
+					"content": maps.Map{
+						"application/json": maps.Map{
+							"schema": requestSchema,
+						},
+					},
+				},
+				"responses": maps.Map{
+					"200": maps.Map{
+						"description": "ok",
+						"content": maps.Map{
+							"application/json": maps.Map{
+								"schema": maps.Map{
+									"type": "object",
+									"properties": maps.Map{
+										"code":    maps.Map{"type": "integer"},
+										"message": maps.Map{"type": "string"},
+										"data":    responseSchema,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	return maps.Map{
+		"openapi": "3.0.3",
+		"info": maps.Map{
+			"title":   teaconst.ProductName,
+			"version": teaconst.Version,
+		},
+		"paths": paths,
+		"components": maps.Map{
+			"schemas": this.schemas,
+			"securitySchemes": maps.Map{
+				"accessToken": maps.Map{
+					"type": "apiKey",
+					"in":   "header",
+					"name": "X-Edge-Access-Token",
+				},
+			},
+		},
+		"security": []maps.Map{
+			{"accessToken": []string{}},
+		},
+	}
+}
+
+// 类型对应的Schema
+func (this *RestOpenAPIGenerator) typeSchema(valueType reflect.Type) maps.Map {
+	if valueType == nil {
+		return maps.Map{"type": "object"}
+	}
+	if valueType.Kind() != reflect.Ptr {
+		valueType = reflect.PtrTo(valueType)
+	}
+	message, ok := reflect.New(valueType.Elem()).Interface().(proto.Message)
+	if !ok {
+		return maps.Map{"type": "object"}
+	}
+	return this.messageSchema(message.ProtoReflect().Descriptor())
+}
+
+// 消息对应的Schema，会放到components中
+func (this *RestOpenAPIGenerator) messageSchema(descriptor protoreflect.MessageDescriptor) maps.Map {
+	var name = string(descriptor.FullName())
+	var ref = maps.Map{"$ref": "#/components/schemas/" + name}
+	if this.schemas.Has(name) {
+		return ref
+	}
+
+	// 先占位，防止循环引用
+	var properties = maps.Map{}
+	this.schemas[name] = maps.Map{
+		"type":       "object",
+		"properties": properties,
+	}
+
+	var fields = descriptor.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		var field = fields.Get(i)
+		properties[field.JSONName()] = this.fieldSchema(field)
+	}
+
+	return ref
+}
+
+// 字段对应的Schema
+func (this *RestOpenAPIGenerator) fieldSchema(field protoreflect.FieldDescriptor) maps.Map {
+	if field.IsMap() {
+		return maps.Map{
+			"type":                 "object",
+			"additionalProperties": this.kindSchema(field.MapValue()),
+		}
+	}
+	if field.IsList() {
+		return maps.Map{
+			"type":  "array",
+			"items": this.kindSchema(field),
+		}
+	}
+	return this.kindSchema(field)
+}
+
+func (this *RestOpenAPIGenerator) kindSchema(field protoreflect.FieldDescriptor) maps.Map {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return maps.Map{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return maps.Map{"type": "integer", "format": "int32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return maps.Map{"type": "integer", "format": "int64"}
+	case protoreflect.FloatKind:
+		return maps.Map{"type": "number", "format": "float"}
+	case protoreflect.DoubleKind:
+		return maps.Map{"type": "number", "format": "double"}
+	case protoreflect.StringKind:
+		return maps.Map{"type": "string"}
+	case protoreflect.BytesKind:
+		// JSON中使用Base64编码
+		return maps.Map{"type": "string", "format": "byte"}
+	case protoreflect.EnumKind:
+		var values = []string{}
+		var enumValues = field.Enum().Values()
+		for i := 0; i < enumValues.Len(); i++ {
+			values = append(values, string(enumValues.Get(i).Name()))
+		}
+		return maps.Map{"type": "string", "enum": values}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return this.messageSchema(field.Message())
+	}
+	return maps.Map{}
+}
+
+func (this *RestOpenAPIGenerator) lowerFirst(s string) string {
+	if len(s) == 0 {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
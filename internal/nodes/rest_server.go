@@ -4,21 +4,26 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
-	"github.com/dashenmiren/EdgeAPI/internal/db/models"
 	"github.com/dashenmiren/EdgeAPI/internal/rpc/services"
 	rpcutils "github.com/dashenmiren/EdgeAPI/internal/rpc/utils"
+	"github.com/dashenmiren/EdgeAPI/internal/utils/ratelimit"
 	"github.com/dashenmiren/EdgeAPI/internal/utils/sizes"
 	"github.com/iwind/TeaGo/maps"
+	"github.com/iwind/TeaGo/types"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 	"io"
+	"math"
 	"net"
 	"net/http"
 	"reflect"
 	"regexp"
 	"strings"
-	"time"
 )
 
 var servicePathReg = regexp.MustCompile(`^/([a-zA-Z0-9]+)/([a-zA-Z0-9]+)$`)
+
+// 服务实例，通过 APINode.rest() 加入，在 APINode.setupRestServices() 中转换为 RestRegistry
 var restServicesMap = map[string]reflect.Value{
 	"APIAccessTokenService": reflect.ValueOf(new(services.APIAccessTokenService)),
 }
@@ -54,6 +59,18 @@ func (this *RestServer) handle(writer http.ResponseWriter, req *http.Request) {
 		shouldPretty = oldShouldPretty == "on"
 	}
 
+	// OpenAPI文档
+	if path == "/openapi.json" {
+		if sharedAPIConfig == nil || !sharedAPIConfig.Rest.IsPublicOpenAPI() {
+			_, ok := this.authorizeOpenAPI(writer, req, shouldPretty)
+			if !ok {
+				return
+			}
+		}
+		this.writeJSON(writer, NewRestOpenAPIGenerator().Generate(sharedRestRegistry.AllMethods()), shouldPretty)
+		return
+	}
+
 	// 欢迎页
 	if path == "/" {
 		this.writeJSON(writer, maps.Map{
@@ -78,55 +95,24 @@ func (this *RestServer) handle(writer http.ResponseWriter, req *http.Request) {
 	var serviceName = matches[1]
 	var methodName = matches[2]
 
-	serviceType, ok := restServicesMap[serviceName]
-	if !ok {
-		writer.WriteHeader(http.StatusNotFound)
-		this.writeJSON(writer, maps.Map{
-			"code":    "404",
-			"message": "service '" + serviceName + "' not found",
-			"data":    maps.Map{},
-		}, shouldPretty)
+	// 导出访问日志，需要流式输出，所以单独处理
+	if serviceName == restExportServiceName && strings.EqualFold(methodName, restExportMethodName) {
+		this.handleExportHTTPAccessLogs(writer, req, shouldPretty)
 		return
 	}
 
-	if len(methodName) == 0 {
+	if !sharedRestRegistry.HasService(serviceName) {
 		writer.WriteHeader(http.StatusNotFound)
 		this.writeJSON(writer, maps.Map{
 			"code":    "404",
-			"message": "method '" + methodName + "' not found",
+			"message": "service '" + serviceName + "' not found",
 			"data":    maps.Map{},
 		}, shouldPretty)
 		return
 	}
 
-	// 再次查找
-	methodName = strings.ToUpper(string(methodName[0])) + methodName[1:]
-	var method = serviceType.MethodByName(methodName)
-	if !method.IsValid() {
-		// 兼容Enabled
-		if strings.Contains(methodName, "Enabled") {
-			methodName = strings.Replace(methodName, "Enabled", "", 1)
-			method = serviceType.MethodByName(methodName)
-			if !method.IsValid() {
-				writer.WriteHeader(http.StatusNotFound)
-				this.writeJSON(writer, maps.Map{
-					"code":    "404",
-					"message": "method '" + methodName + "' not found",
-					"data":    maps.Map{},
-				}, shouldPretty)
-				return
-			}
-		} else {
-			writer.WriteHeader(http.StatusNotFound)
-			this.writeJSON(writer, maps.Map{
-				"code":    "404",
-				"message": "method '" + methodName + "' not found",
-				"data":    maps.Map{},
-			}, shouldPretty)
-			return
-		}
-	}
-	if method.Type().NumIn() != 2 || method.Type().NumOut() != 2 {
+	var restMethod = sharedRestRegistry.FindMethod(serviceName, methodName)
+	if restMethod == nil {
 		writer.WriteHeader(http.StatusNotFound)
 		this.writeJSON(writer, maps.Map{
 			"code":    "404",
@@ -135,66 +121,11 @@ func (this *RestServer) handle(writer http.ResponseWriter, req *http.Request) {
 		}, shouldPretty)
 		return
 	}
-	if method.Type().In(0).Name() != "Context" {
-		writer.WriteHeader(http.StatusNotFound)
-		this.writeJSON(writer, maps.Map{
-			"code":    "404",
-			"message": "method '" + methodName + "' not found (or invalid context)",
-			"data":    maps.Map{},
-		}, shouldPretty)
-		return
-	}
-
-	// 上下文
-	var ctx = context.Background()
-
-	if serviceName != "APIAccessTokenService" || (methodName != "GetAPIAccessToken" && methodName != "getAPIAccessToken") {
-		// 校验TOKEN
-		var token = req.Header.Get("X-Edge-Access-Token")
-		if len(token) == 0 {
-			token = req.Header.Get("Edge-Access-Token")
-			if len(token) == 0 {
-				this.writeJSON(writer, maps.Map{
-					"code":    400,
-					"data":    maps.Map{},
-					"message": "require 'X-Edge-Access-Token' header",
-				}, shouldPretty)
-				return
-			}
-		}
+	methodName = restMethod.Name
 
-		accessToken, err := models.SharedAPIAccessTokenDAO.FindAccessToken(nil, token)
-		if err != nil {
-			this.writeJSON(writer, maps.Map{
-				"code":    400,
-				"data":    maps.Map{},
-				"message": "server error: " + err.Error(),
-			}, shouldPretty)
-			return
-		}
-
-		if accessToken == nil || int64(accessToken.ExpiredAt) < time.Now().Unix() {
-			this.writeJSON(writer, maps.Map{
-				"code":    400,
-				"data":    maps.Map{},
-				"message": "invalid access token",
-			}, shouldPretty)
-			return
-		}
-
-		if accessToken.UserId > 0 {
-			ctx = rpcutils.NewPlainContext("user", int64(accessToken.UserId))
-		} else if accessToken.AdminId > 0 {
-			ctx = rpcutils.NewPlainContext("admin", int64(accessToken.AdminId))
-		} else {
-			// TODO 支持更多类型的角色
-			this.writeJSON(writer, maps.Map{
-				"code":    400,
-				"data":    maps.Map{},
-				"message": "not supported role",
-			}, shouldPretty)
-			return
-		}
+	ctx, ok := this.authorize(writer, req, serviceName, restMethod.FullService, methodName, shouldPretty)
+	if !ok {
+		return
 	}
 
 	// TODO 可以设置最大可接收内容尺寸
@@ -215,8 +146,13 @@ func (this *RestServer) handle(writer http.ResponseWriter, req *http.Request) {
 	}
 
 	// 请求数据
-	var reqValue = reflect.New(method.Type().In(1).Elem()).Interface()
-	err = json.Unmarshal(body, reqValue)
+	var reqValue = reflect.New(restMethod.RequestType).Interface()
+	reqMessage, isMessage := reqValue.(proto.Message)
+	if isMessage {
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, reqMessage)
+	} else {
+		err = json.Unmarshal(body, reqValue)
+	}
 	if err != nil {
 		writer.WriteHeader(http.StatusBadRequest)
 		this.writeJSON(writer, maps.Map{
@@ -227,7 +163,7 @@ func (this *RestServer) handle(writer http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	var result = method.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(reqValue)})
+	var result = restMethod.Method.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(reqValue)})
 	var resultErr = result[1].Interface()
 	if resultErr != nil {
 		e, ok := resultErr.(error)
@@ -250,24 +186,118 @@ func (this *RestServer) handle(writer http.ResponseWriter, req *http.Request) {
 			"message": "ok",
 			"data":    result[0].Interface(),
 		}
-		var dataJSON []byte
+
+		// 使用protojson编码
+		if req.Header.Get("X-Edge-Response-Format") == "protojson" {
+			respMessage, ok := result[0].Interface().(proto.Message)
+			if ok {
+				respJSON, marshalErr := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(respMessage)
+				if marshalErr != nil {
+					this.writeJSON(writer, maps.Map{
+						"code":    500,
+						"message": "server error: marshal json failed: " + marshalErr.Error(),
+						"data":    maps.Map{},
+					}, shouldPretty)
+					return
+				}
+				data["data"] = json.RawMessage(respJSON)
+			}
+		}
+
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
 		if shouldPretty {
-			dataJSON = data.AsPrettyJSON()
+			_, _ = writer.Write(data.AsPrettyJSON())
 		} else {
-			dataJSON = data.AsJSON()
+			_, _ = writer.Write(data.AsJSON())
 		}
+	}
+}
+
+// 校验访问OpenAPI文档的令牌，只检查令牌是否有效及允许访问的IP，失败时直接输出错误信息
+func (this *RestServer) authorizeOpenAPI(writer http.ResponseWriter, req *http.Request, shouldPretty bool) (ctx context.Context, ok bool) {
+	var token = req.Header.Get("X-Edge-Access-Token")
+	if len(token) == 0 {
+		token = req.Header.Get("Edge-Access-Token")
+	}
+	if len(token) == 0 {
+		writer.WriteHeader(http.StatusUnauthorized)
+		this.writeJSON(writer, maps.Map{
+			"code":    401,
+			"data":    maps.Map{},
+			"message": "require 'X-Edge-Access-Token' header",
+		}, shouldPretty)
+		return nil, false
+	}
+
+	remoteIP, _, _ := net.SplitHostPort(req.RemoteAddr)
+	plainCtx, err := rpcutils.ValidateAccessTokenIP(token, remoteIP)
+	if err != nil {
+		writer.WriteHeader(http.StatusUnauthorized)
+		this.writeJSON(writer, maps.Map{
+			"code":    401,
+			"data":    maps.Map{},
+			"message": err.Error(),
+		}, shouldPretty)
+		return nil, false
+	}
+	return plainCtx, true
+}
+
+// 校验访问令牌并限流，失败时直接输出错误信息
+func (this *RestServer) authorize(writer http.ResponseWriter, req *http.Request, serviceName string, fullServiceName string, methodName string, shouldPretty bool) (ctx context.Context, ok bool) {
+	// 上下文
+	ctx = context.Background()
+
+	if serviceName != "APIAccessTokenService" || (methodName != "GetAPIAccessToken" && methodName != "getAPIAccessToken") {
+		// 校验TOKEN
+		var token = req.Header.Get("X-Edge-Access-Token")
+		if len(token) == 0 {
+			token = req.Header.Get("Edge-Access-Token")
+			if len(token) == 0 {
+				this.writeJSON(writer, maps.Map{
+					"code":    400,
+					"data":    maps.Map{},
+					"message": "require 'X-Edge-Access-Token' header",
+				}, shouldPretty)
+				return nil, false
+			}
+		}
+
+		// 检查令牌及其权限范围
+		remoteIP, _, _ := net.SplitHostPort(req.RemoteAddr)
+		plainCtx, err := rpcutils.ValidateAccessToken(token, serviceName, methodName, remoteIP)
 		if err != nil {
 			this.writeJSON(writer, maps.Map{
-				"code":    500,
-				"message": "server error: marshal json failed: " + err.Error(),
+				"code":    400,
 				"data":    maps.Map{},
+				"message": err.Error(),
 			}, shouldPretty)
-		} else {
-			writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+			return nil, false
+		}
+		ctx = plainCtx
+	}
 
-			_, _ = writer.Write(dataJSON)
+	// 限流
+	{
+		plainCtx, _ := ctx.(*rpcutils.PlainContext)
+		var role, identity = rateLimitIdentity(plainCtx, req.RemoteAddr)
+		allowed, retryAfter := ratelimit.SharedLimiter.Allow(role, identity, "/"+fullServiceName+"/"+methodName)
+		if !allowed {
+			var retryAfterSeconds = int64(math.Max(1, math.Ceil(retryAfter.Seconds())))
+			writer.Header().Set("Retry-After", types.String(retryAfterSeconds))
+			writer.WriteHeader(http.StatusTooManyRequests)
+			this.writeJSON(writer, maps.Map{
+				"code":    429,
+				"message": "too many requests, please retry after " + types.String(retryAfterSeconds) + " seconds",
+				"data": maps.Map{
+					"retryAfter": retryAfterSeconds,
+				},
+			}, shouldPretty)
+			return nil, false
 		}
 	}
+
+	return ctx, true
 }
 
 func (this *RestServer) writeJSON(writer http.ResponseWriter, v maps.Map, pretty bool) {
@@ -0,0 +1,114 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package nodes
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/accesslogs"
+	"github.com/dashenmiren/EdgeAPI/internal/rpc/services"
+	"github.com/dashenmiren/EdgeAPI/internal/utils/sizes"
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"github.com/iwind/TeaGo/maps"
+	timeutil "github.com/iwind/TeaGo/utils/time"
+	"google.golang.org/protobuf/encoding/protojson"
+	"io"
+	"net/http"
+)
+
+const (
+	restExportServiceName = "HTTPAccessLogService"
+	restExportMethodName  = "ExportHTTPAccessLogs"
+
+	restExportCursorHeader = "X-Edge-Export-Cursor" // 以Trailer的方式返回最后的导出位置
+	restExportErrorHeader  = "X-Edge-Export-Error"  // 以Trailer的方式返回导出中途发生的错误
+)
+
+// 导出访问日志
+// 请求参数和 HTTPAccessLogService.ExportHTTPAccessLogs 一致，内容直接作为文件下载
+func (this *RestServer) handleExportHTTPAccessLogs(writer http.ResponseWriter, req *http.Request, shouldPretty bool) {
+	ctx, ok := this.authorize(writer, req, restExportServiceName, "pb."+restExportServiceName, restExportMethodName, shouldPretty)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, 1*sizes.M))
+	if err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		this.writeJSON(writer, maps.Map{
+			"code":    400,
+			"message": err.Error(),
+			"data":    maps.Map{},
+		}, shouldPretty)
+		return
+	}
+	if len(body) == 0 {
+		body = []byte("{}")
+	}
+
+	var exportReq = &pb.ExportHTTPAccessLogsRequest{}
+	err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, exportReq)
+	if err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		this.writeJSON(writer, maps.Map{
+			"code":    400,
+			"message": "Decode request failed: " + err.Error() + ". Request body should be a valid JSON data",
+			"data":    maps.Map{},
+		}, shouldPretty)
+		return
+	}
+	if len(exportReq.Format) == 0 {
+		exportReq.Format = accesslogs.ExportFormatNDJSON
+	}
+
+	var exportWriter = &restExportWriter{
+		writer:   writer,
+		format:   exportReq.Format,
+		filename: "access-logs-" + timeutil.FormatTime("YmdHis", exportReq.TimeFrom) + "-" + timeutil.FormatTime("YmdHis", exportReq.TimeTo) + "." + exportReq.Format,
+	}
+	err = new(services.HTTPAccessLogService).ExportHTTPAccessLogsTo(ctx, exportReq, exportWriter, func(cursor string, count int) error {
+		exportWriter.writeHeader()
+		writer.Header().Set(restExportCursorHeader, cursor)
+		flusher, ok := writer.(http.Flusher)
+		if ok {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// 还没有输出内容时可以返回正常的错误信息
+		if !exportWriter.wroteHeader {
+			this.writeJSON(writer, maps.Map{
+				"code":    400,
+				"message": err.Error(),
+				"data":    maps.Map{},
+			}, shouldPretty)
+			return
+		}
+		writer.Header().Set(restExportErrorHeader, err.Error())
+	}
+}
+
+// 在第一次输出内容时才写入Header，以便在出错时仍然可以返回JSON格式的错误信息
+type restExportWriter struct {
+	writer      http.ResponseWriter
+	format      string
+	filename    string
+	wroteHeader bool
+}
+
+func (this *restExportWriter) Write(p []byte) (n int, err error) {
+	this.writeHeader()
+	return this.writer.Write(p)
+}
+
+func (this *restExportWriter) writeHeader() {
+	if this.wroteHeader {
+		return
+	}
+	this.wroteHeader = true
+
+	var header = this.writer.Header()
+	header.Set("Content-Type", accesslogs.ExportContentType(this.format))
+	header.Set("Content-Disposition", "attachment; filename=\""+this.filename+"\"")
+	header.Set("Trailer", restExportCursorHeader+", "+restExportErrorHeader)
+	this.writer.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,171 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package nodes
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+var sharedRestRegistry = NewRestRegistry()
+
+// RestMethod REST可调用的方法
+type RestMethod struct {
+	Service      string // 服务名，比如 NodeService
+	FullService  string // 完整服务名，比如 pb.NodeService
+	Name         string // 方法名，比如 FindEnabledNode
+	Method       reflect.Value
+	RequestType  reflect.Type // 请求类型，比如 pb.FindEnabledNodeRequest
+	ResponseType reflect.Type // 响应类型，比如 pb.FindEnabledNodeResponse
+}
+
+// RestRegistry REST服务注册表
+// 和GRPC服务保持一致，只包含一元调用（Unary）的方法
+type RestRegistry struct {
+	serviceMap map[string]map[string]*RestMethod // service => { method => *RestMethod }
+
+	locker sync.RWMutex
+}
+
+func NewRestRegistry() *RestRegistry {
+	return &RestRegistry{
+		serviceMap: map[string]map[string]*RestMethod{},
+	}
+}
+
+// Load 从GRPC服务信息中加载
+func (this *RestRegistry) Load(serviceInfoMap map[string]grpc.ServiceInfo, instanceMap map[string]reflect.Value) {
+	var serviceMap = map[string]map[string]*RestMethod{}
+
+	for fullServiceName, serviceInfo := range serviceInfoMap {
+		var serviceName = fullServiceName
+		var index = strings.LastIndex(serviceName, ".")
+		if index >= 0 {
+			serviceName = serviceName[index+1:]
+		}
+
+		instance, ok := instanceMap[serviceName]
+		if !ok || !instance.IsValid() {
+			continue
+		}
+
+		var methodMap = map[string]*RestMethod{}
+		for _, methodInfo := range serviceInfo.Methods {
+			// 不支持流式调用
+			if methodInfo.IsClientStream || methodInfo.IsServerStream {
+				continue
+			}
+
+			var method = instance.MethodByName(methodInfo.Name)
+			if !method.IsValid() {
+				continue
+			}
+
+			var methodType = method.Type()
+			if methodType.NumIn() != 2 ||
+				methodType.NumOut() != 2 ||
+				!methodType.In(0).Implements(contextType) ||
+				methodType.In(1).Kind() != reflect.Ptr ||
+				!methodType.Out(1).Implements(errorType) {
+				continue
+			}
+
+			methodMap[methodInfo.Name] = &RestMethod{
+				Service:      serviceName,
+				FullService:  fullServiceName,
+				Name:         methodInfo.Name,
+				Method:       method,
+				RequestType:  methodType.In(1).Elem(),
+				ResponseType: methodType.Out(0),
+			}
+		}
+
+		if len(methodMap) > 0 {
+			serviceMap[serviceName] = methodMap
+		}
+	}
+
+	this.locker.Lock()
+	this.serviceMap = serviceMap
+	this.locker.Unlock()
+}
+
+// HasService 检查服务是否存在
+func (this *RestRegistry) HasService(serviceName string) bool {
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+	_, ok := this.serviceMap[serviceName]
+	return ok
+}
+
+// FindMethod 查找方法
+// 方法名首字母可以小写，同时兼容老的带有 Enabled 的方法名
+func (this *RestRegistry) FindMethod(serviceName string, methodName string) *RestMethod {
+	if len(methodName) == 0 {
+		return nil
+	}
+
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+
+	methodMap, ok := this.serviceMap[serviceName]
+	if !ok {
+		return nil
+	}
+
+	methodName = strings.ToUpper(methodName[:1]) + methodName[1:]
+	method, ok := methodMap[methodName]
+	if ok {
+		return method
+	}
+
+	// 兼容Enabled
+	if strings.Contains(methodName, "Enabled") {
+		return methodMap[strings.Replace(methodName, "Enabled", "", 1)]
+	}
+
+	return nil
+}
+
+// AllMethods 列出所有方法
+func (this *RestRegistry) AllMethods() []*RestMethod {
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+
+	var result = []*RestMethod{}
+	for _, methodMap := range this.serviceMap {
+		for _, method := range methodMap {
+			result = append(result, method)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Service == result[j].Service {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Service < result[j].Service
+	})
+	return result
+}
+
+// 初始化REST服务
+// 使用一个不监听的GRPC服务来收集所有注册的服务，保证REST服务和GRPC服务一致
+func (this *APINode) setupRestServices() {
+	var server = grpc.NewServer()
+	this.registerServices(server)
+
+	this.serviceInstanceLocker.Lock()
+	var instanceMap = map[string]reflect.Value{}
+	for name, instance := range restServicesMap {
+		instanceMap[name] = instance
+	}
+	this.serviceInstanceLocker.Unlock()
+
+	sharedRestRegistry.Load(server.GetServiceInfo(), instanceMap)
+}
@@ -0,0 +1,56 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package nodes
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"reflect"
+	"testing"
+)
+
+func TestRestRegistry_Load(t *testing.T) {
+	var server = grpc.NewServer()
+	var healthServer = health.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, healthServer)
+
+	var registry = NewRestRegistry()
+	registry.Load(server.GetServiceInfo(), map[string]reflect.Value{
+		"Health": reflect.ValueOf(healthServer),
+	})
+
+	if !registry.HasService("Health") {
+		t.Fatal("'Health' service should be registered")
+	}
+	if registry.FindMethod("Health", "check") == nil {
+		t.Fatal("'check' method should be found")
+	}
+	if registry.FindMethod("Health", "watch") != nil {
+		t.Fatal("stream method 'watch' should not be registered")
+	}
+	for _, method := range registry.AllMethods() {
+		t.Log(method.Service, method.Name, method.RequestType, method.ResponseType)
+	}
+}
+
+func TestRestOpenAPIGenerator_Generate(t *testing.T) {
+	var server = grpc.NewServer()
+	var healthServer = health.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, healthServer)
+
+	var registry = NewRestRegistry()
+	registry.Load(server.GetServiceInfo(), map[string]reflect.Value{
+		"Health": reflect.ValueOf(healthServer),
+	})
+
+	var doc = NewRestOpenAPIGenerator().Generate(registry.AllMethods())
+	t.Log(string(doc.AsPrettyJSON()))
+
+	if !doc.GetMap("paths").Has("/Health/check") {
+		t.Fatal("'/Health/check' should be in paths")
+	}
+	if !doc.GetMap("components").GetMap("schemas").Has("grpc.health.v1.HealthCheckResponse") {
+		t.Fatal("response schema should be generated")
+	}
+}
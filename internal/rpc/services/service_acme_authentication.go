@@ -33,3 +33,35 @@ func (this *ACMEAuthenticationService) FindACMEAuthenticationKeyWithToken(ctx co
 	}
 	return &pb.FindACMEAuthenticationKeyWithTokenResponse{Key: auth.Key}, nil
 }
+
+// FindAllACMETLSALPNAuthentications 查找所有有效的TLS-ALPN认证证书
+func (this *ACMEAuthenticationService) FindAllACMETLSALPNAuthentications(ctx context.Context, req *pb.FindAllACMETLSALPNAuthenticationsRequest) (*pb.FindAllACMETLSALPNAuthenticationsResponse, error) {
+	_, err := this.ValidateNode(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx = this.NullTx()
+
+	auths, err := acme.SharedACMEAuthenticationDAO.FindAllTLSALPNAuths(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 同一个域名只使用最新的认证证书
+	var pbAuths = []*pb.ACMETLSALPNAuthentication{}
+	var domainMap = map[string]bool{}
+	for _, auth := range auths {
+		if domainMap[auth.Domain] {
+			continue
+		}
+		domainMap[auth.Domain] = true
+
+		pbAuths = append(pbAuths, &pb.ACMETLSALPNAuthentication{
+			Domain:   auth.Domain,
+			CertData: auth.CertData,
+			KeyData:  auth.KeyData,
+		})
+	}
+	return &pb.FindAllACMETLSALPNAuthenticationsResponse{AcmeTLSALPNAuthentications: pbAuths}, nil
+}
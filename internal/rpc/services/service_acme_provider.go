@@ -4,10 +4,17 @@ package services
 
 import (
 	"context"
+	"crypto/x509"
 	"github.com/dashenmiren/EdgeAPI/internal/acme"
+	acmemodels "github.com/dashenmiren/EdgeAPI/internal/db/models/acme"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
 	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"net/url"
+	"regexp"
 )
 
+var acmeProviderCodeReg = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
 // ACMEProviderService ACME服务商
 type ACMEProviderService struct {
 	BaseService
@@ -21,7 +28,7 @@ func (this *ACMEProviderService) FindAllACMEProviders(ctx context.Context, req *
 	}
 
 	var pbProviders = []*pb.ACMEProvider{}
-	for _, provider := range acme.FindAllProviders() {
+	for _, provider := range acme.FindAllAvailableProviders() {
 		pbProviders = append(pbProviders, &pb.ACMEProvider{
 			Name:           provider.Name,
 			Code:           provider.Code,
@@ -29,6 +36,7 @@ func (this *ACMEProviderService) FindAllACMEProviders(ctx context.Context, req *
 			ApiURL:         provider.APIURL,
 			RequireEAB:     provider.RequireEAB,
 			EabDescription: provider.EABDescription,
+			IsCustom:       provider.IsCustom,
 		})
 	}
 
@@ -57,6 +65,124 @@ func (this *ACMEProviderService) FindACMEProviderWithCode(ctx context.Context, r
 			ApiURL:         provider.APIURL,
 			RequireEAB:     provider.RequireEAB,
 			EabDescription: provider.EABDescription,
+			IsCustom:       provider.IsCustom,
 		},
 	}, nil
 }
+
+// CreateACMEProvider 创建自定义服务商
+func (this *ACMEProviderService) CreateACMEProvider(ctx context.Context, req *pb.CreateACMEProviderRequest) (*pb.CreateACMEProviderResponse, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !acmeProviderCodeReg.MatchString(req.Code) {
+		return nil, errors.New("invalid code '" + req.Code + "'")
+	}
+	err = this.validateACMEProvider(req.Name, req.ApiURL, req.TestAPIURL, req.CaCerts)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx = this.NullTx()
+	providerId, err := acmemodels.SharedACMEProviderDAO.CreateProvider(tx, req.Name, req.Code, req.Description, req.ApiURL, req.TestAPIURL, req.UseTestAPI, req.RequireEAB, req.EabDescription, req.CaCerts)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CreateACMEProviderResponse{AcmeProviderId: providerId}, nil
+}
+
+// UpdateACMEProvider 修改自定义服务商
+func (this *ACMEProviderService) UpdateACMEProvider(ctx context.Context, req *pb.UpdateACMEProviderRequest) (*pb.RPCSuccess, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = this.validateACMEProvider(req.Name, req.ApiURL, req.TestAPIURL, req.CaCerts)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx = this.NullTx()
+	err = acmemodels.SharedACMEProviderDAO.UpdateProvider(tx, req.AcmeProviderId, req.Name, req.Description, req.ApiURL, req.TestAPIURL, req.UseTestAPI, req.RequireEAB, req.EabDescription, req.CaCerts, req.IsOn)
+	if err != nil {
+		return nil, err
+	}
+	return this.Success()
+}
+
+// DeleteACMEProvider 删除自定义服务商
+func (this *ACMEProviderService) DeleteACMEProvider(ctx context.Context, req *pb.DeleteACMEProviderRequest) (*pb.RPCSuccess, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx = this.NullTx()
+	err = acmemodels.SharedACMEProviderDAO.DisableACMEProvider(tx, req.AcmeProviderId)
+	if err != nil {
+		return nil, err
+	}
+	return this.Success()
+}
+
+// FindAllEnabledCustomACMEProviders 查找所有自定义服务商
+func (this *ACMEProviderService) FindAllEnabledCustomACMEProviders(ctx context.Context, req *pb.FindAllEnabledCustomACMEProvidersRequest) (*pb.FindAllEnabledCustomACMEProvidersResponse, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx = this.NullTx()
+	providers, err := acmemodels.SharedACMEProviderDAO.FindAllEnabledProviders(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pbProviders = []*pb.ACMEProvider{}
+	for _, provider := range providers {
+		pbProviders = append(pbProviders, &pb.ACMEProvider{
+			Id:             int64(provider.Id),
+			Name:           provider.Name,
+			Code:           provider.Code,
+			Description:    provider.Description,
+			ApiURL:         provider.ApiURL,
+			TestAPIURL:     provider.TestAPIURL,
+			UseTestAPI:     provider.UseTestAPI,
+			RequireEAB:     provider.RequireEAB,
+			EabDescription: provider.EabDescription,
+			CaCerts:        provider.CaCerts,
+			IsOn:           provider.IsOn,
+			IsCustom:       true,
+		})
+	}
+	return &pb.FindAllEnabledCustomACMEProvidersResponse{AcmeProviders: pbProviders}, nil
+}
+
+// 检查自定义服务商参数
+func (this *ACMEProviderService) validateACMEProvider(name string, apiURL string, testAPIURL string, caCerts []byte) error {
+	if len(name) == 0 {
+		return errors.New("'name' should not be empty")
+	}
+	if !this.isValidDirectoryURL(apiURL) {
+		return errors.New("invalid 'apiURL'")
+	}
+	if len(testAPIURL) > 0 && !this.isValidDirectoryURL(testAPIURL) {
+		return errors.New("invalid 'testAPIURL'")
+	}
+	if len(caCerts) > 0 && !x509.NewCertPool().AppendCertsFromPEM(caCerts) {
+		return errors.New("invalid CA certificates")
+	}
+	return nil
+}
+
+// 检查目录地址
+func (this *ACMEProviderService) isValidDirectoryURL(directoryURL string) bool {
+	u, err := url.Parse(directoryURL)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "https" || u.Scheme == "http") && len(u.Host) > 0
+}
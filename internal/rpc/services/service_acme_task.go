@@ -2,11 +2,13 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/dashenmiren/EdgeAPI/internal/acme"
 	"github.com/dashenmiren/EdgeAPI/internal/db/models"
 	acmemodels "github.com/dashenmiren/EdgeAPI/internal/db/models/acme"
 	"github.com/dashenmiren/EdgeAPI/internal/db/models/dns"
 	"github.com/dashenmiren/EdgeAPI/internal/dnsclients"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
 	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
 )
 
@@ -151,7 +153,7 @@ func (this *ACMETaskService) ListEnabledACMETasks(ctx context.Context, req *pb.L
 		}
 
 		var pbDNSProvider *pb.DNSProvider
-		if task.AuthType == acme.AuthTypeDNS {
+		if task.UsesAuthType(acme.AuthTypeDNS) {
 			// DNS
 			provider, err := dns.SharedDNSProviderDAO.FindEnabledDNSProvider(tx, int64(task.DnsProviderId))
 			if err != nil {
@@ -195,26 +197,29 @@ func (this *ACMETaskService) ListEnabledACMETasks(ctx context.Context, req *pb.L
 		}
 		if taskLog != nil {
 			pbTaskLog = &pb.ACMETaskLog{
-				Id:        int64(taskLog.Id),
-				IsOk:      taskLog.IsOk,
-				Error:     taskLog.Error,
-				CreatedAt: int64(taskLog.CreatedAt),
+				Id:             int64(taskLog.Id),
+				IsOk:           taskLog.IsOk,
+				Error:          taskLog.Error,
+				CreatedAt:      int64(taskLog.CreatedAt),
+				ChallengesJSON: taskLog.Challenges,
 			}
 		}
 
 		result = append(result, &pb.ACMETask{
-			Id:                int64(task.Id),
-			IsOn:              task.IsOn,
-			DnsDomain:         task.DnsDomain,
-			Domains:           task.DecodeDomains(),
-			CreatedAt:         int64(task.CreatedAt),
-			AutoRenew:         task.AutoRenew == 1,
-			AcmeUser:          pbACMEUser,
-			DnsProvider:       pbDNSProvider,
-			SslCert:           pbCert,
-			LatestACMETaskLog: pbTaskLog,
-			AuthType:          task.AuthType,
-			AuthURL:           task.AuthURL,
+			Id:                  int64(task.Id),
+			IsOn:                task.IsOn,
+			DnsDomain:           task.DnsDomain,
+			Domains:             task.DecodeDomains(),
+			CreatedAt:           int64(task.CreatedAt),
+			AutoRenew:           task.AutoRenew == 1,
+			KeyType:             task.DecodeKeyType(),
+			DomainAuthTypesJSON: task.DomainAuthTypes,
+			AcmeUser:            pbACMEUser,
+			DnsProvider:         pbDNSProvider,
+			SslCert:             pbCert,
+			LatestACMETaskLog:   pbTaskLog,
+			AuthType:            task.AuthType,
+			AuthURL:             task.AuthURL,
 		})
 	}
 
@@ -238,8 +243,17 @@ func (this *ACMETaskService) CreateACMETask(ctx context.Context, req *pb.CreateA
 		}
 	}
 
+	// 单个域名的认证方式
+	var domainAuthTypes = map[string]string{}
+	if len(req.DomainAuthTypesJSON) > 0 {
+		err = json.Unmarshal(req.DomainAuthTypesJSON, &domainAuthTypes)
+		if err != nil {
+			return nil, errors.New("decode 'domainAuthTypesJSON' failed: " + err.Error())
+		}
+	}
+
 	var tx = this.NullTx()
-	taskId, err := acmemodels.SharedACMETaskDAO.CreateACMETask(tx, adminId, userId, req.AuthType, req.AcmeUserId, req.DnsProviderId, req.DnsDomain, req.Domains, req.AutoRenew, req.AuthURL)
+	taskId, err := acmemodels.SharedACMETaskDAO.CreateACMETask(tx, adminId, userId, req.AuthType, req.AcmeUserId, req.DnsProviderId, req.DnsDomain, req.Domains, domainAuthTypes, req.KeyType, req.AutoRenew, req.AuthURL)
 	if err != nil {
 		return nil, err
 	}
@@ -263,7 +277,16 @@ func (this *ACMETaskService) UpdateACMETask(ctx context.Context, req *pb.UpdateA
 		return nil, this.PermissionError()
 	}
 
-	err = acmemodels.SharedACMETaskDAO.UpdateACMETask(tx, req.AcmeTaskId, req.AcmeUserId, req.DnsProviderId, req.DnsDomain, req.Domains, req.AutoRenew, req.AuthURL)
+	// 单个域名的认证方式
+	var domainAuthTypes = map[string]string{}
+	if len(req.DomainAuthTypesJSON) > 0 {
+		err = json.Unmarshal(req.DomainAuthTypesJSON, &domainAuthTypes)
+		if err != nil {
+			return nil, errors.New("decode 'domainAuthTypesJSON' failed: " + err.Error())
+		}
+	}
+
+	err = acmemodels.SharedACMETaskDAO.UpdateACMETask(tx, req.AcmeTaskId, req.AcmeUserId, req.DnsProviderId, req.DnsDomain, req.Domains, domainAuthTypes, req.KeyType, req.AutoRenew, req.AuthURL)
 	if err != nil {
 		return nil, err
 	}
@@ -427,17 +450,19 @@ func (this *ACMETaskService) FindEnabledACMETask(ctx context.Context, req *pb.Fi
 	}
 
 	return &pb.FindEnabledACMETaskResponse{AcmeTask: &pb.ACMETask{
-		Id:          int64(task.Id),
-		IsOn:        task.IsOn,
-		DnsDomain:   task.DnsDomain,
-		Domains:     task.DecodeDomains(),
-		CreatedAt:   int64(task.CreatedAt),
-		AutoRenew:   task.AutoRenew == 1,
-		DnsProvider: pbProvider,
-		AcmeUser:    pbACMEUser,
-		AuthType:    task.AuthType,
-		AuthURL:     task.AuthURL,
-		SslCert:     pbCert,
+		Id:                  int64(task.Id),
+		IsOn:                task.IsOn,
+		DnsDomain:           task.DnsDomain,
+		Domains:             task.DecodeDomains(),
+		CreatedAt:           int64(task.CreatedAt),
+		AutoRenew:           task.AutoRenew == 1,
+		KeyType:             task.DecodeKeyType(),
+		DomainAuthTypesJSON: task.DomainAuthTypes,
+		DnsProvider:         pbProvider,
+		AcmeUser:            pbACMEUser,
+		AuthType:            task.AuthType,
+		AuthURL:             task.AuthURL,
+		SslCert:             pbCert,
 	}}, nil
 }
 
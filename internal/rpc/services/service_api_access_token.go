@@ -71,7 +71,7 @@ func (this *APIAccessTokenService) GetAPIAccessToken(ctx context.Context, req *p
 	}
 
 	// 创建AccessToken
-	token, expiresAt, err := models.SharedAPIAccessTokenDAO.GenerateAccessToken(tx, int64(accessKey.AdminId), int64(accessKey.UserId))
+	token, expiresAt, err := models.SharedAPIAccessTokenDAO.GenerateAccessToken(tx, int64(accessKey.AdminId), int64(accessKey.UserId), int64(accessKey.Id), accessKey.Scope)
 	if err != nil {
 		return nil, err
 	}
@@ -81,3 +81,36 @@ func (this *APIAccessTokenService) GetAPIAccessToken(ctx context.Context, req *p
 		ExpiresAt: expiresAt,
 	}, nil
 }
+
+// RevokeAPIAccessToken 吊销AccessToken
+func (this *APIAccessTokenService) RevokeAPIAccessToken(ctx context.Context, req *pb.RevokeAPIAccessTokenRequest) (*pb.RPCSuccess, error) {
+	adminId, userId, err := this.ValidateAdminAndUser(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx = this.NullTx()
+
+	accessToken, err := models.SharedAPIAccessTokenDAO.FindAccessToken(tx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+	if accessToken == nil {
+		return this.Success()
+	}
+
+	// 检查权限
+	if userId > 0 && int64(accessToken.UserId) != userId {
+		return nil, this.PermissionError()
+	}
+	if userId <= 0 && adminId > 0 && accessToken.AdminId > 0 && int64(accessToken.AdminId) != adminId {
+		// 管理员可以吊销用户的令牌，但不能吊销其他管理员的令牌
+		return nil, this.PermissionError()
+	}
+
+	err = models.SharedAPIAccessTokenDAO.RevokeAccessToken(tx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+	return this.Success()
+}
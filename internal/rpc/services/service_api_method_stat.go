@@ -4,8 +4,10 @@ package services
 
 import (
 	"context"
+	teaconst "github.com/dashenmiren/EdgeAPI/internal/const"
 	"github.com/dashenmiren/EdgeAPI/internal/db/models"
 	"github.com/dashenmiren/EdgeAPI/internal/utils"
+	"github.com/dashenmiren/EdgeAPI/internal/utils/ratelimit"
 	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
 	timeutil "github.com/iwind/TeaGo/utils/time"
 )
@@ -81,3 +83,70 @@ func (this *APIMethodStatService) CountAPIMethodStatsWithDay(ctx context.Context
 	}
 	return this.SuccessCount(count)
 }
+
+// FindAPIRateLimitStats 查找当前API节点的限流统计
+func (this *APIMethodStatService) FindAPIRateLimitStats(ctx context.Context, req *pb.FindAPIRateLimitStatsRequest) (*pb.FindAPIRateLimitStatsResponse, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pbStats = []*pb.APIRateLimitStat{}
+	for _, stat := range ratelimit.SharedLimiter.Stats() {
+		if req.OnlyDenied && stat.CountDenied == 0 {
+			continue
+		}
+		pbStats = append(pbStats, &pb.APIRateLimitStat{
+			Role:         stat.Role,
+			Identity:     stat.Identity,
+			Method:       stat.Method,
+			CountAllowed: stat.CountAllowed,
+			CountDenied:  stat.CountDenied,
+		})
+		if req.Size > 0 && int64(len(pbStats)) >= req.Size {
+			break
+		}
+	}
+
+	return &pb.FindAPIRateLimitStatsResponse{
+		ApiNodeId:         teaconst.NodeId,
+		ApiRateLimitStats: pbStats,
+	}, nil
+}
+
+// FindAPIStreamStatsWithDay 查找某天的Stream统计
+func (this *APIMethodStatService) FindAPIStreamStatsWithDay(ctx context.Context, req *pb.FindAPIStreamStatsWithDayRequest) (*pb.FindAPIStreamStatsWithDayResponse, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var day = req.Day
+	if len(day) == 0 {
+		day = timeutil.Format("Ymd")
+	}
+	var tx = this.NullTx()
+	stats, err := models.SharedAPIStreamStatDAO.FindAllStatsWithDay(tx, day)
+	if err != nil {
+		return nil, err
+	}
+	var pbStats = []*pb.APIStreamStat{}
+	for _, stat := range stats {
+		pbStats = append(pbStats, &pb.APIStreamStat{
+			Id:                int64(stat.Id),
+			ApiNodeId:         int64(stat.ApiNodeId),
+			Method:            stat.Method,
+			Role:              stat.Role,
+			CountStreams:      int64(stat.CountStreams),
+			LifetimeSeconds:   stat.LifetimeSeconds,
+			CountRecvMessages: int64(stat.CountRecvMessages),
+			CountSentMessages: int64(stat.CountSentMessages),
+			RecvBytes:         int64(stat.RecvBytes),
+			SentBytes:         int64(stat.SentBytes),
+		})
+	}
+
+	return &pb.FindAPIStreamStatsWithDayResponse{
+		ApiStreamStats: pbStats,
+	}, nil
+}
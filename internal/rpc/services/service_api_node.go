@@ -9,6 +9,7 @@ import (
 	teaconst "github.com/dashenmiren/EdgeAPI/internal/const"
 	"github.com/dashenmiren/EdgeAPI/internal/db/models"
 	"github.com/dashenmiren/EdgeAPI/internal/installers"
+	"github.com/dashenmiren/EdgeAPI/internal/rpc"
 	rpcutils "github.com/dashenmiren/EdgeAPI/internal/rpc/utils"
 	executils "github.com/dashenmiren/EdgeAPI/internal/utils/exec"
 	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
@@ -19,6 +20,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 )
 
 type APINodeService struct {
@@ -234,10 +236,26 @@ func (this *APINodeService) FindEnabledAPINode(ctx context.Context, req *pb.Find
 		AccessAddrs:     accessAddrs,
 		IsPrimary:       node.IsPrimary,
 		StatusJSON:      node.Status,
+		MetricsHTTPJSON: node.MetricsHTTP,
 	}
 	return &pb.FindEnabledAPINodeResponse{ApiNode: result}, nil
 }
 
+// UpdateAPINodeMetricsHTTP 修改API节点监控指标HTTP配置
+func (this *APINodeService) UpdateAPINodeMetricsHTTP(ctx context.Context, req *pb.UpdateAPINodeMetricsHTTPRequest) (*pb.RPCSuccess, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx = this.NullTx()
+	err = models.SharedAPINodeDAO.UpdateAPINodeMetricsHTTP(tx, req.ApiNodeId, req.MetricsHTTPJSON)
+	if err != nil {
+		return nil, err
+	}
+	return this.Success()
+}
+
 // FindCurrentAPINodeVersion 获取当前API节点的版本
 func (this *APINodeService) FindCurrentAPINodeVersion(ctx context.Context, req *pb.FindCurrentAPINodeVersionRequest) (*pb.FindCurrentAPINodeVersionResponse, error) {
 	role, _, _, err := rpcutils.ValidateRequest(ctx)
@@ -296,6 +314,34 @@ func (this *APINodeService) FindCurrentAPINode(ctx context.Context, req *pb.Find
 	}}, nil
 }
 
+// FindAllConnectedNodesWithClusters 查找当前API节点上每个集群连接的边缘节点数量
+func (this *APINodeService) FindAllConnectedNodesWithClusters(ctx context.Context, req *pb.FindAllConnectedNodesWithClustersRequest) (*pb.FindAllConnectedNodesWithClustersResponse, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pbClusters = []*pb.FindAllConnectedNodesWithClustersResponse_Cluster{}
+	for clusterId, countNodes := range rpc.SharedStreamRegistry.CountNodesWithClusters(rpcutils.UserTypeNode) {
+		if req.NodeClusterId > 0 && clusterId != req.NodeClusterId {
+			continue
+		}
+		pbClusters = append(pbClusters, &pb.FindAllConnectedNodesWithClustersResponse_Cluster{
+			NodeClusterId: clusterId,
+			CountNodes:    int64(countNodes),
+		})
+	}
+	sort.Slice(pbClusters, func(i, j int) bool {
+		return pbClusters[i].NodeClusterId < pbClusters[j].NodeClusterId
+	})
+
+	return &pb.FindAllConnectedNodesWithClustersResponse{
+		ApiNodeId:    teaconst.NodeId,
+		CountStreams: int64(rpc.SharedStreamRegistry.Count()),
+		Clusters:     pbClusters,
+	}, nil
+}
+
 // CountAllEnabledAPINodesWithSSLCertId 计算使用某个SSL证书的API节点数量
 func (this *APINodeService) CountAllEnabledAPINodesWithSSLCertId(ctx context.Context, req *pb.CountAllEnabledAPINodesWithSSLCertIdRequest) (*pb.RPCCountResponse, error) {
 	_, err := this.ValidateAdmin(ctx)
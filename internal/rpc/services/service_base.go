@@ -2,20 +2,15 @@ package services
 
 import (
 	"context"
-	"encoding/base64"
-	"encoding/json"
 	teaconst "github.com/dashenmiren/EdgeAPI/internal/const"
 	"github.com/dashenmiren/EdgeAPI/internal/db/models"
 	"github.com/dashenmiren/EdgeAPI/internal/db/models/authority"
-	"github.com/dashenmiren/EdgeAPI/internal/encrypt"
 	"github.com/dashenmiren/EdgeAPI/internal/errors"
 	"github.com/dashenmiren/EdgeAPI/internal/rpc"
 	rpcutils "github.com/dashenmiren/EdgeAPI/internal/rpc/utils"
-	"github.com/dashenmiren/EdgeAPI/internal/utils"
 	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
 	"github.com/iwind/TeaGo/dbs"
 	"github.com/iwind/TeaGo/lists"
-	"github.com/iwind/TeaGo/maps"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -137,36 +132,11 @@ func (this *BaseService) ValidateNodeId(ctx context.Context, roles ...rpcutils.U
 		return rpcutils.UserTypeNone, 0, errors.New("context: unsupported role '" + apiToken.Role + "'")
 	}
 
-	tokens := md.Get("token")
-	if len(tokens) == 0 || len(tokens[0]) == 0 {
-		return rpcutils.UserTypeNone, 0, errors.New("context: need 'token'")
-	}
-	token := tokens[0]
-
-	data, err := base64.StdEncoding.DecodeString(token)
+	_, err = rpcutils.DecodeTokenContext(ctx, md, nodeId, apiToken.Secret)
 	if err != nil {
 		return rpcutils.UserTypeNone, 0, err
 	}
 
-	method, err := encrypt.NewMethodInstance(teaconst.EncryptMethod, apiToken.Secret, nodeId)
-	if err != nil {
-		utils.PrintError(err)
-		return rpcutils.UserTypeNone, 0, err
-	}
-	data, err = method.Decrypt(data)
-	if err != nil {
-		return rpcutils.UserTypeNone, 0, err
-	}
-	if len(data) == 0 {
-		return rpcutils.UserTypeNone, 0, errors.New("invalid token")
-	}
-
-	m := maps.Map{}
-	err = json.Unmarshal(data, &m)
-	if err != nil {
-		return rpcutils.UserTypeNone, 0, errors.New("decode token error: " + err.Error())
-	}
-
 	role = apiToken.Role
 	switch apiToken.Role {
 	case rpcutils.UserTypeNode:
@@ -59,6 +59,8 @@ func (this *DNSTaskService) FindAllDoingDNSTasks(ctx context.Context, req *pb.Fi
 			IsOk:      task.IsOk,
 			Error:     task.Error,
 			UpdatedAt: int64(task.UpdatedAt),
+
+			FailedRecordsJSON: task.FailedRecords,
 		}
 
 		switch task.Type {
@@ -3,9 +3,15 @@ package services
 import (
 	"context"
 	"github.com/dashenmiren/EdgeAPI/internal/db/models"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	rpcutils "github.com/dashenmiren/EdgeAPI/internal/rpc/utils"
 	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"github.com/iwind/TeaGo/types"
 )
 
+// 下载文件时每个消息中内容的最大长度
+const fileStreamMessageSize = 256 * 1024
+
 // FileService 文件相关服务
 type FileService struct {
 	BaseService
@@ -85,3 +91,59 @@ func (this *FileService) UpdateFileFinished(ctx context.Context, req *pb.UpdateF
 	}
 	return this.Success()
 }
+
+// DownloadFile 以流的形式下载文件内容
+// 按顺序从各个片段所在的存储中读取内容，每个消息最多包含256K内容
+func (this *FileService) DownloadFile(req *pb.DownloadFileRequest, stream pb.FileService_DownloadFileServer) error {
+	var ctx = stream.Context()
+	role, _, err := this.ValidateNodeId(ctx, rpcutils.UserTypeNode, rpcutils.UserTypeDNS, rpcutils.UserTypeAdmin, rpcutils.UserTypeUser)
+	if err != nil {
+		return err
+	}
+	var userId int64
+	if role == rpcutils.UserTypeUser {
+		_, userId, err = this.ValidateAdminAndUser(ctx, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	var tx = this.NullTx()
+	file, err := models.SharedFileDAO.FindEnabledFile(tx, req.FileId)
+	if err != nil {
+		return err
+	}
+	if file == nil {
+		return errors.New("can not find file '" + types.String(req.FileId) + "'")
+	}
+	if !file.IsPublic && userId > 0 && int64(file.UserId) != userId {
+		return this.PermissionError()
+	}
+
+	return models.SharedFileChunkDAO.ReadFileTo(tx, req.FileId, &fileStreamWriter{
+		send: func(data []byte) error {
+			return stream.Send(&pb.DownloadFileResponse{Data: data})
+		},
+	})
+}
+
+// 将写入的内容拆分成多个消息发送
+type fileStreamWriter struct {
+	send func(data []byte) error
+}
+
+func (this *fileStreamWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		var size = len(p)
+		if size > fileStreamMessageSize {
+			size = fileStreamMessageSize
+		}
+		err = this.send(p[:size])
+		if err != nil {
+			return n, err
+		}
+		n += size
+		p = p[size:]
+	}
+	return n, nil
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/dashenmiren/EdgeAPI/internal/db/models"
 	rpcutils "github.com/dashenmiren/EdgeAPI/internal/rpc/utils"
 	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"io"
 )
 
 // FileChunkService 文件片段相关服务
@@ -37,6 +38,44 @@ func (this *FileChunkService) CreateFileChunk(ctx context.Context, req *pb.Creat
 	return &pb.CreateFileChunkResponse{FileChunkId: chunkId}, nil
 }
 
+// UploadFileChunks 以流的形式上传文件内容
+// 每个消息创建一个片段，客户端结束发送后返回所有创建的片段ID
+func (this *FileChunkService) UploadFileChunks(stream pb.FileChunkService_UploadFileChunksServer) error {
+	// 校验请求
+	_, userId, err := this.ValidateAdminAndUser(stream.Context(), false)
+	if err != nil {
+		return err
+	}
+
+	var tx = this.NullTx()
+	var checkedFileIds = map[int64]bool{}
+	var chunkIds = []int64{}
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF {
+				return stream.SendAndClose(&pb.UploadFileChunksResponse{FileChunkIds: chunkIds})
+			}
+			return err
+		}
+
+		// 检查权限
+		if userId > 0 && !checkedFileIds[req.FileId] {
+			err = models.SharedFileDAO.CheckUserFile(tx, userId, req.FileId)
+			if err != nil {
+				return err
+			}
+			checkedFileIds[req.FileId] = true
+		}
+
+		chunkId, err := models.SharedFileChunkDAO.CreateFileChunk(tx, req.FileId, req.Data)
+		if err != nil {
+			return err
+		}
+		chunkIds = append(chunkIds, chunkId)
+	}
+}
+
 // FindAllFileChunkIds 获取的一个文件的所有片段IDs
 func (this *FileChunkService) FindAllFileChunkIds(ctx context.Context, req *pb.FindAllFileChunkIdsRequest) (*pb.FindAllFileChunkIdsResponse, error) {
 	// 校验请求
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"github.com/dashenmiren/EdgeAPI/internal/accesslogs"
 	"github.com/dashenmiren/EdgeAPI/internal/db/models"
 	"github.com/dashenmiren/EdgeAPI/internal/errors"
 	rpcutils "github.com/dashenmiren/EdgeAPI/internal/rpc/utils"
@@ -69,7 +70,31 @@ func (this *HTTPAccessLogService) ListHTTPAccessLogs(ctx context.Context, req *p
 		}
 	}
 
-	accessLogs, requestId, hasMore, err := models.SharedHTTPAccessLogDAO.ListAccessLogs(tx, req.Partition, req.RequestId, req.Size, req.Day, req.HourFrom, req.HourTo, req.NodeClusterId, req.NodeId, req.ServerId, req.Reverse, req.HasError, req.FirewallPolicyId, req.FirewallRuleGroupId, req.FirewallRuleSetId, req.HasFirewallPolicy, req.UserId, req.Keyword, req.Ip, req.Domain)
+	var filter = &models.HTTPAccessLogFilter{
+		ClusterId:           req.NodeClusterId,
+		NodeId:              req.NodeId,
+		ServerId:            req.ServerId,
+		HasError:            req.HasError,
+		FirewallPolicyId:    req.FirewallPolicyId,
+		FirewallRuleGroupId: req.FirewallRuleGroupId,
+		FirewallRuleSetId:   req.FirewallRuleSetId,
+		HasFirewallPolicy:   req.HasFirewallPolicy,
+		UserId:              req.UserId,
+		Keyword:             req.Keyword,
+		IP:                  req.Ip,
+		Domain:              req.Domain,
+	}
+
+	// 结构化查询
+	if len(req.Query) > 0 {
+		query, err := accesslogs.ParseQuery(req.Query)
+		if err != nil {
+			return nil, errors.New("invalid 'query': " + err.Error())
+		}
+		filter.Query = query
+	}
+
+	accessLogs, requestId, hasMore, err := models.SharedHTTPAccessLogDAO.ListAccessLogsWithFilter(tx, req.Partition, req.RequestId, req.Size, req.Day, req.HourFrom, req.HourTo, req.Reverse, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -222,3 +247,86 @@ func (this *HTTPAccessLogService) FindHTTPAccessLogPartitions(ctx context.Contex
 		ReversePartitions: reversePartitions,
 	}, nil
 }
+
+// AggregateHTTPAccessLogs 对某天的访问日志分组统计
+func (this *HTTPAccessLogService) AggregateHTTPAccessLogs(ctx context.Context, req *pb.AggregateHTTPAccessLogsRequest) (*pb.AggregateHTTPAccessLogsResponse, error) {
+	_, userId, err := this.ValidateAdminAndUser(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if !regexputils.YYYYMMDD.MatchString(req.Day) {
+		return nil, errors.New("invalid 'day': " + req.Day)
+	}
+
+	var tx = this.NullTx()
+
+	var filter = &models.HTTPAccessLogFilter{
+		ClusterId: req.NodeClusterId,
+		NodeId:    req.NodeId,
+		ServerId:  req.ServerId,
+	}
+
+	// 用户只能统计自己网站的日志
+	if userId > 0 {
+		filter.UserId = userId
+		filter.ClusterId = 0
+		filter.NodeId = 0
+		if req.ServerId > 0 {
+			err = models.SharedServerDAO.CheckUserServer(tx, userId, req.ServerId)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(req.Query) > 0 {
+		query, err := accesslogs.ParseQuery(req.Query)
+		if err != nil {
+			return nil, errors.New("invalid 'query': " + err.Error())
+		}
+		filter.Query = query
+	}
+
+	aggregations, err := models.SharedHTTPAccessLogDAO.AggregateAccessLogs(tx, req.Day, req.HourFrom, req.HourTo, filter, req.GroupBy, req.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	var pbItems = []*pb.AggregateHTTPAccessLogsResponse_Item{}
+	for _, aggregation := range aggregations {
+		pbItems = append(pbItems, &pb.AggregateHTTPAccessLogsResponse_Item{
+			Key:   aggregation.Key,
+			Count: aggregation.Count,
+		})
+	}
+	return &pb.AggregateHTTPAccessLogsResponse{
+		Items: pbItems,
+	}, nil
+}
+
+// FindHTTPAccessLogQueueStatus 查看当前API节点的访问日志队列状态
+func (this *HTTPAccessLogService) FindHTTPAccessLogQueueStatus(ctx context.Context, req *pb.FindHTTPAccessLogQueueStatusRequest) (*pb.FindHTTPAccessLogQueueStatusResponse, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var status = models.AccessLogQueueStatus()
+	var resp = &pb.FindHTTPAccessLogQueueStatusResponse{
+		Percent:        int32(status.Percent),
+		QueueLength:    int64(status.QueueLength),
+		QueueMaxLength: int64(status.QueueMaxLength),
+		CountDropped:   status.CountDropped,
+	}
+	if status.WAL != nil {
+		resp.WalIsOn = true
+		resp.WalSegments = int32(status.WAL.Segments)
+		resp.WalSize = status.WAL.Size
+		resp.WalMaxSize = status.WAL.MaxSize
+		resp.WalRecords = status.WAL.Records
+		resp.WalCountDropped = status.WAL.CountDropped
+		resp.WalCountCorrupted = status.WAL.CountCorrupted
+	}
+	return resp, nil
+}
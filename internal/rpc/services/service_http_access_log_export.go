@@ -0,0 +1,125 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"github.com/dashenmiren/EdgeAPI/internal/accesslogs"
+	"github.com/dashenmiren/EdgeAPI/internal/db/models"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"io"
+)
+
+// ExportHTTPAccessLogs 导出某个时间范围内的访问日志
+// 每批日志编码后作为一个消息发送，消息中的 cursor 可以用来在中断后继续导出；
+// Parquet格式中断后需要从 cursor 处重新导出为一个新的文件
+func (this *HTTPAccessLogService) ExportHTTPAccessLogs(req *pb.ExportHTTPAccessLogsRequest, stream pb.HTTPAccessLogService_ExportHTTPAccessLogsServer) error {
+	var buf = &bytes.Buffer{}
+	return this.ExportHTTPAccessLogsTo(stream.Context(), req, buf, func(cursor string, count int) error {
+		err := stream.Send(&pb.ExportHTTPAccessLogsResponse{
+			Data:   buf.Bytes(),
+			Cursor: cursor,
+			Count:  int64(count),
+		})
+		buf.Reset()
+		return err
+	})
+}
+
+// ExportHTTPAccessLogsTo 导出访问日志到 writer
+// 每写入一批日志调用一次 onBatch，最后一次调用时 count 为0，表示文件已结束；
+// 同时用于GRPC和REST接口
+func (this *HTTPAccessLogService) ExportHTTPAccessLogsTo(ctx context.Context, req *pb.ExportHTTPAccessLogsRequest, writer io.Writer, onBatch func(cursor string, count int) error) error {
+	_, userId, err := this.ValidateAdminAndUser(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	if len(req.Format) == 0 {
+		req.Format = accesslogs.ExportFormatNDJSON
+	}
+	if !accesslogs.IsValidExportFormat(req.Format) {
+		return errors.New("invalid 'format': " + req.Format)
+	}
+
+	var cursor *accesslogs.ExportCursor
+	if len(req.Cursor) > 0 {
+		cursor, err = accesslogs.DecodeExportCursor(req.Cursor)
+		if err != nil {
+			return err
+		}
+	}
+
+	var tx = this.NullTx()
+
+	var filter = &models.HTTPAccessLogFilter{
+		ClusterId:         req.NodeClusterId,
+		NodeId:            req.NodeId,
+		ServerId:          req.ServerId,
+		HasError:          req.HasError,
+		FirewallPolicyId:  req.FirewallPolicyId,
+		HasFirewallPolicy: req.HasFirewallPolicy,
+		Keyword:           req.Keyword,
+		IP:                req.Ip,
+		Domain:            req.Domain,
+	}
+
+	// 用户只能导出自己网站的日志
+	if userId > 0 {
+		filter.UserId = userId
+		if req.ServerId > 0 {
+			err = models.SharedServerDAO.CheckUserServer(tx, userId, req.ServerId)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(req.Query) > 0 {
+		query, err := accesslogs.ParseQuery(req.Query)
+		if err != nil {
+			return errors.New("invalid 'query': " + err.Error())
+		}
+		filter.Query = query
+	}
+
+	exportWriter, err := accesslogs.NewExportWriter(req.Format, writer)
+	if err != nil {
+		return err
+	}
+
+	var lastCursor = req.Cursor
+	err = models.SharedHTTPAccessLogDAO.ExportAccessLogs(tx, req.TimeFrom, req.TimeTo, filter, cursor, req.Size, func(accessLogs []*models.HTTPAccessLog, cursor *accesslogs.ExportCursor) error {
+		// 客户端已断开
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var pbAccessLogs = []*pb.HTTPAccessLog{}
+		for _, accessLog := range accessLogs {
+			pbAccessLog, err := accessLog.ToPB()
+			if err != nil {
+				return err
+			}
+			pbAccessLogs = append(pbAccessLogs, pbAccessLog)
+		}
+
+		err := exportWriter.Write(pbAccessLogs)
+		if err != nil {
+			return err
+		}
+		lastCursor = cursor.Encode()
+		return onBatch(lastCursor, len(pbAccessLogs))
+	})
+	if err != nil {
+		return err
+	}
+
+	err = exportWriter.Close()
+	if err != nil {
+		return err
+	}
+	return onBatch(lastCursor, 0)
+}
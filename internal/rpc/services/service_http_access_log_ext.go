@@ -3,12 +3,16 @@
 
 package services
 
-import "github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/accesslogs"
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+)
 
 func (this *HTTPAccessLogService) canWriteAccessLogsToDB() bool {
-	return true
+	return accesslogs.SharedManager.CanWriteToDB()
 }
 
 func (this *HTTPAccessLogService) writeAccessLogsToPolicy(pbAccessLogs []*pb.HTTPAccessLog) error {
+	accesslogs.SharedManager.Write(pbAccessLogs)
 	return nil
 }
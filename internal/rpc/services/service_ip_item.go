@@ -922,3 +922,189 @@ func (this *IPItemService) FindServerIdWithIPItemId(ctx context.Context, req *pb
 
 	return &pb.FindServerIdWithIPItemIdResponse{ServerId: 0}, nil
 }
+
+// ImportIPItems 批量导入IP
+func (this *IPItemService) ImportIPItems(ctx context.Context, req *pb.ImportIPItemsRequest) (*pb.ImportIPItemsResponse, error) {
+	_, userId, err := this.ValidateAdminAndUser(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if !models.IsValidIPItemFormat(req.Format) {
+		return nil, errors.New("invalid format '" + req.Format + "'")
+	}
+
+	var tx = this.NullTx()
+
+	if userId > 0 {
+		err = models.SharedIPListDAO.CheckUserIPList(tx, userId, req.IpListId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	items, parseConflicts, err := models.SharedIPItemDAO.ParseIPItems(req.Format, req.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	// 默认值
+	for _, item := range items {
+		if item.ExpiredAt <= 0 {
+			item.ExpiredAt = req.ExpiredAt
+		}
+		if len(item.Reason) == 0 {
+			item.Reason = req.Reason
+		}
+		if len(item.EventLevel) == 0 {
+			item.EventLevel = req.EventLevel
+		}
+	}
+
+	result, err := models.SharedIPItemDAO.ImportIPItems(tx, req.IpListId, items, req.Overwrite)
+	if err != nil {
+		return nil, err
+	}
+
+	var pbConflicts = []*pb.IPItemImportConflict{}
+	for _, conflict := range append(parseConflicts, result.Conflicts...) {
+		pbConflicts = append(pbConflicts, &pb.IPItemImportConflict{
+			Line:     int32(conflict.Line),
+			Value:    conflict.Value,
+			Reason:   conflict.Reason,
+			IpItemId: conflict.ItemId,
+		})
+	}
+
+	return &pb.ImportIPItemsResponse{
+		CountCreated: int64(result.CountCreated),
+		CountUpdated: int64(result.CountUpdated),
+		CountSkipped: int64(result.CountSkipped + len(parseConflicts)),
+		Conflicts:    pbConflicts,
+	}, nil
+}
+
+// ExportIPItems 以流的形式导出名单中的IP
+func (this *IPItemService) ExportIPItems(req *pb.ExportIPItemsRequest, stream pb.IPItemService_ExportIPItemsServer) error {
+	_, userId, err := this.ValidateAdminAndUser(stream.Context(), false)
+	if err != nil {
+		return err
+	}
+
+	var tx = this.NullTx()
+
+	if userId > 0 {
+		err = models.SharedIPListDAO.CheckUserIPList(tx, userId, req.IpListId)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = models.SharedIPItemDAO.ExportIPItems(tx, req.IpListId, req.Format, &fileStreamWriter{
+		send: func(data []byte) error {
+			return stream.Send(&pb.ExportIPItemsResponse{Data: data})
+		},
+	})
+	return err
+}
+
+// ExplainIP 查找影响某个IP的所有名单条目
+func (this *IPItemService) ExplainIP(ctx context.Context, req *pb.ExplainIPRequest) (*pb.ExplainIPResponse, error) {
+	_, userId, err := this.ValidateAdminAndUser(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if !iputils.IsValid(req.Ip) {
+		return nil, errors.New("invalid ip '" + req.Ip + "'")
+	}
+
+	var tx = this.NullTx()
+	explanations, err := models.SharedIPItemDAO.ExplainIP(tx, req.Ip, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var results = []*pb.ExplainIPResponse_Result{}
+	for _, explanation := range explanations {
+		var item = explanation.Item
+		var list = explanation.List
+
+		var pbItem = &pb.IPItem{
+			Id:                            int64(item.Id),
+			Value:                         item.ComposeValue(),
+			IpFrom:                        item.IpFrom,
+			IpTo:                          item.IpTo,
+			Version:                       int64(item.Version),
+			CreatedAt:                     int64(item.CreatedAt),
+			ExpiredAt:                     int64(item.ExpiredAt),
+			Reason:                        item.Reason,
+			Type:                          item.Type,
+			EventLevel:                    item.EventLevel,
+			NodeId:                        int64(item.NodeId),
+			ServerId:                      int64(item.ServerId),
+			SourceNodeId:                  int64(item.SourceNodeId),
+			SourceServerId:                int64(item.SourceServerId),
+			SourceHTTPFirewallPolicyId:    int64(item.SourceHTTPFirewallPolicyId),
+			SourceHTTPFirewallRuleGroupId: int64(item.SourceHTTPFirewallRuleGroupId),
+			SourceHTTPFirewallRuleSetId:   int64(item.SourceHTTPFirewallRuleSetId),
+			ListId:                        int64(list.Id),
+			ListType:                      list.Type,
+		}
+
+		// WAF规则集
+		if item.SourceHTTPFirewallRuleSetId > 0 {
+			setName, err := models.SharedHTTPFirewallRuleSetDAO.FindHTTPFirewallRuleSetName(tx, int64(item.SourceHTTPFirewallRuleSetId))
+			if err != nil {
+				return nil, err
+			}
+			pbItem.SourceHTTPFirewallRuleSet = &pb.HTTPFirewallRuleSet{
+				Id:   int64(item.SourceHTTPFirewallRuleSetId),
+				Name: setName,
+			}
+		}
+
+		// 订阅
+		var pbFeed *pb.IPListFeed
+		if item.SourceFeedId > 0 {
+			feed, err := models.SharedIPListFeedDAO.FindEnabledFeed(tx, int64(item.SourceFeedId))
+			if err != nil {
+				return nil, err
+			}
+			if feed != nil {
+				pbFeed = &pb.IPListFeed{
+					Id:   int64(feed.Id),
+					Name: feed.Name,
+				}
+			}
+		}
+
+		// 所属策略
+		var pbFirewallPolicy *pb.HTTPFirewallPolicy
+		if explanation.Policy != nil {
+			pbFirewallPolicy = &pb.HTTPFirewallPolicy{
+				Id:       int64(explanation.Policy.Id),
+				Name:     explanation.Policy.Name,
+				ServerId: int64(explanation.Policy.ServerId),
+			}
+		}
+
+		results = append(results, &pb.ExplainIPResponse_Result{
+			IpList: &pb.IPList{
+				Id:       int64(list.Id),
+				IsOn:     list.IsOn,
+				Name:     list.Name,
+				Type:     list.Type,
+				IsPublic: list.IsPublic,
+				IsGlobal: list.IsGlobal,
+			},
+			IpItem:             pbItem,
+			HttpFirewallPolicy: pbFirewallPolicy,
+			IpListFeed:         pbFeed,
+			Scope:              explanation.Scope,
+			Source:             explanation.Source,
+			IpListActionsJSON:  list.Actions,
+		})
+	}
+	return &pb.ExplainIPResponse{Results: results}, nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package services
+
+import (
+	"context"
+	"github.com/dashenmiren/EdgeAPI/internal/db/models"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"github.com/iwind/TeaGo/dbs"
+	"github.com/iwind/TeaGo/types"
+)
+
+// IPListFeedService IP名单订阅相关服务
+type IPListFeedService struct {
+	BaseService
+}
+
+// CreateIPListFeed 创建订阅
+func (this *IPListFeedService) CreateIPListFeed(ctx context.Context, req *pb.CreateIPListFeedRequest) (*pb.CreateIPListFeedResponse, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx = this.NullTx()
+	exists, err := models.SharedIPListDAO.ExistsEnabledIPList(tx, req.IpListId)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("can not find ip list '" + types.String(req.IpListId) + "'")
+	}
+
+	feedId, err := models.SharedIPListFeedDAO.CreateFeed(tx, req.IpListId, req.Name, req.Url, req.Format, req.Interval, req.IsOn)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CreateIPListFeedResponse{IpListFeedId: feedId}, nil
+}
+
+// UpdateIPListFeed 修改订阅
+func (this *IPListFeedService) UpdateIPListFeed(ctx context.Context, req *pb.UpdateIPListFeedRequest) (*pb.RPCSuccess, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx = this.NullTx()
+	err = models.SharedIPListFeedDAO.UpdateFeed(tx, req.IpListFeedId, req.Name, req.Url, req.Format, req.Interval, req.IsOn)
+	if err != nil {
+		return nil, err
+	}
+	return this.Success()
+}
+
+// DeleteIPListFeed 删除订阅，同时删除从订阅中导入的IP
+func (this *IPListFeedService) DeleteIPListFeed(ctx context.Context, req *pb.DeleteIPListFeedRequest) (*pb.RPCSuccess, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = this.RunTx(func(tx *dbs.Tx) error {
+		return models.SharedIPListFeedDAO.DisableFeed(tx, req.IpListFeedId)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return this.Success()
+}
+
+// FindEnabledIPListFeed 查找单个订阅
+func (this *IPListFeedService) FindEnabledIPListFeed(ctx context.Context, req *pb.FindEnabledIPListFeedRequest) (*pb.FindEnabledIPListFeedResponse, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx = this.NullTx()
+	feed, err := models.SharedIPListFeedDAO.FindEnabledFeed(tx, req.IpListFeedId)
+	if err != nil {
+		return nil, err
+	}
+	if feed == nil {
+		return &pb.FindEnabledIPListFeedResponse{IpListFeed: nil}, nil
+	}
+	return &pb.FindEnabledIPListFeedResponse{IpListFeed: this.convertFeed(feed)}, nil
+}
+
+// FindAllEnabledIPListFeedsWithIPListId 查找名单的所有订阅
+func (this *IPListFeedService) FindAllEnabledIPListFeedsWithIPListId(ctx context.Context, req *pb.FindAllEnabledIPListFeedsWithIPListIdRequest) (*pb.FindAllEnabledIPListFeedsWithIPListIdResponse, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx = this.NullTx()
+	feeds, err := models.SharedIPListFeedDAO.FindAllEnabledFeedsWithListId(tx, req.IpListId)
+	if err != nil {
+		return nil, err
+	}
+	var pbFeeds = []*pb.IPListFeed{}
+	for _, feed := range feeds {
+		pbFeeds = append(pbFeeds, this.convertFeed(feed))
+	}
+	return &pb.FindAllEnabledIPListFeedsWithIPListIdResponse{IpListFeeds: pbFeeds}, nil
+}
+
+// SyncIPListFeed 立即同步订阅
+func (this *IPListFeedService) SyncIPListFeed(ctx context.Context, req *pb.SyncIPListFeedRequest) (*pb.SyncIPListFeedResponse, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx = this.NullTx()
+	result, err := models.SharedIPListFeedDAO.SyncFeed(tx, req.IpListFeedId)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SyncIPListFeedResponse{
+		CountItems:     int64(result.CountItems),
+		CountAdded:     int64(result.CountAdded),
+		CountRemoved:   int64(result.CountRemoved),
+		CountConflicts: int64(result.CountConflicts),
+	}, nil
+}
+
+func (this *IPListFeedService) convertFeed(feed *models.IPListFeed) *pb.IPListFeed {
+	return &pb.IPListFeed{
+		Id:               int64(feed.Id),
+		IpListId:         int64(feed.ListId),
+		Name:             feed.Name,
+		Url:              feed.Url,
+		Format:           feed.Format,
+		Interval:         int32(feed.Interval),
+		IsOn:             feed.IsOn,
+		LastSyncAt:       int64(feed.LastSyncAt),
+		LastError:        feed.LastError,
+		LastCountItems:   int64(feed.LastCountItems),
+		LastCountAdded:   int64(feed.LastCountAdded),
+		LastCountRemoved: int64(feed.LastCountRemoved),
+		CreatedAt:        int64(feed.CreatedAt),
+	}
+}
@@ -2,17 +2,14 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
-	"fmt"
 	"github.com/dashenmiren/EdgeAPI/internal/db/models"
+	"github.com/dashenmiren/EdgeAPI/internal/installers"
 	"github.com/dashenmiren/EdgeAPI/internal/utils/numberutils"
-	"github.com/dashenmiren/EdgeCommon/pkg/configutils"
 	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
-	"golang.org/x/crypto/ssh"
-	"net"
 	"regexp"
 	"strings"
-	"time"
 )
 
 type NodeGrantService struct {
@@ -176,16 +173,18 @@ func (this *NodeGrantService) FindEnabledNodeGrant(ctx context.Context, req *pb.
 		return &pb.FindEnabledNodeGrantResponse{}, nil
 	}
 	return &pb.FindEnabledNodeGrantResponse{NodeGrant: &pb.NodeGrant{
-		Id:          int64(grant.Id),
-		Name:        grant.Name,
-		Method:      grant.Method,
-		Username:    grant.Username,
-		Password:    grant.Password,
-		Su:          grant.Su == 1,
-		PrivateKey:  grant.PrivateKey,
-		Passphrase:  grant.Passphrase,
-		Description: grant.Description,
-		NodeId:      int64(grant.NodeId),
+		Id:            int64(grant.Id),
+		Name:          grant.Name,
+		Method:        grant.Method,
+		Username:      grant.Username,
+		Password:      grant.Password,
+		Su:            grant.Su == 1,
+		PrivateKey:    grant.PrivateKey,
+		Passphrase:    grant.Passphrase,
+		Description:   grant.Description,
+		NodeId:        int64(grant.NodeId),
+		HostKeyPolicy: grant.FindHostKeyPolicy(),
+		JumpHostsJSON: grant.JumpHosts,
 	}}, nil
 }
 
@@ -196,8 +195,6 @@ func (this *NodeGrantService) TestNodeGrant(ctx context.Context, req *pb.TestNod
 		return nil, err
 	}
 
-	var hostKeyCallback ssh.HostKeyCallback = nil
-
 	resp := &pb.TestNodeGrantResponse{
 		IsOk:  false,
 		Error: "",
@@ -228,65 +225,22 @@ func (this *NodeGrantService) TestNodeGrant(ctx context.Context, req *pb.TestNod
 		return resp, nil
 	}
 
-	// 不使用known_hosts
-	if hostKeyCallback == nil {
-		hostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			return nil
-		}
-	}
-
-	// 认证
-	methods := []ssh.AuthMethod{}
-	if grant.Method == "user" {
-		{
-			authMethod := ssh.Password(grant.Password)
-			methods = append(methods, authMethod)
-		}
-
-		{
-			authMethod := ssh.KeyboardInteractive(func(user, instruction string, questions []string, echos []bool) (answers []string, err error) {
-				if len(questions) == 0 {
-					return []string{}, nil
-				}
-				return []string{grant.Password}, nil
-			})
-			methods = append(methods, authMethod)
-		}
-	} else if grant.Method == "privateKey" {
-		var signer ssh.Signer
-		if len(grant.Passphrase) != 0 {
-			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(grant.PrivateKey), []byte(grant.Passphrase))
-		} else {
-			signer, err = ssh.ParsePrivateKey([]byte(grant.PrivateKey))
-		}
-		if err != nil {
-			resp.Error = "parse private key: " + err.Error()
+	// 和安装节点时一样校验主机公钥并使用跳板机
+	credentials, err := installers.NewGrantCredentials(grant, req.Host, int(req.Port))
+	if err != nil {
+		if installers.IsGrantError(err) {
+			resp.Error = err.Error()
 			return resp, nil
 		}
-		authMethod := ssh.PublicKeys(signer)
-		methods = append(methods, authMethod)
-	} else {
-		return nil, errors.New("invalid method '" + grant.Method + "'")
-	}
-
-	// SSH客户端
-	if len(grant.Username) == 0 {
-		grant.Username = "root"
-	}
-	config := &ssh.ClientConfig{
-		User:            grant.Username,
-		Auth:            methods,
-		HostKeyCallback: hostKeyCallback,
-		Timeout:         5 * time.Second, // TODO 后期可以设置这个超时时间
+		return nil, err
 	}
-
-	sshClient, err := ssh.Dial("tcp", configutils.QuoteIP(req.Host)+":"+fmt.Sprintf("%d", req.Port), config)
+	sshConn, err := installers.DialSSH(credentials)
 	if err != nil {
 		resp.Error = "connect failed: " + err.Error()
 		return resp, nil
 	}
 	defer func() {
-		_ = sshClient.Close()
+		_ = sshConn.Close()
 	}()
 
 	resp.IsOk = true
@@ -324,3 +278,100 @@ func (this *NodeGrantService) FindSuggestNodeGrants(ctx context.Context, req *pb
 	}
 	return &pb.FindSuggestNodeGrantsResponse{NodeGrants: pbGrants}, nil
 }
+
+// UpdateNodeGrantSSHOptions 修改认证的主机公钥校验策略和跳板机
+func (this *NodeGrantService) UpdateNodeGrantSSHOptions(ctx context.Context, req *pb.UpdateNodeGrantSSHOptionsRequest) (*pb.RPCSuccess, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var jumpHosts = []*models.NodeGrantJumpHost{}
+	if len(req.JumpHostsJSON) > 0 {
+		err = json.Unmarshal(req.JumpHostsJSON, &jumpHosts)
+		if err != nil {
+			return nil, errors.New("decode 'jumpHostsJSON' failed: " + err.Error())
+		}
+	}
+
+	var tx = this.NullTx()
+	for _, jumpHost := range jumpHosts {
+		jumpGrant, err := models.SharedNodeGrantDAO.FindEnabledNodeGrant(tx, jumpHost.GrantId)
+		if err != nil {
+			return nil, err
+		}
+		if jumpGrant == nil {
+			return nil, errors.New("can not find grant with id '" + numberutils.FormatInt64(jumpHost.GrantId) + "' for jump host '" + jumpHost.Host + "'")
+		}
+	}
+
+	err = models.SharedNodeGrantDAO.UpdateGrantSSHOptions(tx, req.NodeGrantId, req.HostKeyPolicy, jumpHosts)
+	if err != nil {
+		return nil, err
+	}
+	return this.Success()
+}
+
+// FindNodeGrantHostKeys 查找认证中保存的主机公钥
+func (this *NodeGrantService) FindNodeGrantHostKeys(ctx context.Context, req *pb.FindNodeGrantHostKeysRequest) (*pb.FindNodeGrantHostKeysResponse, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	grant, err := models.SharedNodeGrantDAO.FindEnabledNodeGrant(this.NullTx(), req.NodeGrantId)
+	if err != nil {
+		return nil, err
+	}
+	if grant == nil {
+		return &pb.FindNodeGrantHostKeysResponse{}, nil
+	}
+	hostKeys, err := grant.DecodeHostKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	var pbHostKeys = []*pb.NodeGrantHostKey{}
+	for _, hostKey := range hostKeys {
+		pbHostKeys = append(pbHostKeys, &pb.NodeGrantHostKey{
+			Host:        hostKey.Host,
+			KeyType:     hostKey.KeyType,
+			Fingerprint: hostKey.Fingerprint,
+			State:       hostKey.State,
+			CreatedAt:   hostKey.CreatedAt,
+		})
+	}
+	return &pb.FindNodeGrantHostKeysResponse{
+		HostKeyPolicy:     grant.FindHostKeyPolicy(),
+		NodeGrantHostKeys: pbHostKeys,
+	}, nil
+}
+
+// ApproveNodeGrantHostKey 信任某个主机公钥
+// 同一个主机的其他公钥会被删除；也可以用来预先设置主机公钥
+func (this *NodeGrantService) ApproveNodeGrantHostKey(ctx context.Context, req *pb.ApproveNodeGrantHostKeyRequest) (*pb.RPCSuccess, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = models.SharedNodeGrantDAO.ApproveHostKey(this.NullTx(), req.NodeGrantId, req.Host, req.Fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	return this.Success()
+}
+
+// DeleteNodeGrantHostKeys 删除某个主机的公钥
+func (this *NodeGrantService) DeleteNodeGrantHostKeys(ctx context.Context, req *pb.DeleteNodeGrantHostKeysRequest) (*pb.RPCSuccess, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = models.SharedNodeGrantDAO.DeleteHostKeys(this.NullTx(), req.NodeGrantId, req.Host)
+	if err != nil {
+		return nil, err
+	}
+	return this.Success()
+}
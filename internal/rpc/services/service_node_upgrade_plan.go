@@ -0,0 +1,195 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dashenmiren/EdgeAPI/internal/db/models"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/dashenmiren/EdgeAPI/internal/installers"
+	"github.com/dashenmiren/EdgeCommon/pkg/rpc/pb"
+	"github.com/iwind/TeaGo/dbs"
+	"github.com/iwind/TeaGo/lists"
+	"github.com/iwind/TeaGo/types"
+	"sort"
+	"strings"
+)
+
+// NodeUpgradePlanService 集群节点升级计划
+type NodeUpgradePlanService struct {
+	BaseService
+}
+
+// StartNodeUpgradePlan 创建并开始执行集群升级计划
+// 计划中包含集群中所有低于当前可升级版本的节点，也可以通过 nodeIds 指定其中一部分节点
+func (this *NodeUpgradePlanService) StartNodeUpgradePlan(ctx context.Context, req *pb.StartNodeUpgradePlanRequest) (*pb.StartNodeUpgradePlanResponse, error) {
+	adminId, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var gates = &models.NodeUpgradePlanGates{}
+	if len(req.GatesJSON) > 0 {
+		err = json.Unmarshal(req.GatesJSON, gates)
+		if err != nil {
+			return nil, errors.New("decode 'gatesJSON' failed: " + err.Error())
+		}
+	}
+
+	var tx = this.NullTx()
+
+	cluster, err := models.SharedNodeClusterDAO.FindEnabledNodeCluster(tx, req.NodeClusterId)
+	if err != nil {
+		return nil, err
+	}
+	if cluster == nil {
+		return nil, errors.New("can not find cluster with id '" + types.String(req.NodeClusterId) + "'")
+	}
+	if gates.HealthCheck && !cluster.HealthCheck.IsNotNull() {
+		return nil, errors.New("health check of the cluster has not been configured")
+	}
+
+	var planNodes = []*models.NodeUpgradePlanNode{}
+	for _, deployFile := range installers.SharedDeployManager.LoadNodeFiles() {
+		nodes, err := models.SharedNodeDAO.FindAllLowerVersionNodesWithClusterId(tx, req.NodeClusterId, deployFile.OS, deployFile.Arch, deployFile.Version)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodes {
+			if !node.IsOn {
+				continue
+			}
+			if len(req.NodeIds) > 0 && !lists.ContainsInt64(req.NodeIds, int64(node.Id)) {
+				continue
+			}
+			var oldVersion = ""
+			status, err := node.DecodeStatus()
+			if err == nil && status != nil {
+				oldVersion = status.BuildVersion
+			}
+			planNodes = append(planNodes, &models.NodeUpgradePlanNode{
+				NodeId:     int64(node.Id),
+				OS:         deployFile.OS,
+				Arch:       deployFile.Arch,
+				OldVersion: oldVersion,
+				NewVersion: deployFile.Version,
+			})
+		}
+	}
+	if len(planNodes) == 0 {
+		return nil, errors.New("there are no nodes to upgrade")
+	}
+	sort.Slice(planNodes, func(i, j int) bool {
+		return planNodes[i].NodeId < planNodes[j].NodeId
+	})
+
+	planId, err := models.SharedNodeUpgradePlanDAO.CreatePlan(tx, adminId, req.NodeClusterId, req.CanaryPercent, req.BatchSize, req.WaitSeconds, gates, planNodes)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.StartNodeUpgradePlanResponse{NodeUpgradePlanId: planId}, nil
+}
+
+// PauseNodeUpgradePlan 暂停升级计划
+// 正在升级的节点会继续完成升级，之后不再开始新的批次
+func (this *NodeUpgradePlanService) PauseNodeUpgradePlan(ctx context.Context, req *pb.PauseNodeUpgradePlanRequest) (*pb.RPCSuccess, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = this.updatePlanStatus(this.NullTx(), req.NodeUpgradePlanId, models.NodeUpgradePlanStatusPaused, models.NodeUpgradePlanStatusRunning)
+	if err != nil {
+		return nil, err
+	}
+	return this.Success()
+}
+
+// ResumeNodeUpgradePlan 继续执行暂停的升级计划
+func (this *NodeUpgradePlanService) ResumeNodeUpgradePlan(ctx context.Context, req *pb.ResumeNodeUpgradePlanRequest) (*pb.RPCSuccess, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = this.updatePlanStatus(this.NullTx(), req.NodeUpgradePlanId, models.NodeUpgradePlanStatusRunning, models.NodeUpgradePlanStatusPaused)
+	if err != nil {
+		return nil, err
+	}
+	return this.Success()
+}
+
+// AbortNodeUpgradePlan 终止升级计划
+// 已经升级的节点不会回滚
+func (this *NodeUpgradePlanService) AbortNodeUpgradePlan(ctx context.Context, req *pb.AbortNodeUpgradePlanRequest) (*pb.RPCSuccess, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = this.updatePlanStatus(this.NullTx(), req.NodeUpgradePlanId, models.NodeUpgradePlanStatusAborted, models.NodeUpgradePlanStatusRunning, models.NodeUpgradePlanStatusPaused)
+	if err != nil {
+		return nil, err
+	}
+	return this.Success()
+}
+
+// FindNodeUpgradePlan 查找升级计划及其进度
+func (this *NodeUpgradePlanService) FindNodeUpgradePlan(ctx context.Context, req *pb.FindNodeUpgradePlanRequest) (*pb.FindNodeUpgradePlanResponse, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := models.SharedNodeUpgradePlanDAO.FindEnabledPlan(this.NullTx(), req.NodeUpgradePlanId)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.FindNodeUpgradePlanResponse{NodeUpgradePlan: this.convertPlanToPB(plan)}, nil
+}
+
+// FindLatestNodeUpgradePlanWithNodeClusterId 查找集群最近的升级计划
+func (this *NodeUpgradePlanService) FindLatestNodeUpgradePlanWithNodeClusterId(ctx context.Context, req *pb.FindLatestNodeUpgradePlanWithNodeClusterIdRequest) (*pb.FindLatestNodeUpgradePlanWithNodeClusterIdResponse, error) {
+	_, err := this.ValidateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := models.SharedNodeUpgradePlanDAO.FindLatestPlanWithClusterId(this.NullTx(), req.NodeClusterId)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.FindLatestNodeUpgradePlanWithNodeClusterIdResponse{NodeUpgradePlan: this.convertPlanToPB(plan)}, nil
+}
+
+// 修改计划状态
+func (this *NodeUpgradePlanService) updatePlanStatus(tx *dbs.Tx, planId int64, status string, fromStatuses ...string) error {
+	ok, err := models.SharedNodeUpgradePlanDAO.UpdatePlanStatus(tx, planId, status, "", fromStatuses...)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("upgrade plan is not " + strings.Join(fromStatuses, " or "))
+	}
+	return nil
+}
+
+func (this *NodeUpgradePlanService) convertPlanToPB(plan *models.NodeUpgradePlan) *pb.NodeUpgradePlan {
+	if plan == nil {
+		return nil
+	}
+	return &pb.NodeUpgradePlan{
+		Id:            int64(plan.Id),
+		NodeClusterId: int64(plan.ClusterId),
+		CanaryPercent: int32(plan.CanaryPercent),
+		BatchSize:     int32(plan.BatchSize),
+		WaitSeconds:   int32(plan.WaitSeconds),
+		GatesJSON:     plan.Gates,
+		NodesJSON:     plan.Nodes,
+		Status:        plan.Status,
+		Error:         plan.Error,
+		CreatedAt:     int64(plan.CreatedAt),
+		UpdatedAt:     int64(plan.UpdatedAt),
+	}
+}
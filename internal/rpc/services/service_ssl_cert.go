@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"encoding/json"
+	acmeutils "github.com/dashenmiren/EdgeAPI/internal/acme"
 	"github.com/dashenmiren/EdgeAPI/internal/db/models"
 	"github.com/dashenmiren/EdgeAPI/internal/db/models/acme"
 	"github.com/dashenmiren/EdgeAPI/internal/errors"
@@ -175,6 +176,60 @@ func (this *SSLCertService) DeleteSSLCert(ctx context.Context, req *pb.DeleteSSL
 	return this.Success()
 }
 
+// RevokeSSLCert 通过ACME服务商吊销证书，并使用新的私钥重新签发
+func (this *SSLCertService) RevokeSSLCert(ctx context.Context, req *pb.RevokeSSLCertRequest) (*pb.RevokeSSLCertResponse, error) {
+	// 校验请求
+	_, userId, err := this.ValidateAdminAndUser(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx = this.NullTx()
+
+	// 检查权限
+	if userId > 0 {
+		err := models.SharedSSLCertDAO.CheckUserCert(tx, req.SslCertId, userId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Reason < 0 || !acmeutils.IsValidRevokeReason(acmeutils.RevokeReason(req.Reason)) {
+		return nil, errors.New("invalid reason '" + types.String(req.Reason) + "'")
+	}
+
+	cert, err := models.SharedSSLCertDAO.FindEnabledSSLCert(tx, req.SslCertId)
+	if err != nil {
+		return nil, err
+	}
+	if cert == nil {
+		return nil, errors.New("can not find cert '" + types.String(req.SslCertId) + "'")
+	}
+	if cert.AcmeTaskId == 0 {
+		return nil, errors.New("only certs issued by ACME tasks can be revoked")
+	}
+
+	// 已经吊销过的证书只需要重新签发
+	if !cert.IsRevoked {
+		err = acme.SharedACMETaskDAO.RevokeACMETaskCert(tx, int64(cert.AcmeTaskId), cert.CertData, acmeutils.RevokeReason(req.Reason))
+		if err != nil {
+			return nil, errors.New("revoke cert failed: " + err.Error())
+		}
+
+		err = models.SharedSSLCertDAO.UpdateCertRevoked(tx, int64(cert.Id), uint8(req.Reason))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 重新签发，每次签发时都会生成新的私钥
+	isOk, errMsg, _ := acme.SharedACMETaskDAO.RunTask(tx, int64(cert.AcmeTaskId))
+	return &pb.RevokeSSLCertResponse{
+		IsReissued:   isOk,
+		ReissueError: errMsg,
+	}, nil
+}
+
 // CountSSLCerts 计算匹配的Cert数量
 func (this *SSLCertService) CountSSLCerts(ctx context.Context, req *pb.CountSSLCertRequest) (*pb.RPCCountResponse, error) {
 	// 校验请求
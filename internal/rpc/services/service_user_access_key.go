@@ -58,6 +58,7 @@ func (this *UserAccessKeyService) FindAllEnabledUserAccessKeys(ctx context.Conte
 			Secret:      accessKey.Secret,
 			Description: accessKey.Description,
 			AccessedAt:  int64(accessKey.AccessedAt),
+			ScopeJSON:   accessKey.Scope,
 		})
 	}
 
@@ -116,6 +117,32 @@ func (this *UserAccessKeyService) UpdateUserAccessKeyIsOn(ctx context.Context, r
 	return this.Success()
 }
 
+// UpdateUserAccessKeyScope 修改AccessKey的权限范围
+func (this *UserAccessKeyService) UpdateUserAccessKeyScope(ctx context.Context, req *pb.UpdateUserAccessKeyScopeRequest) (*pb.RPCSuccess, error) {
+	_, userId, err := this.ValidateAdminAndUser(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx = this.NullTx()
+
+	if userId > 0 {
+		ok, err := models.SharedUserAccessKeyDAO.CheckUserAccessKey(tx, 0, userId, req.UserAccessKeyId)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, this.PermissionError()
+		}
+	}
+
+	err = models.SharedUserAccessKeyDAO.UpdateAccessKeyScope(tx, req.UserAccessKeyId, req.ScopeJSON)
+	if err != nil {
+		return nil, err
+	}
+	return this.Success()
+}
+
 // CountAllEnabledUserAccessKeys 计算AccessKey数量
 func (this *UserAccessKeyService) CountAllEnabledUserAccessKeys(ctx context.Context, req *pb.CountAllEnabledUserAccessKeysRequest) (*pb.RPCCountResponse, error) {
 	_, userId, err := this.ValidateAdminAndUser(ctx, true)
@@ -0,0 +1,136 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package rpc
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var SharedStreamRegistry = NewStreamRegistry()
+
+// StreamInfo 当前连接的Stream信息
+type StreamInfo struct {
+	Id        int64
+	Method    string // 完整方法名，比如 /pb.NodeService/NodeStream
+	Role      string // 认证后的角色
+	NodeId    string // 认证后的节点唯一ID
+	NodeIntId int64  // 认证后的节点数字ID，只有边缘节点才有
+	ClusterId int64  // 节点所属集群ID，只有边缘节点才有
+	StartedAt time.Time
+
+	countRecvMessages int64
+	countSentMessages int64
+	recvBytes         int64
+	sentBytes         int64
+}
+
+// AddRecv 增加接收的消息
+func (this *StreamInfo) AddRecv(bytes int64) {
+	atomic.AddInt64(&this.countRecvMessages, 1)
+	atomic.AddInt64(&this.recvBytes, bytes)
+}
+
+// AddSent 增加发送的消息
+func (this *StreamInfo) AddSent(bytes int64) {
+	atomic.AddInt64(&this.countSentMessages, 1)
+	atomic.AddInt64(&this.sentBytes, bytes)
+}
+
+// Counters 读取消息数和字节数
+func (this *StreamInfo) Counters() (countRecvMessages int64, countSentMessages int64, recvBytes int64, sentBytes int64) {
+	return atomic.LoadInt64(&this.countRecvMessages),
+		atomic.LoadInt64(&this.countSentMessages),
+		atomic.LoadInt64(&this.recvBytes),
+		atomic.LoadInt64(&this.sentBytes)
+}
+
+// Lifetime 连接时长
+func (this *StreamInfo) Lifetime() time.Duration {
+	return time.Since(this.StartedAt)
+}
+
+// StreamRegistry 当前API节点上连接的Stream
+type StreamRegistry struct {
+	streamMap map[int64]*StreamInfo // id => *StreamInfo
+	lastId    int64
+
+	locker sync.RWMutex
+}
+
+func NewStreamRegistry() *StreamRegistry {
+	return &StreamRegistry{
+		streamMap: map[int64]*StreamInfo{},
+	}
+}
+
+// Add 添加Stream，并返回分配的ID
+func (this *StreamRegistry) Add(info *StreamInfo) int64 {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	this.lastId++
+	info.Id = this.lastId
+	if info.StartedAt.IsZero() {
+		info.StartedAt = time.Now()
+	}
+	this.streamMap[info.Id] = info
+	return info.Id
+}
+
+// Remove 删除Stream
+func (this *StreamRegistry) Remove(streamId int64) {
+	this.locker.Lock()
+	delete(this.streamMap, streamId)
+	this.locker.Unlock()
+}
+
+// Count 当前Stream数量
+func (this *StreamRegistry) Count() int {
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+	return len(this.streamMap)
+}
+
+// FindAll 列出所有Stream，按照开始时间排序
+func (this *StreamRegistry) FindAll() []*StreamInfo {
+	this.locker.RLock()
+	var result = make([]*StreamInfo, 0, len(this.streamMap))
+	for _, info := range this.streamMap {
+		result = append(result, info)
+	}
+	this.locker.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Id < result[j].Id
+	})
+	return result
+}
+
+// CountNodesWithClusters 计算每个集群中连接的节点数量
+// 同一个节点有多个Stream时只计算一次
+func (this *StreamRegistry) CountNodesWithClusters(role string) map[int64]int {
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+
+	var nodeMap = map[int64]map[int64]bool{} // clusterId => { nodeId => true }
+	for _, info := range this.streamMap {
+		if info.Role != role || info.NodeIntId <= 0 {
+			continue
+		}
+		nodeIds, ok := nodeMap[info.ClusterId]
+		if !ok {
+			nodeIds = map[int64]bool{}
+			nodeMap[info.ClusterId] = nodeIds
+		}
+		nodeIds[info.NodeIntId] = true
+	}
+
+	var result = map[int64]int{}
+	for clusterId, nodeIds := range nodeMap {
+		result[clusterId] = len(nodeIds)
+	}
+	return result
+}
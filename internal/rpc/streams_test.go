@@ -0,0 +1,37 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package rpc_test
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/rpc"
+	"testing"
+)
+
+func TestStreamRegistry_CountNodesWithClusters(t *testing.T) {
+	var registry = rpc.NewStreamRegistry()
+	var streamId1 = registry.Add(&rpc.StreamInfo{Method: "/pb.NodeService/NodeStream", Role: "node", NodeIntId: 1, ClusterId: 1})
+	registry.Add(&rpc.StreamInfo{Method: "/pb.NodeTaskService/NodeTaskStream", Role: "node", NodeIntId: 1, ClusterId: 1})
+	registry.Add(&rpc.StreamInfo{Method: "/pb.NodeService/NodeStream", Role: "node", NodeIntId: 2, ClusterId: 1})
+	registry.Add(&rpc.StreamInfo{Method: "/pb.NodeService/NodeStream", Role: "node", NodeIntId: 3, ClusterId: 2})
+	registry.Add(&rpc.StreamInfo{Method: "/pb.NSNodeService/NsNodeStream", Role: "dns", NodeIntId: 4, ClusterId: 2})
+
+	var counts = registry.CountNodesWithClusters("node")
+	t.Log(counts)
+	if counts[1] != 2 || counts[2] != 1 {
+		t.Fatal("invalid counts")
+	}
+
+	registry.Remove(streamId1)
+	t.Log(registry.Count(), registry.CountNodesWithClusters("node"))
+}
+
+func TestStreamInfo_Counters(t *testing.T) {
+	var info = &rpc.StreamInfo{}
+	info.AddRecv(100)
+	info.AddRecv(50)
+	info.AddSent(10)
+	countRecvMessages, countSentMessages, recvBytes, sentBytes := info.Counters()
+	if countRecvMessages != 2 || countSentMessages != 1 || recvBytes != 150 || sentBytes != 10 {
+		t.Fatal("invalid counters")
+	}
+}
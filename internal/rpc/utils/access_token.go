@@ -0,0 +1,61 @@
+package rpcutils
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/db/models"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"time"
+)
+
+// ValidateAccessToken 校验AccessToken，并检查权限范围
+// 校验通过后返回对应角色的 PlainContext
+func ValidateAccessToken(token string, serviceName string, methodName string, remoteIP string) (*PlainContext, error) {
+	accessToken, scope, err := findAccessToken(token, remoteIP)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.AllowMethod(serviceName, methodName) {
+		return nil, errors.New("access token is not allowed to call '" + serviceName + "/" + methodName + "'")
+	}
+	return accessTokenContext(accessToken)
+}
+
+// ValidateAccessTokenIP 校验AccessToken及其允许访问的IP，不检查可以调用的方法
+// 用于API文档等不对应具体方法的接口
+func ValidateAccessTokenIP(token string, remoteIP string) (*PlainContext, error) {
+	accessToken, _, err := findAccessToken(token, remoteIP)
+	if err != nil {
+		return nil, err
+	}
+	return accessTokenContext(accessToken)
+}
+
+// 查找有效的AccessToken，并检查是否允许从当前IP访问
+func findAccessToken(token string, remoteIP string) (*models.APIAccessToken, *models.APIAccessScope, error) {
+	accessToken, err := models.SharedAPIAccessTokenDAO.FindAccessToken(nil, token)
+	if err != nil {
+		return nil, nil, errors.New("server error: " + err.Error())
+	}
+
+	if accessToken == nil || int64(accessToken.ExpiredAt) < time.Now().Unix() {
+		return nil, nil, errors.New("invalid access token")
+	}
+
+	scope, err := accessToken.DecodeScope()
+	if err != nil {
+		return nil, nil, errors.New("decode access token scope failed: " + err.Error())
+	}
+	if !scope.AllowIP(remoteIP) {
+		return nil, nil, errors.New("access token is not allowed from ip '" + remoteIP + "'")
+	}
+	return accessToken, scope, nil
+}
+
+func accessTokenContext(accessToken *models.APIAccessToken) (*PlainContext, error) {
+	if accessToken.UserId > 0 {
+		return NewPlainContext(UserTypeUser, int64(accessToken.UserId)), nil
+	}
+	if accessToken.AdminId > 0 {
+		return NewPlainContext(UserTypeAdmin, int64(accessToken.AdminId)), nil
+	}
+	return nil, errors.New("not supported role")
+}
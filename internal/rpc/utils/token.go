@@ -0,0 +1,36 @@
+package rpcutils
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/configs"
+	teaconst "github.com/dashenmiren/EdgeAPI/internal/const"
+	"github.com/dashenmiren/EdgeAPI/internal/encrypt"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/iwind/TeaGo/maps"
+	"google.golang.org/grpc/metadata"
+)
+
+// DecodeToken 从请求元数据中解析节点令牌
+// 客户端通过 encryptmethod 元数据协商加密方法，未提供时使用旧的 teaconst.EncryptMethod
+func DecodeToken(md metadata.MD, nodeId string, secret string) (maps.Map, error) {
+	tokens := md.Get("token")
+	if len(tokens) == 0 || len(tokens[0]) == 0 {
+		return nil, errors.New("context: need 'token'")
+	}
+
+	var method = teaconst.EncryptMethod
+	methods := md.Get("encryptmethod")
+	if len(methods) > 0 && len(methods[0]) > 0 {
+		method = methods[0]
+	}
+
+	var tokenConfig *configs.TokenConfig
+	apiConfig, err := configs.SharedAPIConfig()
+	if err == nil && apiConfig != nil {
+		tokenConfig = apiConfig.Token
+	}
+
+	return encrypt.DecodeToken(method, secret, nodeId, tokens[0], &encrypt.TokenOptions{
+		ClockSkew:   tokenConfig.ClockSkewDuration(),
+		AllowLegacy: tokenConfig.AllowLegacy(),
+	})
+}
@@ -0,0 +1,63 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package rpcutils
+
+import (
+	"context"
+	"github.com/dashenmiren/EdgeAPI/internal/db/models"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/iwind/TeaGo/maps"
+	"google.golang.org/grpc/metadata"
+)
+
+type tokenIdentityKey struct{}
+
+// TokenIdentity 已校验的节点令牌信息
+type TokenIdentity struct {
+	NodeId  string   // 节点唯一ID
+	Role    string   // 令牌角色
+	Payload maps.Map // 令牌内容
+}
+
+// VerifyTokenContext 在拦截器中校验节点令牌，并将校验结果保存在返回的上下文中
+// AEAD令牌中的随机数只能使用一次，所以同一个请求中后续的 DecodeTokenContext() 会直接使用保存的结果
+func VerifyTokenContext(ctx context.Context) (context.Context, *TokenIdentity, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx, nil, errors.New("context: need 'nodeId'")
+	}
+	var nodeIds = md.Get("nodeid")
+	if len(nodeIds) == 0 || len(nodeIds[0]) == 0 {
+		return ctx, nil, errors.New("context: need 'nodeId'")
+	}
+	var nodeId = nodeIds[0]
+
+	apiToken, err := models.SharedApiTokenDAO.FindEnabledTokenWithNodeCacheable(nil, nodeId)
+	if err != nil {
+		return ctx, nil, err
+	}
+	if apiToken == nil {
+		return ctx, nil, errors.New("context: can not find api token for node '" + nodeId + "'")
+	}
+
+	payload, err := DecodeToken(md, nodeId, apiToken.Secret)
+	if err != nil {
+		return ctx, nil, err
+	}
+
+	var identity = &TokenIdentity{
+		NodeId:  nodeId,
+		Role:    apiToken.Role,
+		Payload: payload,
+	}
+	return context.WithValue(ctx, tokenIdentityKey{}, identity), identity, nil
+}
+
+// DecodeTokenContext 解析节点令牌，如果拦截器中已经校验过同一个节点的令牌，则直接使用校验结果
+func DecodeTokenContext(ctx context.Context, md metadata.MD, nodeId string, secret string) (maps.Map, error) {
+	identity, ok := ctx.Value(tokenIdentityKey{}).(*TokenIdentity)
+	if ok && identity != nil && identity.NodeId == nodeId {
+		return identity.Payload, nil
+	}
+	return DecodeToken(md, nodeId, secret)
+}
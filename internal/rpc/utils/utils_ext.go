@@ -4,15 +4,10 @@ package rpcutils
 
 import (
 	"context"
-	"encoding/base64"
-	"encoding/json"
-	teaconst "github.com/dashenmiren/EdgeAPI/internal/const"
 	"github.com/dashenmiren/EdgeAPI/internal/db/models"
-	"github.com/dashenmiren/EdgeAPI/internal/encrypt"
 	"github.com/dashenmiren/EdgeAPI/internal/errors"
 	"github.com/dashenmiren/EdgeAPI/internal/utils"
 	"github.com/iwind/TeaGo/lists"
-	"github.com/iwind/TeaGo/maps"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -77,35 +72,10 @@ func ValidateRequest(ctx context.Context, userTypes ...UserType) (userType UserT
 		return UserTypeNode, 0, 0, errors.New("context: can not find api token for node '" + nodeId + "'")
 	}
 
-	tokens := md.Get("token")
-	if len(tokens) == 0 || len(tokens[0]) == 0 {
-		return UserTypeNone, 0, 0, errors.New("context: need 'token'")
-	}
-	token := tokens[0]
-
-	data, err := base64.StdEncoding.DecodeString(token)
-	if err != nil {
-		return UserTypeNone, 0, 0, err
-	}
-
-	method, err := encrypt.NewMethodInstance(teaconst.EncryptMethod, apiToken.Secret, nodeId)
-	if err != nil {
-		utils.PrintError(err)
-		return UserTypeNone, 0, 0, err
-	}
-	data, err = method.Decrypt(data)
+	m, err := DecodeTokenContext(ctx, md, nodeId, apiToken.Secret)
 	if err != nil {
 		return UserTypeNone, 0, 0, err
 	}
-	if len(data) == 0 {
-		return UserTypeNone, 0, 0, errors.New("invalid token")
-	}
-
-	var m = maps.Map{}
-	err = json.Unmarshal(data, &m)
-	if err != nil {
-		return UserTypeNone, 0, 0, errors.New("decode token error: " + err.Error())
-	}
 
 	t := m.GetString("type")
 	if len(userTypes) > 0 && !lists.ContainsString(userTypes, t) {
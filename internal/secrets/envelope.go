@@ -0,0 +1,213 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package secrets
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"github.com/dashenmiren/EdgeAPI/internal/encrypt"
+	"strings"
+)
+
+// 信封加密格式：EDGE_SECRET:v1:<主密钥ID>:<base64(被主密钥加密的数据密钥)>:<base64(被数据密钥加密的数据)>
+// 每个值使用单独随机生成的数据密钥，轮换主密钥时只需要重新加密数据密钥
+const (
+	EnvelopePrefix = "EDGE_SECRET:"
+	envelopeV1     = "v1"
+
+	envelopeMethod = "aes-256-gcm"
+	dataKeySize    = 32
+)
+
+var ErrKeyNotFound = errors.New("secrets: master key not found")
+var ErrInvalidEnvelope = errors.New("secrets: invalid envelope")
+
+// 解析后的信封
+type envelope struct {
+	KeyId          string
+	WrappedDataKey []byte
+	Ciphertext     []byte
+}
+
+// IsEncrypted 判断是否为加密后的值
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, EnvelopePrefix)
+}
+
+// Encrypt 使用当前主密钥加密数据
+// context 为数据的用途，比如 "nodeGrant.password"，解密时必须使用相同的 context，防止密文被挪用到其他字段
+func (this *Keyring) Encrypt(context string, plaintext []byte) (string, error) {
+	if !this.IsConfigured() {
+		return "", ErrKeyNotFound
+	}
+
+	var dataKey = make([]byte, dataKeySize)
+	_, err := rand.Read(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	dataMethod, err := encrypt.NewMethodInstance(envelopeMethod, string(dataKey), dataAAD(context))
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := dataMethod.Encrypt(plaintext)
+	if err != nil {
+		return "", err
+	}
+
+	wrappedDataKey, err := this.wrapDataKey(this.currentKey, dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	return (&envelope{
+		KeyId:          this.currentKey.Id,
+		WrappedDataKey: wrappedDataKey,
+		Ciphertext:     ciphertext,
+	}).encode(), nil
+}
+
+// Decrypt 解密数据
+func (this *Keyring) Decrypt(context string, value string) ([]byte, error) {
+	env, err := decodeEnvelope(value)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := this.unwrapDataKey(env)
+	if err != nil {
+		return nil, err
+	}
+
+	dataMethod, err := encrypt.NewMethodInstance(envelopeMethod, string(dataKey), dataAAD(context))
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := dataMethod.Decrypt(env.Ciphertext)
+	if err != nil {
+		return nil, errors.New("secrets: decrypt data failed: " + err.Error())
+	}
+	if plaintext == nil {
+		plaintext = []byte{}
+	}
+	return plaintext, nil
+}
+
+// Rewrap 使用当前主密钥重新加密数据密钥，数据本身的密文不变
+// 如果已经是当前主密钥加密的，则 changed 为false
+func (this *Keyring) Rewrap(value string) (newValue string, changed bool, err error) {
+	if !this.IsConfigured() {
+		return "", false, ErrKeyNotFound
+	}
+
+	env, err := decodeEnvelope(value)
+	if err != nil {
+		return "", false, err
+	}
+	if env.KeyId == this.currentKey.Id {
+		return value, false, nil
+	}
+
+	dataKey, err := this.unwrapDataKey(env)
+	if err != nil {
+		return "", false, err
+	}
+	env.WrappedDataKey, err = this.wrapDataKey(this.currentKey, dataKey)
+	if err != nil {
+		return "", false, err
+	}
+	env.KeyId = this.currentKey.Id
+	return env.encode(), true, nil
+}
+
+// Reseal 将值转换为当前主密钥下的加密格式
+// 明文值会被加密；已加密的值只在 rotate 为 true 时重新加密数据密钥
+func (this *Keyring) Reseal(context string, value string, rotate bool) (newValue string, changed bool, err error) {
+	if len(value) == 0 {
+		return value, false, nil
+	}
+	if !IsEncrypted(value) {
+		newValue, err = this.Encrypt(context, []byte(value))
+		if err != nil {
+			return "", false, err
+		}
+		return newValue, true, nil
+	}
+	if !rotate {
+		return value, false, nil
+	}
+	return this.Rewrap(value)
+}
+
+// KeyIdOf 读取加密值使用的主密钥ID
+func KeyIdOf(value string) (string, error) {
+	env, err := decodeEnvelope(value)
+	if err != nil {
+		return "", err
+	}
+	return env.KeyId, nil
+}
+
+func (this *Keyring) wrapDataKey(masterKey *MasterKey, dataKey []byte) ([]byte, error) {
+	method, err := encrypt.NewMethodInstance(envelopeMethod, string(masterKey.Key), keyAAD(masterKey.Id))
+	if err != nil {
+		return nil, err
+	}
+	return method.Encrypt(dataKey)
+}
+
+func (this *Keyring) unwrapDataKey(env *envelope) ([]byte, error) {
+	var masterKey = this.lookupKey(env.KeyId)
+	if masterKey == nil {
+		return nil, errors.New(ErrKeyNotFound.Error() + ": '" + env.KeyId + "'")
+	}
+	method, err := encrypt.NewMethodInstance(envelopeMethod, string(masterKey.Key), keyAAD(masterKey.Id))
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := method.Decrypt(env.WrappedDataKey)
+	if err != nil {
+		return nil, errors.New("secrets: unwrap data key failed: " + err.Error())
+	}
+	if len(dataKey) != dataKeySize {
+		return nil, ErrInvalidEnvelope
+	}
+	return dataKey, nil
+}
+
+func (this *envelope) encode() string {
+	return EnvelopePrefix + envelopeV1 + ":" + this.KeyId + ":" + base64.RawStdEncoding.EncodeToString(this.WrappedDataKey) + ":" + base64.RawStdEncoding.EncodeToString(this.Ciphertext)
+}
+
+func decodeEnvelope(value string) (*envelope, error) {
+	if !IsEncrypted(value) {
+		return nil, ErrInvalidEnvelope
+	}
+	var pieces = strings.Split(value[len(EnvelopePrefix):], ":")
+	if len(pieces) != 4 || pieces[0] != envelopeV1 || len(pieces[1]) == 0 {
+		return nil, ErrInvalidEnvelope
+	}
+	wrappedDataKey, err := base64.RawStdEncoding.DecodeString(pieces[2])
+	if err != nil {
+		return nil, ErrInvalidEnvelope
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(pieces[3])
+	if err != nil {
+		return nil, ErrInvalidEnvelope
+	}
+	return &envelope{
+		KeyId:          pieces[1],
+		WrappedDataKey: wrappedDataKey,
+		Ciphertext:     ciphertext,
+	}, nil
+}
+
+func keyAAD(keyId string) string {
+	return EnvelopePrefix + envelopeV1 + ":key:" + keyId
+}
+
+func dataAAD(context string) string {
+	return EnvelopePrefix + envelopeV1 + ":data:" + context
+}
@@ -0,0 +1,170 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	EnvKey      = "EDGE_API_SECRETS_KEY" // 主密钥环境变量，多个密钥用逗号分隔
+	KeyFileName = "secrets.key"          // 主密钥文件，位于 configs/ 目录下，每行一个密钥
+
+	MasterKeySize = 32
+)
+
+var keyIdReg = regexp.MustCompile(`^[\w-]{1,32}$`)
+
+// MasterKey 主密钥
+type MasterKey struct {
+	Id  string
+	Key []byte
+}
+
+// Keyring 主密钥集合
+// 第一个密钥为当前密钥，用于加密新的数据；其余密钥只用于解密轮换之前的数据
+type Keyring struct {
+	keys       []*MasterKey
+	keyMap     map[string]*MasterKey // id => key
+	currentKey *MasterKey
+}
+
+// NewKeyring 构造主密钥集合
+func NewKeyring(keys []*MasterKey) (*Keyring, error) {
+	var keyring = &Keyring{
+		keyMap: map[string]*MasterKey{},
+	}
+	for _, key := range keys {
+		if !keyIdReg.MatchString(key.Id) {
+			return nil, errors.New("invalid key id '" + key.Id + "'")
+		}
+		if len(key.Key) != MasterKeySize {
+			return nil, errors.New("key '" + key.Id + "' should be 32 bytes")
+		}
+		_, ok := keyring.keyMap[key.Id]
+		if ok {
+			return nil, errors.New("duplicate key id '" + key.Id + "'")
+		}
+		keyring.keys = append(keyring.keys, key)
+		keyring.keyMap[key.Id] = key
+	}
+	if len(keyring.keys) > 0 {
+		keyring.currentKey = keyring.keys[0]
+	}
+	return keyring, nil
+}
+
+// ParseKeyring 从文本中解析主密钥
+// 每个密钥格式为 id:base64(32字节)，可以用换行或者逗号分隔，以#开头的行为注释
+func ParseKeyring(data []byte) (*Keyring, error) {
+	var keys = []*MasterKey{}
+	var scanner = bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var line = strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, piece := range strings.Split(line, ",") {
+			piece = strings.TrimSpace(piece)
+			if len(piece) == 0 {
+				continue
+			}
+			keyId, keyString, found := strings.Cut(piece, ":")
+			if !found {
+				return nil, errors.New("invalid key format, expected 'id:base64Key'")
+			}
+			key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(keyString))
+			if err != nil {
+				return nil, errors.New("decode key '" + keyId + "' failed: " + err.Error())
+			}
+			keys = append(keys, &MasterKey{
+				Id:  strings.TrimSpace(keyId),
+				Key: key,
+			})
+		}
+	}
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyring(keys)
+}
+
+// LoadKeyring 加载主密钥，环境变量优先于密钥文件
+// 两者都没有设置时返回空的密钥集合
+func LoadKeyring(keyFile string) (*Keyring, error) {
+	var envValue = os.Getenv(EnvKey)
+	if len(envValue) > 0 {
+		keyring, err := ParseKeyring([]byte(envValue))
+		if err != nil {
+			return nil, errors.New("load keys from '" + EnvKey + "' failed: " + err.Error())
+		}
+		return keyring, nil
+	}
+
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NewKeyring(nil)
+		}
+		return nil, err
+	}
+	keyring, err := ParseKeyring(data)
+	if err != nil {
+		return nil, errors.New("load keys from '" + keyFile + "' failed: " + err.Error())
+	}
+	return keyring, nil
+}
+
+// GenerateKey 生成新的主密钥，返回可以直接写入密钥文件的格式
+func GenerateKey(keyId string) (string, error) {
+	if !keyIdReg.MatchString(keyId) {
+		return "", errors.New("invalid key id '" + keyId + "'")
+	}
+	var key = make([]byte, MasterKeySize)
+	_, err := rand.Read(key)
+	if err != nil {
+		return "", err
+	}
+	return keyId + ":" + base64.StdEncoding.EncodeToString(key), nil
+}
+
+// IsConfigured 是否已经设置主密钥
+func (this *Keyring) IsConfigured() bool {
+	return this != nil && this.currentKey != nil
+}
+
+// CurrentKeyId 当前密钥ID
+func (this *Keyring) CurrentKeyId() string {
+	if !this.IsConfigured() {
+		return ""
+	}
+	return this.currentKey.Id
+}
+
+// KeyIds 所有密钥ID
+func (this *Keyring) KeyIds() []string {
+	var result = []string{}
+	if this == nil {
+		return result
+	}
+	for _, key := range this.keys {
+		result = append(result, key.Id)
+	}
+	return result
+}
+
+// 根据ID查找密钥
+func (this *Keyring) lookupKey(keyId string) *MasterKey {
+	if this == nil {
+		return nil
+	}
+	return this.keyMap[keyId]
+}
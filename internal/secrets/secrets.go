@@ -0,0 +1,77 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package secrets
+
+import (
+	"github.com/iwind/TeaGo/Tea"
+	"github.com/iwind/TeaGo/logs"
+	"sync"
+)
+
+var sharedKeyring *Keyring
+var sharedKeyringErr error
+var sharedKeyringLoaded bool
+var sharedLocker = &sync.RWMutex{}
+
+// SharedKeyring 读取当前使用的主密钥集合，第一次调用时从环境变量或密钥文件中加载
+func SharedKeyring() (*Keyring, error) {
+	sharedLocker.RLock()
+	if sharedKeyringLoaded {
+		defer sharedLocker.RUnlock()
+		return sharedKeyring, sharedKeyringErr
+	}
+	sharedLocker.RUnlock()
+
+	return ReloadSharedKeyring()
+}
+
+// ReloadSharedKeyring 重新加载主密钥，用于在轮换前加入新的密钥
+func ReloadSharedKeyring() (*Keyring, error) {
+	keyring, err := LoadKeyring(Tea.ConfigFile(KeyFileName))
+
+	sharedLocker.Lock()
+	defer sharedLocker.Unlock()
+
+	if err != nil {
+		logs.Println("[SECRETS]" + err.Error())
+	} else if !keyring.IsConfigured() {
+		logs.Println("[SECRETS]no master key found in '" + EnvKey + "' or 'configs/" + KeyFileName + "', secrets will be stored without encryption")
+	}
+
+	sharedKeyring = keyring
+	sharedKeyringErr = err
+	sharedKeyringLoaded = true
+	return keyring, err
+}
+
+// EncryptString 加密字符串
+// 没有设置主密钥时保持原样，以兼容尚未设置主密钥的系统
+func EncryptString(context string, value string) (string, error) {
+	if len(value) == 0 || IsEncrypted(value) {
+		return value, nil
+	}
+	keyring, err := SharedKeyring()
+	if err != nil {
+		return "", err
+	}
+	if !keyring.IsConfigured() {
+		return value, nil
+	}
+	return keyring.Encrypt(context, []byte(value))
+}
+
+// DecryptString 解密字符串，非加密的值原样返回
+func DecryptString(context string, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	keyring, err := SharedKeyring()
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := keyring.Decrypt(context, value)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
@@ -2,6 +2,7 @@ package tasks
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/dashenmiren/EdgeAPI/internal/db/models"
 	dnsmodels "github.com/dashenmiren/EdgeAPI/internal/db/models/dns"
 	"github.com/dashenmiren/EdgeAPI/internal/dnsclients"
@@ -46,7 +47,7 @@ func (this *DNSTaskExecutor) Start() {
 			time.Sleep(3 * time.Second) // 人为延长N秒，等待可能的几个任务合并
 		}
 
-		err := this.Loop()
+		err := this.runLoop("DNSTaskExecutor", this.Loop)
 		if err != nil {
 			this.logErr("DNSTaskExecutor", err.Error())
 		}
@@ -54,20 +55,26 @@ func (this *DNSTaskExecutor) Start() {
 }
 
 func (this *DNSTaskExecutor) Loop() error {
-	if !this.IsPrimaryNode() {
+	primaryToken, ok := this.PrimaryToken()
+	if !ok {
 		return nil
 	}
 
-	return this.loop()
+	return this.loop(primaryToken)
 }
 
-func (this *DNSTaskExecutor) loop() error {
+func (this *DNSTaskExecutor) loop(primaryToken int64) error {
 	tasks, err := dnsmodels.SharedDNSTaskDAO.FindAllDoingTasks(nil)
 	if err != nil {
 		return err
 	}
 
 	for _, task := range tasks {
+		// 主节点已切换，剩余的任务由新的主节点执行
+		if !this.IsPrimaryToken(primaryToken) {
+			return nil
+		}
+
 		var taskId = int64(task.Id)
 		var taskVersion = int64(task.Version)
 		switch task.Type {
@@ -369,13 +376,15 @@ func (this *DNSTaskExecutor) doCluster(taskId int64, taskVersion int64, clusterI
 		}
 	}
 
+	// 需要修改的记录，最后统一提交
+	var changes = &dnsclients.RecordChanges{}
+
 	// 当前的节点记录
 	var newRecordKeys = []string{}
 	nodes, err := models.SharedNodeDAO.FindAllEnabledNodesDNSWithClusterId(tx, clusterId, true, dnsConfig != nil && dnsConfig.IncludingLnNodes, true)
 	if err != nil {
 		return err
 	}
-	var isChanged = false
 	var addingNodeRecordKeysMap = map[string]bool{} // clusterDnsName_type_ip_route
 	for _, node := range nodes {
 		shouldSkip, shouldOverwrite, ipAddressesStrings, err := models.SharedNodeDAO.CheckNodeIPAddresses(tx, node)
@@ -445,7 +454,7 @@ func (this *DNSTaskExecutor) doCluster(taskId int64, taskVersion int64, clusterI
 				}
 				addingNodeRecordKeysMap[fullKey] = true
 
-				err = manager.AddRecord(domain, &dnstypes.Record{
+				changes.Adds = append(changes.Adds, &dnstypes.Record{
 					Id:    "",
 					Name:  clusterDNSName,
 					Type:  recordType,
@@ -453,10 +462,6 @@ func (this *DNSTaskExecutor) doCluster(taskId int64, taskVersion int64, clusterI
 					Route: route,
 					TTL:   ttl,
 				})
-				if err != nil {
-					return err
-				}
-				isChanged = true
 				newRecordKeys = append(newRecordKeys, key)
 			}
 		}
@@ -465,11 +470,7 @@ func (this *DNSTaskExecutor) doCluster(taskId int64, taskVersion int64, clusterI
 	// 删除多余的节点解析记录
 	for key, record := range oldRecordsMap {
 		if !lists.ContainsString(newRecordKeys, key) {
-			isChanged = true
-			err = manager.DeleteRecord(domain, record)
-			if err != nil {
-				return err
-			}
+			changes.Deletes = append(changes.Deletes, record)
 		}
 	}
 
@@ -498,8 +499,7 @@ func (this *DNSTaskExecutor) doCluster(taskId int64, taskVersion int64, clusterI
 			serverDNSNames = append(serverDNSNames, dnsName)
 			_, ok := serverRecordsMap[dnsName]
 			if !ok {
-				isChanged = true
-				err = manager.AddRecord(domain, &dnstypes.Record{
+				changes.Adds = append(changes.Adds, &dnstypes.Record{
 					Id:    "",
 					Name:  dnsName,
 					Type:  dnstypes.RecordTypeCNAME,
@@ -507,9 +507,6 @@ func (this *DNSTaskExecutor) doCluster(taskId int64, taskVersion int64, clusterI
 					Route: "", // 注意这里为空，需要在执行过程中获取默认值
 					TTL:   ttl,
 				})
-				if err != nil {
-					return err
-				}
 			}
 		}
 
@@ -527,8 +524,7 @@ func (this *DNSTaskExecutor) doCluster(taskId int64, taskVersion int64, clusterI
 			serverDNSNames = append(serverDNSNames, cnameRecord)
 			_, ok := serverRecordsMap[cnameRecord]
 			if !ok {
-				isChanged = true
-				err = manager.AddRecord(domain, &dnstypes.Record{
+				changes.Adds = append(changes.Adds, &dnstypes.Record{
 					Id:    "",
 					Name:  cnameRecord,
 					Type:  dnstypes.RecordTypeCNAME,
@@ -536,30 +532,46 @@ func (this *DNSTaskExecutor) doCluster(taskId int64, taskVersion int64, clusterI
 					Route: "", // 注意这里为空，需要在执行过程中获取默认值
 					TTL:   ttl,
 				})
-				if err != nil {
-					return err
-				}
 			}
 		}
 
 		// 多余的域名
 		for _, record := range serverRecords {
 			if !lists.ContainsString(serverDNSNames, record.Name) {
-				isChanged = true
-				err = manager.DeleteRecord(domain, record)
-				if err != nil {
-					return err
-				}
+				changes.Deletes = append(changes.Deletes, record)
 			}
 		}
 	}
 
+	if changes.IsEmpty() {
+		isOk = true
+		return nil
+	}
+
+	// 提交修改
+	failures, err := dnsclients.ApplyRecordChanges(manager, domain, changes)
+	if err != nil {
+		return err
+	}
+
 	// 通知更新域名
-	if isChanged {
-		err = dnsmodels.SharedDNSTaskDAO.CreateDomainTask(tx, domainId, dnsmodels.DNSTaskTypeDomainChange)
+	// 部分记录失败时，其余的记录已经生效，所以仍然需要更新
+	err = dnsmodels.SharedDNSTaskDAO.CreateDomainTask(tx, domainId, dnsmodels.DNSTaskTypeDomainChange)
+	if err != nil {
+		return err
+	}
+
+	// 记录失败的记录
+	if len(failures) > 0 {
+		failuresJSON, err := json.Marshal(failures)
+		if err != nil {
+			return err
+		}
+		err = dnsmodels.SharedDNSTaskDAO.UpdateDNSTaskFailedRecords(tx, taskId, failuresJSON)
 		if err != nil {
 			return err
 		}
+		return fmt.Errorf("%d/%d records failed, first error: %s", len(failures), changes.Count(), failures[0].Error)
 	}
 
 	isOk = true
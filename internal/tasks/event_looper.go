@@ -30,7 +30,7 @@ func NewEventLooper(duration time.Duration) *EventLooper {
 
 func (this *EventLooper) Start() {
 	for range this.ticker.C {
-		err := this.Loop()
+		err := this.runLoop("EventLooper", this.Loop)
 		if err != nil {
 			this.logErr("EventLooper", err.Error())
 		}
@@ -38,7 +38,8 @@ func (this *EventLooper) Start() {
 }
 
 func (this *EventLooper) Loop() error {
-	if !this.IsPrimaryNode() {
+	primaryToken, ok := this.PrimaryToken()
+	if !ok {
 		return nil
 	}
 
@@ -47,6 +48,11 @@ func (this *EventLooper) Loop() error {
 		return err
 	}
 	for _, eventOne := range events {
+		// 主节点已切换，剩余的事件由新的主节点执行
+		if !this.IsPrimaryToken(primaryToken) {
+			return nil
+		}
+
 		event, err := eventOne.DecodeEvent()
 		if err != nil {
 			this.logErr("EventLooper", err.Error())
@@ -73,7 +73,7 @@ func (this *HealthCheckClusterTask) Run() {
 	var ticker = utils.NewTicker(duration)
 	goman.New(func() {
 		for ticker.Wait() {
-			err := this.Loop()
+			err := this.runLoop("HealthCheckClusterTask", this.Loop)
 			if err != nil {
 				this.logErr("HealthCheckClusterTask", err.Error())
 			}
@@ -94,17 +94,24 @@ func (this *HealthCheckClusterTask) Stop() {
 // Loop 单个循环任务
 func (this *HealthCheckClusterTask) Loop() error {
 	// 检查是否为主节点
-	if !this.IsPrimaryNode() {
+	primaryToken, ok := this.PrimaryToken()
+	if !ok {
 		return nil
 	}
 
 	// 开始运行
 	var executor = NewHealthCheckExecutor(this.clusterId)
+	executor.SetPrimaryToken(primaryToken)
 	results, err := executor.Run()
 	if err != nil {
 		return err
 	}
 
+	// 主节点已切换，由新的主节点发送通知
+	if !this.IsPrimaryToken(primaryToken) {
+		return nil
+	}
+
 	var failedResults = []maps.Map{}
 	for _, result := range results {
 		if !result.IsOk {
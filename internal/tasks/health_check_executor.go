@@ -28,13 +28,20 @@ import (
 type HealthCheckExecutor struct {
 	BaseTask
 
-	clusterId int64
+	clusterId    int64
+	primaryToken int64 // 主节点租约的防护令牌，为0表示不检查
 }
 
 func NewHealthCheckExecutor(clusterId int64) *HealthCheckExecutor {
 	return &HealthCheckExecutor{clusterId: clusterId}
 }
 
+// SetPrimaryToken 设置主节点租约的防护令牌
+// 设置后如果在检查过程中发生主节点切换，则不再修改节点状态和发送通知
+func (this *HealthCheckExecutor) SetPrimaryToken(primaryToken int64) {
+	this.primaryToken = primaryToken
+}
+
 func (this *HealthCheckExecutor) Run() ([]*HealthCheckResult, error) {
 	cluster, err := models.NewNodeClusterDAO().FindEnabledNodeCluster(nil, this.clusterId)
 	if err != nil {
@@ -165,6 +172,11 @@ func (this *HealthCheckExecutor) runNode(healthCheckConfig *serverconfigs.Health
 		}
 	}
 
+	// 主节点已切换，检查结果由新的主节点处理
+	if this.primaryToken > 0 && !this.IsPrimaryToken(this.primaryToken) {
+		return
+	}
+
 	// 修改节点IP状态
 	if teaconst.IsPlus {
 		isChanged, err := models.SharedNodeIPAddressDAO.UpdateAddressHealthCount(nil, result.NodeAddrId, result.IsOk, healthCheckConfig.CountUp, healthCheckConfig.CountDown, healthCheckConfig.AutoDown)
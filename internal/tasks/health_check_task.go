@@ -37,13 +37,13 @@ func NewHealthCheckTask(duration time.Duration) *HealthCheckTask {
 }
 
 func (this *HealthCheckTask) Start() {
-	err := this.Loop()
+	err := this.runLoop("HealthCheckTask", this.Loop)
 	if err != nil {
 		this.logErr("HealthCheckTask", err.Error())
 	}
 
 	for range this.ticker.C {
-		err := this.Loop()
+		err := this.runLoop("HealthCheckTask", this.Loop)
 		if err != nil {
 			this.logErr("HealthCheckTask", err.Error())
 		}
@@ -0,0 +1,66 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package tasks
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/db/models"
+	"github.com/dashenmiren/EdgeAPI/internal/goman"
+	"github.com/iwind/TeaGo/dbs"
+	"github.com/iwind/TeaGo/types"
+	"time"
+)
+
+func init() {
+	dbs.OnReadyDone(func() {
+		goman.New(func() {
+			NewIPListFeedTask(1 * time.Minute).Start()
+		})
+	})
+}
+
+// IPListFeedTask 定期同步IP名单订阅
+type IPListFeedTask struct {
+	BaseTask
+
+	ticker *time.Ticker
+}
+
+func NewIPListFeedTask(duration time.Duration) *IPListFeedTask {
+	return &IPListFeedTask{
+		ticker: time.NewTicker(duration),
+	}
+}
+
+func (this *IPListFeedTask) Start() {
+	for range this.ticker.C {
+		err := this.runLoop("IPListFeedTask", this.Loop)
+		if err != nil {
+			this.logErr("IPListFeedTask", err.Error())
+		}
+	}
+}
+
+func (this *IPListFeedTask) Loop() error {
+	// 检查是否为主节点
+	primaryToken, ok := this.PrimaryToken()
+	if !ok {
+		return nil
+	}
+
+	feedIds, err := models.SharedIPListFeedDAO.FindAllDueFeedIds(nil)
+	if err != nil {
+		return err
+	}
+	for _, feedId := range feedIds {
+		// 同步过程中发生主节点切换时停止
+		if !this.IsPrimaryToken(primaryToken) {
+			return nil
+		}
+
+		_, err = models.SharedIPListFeedDAO.SyncFeed(nil, feedId)
+		if err != nil {
+			this.logErr("IPListFeedTask", "feed '"+types.String(feedId)+"': "+err.Error())
+		}
+	}
+	return nil
+}
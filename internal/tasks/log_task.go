@@ -86,7 +86,8 @@ func (this *LogTask) RunMonitor() {
 
 func (this *LogTask) LoopMonitor() error {
 	// 检查是否为主节点
-	if !this.IsPrimaryNode() {
+	primaryToken, ok := this.PrimaryToken()
+	if !ok {
 		return nil
 	}
 
@@ -112,7 +113,8 @@ func (this *LogTask) LoopMonitor() error {
 			if err != nil {
 				return err
 			}
-			if sumBytes > capacityBytes {
+			// 统计用量的过程中主节点可能已切换
+			if sumBytes > capacityBytes && this.IsPrimaryToken(primaryToken) {
 				err := models.SharedMessageDAO.CreateMessage(nil, 0, 0, models.MessageTypeLogCapacityOverflow, models.MessageLevelError, "日志用量已经超出最大限制", "日志用量已经超出最大限制，当前的用量为"+this.formatBytes(sumBytes)+"，而设置的最大容量为"+this.formatBytes(capacityBytes)+"。", nil)
 				if err != nil {
 					return err
@@ -32,7 +32,7 @@ func NewMessageTask(duration time.Duration) *MessageTask {
 // Start 开始运行
 func (this *MessageTask) Start() {
 	for range this.ticker.C {
-		err := this.Loop()
+		err := this.runLoop("MessageTask", this.Loop)
 		if err != nil {
 			this.logErr("MessageTask", err.Error())
 		}
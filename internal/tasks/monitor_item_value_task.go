@@ -39,7 +39,7 @@ func NewMonitorItemValueTask(duration time.Duration) *MonitorItemValueTask {
 
 func (this *MonitorItemValueTask) Start() {
 	for range this.ticker.C {
-		err := this.Loop()
+		err := this.runLoop("MonitorItemValueTask", this.Loop)
 		if err != nil {
 			this.logErr("MonitorItemValueTask", err.Error())
 		}
@@ -30,7 +30,7 @@ func NewNodeLogCleanerTask(duration time.Duration) *NodeLogCleanerTask {
 
 func (this *NodeLogCleanerTask) Start() {
 	for range this.ticker.C {
-		err := this.Loop()
+		err := this.runLoop("NodeLogCleanerTask", this.Loop)
 		if err != nil {
 			this.logErr("NodeLogCleanerTask", err.Error())
 		}
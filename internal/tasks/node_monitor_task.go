@@ -48,7 +48,7 @@ func NewNodeMonitorTask(duration time.Duration) *NodeMonitorTask {
 
 func (this *NodeMonitorTask) Start() {
 	for range this.ticker.C {
-		err := this.Loop()
+		err := this.runLoop("NodeMonitorTask", this.Loop)
 		if err != nil {
 			this.logErr("NodeMonitorTask", err.Error())
 		}
@@ -57,7 +57,8 @@ func (this *NodeMonitorTask) Start() {
 
 func (this *NodeMonitorTask) Loop() error {
 	// 检查是否为主节点
-	if !this.IsPrimaryNode() {
+	primaryToken, ok := this.PrimaryToken()
+	if !ok {
 		return nil
 	}
 
@@ -66,7 +67,12 @@ func (this *NodeMonitorTask) Loop() error {
 		return err
 	}
 	for _, cluster := range clusters {
-		err := this.MonitorCluster(cluster)
+		// 主节点已切换，剩余的集群由新的主节点检查
+		if !this.IsPrimaryToken(primaryToken) {
+			return nil
+		}
+
+		err := this.MonitorCluster(cluster, primaryToken)
 		if err != nil {
 			return err
 		}
@@ -75,7 +81,9 @@ func (this *NodeMonitorTask) Loop() error {
 	return nil
 }
 
-func (this *NodeMonitorTask) MonitorCluster(cluster *models.NodeCluster) error {
+// MonitorCluster 检查集群中的离线节点
+// primaryToken 为开始执行时主节点租约的防护令牌
+func (this *NodeMonitorTask) MonitorCluster(cluster *models.NodeCluster, primaryToken int64) error {
 	var clusterId = int64(cluster.Id)
 
 	// 检查离线节点
@@ -85,9 +93,14 @@ func (this *NodeMonitorTask) MonitorCluster(cluster *models.NodeCluster) error {
 	}
 
 	// 尝试自动远程启动
-	if cluster.AutoRemoteStart {
+	// 在数据库中确认仍然持有租约，避免和新的主节点同时远程启动节点
+	if cluster.AutoRemoteStart && len(inactiveNodes) > 0 && this.CheckPrimaryToken(primaryToken) {
 		var nodeQueue = installers.NewNodeQueue()
 		for _, node := range inactiveNodes {
+			if !this.IsPrimaryToken(primaryToken) {
+				return nil
+			}
+
 			var nodeId = int64(node.Id)
 			tryInfo, ok := this.recoverMap[nodeId]
 			if !ok {
@@ -137,6 +150,10 @@ func (this *NodeMonitorTask) MonitorCluster(cluster *models.NodeCluster) error {
 		var nodeId = types.Int64(pieces[1])
 		node, ok := nodeMap[nodeId]
 		if ok {
+			if !this.IsPrimaryToken(primaryToken) {
+				return nil
+			}
+
 			// 连续 N 次离线发送通知
 			// 同时也要确保两次发送通知的时间不会过近
 			if count >= maxInactiveTries && time.Now().Unix()-this.notifiedMap[nodeId] > 3600 {
@@ -25,7 +25,7 @@ func TestNodeMonitorTask_Monitor(t *testing.T) {
 	for i := 0; i < 5; i++ {
 		err := task.MonitorCluster(&models.NodeCluster{
 			Id: 42,
-		})
+		}, 0)
 		if err != nil {
 			t.Fatal(err)
 		}
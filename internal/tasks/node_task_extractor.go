@@ -31,7 +31,7 @@ func NewNodeTaskExtractor(duration time.Duration) *NodeTaskExtractor {
 
 func (this *NodeTaskExtractor) Start() {
 	for range this.ticker.C {
-		err := this.Loop()
+		err := this.runLoop("NodeTaskExtractor", this.Loop)
 		if err != nil {
 			this.logErr("NodeTaskExtractor", err.Error())
 		}
@@ -40,13 +40,19 @@ func (this *NodeTaskExtractor) Start() {
 
 func (this *NodeTaskExtractor) Loop() error {
 	// 检查是否为主节点
-	if !this.IsPrimaryNode() {
+	primaryToken, ok := this.PrimaryToken()
+	if !ok {
 		return nil
 	}
 
 	// 这里不解锁，是为了让任务N秒钟之内只运行一次
 
 	for _, role := range []string{nodeconfigs.NodeRoleNode, nodeconfigs.NodeRoleDNS} {
+		// 主节点已切换，剩余的任务由新的主节点提取
+		if !this.IsPrimaryToken(primaryToken) {
+			return nil
+		}
+
 		err := models.SharedNodeTaskDAO.ExtractAllClusterTasks(nil, role)
 		if err != nil {
 			return err
@@ -0,0 +1,300 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package tasks
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/db/models"
+	"github.com/dashenmiren/EdgeAPI/internal/errors"
+	"github.com/dashenmiren/EdgeAPI/internal/installers"
+	"github.com/iwind/TeaGo/dbs"
+	"github.com/iwind/TeaGo/types"
+	"sync"
+	"time"
+)
+
+const (
+	nodeUpgradeGateTimeout  = 2 * time.Minute  // 健康检查未通过时最多重试的时间
+	nodeUpgradeGateInterval = 10 * time.Second // 健康检查重试间隔
+	nodeUpgradeWaitInterval = 5 * time.Second  // 等待期间检查计划状态的间隔
+)
+
+// NodeUpgradePlanExecutor 升级计划执行器
+// 按批次升级节点，每批升级后等待一段时间并检查节点状态，检查失败时停止计划并回滚当前批次
+type NodeUpgradePlanExecutor struct {
+	BaseTask
+
+	planId       int64
+	primaryToken int64 // 启动执行器时主节点租约的防护令牌
+}
+
+func NewNodeUpgradePlanExecutor(planId int64, primaryToken int64) *NodeUpgradePlanExecutor {
+	return &NodeUpgradePlanExecutor{
+		planId:       planId,
+		primaryToken: primaryToken,
+	}
+}
+
+// Run 执行升级计划，直到计划完成、暂停、终止或者失败
+// 进度随时保存在数据库中，API节点重启后可以继续执行
+func (this *NodeUpgradePlanExecutor) Run() error {
+	var tx *dbs.Tx
+	for {
+		// 主节点变化后由新的主节点继续执行
+		if !this.IsPrimaryToken(this.primaryToken) {
+			return nil
+		}
+
+		plan, err := models.SharedNodeUpgradePlanDAO.FindEnabledPlan(tx, this.planId)
+		if err != nil {
+			return err
+		}
+		if plan == nil || plan.Status != models.NodeUpgradePlanStatusRunning {
+			return nil
+		}
+
+		nodes, err := plan.DecodeNodes()
+		if err != nil {
+			return err
+		}
+
+		// 下一个未完成的批次
+		var batch = -1
+		for _, node := range nodes {
+			if node.Status != models.NodeUpgradePlanNodeStatusOk && (batch < 0 || node.Batch < batch) {
+				batch = node.Batch
+			}
+		}
+		if batch < 0 {
+			_, err = models.SharedNodeUpgradePlanDAO.UpdatePlanStatus(tx, this.planId, models.NodeUpgradePlanStatusFinished, "", models.NodeUpgradePlanStatusRunning)
+			return err
+		}
+
+		var batchNodes = []*models.NodeUpgradePlanNode{}
+		for _, node := range nodes {
+			if node.Batch == batch {
+				batchNodes = append(batchNodes, node)
+			}
+		}
+		err = this.runBatch(plan, nodes, batchNodes)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// 执行单个批次
+func (this *NodeUpgradePlanExecutor) runBatch(plan *models.NodeUpgradePlan, nodes []*models.NodeUpgradePlanNode, batchNodes []*models.NodeUpgradePlanNode) error {
+	var tx *dbs.Tx
+
+	// 升级
+	var upgradingNodes = []*models.NodeUpgradePlanNode{}
+	for _, node := range batchNodes {
+		if node.Status == models.NodeUpgradePlanNodeStatusPending || node.Status == models.NodeUpgradePlanNodeStatusUpgrading {
+			this.updateNode(node, models.NodeUpgradePlanNodeStatusUpgrading, "")
+			upgradingNodes = append(upgradingNodes, node)
+		}
+	}
+	if len(upgradingNodes) > 0 {
+		err := models.SharedNodeUpgradePlanDAO.UpdatePlanNodes(tx, this.planId, nodes)
+		if err != nil {
+			return err
+		}
+
+		var wg = &sync.WaitGroup{}
+		wg.Add(len(upgradingNodes))
+		for _, node := range upgradingNodes {
+			go func(node *models.NodeUpgradePlanNode) {
+				defer wg.Done()
+
+				err := this.installNode(node.NodeId, node.NewVersion)
+				if err != nil {
+					this.updateNode(node, models.NodeUpgradePlanNodeStatusFailed, err.Error())
+				} else {
+					this.updateNode(node, models.NodeUpgradePlanNodeStatusChecking, "")
+				}
+			}(node)
+		}
+		wg.Wait()
+
+		err = models.SharedNodeUpgradePlanDAO.UpdatePlanNodes(tx, this.planId, nodes)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, node := range batchNodes {
+		if node.Status == models.NodeUpgradePlanNodeStatusFailed {
+			return this.fail(nodes, batchNodes, "upgrade node '"+types.String(node.NodeId)+"' failed: "+node.Error)
+		}
+	}
+
+	// 等待
+	isRunning, err := this.wait(time.Duration(plan.WaitSeconds) * time.Second)
+	if err != nil || !isRunning {
+		return err
+	}
+
+	// 检查
+	var gateErr error
+	var gateDeadline = time.Now().Add(nodeUpgradeGateTimeout)
+	for {
+		gateErr = this.checkGates(plan, batchNodes)
+		if gateErr == nil || time.Now().After(gateDeadline) {
+			break
+		}
+		isRunning, err = this.wait(nodeUpgradeGateInterval)
+		if err != nil || !isRunning {
+			return err
+		}
+	}
+	if gateErr != nil {
+		return this.fail(nodes, batchNodes, gateErr.Error())
+	}
+
+	for _, node := range batchNodes {
+		this.updateNode(node, models.NodeUpgradePlanNodeStatusOk, "")
+	}
+	return models.SharedNodeUpgradePlanDAO.UpdatePlanNodes(tx, this.planId, nodes)
+}
+
+// 检查当前批次节点是否正常
+func (this *NodeUpgradePlanExecutor) checkGates(plan *models.NodeUpgradePlan, batchNodes []*models.NodeUpgradePlanNode) error {
+	var tx *dbs.Tx
+	var gates = plan.DecodeGates()
+
+	var batchNodeMap = map[int64]*models.NodeUpgradePlanNode{}
+	for _, planNode := range batchNodes {
+		batchNodeMap[planNode.NodeId] = planNode
+
+		node, err := models.SharedNodeDAO.FindEnabledNode(tx, planNode.NodeId)
+		if err != nil {
+			return err
+		}
+		if node == nil {
+			return errors.New("node '" + types.String(planNode.NodeId) + "' has been deleted")
+		}
+		status, err := node.DecodeStatus()
+		if err != nil {
+			return errors.New("node '" + node.Name + "': decode status failed: " + err.Error())
+		}
+		err = gates.CheckNodeStatus(status, planNode.NewVersion, planNode.UpdatedAt)
+		if err != nil {
+			return errors.New("node '" + node.Name + "': " + err.Error())
+		}
+	}
+
+	// 使用集群的健康检查设置
+	if gates.HealthCheck {
+		var healthCheckExecutor = NewHealthCheckExecutor(int64(plan.ClusterId))
+		healthCheckExecutor.SetPrimaryToken(this.primaryToken)
+		results, err := healthCheckExecutor.Run()
+		if err != nil {
+			return errors.New("health check failed: " + err.Error())
+		}
+		for _, result := range results {
+			if result.Node == nil {
+				continue
+			}
+			_, ok := batchNodeMap[int64(result.Node.Id)]
+			if ok && !result.IsOk {
+				return errors.New("node '" + result.Node.Name + "': health check failed on '" + result.NodeAddr + "': " + result.Error)
+			}
+		}
+	}
+
+	return nil
+}
+
+// 停止计划并回滚当前批次中已经升级的节点
+func (this *NodeUpgradePlanExecutor) fail(nodes []*models.NodeUpgradePlanNode, batchNodes []*models.NodeUpgradePlanNode, reason string) error {
+	var tx *dbs.Tx
+
+	// 只有在计划仍在执行时才回滚，防止和暂停、终止操作冲突
+	ok, err := models.SharedNodeUpgradePlanDAO.UpdatePlanStatus(tx, this.planId, models.NodeUpgradePlanStatusFailed, reason, models.NodeUpgradePlanStatusRunning)
+	if err != nil || !ok {
+		return err
+	}
+
+	var wg = &sync.WaitGroup{}
+	for _, node := range batchNodes {
+		if node.Status != models.NodeUpgradePlanNodeStatusChecking && node.Status != models.NodeUpgradePlanNodeStatusFailed {
+			continue
+		}
+		if len(node.OldVersion) == 0 {
+			this.updateNode(node, models.NodeUpgradePlanNodeStatusRollbackFailed, "unknown previous version")
+			continue
+		}
+
+		wg.Add(1)
+		go func(node *models.NodeUpgradePlanNode) {
+			defer wg.Done()
+
+			err := this.installNode(node.NodeId, node.OldVersion)
+			if err != nil {
+				this.updateNode(node, models.NodeUpgradePlanNodeStatusRollbackFailed, err.Error())
+			} else {
+				this.updateNode(node, models.NodeUpgradePlanNodeStatusRolledBack, "")
+			}
+		}(node)
+	}
+	wg.Wait()
+
+	return models.SharedNodeUpgradePlanDAO.UpdatePlanNodes(tx, this.planId, nodes)
+}
+
+// 等待一段时间，如果计划已经不在执行中，则提前返回
+func (this *NodeUpgradePlanExecutor) wait(duration time.Duration) (isRunning bool, err error) {
+	var deadline = time.Now().Add(duration)
+	for {
+		plan, err := models.SharedNodeUpgradePlanDAO.FindEnabledPlan(nil, this.planId)
+		if err != nil {
+			return false, err
+		}
+		if plan == nil || plan.Status != models.NodeUpgradePlanStatusRunning {
+			return false, nil
+		}
+
+		var leftDuration = time.Until(deadline)
+		if leftDuration <= 0 {
+			return true, nil
+		}
+		if leftDuration > nodeUpgradeWaitInterval {
+			leftDuration = nodeUpgradeWaitInterval
+		}
+		time.Sleep(leftDuration)
+	}
+}
+
+// 安装某个版本的节点
+func (this *NodeUpgradePlanExecutor) installNode(nodeId int64, version string) error {
+	var tx *dbs.Tx
+
+	err := models.SharedNodeDAO.UpdateNodeIsInstalled(tx, nodeId, false)
+	if err != nil {
+		return err
+	}
+
+	err = installers.SharedNodeQueue().InstallNodeProcessWithVersion(nodeId, true, version)
+	if err != nil {
+		return err
+	}
+
+	installStatus, err := models.SharedNodeDAO.FindNodeInstallStatus(tx, nodeId)
+	if err != nil {
+		return err
+	}
+	if installStatus == nil || !installStatus.IsOk {
+		if installStatus != nil && len(installStatus.Error) > 0 {
+			return errors.New(installStatus.Error)
+		}
+		return errors.New("install failed")
+	}
+	return nil
+}
+
+// 修改节点状态，节点的UpdatedAt同时作为健康检查时判断节点状态是否已更新的依据
+func (this *NodeUpgradePlanExecutor) updateNode(node *models.NodeUpgradePlanNode, status string, errString string) {
+	node.Status = status
+	node.Error = errString
+	node.UpdatedAt = time.Now().Unix()
+}
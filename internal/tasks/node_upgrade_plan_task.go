@@ -0,0 +1,83 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package tasks
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/db/models"
+	"github.com/dashenmiren/EdgeAPI/internal/goman"
+	"github.com/iwind/TeaGo/dbs"
+	"github.com/iwind/TeaGo/types"
+	"sync"
+	"time"
+)
+
+func init() {
+	dbs.OnReadyDone(func() {
+		goman.New(func() {
+			NewNodeUpgradePlanTask(10 * time.Second).Start()
+		})
+	})
+}
+
+// NodeUpgradePlanTask 检查并执行集群节点升级计划
+type NodeUpgradePlanTask struct {
+	BaseTask
+
+	ticker *time.Ticker
+
+	runningPlanIds map[int64]bool
+	locker         sync.Mutex
+}
+
+func NewNodeUpgradePlanTask(duration time.Duration) *NodeUpgradePlanTask {
+	return &NodeUpgradePlanTask{
+		ticker:         time.NewTicker(duration),
+		runningPlanIds: map[int64]bool{},
+	}
+}
+
+func (this *NodeUpgradePlanTask) Start() {
+	for range this.ticker.C {
+		err := this.runLoop("NodeUpgradePlanTask", this.Loop)
+		if err != nil {
+			this.logErr("NodeUpgradePlanTask", err.Error())
+		}
+	}
+}
+
+func (this *NodeUpgradePlanTask) Loop() error {
+	// 检查是否为主节点
+	primaryToken, ok := this.PrimaryToken()
+	if !ok {
+		return nil
+	}
+
+	planIds, err := models.SharedNodeUpgradePlanDAO.FindAllRunningPlanIds(nil)
+	if err != nil {
+		return err
+	}
+
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	for _, planId := range planIds {
+		if this.runningPlanIds[planId] {
+			continue
+		}
+		this.runningPlanIds[planId] = true
+
+		var executorPlanId = planId
+		goman.New(func() {
+			err := NewNodeUpgradePlanExecutor(executorPlanId, primaryToken).Run()
+			if err != nil {
+				this.logErr("NodeUpgradePlanTask", "plan '"+types.String(executorPlanId)+"': "+err.Error())
+			}
+
+			this.locker.Lock()
+			delete(this.runningPlanIds, executorPlanId)
+			this.locker.Unlock()
+		})
+	}
+
+	return nil
+}
@@ -36,7 +36,7 @@ func NewServerAccessLogCleaner(duration time.Duration) *ServerAccessLogCleaner {
 
 func (this *ServerAccessLogCleaner) Start() {
 	for range this.ticker.C {
-		err := this.Loop()
+		err := this.runLoop("ServerAccessLogCleaner", this.Loop)
 		if err != nil {
 			this.logErr("[TASK][ServerAccessLogCleaner]", err.Error())
 		}
@@ -22,6 +22,12 @@ func init() {
 	})
 }
 
+const (
+	SSLCertARICheckDays          = 90             // 检查ARI续期信息的证书有效天数范围
+	SSLCertARICheckInterval      = 6 * time.Hour  // ARI续期信息的检查间隔
+	SSLCertARIRenewRetryInterval = 12 * time.Hour // ARI续期失败后的重试间隔
+)
+
 // SSLCertExpireCheckExecutor 证书检查任务
 type SSLCertExpireCheckExecutor struct {
 	BaseTask
@@ -38,7 +44,7 @@ func NewSSLCertExpireCheckExecutor(duration time.Duration) *SSLCertExpireCheckEx
 // Start 启动任务
 func (this *SSLCertExpireCheckExecutor) Start() {
 	for range this.ticker.C {
-		err := this.Loop()
+		err := this.runLoop("SSLCertExpireCheckExecutor", this.Loop)
 		if err != nil {
 			this.logErr("SSLCertExpireCheckExecutor", err.Error())
 		}
@@ -48,10 +54,17 @@ func (this *SSLCertExpireCheckExecutor) Start() {
 // Loop 单次执行
 func (this *SSLCertExpireCheckExecutor) Loop() error {
 	// 检查是否为主节点
-	if !this.IsPrimaryNode() {
+	primaryToken, ok := this.PrimaryToken()
+	if !ok {
 		return nil
 	}
 
+	// 按ACME服务商建议的时间窗口续期
+	err := this.renewWithARI(primaryToken)
+	if err != nil {
+		return err
+	}
+
 	// 查找需要自动更新的证书
 	// 30, 14 ... 是到期的天数
 	for _, days := range []int{30, 14, 7} {
@@ -60,6 +73,11 @@ func (this *SSLCertExpireCheckExecutor) Loop() error {
 			return err
 		}
 		for _, cert := range certs {
+			// 主节点已切换，剩余的证书由新的主节点处理
+			if !this.IsPrimaryToken(primaryToken) {
+				return nil
+			}
+
 			// 发送消息
 			var subject = "SSL证书\"" + cert.Name + "\"在" + strconv.Itoa(days) + "天后将到期，"
 			var msg = "SSL证书\"" + cert.Name + "\"（" + this.summaryDNSNames(cert.DnsNames) + "）在" + strconv.Itoa(days) + "天后将到期，"
@@ -104,6 +122,11 @@ func (this *SSLCertExpireCheckExecutor) Loop() error {
 			return err
 		}
 		for _, cert := range certs {
+			// 主节点已切换，剩余的证书由新的主节点处理
+			if !this.IsPrimaryToken(primaryToken) {
+				return nil
+			}
+
 			// 发送消息
 			var subject = "SSL证书\"" + cert.Name + "\"在" + strconv.Itoa(days) + "天后将到期，"
 			var msg = "SSL证书\"" + cert.Name + "\"（" + this.summaryDNSNames(cert.DnsNames) + "）在" + strconv.Itoa(days) + "天后将到期，"
@@ -116,41 +139,15 @@ func (this *SSLCertExpireCheckExecutor) Loop() error {
 				}
 				if task != nil {
 					if task.AutoRenew == 1 {
+						// 续期前在数据库中确认仍然持有租约，避免和新的主节点重复申请证书
+						if !this.CheckPrimaryToken(primaryToken) {
+							return nil
+						}
+
 						isOk, errMsg, _ := acme.SharedACMETaskDAO.RunTask(nil, int64(cert.AcmeTaskId))
-						if isOk {
-							// 发送成功通知
-							subject = "系统已成功为你自动更新了证书\"" + cert.Name + "\""
-							msg = "系统已成功为你自动更新了证书\"" + cert.Name + "\"（" + this.summaryDNSNames(cert.DnsNames) + "）。"
-							err = models.SharedMessageDAO.CreateMessage(nil, int64(cert.AdminId), int64(cert.UserId), models.MessageTypeSSLCertACMETaskSuccess, models.MessageLevelSuccess, subject, msg, maps.Map{
-								"certId":     cert.Id,
-								"acmeTaskId": cert.AcmeTaskId,
-							}.AsJSON())
-							if err != nil {
-								return err
-							}
-
-							// 更新通知时间
-							err = models.SharedSSLCertDAO.UpdateCertNotifiedAt(nil, int64(cert.Id))
-							if err != nil {
-								return err
-							}
-						} else {
-							// 发送失败通知
-							subject = "系统在尝试自动更新证书\"" + cert.Name + "\"时发生错误"
-							msg = "系统在尝试自动更新证书\"" + cert.Name + "\"（" + this.summaryDNSNames(cert.DnsNames) + "）时发生错误：" + errMsg + "。请检查系统设置并修复错误。"
-							err = models.SharedMessageDAO.CreateMessage(nil, int64(cert.AdminId), int64(cert.UserId), models.MessageTypeSSLCertACMETaskFailed, models.MessageLevelError, subject, msg, maps.Map{
-								"certId":     cert.Id,
-								"acmeTaskId": cert.AcmeTaskId,
-							}.AsJSON())
-							if err != nil {
-								return err
-							}
-
-							// 更新通知时间
-							err = models.SharedSSLCertDAO.UpdateCertNotifiedAt(nil, int64(cert.Id))
-							if err != nil {
-								return err
-							}
+						err = this.notifyRenewResult(cert, isOk, errMsg)
+						if err != nil {
+							return err
 						}
 
 						// 中止不发送消息
@@ -187,6 +184,11 @@ func (this *SSLCertExpireCheckExecutor) Loop() error {
 			return err
 		}
 		for _, cert := range certs {
+			// 主节点已切换，剩余的证书由新的主节点处理
+			if !this.IsPrimaryToken(primaryToken) {
+				return nil
+			}
+
 			// 发送消息
 			var today = timeutil.Format("Y-m-d")
 			var subject = "SSL证书\"" + cert.Name + "\"在今天（" + today + "）过期"
@@ -210,6 +212,92 @@ func (this *SSLCertExpireCheckExecutor) Loop() error {
 	return nil
 }
 
+// 根据ACME服务商的ARI建议自动续期
+// 服务商不支持ARI或者查询失败时，仍然按到期前的天数续期
+func (this *SSLCertExpireCheckExecutor) renewWithARI(primaryToken int64) error {
+	certs, err := models.SharedSSLCertDAO.FindAllExpiringACMECerts(nil, SSLCertARICheckDays)
+	if err != nil {
+		return err
+	}
+	for _, cert := range certs {
+		if !this.IsPrimaryToken(primaryToken) {
+			return nil
+		}
+
+		task, err := acme.SharedACMETaskDAO.FindEnabledACMETask(nil, int64(cert.AcmeTaskId))
+		if err != nil {
+			return err
+		}
+		if task == nil || !task.IsOn || task.AutoRenew != 1 {
+			continue
+		}
+
+		var now = time.Now().Unix()
+		var renewalInfo = task.DecodeRenewalInfo()
+		if renewalInfo == nil || renewalInfo.CheckedAt < now-int64(SSLCertARICheckInterval.Seconds()) {
+			renewalInfo, err = acme.SharedACMETaskDAO.CheckACMETaskRenewalInfo(nil, int64(task.Id), cert.CertData)
+			if err != nil {
+				this.logErr("SSLCertExpireCheckExecutor", "check renewal info of cert '"+types.String(cert.Id)+"' failed: "+err.Error())
+				continue
+			}
+		}
+		if !renewalInfo.IsSupported || renewalInfo.RenewAt <= 0 || renewalInfo.RenewAt > now {
+			continue
+		}
+
+		// 续期前在数据库中确认仍然持有租约，避免和新的主节点重复申请证书
+		if !this.CheckPrimaryToken(primaryToken) {
+			return nil
+		}
+
+		isOk, errMsg, _ := acme.SharedACMETaskDAO.RunTask(nil, int64(task.Id))
+		if !isOk {
+			// 推迟下次尝试的时间
+			err = acme.SharedACMETaskDAO.PostponeACMETaskRenewal(nil, int64(task.Id), now+int64(SSLCertARIRenewRetryInterval.Seconds()))
+			if err != nil {
+				return err
+			}
+		}
+		err = this.notifyRenewResult(cert, isOk, errMsg)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 发送自动续期结果通知
+func (this *SSLCertExpireCheckExecutor) notifyRenewResult(cert *models.SSLCert, isOk bool, errMsg string) error {
+	var subject string
+	var msg string
+	var messageType models.MessageType
+	var messageLevel string
+	if isOk {
+		// 发送成功通知
+		subject = "系统已成功为你自动更新了证书\"" + cert.Name + "\""
+		msg = "系统已成功为你自动更新了证书\"" + cert.Name + "\"（" + this.summaryDNSNames(cert.DnsNames) + "）。"
+		messageType = models.MessageTypeSSLCertACMETaskSuccess
+		messageLevel = models.MessageLevelSuccess
+	} else {
+		// 发送失败通知
+		subject = "系统在尝试自动更新证书\"" + cert.Name + "\"时发生错误"
+		msg = "系统在尝试自动更新证书\"" + cert.Name + "\"（" + this.summaryDNSNames(cert.DnsNames) + "）时发生错误：" + errMsg + "。请检查系统设置并修复错误。"
+		messageType = models.MessageTypeSSLCertACMETaskFailed
+		messageLevel = models.MessageLevelError
+	}
+
+	err := models.SharedMessageDAO.CreateMessage(nil, int64(cert.AdminId), int64(cert.UserId), messageType, messageLevel, subject, msg, maps.Map{
+		"certId":     cert.Id,
+		"acmeTaskId": cert.AcmeTaskId,
+	}.AsJSON())
+	if err != nil {
+		return err
+	}
+
+	// 更新通知时间
+	return models.SharedSSLCertDAO.UpdateCertNotifiedAt(nil, int64(cert.Id))
+}
+
 // 对证书中DNS域名的描述
 func (this *SSLCertExpireCheckExecutor) summaryDNSNames(dnsNamesJSON []byte) string {
 	if len(dnsNamesJSON) == 0 {
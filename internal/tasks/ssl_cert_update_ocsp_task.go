@@ -45,7 +45,7 @@ func NewSSLCertUpdateOCSPTask(duration time.Duration) *SSLCertUpdateOCSPTask {
 
 func (this *SSLCertUpdateOCSPTask) Start() {
 	for range this.ticker.C {
-		err := this.Loop()
+		err := this.runLoop("SSLCertUpdateOCSPTask", this.Loop)
 		if err != nil {
 			this.logErr("SSLCertUpdateOCSPTask", err.Error())
 		}
@@ -54,7 +54,8 @@ func (this *SSLCertUpdateOCSPTask) Start() {
 
 func (this *SSLCertUpdateOCSPTask) Loop() error {
 	// 检查是否为主节点
-	if !this.IsPrimaryNode() {
+	primaryToken, ok := this.PrimaryToken()
+	if !ok {
 		return nil
 	}
 
@@ -80,6 +81,11 @@ func (this *SSLCertUpdateOCSPTask) Loop() error {
 	}
 
 	for _, cert := range certs {
+		// 主节点已切换，未处理的证书在锁定超时后由新的主节点更新
+		if !this.IsPrimaryToken(primaryToken) {
+			return nil
+		}
+
 		ocspData, expiresAt, err := this.UpdateCertOCSP(cert)
 		var errString = ""
 		var hasErr = false
@@ -5,8 +5,13 @@ package tasks
 import (
 	"github.com/dashenmiren/EdgeAPI/internal/db/models"
 	"github.com/dashenmiren/EdgeAPI/internal/remotelogs"
+	"github.com/dashenmiren/EdgeAPI/internal/utils/openmetrics"
+	"time"
 )
 
+// 任务循环耗时区间，单位为秒
+var taskLoopBuckets = []float64{0.01, 0.1, 0.5, 1, 5, 10, 30, 60, 300, 600}
+
 type BaseTask struct {
 }
 
@@ -14,6 +19,36 @@ func (this *BaseTask) logErr(taskType string, errString string) {
 	remotelogs.Error("TASK", "run '"+taskType+"' failed: "+errString)
 }
 
-func (this *BaseTask) IsPrimaryNode() bool {
-	return models.SharedAPINodeDAO.CheckAPINodeIsPrimaryWithoutErr()
+// 执行一次循环，并记录耗时
+func (this *BaseTask) runLoop(taskType string, loop func() error) error {
+	var before = time.Now()
+	err := loop()
+	var labels = openmetrics.Labels{"task": taskType}
+	openmetrics.SharedRegistry.Observe("edge_api_task_loop_duration_seconds", "Duration of task loops.", labels, time.Since(before).Seconds(), taskLoopBuckets)
+	if err != nil {
+		openmetrics.SharedRegistry.AddCounter("edge_api_task_loop_errors", "Failed task loops.", labels, 1)
+	}
+	return err
+}
+
+// PrimaryToken 读取当前节点持有的主节点租约防护令牌
+func (this *BaseTask) PrimaryToken() (token int64, ok bool) {
+	return models.SharedAPINodePrimaryElector.Token()
+}
+
+// IsPrimaryToken 检查防护令牌是否仍然有效
+// 执行时间较长的任务在开始时读取令牌，在执行过程中用来判断是否发生过主节点切换
+func (this *BaseTask) IsPrimaryToken(token int64) bool {
+	return models.SharedAPINodePrimaryElector.IsToken(token)
+}
+
+// CheckPrimaryToken 在数据库中确认防护令牌仍然持有租约
+// 用于申请证书、远程启动节点等不能重复执行的操作之前，查询失败时视为无效
+func (this *BaseTask) CheckPrimaryToken(token int64) bool {
+	ok, err := models.SharedAPINodePrimaryElector.CheckToken(nil, token)
+	if err != nil {
+		remotelogs.Error("TASK", "check primary token failed: "+err.Error())
+		return false
+	}
+	return ok
 }
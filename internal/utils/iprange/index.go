@@ -0,0 +1,223 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package iprange
+
+import (
+	"net/netip"
+	"sort"
+	"sync"
+)
+
+// 增量修改达到此数量时重建有序索引
+const indexRebuildThreshold = 4096
+
+type indexEntry struct {
+	id uint64
+	r  Range
+}
+
+// 按照开始IP排序的有序列表
+// maxTo[i] 为 entries[0..i] 中最大的结束IP，查找时据此提前结束向前扫描
+type indexTable struct {
+	entries []indexEntry
+	maxTo   []netip.Addr
+}
+
+func (this *indexTable) lookup(ip netip.Addr, removed map[uint64]bool, fn func(id uint64)) {
+	var k = sort.Search(len(this.entries), func(i int) bool {
+		return ip.Less(this.entries[i].r.From)
+	}) - 1
+	for i := k; i >= 0 && !this.maxTo[i].Less(ip); i-- {
+		var entry = this.entries[i]
+		if !entry.r.To.Less(ip) && !removed[entry.id] {
+			fn(entry.id)
+		}
+	}
+}
+
+// Index IP范围索引
+// 使用按开始IP排序的区间列表支持查找包含某个IP的所有范围；新增和删除先记录在增量中，达到一定数量后再重建有序列表，
+// 以避免频繁修改时每次都要重新排序
+type Index struct {
+	entries map[uint64]Range // id => range
+	all     map[uint64]bool  // 匹配所有IP的条目
+
+	table4 *indexTable
+	table6 *indexTable
+
+	pending map[uint64]Range // 重建之后新增或者修改的条目
+	removed map[uint64]bool  // 重建之后在有序列表中失效的条目
+
+	locker sync.RWMutex
+}
+
+// NewIndex 获取新对象
+func NewIndex() *Index {
+	return &Index{
+		entries: map[uint64]Range{},
+		all:     map[uint64]bool{},
+		table4:  &indexTable{},
+		table6:  &indexTable{},
+		pending: map[uint64]Range{},
+		removed: map[uint64]bool{},
+	}
+}
+
+// Load 替换所有条目，并一次性构建有序列表
+// 用于初始加载大量条目，避免逐个添加时反复重建
+func (this *Index) Load(ranges map[uint64]Range, allIds []uint64) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	this.entries = map[uint64]Range{}
+	for id, r := range ranges {
+		this.entries[id] = r
+	}
+	this.all = map[uint64]bool{}
+	for _, id := range allIds {
+		delete(this.entries, id)
+		this.all[id] = true
+	}
+	this.rebuild()
+}
+
+// Add 添加或者替换IP范围
+func (this *Index) Add(id uint64, r Range) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	this.remove(id)
+	this.entries[id] = r
+	this.pending[id] = r
+
+	if len(this.pending)+len(this.removed) >= indexRebuildThreshold {
+		this.rebuild()
+	}
+}
+
+// AddAll 添加匹配所有IP的条目
+func (this *Index) AddAll(id uint64) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	this.remove(id)
+	this.all[id] = true
+}
+
+// Remove 删除条目
+func (this *Index) Remove(id uint64) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	this.remove(id)
+
+	if len(this.pending)+len(this.removed) >= indexRebuildThreshold {
+		this.rebuild()
+	}
+}
+
+// Lookup 查找包含某个IP的所有条目ID
+func (this *Index) Lookup(ip netip.Addr) []uint64 {
+	ip = ip.Unmap()
+	if !ip.IsValid() {
+		return nil
+	}
+
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+
+	var result = []uint64{}
+	var fn = func(id uint64) {
+		result = append(result, id)
+	}
+	for id := range this.all {
+		fn(id)
+	}
+	if ip.Is4() {
+		this.table4.lookup(ip, this.removed, fn)
+	} else {
+		this.table6.lookup(ip, this.removed, fn)
+	}
+	for id, r := range this.pending {
+		if r.Contains(ip) {
+			fn(id)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+	return result
+}
+
+// Len 条目数量
+func (this *Index) Len() int {
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+	return len(this.entries) + len(this.all)
+}
+
+// Reset 清空所有条目
+func (this *Index) Reset() {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	this.entries = map[uint64]Range{}
+	this.all = map[uint64]bool{}
+	this.table4 = &indexTable{}
+	this.table6 = &indexTable{}
+	this.pending = map[uint64]Range{}
+	this.removed = map[uint64]bool{}
+}
+
+func (this *Index) remove(id uint64) {
+	delete(this.all, id)
+
+	_, exists := this.entries[id]
+	if !exists {
+		return
+	}
+	delete(this.entries, id)
+
+	_, isPending := this.pending[id]
+	if isPending {
+		// 如果是替换了有序列表中的条目，removed 中已有记录
+		delete(this.pending, id)
+	} else {
+		this.removed[id] = true
+	}
+}
+
+// 重建有序列表
+func (this *Index) rebuild() {
+	var entries4 = []indexEntry{}
+	var entries6 = []indexEntry{}
+	for id, r := range this.entries {
+		if r.From.Is4() {
+			entries4 = append(entries4, indexEntry{id: id, r: r})
+		} else {
+			entries6 = append(entries6, indexEntry{id: id, r: r})
+		}
+	}
+	this.table4 = buildIndexTable(entries4)
+	this.table6 = buildIndexTable(entries6)
+	this.pending = map[uint64]Range{}
+	this.removed = map[uint64]bool{}
+}
+
+func buildIndexTable(entries []indexEntry) *indexTable {
+	sort.Slice(entries, func(i, j int) bool {
+		return compareRange(entries[i].r, entries[j].r) < 0
+	})
+	var maxTo = make([]netip.Addr, len(entries))
+	for i, entry := range entries {
+		maxTo[i] = entry.r.To
+		if i > 0 && entry.r.To.Less(maxTo[i-1]) {
+			maxTo[i] = maxTo[i-1]
+		}
+	}
+	return &indexTable{
+		entries: entries,
+		maxTo:   maxTo,
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package iprange
+
+import (
+	"math/rand"
+	"net/netip"
+	"sort"
+	"testing"
+)
+
+func TestIndex_Lookup(t *testing.T) {
+	var index = NewIndex()
+	var add = func(id uint64, from string, to string) {
+		r, err := ParseRange(from, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		index.Add(id, r)
+	}
+	var lookup = func(ip string) []uint64 {
+		return index.Lookup(netip.MustParseAddr(ip))
+	}
+	var assertIds = func(ip string, expected ...uint64) {
+		var ids = lookup(ip)
+		if len(ids) != len(expected) {
+			t.Fatal(ip, "expect", expected, "got", ids)
+		}
+		for i, id := range ids {
+			if id != expected[i] {
+				t.Fatal(ip, "expect", expected, "got", ids)
+			}
+		}
+	}
+
+	add(1, "10.0.0.0", "10.255.255.255")
+	add(2, "10.0.0.1", "")
+	add(3, "10.0.0.1", "10.0.0.10")
+	add(4, "::1", "::ff")
+	index.AddAll(5)
+
+	// 增量中查找
+	assertIds("10.0.0.1", 1, 2, 3, 5)
+	assertIds("10.1.0.1", 1, 5)
+	assertIds("11.0.0.1", 5)
+	assertIds("::10", 4, 5)
+	assertIds("::ffff:10.0.0.2", 1, 3, 5)
+
+	// 在有序列表中查找
+	index.locker.Lock()
+	index.rebuild()
+	index.locker.Unlock()
+	assertIds("10.0.0.1", 1, 2, 3, 5)
+	assertIds("10.0.0.11", 1, 5)
+
+	// 修改和删除
+	add(3, "10.0.0.20", "10.0.0.30")
+	index.Remove(2)
+	index.Remove(5)
+	assertIds("10.0.0.1", 1)
+	assertIds("10.0.0.25", 1, 3)
+
+	index.Remove(3)
+	assertIds("10.0.0.25", 1)
+	if index.Len() != 2 {
+		t.Fatal("expect 2, got", index.Len())
+	}
+
+	index.Reset()
+	assertIds("10.0.0.1")
+
+	// 批量加载
+	r, _ := ParseRange("10.0.0.1", "10.0.0.2")
+	index.Load(map[uint64]Range{1: r, 2: r}, []uint64{2})
+	assertIds("10.0.0.1", 1, 2)
+	assertIds("10.0.0.3", 2)
+	if index.Len() != 2 {
+		t.Fatal("expect 2, got", index.Len())
+	}
+}
+
+func TestIndex_Random(t *testing.T) {
+	var index = NewIndex()
+	var ranges = map[uint64]Range{}
+	var randomAddr = func() netip.Addr {
+		return netip.AddrFrom4([4]byte{10, 0, byte(rand.Intn(4)), byte(rand.Intn(256))})
+	}
+
+	for i := 0; i < indexRebuildThreshold*3; i++ {
+		var id = uint64(rand.Intn(2000) + 1)
+		if rand.Intn(4) == 0 {
+			index.Remove(id)
+			delete(ranges, id)
+			continue
+		}
+		r, err := NewRange(randomAddr(), randomAddr())
+		if err != nil {
+			t.Fatal(err)
+		}
+		index.Add(id, r)
+		ranges[id] = r
+	}
+
+	for i := 0; i < 1000; i++ {
+		var ip = randomAddr()
+		var expected = []uint64{}
+		for id, r := range ranges {
+			if r.Contains(ip) {
+				expected = append(expected, id)
+			}
+		}
+		sort.Slice(expected, func(i, j int) bool {
+			return expected[i] < expected[j]
+		})
+
+		var ids = index.Lookup(ip)
+		if len(ids) != len(expected) {
+			t.Fatal(ip, "expect", len(expected), "ids, got", len(ids))
+		}
+		for j, id := range ids {
+			if id != expected[j] {
+				t.Fatal(ip, "unexpected ids")
+			}
+		}
+	}
+}
+
+func BenchmarkIndex_Lookup(b *testing.B) {
+	var ranges = map[uint64]Range{}
+	for i := 0; i < 1_000_000; i++ {
+		var from = netip.AddrFrom4([4]byte{byte(i >> 16), byte(i >> 8), byte(i), 0})
+		r, _ := NewRange(from, from)
+		ranges[uint64(i+1)] = r
+	}
+	var index = NewIndex()
+	index.Load(ranges, nil)
+	for i := 0; i < 1000; i++ {
+		var from = netip.AddrFrom4([4]byte{1, 2, byte(i >> 8), byte(i)})
+		r, _ := NewRange(from, from)
+		index.Add(uint64(2_000_000+i), r)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = index.Lookup(netip.AddrFrom4([4]byte{byte(i >> 16), byte(i >> 8), byte(i), 0}))
+	}
+}
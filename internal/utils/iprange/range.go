@@ -0,0 +1,160 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package iprange
+
+import (
+	"errors"
+	"net/netip"
+	"sort"
+)
+
+// Range IP范围，包含开始和结束IP
+type Range struct {
+	From netip.Addr
+	To   netip.Addr
+}
+
+// ParseRange 解析IP范围，结束IP为空时表示单个IP
+func ParseRange(ipFrom string, ipTo string) (Range, error) {
+	from, err := netip.ParseAddr(ipFrom)
+	if err != nil {
+		return Range{}, errors.New("invalid ip '" + ipFrom + "'")
+	}
+	var to = from
+	if len(ipTo) > 0 {
+		to, err = netip.ParseAddr(ipTo)
+		if err != nil {
+			return Range{}, errors.New("invalid ip '" + ipTo + "'")
+		}
+	}
+	return NewRange(from, to)
+}
+
+// NewRange 构造IP范围
+// IPv4映射的IPv6地址按照IPv4处理，开始IP大于结束IP时自动交换
+func NewRange(from netip.Addr, to netip.Addr) (Range, error) {
+	from = from.Unmap()
+	to = to.Unmap()
+	if !from.IsValid() || !to.IsValid() {
+		return Range{}, errors.New("invalid ip")
+	}
+	if from.BitLen() != to.BitLen() {
+		return Range{}, errors.New("'" + from.String() + "' and '" + to.String() + "' should be in same version")
+	}
+	if to.Less(from) {
+		from, to = to, from
+	}
+	return Range{From: from, To: to}, nil
+}
+
+// Contains 判断是否包含某个IP
+func (this Range) Contains(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.BitLen() == this.From.BitLen() && !ip.Less(this.From) && !this.To.Less(ip)
+}
+
+// IsSingle 是否只包含一个IP
+func (this Range) IsSingle() bool {
+	return this.From == this.To
+}
+
+// String 转换为字符串，单个IP只显示IP
+func (this Range) String() string {
+	if this.IsSingle() {
+		return this.From.String()
+	}
+	return this.From.String() + "-" + this.To.String()
+}
+
+// Prefixes 将IP范围拆分成最少数量的CIDR
+func (this Range) Prefixes() (result []netip.Prefix) {
+	var from = this.From
+	var to = this.To
+	if !from.IsValid() || !to.IsValid() || from.BitLen() != to.BitLen() {
+		return nil
+	}
+
+	for {
+		// 找到以 from 开头并且不超过 to 的最大网段
+		var prefix = netip.PrefixFrom(from, from.BitLen())
+		for bits := 0; bits <= from.BitLen(); bits++ {
+			var p = netip.PrefixFrom(from, bits)
+			if p.Masked().Addr() == from && !to.Less(LastAddr(p)) {
+				prefix = p
+				break
+			}
+		}
+		result = append(result, prefix)
+
+		var last = LastAddr(prefix)
+		if last == to {
+			break
+		}
+		from = last.Next()
+		if !from.IsValid() {
+			break
+		}
+	}
+	return
+}
+
+// LastAddr 网段中的最后一个IP
+func LastAddr(prefix netip.Prefix) netip.Addr {
+	var addr = prefix.Addr()
+	var ipBytes = addr.AsSlice()
+	for i := prefix.Bits(); i < addr.BitLen(); i++ {
+		ipBytes[i/8] |= 1 << (7 - uint(i%8))
+	}
+	last, _ := netip.AddrFromSlice(ipBytes)
+	return last
+}
+
+// Merge 合并重叠或者相邻的IP范围
+// 返回的结果按照IPv4、IPv6以及开始IP排序
+func Merge(ranges []Range) []Range {
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	var sorted = make([]Range, len(ranges))
+	copy(sorted, ranges)
+	sort.Slice(sorted, func(i, j int) bool {
+		return compareRange(sorted[i], sorted[j]) < 0
+	})
+
+	var result = []Range{sorted[0]}
+	for _, r := range sorted[1:] {
+		var last = &result[len(result)-1]
+		if last.From.BitLen() == r.From.BitLen() && canMerge(last.To, r.From) {
+			if last.To.Less(r.To) {
+				last.To = r.To
+			}
+			continue
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+// 判断开始于 from 的范围是否和结束于 to 的范围重叠或者相邻
+func canMerge(to netip.Addr, from netip.Addr) bool {
+	if !to.Less(from) {
+		return true
+	}
+	var next = to.Next()
+	return next.IsValid() && next == from
+}
+
+func compareRange(r1 Range, r2 Range) int {
+	if r1.From.BitLen() != r2.From.BitLen() {
+		if r1.From.BitLen() < r2.From.BitLen() {
+			return -1
+		}
+		return 1
+	}
+	var c = r1.From.Compare(r2.From)
+	if c != 0 {
+		return c
+	}
+	return r1.To.Compare(r2.To)
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package iprange_test
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/utils/iprange"
+	"net/netip"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	r, err := iprange.ParseRange("1.2.3.10", "1.2.3.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.String() != "1.2.3.1-1.2.3.10" {
+		t.Fatal("unexpected range:", r.String())
+	}
+	if !r.Contains(netip.MustParseAddr("1.2.3.5")) || r.Contains(netip.MustParseAddr("1.2.3.11")) || r.Contains(netip.MustParseAddr("::1")) {
+		t.Fatal("unexpected contains result")
+	}
+
+	r, err = iprange.ParseRange("::ffff:1.2.3.4", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.IsSingle() || r.String() != "1.2.3.4" {
+		t.Fatal("unexpected range:", r.String())
+	}
+
+	for _, testCase := range [][2]string{{"abc", ""}, {"1.2.3.4", "::1"}, {"1.2.3.4", "abc"}} {
+		_, err = iprange.ParseRange(testCase[0], testCase[1])
+		if err == nil {
+			t.Fatal("expect error:", testCase)
+		}
+	}
+}
+
+func TestRange_Prefixes(t *testing.T) {
+	for _, testCase := range []struct {
+		from   string
+		to     string
+		result []string
+	}{
+		{"1.2.3.0", "1.2.3.255", []string{"1.2.3.0/24"}},
+		{"1.2.3.1", "1.2.3.10", []string{"1.2.3.1/32", "1.2.3.2/31", "1.2.3.4/30", "1.2.3.8/31", "1.2.3.10/32"}},
+		{"0.0.0.0", "255.255.255.255", []string{"0.0.0.0/0"}},
+		{"255.255.255.254", "255.255.255.255", []string{"255.255.255.254/31"}},
+		{"::1", "::3", []string{"::1/128", "::2/127"}},
+	} {
+		r, err := iprange.ParseRange(testCase.from, testCase.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var prefixes = r.Prefixes()
+		if len(prefixes) != len(testCase.result) {
+			t.Fatal(testCase.from, testCase.to, "unexpected result:", prefixes)
+		}
+		for index, prefix := range prefixes {
+			if prefix.String() != testCase.result[index] {
+				t.Fatal(testCase.from, testCase.to, "unexpected result:", prefixes)
+			}
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	var ranges = []iprange.Range{}
+	for _, value := range [][2]string{
+		{"10.0.0.5", "10.0.0.9"},
+		{"::1", "::5"},
+		{"10.0.0.1", "10.0.0.4"},  // 相邻
+		{"10.0.0.7", "10.0.0.20"}, // 重叠
+		{"10.0.1.1", ""},
+		{"::6", "::6"},
+		{"255.255.255.255", ""},
+		{"255.255.255.0", "255.255.255.254"},
+	} {
+		r, err := iprange.ParseRange(value[0], value[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		ranges = append(ranges, r)
+	}
+
+	var result = []string{}
+	for _, r := range iprange.Merge(ranges) {
+		result = append(result, r.String())
+	}
+	var expected = []string{"10.0.0.1-10.0.0.20", "10.0.1.1", "255.255.255.0-255.255.255.255", "::1-::6"}
+	if len(result) != len(expected) {
+		t.Fatal("unexpected result:", result)
+	}
+	for index, value := range expected {
+		if result[index] != value {
+			t.Fatal("unexpected result:", result)
+		}
+	}
+
+	if len(iprange.Merge(nil)) != 0 {
+		t.Fatal("expect empty result")
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package openmetrics
+
+// 指标族
+type family struct {
+	name       string
+	help       string
+	metricType MetricType
+	buckets    []float64
+
+	sampleMap map[string]*sample // labels => *sample
+}
+
+// 单个样本
+type sample struct {
+	labels string
+	value  float64 // 计数器和测量值的值，或者直方图的总和
+
+	// 直方图
+	buckets      []float64
+	bucketCounts []uint64
+	count        uint64
+}
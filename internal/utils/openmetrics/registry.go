@@ -0,0 +1,143 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package openmetrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+const ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+type MetricType = string
+
+const (
+	MetricTypeCounter   MetricType = "counter"
+	MetricTypeGauge     MetricType = "gauge"
+	MetricTypeHistogram MetricType = "histogram"
+)
+
+// DefaultBuckets 默认的直方图区间，单位为秒
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+var SharedRegistry = NewRegistry()
+
+// Labels 标签
+type Labels = map[string]string
+
+// Registry 指标注册表
+type Registry struct {
+	familyMap map[string]*family // name => *family
+
+	locker sync.Mutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		familyMap: map[string]*family{},
+	}
+}
+
+// AddCounter 增加计数器的值
+func (this *Registry) AddCounter(name string, help string, labels Labels, delta float64) {
+	if delta < 0 {
+		return
+	}
+
+	this.locker.Lock()
+	var s = this.sample(name, help, MetricTypeCounter, labels, nil)
+	s.value += delta
+	this.locker.Unlock()
+}
+
+// SetGauge 设置测量值
+func (this *Registry) SetGauge(name string, help string, labels Labels, value float64) {
+	this.locker.Lock()
+	var s = this.sample(name, help, MetricTypeGauge, labels, nil)
+	s.value = value
+	this.locker.Unlock()
+}
+
+// Observe 在直方图中记录一个值
+// buckets 为空时使用 DefaultBuckets，同一个指标只有第一次设置的区间有效
+func (this *Registry) Observe(name string, help string, labels Labels, value float64, buckets []float64) {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	this.locker.Lock()
+	var s = this.sample(name, help, MetricTypeHistogram, labels, buckets)
+	for index, bucket := range s.buckets {
+		if value <= bucket {
+			s.bucketCounts[index]++
+		}
+	}
+	s.count++
+	s.value += value
+	this.locker.Unlock()
+}
+
+// Reset 清除所有指标
+func (this *Registry) Reset() {
+	this.locker.Lock()
+	this.familyMap = map[string]*family{}
+	this.locker.Unlock()
+}
+
+// 查找或创建样本，需要在锁中调用
+func (this *Registry) sample(name string, help string, metricType MetricType, labels Labels, buckets []float64) *sample {
+	f, ok := this.familyMap[name]
+	if !ok {
+		f = &family{
+			name:       name,
+			help:       help,
+			metricType: metricType,
+			sampleMap:  map[string]*sample{},
+		}
+		if metricType == MetricTypeHistogram {
+			f.buckets = append([]float64{}, buckets...)
+			sort.Float64s(f.buckets)
+		}
+		this.familyMap[name] = f
+	}
+
+	var key = labelsString(labels)
+	s, ok := f.sampleMap[key]
+	if !ok {
+		s = &sample{
+			labels: key,
+		}
+		if metricType == MetricTypeHistogram {
+			s.buckets = f.buckets
+			s.bucketCounts = make([]uint64, len(f.buckets))
+		}
+		f.sampleMap[key] = s
+	}
+	return s
+}
+
+// 将标签转换为字符串，按名称排序，比如 a="1",b="2"
+func labelsString(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	var names = make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pieces = make([]string, 0, len(names))
+	for _, name := range names {
+		pieces = append(pieces, name+"=\""+escapeLabelValue(labels[name])+"\"")
+	}
+	return strings.Join(pieces, ",")
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
@@ -0,0 +1,63 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package openmetrics_test
+
+import (
+	"bytes"
+	"github.com/dashenmiren/EdgeAPI/internal/utils/openmetrics"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	var registry = openmetrics.NewRegistry()
+	registry.AddCounter("edge_api_rpc_requests", "RPC requests", openmetrics.Labels{"method": "/pb.NodeService/FindEnabledNode", "code": "OK"}, 1)
+	registry.AddCounter("edge_api_rpc_requests", "RPC requests", openmetrics.Labels{"method": "/pb.NodeService/FindEnabledNode", "code": "OK"}, 2)
+	registry.SetGauge("edge_api_goroutines", "Goroutines", nil, 10)
+	registry.Observe("edge_api_rpc_request_duration_seconds", "RPC latency", openmetrics.Labels{"method": "a\"b"}, 0.02, []float64{0.01, 0.1})
+	registry.Observe("edge_api_rpc_request_duration_seconds", "RPC latency", openmetrics.Labels{"method": "a\"b"}, 0.2, nil)
+
+	var buf = &bytes.Buffer{}
+	_, err := registry.WriteTo(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = openmetrics.WriteEOF(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output = buf.String()
+	t.Log("\n" + output)
+
+	for _, line := range []string{
+		"# TYPE edge_api_rpc_requests counter",
+		`edge_api_rpc_requests_total{code="OK",method="/pb.NodeService/FindEnabledNode"} 3`,
+		"edge_api_goroutines 10",
+		`edge_api_rpc_request_duration_seconds_bucket{method="a\"b",le="0.01"} 0`,
+		`edge_api_rpc_request_duration_seconds_bucket{method="a\"b",le="0.1"} 1`,
+		`edge_api_rpc_request_duration_seconds_bucket{method="a\"b",le="+Inf"} 2`,
+		`edge_api_rpc_request_duration_seconds_count{method="a\"b"} 2`,
+	} {
+		if !strings.Contains(output, line+"\n") {
+			t.Fatal("missing line: " + line)
+		}
+	}
+	if !strings.HasSuffix(output, "# EOF\n") {
+		t.Fatal("missing EOF")
+	}
+}
+
+func TestRegistry_Reset(t *testing.T) {
+	var registry = openmetrics.NewRegistry()
+	registry.SetGauge("a", "", nil, 1)
+	registry.Reset()
+
+	var buf = &bytes.Buffer{}
+	_, err := registry.WriteTo(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Fatal("should be empty")
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package openmetrics
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WriteTo 以OpenMetrics文本格式输出所有指标，不包含结尾的 # EOF
+func (this *Registry) WriteTo(writer io.Writer) (int64, error) {
+	var buf = &bytes.Buffer{}
+
+	this.locker.Lock()
+	var names = make([]string, 0, len(this.familyMap))
+	for name := range this.familyMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		this.familyMap[name].write(buf)
+	}
+	this.locker.Unlock()
+
+	n, err := writer.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// WriteEOF 输出结尾标记
+func WriteEOF(writer io.Writer) error {
+	_, err := io.WriteString(writer, "# EOF\n")
+	return err
+}
+
+func (this *family) write(buf *bytes.Buffer) {
+	buf.WriteString("# TYPE " + this.name + " " + this.metricType + "\n")
+	if len(this.help) > 0 {
+		buf.WriteString("# HELP " + this.name + " " + escapeHelp(this.help) + "\n")
+	}
+
+	var keys = make([]string, 0, len(this.sampleMap))
+	for key := range this.sampleMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		var s = this.sampleMap[key]
+		switch this.metricType {
+		case MetricTypeCounter:
+			writeSample(buf, this.name+"_total", s.labels, "", formatFloat(s.value))
+		case MetricTypeGauge:
+			writeSample(buf, this.name, s.labels, "", formatFloat(s.value))
+		case MetricTypeHistogram:
+			for index, bucket := range s.buckets {
+				writeSample(buf, this.name+"_bucket", s.labels, `le="`+formatFloat(bucket)+`"`, strconv.FormatUint(s.bucketCounts[index], 10))
+			}
+			writeSample(buf, this.name+"_bucket", s.labels, `le="+Inf"`, strconv.FormatUint(s.count, 10))
+			writeSample(buf, this.name+"_sum", s.labels, "", formatFloat(s.value))
+			writeSample(buf, this.name+"_count", s.labels, "", strconv.FormatUint(s.count, 10))
+		}
+	}
+}
+
+func writeSample(buf *bytes.Buffer, name string, labels string, extraLabel string, value string) {
+	buf.WriteString(name)
+	if len(labels) > 0 || len(extraLabel) > 0 {
+		buf.WriteString("{")
+		buf.WriteString(labels)
+		if len(extraLabel) > 0 {
+			if len(labels) > 0 {
+				buf.WriteString(",")
+			}
+			buf.WriteString(extraLabel)
+		}
+		buf.WriteString("}")
+	}
+	buf.WriteString(" ")
+	buf.WriteString(value)
+	buf.WriteString("\n")
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeHelp(help string) string {
+	return helpReplacer.Replace(help)
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package ratelimit
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	AnyRole   = "*"
+	AnyMethod = "*"
+)
+
+// Config API限流配置
+type Config struct {
+	IsOn  bool    `json:"isOn"`  // 是否启用
+	Rules []*Rule `json:"rules"` // 限流规则
+}
+
+// Rule 限流规则
+type Rule struct {
+	Role   string  `json:"role"`   // 角色：admin、user、node、dns 等，"*" 表示所有角色
+	Method string  `json:"method"` // 方法：/pb.NodeService/FindEnabledNode、pb.NodeService、FindEnabledNode，"*" 表示所有方法
+	Rate   float64 `json:"rate"`   // 每秒允许的请求数
+	Burst  int     `json:"burst"`  // 最多允许突发的请求数，小于等于0时使用Rate的值
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		IsOn:  false,
+		Rules: []*Rule{},
+	}
+}
+
+// Init 初始化
+func (this *Config) Init() error {
+	for _, rule := range this.Rules {
+		if rule == nil {
+			return errors.New("rule should not be nil")
+		}
+		if len(rule.Role) == 0 {
+			rule.Role = AnyRole
+		}
+		if len(rule.Method) == 0 {
+			rule.Method = AnyMethod
+		}
+		if rule.Rate <= 0 {
+			return errors.New("invalid rate for rule '" + rule.Role + " " + rule.Method + "'")
+		}
+	}
+	return nil
+}
+
+// MatchRule 查找最匹配的规则
+// 匹配优先级：角色+完整方法 > 角色+服务 > 角色 > 任意角色+完整方法 > 任意角色+服务 > 任意角色
+func (this *Config) MatchRule(role string, fullMethod string) *Rule {
+	if this == nil || !this.IsOn {
+		return nil
+	}
+
+	var bestRule *Rule
+	var bestScore = -1
+	for _, rule := range this.Rules {
+		var score = 0
+		if rule.Role == role {
+			score += 10
+		} else if rule.Role != AnyRole {
+			continue
+		}
+
+		var methodScore = matchMethod(rule.Method, fullMethod)
+		if methodScore < 0 {
+			continue
+		}
+		score += methodScore
+
+		if score > bestScore {
+			bestScore = score
+			bestRule = rule
+		}
+	}
+	return bestRule
+}
+
+// 匹配方法，返回匹配程度，-1表示不匹配
+// fullMethod 格式为：/pb.NodeService/FindEnabledNode
+func matchMethod(ruleMethod string, fullMethod string) int {
+	if ruleMethod == AnyMethod {
+		return 0
+	}
+	if ruleMethod == fullMethod {
+		return 3
+	}
+
+	var pieces = strings.Split(strings.TrimPrefix(fullMethod, "/"), "/")
+	if len(pieces) != 2 {
+		return -1
+	}
+	var serviceName = pieces[0]
+	var methodName = pieces[1]
+	var shortServiceName = serviceName
+	var index = strings.LastIndex(serviceName, ".")
+	if index >= 0 {
+		shortServiceName = serviceName[index+1:]
+	}
+
+	if ruleMethod == serviceName || ruleMethod == shortServiceName {
+		return 1
+	}
+	if ruleMethod == methodName || ruleMethod == shortServiceName+"/"+methodName {
+		return 2
+	}
+	return -1
+}
@@ -0,0 +1,191 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package ratelimit
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+var SharedLimiter = NewLimiter()
+
+const maxStats = 100_000 // 最多保留的统计条数
+
+// 令牌桶
+type bucket struct {
+	role         string
+	method       string
+	tokens       float64
+	lastTime     time.Time
+	rate         float64
+	burst        float64
+	countAllowed int64
+	countDenied  int64
+}
+
+// Stat 限流统计
+type Stat struct {
+	Role         string
+	Identity     string
+	Method       string
+	CountAllowed int64
+	CountDenied  int64
+}
+
+// Limiter 按照 角色+身份+方法 进行限流的令牌桶限流器
+type Limiter struct {
+	config  *Config
+	buckets map[string]*bucket // key => bucket
+	stats   map[string]*Stat   // key => stat
+
+	lastGCTime time.Time
+
+	locker sync.Mutex
+}
+
+func NewLimiter() *Limiter {
+	return &Limiter{
+		config:  DefaultConfig(),
+		buckets: map[string]*bucket{},
+		stats:   map[string]*Stat{},
+	}
+}
+
+// UpdateConfig 修改配置
+// 只重置限制发生变化的令牌桶，以免定时重新加载配置时所有调用方都重新获得完整的突发额度
+func (this *Limiter) UpdateConfig(config *Config) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	this.config = config
+	for key, b := range this.buckets {
+		rate, burst, ok := ruleLimits(config.MatchRule(b.role, b.method))
+		if !ok || rate != b.rate || burst != b.burst {
+			delete(this.buckets, key)
+		}
+	}
+}
+
+// Config 读取当前配置
+func (this *Limiter) Config() *Config {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.config
+}
+
+// Allow 检查是否允许访问
+// 如果不允许，返回需要等待的时间
+func (this *Limiter) Allow(role string, identity string, fullMethod string) (ok bool, retryAfter time.Duration) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	var rule = this.config.MatchRule(role, fullMethod)
+	if rule == nil {
+		return true, 0
+	}
+
+	var now = time.Now()
+	this.gc(now)
+
+	var key = role + "@" + identity + "@" + fullMethod
+	b, exists := this.buckets[key]
+	if !exists {
+		rate, burst, _ := ruleLimits(rule)
+		b = &bucket{
+			role:     role,
+			method:   fullMethod,
+			tokens:   burst,
+			lastTime: now,
+			rate:     rate,
+			burst:    burst,
+		}
+		this.buckets[key] = b
+	} else {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.lastTime).Seconds()*b.rate)
+		b.lastTime = now
+	}
+
+	stat, exists := this.stats[key]
+	if !exists {
+		stat = &Stat{
+			Role:     role,
+			Identity: identity,
+			Method:   fullMethod,
+		}
+		if len(this.stats) < maxStats {
+			this.stats[key] = stat
+		}
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		stat.CountAllowed++
+		return true, 0
+	}
+
+	stat.CountDenied++
+	retryAfter = time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	if retryAfter < time.Millisecond {
+		retryAfter = time.Millisecond
+	}
+	return false, retryAfter
+}
+
+// Stats 读取统计数据，按被拒绝次数倒序排列
+func (this *Limiter) Stats() []*Stat {
+	this.locker.Lock()
+	var result = make([]*Stat, 0, len(this.stats))
+	for _, stat := range this.stats {
+		var statCopy = *stat
+		result = append(result, &statCopy)
+	}
+	this.locker.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CountDenied == result[j].CountDenied {
+			return result[i].CountAllowed > result[j].CountAllowed
+		}
+		return result[i].CountDenied > result[j].CountDenied
+	})
+	return result
+}
+
+// ResetStats 清空统计数据
+func (this *Limiter) ResetStats() {
+	this.locker.Lock()
+	this.stats = map[string]*Stat{}
+	this.locker.Unlock()
+}
+
+// 规则对应的速率和突发数量
+func ruleLimits(rule *Rule) (rate float64, burst float64, ok bool) {
+	if rule == nil {
+		return 0, 0, false
+	}
+	burst = float64(rule.Burst)
+	if burst <= 0 {
+		burst = math.Max(rule.Rate, 1)
+	}
+	return rule.Rate, burst, true
+}
+
+// 清理长时间没有访问的令牌桶
+func (this *Limiter) gc(now time.Time) {
+	if now.Sub(this.lastGCTime) < time.Minute {
+		return
+	}
+	this.lastGCTime = now
+
+	for key, b := range this.buckets {
+		// 已经装满的令牌桶可以删除，下次访问时会重新创建
+		if b.tokens+now.Sub(b.lastTime).Seconds()*b.rate >= b.burst {
+			delete(this.buckets, key)
+		}
+	}
+}
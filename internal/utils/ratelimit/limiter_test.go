@@ -0,0 +1,162 @@
+// Copyright 2024 GoEdge CDN goedge.cdn@gmail.com. All rights reserved. Official site: https://cdn.foyeseo.com .
+
+package ratelimit_test
+
+import (
+	"github.com/dashenmiren/EdgeAPI/internal/utils/ratelimit"
+	"testing"
+	"time"
+)
+
+func TestConfig_MatchRule(t *testing.T) {
+	var config = &ratelimit.Config{
+		IsOn: true,
+		Rules: []*ratelimit.Rule{
+			{Role: "*", Method: "*", Rate: 100},
+			{Role: "node", Method: "*", Rate: 50},
+			{Role: "node", Method: "NodeService", Rate: 20},
+			{Role: "node", Method: "/pb.NodeService/FindCurrentNodeConfig", Rate: 10},
+			{Role: "*", Method: "FindEnabledServer", Rate: 5},
+		},
+	}
+	err := config.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, item := range []struct {
+		role   string
+		method string
+		rate   float64
+	}{
+		{"admin", "/pb.NodeService/FindEnabledNode", 100},
+		{"node", "/pb.ServerService/UpdateServer", 50},
+		{"node", "/pb.NodeService/FindEnabledNode", 20},
+		{"node", "/pb.NodeService/FindCurrentNodeConfig", 10},
+		{"admin", "/pb.ServerService/FindEnabledServer", 5},
+	} {
+		var rule = config.MatchRule(item.role, item.method)
+		if rule == nil {
+			t.Fatal("rule not found for", item.role, item.method)
+		}
+		if rule.Rate != item.rate {
+			t.Fatal(item.role, item.method, "expect rate", item.rate, "but got", rule.Rate)
+		}
+	}
+
+	config.IsOn = false
+	if config.MatchRule("admin", "/pb.NodeService/FindEnabledNode") != nil {
+		t.Fatal("should not match any rule when disabled")
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	var limiter = ratelimit.NewLimiter()
+
+	// 默认不限制
+	for i := 0; i < 100; i++ {
+		ok, _ := limiter.Allow("node", "1", "/pb.NodeService/FindEnabledNode")
+		if !ok {
+			t.Fatal("should allow when disabled")
+		}
+	}
+
+	var config = &ratelimit.Config{
+		IsOn: true,
+		Rules: []*ratelimit.Rule{
+			{Role: "node", Method: "*", Rate: 10, Burst: 3},
+		},
+	}
+	err := config.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter.UpdateConfig(config)
+
+	var countAllowed = 0
+	for i := 0; i < 5; i++ {
+		ok, retryAfter := limiter.Allow("node", "1", "/pb.NodeService/FindEnabledNode")
+		if ok {
+			countAllowed++
+		} else {
+			t.Log("retry after:", retryAfter)
+		}
+	}
+	if countAllowed != 3 {
+		t.Fatal("expect 3 allowed requests, but got", countAllowed)
+	}
+
+	// 其他节点不受影响
+	ok, _ := limiter.Allow("node", "2", "/pb.NodeService/FindEnabledNode")
+	if !ok {
+		t.Fatal("other nodes should not be limited")
+	}
+
+	// 等待令牌补充
+	time.Sleep(200 * time.Millisecond)
+	ok, _ = limiter.Allow("node", "1", "/pb.NodeService/FindEnabledNode")
+	if !ok {
+		t.Fatal("should allow after tokens refilled")
+	}
+
+	for _, stat := range limiter.Stats() {
+		t.Logf("%+v", stat)
+	}
+}
+
+func TestLimiter_UpdateConfig(t *testing.T) {
+	var newConfig = func(rate float64, burst int) *ratelimit.Config {
+		var config = &ratelimit.Config{
+			IsOn: true,
+			Rules: []*ratelimit.Rule{
+				{Role: "node", Method: "*", Rate: rate, Burst: burst},
+			},
+		}
+		err := config.Init()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return config
+	}
+	var drain = func(limiter *ratelimit.Limiter) (countAllowed int) {
+		for i := 0; i < 10; i++ {
+			ok, _ := limiter.Allow("node", "1", "/pb.NodeService/FindEnabledNode")
+			if ok {
+				countAllowed++
+			}
+		}
+		return
+	}
+
+	var limiter = ratelimit.NewLimiter()
+	limiter.UpdateConfig(newConfig(0.01, 3))
+	if drain(limiter) != 3 {
+		t.Fatal("expect 3 allowed requests")
+	}
+
+	// 重新加载相同的配置时不重置令牌桶
+	limiter.UpdateConfig(newConfig(0.01, 3))
+	if drain(limiter) != 0 {
+		t.Fatal("reloading same config should not refill buckets")
+	}
+
+	// 限制变化后重置
+	limiter.UpdateConfig(newConfig(0.01, 5))
+	if drain(limiter) != 5 {
+		t.Fatal("changed limits should reset buckets")
+	}
+}
+
+func BenchmarkLimiter_Allow(b *testing.B) {
+	var limiter = ratelimit.NewLimiter()
+	limiter.UpdateConfig(&ratelimit.Config{
+		IsOn: true,
+		Rules: []*ratelimit.Rule{
+			{Role: "*", Method: "*", Rate: 1_000_000},
+		},
+	})
+
+	for i := 0; i < b.N; i++ {
+		_, _ = limiter.Allow("node", "1", "/pb.NodeService/FindEnabledNode")
+	}
+}